@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-core/pkg/logger"
+	"gopkg.in/typ.v4/slices"
+)
+
+// accessLogRedactedParams are the route parameters whose values are redacted
+// from the logged paths, as they are secrets.
+var accessLogRedactedParams = []string{"projectToken"}
+
+// newAccessLogMiddleware creates a Gin middleware that logs all requests using
+// the wharf-core logger, except for the skipped paths.
+//
+// Requests to the routes listed in HTTPAccessLogConfig.SampledRoutes are only
+// logged for a fraction of the requests, unless they fail.
+func newAccessLogMiddleware(config HTTPAccessLogConfig, skipPaths []string) gin.HandlerFunc {
+	ginLog := logger.NewScoped("GIN")
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		if slices.Contains(skipPaths, path) {
+			return
+		}
+		for _, param := range accessLogRedactedParams {
+			if value := c.Param(param); value != "" {
+				path = strings.Replace(path, value, redactedConfigValue, 1)
+			}
+		}
+		if c.Request.URL.RawQuery != "" {
+			path += "?" + c.Request.URL.RawQuery
+		}
+		route := c.FullPath()
+		status := c.Writer.Status()
+		if status < 400 && !shouldLogSampledRoute(config, c.Request.Method+" "+route) {
+			return
+		}
+		ev := ginLog.Debug().
+			WithString("clientIp", c.ClientIP()).
+			WithString("method", c.Request.Method).
+			WithString("path", path).
+			WithString("route", route).
+			WithInt("status", status).
+			WithDuration("latency", time.Since(start)).
+			WithInt64("requestSize", c.Request.ContentLength).
+			WithInt("responseSize", c.Writer.Size())
+		if subject := getRequestSubject(c); subject != "" {
+			ev = ev.WithString("subject", subject)
+		}
+		if requestID := getRequestID(c); requestID != "" {
+			ev = ev.WithString("requestId", requestID)
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate); len(errs) > 0 {
+			ev = ev.WithError(errors.New(errs.String()))
+		}
+		ev.Message("")
+	}
+}
+
+func shouldLogSampledRoute(config HTTPAccessLogConfig, methodAndRoute string) bool {
+	if !slices.Contains(config.SampledRoutes, methodAndRoute) {
+		return true
+	}
+	return rand.Float64() < config.SampleRate
+}
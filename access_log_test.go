@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldLogSampledRoute(t *testing.T) {
+	sampledRoutes := []string{"POST /api/build/:buildId/log"}
+
+	none := HTTPAccessLogConfig{SampledRoutes: sampledRoutes, SampleRate: 0}
+	assert.False(t, shouldLogSampledRoute(none, "POST /api/build/:buildId/log"), "sampled route with rate 0")
+	assert.True(t, shouldLogSampledRoute(none, "GET /api/build/:buildId/log"), "other method")
+	assert.True(t, shouldLogSampledRoute(none, "POST /api/build"), "other route")
+
+	all := HTTPAccessLogConfig{SampledRoutes: sampledRoutes, SampleRate: 1}
+	assert.True(t, shouldLogSampledRoute(all, "POST /api/build/:buildId/log"), "sampled route with rate 1")
+}
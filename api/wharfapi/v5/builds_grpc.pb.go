@@ -23,6 +23,20 @@ type BuildsClient interface {
 	// Logs targeting non-existing builds as well as logs that has already been
 	// added before (based on the build, log, and step IDs) will be discarded.
 	CreateLogStream(ctx context.Context, opts ...grpc.CallOption) (Builds_CreateLogStreamClient, error)
+	// CreateBuildLink adds an external link to a build, such as to a test
+	// report or a deployment dashboard.
+	CreateBuildLink(ctx context.Context, in *CreateBuildLinkRequest, opts ...grpc.CallOption) (*CreateBuildLinkResponse, error)
+	// StreamLogs sends the log lines of a build as a server-side stream, starting
+	// with the already added log lines and then following new log lines as they
+	// are added, until the client closes the stream.
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Builds_StreamLogsClient, error)
+	// DownloadArtifact sends the data of a build artifact as a server-side
+	// stream of chunks, with the artifact's metadata in the first message.
+	DownloadArtifact(ctx context.Context, in *DownloadArtifactRequest, opts ...grpc.CallOption) (Builds_DownloadArtifactClient, error)
+	// UpdateBuildStatus updates the status of a build, as reported by the worker
+	// running the build, together with the reason of the status and the results
+	// of the build's stages.
+	UpdateBuildStatus(ctx context.Context, in *UpdateBuildStatusRequest, opts ...grpc.CallOption) (*UpdateBuildStatusResponse, error)
 }
 
 type buildsClient struct {
@@ -67,6 +81,88 @@ func (x *buildsCreateLogStreamClient) CloseAndRecv() (*CreateLogStreamResponse,
 	return m, nil
 }
 
+func (c *buildsClient) CreateBuildLink(ctx context.Context, in *CreateBuildLinkRequest, opts ...grpc.CallOption) (*CreateBuildLinkResponse, error) {
+	out := new(CreateBuildLinkResponse)
+	err := c.cc.Invoke(ctx, "/wharf.api.v5.Builds/CreateBuildLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *buildsClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Builds_StreamLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Builds_ServiceDesc.Streams[1], "/wharf.api.v5.Builds/StreamLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &buildsStreamLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Builds_StreamLogsClient interface {
+	Recv() (*LogLine, error)
+	grpc.ClientStream
+}
+
+type buildsStreamLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *buildsStreamLogsClient) Recv() (*LogLine, error) {
+	m := new(LogLine)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *buildsClient) DownloadArtifact(ctx context.Context, in *DownloadArtifactRequest, opts ...grpc.CallOption) (Builds_DownloadArtifactClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Builds_ServiceDesc.Streams[2], "/wharf.api.v5.Builds/DownloadArtifact", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &buildsDownloadArtifactClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Builds_DownloadArtifactClient interface {
+	Recv() (*DownloadArtifactResponse, error)
+	grpc.ClientStream
+}
+
+type buildsDownloadArtifactClient struct {
+	grpc.ClientStream
+}
+
+func (x *buildsDownloadArtifactClient) Recv() (*DownloadArtifactResponse, error) {
+	m := new(DownloadArtifactResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *buildsClient) UpdateBuildStatus(ctx context.Context, in *UpdateBuildStatusRequest, opts ...grpc.CallOption) (*UpdateBuildStatusResponse, error) {
+	out := new(UpdateBuildStatusResponse)
+	err := c.cc.Invoke(ctx, "/wharf.api.v5.Builds/UpdateBuildStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BuildsServer is the server API for Builds service.
 // All implementations must embed UnimplementedBuildsServer
 // for forward compatibility
@@ -75,6 +171,20 @@ type BuildsServer interface {
 	// Logs targeting non-existing builds as well as logs that has already been
 	// added before (based on the build, log, and step IDs) will be discarded.
 	CreateLogStream(Builds_CreateLogStreamServer) error
+	// CreateBuildLink adds an external link to a build, such as to a test
+	// report or a deployment dashboard.
+	CreateBuildLink(context.Context, *CreateBuildLinkRequest) (*CreateBuildLinkResponse, error)
+	// StreamLogs sends the log lines of a build as a server-side stream, starting
+	// with the already added log lines and then following new log lines as they
+	// are added, until the client closes the stream.
+	StreamLogs(*StreamLogsRequest, Builds_StreamLogsServer) error
+	// DownloadArtifact sends the data of a build artifact as a server-side
+	// stream of chunks, with the artifact's metadata in the first message.
+	DownloadArtifact(*DownloadArtifactRequest, Builds_DownloadArtifactServer) error
+	// UpdateBuildStatus updates the status of a build, as reported by the worker
+	// running the build, together with the reason of the status and the results
+	// of the build's stages.
+	UpdateBuildStatus(context.Context, *UpdateBuildStatusRequest) (*UpdateBuildStatusResponse, error)
 	mustEmbedUnimplementedBuildsServer()
 }
 
@@ -85,6 +195,18 @@ type UnimplementedBuildsServer struct {
 func (UnimplementedBuildsServer) CreateLogStream(Builds_CreateLogStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method CreateLogStream not implemented")
 }
+func (UnimplementedBuildsServer) CreateBuildLink(context.Context, *CreateBuildLinkRequest) (*CreateBuildLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBuildLink not implemented")
+}
+func (UnimplementedBuildsServer) StreamLogs(*StreamLogsRequest, Builds_StreamLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedBuildsServer) DownloadArtifact(*DownloadArtifactRequest, Builds_DownloadArtifactServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadArtifact not implemented")
+}
+func (UnimplementedBuildsServer) UpdateBuildStatus(context.Context, *UpdateBuildStatusRequest) (*UpdateBuildStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBuildStatus not implemented")
+}
 func (UnimplementedBuildsServer) mustEmbedUnimplementedBuildsServer() {}
 
 // UnsafeBuildsServer may be embedded to opt out of forward compatibility for this service.
@@ -124,19 +246,116 @@ func (x *buildsCreateLogStreamServer) Recv() (*CreateLogStreamRequest, error) {
 	return m, nil
 }
 
+func _Builds_CreateBuildLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBuildLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BuildsServer).CreateBuildLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wharf.api.v5.Builds/CreateBuildLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BuildsServer).CreateBuildLink(ctx, req.(*CreateBuildLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Builds_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BuildsServer).StreamLogs(m, &buildsStreamLogsServer{stream})
+}
+
+type Builds_StreamLogsServer interface {
+	Send(*LogLine) error
+	grpc.ServerStream
+}
+
+type buildsStreamLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *buildsStreamLogsServer) Send(m *LogLine) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Builds_DownloadArtifact_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadArtifactRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BuildsServer).DownloadArtifact(m, &buildsDownloadArtifactServer{stream})
+}
+
+type Builds_DownloadArtifactServer interface {
+	Send(*DownloadArtifactResponse) error
+	grpc.ServerStream
+}
+
+type buildsDownloadArtifactServer struct {
+	grpc.ServerStream
+}
+
+func (x *buildsDownloadArtifactServer) Send(m *DownloadArtifactResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Builds_UpdateBuildStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBuildStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BuildsServer).UpdateBuildStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wharf.api.v5.Builds/UpdateBuildStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BuildsServer).UpdateBuildStatus(ctx, req.(*UpdateBuildStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Builds_ServiceDesc is the grpc.ServiceDesc for Builds service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Builds_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "wharf.api.v5.Builds",
 	HandlerType: (*BuildsServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateBuildLink",
+			Handler:    _Builds_CreateBuildLink_Handler,
+		},
+		{
+			MethodName: "UpdateBuildStatus",
+			Handler:    _Builds_UpdateBuildStatus_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "CreateLogStream",
 			Handler:       _Builds_CreateLogStream_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _Builds_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadArtifact",
+			Handler:       _Builds_DownloadArtifact_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "api/wharfapi/v5/builds.proto",
 }
@@ -7,26 +7,32 @@ import (
 	"mime"
 	"net/http"
 	"path/filepath"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/iver-wharf/wharf-api/v5/internal/ctxparser"
-	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
-	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
-	"gorm.io/gorm"
 )
 
 type artifactModule struct {
-	Database *gorm.DB
+	Builds    BuildRepo
+	Artifacts ArtifactRepo
+	// UploadLimit is the middleware that limits the size of uploads.
+	UploadLimit gin.HandlerFunc
+	// Idempotent is the middleware that handles the Idempotency-Key header on
+	// uploads.
+	Idempotent gin.HandlerFunc
 }
 
 func (m artifactModule) Register(g *gin.RouterGroup) {
 	g.GET("/artifact", m.getBuildArtifactListHandler)
 	g.GET("/artifact/:artifactId", m.getBuildArtifactHandler)
-	g.POST("/artifact", m.createBuildArtifactHandler)
+	g.DELETE("/artifact/:artifactId", m.deleteBuildArtifactHandler)
+	g.POST("/artifact", m.UploadLimit, m.Idempotent, m.createBuildArtifactHandler)
 	// deprecated
 	g.GET("/tests-results", m.getBuildTestResultListHandler)
 }
@@ -37,7 +43,50 @@ var artifactJSONToColumns = map[string]database.SafeSQLName{
 	response.ArtifactJSONFields.FileName:   database.ArtifactColumns.FileName,
 }
 
-var defaultGetArtifactsOrderBy = orderby.Column{Name: database.ArtifactColumns.ArtifactID, Direction: orderby.Desc}
+// artifactListQueryParams holds the query parameters shared by the endpoints
+// that list artifacts.
+type artifactListQueryParams struct {
+	commonGetQueryParams
+
+	Name     *string `form:"name"`
+	FileName *string `form:"fileName"`
+
+	NameMatch     *string `form:"nameMatch" binding:"excluded_with=Name"`
+	FileNameMatch *string `form:"fileNameMatch" binding:"excluded_with=FileName"`
+
+	Match *string `form:"match"`
+
+	MinSize *int64 `form:"minSize" binding:"omitempty,min=0"`
+	MaxSize *int64 `form:"maxSize" binding:"omitempty,min=0"`
+}
+
+// bindArtifactListOptions binds the artifactListQueryParams, or writes a
+// problem response and returns false if they are invalid.
+func bindArtifactListOptions(c *gin.Context) (ArtifactListOptions, bool) {
+	params := artifactListQueryParams{
+		commonGetQueryParams: defaultCommonGetQueryParams,
+	}
+	if !bindCommonGetQueryParams(c, &params) {
+		return ArtifactListOptions{}, false
+	}
+	orderBySlice, ok := parseCommonOrderBySlice(c, params.OrderBy, artifactJSONToColumns)
+	if !ok {
+		return ArtifactListOptions{}, false
+	}
+	return ArtifactListOptions{
+		Limit:         params.Limit,
+		Offset:        params.Offset,
+		SkipTotal:     params.SkipTotal,
+		OrderBy:       orderBySlice,
+		Name:          params.Name,
+		FileName:      params.FileName,
+		NameMatch:     params.NameMatch,
+		FileNameMatch: params.FileNameMatch,
+		Match:         params.Match,
+		MinSize:       params.MinSize,
+		MaxSize:       params.MaxSize,
+	}, true
+}
 
 // getBuildArtifactListHandler godoc
 // @id getBuildArtifactList
@@ -49,14 +98,17 @@ var defaultGetArtifactsOrderBy = orderby.Column{Name: database.ArtifactColumns.A
 // @tags artifact
 // @produce json
 // @param buildId path uint true "Build ID" minimum(0)
-// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`). Required if `offset` is used." default(100)
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
 // @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
-// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc'. Can be specified multiple times for more granular sorting. Defaults to `?orderby=artifactId desc`"
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Casing, prefixes, and commas added in v5.3.0. Defaults to `?orderby=artifactId desc`"
 // @param name query string false "Filter by verbatim artifact name."
 // @param fileName query string false "Filter by verbatim artifact file name."
 // @param nameMatch query string false "Filter by matching artifact name. Cannot be used with `name`."
 // @param fileNameMatch query string false "Filter by matching artifact file name. Cannot be used with `fileName`."
 // @param match query string false "Filter by matching on any supported fields."
+// @param minSize query int false "Filter by artifacts with at least this many bytes. Added in v5.3.0." minimum(0)
+// @param maxSize query int false "Filter by artifacts with at most this many bytes. Added in v5.3.0." minimum(0)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedArtifacts
 // @failure 400 {object} problem.Response "Bad request"
@@ -68,52 +120,12 @@ func (m artifactModule) getBuildArtifactListHandler(c *gin.Context) {
 	if !ok {
 		return
 	}
-	var params = struct {
-		commonGetQueryParams
-
-		Name     *string `form:"name"`
-		FileName *string `form:"fileName"`
-
-		NameMatch     *string `form:"nameMatch" binding:"excluded_with=Name"`
-		FileNameMatch *string `form:"fileNameMatch" binding:"excluded_with=FileName"`
-
-		Match *string `form:"match"`
-	}{
-		commonGetQueryParams: defaultCommonGetQueryParams,
-	}
-	if !bindCommonGetQueryParams(c, &params) {
-		return
-	}
-	orderBySlice, ok := parseCommonOrderBySlice(c, params.OrderBy, artifactJSONToColumns)
+	opts, ok := bindArtifactListOptions(c)
 	if !ok {
 		return
 	}
 
-	var where wherefields.Collection
-	where.AddFieldName(database.ArtifactFields.BuildID)
-
-	query := m.Database.
-		Clauses(orderBySlice.ClauseIfNone(defaultGetArtifactsOrderBy)).
-		Where(&database.Artifact{
-			BuildID:  buildID,
-			Name:     where.String(database.ArtifactFields.Name, params.Name),
-			FileName: where.String(database.ArtifactFields.FileName, params.FileName),
-		}, where.NonNilFieldNames()...).
-		Scopes(
-			whereLikeScope(map[database.SafeSQLName]*string{
-				database.ArtifactColumns.Name:     params.NameMatch,
-				database.ArtifactColumns.FileName: params.FileNameMatch,
-			}),
-			whereAnyLikeScope(
-				params.Match,
-				database.ArtifactColumns.Name,
-				database.ArtifactColumns.FileName,
-			),
-		)
-
-	var dbArtifacts []database.Artifact
-	var totalCount int64
-	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, &dbArtifacts, &totalCount)
+	dbArtifacts, totalCount, err := m.Artifacts.List(buildID, opts)
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
 			"Failed fetching list of artifacts for build with ID %d from database.",
@@ -122,7 +134,7 @@ func (m artifactModule) getBuildArtifactListHandler(c *gin.Context) {
 		return
 	}
 
-	renderJSON(c, http.StatusOK, response.PaginatedArtifacts{
+	ginrender.Response(c, http.StatusOK, response.PaginatedArtifacts{
 		List:       modelconv.DBArtifactsToResponses(dbArtifacts),
 		TotalCount: totalCount,
 	})
@@ -153,15 +165,8 @@ func (m artifactModule) getBuildArtifactHandler(c *gin.Context) {
 		return
 	}
 
-	var dbArtifact database.Artifact
-	err := m.Database.
-		Where(&database.Artifact{
-			BuildID:    buildID,
-			ArtifactID: artifactID}).
-		Order(database.ArtifactColumns.ArtifactID + " DESC").
-		First(&dbArtifact).
-		Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
+	dbArtifact, err := m.Artifacts.Get(buildID, artifactID)
+	if errors.Is(err, errRepoNotFound) {
 		ginutil.WriteDBNotFound(c, fmt.Sprintf(
 			"Artifact with ID %d was not found on build with ID %d.",
 			artifactID, buildID))
@@ -173,14 +178,59 @@ func (m artifactModule) getBuildArtifactHandler(c *gin.Context) {
 		return
 	}
 
-	extension := filepath.Ext(dbArtifact.FileName)
-	mimeType := mime.TypeByExtension(extension)
+	mimeType := dbArtifact.ContentType
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(dbArtifact.FileName))
+	}
 	disposition := fmt.Sprintf("attachment; filename=\"%s\"", dbArtifact.FileName)
 
 	c.Header("Content-Disposition", disposition)
 	c.Data(http.StatusOK, mimeType, dbArtifact.Data)
 }
 
+// deleteBuildArtifactHandler godoc
+// @id deleteBuildArtifact
+// @summary Delete build artifact
+// @description Deletes the artifact's file data, such as to free up space in the database.
+// @description Test results parsed from the artifact, when uploaded as test results, are kept,
+// @description but their `artifactId` is set to null.
+// @description Added in v5.3.0.
+// @tags artifact
+// @param buildId path uint true "Build ID" minimum(0)
+// @param artifactId path uint true "Artifact ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Artifact not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/artifact/{artifactId} [delete]
+func (m artifactModule) deleteBuildArtifactHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+
+	artifactID, ok := ginutil.ParseParamUint(c, "artifactId")
+	if !ok {
+		return
+	}
+
+	err := m.Artifacts.Delete(buildID, artifactID)
+	if errors.Is(err, errRepoNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Artifact with ID %d was not found on build with ID %d.",
+			artifactID, buildID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting artifact with ID %d on build with ID %d from database.",
+			artifactID, buildID))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // createBuildArtifactHandler godoc
 // @id createBuildArtifact
 // @summary Post build artifact
@@ -189,14 +239,18 @@ func (m artifactModule) getBuildArtifactHandler(c *gin.Context) {
 // @accept multipart/form-data
 // @param buildId path uint true "Build ID" minimum(0)
 // @param files formData file true "Build artifact file"
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
 // @success 201 "Added new artifacts"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
-// @failure 404 {object} problem.Response "Artifact not found"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 409 {object} problem.Response "Request with the same idempotency key is still being handled"
+// @failure 413 {object} problem.Response "Request body is larger than the artifact.maxUploadSize config. Added in v5.3.0."
+// @failure 422 {object} problem.Response "Idempotency key was already used for a different request"
 // @failure 502 {object} problem.Response "Database is unreachable"
 // @router /build/{buildId}/artifact [post]
 func (m artifactModule) createBuildArtifactHandler(c *gin.Context) {
-	log.Debug().Message("Start of createBuildArtifactHandler")
+	requestLog(c).Debug().Message("Start of createBuildArtifactHandler")
 	buildID, ok := ginutil.ParseParamUint(c, "buildId")
 	if !ok {
 		return
@@ -204,13 +258,20 @@ func (m artifactModule) createBuildArtifactHandler(c *gin.Context) {
 
 	files, err := ctxparser.ParseMultipartFormDataFiles(c, "files")
 	if err != nil {
+		if writeUploadTooLargeIfExceeded(c) {
+			return
+		}
 		ginutil.WriteMultipartFormReadError(c, err,
 			fmt.Sprintf("Failed reading multipart-form's file data from request body when uploading"+
 				" new artifact for build with ID %d.", buildID))
 		return
 	}
 
-	_, ok = createArtifacts(c, m.Database, files, buildID)
+	if !validateRepoObjExistsByID(c, m.Builds.Exists, buildID, "build", "when uploading artifacts") {
+		return
+	}
+
+	_, ok = createArtifacts(c, m.Artifacts, files, buildID)
 	if !ok {
 		return
 	}
@@ -239,13 +300,7 @@ func (m artifactModule) getBuildTestResultListHandler(c *gin.Context) {
 		return
 	}
 
-	testRunFiles := []database.Artifact{}
-
-	err := m.Database.
-		Where(&database.Artifact{BuildID: buildID}).
-		Where(database.ArtifactColumns.FileName+" LIKE ?", "%.trx").
-		Find(&testRunFiles).
-		Error
+	testRunFiles, err := m.Artifacts.ListByFileExtension(buildID, ".trx")
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
 			"Failed fetching test result artifacts for build with ID %d from database.",
@@ -270,19 +325,21 @@ func (m artifactModule) getBuildTestResultListHandler(c *gin.Context) {
 		resResults.Status = response.TestStatusFailed
 	}
 
-	renderJSON(c, http.StatusOK, resResults)
+	ginrender.Response(c, http.StatusOK, resResults)
 }
 
-func createArtifacts(c *gin.Context, db *gorm.DB, files []ctxparser.File, buildID uint) ([]database.Artifact, bool) {
+func createArtifacts(c *gin.Context, artifacts ArtifactRepo, files []ctxparser.File, buildID uint) ([]database.Artifact, bool) {
 	dbArtifacts := make([]database.Artifact, len(files))
 	for idx, f := range files {
 		artifactPtr := &dbArtifacts[idx]
 		artifactPtr.Data = f.Data
 		artifactPtr.Name = f.Name
 		artifactPtr.FileName = f.FileName
+		artifactPtr.Size = int64(len(f.Data))
+		artifactPtr.ContentType = detectArtifactContentType(f.FileName, f.Data)
 		artifactPtr.BuildID = buildID
 
-		err := db.Create(artifactPtr).Error
+		err := artifacts.Create(artifactPtr)
 		if err != nil {
 			ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 				"Failed saving artifact with name %q for build with ID %d in database.",
@@ -290,7 +347,7 @@ func createArtifacts(c *gin.Context, db *gorm.DB, files []ctxparser.File, buildI
 			return dbArtifacts, false
 		}
 
-		log.Debug().
+		requestLog(c).Debug().
 			WithString("filename", artifactPtr.FileName).
 			WithUint("build", buildID).
 			WithUint("artifact", artifactPtr.ArtifactID).
@@ -298,3 +355,16 @@ func createArtifacts(c *gin.Context, db *gorm.DB, files []ctxparser.File, buildI
 	}
 	return dbArtifacts, true
 }
+
+// detectArtifactContentType returns the MIME type of the artifact, based on
+// its file extension, or on its contents if the extension is not known.
+func detectArtifactContentType(fileName string, data []byte) string {
+	contentType := mime.TypeByExtension(filepath.Ext(fileName))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	if utf8.RuneCountInString(contentType) > database.ArtifactSizes.ContentType {
+		return ""
+	}
+	return contentType
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+type artifactPromotionModule struct {
+	Database *gorm.DB
+	// Idempotent is the middleware that handles the Idempotency-Key header on
+	// promotions.
+	Idempotent gin.HandlerFunc
+}
+
+func (m artifactPromotionModule) Register(g *gin.RouterGroup) {
+	g.POST("/artifact/:artifactId/promote", m.Idempotent, m.promoteBuildArtifactHandler)
+}
+
+// promoteBuildArtifactHandler godoc
+// @id promoteBuildArtifact
+// @summary Promote build artifact to another build or to a release.
+// @description Copies the artifact to another build, or to a named release of
+// @description the build's project, such as when a release pipeline promotes
+// @description a tested binary without uploading it again. Exactly one of
+// @description `targetBuildId` and `release` must be set. The copy records
+// @description the IDs of the build and artifact it was promoted from, which
+// @description are kept even after the source is deleted.
+// @description Release artifacts are listed via the
+// @description GET /project/{projectId}/release-artifact endpoint, and a
+// @description release can only have one artifact per file name. The
+// @description response is a release artifact when promoted to a release.
+// @description Added in v5.3.0.
+// @tags artifact
+// @accept json
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param artifactId path uint true "Artifact ID" minimum(0)
+// @param promotion body request.ArtifactPromotion true "Where to promote the artifact to"
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 201 {object} response.Artifact "Promoted artifact, or a response.ReleaseArtifact when promoted to a release"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Artifact or target build not found"
+// @failure 409 {object} problem.Response "Release already has an artifact with the same file name"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/artifact/{artifactId}/promote [post]
+func (m artifactPromotionModule) promoteBuildArtifactHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	artifactID, ok := ginutil.ParseParamUint(c, "artifactId")
+	if !ok {
+		return
+	}
+	var reqPromotion request.ArtifactPromotion
+	if err := c.ShouldBindJSON(&reqPromotion); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for artifact promotion.")
+		return
+	}
+	if !validateArtifactPromotion(c, buildID, reqPromotion) {
+		return
+	}
+	var dbArtifact database.Artifact
+	err := m.Database.
+		Where(&database.Artifact{BuildID: buildID, ArtifactID: artifactID}).
+		First(&dbArtifact).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Artifact with ID %d was not found on build with ID %d.",
+			artifactID, buildID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching artifact with ID %d on build with ID %d.",
+			artifactID, buildID))
+		return
+	}
+	if reqPromotion.TargetBuildID != nil {
+		m.promoteArtifactToBuild(c, dbArtifact, *reqPromotion.TargetBuildID)
+	} else {
+		m.promoteArtifactToRelease(c, dbArtifact, reqPromotion.Release)
+	}
+}
+
+func (m artifactPromotionModule) promoteArtifactToBuild(c *gin.Context, dbSource database.Artifact, targetBuildID uint) {
+	if !validateBuildExistsByID(c, m.Database, targetBuildID, "when promoting artifact") {
+		return
+	}
+	dbArtifact := database.Artifact{
+		BuildID:                targetBuildID,
+		Name:                   dbSource.Name,
+		FileName:               dbSource.FileName,
+		Size:                   dbSource.Size,
+		ContentType:            dbSource.ContentType,
+		Data:                   dbSource.Data,
+		PromotedFromBuildID:    null.IntFrom(int64(dbSource.BuildID)),
+		PromotedFromArtifactID: null.IntFrom(int64(dbSource.ArtifactID)),
+	}
+	if err := m.Database.Create(&dbArtifact).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed promoting artifact with ID %d to build with ID %d in database.",
+			dbSource.ArtifactID, targetBuildID))
+		return
+	}
+	requestLog(c).Info().
+		WithUint("build", dbSource.BuildID).
+		WithUint("artifact", dbSource.ArtifactID).
+		WithUint("targetBuild", targetBuildID).
+		WithUint("targetArtifact", dbArtifact.ArtifactID).
+		Message("Promoted artifact to build.")
+	ginrender.Response(c, http.StatusCreated, modelconv.DBArtifactToResponse(dbArtifact))
+}
+
+func (m artifactPromotionModule) promoteArtifactToRelease(c *gin.Context, dbSource database.Artifact, release string) {
+	var dbBuild database.Build
+	err := m.Database.
+		Select(database.BuildColumns.ProjectID).
+		First(&dbBuild, dbSource.BuildID).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Build with ID %d was not found when promoting artifact.",
+			dbSource.BuildID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching project of build with ID %d when promoting artifact.",
+			dbSource.BuildID))
+		return
+	}
+	var count int64
+	if err := m.Database.
+		Model(&database.ReleaseArtifact{}).
+		Where(&database.ReleaseArtifact{
+			ProjectID: dbBuild.ProjectID,
+			Release:   release,
+			FileName:  dbSource.FileName,
+		}, database.ReleaseArtifactFields.ProjectID,
+			database.ReleaseArtifactFields.Release,
+			database.ReleaseArtifactFields.FileName).
+		Count(&count).
+		Error; err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed checking artifacts of release %q of project with ID %d.",
+			release, dbBuild.ProjectID))
+		return
+	}
+	if count > 0 {
+		ginutil.WriteProblem(c, problems.ArtifactReleaseConflict.Newf(
+			"Release %q of project with ID %d already has an artifact with the file name %q.",
+			release, dbBuild.ProjectID, dbSource.FileName))
+		return
+	}
+	dbArtifact := database.ReleaseArtifact{
+		ProjectID:              dbBuild.ProjectID,
+		Release:                release,
+		Name:                   dbSource.Name,
+		FileName:               dbSource.FileName,
+		Size:                   dbSource.Size,
+		ContentType:            dbSource.ContentType,
+		Data:                   dbSource.Data,
+		PromotedFromBuildID:    dbSource.BuildID,
+		PromotedFromArtifactID: dbSource.ArtifactID,
+	}
+	dbArtifact.PromotedByType, dbArtifact.PromotedByName, dbArtifact.PromotedBySubject = getUserFromContext(c)
+	if err := m.Database.Create(&dbArtifact).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed promoting artifact with ID %d to release %q of project with ID %d in database.",
+			dbSource.ArtifactID, release, dbBuild.ProjectID))
+		return
+	}
+	requestLog(c).Info().
+		WithUint("build", dbSource.BuildID).
+		WithUint("artifact", dbSource.ArtifactID).
+		WithUint("project", dbBuild.ProjectID).
+		WithString("release", release).
+		WithUint("releaseArtifact", dbArtifact.ReleaseArtifactID).
+		Message("Promoted artifact to release.")
+	ginrender.Response(c, http.StatusCreated, modelconv.DBReleaseArtifactToResponse(dbArtifact))
+}
+
+func validateArtifactPromotion(c *gin.Context, buildID uint, reqPromotion request.ArtifactPromotion) bool {
+	writeInvalid := func(field, detail string) bool {
+		ginutil.WriteProblem(c, problems.InvalidParam.With(problem.Response{
+			Detail:   detail,
+			Instance: c.Request.RequestURI + "#" + field,
+		}))
+		return false
+	}
+	if reqPromotion.TargetBuildID == nil && reqPromotion.Release == "" {
+		return writeInvalid("targetBuildId",
+			"Either the target build ID or the release must be set.")
+	}
+	if reqPromotion.TargetBuildID != nil && reqPromotion.Release != "" {
+		return writeInvalid("release",
+			"The target build ID and the release cannot both be set.")
+	}
+	if reqPromotion.TargetBuildID != nil && *reqPromotion.TargetBuildID == buildID {
+		return writeInvalid("targetBuildId", fmt.Sprintf(
+			"Cannot promote an artifact of build with ID %d to the same build.",
+			buildID))
+	}
+	return true
+}
+
+type releaseArtifactModule struct {
+	Database *gorm.DB
+}
+
+func (m releaseArtifactModule) Register(g *gin.RouterGroup) {
+	releaseArtifact := g.Group("/project/:projectId/release-artifact")
+	{
+		releaseArtifact.GET("", m.getReleaseArtifactListHandler)
+		releaseArtifact.GET("/:releaseArtifactId", m.getReleaseArtifactHandler)
+		releaseArtifact.DELETE("/:releaseArtifactId", m.deleteReleaseArtifactHandler)
+	}
+}
+
+// getReleaseArtifactListHandler godoc
+// @id getReleaseArtifactList
+// @summary Get list of release artifacts of project.
+// @description List the artifacts that have been promoted to the project's
+// @description releases, newest first, via the
+// @description POST /build/{buildId}/artifact/{artifactId}/promote endpoint.
+// @description Added in v5.3.0.
+// @tags artifact
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param release query string false "Filter by verbatim release name."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedReleaseArtifacts "Release artifacts"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/release-artifact [get]
+func (m releaseArtifactModule) getReleaseArtifactListHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching list of release artifacts for project") {
+		return
+	}
+	query := m.Database.
+		Omit(database.ReleaseArtifactColumns.Data).
+		Where(&database.ReleaseArtifact{ProjectID: projectID}, database.ReleaseArtifactFields.ProjectID)
+	if release, ok := c.GetQuery("release"); ok {
+		query = query.Where(&database.ReleaseArtifact{Release: release}, database.ReleaseArtifactFields.Release)
+	}
+	var dbArtifacts []database.ReleaseArtifact
+	if err := query.
+		Order(database.ReleaseArtifactColumns.ReleaseArtifactID + " DESC").
+		Find(&dbArtifacts).Error; err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching list of release artifacts for project with ID %d.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, response.PaginatedReleaseArtifacts{
+		List:       modelconv.DBReleaseArtifactsToResponses(dbArtifacts),
+		TotalCount: int64(len(dbArtifacts)),
+	})
+}
+
+// getReleaseArtifactHandler godoc
+// @id getReleaseArtifact
+// @summary Get release artifact
+// @description Downloads the file of an artifact promoted to a release.
+// @description Added in v5.3.0.
+// @tags artifact
+// @produce multipart/form-data
+// @param projectId path uint true "project ID" minimum(0)
+// @param releaseArtifactId path uint true "Release artifact ID" minimum(0)
+// @success 200 {file} string "OK"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Release artifact not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/release-artifact/{releaseArtifactId} [get]
+func (m releaseArtifactModule) getReleaseArtifactHandler(c *gin.Context) {
+	dbArtifact, ok := m.fetchReleaseArtifactFromParams(c, "")
+	if !ok {
+		return
+	}
+	mimeType := dbArtifact.ContentType
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(dbArtifact.FileName))
+	}
+	disposition := fmt.Sprintf("attachment; filename=\"%s\"", dbArtifact.FileName)
+
+	c.Header("Content-Disposition", disposition)
+	c.Data(http.StatusOK, mimeType, dbArtifact.Data)
+}
+
+// deleteReleaseArtifactHandler godoc
+// @id deleteReleaseArtifact
+// @summary Delete release artifact
+// @description Removes the artifact from its release. The build artifact it
+// @description was promoted from is not affected.
+// @description Added in v5.3.0.
+// @tags artifact
+// @param projectId path uint true "project ID" minimum(0)
+// @param releaseArtifactId path uint true "Release artifact ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Release artifact not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/release-artifact/{releaseArtifactId} [delete]
+func (m releaseArtifactModule) deleteReleaseArtifactHandler(c *gin.Context) {
+	dbArtifact, ok := m.fetchReleaseArtifactFromParams(c, "when deleting release artifact")
+	if !ok {
+		return
+	}
+	if err := m.Database.Delete(&dbArtifact).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting release artifact with ID %d from database.",
+			dbArtifact.ReleaseArtifactID))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (m releaseArtifactModule) fetchReleaseArtifactFromParams(c *gin.Context, whenMsg string) (database.ReleaseArtifact, bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return database.ReleaseArtifact{}, false
+	}
+	artifactID, ok := ginutil.ParseParamUint(c, "releaseArtifactId")
+	if !ok {
+		return database.ReleaseArtifact{}, false
+	}
+	var dbArtifact database.ReleaseArtifact
+	err := m.Database.
+		Where(&database.ReleaseArtifact{ProjectID: projectID}, database.ReleaseArtifactFields.ProjectID).
+		First(&dbArtifact, artifactID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Release artifact with ID %d was not found on project with ID %d%s.",
+			artifactID, projectID, spaceWhenMessage(whenMsg)))
+		return database.ReleaseArtifact{}, false
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching release artifact with ID %d on project with ID %d%s.",
+			artifactID, projectID, spaceWhenMessage(whenMsg)))
+		return database.ReleaseArtifact{}, false
+	}
+	return dbArtifact, true
+}
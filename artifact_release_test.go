@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestArtifactPromotionRouter(t *testing.T) (*gin.Engine, *gorm.DB, []database.Build, database.Artifact) {
+	gin.SetMode(gin.TestMode)
+	builds, dbProject := newTestInsertBuildModule(t)
+	dbBuilds := []database.Build{
+		{ProjectID: dbProject.ProjectID, GitBranch: "master", Stage: "ALL"},
+		{ProjectID: dbProject.ProjectID, GitBranch: "master", Stage: "deploy"},
+	}
+	require.NoError(t, builds.Database.Create(&dbBuilds).Error)
+	dbArtifact := database.Artifact{
+		BuildID:     dbBuilds[0].BuildID,
+		Name:        "files",
+		FileName:    "app.tar.gz",
+		Size:        5,
+		ContentType: "application/gzip",
+		Data:        []byte("hello"),
+	}
+	require.NoError(t, builds.Database.Create(&dbArtifact).Error)
+	r := gin.New()
+	g := r.Group("/api")
+	artifactPromotionModule{Database: builds.Database, Idempotent: func(*gin.Context) {}}.
+		Register(g.Group("/build/:buildId"))
+	releaseArtifactModule{Database: builds.Database}.Register(g)
+	return r, builds.Database, dbBuilds, dbArtifact
+}
+
+func TestPromoteBuildArtifactHandler_toBuild(t *testing.T) {
+	r, db, dbBuilds, dbSource := newTestArtifactPromotionRouter(t)
+	url := fmt.Sprintf("/api/build/%d/artifact/%d/promote", dbSource.BuildID, dbSource.ArtifactID)
+
+	w := serveTestWebhookRequest(r, http.MethodPost, url,
+		fmt.Sprintf(`{"targetBuildId":%d}`, dbSource.BuildID), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "must not promote to same build")
+
+	w = serveTestWebhookRequest(r, http.MethodPost, url,
+		fmt.Sprintf(`{"targetBuildId":%d}`, dbBuilds[1].BuildID), nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resArtifact response.Artifact
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resArtifact))
+	assert.Equal(t, dbBuilds[1].BuildID, resArtifact.BuildID)
+	assert.EqualValues(t, dbSource.BuildID, resArtifact.PromotedFromBuildID.Int64)
+	assert.EqualValues(t, dbSource.ArtifactID, resArtifact.PromotedFromArtifactID.Int64)
+
+	require.NoError(t, db.Delete(&dbSource).Error)
+	var dbArtifact database.Artifact
+	require.NoError(t, db.First(&dbArtifact, resArtifact.ArtifactID).Error)
+	assert.Equal(t, []byte("hello"), dbArtifact.Data, "must copy data")
+	assert.EqualValues(t, dbSource.ArtifactID, dbArtifact.PromotedFromArtifactID.Int64,
+		"must keep provenance after source is deleted")
+}
+
+func TestPromoteBuildArtifactHandler_toRelease(t *testing.T) {
+	r, _, _, dbSource := newTestArtifactPromotionRouter(t)
+	url := fmt.Sprintf("/api/build/%d/artifact/%d/promote", dbSource.BuildID, dbSource.ArtifactID)
+
+	w := serveTestWebhookRequest(r, http.MethodPost, url, `{"release":"v1.0.0"}`, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resArtifact response.ReleaseArtifact
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resArtifact))
+	assert.Equal(t, "v1.0.0", resArtifact.Release)
+	assert.Equal(t, dbSource.BuildID, resArtifact.PromotedFromBuildID)
+
+	w = serveTestWebhookRequest(r, http.MethodPost, url, `{"release":"v1.0.0"}`, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "/prob/api/artifact/release-conflict")
+
+	w = serveTestWebhookRequest(r, http.MethodPost, url, `{"release":"v1.1.0"}`, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	listURL := fmt.Sprintf("/api/project/%d/release-artifact", resArtifact.ProjectID)
+	w = serveTestWebhookRequest(r, http.MethodGet, listURL+"?release=v1.1.0", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resList response.PaginatedReleaseArtifacts
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resList))
+	require.Len(t, resList.List, 1)
+	assert.Equal(t, "v1.1.0", resList.List[0].Release)
+
+	w = serveTestWebhookRequest(r, http.MethodGet,
+		fmt.Sprintf("%s/%d", listURL, resArtifact.ReleaseArtifactID), "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "hello", w.Body.String())
+}
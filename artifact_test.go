@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestArtifactRouter(builds *fakeBuildRepo, artifacts *fakeArtifactRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	artifactModule{
+		Builds:      builds,
+		Artifacts:   artifacts,
+		UploadLimit: func(*gin.Context) {},
+		Idempotent:  func(*gin.Context) {},
+	}.Register(r.Group("/api/build/:buildId"))
+	return r
+}
+
+func TestGetBuildArtifactListHandler(t *testing.T) {
+	artifacts := &fakeArtifactRepo{artifacts: []database.Artifact{
+		{ArtifactID: 1, BuildID: 1, Name: "cache", FileName: "cache.tar", Size: 4096},
+		{ArtifactID: 2, BuildID: 1, Name: "results", FileName: "results.trx", Size: 512},
+		{ArtifactID: 3, BuildID: 1, Name: "coverage", FileName: "coverage.xml", Size: 1024},
+		{ArtifactID: 4, BuildID: 2, Name: "cache", FileName: "cache.tar", Size: 4096},
+	}}
+	r := newTestArtifactRouter(&fakeBuildRepo{}, artifacts)
+
+	testCases := []struct {
+		name      string
+		query     string
+		wantIDs   []uint
+		wantTotal int64
+	}{
+		{name: "default order", query: "", wantIDs: []uint{3, 2, 1}, wantTotal: 3},
+		{name: "order by name", query: "?orderby=name+asc", wantIDs: []uint{1, 3, 2}, wantTotal: 3},
+		{name: "match", query: "?match=C", wantIDs: []uint{3, 1}, wantTotal: 2},
+		{name: "file name", query: "?fileName=results.trx", wantIDs: []uint{2}, wantTotal: 1},
+		{name: "paginated", query: "?limit=1&offset=1", wantIDs: []uint{2}, wantTotal: 3},
+		{name: "min size", query: "?minSize=1024", wantIDs: []uint{3, 1}, wantTotal: 2},
+		{name: "size range", query: "?minSize=512&maxSize=1024", wantIDs: []uint{3, 2}, wantTotal: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/build/1/artifact"+tc.query, nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var res response.PaginatedArtifacts
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			gotIDs := make([]uint, len(res.List))
+			for i, a := range res.List {
+				gotIDs[i] = a.ArtifactID
+			}
+			assert.Equal(t, tc.wantIDs, gotIDs)
+			assert.Equal(t, tc.wantTotal, res.TotalCount)
+		})
+	}
+}
+
+func TestGetBuildArtifactListHandler_dbError(t *testing.T) {
+	r := newTestArtifactRouter(&fakeBuildRepo{}, &fakeArtifactRepo{err: errors.New("connection refused")})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/build/1/artifact", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestGetBuildArtifactHandler(t *testing.T) {
+	artifacts := &fakeArtifactRepo{artifacts: []database.Artifact{
+		{ArtifactID: 1, BuildID: 1, Name: "report", FileName: "report.txt", Data: []byte("hello")},
+	}}
+	r := newTestArtifactRouter(&fakeBuildRepo{}, artifacts)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/build/1/artifact/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, `attachment; filename="report.txt"`, w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/build/2/artifact/1", nil))
+	assert.Contains(t, w.Body.String(), "/prob/api/record-not-found", "artifact of other build")
+}
+
+func TestDeleteBuildArtifactHandler(t *testing.T) {
+	artifacts := &fakeArtifactRepo{artifacts: []database.Artifact{
+		{ArtifactID: 1, BuildID: 1, FileName: "report.txt"},
+		{ArtifactID: 2, BuildID: 1, FileName: "results.trx"},
+	}}
+	r := newTestArtifactRouter(&fakeBuildRepo{}, artifacts)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/build/2/artifact/1", nil))
+	assert.Contains(t, w.Body.String(), "/prob/api/record-not-found", "artifact of other build")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/build/1/artifact/1", nil))
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	require.Len(t, artifacts.artifacts, 1)
+	assert.Equal(t, uint(2), artifacts.artifacts[0].ArtifactID)
+}
+
+func TestCreateBuildArtifactHandler(t *testing.T) {
+	builds := &fakeBuildRepo{builds: []database.Build{{BuildID: 1}}}
+	artifacts := &fakeArtifactRepo{}
+	r := newTestArtifactRouter(builds, artifacts)
+
+	newUploadRequest := func(buildID string) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("files", "report.txt")
+		require.NoError(t, err)
+		fw.Write([]byte("hello"))
+		require.NoError(t, mw.Close())
+		req := httptest.NewRequest(http.MethodPost, "/api/build/"+buildID+"/artifact", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest("1"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.Len(t, artifacts.artifacts, 1)
+	assert.Equal(t, uint(1), artifacts.artifacts[0].BuildID)
+	assert.Equal(t, "report.txt", artifacts.artifacts[0].FileName)
+	assert.Equal(t, []byte("hello"), artifacts.artifacts[0].Data)
+	assert.Equal(t, int64(5), artifacts.artifacts[0].Size)
+	assert.Contains(t, artifacts.artifacts[0].ContentType, "text/plain")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest("2"))
+	assert.Contains(t, w.Body.String(), "/prob/api/record-not-found", "unknown build")
+	assert.Len(t, artifacts.artifacts, 1)
+}
+
+func TestCreateBuildArtifactHandler_tooLarge(t *testing.T) {
+	builds := &fakeBuildRepo{builds: []database.Build{{BuildID: 1}}}
+	artifacts := &fakeArtifactRepo{}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	artifactModule{
+		Builds:      builds,
+		Artifacts:   artifacts,
+		UploadLimit: newUploadSizeLimitMiddleware(64).handle,
+		Idempotent:  func(*gin.Context) {},
+	}.Register(r.Group("/api/build/:buildId"))
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("files", "report.txt")
+	require.NoError(t, err)
+	fw.Write(bytes.Repeat([]byte("a"), 100))
+	require.NoError(t, mw.Close())
+	req := httptest.NewRequest(http.MethodPost, "/api/build/1/artifact", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.ContentLength = -1
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "/prob/api/upload-too-large")
+	assert.Empty(t, artifacts.artifacts)
+}
+
+func TestDetectArtifactContentType(t *testing.T) {
+	assert.Equal(t, "image/png", detectArtifactContentType("logo.png", nil))
+	assert.Equal(t, "application/pdf", detectArtifactContentType("report", []byte("%PDF-1.4")))
+	assert.Equal(t, "application/octet-stream", detectArtifactContentType("cache.unknown-ext", []byte{0, 1, 2}))
+}
+
+func TestMigrateAddArtifactSizeAndContentType(t *testing.T) {
+	db := newTestSqliteDB(t)
+	dbBuild := database.Build{}
+	require.NoError(t, db.Create(&dbBuild).Error)
+	dbArtifacts := []database.Artifact{
+		{BuildID: dbBuild.BuildID, Name: "logo", FileName: "logo.png", Data: []byte("not really a png")},
+		{BuildID: dbBuild.BuildID, Name: "empty", FileName: "empty"},
+	}
+	require.NoError(t, db.Create(&dbArtifacts).Error)
+	require.NoError(t, db.Migrator().DropColumn(&database.Artifact{}, database.ArtifactFields.Size))
+	require.NoError(t, db.Migrator().DropColumn(&database.Artifact{}, database.ArtifactFields.ContentType))
+
+	require.NoError(t, migrateAddArtifactSizeAndContentType(db))
+
+	var gotArtifacts []database.Artifact
+	require.NoError(t, db.Order(database.ArtifactColumns.ArtifactID).Find(&gotArtifacts).Error)
+	require.Len(t, gotArtifacts, 2)
+	assert.Equal(t, int64(16), gotArtifacts[0].Size)
+	assert.Equal(t, "image/png", gotArtifacts[0].ContentType)
+	assert.Equal(t, int64(0), gotArtifacts[1].Size)
+	assert.Equal(t, "text/plain; charset=utf-8", gotArtifacts[1].ContentType)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+// badgeCacheControl allows clients and proxies, such as the image proxies used
+// when rendering READMEs, to cache badges for a short while.
+const badgeCacheControl = "public, max-age=60"
+
+const badgeLabel = "build"
+
+type badgeModule struct {
+	Projects ProjectRepo
+	Builds   BuildRepo
+}
+
+func (m badgeModule) Register(g *gin.RouterGroup) {
+	projectByID := g.Group("/project/:projectId")
+	{
+		projectByID.GET("/badge.svg", m.getProjectBadgeSVGHandler)
+		projectByID.GET("/badge.json", m.getProjectBadgeJSONHandler)
+	}
+}
+
+// badge holds the text and color of a build status badge.
+type badge struct {
+	message string
+	color   string
+}
+
+var (
+	badgeUnknown       = badge{"unknown", "#9f9f9f"}
+	badgeScheduling    = badge{"scheduling", "#9f9f9f"}
+	badgeRunning       = badge{"running", "#007ec6"}
+	badgePassing       = badge{"passing", "#4c1"}
+	badgeFailing       = badge{"failing", "#e05d44"}
+	badgeTriggerFailed = badge{"trigger failed", "#e05d44"}
+	badgeAwaiting      = badge{"awaiting approval", "#dfb317"}
+	badgePending       = badge{"pending", "#9f9f9f"}
+)
+
+func getBadgeForBuildStatus(status database.BuildStatus) badge {
+	switch status {
+	case database.BuildScheduling:
+		return badgeScheduling
+	case database.BuildRunning:
+		return badgeRunning
+	case database.BuildCompleted:
+		return badgePassing
+	case database.BuildFailed:
+		return badgeFailing
+	case database.BuildTriggerFailed:
+		return badgeTriggerFailed
+	case database.BuildAwaitingApproval:
+		return badgeAwaiting
+	case database.BuildPending:
+		return badgePending
+	default:
+		return badgeUnknown
+	}
+}
+
+// getProjectBadgeSVGHandler godoc
+// @id getProjectBadgeSVG
+// @summary Get build status badge of project as SVG image
+// @description Shows the status of the latest build of the project, optionally
+// @description filtered by branch and stage, meant to be embedded in READMEs.
+// @description Does not require authentication if `http.publicBadges` is enabled
+// @description in the wharf-api configuration.
+// @description Added in v5.3.0.
+// @tags project
+// @produce image/svg+xml
+// @param projectId path uint true "project ID" minimum(0)
+// @param branch query string false "Filter by verbatim build Git branch."
+// @param stage query string false "Filter by verbatim build stage."
+// @success 200 {string} string "SVG image"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/badge.svg [get]
+func (m badgeModule) getProjectBadgeSVGHandler(c *gin.Context) {
+	b, ok := m.getProjectBadge(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", badgeCacheControl)
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(renderBadgeSVG(badgeLabel, b)))
+}
+
+// getProjectBadgeJSONHandler godoc
+// @id getProjectBadgeJSON
+// @summary Get build status badge of project as JSON
+// @description Shows the status of the latest build of the project, optionally
+// @description filtered by branch and stage, in the format of the shields.io
+// @description "endpoint" badges: https://shields.io/endpoint
+// @description Does not require authentication if `http.publicBadges` is enabled
+// @description in the wharf-api configuration.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param branch query string false "Filter by verbatim build Git branch."
+// @param stage query string false "Filter by verbatim build stage."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Badge
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/badge.json [get]
+func (m badgeModule) getProjectBadgeJSONHandler(c *gin.Context) {
+	b, ok := m.getProjectBadge(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", badgeCacheControl)
+	ginrender.Response(c, http.StatusOK, response.Badge{
+		SchemaVersion: 1,
+		Label:         badgeLabel,
+		Message:       b.message,
+		Color:         b.color,
+	})
+}
+
+func (m badgeModule) getProjectBadge(c *gin.Context) (badge, bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return badge{}, false
+	}
+	var params = struct {
+		Branch *string `form:"branch"`
+		Stage  *string `form:"stage"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return badge{}, false
+	}
+	if !validateRepoObjExistsByID(c, m.Projects.Exists, projectID, "project", "when getting build badge") {
+		return badge{}, false
+	}
+
+	dbBuild, err := m.Builds.FindLatest(projectID, BuildLatestFilter{
+		GitBranch: params.Branch,
+		Stage:     params.Stage,
+	})
+	if errors.Is(err, errRepoNotFound) {
+		return badgeUnknown, true
+	}
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching latest build of project with ID %d.",
+			projectID))
+		return badge{}, false
+	}
+	return getBadgeForBuildStatus(dbBuild.StatusID), true
+}
+
+// renderBadgeSVG returns an SVG image in the style of the "flat" shields.io
+// badges. The text widths are estimated, as the exact widths depend on the
+// fonts available to the viewer.
+func renderBadgeSVG(label string, b badge) string {
+	labelWidth := estimateBadgeTextWidth(label)
+	messageWidth := estimateBadgeTextWidth(b.message)
+	width := labelWidth + messageWidth
+	label = html.EscapeString(label)
+	message := html.EscapeString(b.message)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>`+
+		`</g></svg>`,
+		width, labelWidth, messageWidth, label, message, b.color,
+		labelWidth/2, labelWidth+messageWidth/2)
+}
+
+// estimateBadgeTextWidth returns the estimated width in pixels of the text in
+// 11px Verdana, including padding.
+func estimateBadgeTextWidth(text string) int {
+	const charWidth, padding = 7, 10
+	return len([]rune(text))*charWidth + padding
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBadgeForBuildStatus(t *testing.T) {
+	testCases := []struct {
+		status database.BuildStatus
+		want   badge
+	}{
+		{status: database.BuildScheduling, want: badgeScheduling},
+		{status: database.BuildRunning, want: badgeRunning},
+		{status: database.BuildCompleted, want: badgePassing},
+		{status: database.BuildFailed, want: badgeFailing},
+		{status: database.BuildTriggerFailed, want: badgeTriggerFailed},
+		{status: database.BuildStatus(-1), want: badgeUnknown},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.want.message, func(t *testing.T) {
+			assert.Equal(t, tc.want, getBadgeForBuildStatus(tc.status))
+		})
+	}
+}
+
+func TestRenderBadgeSVG(t *testing.T) {
+	svg := renderBadgeSVG("a<b", badge{"c&d", "#4c1"})
+	assert.Contains(t, svg, `<title>a&lt;b: c&amp;d</title>`)
+	assert.Contains(t, svg, `fill="#4c1"`)
+	assert.NotContains(t, svg, "a<b")
+	assert.NotContains(t, svg, "%!")
+}
+
+func TestGetProjectBadgeJSONHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	badgeModule{
+		Projects: &fakeProjectRepo{projects: []database.Project{{ProjectID: 1}, {ProjectID: 2}}},
+		Builds: &fakeBuildRepo{builds: []database.Build{
+			{BuildID: 1, ProjectID: 1, GitBranch: "master", StatusID: database.BuildCompleted},
+			{BuildID: 2, ProjectID: 1, GitBranch: "feature", StatusID: database.BuildFailed},
+		}},
+	}.Register(r.Group("/api"))
+
+	testCases := []struct {
+		name        string
+		path        string
+		wantCode    int
+		wantMessage string
+		wantProblem string
+	}{
+		{name: "latest build", path: "/api/project/1/badge.json", wantCode: http.StatusOK, wantMessage: badgeFailing.message},
+		{name: "by branch", path: "/api/project/1/badge.json?branch=master", wantCode: http.StatusOK, wantMessage: badgePassing.message},
+		{name: "no builds", path: "/api/project/2/badge.json", wantCode: http.StatusOK, wantMessage: badgeUnknown.message},
+		{name: "unknown project", path: "/api/project/3/badge.json", wantCode: http.StatusBadGateway, wantProblem: "/prob/api/record-not-found"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.wantCode, w.Code, w.Body.String())
+			if tc.wantProblem != "" {
+				assert.Contains(t, w.Body.String(), tc.wantProblem)
+				return
+			}
+			var res response.Badge
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantMessage, res.Message)
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
@@ -16,6 +21,7 @@ import (
 
 type branchModule struct {
 	Database *gorm.DB
+	Cache    *dbCache
 }
 
 func (m branchModule) Register(g *gin.RouterGroup) {
@@ -24,9 +30,140 @@ func (m branchModule) Register(g *gin.RouterGroup) {
 		projectBranch.GET("", m.getProjectBranchListHandler)
 		projectBranch.PUT("", m.updateProjectBranchListHandler)
 		projectBranch.POST("", m.createProjectBranchHandler)
+		// Catch-all parameter, as branch names may contain slashes.
+		projectBranch.GET("/*branchName", m.getProjectBranchHandler)
+		projectBranch.DELETE("/*branchName", m.deleteProjectBranchHandler)
 	}
 }
 
+// getProjectBranchHandler godoc
+// @id getProjectBranch
+// @summary Get branch of project by name.
+// @description Gets a branch by its name, together with the status of the
+// @description latest build on that branch.
+// @description Replaces the never implemented `GET /branch/{branchId}`.
+// @description Added in v5.3.0.
+// @tags branch
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param branchName path string true "branch name, may contain slashes"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BranchDetails "Branch"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project or branch not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/branch/{branchName} [get]
+func (m branchModule) getProjectBranchHandler(c *gin.Context) {
+	projectID, branchName, ok := parseProjectBranchParams(c)
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching branch") {
+		return
+	}
+	dbBranch, ok := fetchProjectBranchByName(c, m.Database, projectID, branchName, "when fetching branch")
+	if !ok {
+		return
+	}
+	var dbLatestBuild database.Build
+	err := m.Database.
+		Where(&database.Build{ProjectID: projectID, GitBranch: branchName},
+			database.BuildFields.ProjectID,
+			database.BuildFields.GitBranch).
+		Order(database.BuildColumns.BuildID + " DESC").
+		First(&dbLatestBuild).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginrender.Response(c, http.StatusOK, modelconv.DBBranchToDetailsResponse(dbBranch, nil))
+		return
+	}
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching latest build of branch %q for project with ID %d.",
+			branchName, projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBranchToDetailsResponse(dbBranch, &dbLatestBuild))
+}
+
+// deleteProjectBranchHandler godoc
+// @id deleteProjectBranch
+// @summary Delete branch of project by name.
+// @description Deleting the default branch leaves the project without a
+// @description default branch. Builds of the branch are not deleted.
+// @description Added in v5.3.0.
+// @tags branch
+// @param projectId path uint true "project ID" minimum(0)
+// @param branchName path string true "branch name, may contain slashes"
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project or branch to delete is not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/branch/{branchName} [delete]
+func (m branchModule) deleteProjectBranchHandler(c *gin.Context) {
+	projectID, branchName, ok := parseProjectBranchParams(c)
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when deleting branch") {
+		return
+	}
+	dbBranch, ok := fetchProjectBranchByName(c, m.Database, projectID, branchName, "when deleting branch")
+	if !ok {
+		return
+	}
+	if err := m.Database.Delete(&dbBranch).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting branch %q of project with ID %d from database.",
+			branchName, projectID))
+		return
+	}
+	requestLog(c).Info().
+		WithString("branch", branchName).
+		WithUint("project", projectID).
+		Message("Deleted branch from project.")
+	c.Status(http.StatusNoContent)
+}
+
+func parseProjectBranchParams(c *gin.Context) (uint, string, bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return 0, "", false
+	}
+	branchName := strings.TrimPrefix(c.Param("branchName"), "/")
+	if branchName == "" {
+		ginutil.WriteInvalidParamError(c, errors.New("empty branch name"), "branchName",
+			"Branch name must not be empty.")
+		return 0, "", false
+	}
+	return projectID, branchName, true
+}
+
+func fetchProjectBranchByName(c *gin.Context, db *gorm.DB, projectID uint, branchName, whenMsg string) (database.Branch, bool) {
+	var dbBranch database.Branch
+	err := db.
+		Where(&database.Branch{ProjectID: projectID, Name: branchName},
+			database.BranchFields.ProjectID,
+			database.BranchFields.Name).
+		First(&dbBranch).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Branch %q was not found in project with ID %d%s.",
+			branchName, projectID, spaceWhenMessage(whenMsg)))
+		return database.Branch{}, false
+	}
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching branch %q of project with ID %d from database%s.",
+			branchName, projectID, spaceWhenMessage(whenMsg)))
+		return database.Branch{}, false
+	}
+	return dbBranch, true
+}
+
 // getProjectBranchListHandler godoc
 // @id getProjectBranchList
 // @summary Get list of branches.
@@ -34,6 +171,7 @@ func (m branchModule) Register(g *gin.RouterGroup) {
 // @tags branch
 // @produce json
 // @param projectId path uint true "project ID" minimum(0)
+// @param staleSince query string false "Only list branches that have not been built within this duration, such as `30d` or `12h`, including branches that have never been built. Added in v5.3.0."
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedBranches "Branches"
 // @failure 400 {object} problem.Response "Bad request"
@@ -46,13 +184,37 @@ func (m branchModule) getProjectBranchListHandler(c *gin.Context) {
 	if !ok {
 		return
 	}
+	var params = struct {
+		StaleSince *string `form:"staleSince"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	if params.StaleSince == nil {
+		if resBranches, ok := m.Cache.getBranchList(projectID); ok {
+			ginrender.Response(c, http.StatusOK, resBranches)
+			return
+		}
+	}
+	query := m.Database.
+		Where(&database.Branch{ProjectID: projectID})
+	if params.StaleSince != nil {
+		staleSince, err := parseDaysDuration(*params.StaleSince)
+		if err != nil {
+			ginutil.WriteInvalidParamError(c, err, "staleSince", fmt.Sprintf(
+				"Invalid duration %q, expected for example 30d or 12h.",
+				*params.StaleSince))
+			return
+		}
+		query = query.Where(fmt.Sprintf("(%[1]s IS NULL OR %[1]s < ?)", database.BranchColumns.LastBuiltOn),
+			time.Now().UTC().Add(-staleSince))
+	}
 	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching list of branches for project") {
 		return
 	}
 	var dbBranches []database.Branch
-	err := m.Database.
-		Where(&database.Branch{ProjectID: projectID}).
-		Find(&dbBranches).Error
+	err := query.Find(&dbBranches).Error
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
 			"Failed fetching list of branches for project with ID %d.",
@@ -60,7 +222,11 @@ func (m branchModule) getProjectBranchListHandler(c *gin.Context) {
 		return
 	}
 	dbDefaultBranch := findDefaultDBBranch(dbBranches)
-	renderJSON(c, http.StatusOK, modelconv.DBBranchListToPaginatedResponse(dbBranches, int64(len(dbBranches)), dbDefaultBranch))
+	resBranches := modelconv.DBBranchListToPaginatedResponse(dbBranches, int64(len(dbBranches)), dbDefaultBranch)
+	if params.StaleSince == nil {
+		m.Cache.setBranchList(projectID, resBranches)
+	}
+	ginrender.Response(c, http.StatusOK, resBranches)
 }
 
 // createProjectBranchHandler godoc
@@ -88,7 +254,7 @@ func (m branchModule) createProjectBranchHandler(c *gin.Context) {
 	}
 	var reqBranch request.Branch
 	if err := c.ShouldBindJSON(&reqBranch); err != nil {
-		ginutil.WriteInvalidBindError(c, err,
+		writeInvalidBindError(c, err,
 			"One or more parameters failed to parse when reading the request body for branch object to create.")
 		return
 	}
@@ -116,6 +282,9 @@ func (m branchModule) createProjectBranchHandler(c *gin.Context) {
 				return err
 			}
 		}
+		if err := updateBranchesLastBuild(tx, &projectID); err != nil {
+			return err
+		}
 		return tx.First(&dbBranch, dbBranch.BranchID).Error
 	})
 	if err != nil {
@@ -124,7 +293,7 @@ func (m branchModule) createProjectBranchHandler(c *gin.Context) {
 			projectID))
 		return
 	}
-	renderJSON(c, http.StatusCreated, modelconv.DBBranchToResponse(dbBranch))
+	ginrender.Response(c, http.StatusCreated, modelconv.DBBranchToResponse(dbBranch))
 }
 
 // updateProjectBranchListHandler godoc
@@ -154,7 +323,7 @@ func (m branchModule) updateProjectBranchListHandler(c *gin.Context) {
 	}
 	var reqBranchListUpdate request.BranchListUpdate
 	if err := c.ShouldBindJSON(&reqBranchListUpdate); err != nil {
-		ginutil.WriteInvalidBindError(c, err,
+		writeInvalidBindError(c, err,
 			"One or more parameters failed to parse when reading the request body for branch object array to update.")
 		return
 	}
@@ -168,7 +337,7 @@ func (m branchModule) updateProjectBranchListHandler(c *gin.Context) {
 		return
 	}
 	resBranchList := modelconv.DBBranchListToResponse(dbBranchList.branches, dbBranchList.defaultBranch)
-	renderJSON(c, http.StatusOK, resBranchList)
+	ginrender.Response(c, http.StatusOK, resBranchList)
 }
 
 type databaseBranchList struct {
@@ -236,6 +405,26 @@ func ensureOnlyRequestedBranchesExist(db *gorm.DB, projectID uint, tokenID uint,
 	})
 }
 
+// parseDaysDuration parses a duration such as "30d", in days, or any duration
+// supported by time.ParseDuration, such as "12h".
+func parseDaysDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.ParseUint(strings.TrimSuffix(s, "d"), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("parse days: %w", err)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("negative duration")
+	}
+	return d, nil
+}
+
 func findDefaultDBBranch(dbBranches []database.Branch) *database.Branch {
 	for _, dbNewBranch := range dbBranches {
 		if dbNewBranch.Default {
@@ -254,7 +443,54 @@ func createBranchesWithNames(db *gorm.DB, projectID, tokenID uint, branchNames [
 			Name:      branchName,
 		})
 	}
-	return db.Create(dbBranches).Error
+	if err := db.Create(dbBranches).Error; err != nil {
+		return err
+	}
+	return updateBranchesLastBuild(db, &projectID)
+}
+
+// updateBranchesLastBuild sets the last build ID and date of the project's
+// branches, or of all branches if projectID is nil, from the latest builds on
+// the branches' names. Used for branches that may already have builds, such as
+// when recreating a previously deleted branch.
+func updateBranchesLastBuild(db *gorm.DB, projectID *uint) error {
+	branchesQuery := func() *gorm.DB {
+		query := db.Model(&database.Branch{})
+		if projectID == nil {
+			return query.Where("1 = 1")
+		}
+		return query.Where(&database.Branch{ProjectID: *projectID}, database.BranchFields.ProjectID)
+	}
+	if err := branchesQuery().
+		UpdateColumn(string(database.BranchColumns.LastBuildID), gorm.Expr(fmt.Sprintf(
+			"(SELECT MAX(%[1]s.%[2]s) FROM %[1]s WHERE %[1]s.%[3]s = %[4]s.%[5]s AND %[1]s.%[6]s = %[4]s.%[7]s)",
+			database.BuildTable, database.BuildColumns.BuildID,
+			database.BuildColumns.ProjectID, database.BranchTable, database.BranchColumns.ProjectID,
+			database.BuildColumns.GitBranch, database.BranchColumns.Name))).
+		Error; err != nil {
+		return err
+	}
+	// Old builds may lack the scheduled date.
+	return branchesQuery().
+		UpdateColumn(string(database.BranchColumns.LastBuiltOn), gorm.Expr(fmt.Sprintf(
+			"(SELECT COALESCE(%[1]s.%[2]s, %[1]s.created_at) FROM %[1]s WHERE %[1]s.%[3]s = %[4]s.%[5]s)",
+			database.BuildTable, database.BuildColumns.ScheduledOn, database.BuildColumns.BuildID,
+			database.BranchTable, database.BranchColumns.LastBuildID))).
+		Error
+}
+
+// setBranchLastBuild sets the last build ID and date of the branch that the
+// build was started on, if the branch exists.
+func setBranchLastBuild(db *gorm.DB, dbBuild database.Build) error {
+	return db.
+		Model(&database.Branch{}).
+		Where(&database.Branch{ProjectID: dbBuild.ProjectID, Name: dbBuild.GitBranch},
+			database.BranchFields.ProjectID,
+			database.BranchFields.Name).
+		UpdateColumns(map[string]any{
+			string(database.BranchColumns.LastBuildID): dbBuild.BuildID,
+			string(database.BranchColumns.LastBuiltOn): dbBuild.ScheduledOn,
+		}).Error
 }
 
 func deleteBranchesByNames(db *gorm.DB, projectID uint, branchNames []string) error {
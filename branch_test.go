@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/deprecated"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDaysDuration(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "0d", want: 0},
+		{input: "12h", want: 12 * time.Hour},
+		{input: "1h30m", want: 90 * time.Minute},
+		{input: "-1d", wantErr: true},
+		{input: "-1h", wantErr: true},
+		{input: "1.5d", wantErr: true},
+		{input: "d", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseDaysDuration(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDeprecatedUpdateProjectBranchListHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestSqliteDB(t)
+	dbProjects := []database.Project{{Name: "wharf-api"}, {Name: "wharf-web"}}
+	require.NoError(t, db.Create(&dbProjects).Error)
+	dbToken := database.Token{Value: "secret"}
+	require.NoError(t, db.Create(&dbToken).Error)
+	require.NoError(t, db.Create(&[]database.Branch{
+		{ProjectID: dbProjects[0].ProjectID, TokenID: dbToken.TokenID, Name: "old"},
+		{ProjectID: dbProjects[1].ProjectID, TokenID: dbToken.TokenID, Name: "master", Default: true},
+	}).Error)
+	r := gin.New()
+	deprecated.BranchModule{Database: db}.Register(r.Group("/api"))
+
+	// The test database only allows a single connection, so this would
+	// deadlock if any query was made outside of the transaction.
+	body := `[{"projectId":1,"tokenId":1,"name":"master","default":true},{"projectId":1,"tokenId":1,"name":"feature"}]`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/branches", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resBranches []response.Branch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resBranches))
+	var gotNames []string
+	for _, resBranch := range resBranches {
+		assert.Equal(t, dbProjects[0].ProjectID, resBranch.ProjectID, "must only respond with branches of updated project")
+		gotNames = append(gotNames, resBranch.Name)
+	}
+	assert.ElementsMatch(t, []string{"master", "feature"}, gotNames)
+}
@@ -1,40 +1,59 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"net/http"
-	"net/url"
 
-	"github.com/dustin/go-broadcast"
-	"github.com/ghodss/yaml"
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/builddef"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/secretbox"
 	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
 	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
 	"github.com/iver-wharf/wharf-core/pkg/problem"
 	"gopkg.in/guregu/null.v4"
 	"gopkg.in/typ.v4"
+	"gopkg.in/typ.v4/maps"
+	"gopkg.in/typ.v4/slices"
 	"gorm.io/gorm"
 )
 
 type buildModule struct {
-	Database *gorm.DB
-	Config   *Config
+	Database     *gorm.DB
+	Config       *Config
+	EngineClient *engineClient
+	Dispatcher   *buildTriggerDispatcher
+	LogIngester  *logIngester
+	// Email sends emails to the users subscribed to failed builds, and is nil
+	// if no SMTP server is configured.
+	Email *emailNotifier
+	// Clock tells the time used for the build's dates, and defaults to the
+	// system's time if nil.
+	Clock Clock
 }
 
 func (m buildModule) Register(g *gin.RouterGroup) {
+	idempotent := newIdempotencyKeyMiddleware(m.Database, m.Config.HTTP.IdempotencyKeyTTL).handle
+	uploadLimit := newUploadSizeLimitMiddleware(m.Config.Artifact.MaxUploadSize).handle
+
 	build := g.Group("/build")
 	{
 		build.GET("", m.getBuildListHandler)
@@ -42,48 +61,50 @@ func (m buildModule) Register(g *gin.RouterGroup) {
 		buildByID := build.Group("/:buildId")
 		{
 			buildByID.GET("", m.getBuildHandler)
+			buildByID.DELETE("", m.cancelBuildHandler)
 			buildByID.PUT("/status", m.updateBuildStatusHandler)
-			buildByID.POST("/log", m.createBuildLogHandler)
+			buildByID.PUT("/stage/:stageName/status", m.updateBuildStageStatusHandler)
+			buildByID.POST("/retrigger", m.retriggerBuildHandler)
+			buildByID.POST("/approve", m.approveBuildHandler)
+			buildByID.POST("/reject", m.rejectBuildHandler)
+			buildByID.POST("/log", uploadLimit, idempotent, m.createBuildLogHandler)
 			buildByID.GET("/log", m.getBuildLogListHandler)
+			buildByID.GET("/log/download", m.downloadBuildLogHandler)
 			buildByID.GET("/stream", m.streamBuildLogHandler)
+			buildByID.GET("/param", m.getBuildParamListHandler)
+			buildByID.GET("/timeline", m.getBuildTimelineHandler)
+			buildByID.GET("/status-history", m.getBuildStatusHistoryHandler)
 
-			artifacts := artifactModule{m.Database}
+			repos := newGormRepositories(m.Database)
+			artifacts := artifactModule{Builds: repos.Builds, Artifacts: repos.Artifacts, UploadLimit: uploadLimit, Idempotent: idempotent}
 			artifacts.Register(buildByID)
+			artifactPromotionModule{Database: m.Database, Idempotent: idempotent}.Register(buildByID)
 
-			buildTestResults := buildTestResultModule{m.Database}
+			buildTestResults := buildTestResultModule{Database: m.Database, Config: m.Config, UploadLimit: uploadLimit, Idempotent: idempotent}
 			buildTestResults.Register(buildByID)
+
+			buildComments := buildCommentModule{Database: m.Database, Idempotent: idempotent}
+			buildComments.Register(buildByID)
+
+			buildLinks := buildLinkModule{Database: m.Database, Idempotent: idempotent}
+			buildLinks.Register(buildByID)
 		}
 	}
+	g.GET("/build-group/:buildGroupId", m.getBuildGroupHandler)
+	g.GET("/approval", m.getApprovalListHandler)
+	g.POST("/admin/test-result/reparse", buildTestResultModule{Database: m.Database, Config: m.Config}.reparseAllTestResultsHandler)
 	projectByID := g.Group("/project/:projectId")
 	{
-		projectByID.POST("/build", m.startProjectBuildHandler)
+		projectByID.POST("/build", idempotent, m.startProjectBuildHandler)
+		projectByID.GET("/build/latest", m.getLatestProjectBuildHandler)
+		projectByID.GET("/build/latest-per-branch", m.getLatestProjectBuildPerBranchHandler)
+		projectByID.GET("/build/number/:buildNumber", m.getProjectBuildByNumberHandler)
+		projectByID.POST("/build/matrix", idempotent, m.startProjectBuildMatrixHandler)
 		// Deprecated:
 		projectByID.POST("/:stage/run", m.oldStartProjectBuildHandler)
 	}
 }
 
-var buildChannels = make(map[uint]broadcast.Broadcaster)
-
-func openListener(buildID uint) chan any {
-	listener := make(chan any)
-	build(buildID).Register(listener)
-	return listener
-}
-
-func closeListener(buildID uint, listener chan any) {
-	build(buildID).Unregister(listener)
-	close(listener)
-}
-
-func build(buildID uint) broadcast.Broadcaster {
-	b, ok := buildChannels[buildID]
-	if !ok {
-		b = broadcast.NewBroadcaster(10)
-		buildChannels[buildID] = b
-	}
-	return b
-}
-
 // getBuildHandler godoc
 // @id getBuild
 // @summary Finds build by build ID
@@ -117,8 +138,17 @@ func (m buildModule) getBuildHandler(c *gin.Context) {
 		return
 	}
 
+	expiries, err := findBuildExpiries(m.Database, m.Config.CI, []database.Build{dbBuild})
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching timeouts of build with ID %d from database.",
+			buildID))
+		return
+	}
+
 	resBuild := modelconv.DBBuildToResponse(dbBuild, m.engineLookup)
-	renderJSON(c, http.StatusOK, resBuild)
+	setBuildExpiresOn(&resBuild, expiries)
+	ginrender.Response(c, http.StatusOK, resBuild)
 }
 
 var buildJSONToColumns = map[string]database.SafeSQLName{
@@ -130,6 +160,53 @@ var buildJSONToColumns = map[string]database.SafeSQLName{
 	response.BuildJSONFields.Stage:       database.BuildColumns.Stage,
 	response.BuildJSONFields.StatusID:    database.BuildColumns.StatusID,
 	response.BuildJSONFields.IsInvalid:   database.BuildColumns.IsInvalid,
+	response.BuildJSONFields.Duration:    database.BuildColumns.Duration,
+}
+
+// buildJSONToExpressions holds the fields of the build's project, and the
+// aggregates of the build's test results, that builds can also be sorted by.
+// They are sorted by subqueries instead of joins, so that the columns used in
+// the rest of the query stay unambiguous.
+var buildJSONToExpressions = map[string]database.SafeSQLName{
+	response.BuildJSONFields.ProjectName:      buildProjectColumnSubquery(database.ProjectColumns.Name),
+	response.BuildJSONFields.ProjectGroupName: buildProjectColumnSubquery(database.ProjectColumns.GroupName),
+	response.BuildJSONFields.TestsFailed:      buildTestResultSumSubquery(database.TestResultSummaryColumns.Failed),
+	response.BuildJSONFields.TestsTotal:       buildTestResultSumSubquery(database.TestResultSummaryColumns.Total),
+}
+
+func buildProjectColumnSubquery(column database.SafeSQLName) database.SafeSQLName {
+	return fmt.Sprintf("(SELECT %[1]s.%[2]s FROM %[1]s WHERE %[1]s.%[3]s = %[4]s.%[5]s)",
+		database.ProjectTable, column, database.ProjectColumns.ProjectID,
+		database.BuildTable, database.BuildColumns.ProjectID)
+}
+
+func buildTestResultSumSubquery(column database.SafeSQLName) database.SafeSQLName {
+	return fmt.Sprintf("(SELECT COALESCE(SUM(%[1]s.%[2]s), 0) FROM %[1]s WHERE %[1]s.%[3]s = %[4]s.%[5]s)",
+		database.TestResultSummaryTable, column, database.TestResultSummaryColumns.BuildID,
+		database.BuildTable, database.BuildColumns.BuildID)
+}
+
+// whereBuildParamScope filters on builds that were started with an input
+// parameter of the given name, and of the given value if not nil. Uses a
+// subquery instead of a join, so that builds with multiple matching parameters
+// are not duplicated in the result. Secret parameters are never matched, as
+// their values are stored encrypted.
+func whereBuildParamScope(name, value *string) func(*gorm.DB) *gorm.DB {
+	if name == nil {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		subquery := fmt.Sprintf("SELECT %[1]s.%[2]s FROM %[1]s WHERE %[1]s.%[3]s = ? AND %[1]s.%[4]s = ?",
+			database.BuildParamTable, database.BuildParamColumns.BuildID,
+			database.BuildParamColumns.Name, database.BuildParamColumns.IsSecret)
+		args := []any{*name, false}
+		if value != nil {
+			subquery += fmt.Sprintf(" AND %s.%s = ?", database.BuildParamTable, database.BuildParamColumns.Value)
+			args = append(args, *value)
+		}
+		return db.Where(fmt.Sprintf("%s.%s IN (%s)",
+			database.BuildTable, database.BuildColumns.BuildID, subquery), args...)
+	}
 }
 
 var defaultGetBuildsOrderBy = orderby.Column{Name: database.BuildColumns.BuildID, Direction: orderby.Desc}
@@ -141,27 +218,44 @@ var defaultGetBuildsOrderBy = orderby.Column{Name: database.BuildColumns.BuildID
 // @description Verbatim filters will match on the entire string used to find exact matches,
 // @description while the matching filters are meant for searches by humans where it tries to find soft matches and is therefore inaccurate by nature.
 // @description Added in v5.0.0.
+// @description Responds with CSV instead, using the same filters and ordering, if requested via the `Accept: text/csv` header or the `format=csv` query parameter. CSV added in v5.3.0.
 // @tags build
-// @produce json
-// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`). Required if `offset` is used." default(100)
+// @produce json,text/csv
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
 // @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
-// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc'. Can be specified multiple times for more granular sorting. Defaults to `?orderby=buildId desc`"
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Casing, prefixes, and commas added in v5.3.0. Besides the build's own fields, also supports sorting by `projectName`, `projectGroupName`, `testsFailed`, and `testsTotal`. Defaults to `?orderby=buildId desc`"
 // @param projectId query uint false "Filter by project ID."
 // @param scheduledAfter query string false "Filter by builds with scheduled date later than value." format(date-time)
 // @param scheduledBefore query string false "Filter by builds with scheduled date earlier than value." format(date-time)
 // @param finishedAfter query string false "Filter by builds with finished date later than value." format(date-time)
 // @param finishedBefore query string false "Filter by builds with finished date earlier than value." format(date-time)
+// @param minDuration query string false "Filter by builds that took at least this long to run. Uses Go duration syntax, such as `90s` or `1h30m`."
+// @param maxDuration query string false "Filter by builds that took at most this long to run. Uses Go duration syntax, such as `90s` or `1h30m`."
 // @param environment query string false "Filter by verbatim build environment."
 // @param gitBranch query string false "Filter by verbatim build Git branch."
+// @param gitCommit query string false "Filter by verbatim build Git commit SHA."
+// @param gitTag query string false "Filter by verbatim build Git tag."
 // @param stage query string false "Filter by verbatim build stage."
 // @param workerId query string false "Filter by verbatim worker ID."
+// @param engineId query string false "Filter by verbatim ID of the execution engine the build was started on. Added in v5.3.0."
+// @param triggeredBy query string false "Filter by verbatim name or subject of who or what started the build, such as an e-mail or OIDC subject."
+// @param triggeredByType query string false "Filter by what kind of source started the build." enums(oidc,basic-auth,webhook,schedule,build)
 // @param isInvalid query bool false "Filter by build's valid/invalid state."
-// @param status query []string false "Filter by build status name" enums(Scheduling,Running,Completed,Failed)
-// @param statusId query []int false "Filter by build status ID. Cannot be used with `status`." enums(0,1,2,3)
+// @param triggerFailed query bool false "Filter by builds that failed, or did not fail, to be triggered. Same as filtering on the `TriggerFailed` status."
+// @param status query []string false "Filter by build status name" enums(Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending)
+// @param statusId query []int false "Filter by build status ID. Cannot be used with `status`." enums(0,1,2,3,4,5,6)
 // @param environmentMatch query string false "Filter by matching build environment. Cannot be used with `environment`."
 // @param gitBranchMatch query string false "Filter by matching build Git branch. Cannot be used with `gitBranch`."
+// @param gitCommitMatch query string false "Filter by matching build Git commit SHA. Cannot be used with `gitCommit`."
+// @param gitTagMatch query string false "Filter by matching build Git tag. Cannot be used with `gitTag`."
 // @param stageMatch query string false "Filter by matching build stage. Cannot be used with `stage`."
 // @param match query string false "Filter by matching on any supported fields."
+// @param param.name query string false "Filter by builds started with an input parameter of this verbatim name. Secret parameters are never matched. Added in v5.3.0."
+// @param param.value query string false "Filter by builds started with an input parameter of this verbatim value. Requires `param.name`. Added in v5.3.0."
+// @param label query []string false "Filter by label, either as `key=value` to match the label's value, or as `key` to match any value. Can be specified multiple times to match on all the labels. Added in v5.3.0."
+// @param stale query bool false "Filter by builds that will, or will not, be marked as failed if they stay in their current Scheduling or Running status for too long. Their `expiresOn` field tells when. Added in v5.3.0."
+// @param format query string false "Response format, where `csv` responds with CSV instead of JSON. Added in v5.3.0." Enums(json,csv)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedBuilds
 // @failure 400 {object} problem.Response "Bad request"
@@ -177,29 +271,52 @@ func (m buildModule) getBuildListHandler(c *gin.Context) {
 		FinishedAfter   *time.Time `form:"finishedAfter"`
 		FinishedBefore  *time.Time `form:"finishedBefore"`
 
+		MinDuration *time.Duration `form:"minDuration"`
+		MaxDuration *time.Duration `form:"maxDuration"`
+
 		ProjectID   *uint   `form:"projectId"`
 		Environment *string `form:"environment"`
 		GitBranch   *string `form:"gitBranch"`
+		GitCommit   *string `form:"gitCommit"`
+		GitTag      *string `form:"gitTag"`
 		Stage       *string `form:"stage"`
 		WorkerID    *string `form:"workerId"`
+		EngineID    *string `form:"engineId"`
 
-		IsInvalid *bool `form:"isInvalid"`
+		TriggeredBy     *string `form:"triggeredBy"`
+		TriggeredByType *string `form:"triggeredByType"`
+
+		IsInvalid     *bool `form:"isInvalid"`
+		TriggerFailed *bool `form:"triggerFailed"`
 
 		Status   []string `form:"status"`
 		StatusID []int    `form:"statusId" binding:"excluded_with=Status"`
 
 		EnvironmentMatch *string `form:"environmentMatch" binding:"excluded_with=Environment"`
 		GitBranchMatch   *string `form:"gitBranchMatch" binding:"excluded_with=GitBranch"`
+		GitCommitMatch   *string `form:"gitCommitMatch" binding:"excluded_with=GitCommit"`
+		GitTagMatch      *string `form:"gitTagMatch" binding:"excluded_with=GitTag"`
 		StageMatch       *string `form:"stageMatch" binding:"excluded_with=Stage"`
 
 		Match *string `form:"match"`
+
+		ParamName  *string `form:"param.name" binding:"required_with=ParamValue"`
+		ParamValue *string `form:"param.value"`
+
+		Label []string `form:"label"`
+
+		Stale *bool `form:"stale"`
 	}{
 		commonGetQueryParams: defaultCommonGetQueryParams,
 	}
 	if !bindCommonGetQueryParams(c, &params) {
 		return
 	}
-	orderBySlice, ok := parseCommonOrderBySlice(c, params.OrderBy, buildJSONToColumns)
+	orderBySlice, ok := parseCommonOrderBySliceWithExpressions(c, params.OrderBy, buildJSONToColumns, buildJSONToExpressions)
+	if !ok {
+		return
+	}
+	labelFilters, ok := parseLabelFilters(c, params.Label, "label")
 	if !ok {
 		return
 	}
@@ -212,24 +329,44 @@ func (m buildModule) getBuildListHandler(c *gin.Context) {
 			ProjectID:   where.Uint(database.BuildFields.ProjectID, params.ProjectID),
 			Environment: where.NullStringEmptyNull(database.BuildFields.Environment, params.Environment),
 			GitBranch:   where.String(database.BuildFields.GitBranch, params.GitBranch),
+			GitCommit:   where.String(database.BuildFields.GitCommit, params.GitCommit),
+			GitTag:      where.String(database.BuildFields.GitTag, params.GitTag),
 			IsInvalid:   where.Bool(database.BuildFields.IsInvalid, params.IsInvalid),
 			Stage:       where.String(database.BuildFields.Stage, params.Stage),
 			WorkerID:    where.String(database.BuildFields.WorkerID, params.WorkerID),
+			EngineID:    where.String(database.BuildFields.EngineID, params.EngineID),
+
+			TriggeredByType: database.BuildTriggerType(
+				where.String(database.BuildFields.TriggeredByType, params.TriggeredByType)),
 		}, where.NonNilFieldNames()...).
 		Scopes(
+			whereAnyEqualScope(
+				params.TriggeredBy,
+				database.BuildColumns.TriggeredByName,
+				database.BuildColumns.TriggeredBySubject,
+			),
 			optionalTimeRangeScope(database.BuildColumns.ScheduledOn, params.ScheduledAfter, params.ScheduledBefore),
 			optionalTimeRangeScope(database.BuildColumns.CompletedOn, params.FinishedAfter, params.FinishedBefore),
+			optionalDurationRangeScope(database.BuildColumns.Duration, params.MinDuration, params.MaxDuration),
 			whereLikeScope(map[database.SafeSQLName]*string{
 				database.BuildColumns.Environment: params.EnvironmentMatch,
 				database.BuildColumns.GitBranch:   params.GitBranchMatch,
+				database.BuildColumns.GitCommit:   params.GitCommitMatch,
+				database.BuildColumns.GitTag:      params.GitTagMatch,
 				database.BuildColumns.Stage:       params.StageMatch,
 			}),
 			whereAnyLikeScope(
 				params.Match,
 				database.BuildColumns.Environment,
 				database.BuildColumns.GitBranch,
+				database.BuildColumns.GitCommit,
+				database.BuildColumns.GitTag,
 				database.BuildColumns.Stage,
 			),
+			whereBuildParamScope(params.ParamName, params.ParamValue),
+			whereLabelsScope(buildLabelTable, labelFilters),
+			whereBuildInstanceScope(c),
+			whereBuildStaleScope(m.Config.CI, params.Stale),
 		)
 
 	type statusID struct {
@@ -259,6 +396,14 @@ func (m buildModule) getBuildListHandler(c *gin.Context) {
 		}
 	}
 
+	if params.TriggerFailed != nil {
+		op := "="
+		if !*params.TriggerFailed {
+			op = "<>"
+		}
+		query = query.Where(fmt.Sprintf("%s %s ?", database.BuildColumns.StatusID, op), int(database.BuildTriggerFailed))
+	}
+
 	if len(statusIDs) > 0 {
 		ids := make([]any, len(statusIDs))
 		for i, status := range statusIDs {
@@ -267,20 +412,43 @@ func (m buildModule) getBuildListHandler(c *gin.Context) {
 		query = query.Where(fmt.Sprintf("%s IN ?", database.BuildColumns.StatusID), ids)
 	}
 
+	if ginrender.IsCSVRequested(c) {
+		writeDBQueryCSV(c, query, params.Limit, params.Offset, "builds.csv", buildCSVHeader,
+			func(dbBuild database.Build) []string {
+				return resBuildToCSVRecord(modelconv.DBBuildToResponse(dbBuild, m.engineLookup))
+			}, "Failed fetching list of builds from database.")
+		return
+	}
+
 	var dbBuilds []database.Build
 	var totalCount int64
-	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, &dbBuilds, &totalCount)
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbBuilds, &totalCount)
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, "Failed fetching list of builds from database.")
 		return
 	}
+	expiries, err := findBuildExpiries(m.Database, m.Config.CI, dbBuilds)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching timeouts of list of builds from database.")
+		return
+	}
 
-	renderJSON(c, http.StatusOK, response.PaginatedBuilds{
-		List:       modelconv.DBBuildsToResponses(dbBuilds, m.engineLookup),
+	resBuilds := modelconv.DBBuildsToResponses(dbBuilds, m.engineLookup)
+	for i := range resBuilds {
+		setBuildExpiresOn(&resBuilds[i], expiries)
+	}
+	ginrender.Response(c, http.StatusOK, response.PaginatedBuilds{
+		List:       resBuilds,
 		TotalCount: totalCount,
 	})
 }
 
+func setBuildExpiresOn(resBuild *response.Build, expiries map[uint]time.Time) {
+	if expiresOn, ok := expiries[resBuild.BuildID]; ok {
+		resBuild.ExpiresOn = null.TimeFrom(expiresOn)
+	}
+}
+
 func parseBuildStatusOrWriteError(c *gin.Context, str, paramName string) (database.BuildStatus, bool) {
 	reqStatusID := request.BuildStatus(str)
 	id, ok := modelconv.ReqBuildStatusToDatabase(reqStatusID)
@@ -299,6 +467,7 @@ func parseBuildStatusOrWriteError(c *gin.Context, str, paramName string) (databa
 // @tags build
 // @produce json
 // @param buildId path uint true "build id" minimum(0)
+// @param match query string false "Filter by log lines matching all words in the value. Uses full-text search when using PostgreSQL. Added in v5.3.0."
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} []response.Log "logs from selected build"
 // @failure 400 {object} problem.Response "Bad request"
@@ -310,8 +479,15 @@ func (m buildModule) getBuildLogListHandler(c *gin.Context) {
 	if !ok {
 		return
 	}
+	var params = struct {
+		Match *string `form:"match"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
 
-	dbLogs, err := m.getLogs(buildID)
+	dbLogs, err := m.getLogs(buildID, params.Match)
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
 			"Failed fetching logs for build with ID %d.",
@@ -329,12 +505,107 @@ func (m buildModule) getBuildLogListHandler(c *gin.Context) {
 		}
 	}
 
-	renderJSON(c, http.StatusOK, resLogs)
+	ginrender.Response(c, http.StatusOK, resLogs)
 }
 
+// buildLogDownloadChunkSize is the number of log lines fetched from the
+// database at a time when downloading a build's log.
+const buildLogDownloadChunkSize = 1000
+
+// downloadBuildLogHandler godoc
+// @id downloadBuildLog
+// @summary Download the full log of a build as a file
+// @description Each log line is prefixed with its timestamp, in UTC.
+// @description Added in v5.3.0.
+// @tags build
+// @produce plain,application/gzip
+// @param buildId path uint true "build id" minimum(0)
+// @param gzip query bool false "Compress the log file using gzip"
+// @success 200 {file} string "Log file"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/log/download [get]
+func (m buildModule) downloadBuildLogHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	var params = struct {
+		Gzip bool `form:"gzip"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	if !validateDatabaseObjExistsByID(c, m.Database, &database.Build{}, buildID, "build", "when downloading build log") {
+		return
+	}
+
+	// The first chunk is fetched before writing any headers, so that errors
+	// can still be written as a problem response.
+	dbLogs, err := getBuildLogsChunk(m.Database, buildID, 0)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching logs for build with ID %d.",
+			buildID))
+		return
+	}
+
+	fileName := fmt.Sprintf("build-%d.log", buildID)
+	contentType := "text/plain; charset=utf-8"
+	if params.Gzip {
+		fileName += ".gz"
+		contentType = "application/gzip"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	c.Status(http.StatusOK)
+
+	var w io.Writer = c.Writer
+	if params.Gzip {
+		gz := gzip.NewWriter(c.Writer)
+		defer gz.Close()
+		w = gz
+	}
+	buf := bufio.NewWriter(w)
+	defer buf.Flush()
+
+	for len(dbLogs) > 0 {
+		for _, dbLog := range dbLogs {
+			buf.WriteString(dbLog.Timestamp.UTC().Format(buildLogTimestampFormat))
+			buf.WriteByte(' ')
+			buf.WriteString(dbLog.Message)
+			buf.WriteByte('\n')
+		}
+		if len(dbLogs) < buildLogDownloadChunkSize {
+			return
+		}
+		dbLogs, err = getBuildLogsChunk(m.Database, buildID, dbLogs[len(dbLogs)-1].LogID)
+		if err != nil {
+			// Too late to write a problem response, as the headers and
+			// parts of the log has already been sent.
+			c.Error(err)
+			requestLog(c).Error().WithError(err).
+				WithUint("build", buildID).
+				Message("Failed fetching logs chunk when downloading build log.")
+			return
+		}
+	}
+}
+
+// buildLogTimestampFormat is RFC 3339 with a fixed number of decimals, so that
+// the log messages line up.
+const buildLogTimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
 // streamBuildLogHandler godoc
 // @id streamBuildLog
 // @summary Opens stream listener
+// @description Log lines are sent as `message` events. Changes to the build's
+// @description status are sent as `status` events, holding a `response.BuildStatusEvent`.
+// @description Empty `heartbeat` events are sent periodically to keep the
+// @description connection alive, and have been sent since v5.3.0.
 // @description Added in v0.3.8.
 // @tags build
 // @produce json-stream
@@ -342,6 +613,7 @@ func (m buildModule) getBuildLogListHandler(c *gin.Context) {
 // @success 200 "Open stream"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 503 {object} problem.Response "Too many listeners on the build's event stream"
 // @router /build/{buildId}/stream [get]
 func (m buildModule) streamBuildLogHandler(c *gin.Context) {
 	buildID, ok := ginutil.ParseParamUint(c, "buildId")
@@ -349,19 +621,48 @@ func (m buildModule) streamBuildLogHandler(c *gin.Context) {
 		return
 	}
 
-	listener := openListener(buildID)
-	defer closeListener(buildID, listener)
+	listener, err := buildStreams.listen(buildID)
+	if err != nil {
+		ginutil.WriteProblemError(c, err, problems.BuildStreamTooManyListeners.Newf(
+			"The event stream of build with ID %d already has the maximum of %d listeners.",
+			buildID, buildStreams.config.MaxListenersPerBuild))
+		return
+	}
+	defer listener.close()
 
-	clientGone := c.Writer.CloseNotify()
-	c.Stream(func(w io.Writer) bool {
+	streamConfig := m.Config.HTTP.BuildStream
+	var heartbeat <-chan time.Time
+	if streamConfig.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(streamConfig.HeartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	// Initial heartbeat sends the headers right away, and tells the client
+	// how long to wait before reconnecting.
+	c.Render(-1, sse.Event{
+		Event: "heartbeat",
+		Retry: uint(streamConfig.RetryInterval.Milliseconds()),
+		Data:  "",
+	})
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for {
 		select {
-		case <-clientGone:
-			return false
-		case message := <-listener:
-			c.SSEvent("message", message)
-			return true
+		case <-ctx.Done():
+			return
+		case <-heartbeat:
+			c.SSEvent("heartbeat", "")
+		case message := <-listener.C:
+			if _, ok := message.(response.BuildStatusEvent); ok {
+				c.SSEvent("status", message)
+			} else {
+				c.SSEvent("message", message)
+			}
 		}
-	})
+		c.Writer.Flush()
+	}
 }
 
 // createBuildLogHandler godoc
@@ -372,10 +673,15 @@ func (m buildModule) streamBuildLogHandler(c *gin.Context) {
 // @accept json
 // @param buildId path uint true "build id" minimum(0)
 // @param data body request.LogOrStatusUpdate true "data"
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
 // @success 201 "Created"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 409 {object} problem.Response "Request with the same idempotency key is still being handled"
+// @failure 413 {object} problem.Response "Request body is larger than the artifact.maxUploadSize config. Added in v5.3.0."
+// @failure 422 {object} problem.Response "Idempotency key was already used for a different request"
 // @failure 502 {object} problem.Response "Database is unreachable"
+// @failure 503 {object} problem.Response "Too many logs waiting to be inserted"
 // @router /build/{buildId}/log [post]
 func (m buildModule) createBuildLogHandler(c *gin.Context) {
 	buildID, ok := ginutil.ParseParamUint(c, "buildId")
@@ -385,13 +691,19 @@ func (m buildModule) createBuildLogHandler(c *gin.Context) {
 
 	var reqLogOrStatusUpdate request.LogOrStatusUpdate
 	if err := c.ShouldBindJSON(&reqLogOrStatusUpdate); err != nil {
-		ginutil.WriteInvalidBindError(c, err,
+		if writeUploadTooLargeIfExceeded(c) {
+			return
+		}
+		writeInvalidBindError(c, err,
 			"One or more parameters failed to parse when reading the request body for log object to post.")
 		return
 	}
 
 	if dbBuildStatus, ok := modelconv.ReqBuildStatusToDatabase(reqLogOrStatusUpdate.Status); ok {
-		_, err := m.updateBuildStatus(buildID, dbBuildStatus)
+		_, err := m.updateBuildStatus(buildID, buildStatusUpdate{
+			status: dbBuildStatus,
+			actor:  getBuildStatusActorFromContext(c),
+		})
 		if err != nil {
 			ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 				"Failed updating status on build with ID %d to status with ID %d.",
@@ -399,23 +711,22 @@ func (m buildModule) createBuildLogHandler(c *gin.Context) {
 			return
 		}
 	} else {
-		dbLog, err := saveLog(m.Database,
-			buildID,
-			reqLogOrStatusUpdate.Message,
-			reqLogOrStatusUpdate.Timestamp)
-		if err != nil {
+		_, err := m.LogIngester.save(c.Request.Context(), database.Log{
+			BuildID:   buildID,
+			Message:   reqLogOrStatusUpdate.Message,
+			Timestamp: reqLogOrStatusUpdate.Timestamp,
+		})
+		if errors.Is(err, errLogIngestQueueFull) {
+			ginutil.WriteProblemError(c, err, problems.BuildLogQueueFull.Newf(
+				"The queue of logs waiting to be inserted is full. The log message to build with ID %d was not added.",
+				buildID))
+			return
+		} else if err != nil {
 			ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 				"Failed adding log message to build with ID %d.",
 				buildID))
 			return
 		}
-		resLog := response.Log{
-			LogID:     dbLog.LogID,
-			BuildID:   dbLog.BuildID,
-			Message:   dbLog.Message,
-			Timestamp: dbLog.Timestamp,
-		}
-		build(buildID).Submit(resLog)
 	}
 
 	c.Status(http.StatusCreated)
@@ -424,6 +735,8 @@ func (m buildModule) createBuildLogHandler(c *gin.Context) {
 // updateBuildStatusHandler godoc
 // @id updateBuildStatus
 // @summary Update a build's status.
+// @description The optional status message, such as why the build failed, is
+// @description stored on the build since v5.3.0.
 // @description Added in v5.0.0.
 // @tags build
 // @accept json
@@ -442,7 +755,7 @@ func (m buildModule) updateBuildStatusHandler(c *gin.Context) {
 	}
 	var reqStatusUpdate request.BuildStatusUpdate
 	if err := c.ShouldBindJSON(&reqStatusUpdate); err != nil {
-		ginutil.WriteInvalidBindError(c, err,
+		writeInvalidBindError(c, err,
 			"One or more parameters failed to parse when reading the request body for build status update.")
 		return
 	}
@@ -456,20 +769,38 @@ func (m buildModule) updateBuildStatusHandler(c *gin.Context) {
 			"The new build status %q is not a valid build status value.",
 			reqStatusUpdate.Status,
 		))
+		return
 	}
-	updatedBuild, err := m.updateBuildStatus(buildID, dbBuildStatus)
+	updatedBuild, err := m.updateBuildStatus(buildID, buildStatusUpdate{
+		status:        dbBuildStatus,
+		statusMessage: reqStatusUpdate.StatusMessage,
+		actor:         getBuildStatusActorFromContext(c),
+	})
 	if err != nil {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 			"Failed updating status on build with ID %d to status with ID %d.",
 			buildID, dbBuildStatus))
 		return
 	}
-	c.JSON(http.StatusOK, modelconv.DBBuildToResponse(updatedBuild, m.engineLookup))
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildToResponse(updatedBuild, m.engineLookup))
+}
+
+// buildStatusUpdate holds the values of a build status update, as used by
+// buildModule.updateBuildStatus.
+type buildStatusUpdate struct {
+	status        database.BuildStatus
+	statusMessage string
+	// actor is who or what updated the status, as recorded in the build's
+	// status history.
+	actor buildStatusActor
+	// stageResults replace the build's existing stage results of the same
+	// names, and are added otherwise.
+	stageResults []database.BuildStageResult
 }
 
-func (m buildModule) updateBuildStatus(buildID uint, statusID database.BuildStatus) (database.Build, error) {
-	if !statusID.IsValid() {
-		return database.Build{}, fmt.Errorf("invalid status ID: %+v", statusID)
+func (m buildModule) updateBuildStatus(buildID uint, update buildStatusUpdate) (database.Build, error) {
+	if !update.status.IsValid() {
+		return database.Build{}, fmt.Errorf("invalid status ID: %+v", update.status)
 	}
 
 	dbBuild, err := m.getBuild(buildID)
@@ -477,46 +808,75 @@ func (m buildModule) updateBuildStatus(buildID uint, statusID database.BuildStat
 		return database.Build{}, err
 	}
 
-	message := struct {
-		StatusBefore database.BuildStatus
-		StatusAfter  database.BuildStatus
-		Build        database.Build
-	}{
-		StatusBefore: dbBuild.StatusID,
-		StatusAfter:  statusID,
-	}
-
-	dbBuild.StatusID = statusID
-	setStatusDate(&dbBuild, statusID)
-
-	message.Build = dbBuild
+	statusBefore := dbBuild.StatusID
+	dbBuild.StatusID = update.status
+	dbBuild.StatusMessage = update.statusMessage
+	now := nowUTC(m.Clock)
+	setStatusDate(&dbBuild, update.status, now)
 
-	if err := m.Database.Save(&dbBuild).Error; err != nil {
+	err = m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&dbBuild).Error; err != nil {
+			return err
+		}
+		if err := saveBuildStatusTransition(tx, dbBuild, null.IntFrom(int64(statusBefore)),
+			update.actor, update.statusMessage, now); err != nil {
+			return err
+		}
+		return saveBuildStageResults(tx, &dbBuild, update.stageResults, now)
+	})
+	if err != nil {
 		return database.Build{}, err
 	}
 
+	submitBuildStatusEvent(dbBuild, statusBefore, update.statusMessage)
+	if update.status == database.BuildCompleted && statusBefore != database.BuildCompleted {
+		m.startTriggeredBuilds(dbBuild)
+	}
+	if update.status == database.BuildFailed && statusBefore != database.BuildFailed {
+		m.emailFailedBuildSubscribers(dbBuild)
+	}
 	return dbBuild, nil
 }
 
-func saveLog(db *gorm.DB, buildID uint, message string, timestamp time.Time) (database.Log, error) {
-	dbLog := database.Log{
-		BuildID:   buildID,
-		Message:   message,
-		Timestamp: timestamp,
-	}
-	if err := db.Save(&dbLog).Error; err != nil {
-		return database.Log{}, err
+// saveBuildStageResults replaces the build's stage results with the new results
+// of the same stage names, and adds the rest. The stages' start and completion
+// dates are set from the new statuses, as of the given time.
+func saveBuildStageResults(tx *gorm.DB, dbBuild *database.Build, dbResults []database.BuildStageResult, now time.Time) error {
+	for _, dbResult := range dbResults {
+		dbResult.BuildID = dbBuild.BuildID
+		existingIdx := -1
+		for i, existing := range dbBuild.StageResults {
+			if existing.Name == dbResult.Name {
+				dbResult.TimeMetadata = existing.TimeMetadata
+				dbResult.BuildStageResultID = existing.BuildStageResultID
+				dbResult.StartedOn = existing.StartedOn
+				dbResult.CompletedOn = existing.CompletedOn
+				existingIdx = i
+				break
+			}
+		}
+		setBuildStageResultStatusDate(&dbResult, now)
+		if err := tx.Save(&dbResult).Error; err != nil {
+			return err
+		}
+		if existingIdx != -1 {
+			dbBuild.StageResults[existingIdx] = dbResult
+		} else {
+			dbBuild.StageResults = append(dbBuild.StageResults, dbResult)
+		}
 	}
-	return dbLog, nil
+	return nil
 }
 
-func setStatusDate(build *database.Build, statusID database.BuildStatus) {
-	now := time.Now().UTC()
+func setStatusDate(build *database.Build, statusID database.BuildStatus, now time.Time) {
 	switch statusID {
 	case database.BuildRunning:
 		build.StartedOn.SetValid(now)
 	case database.BuildCompleted, database.BuildFailed:
 		build.CompletedOn.SetValid(now)
+		if build.StartedOn.Valid {
+			build.Duration.SetValid(now.Sub(build.StartedOn.Time).Milliseconds())
+		}
 	}
 }
 
@@ -531,10 +891,11 @@ func (m buildModule) getBuild(buildID uint) (database.Build, error) {
 	return dbBuild, nil
 }
 
-func (m buildModule) getLogs(buildID uint) ([]database.Log, error) {
+func (m buildModule) getLogs(buildID uint, match *string) ([]database.Log, error) {
 	var dbLogs []database.Log
 	if err := m.Database.
 		Where(&database.Build{BuildID: buildID}).
+		Scopes(whereFullTextMatchScope(match, database.LogColumns.Message)).
 		Find(&dbLogs).
 		Error; err != nil {
 		return []database.Log{}, err
@@ -542,6 +903,22 @@ func (m buildModule) getLogs(buildID uint) ([]database.Log, error) {
 	return dbLogs, nil
 }
 
+// getBuildLogsChunk returns the next chunk of logs of a build, ordered by
+// their IDs, starting after the log with the ID afterLogID.
+func getBuildLogsChunk(db *gorm.DB, buildID, afterLogID uint) ([]database.Log, error) {
+	var dbLogs []database.Log
+	if err := db.
+		Where(database.LogColumns.BuildID+" = ?", buildID).
+		Where(database.LogColumns.LogID+" > ?", afterLogID).
+		Order(database.LogColumns.LogID).
+		Limit(buildLogDownloadChunkSize).
+		Find(&dbLogs).
+		Error; err != nil {
+		return nil, err
+	}
+	return dbLogs, nil
+}
+
 // oldStartProjectBuildHandler godoc
 // @id oldStartProjectBuild
 // @deprecated
@@ -586,15 +963,24 @@ func (m buildModule) oldStartProjectBuildHandler(c *gin.Context) {
 // @param projectId path uint true "Project ID" minimum(0)
 // @param stage query string false "Name of stage to run, or specify `ALL` to run all stages." default(ALL)
 // @param branch query string false "Branch name. Uses project's default branch if omitted"
+// @param commit query string false "Git commit SHA to build. Passed on to the execution engine as `GIT_COMMIT`. Can also be set via the `commit` field of the body, but the query parameter takes precedence." maxlength(64)
+// @param tag query string false "Git tag to build. Passed on to the execution engine as `GIT_TAG`. Can also be set via the `tag` field of the body, but the query parameter takes precedence." maxlength(300)
 // @param environment query string false "Environment name filter. If left empty it will run all stages without any environment filters."
-// @param engine query string false "Execution engine ID"
-// @param inputs body request.BuildInputs _ "Input variable values. Map of variable names (as defined in the project's `.wharf-ci.yml` file) as keys paired with their string, boolean, or numeric value."
+// @param engine query string false "Execution engine ID. Must be one of the project's allowed execution engines, if it has any."
+// @param async query bool false "Trigger the build in the background, and respond without waiting for the execution engine. Failures to trigger the build sets the build's status to `TriggerFailed`, which is also sent on the build's event stream."
+// @param scheduleAt query string false "Date and time to trigger the build at, in RFC 3339 format, such as `2024-05-01T03:00:00Z`. Must be in the future. The build gets the status `Pending` until then, and can be cancelled via `DELETE /build/{buildId}`. Added in v5.3.0." format(date-time)
+// @param inputs body request.BuildInputs _ "Input variable values. Map of variable names (as defined in the project's `.wharf-ci.yml` file) as keys paired with their string, boolean, or numeric value. Values are validated against the input's declared type, and unknown input names are rejected. The `commit` and `tag` fields are not input variables, but the Git commit SHA and tag to build, and are rejected if the build definition declares input variables with the same names."
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.BuildReferenceWrapper "Build scheduled"
-// @failure 400 {object} problem.Response "Bad request, such as invalid body JSON"
+// @success 202 {object} response.BuildReferenceWrapper "Build created, and will be triggered in the background, at its scheduled time, or after it has been approved if it targets a protected environment"
+// @failure 400 {object} problem.Response "Bad request, such as invalid body JSON or input variables, or an execution engine that the project is not allowed to use"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 404 {object} problem.Response "Project was not found"
+// @failure 409 {object} problem.Response "Request with the same idempotency key is still being handled"
+// @failure 422 {object} problem.Response "Idempotency key was already used for a different request"
 // @failure 502 {object} problem.Response "Database or code execution engine is unreachable"
+// @failure 503 {object} problem.Response "Code execution engine has failed too many times in a row, or too many builds are waiting to be triggered"
 // @router /project/{projectId}/build [post]
 func (m buildModule) startProjectBuildHandler(c *gin.Context) {
 	projectID, ok := ginutil.ParseParamUint(c, "projectId")
@@ -610,26 +996,34 @@ func (m buildModule) startProjectBuildHandler(c *gin.Context) {
 }
 
 func (m buildModule) startBuildHandler(c *gin.Context, projectID uint, stageName string, engineID string) {
-	engine, ok := lookupEngineOrDefaultFromConfig(m.Config.CI, engineID)
-	if !ok {
-		if engineID == "" {
-			ginutil.WriteProblem(c, problem.Response{
-				Type:   "/prob/api/engine/no-default",
-				Title:  "No default execution engine configured.",
-				Status: http.StatusInternalServerError,
-				Detail: "The wharf-api does not have any default execution engine configured, meaning it doesn't know where to run your Wharf build.",
-			})
+	var params = struct {
+		Async      bool       `form:"async"`
+		ScheduleAt *time.Time `form:"scheduleAt"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	var scheduleAt null.Time
+	if params.ScheduleAt != nil {
+		if now := nowUTC(m.Clock); !params.ScheduleAt.After(now) {
+			err := fmt.Errorf("schedule time is not in the future: %s", params.ScheduleAt.Format(time.RFC3339))
+			ginutil.WriteInvalidParamError(c, err, "scheduleAt", fmt.Sprintf(
+				"The time to schedule the build at must be in the future, but was %s.",
+				params.ScheduleAt.UTC().Format(time.RFC3339)))
 			return
 		}
-		err := fmt.Errorf("unknown engine by ID: %q", engineID)
-		ginutil.WriteInvalidParamError(c, err, "engine", fmt.Sprintf(
-			"No execution engine was found by ID %q. You can skip to specify the engine ID to use the default execution engine.",
-			engineID))
+		scheduleAt = null.TimeFrom(params.ScheduleAt.UTC())
+	}
+
+	engine, ok := m.lookupEngineOrWriteProblem(c, engineID)
+	if !ok {
 		return
 	}
 
 	dbProject, ok := fetchProjectByID(c, m.Database, projectID, "when starting a new build")
-	if !ok {
+	if !ok || !validateProjectNotArchived(c, dbProject) ||
+		!validateProjectEngineAllowed(c, m.Database, projectID, engine) {
 		return
 	}
 
@@ -641,8 +1035,27 @@ func (m buildModule) startBuildHandler(c *gin.Context, projectID uint, stageName
 		return
 	}
 
+	bodyCommit, bodyTag, body, err := extractBuildGitRefs(body, buildDefInputNames(dbProject.BuildDefinition))
+	var collisionErr buildGitRefInputCollisionError
+	if errors.As(err, &collisionErr) {
+		ginutil.WriteInvalidParamError(c, err, collisionErr.field, fmt.Sprintf(
+			"The %q field of the body is ambiguous, as it is both the Git %s to build and an input variable declared in the build definition of project with ID %d. Rename the input variable to be able to set it.",
+			collisionErr.field, buildGitRefFieldNames[collisionErr.field], projectID))
+		return
+	} else if err != nil {
+		ginutil.WriteInvalidBindError(c, err, fmt.Sprintf(
+			"The commit and tag fields of the body must be strings when starting a new build for project with ID %d.",
+			projectID))
+		return
+	}
+
 	env, hasEnv := c.GetQuery("environment")
 	branch, hasBranch := c.GetQuery("branch")
+	commit := c.DefaultQuery("commit", bodyCommit)
+	tag := c.DefaultQuery("tag", bodyTag)
+	if !validateBuildGitRefs(c, commit, tag) {
+		return
+	}
 
 	if !hasBranch {
 		b, ok := findDefaultBranch(dbProject.Branches)
@@ -655,129 +1068,679 @@ func (m buildModule) startBuildHandler(c *gin.Context, projectID uint, stageName
 		branch = b.Name
 	}
 
-	now := time.Now().UTC()
+	dbBuild, dbJobParams, ok := m.createBuild(c, dbProject, newBuildOptions{
+		stage:       stageName,
+		branch:      branch,
+		commit:      commit,
+		tag:         tag,
+		environment: null.NewString(env, hasEnv),
+		engine:      engine,
+		inputs:      body,
+		scheduleAt:  scheduleAt,
+	})
+	if !ok {
+		return
+	}
+
+	m.triggerBuildAndRespond(c, dbBuild, dbJobParams, engine, params.Async)
+}
+
+// buildGitRefFieldNames are the body fields holding the Git commit SHA and tag
+// to build, paired with their human readable names.
+var buildGitRefFieldNames = map[string]string{
+	"commit": "commit SHA",
+	"tag":    "tag",
+}
+
+// buildGitRefInputCollisionError is returned by extractBuildGitRefs when the
+// body has a "commit" or "tag" field, but it is also the name of an input
+// variable, so it is unclear which one the field is meant as.
+type buildGitRefInputCollisionError struct {
+	field string
+}
+
+func (err buildGitRefInputCollisionError) Error() string {
+	return fmt.Sprintf("%s: field is both a Git ref and an input variable", err.field)
+}
+
+// extractBuildGitRefs returns the Git commit SHA and tag from the "commit" and
+// "tag" fields of a start build body, together with the body without those
+// fields, so that the rest of the body only holds input variables. Bodies that
+// are not JSON objects are returned as-is, and are left to the input variable
+// parsing to reject. Returns buildGitRefInputCollisionError if one of the
+// fields is also the name of a declared input variable.
+func extractBuildGitRefs(body []byte, inputNames []string) (commit, tag string, inputs []byte, err error) {
+	var fields map[string]json.RawMessage
+	if len(bytes.TrimSpace(body)) == 0 || json.Unmarshal(body, &fields) != nil {
+		return "", "", body, nil
+	}
+	rawCommit, hasCommit := fields["commit"]
+	rawTag, hasTag := fields["tag"]
+	if !hasCommit && !hasTag {
+		return "", "", body, nil
+	}
+	if hasCommit {
+		if slices.Contains(inputNames, "commit") {
+			return "", "", nil, buildGitRefInputCollisionError{"commit"}
+		}
+		if err := json.Unmarshal(rawCommit, &commit); err != nil {
+			return "", "", nil, fmt.Errorf("commit: %w", err)
+		}
+		delete(fields, "commit")
+	}
+	if hasTag {
+		if slices.Contains(inputNames, "tag") {
+			return "", "", nil, buildGitRefInputCollisionError{"tag"}
+		}
+		if err := json.Unmarshal(rawTag, &tag); err != nil {
+			return "", "", nil, fmt.Errorf("tag: %w", err)
+		}
+		delete(fields, "tag")
+	}
+	inputs, err = json.Marshal(fields)
+	return commit, tag, inputs, err
+}
+
+// buildDefInputNames returns the names of the input variables declared in the
+// build definition. Returns nil if the build definition fails to parse, which
+// is instead reported when parsing the input variables.
+func buildDefInputNames(buildDef string) []string {
+	def, err := builddef.Parse(buildDef)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, len(def.Inputs))
+	for i, input := range def.Inputs {
+		names[i] = input.Name
+	}
+	return names
+}
+
+// validateBuildGitRefs writes a problem response and returns false if the Git
+// commit SHA or tag of a new build is too long.
+func validateBuildGitRefs(c *gin.Context, commit, tag string) bool {
+	if len(commit) > database.BuildSizes.GitCommit {
+		err := fmt.Errorf("commit SHA too long: max %d chars, but was: %d", database.BuildSizes.GitCommit, len(commit))
+		ginutil.WriteInvalidParamError(c, err, "commit", fmt.Sprintf(
+			"The Git commit SHA must not be longer than %d characters.",
+			database.BuildSizes.GitCommit))
+		return false
+	}
+	if len(tag) > database.BuildSizes.GitTag {
+		err := fmt.Errorf("tag too long: max %d chars, but was: %d", database.BuildSizes.GitTag, len(tag))
+		ginutil.WriteInvalidParamError(c, err, "tag", fmt.Sprintf(
+			"The Git tag must not be longer than %d characters.",
+			database.BuildSizes.GitTag))
+		return false
+	}
+	return true
+}
+
+// lookupEngineOrWriteProblem returns the execution engine by ID, or the
+// default engine if the ID is empty. Problem responses are written on failure.
+func (m buildModule) lookupEngineOrWriteProblem(c *gin.Context, engineID string) (CIEngineConfig, bool) {
+	engine, ok := lookupEngineOrDefaultFromConfig(m.Config.current().CI, engineID)
+	if !ok {
+		if engineID == "" {
+			ginutil.WriteProblem(c, problems.EngineNoDefault.New(
+				"The wharf-api does not have any default execution engine configured, meaning it doesn't know where to run your Wharf build."))
+			return CIEngineConfig{}, false
+		}
+		err := fmt.Errorf("unknown engine by ID: %q", engineID)
+		ginutil.WriteInvalidParamError(c, err, "engine", fmt.Sprintf(
+			"No execution engine was found by ID %q. You can skip to specify the engine ID to use the default execution engine.",
+			engineID))
+		return CIEngineConfig{}, false
+	}
+	return engine, true
+}
+
+// newBuildOptions holds the values of a new build, as used by
+// buildModule.createBuild.
+type newBuildOptions struct {
+	stage        string
+	branch       string
+	commit       string
+	tag          string
+	environment  null.String
+	engine       CIEngineConfig
+	buildGroupID null.Int
+	// inputs is the JSON or YAML encoded input variable values.
+	inputs []byte
+	// scheduleAt is when to send the build to the execution engine, or null
+	// to send it right away.
+	scheduleAt null.Time
+}
+
+// createBuild adds a new build with its parameters to the database, and
+// returns the build together with the job parameters to send to the execution
+// engine. Problem responses are written on failure.
+func (m buildModule) createBuild(c *gin.Context, dbProject database.Project, opts newBuildOptions) (database.Build, []database.Param, bool) {
+	var (
+		stageName = opts.stage
+		branch    = opts.branch
+		projectID = dbProject.ProjectID
+	)
 	dbBuild := database.Build{
-		ProjectID:   dbProject.ProjectID,
-		ScheduledOn: null.TimeFrom(now),
-		GitBranch:   branch,
-		Environment: null.NewString(env, hasEnv),
-		Stage:       stageName,
-		EngineID:    engine.ID,
-	}
-	if err := m.Database.Create(&dbBuild).Error; err != nil {
-		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+		ProjectID:    dbProject.ProjectID,
+		GitBranch:    branch,
+		GitCommit:    opts.commit,
+		GitTag:       opts.tag,
+		Environment:  opts.environment,
+		Stage:        stageName,
+		EngineID:     opts.engine.ID,
+		BuildGroupID: opts.buildGroupID,
+		ScheduleAt:   opts.scheduleAt,
+	}
+	setBuildTriggeredByFromContext(c, &dbBuild)
+	dbJobParams, err := m.insertBuild(&dbBuild, dbProject, opts.engine, opts.inputs)
+	if err == nil {
+		return dbBuild, dbJobParams, true
+	}
+	var insertErr insertBuildError
+	if !errors.As(err, &insertErr) {
+		insertErr = insertBuildError{insertBuildSaveBuild, err}
+	}
+	switch insertErr.step {
+	case insertBuildParseParams:
+		writeParseBuildParamsProblem(c, insertErr.err, stageName, branch, projectID)
+	case insertBuildSaveBuild:
+		ginutil.WriteDBWriteError(c, insertErr.err, fmt.Sprintf(
 			"Failed creating build on stage %q and branch %q for project with ID %d in database.",
 			stageName, branch, projectID))
-		return
+	case insertBuildSaveParams:
+		ginutil.WriteDBWriteError(c, insertErr.err, fmt.Sprintf(
+			"Failed saving build parameters for build on stage %q and branch %q for project with ID %d in database.",
+			stageName, branch, projectID))
+	default:
+		ginutil.WriteProblemError(c, insertErr.err, problems.ProjectRunParamsSerialize.Newf(
+			"Failed to serialize build parameters before sending them onwards to Wharfs execution engine for build on stage %q and branch %q for project with ID %d.",
+			stageName, branch, projectID))
 	}
+	return database.Build{}, nil, false
+}
+
+// insertBuildStep is an enum of the steps of buildModule.insertBuild.
+type insertBuildStep int
 
-	dbBuildParams, err := parseDBBuildParams(dbBuild.BuildID, []byte(dbProject.BuildDefinition), body)
+const (
+	insertBuildParseParams insertBuildStep = iota
+	insertBuildSaveBuild
+	insertBuildSaveParams
+	insertBuildSerializeParams
+)
+
+// insertBuildError is returned by buildModule.insertBuild, and holds which
+// step of adding the build failed.
+type insertBuildError struct {
+	step insertBuildStep
+	err  error
+}
+
+func (e insertBuildError) Error() string {
+	return e.err.Error()
+}
+
+func (e insertBuildError) Unwrap() error {
+	return e.err
+}
+
+// insertBuild adds the new build with its parameters, parsed from the
+// project's build definition and the input variable values, to the database.
+// Returns the job parameters to send to the execution engine. Nothing is added
+// to the database if any step fails.
+func (m buildModule) insertBuild(dbBuild *database.Build, dbProject database.Project, engine CIEngineConfig, inputs []byte) ([]database.Param, error) {
+	inputDefaults, err := findProjectInputDefaults(m.Database, dbProject.ProjectID, dbBuild.Environment)
 	if err != nil {
-		dbBuild.IsInvalid = true
-		if saveErr := m.Database.Save(&dbBuild).Error; saveErr != nil {
-			c.Error(saveErr)
+		return nil, insertBuildError{insertBuildSaveBuild, err}
+	}
+	dbBuildParams, err := parseDBBuildParams(0, []byte(dbProject.BuildDefinition), inputDefaults, inputs)
+	if err != nil {
+		return nil, insertBuildError{insertBuildParseParams, err}
+	}
+	if err := validateBuildVarsSize(m.Config.CI, engine, dbBuildParams); err != nil {
+		return nil, insertBuildError{insertBuildParseParams, err}
+	}
+	// Builds always belong to the same Wharf installation as their project.
+	dbBuild.InstanceID = dbProject.InstanceID
+
+	if dbBuild.Environment.Valid {
+		isProtected, err := isEnvironmentProtected(m.Database, dbBuild.ProjectID, dbBuild.Environment.String)
+		if err != nil {
+			return nil, insertBuildError{insertBuildSaveBuild, err}
 		}
-		ginutil.WriteProblemError(c, err, problem.Response{
-			Type:   "/prob/api/project/run/params-deserialize",
-			Title:  "Parsing build parameters failed.",
-			Status: http.StatusBadRequest,
-			Detail: fmt.Sprintf(
-				"Failed to deserialize build parameters from request body for build on stage %q and branch %q for project with ID %d.",
-				stageName, branch, projectID),
-		})
-		return
+		if isProtected {
+			dbBuild.StatusID = database.BuildAwaitingApproval
+			dbBuild.ApprovalStatus = database.BuildApprovalPending
+		}
+	}
+	if dbBuild.ScheduleAt.Valid && dbBuild.StatusID != database.BuildAwaitingApproval {
+		dbBuild.StatusID = database.BuildPending
 	}
 
-	err = m.SaveBuildParams(dbBuildParams)
+	dbBuild.ScheduledOn = null.TimeFrom(nowUTC(m.Clock))
+
+	// The build and its parameters are added in a single transaction, so that
+	// no build is left behind without its parameters if any step fails.
+	var dbJobParams []database.Param
+	err = m.Database.Transaction(func(tx *gorm.DB) error {
+		buildNumber, err := nextBuildNumber(tx, dbBuild.ProjectID)
+		if err != nil {
+			return insertBuildError{insertBuildSaveBuild, err}
+		}
+		dbBuild.BuildNumber = buildNumber
+		if err := tx.Create(dbBuild).Error; err != nil {
+			return insertBuildError{insertBuildSaveBuild, err}
+		}
+		actor := buildStatusActor{
+			actorType: dbBuild.TriggeredByType,
+			name:      dbBuild.TriggeredByName,
+			subject:   dbBuild.TriggeredBySubject,
+		}
+		if err := saveBuildStatusTransition(tx, *dbBuild, null.Int{}, actor, "", dbBuild.ScheduledOn.Time); err != nil {
+			return insertBuildError{insertBuildSaveBuild, err}
+		}
+		for i := range dbBuildParams {
+			dbBuildParams[i].BuildID = dbBuild.BuildID
+		}
+		dbBuildParamsToSave, err := m.encryptSecretBuildParams(dbBuildParams)
+		if err == nil {
+			err = saveBuildParams(tx, dbBuildParamsToSave)
+		}
+		if err != nil {
+			return insertBuildError{insertBuildSaveParams, err}
+		}
+		dbJobParams, err = getEngineJobParams(m.Config.CI, engine, dbProject, *dbBuild, dbBuildParams, m.Config.InstanceID)
+		if err != nil {
+			return insertBuildError{insertBuildSerializeParams, err}
+		}
+		return nil
+	})
 	if err != nil {
-		dbBuild.IsInvalid = true
-		if saveErr := m.Database.Save(&dbBuild).Error; saveErr != nil {
-			c.Error(saveErr)
+		dbBuild.BuildID = 0
+		dbBuild.BuildNumber = 0
+		return nil, err
+	}
+
+	// Updated after the transaction, as a failed statement aborts the whole
+	// transaction in PostgreSQL, and the last build of the branch is only
+	// informational.
+	if err := setBranchLastBuild(m.Database, *dbBuild); err != nil {
+		log.Warn().
+			WithError(err).
+			WithUint("build", dbBuild.BuildID).
+			WithString("branch", dbBuild.GitBranch).
+			Message("Failed to update last build of branch.")
+	}
+	busEvents.publishBuildEvent(response.BusEventBuildCreated, *dbBuild)
+	return dbJobParams, nil
+}
+
+// storedBuildJobStep is an enum of the steps of buildModule.storedBuildJob.
+type storedBuildJobStep int
+
+const (
+	storedBuildJobLookupEngine storedBuildJobStep = iota
+	storedBuildJobDecryptParams
+	storedBuildJobSerializeParams
+)
+
+// storedBuildJobError is returned by buildModule.storedBuildJob, and holds
+// which step of loading the build's job failed.
+type storedBuildJobError struct {
+	step storedBuildJobStep
+	err  error
+}
+
+func (e storedBuildJobError) Error() string {
+	return e.err.Error()
+}
+
+func (e storedBuildJobError) Unwrap() error {
+	return e.err
+}
+
+// storedBuildJob returns the execution engine and the job parameters of an
+// existing build, using the build's stored parameters. The build's parameters
+// must be preloaded.
+func (m buildModule) storedBuildJob(dbBuild database.Build, dbProject database.Project) (CIEngineConfig, []database.Param, error) {
+	engine, ok := lookupEngineOrDefaultFromConfig(m.Config.current().CI, dbBuild.EngineID)
+	if !ok {
+		err := fmt.Errorf("unknown engine by ID: %q", dbBuild.EngineID)
+		return CIEngineConfig{}, nil, storedBuildJobError{storedBuildJobLookupEngine, err}
+	}
+	dbBuildParams, err := m.decryptSecretBuildParams(dbBuild.Params)
+	if err != nil {
+		return CIEngineConfig{}, nil, storedBuildJobError{storedBuildJobDecryptParams, err}
+	}
+	dbJobParams, err := getEngineJobParams(m.Config.CI, engine, dbProject, dbBuild, dbBuildParams, m.Config.InstanceID)
+	if err != nil {
+		return CIEngineConfig{}, nil, storedBuildJobError{storedBuildJobSerializeParams, err}
+	}
+	return engine, dbJobParams, nil
+}
+
+// loadStoredBuildJob returns the execution engine and the job parameters of an
+// existing build, as described by buildModule.storedBuildJob. Problem
+// responses are written on failure.
+func (m buildModule) loadStoredBuildJob(c *gin.Context, dbBuild database.Build, whenMsg string) (CIEngineConfig, []database.Param, bool) {
+	buildID := dbBuild.BuildID
+	dbProject, ok := fetchProjectByID(c, m.Database, dbBuild.ProjectID, whenMsg)
+	if !ok {
+		return CIEngineConfig{}, nil, false
+	}
+
+	engine, dbJobParams, err := m.storedBuildJob(dbBuild, dbProject)
+	if err == nil {
+		return engine, dbJobParams, true
+	}
+	var jobErr storedBuildJobError
+	if !errors.As(err, &jobErr) {
+		jobErr = storedBuildJobError{storedBuildJobSerializeParams, err}
+	}
+	switch {
+	case jobErr.step == storedBuildJobLookupEngine:
+		ginutil.WriteProblem(c, problems.BuildRetriggerUnknownEngine.Newf(
+			"The execution engine %q of build with ID %d is no longer configured in the wharf-api.",
+			dbBuild.EngineID, buildID))
+	case errors.Is(err, errSecretBuildParamNotStored):
+		ginutil.WriteProblemError(c, err, problems.BuildRetriggerMissingSecrets.Newf(
+			"The values of the secret inputs of build with ID %d were not stored, as no database encryption key is configured. Start a new build instead.",
+			buildID))
+	case jobErr.step == storedBuildJobDecryptParams:
+		ginutil.WriteProblemError(c, err, problems.BuildRetriggerDecrypt.Newf(
+			"Failed to decrypt the values of the secret inputs of build with ID %d.",
+			buildID))
+	default:
+		ginutil.WriteProblemError(c, err, problems.ProjectRunParamsSerialize.Newf(
+			"Failed to serialize build parameters before sending them onwards to Wharfs execution engine for build with ID %d.",
+			buildID))
+	}
+	return CIEngineConfig{}, nil, false
+}
+
+// retriggerBuildHandler godoc
+// @id retriggerBuild
+// @summary Retry triggering a build that failed to be triggered.
+// @description Sends the build to the execution engine again, using the same
+// @description parameters, without creating a new build. Only builds with the
+// @description `TriggerFailed` status can be retriggered.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param async query bool false "Trigger the build in the background, and respond without waiting for the execution engine."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildReferenceWrapper "Build scheduled"
+// @success 202 {object} response.BuildReferenceWrapper "Build will be triggered in the background"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 409 {object} problem.Response "Build cannot be retriggered, such as when it was triggered successfully"
+// @failure 502 {object} problem.Response "Database or code execution engine is unreachable"
+// @failure 503 {object} problem.Response "Code execution engine has failed too many times in a row, or too many builds are waiting to be triggered"
+// @router /build/{buildId}/retrigger [post]
+func (m buildModule) retriggerBuildHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	var params = struct {
+		Async bool `form:"async"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+
+	dbBuild, err := m.getBuild(buildID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Build with ID %d was not found.",
+			buildID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching build with ID %d from database.",
+			buildID))
+		return
+	}
+
+	if dbBuild.StatusID != database.BuildTriggerFailed {
+		ginutil.WriteProblem(c, problems.BuildRetriggerInvalidStatus.Newf(
+			"Only builds with the status %q can be retriggered, but build with ID %d has the status %q.",
+			response.BuildTriggerFailed, buildID, modelconv.DBBuildStatusToResponse(dbBuild.StatusID)))
+		return
+	}
+
+	engine, dbJobParams, ok := m.loadStoredBuildJob(c, dbBuild, "when retriggering build")
+	if !ok {
+		return
+	}
+
+	statusBefore := dbBuild.StatusID
+	dbBuild.StatusID = database.BuildScheduling
+	dbBuild.IsInvalid = false
+	dbBuild.TriggerError = ""
+	err = m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&dbBuild).Error; err != nil {
+			return err
 		}
+		return saveBuildStatusTransition(tx, dbBuild, null.IntFrom(int64(statusBefore)),
+			getBuildStatusActorFromContext(c), "", nowUTC(m.Clock))
+	})
+	if err != nil {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
-			"Failed saving build parameters for build on stage %q and branch %q for project with ID %d in database.",
-			stageName, branch, projectID))
+			"Failed resetting status of build with ID %d in database.",
+			buildID))
 		return
 	}
+	submitBuildStatusEvent(dbBuild, statusBefore, "")
 
-	dbJobParams, err := getDBJobParams(dbProject, dbBuild, dbBuildParams, m.Config.InstanceID)
-	if err != nil {
-		dbBuild.IsInvalid = true
-		if saveErr := m.Database.Save(&dbBuild).Error; saveErr != nil {
-			c.Error(saveErr)
-		}
-		ginutil.WriteProblemError(c, err, problem.Response{
-			Type:   "/prob/api/project/run/params-serialize",
-			Title:  "Serializing build parameters failed.",
-			Status: http.StatusBadRequest,
-			Detail: fmt.Sprintf(
-				"Failed to serialize build parameters before sending them onwards to Wharfs execution engine for build on stage %q and branch %q for project with ID %d.",
-				stageName, branch, projectID),
-		})
+	m.triggerBuildAndRespond(c, dbBuild, dbJobParams, engine, params.Async)
+}
+
+// triggerBuildAndRespond triggers the build on the execution engine, either
+// directly or in the background if async is true, and writes the response.
+//
+// If the trigger fails, then the build and its parameters are kept, but the
+// build is marked as invalid with the TriggerFailed status and the trigger
+// error, as described by saveBuildTriggerFailed, so that it can be retriggered
+// later.
+func (m buildModule) triggerBuildAndRespond(c *gin.Context, dbBuild database.Build, dbJobParams []database.Param, engine CIEngineConfig, async bool) {
+	var (
+		stageName = dbBuild.Stage
+		branch    = dbBuild.GitBranch
+		projectID = dbBuild.ProjectID
+	)
+
+	if dbBuild.StatusID == database.BuildAwaitingApproval || dbBuild.StatusID == database.BuildPending {
+		ginrender.Response(c, http.StatusAccepted, modelconv.DBBuildToResponseBuildReferenceWrapper(dbBuild))
 		return
 	}
 
 	if m.Config.CI.MockTriggerResponse {
-		log.Info().Message("Setting for mocking build triggers was true, mocking CI response.")
-		c.JSON(http.StatusOK, modelconv.DBBuildToResponseBuildReferenceWrapper(dbBuild))
+		requestLog(c).Info().Message("Setting for mocking build triggers was true, mocking CI response.")
+		ginrender.Response(c, http.StatusOK, modelconv.DBBuildToResponseBuildReferenceWrapper(dbBuild))
+		return
+	}
+
+	if async {
+		err := m.Dispatcher.enqueue(buildTriggerJob{
+			dbBuild:     dbBuild,
+			dbJobParams: dbJobParams,
+			engine:      engine,
+			requestID:   getRequestID(c),
+		})
+		if err != nil {
+			if saveErr := saveBuildTriggerFailed(m.Database, &dbBuild, err); saveErr != nil {
+				c.Error(saveErr)
+			}
+			ginutil.WriteProblemError(c, err, problems.ProjectRunQueueFull.Newf(
+				"The queue of builds waiting to be triggered is full. The build with ID %d on stage %q on branch %q for project with ID %d was not triggered.",
+				dbBuild.BuildID, stageName, branch, projectID))
+			return
+		}
+		ginrender.Response(c, http.StatusAccepted, modelconv.DBBuildToResponseBuildReferenceWrapper(dbBuild))
 		return
 	}
 
-	workerID, err := triggerBuild(dbJobParams, engine)
+	workerID, err := m.EngineClient.triggerBuild(c.Request.Context(), dbJobParams, engine, getRequestID(c))
 	if err != nil {
-		dbBuild.IsInvalid = true
-		if saveErr := m.Database.Save(&dbBuild).Error; saveErr != nil {
+		if saveErr := saveBuildTriggerFailed(m.Database, &dbBuild, err); saveErr != nil {
 			c.Error(saveErr)
 		}
 
-		ginutil.WriteProblemError(c, err, problem.Response{
-			Type:   "/prob/api/project/run/trigger",
-			Title:  "Triggering build failed.",
-			Status: http.StatusBadGateway,
-			Detail: fmt.Sprintf(
-				"Failed to trigger code execution engine to schedule the build with ID %d on stage %q on branch %q for project with ID %d.",
-				dbBuild.BuildID, stageName, branch, projectID),
-		})
+		var circuitErr engineCircuitOpenError
+		if errors.As(err, &circuitErr) {
+			ginutil.WriteProblemError(c, err, problems.EngineCircuitOpen.Newf(
+				"The execution engine %q has failed too many times in a row, and will not receive any new builds for another %s. The build with ID %d on stage %q on branch %q for project with ID %d was not triggered.",
+				engine.ID, circuitErr.retryAfter.Round(time.Second), dbBuild.BuildID, stageName, branch, projectID))
+			return
+		}
+
+		ginutil.WriteProblemError(c, err, problems.ProjectRunTrigger.Newf(
+			"Failed to trigger code execution engine to schedule the build with ID %d on stage %q on branch %q for project with ID %d.",
+			dbBuild.BuildID, stageName, branch, projectID))
 		return
 	}
 
 	if workerID != "" {
 		dbBuild.WorkerID = workerID
-		if saveErr := m.Database.Save(&dbBuild).Error; saveErr != nil {
-			ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+		if saveErr := saveBuildWorkerID(m.Database, dbBuild); saveErr != nil {
+			ginutil.WriteDBWriteError(c, saveErr, fmt.Sprintf(
 				"Failed saving worker ID %q for build on stage %q and branch %q for project with ID %d in database.",
 				workerID, stageName, branch, projectID))
 			return
 		}
 	}
 
-	renderJSON(c, http.StatusOK, modelconv.DBBuildToResponseBuildReferenceWrapper(dbBuild))
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildToResponseBuildReferenceWrapper(dbBuild))
 }
 
-func (m buildModule) SaveBuildParams(dbParams []database.BuildParam) error {
-	for _, dbParam := range dbParams {
-		if err := m.Database.Create(&dbParam).Error; err != nil {
-			return err
+// saveBuildParamsBatchSize is the maximum number of build parameters to insert
+// per SQL statement, to stay well below the limit of bound variables per
+// statement in SQLite and PostgreSQL.
+const saveBuildParamsBatchSize = 100
+
+func saveBuildParams(db *gorm.DB, dbParams []database.BuildParam) error {
+	if len(dbParams) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(&dbParams, saveBuildParamsBatchSize).Error
+}
+
+// encryptSecretBuildParams returns a copy of the build parameters where the
+// values of all secret parameters have been encrypted, so they can be stored in
+// the database. If no encryption key is configured, then the secret values are
+// left out instead.
+func (m buildModule) encryptSecretBuildParams(dbParams []database.BuildParam) ([]database.BuildParam, error) {
+	var box *secretbox.Box
+	if m.Config.DB.EncryptionKey != "" {
+		var err error
+		box, err = secretbox.New(m.Config.DB.EncryptionKey)
+		if err != nil {
+			return nil, err
 		}
 	}
-	return nil
+	encrypted := make([]database.BuildParam, len(dbParams))
+	copy(encrypted, dbParams)
+	for i, dbParam := range encrypted {
+		if !dbParam.IsSecret || dbParam.Value == "" {
+			continue
+		}
+		if box == nil {
+			log.Warn().
+				WithUint("build", dbParam.BuildID).
+				WithString("input", dbParam.Name).
+				Message("No database encryption key configured. Skipping storing value of secret build input.")
+			encrypted[i].Value = ""
+			continue
+		}
+		value, err := box.Encrypt(dbParam.Value)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt build param %q: %w", dbParam.Name, err)
+		}
+		encrypted[i].Value = value
+	}
+	return encrypted, nil
+}
+
+var errSecretBuildParamNotStored = errors.New("value of secret build input was not stored")
+
+// decryptSecretBuildParams returns a copy of the build parameters where the
+// values of all secret parameters have been decrypted. Returns an error
+// wrapping errSecretBuildParamNotStored if no encryption key is configured, as
+// the secret values were then never stored.
+func (m buildModule) decryptSecretBuildParams(dbParams []database.BuildParam) ([]database.BuildParam, error) {
+	var box *secretbox.Box
+	if m.Config.DB.EncryptionKey != "" {
+		var err error
+		box, err = secretbox.New(m.Config.DB.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	decrypted := make([]database.BuildParam, len(dbParams))
+	copy(decrypted, dbParams)
+	for i, dbParam := range decrypted {
+		if !dbParam.IsSecret {
+			continue
+		}
+		if box == nil {
+			return nil, fmt.Errorf("build param %q: %w", dbParam.Name, errSecretBuildParamNotStored)
+		}
+		if dbParam.Value == "" {
+			continue
+		}
+		value, err := box.Decrypt(dbParam.Value)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt build param %q: %w", dbParam.Name, err)
+		}
+		decrypted[i].Value = value
+	}
+	return decrypted, nil
 }
 
 func (m buildModule) engineLookup(id string) *response.Engine {
-	return lookupResponseEngineFromConfig(m.Config.CI, id)
+	return lookupResponseEngineFromConfig(m.Config.current().CI, id)
 }
 
-func parseDBBuildParams(buildID uint, buildDef []byte, vars []byte) ([]database.BuildParam, error) {
-	type BuildDefinition struct {
-		Inputs []struct {
-			Name    string
-			Type    string
-			Default string
-		}
+// invalidBuildInputsError is returned by parseDBBuildParams when the input
+// variables does not match the inputs declared in the build definition.
+type invalidBuildInputsError struct {
+	problems []string
+}
+
+func (err invalidBuildInputsError) Error() string {
+	return "invalid build inputs: " + strings.Join(err.problems, "; ")
+}
+
+func writeParseBuildParamsProblem(c *gin.Context, err error, stageName, branch string, projectID uint) {
+	var tooLargeErr buildVarsTooLargeError
+	if errors.As(err, &tooLargeErr) {
+		ginutil.WriteProblemError(c, err, problems.ProjectRunParamsTooLarge.Newf(
+			"The input variables are %d bytes when serialized, which is more than the maximum of %d bytes that can be sent to the execution engine, for build on stage %q and branch %q for project with ID %d.",
+			tooLargeErr.size, tooLargeErr.maxSize, stageName, branch, projectID))
+		return
 	}
+	var inputsErr invalidBuildInputsError
+	if errors.As(err, &inputsErr) {
+		ginutil.WriteProblemError(c, err, problems.ProjectRunInvalidInput.With(problem.Response{
+			Detail: fmt.Sprintf(
+				"One or more input variables does not match the inputs declared in the build definition, for build on stage %q and branch %q for project with ID %d.",
+				stageName, branch, projectID),
+			Errors: inputsErr.problems,
+		}))
+		return
+	}
+	ginutil.WriteProblemError(c, err, problems.ProjectRunParamsDeserialize.Newf(
+		"Failed to deserialize build parameters from request body for build on stage %q and branch %q for project with ID %d.",
+		stageName, branch, projectID))
+}
 
-	var def BuildDefinition
-	err := yaml.Unmarshal(buildDef, &def)
+func parseDBBuildParams(buildID uint, buildDef []byte, inputDefaults map[string]string, vars []byte) ([]database.BuildParam, error) {
+	def, err := builddef.Parse(string(buildDef))
 	if err != nil {
 		log.Error().WithError(err).Message("Failed unmarshaling build-def.")
 		return nil, err
@@ -788,91 +1751,109 @@ func parseDBBuildParams(buildID uint, buildDef []byte, vars []byte) ([]database.
 		Message("Unmarshaled build-def.")
 
 	m := make(request.BuildInputs)
-	err = json.Unmarshal(vars, &m)
-	if err != nil {
-		log.Error().WithError(err).Message("Failed unmarshaling input variables JSON.")
-		return nil, err
+	if len(bytes.TrimSpace(vars)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(vars))
+		dec.UseNumber()
+		if err := dec.Decode(&m); err != nil {
+			log.Error().WithError(err).Message("Failed unmarshaling input variables JSON.")
+			return nil, err
+		}
+	}
+
+	var problems []string
+	inputNames := make([]string, len(def.Inputs))
+	for i, input := range def.Inputs {
+		inputNames[i] = input.Name
+	}
+	postedNames := maps.Keys(m)
+	sort.Strings(postedNames)
+	for _, name := range postedNames {
+		if !slices.Contains(inputNames, name) {
+			problems = append(problems, fmt.Sprintf(
+				"unknown input %q, allowed inputs are: %s",
+				name, strings.Join(inputNames, ", ")))
+		}
 	}
 
 	var params []database.BuildParam
 	for _, input := range def.Inputs {
 		param := database.BuildParam{
-			Name:    input.Name,
-			BuildID: buildID,
+			Name:     input.Name,
+			BuildID:  buildID,
+			IsSecret: input.Type == "password",
 		}
 
 		if m[input.Name] == nil {
-			param.Value = input.Default
+			if inputDefault, ok := inputDefaults[input.Name]; ok {
+				value, err := coerceBuildInputValue(input, inputDefault)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("input %q: project default: %s", input.Name, err))
+					continue
+				}
+				param.Value = value
+			} else {
+				param.Value = formatBuildInputDefault(input.Default)
+			}
 		} else {
-			param.Value = fmt.Sprintf("%v", m[input.Name])
+			value, err := coerceBuildInputValue(input, m[input.Name])
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("input %q: %s", input.Name, err))
+				continue
+			}
+			param.Value = value
 		}
 
 		params = append(params, param)
 	}
 
+	if len(problems) > 0 {
+		return nil, invalidBuildInputsError{problems}
+	}
 	return params, nil
 }
 
-func triggerBuild(dbJobParams []database.Param, engine CIEngineConfig) (string, error) {
-	u, err := url.Parse(engine.URL)
-	if err != nil {
-		return "", fmt.Errorf("parse engine URL: %w", err)
-	}
-	q := url.Values{}
-	for _, dbJobParam := range dbJobParams {
-		if dbJobParam.Value != "" {
-			q.Set(dbJobParam.Name, dbJobParam.Value)
-		}
+func formatBuildInputDefault(value any) string {
+	if value == nil {
+		return ""
 	}
-	q.Set("token", engine.Token)
-	u.RawQuery = q.Encode()
-
-	redactedURL := *u
-	redactedURL.User = nil
-	q.Set("token", "~~redacted~~")
-	redactedURL.RawQuery = q.Encode()
-
-	log.Info().
-		WithString("method", "POST").
-		WithString("url", redactedURL.Redacted()).
-		Message("Triggering build.")
+	return fmt.Sprint(value)
+}
 
-	resp, err := http.Post(u.String(), "", nil)
-	if err != nil {
-		return "", err
+// coerceBuildInputValue validates an input variable value against the input's
+// declared type, and returns the value formatted as it should be stored and
+// passed on to the execution engine.
+func coerceBuildInputValue(input builddef.Input, value any) (string, error) {
+	switch value.(type) {
+	case string, bool, json.Number:
+	default:
+		return "", fmt.Errorf("expected a %s, but got %T", input.Type, value)
 	}
-
-	defer resp.Body.Close()
-	switch engine.API {
-	case CIEngineAPIWharfCMDv1:
-		if problem.IsHTTPResponse(resp) {
-			prob, err := problem.ParseHTTPResponse(resp)
-			if err != nil {
-				return "", fmt.Errorf("parse response as problem: %w", err)
-			}
-			return "", prob
+	str := fmt.Sprint(value)
+	switch input.Type {
+	case "number":
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return "", fmt.Errorf("expected a number, but got %q", str)
 		}
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return "", fmt.Errorf("non-2xx response: %s", resp.Status)
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case "boolean":
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return "", fmt.Errorf("expected a boolean, but got %q", str)
 		}
-		var worker struct {
-			WorkerID string `json:"workerId"`
+		return strconv.FormatBool(b), nil
+	case "choice":
+		allowed := make([]string, len(input.Values))
+		for i, v := range input.Values {
+			allowed[i] = fmt.Sprint(v)
 		}
-		dec := json.NewDecoder(resp.Body)
-		if err := dec.Decode(&worker); err != nil {
-			return "", fmt.Errorf("decode wharf-cmd.v1 response: %w", err)
+		if !slices.Contains(allowed, str) {
+			return "", fmt.Errorf("value %q is not allowed, allowed values are: %s",
+				str, strings.Join(allowed, ", "))
 		}
-		return worker.WorkerID, nil
-
+		return str, nil
 	default:
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				return "", err
-			}
-			return "", fmt.Errorf("non-2xx response: %s: %q", resp.Status, string(body))
-		}
-		return "", nil
+		return str, nil
 	}
 }
 
@@ -882,21 +1863,18 @@ func getDBJobParams(
 	dbBuildParams []database.BuildParam,
 	wharfInstanceID string,
 ) ([]database.Param, error) {
-	var err error
-	var v []byte
-	if len(dbBuildParams) > 0 {
-		m := make(map[string]any)
-
-		for _, input := range dbBuildParams {
-			m[input.Name] = input.Value
-		}
-
-		v, err = yaml.Marshal(m)
-		if err != nil {
-			log.Error().WithError(err).Message("Failed to marshal input variables YAML for build.")
-			return nil, err
+	varsType := "string"
+	for _, input := range dbBuildParams {
+		if input.IsSecret {
+			varsType = "password"
 		}
-	} else {
+	}
+	v, err := marshalBuildVars(dbBuildParams)
+	if err != nil {
+		log.Error().WithError(err).Message("Failed to marshal input variables YAML for build.")
+		return nil, err
+	}
+	if len(dbBuildParams) == 0 {
 		log.Debug().Message("Skipping input variables, nothing in body.")
 	}
 
@@ -910,11 +1888,14 @@ func getDBJobParams(
 		{Type: "string", Name: "REPO_GROUP", Value: strings.ToLower(dbProject.GroupName)},
 		{Type: "string", Name: "REPO_BRANCH", Value: dbBuild.GitBranch},
 		{Type: "string", Name: "GIT_BRANCH", Value: dbBuild.GitBranch},
+		{Type: "string", Name: "GIT_COMMIT", Value: dbBuild.GitCommit},
+		{Type: "string", Name: "GIT_TAG", Value: dbBuild.GitTag},
 		{Type: "string", Name: "RUN_STAGES", Value: dbBuild.Stage},
 		{Type: "string", Name: "BUILD_REF", Value: strconv.FormatUint(uint64(dbBuild.BuildID), 10)},
-		{Type: "string", Name: "VARS", Value: string(v)},
+		{Type: "string", Name: "BUILD_NUMBER", Value: strconv.FormatUint(uint64(dbBuild.BuildNumber), 10)},
+		{Type: varsType, Name: buildVarsJobParamName, Value: string(v)},
 		{Type: "string", Name: "GIT_FULLURL", Value: typ.Coal(dbProject.Overrides.GitURL, dbProject.GitURL)},
-		{Type: "string", Name: "GIT_TOKEN", Value: token},
+		{Type: "password", Name: "GIT_TOKEN", Value: token},
 		{Type: "string", Name: "WHARF_PROJECT_ID", Value: strconv.FormatUint(uint64(dbProject.ProjectID), 10)},
 		{Type: "string", Name: "WHARF_INSTANCE", Value: wharfInstanceID},
 	}
@@ -930,6 +1911,30 @@ func getDBJobParams(
 	return dbJobParams, nil
 }
 
+// setBuildTriggeredByFromContext sets the build's triggered-by fields based on
+// the authentication used in the HTTP request, if any.
+func setBuildTriggeredByFromContext(c *gin.Context, dbBuild *database.Build) {
+	dbBuild.TriggeredByType, dbBuild.TriggeredByName, dbBuild.TriggeredBySubject = getUserFromContext(c)
+}
+
+// getUserFromContext returns how the user of the request was authenticated,
+// together with their name and subject, if any. Calls to inbound webhooks are
+// identified by the webhook's caller instead.
+func getUserFromContext(c *gin.Context) (authType database.BuildTriggerType, name, subject string) {
+	if name := c.GetString(webhookCallerContextKey); name != "" {
+		return database.BuildTriggerWebhook, name, ""
+	}
+	if claims, ok := getOIDCClaims(c); ok {
+		return database.BuildTriggerOIDC,
+			getOIDCClaimString(claims, "email", "preferred_username", "upn", "name"),
+			getOIDCClaimString(claims, "sub")
+	}
+	if user := c.GetString(gin.AuthUserKey); user != "" {
+		return database.BuildTriggerBasicAuth, user, ""
+	}
+	return database.BuildTriggerUnknown, "", ""
+}
+
 func validateBuildExistsByID(c *gin.Context, db *gorm.DB, buildID uint, whenMsg string) bool {
 	return validateDatabaseObjExistsByID(c, db, &database.Build{}, buildID, "build", whenMsg)
 }
@@ -937,5 +1942,16 @@ func validateBuildExistsByID(c *gin.Context, db *gorm.DB, buildID uint, whenMsg
 func databaseBuildPreloaded(db *gorm.DB) *gorm.DB {
 	return db.Set("gorm:auto_preload", false).
 		Preload(database.BuildFields.TestResultSummaries).
-		Preload(database.BuildFields.Params)
+		Preload(database.BuildFields.Params).
+		Preload(database.BuildFields.Labels).
+		Preload(database.BuildFields.Links, func(db *gorm.DB) *gorm.DB {
+			return db.Order(database.BuildLinkColumns.BuildLinkID)
+		}).
+		Preload(database.BuildFields.StageResults, func(db *gorm.DB) *gorm.DB {
+			return db.Order(database.BuildStageResultColumns.BuildStageResultID)
+		}).
+		// Only the IDs are needed, to count the comments.
+		Preload(database.BuildFields.Comments, func(db *gorm.DB) *gorm.DB {
+			return db.Select(database.BuildCommentColumns.BuildCommentID, database.BuildCommentColumns.BuildID)
+		})
 }
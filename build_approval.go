@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+// getApprovalListHandler godoc
+// @id getApprovalList
+// @summary Get list of builds awaiting approval.
+// @description Lists builds that target protected environments, and that must
+// @description be approved or rejected before they are sent to the execution
+// @description engine. Ordered by oldest first.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
+// @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param projectId query uint false "Filter by project ID." minimum(0)
+// @param environment query string false "Filter by verbatim build environment."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedBuilds
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /approval [get]
+func (m buildModule) getApprovalListHandler(c *gin.Context) {
+	var params = struct {
+		commonGetQueryParams
+
+		ProjectID   *uint   `form:"projectId"`
+		Environment *string `form:"environment"`
+	}{
+		commonGetQueryParams: defaultCommonGetQueryParams,
+	}
+	if !bindCommonGetQueryParams(c, &params) {
+		return
+	}
+
+	var where wherefields.Collection
+	query := databaseBuildPreloaded(m.Database).
+		Where(&database.Build{
+			StatusID:    database.BuildAwaitingApproval,
+			ProjectID:   where.Uint(database.BuildFields.ProjectID, params.ProjectID),
+			Environment: where.NullStringEmptyNull(database.BuildFields.Environment, params.Environment),
+		}, append(where.NonNilFieldNames(), database.BuildFields.StatusID)...).
+		Scopes(whereBuildInstanceScope(c)).
+		Order(database.BuildColumns.BuildID)
+
+	var dbBuilds []database.Build
+	var totalCount int64
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbBuilds, &totalCount)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching list of builds awaiting approval from database.")
+		return
+	}
+
+	ginrender.Response(c, http.StatusOK, response.PaginatedBuilds{
+		List:       modelconv.DBBuildsToResponses(dbBuilds, m.engineLookup),
+		TotalCount: totalCount,
+	})
+}
+
+// approveBuildHandler godoc
+// @id approveBuild
+// @summary Approve a build that targets a protected environment.
+// @description Records who approved the build, and then sends the build to
+// @description the execution engine. Builds that were started with a
+// @description scheduled time that has not yet passed instead get the
+// @description `Pending` status, and are sent at the scheduled time.
+// @description Only builds with the `AwaitingApproval` status can be approved.
+// @description Added in v5.3.0.
+// @tags build
+// @accept json
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param review body request.BuildReview false "Optional comment"
+// @param async query bool false "Trigger the build in the background, and respond without waiting for the execution engine."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildReferenceWrapper "Build approved and scheduled"
+// @success 202 {object} response.BuildReferenceWrapper "Build approved, and will be triggered in the background or at its scheduled time"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 409 {object} problem.Response "Build is not awaiting approval"
+// @failure 502 {object} problem.Response "Database or code execution engine is unreachable"
+// @failure 503 {object} problem.Response "Code execution engine has failed too many times in a row, or too many builds are waiting to be triggered"
+// @router /build/{buildId}/approve [post]
+func (m buildModule) approveBuildHandler(c *gin.Context) {
+	var params = struct {
+		Async bool `form:"async"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	dbBuild, reqReview, ok := m.fetchBuildToReview(c, "approved")
+	if !ok {
+		return
+	}
+	engine, dbJobParams, ok := m.loadStoredBuildJob(c, dbBuild, "when approving build")
+	if !ok {
+		return
+	}
+	if !m.saveBuildReview(c, &dbBuild, reqReview, database.BuildApprovalApproved) {
+		return
+	}
+	m.triggerBuildAndRespond(c, dbBuild, dbJobParams, engine, params.Async)
+}
+
+// rejectBuildHandler godoc
+// @id rejectBuild
+// @summary Reject a build that targets a protected environment.
+// @description Records who rejected the build, and sets the build's status to
+// @description `Failed`. The build is never sent to the execution engine.
+// @description Only builds with the `AwaitingApproval` status can be rejected.
+// @description Added in v5.3.0.
+// @tags build
+// @accept json
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param review body request.BuildReview false "Optional comment"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Build "Rejected build"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 409 {object} problem.Response "Build is not awaiting approval"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/reject [post]
+func (m buildModule) rejectBuildHandler(c *gin.Context) {
+	dbBuild, reqReview, ok := m.fetchBuildToReview(c, "rejected")
+	if !ok {
+		return
+	}
+	if !m.saveBuildReview(c, &dbBuild, reqReview, database.BuildApprovalRejected) {
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildToResponse(dbBuild, m.engineLookup))
+}
+
+func (m buildModule) fetchBuildToReview(c *gin.Context, verb string) (database.Build, request.BuildReview, bool) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return database.Build{}, request.BuildReview{}, false
+	}
+	var reqReview request.BuildReview
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&reqReview); err != nil {
+			writeInvalidBindError(c, err,
+				"One or more parameters failed to parse when reading the request body for build review.")
+			return database.Build{}, request.BuildReview{}, false
+		}
+	}
+
+	dbBuild, err := m.getBuild(buildID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Build with ID %d was not found.",
+			buildID))
+		return database.Build{}, request.BuildReview{}, false
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching build with ID %d from database.",
+			buildID))
+		return database.Build{}, request.BuildReview{}, false
+	}
+
+	if dbBuild.StatusID != database.BuildAwaitingApproval {
+		ginutil.WriteProblem(c, problems.BuildApprovalInvalidStatus.Newf(
+			"Only builds with the status %q can be %s, but build with ID %d has the status %q.",
+			response.BuildAwaitingApproval, verb, buildID,
+			modelconv.DBBuildStatusToResponse(dbBuild.StatusID)))
+		return database.Build{}, request.BuildReview{}, false
+	}
+	return dbBuild, reqReview, true
+}
+
+// saveBuildReview records the approval or rejection of the build, and
+// updates the build's status accordingly. Fails with a problem response if
+// the build was reviewed by someone else in the meantime.
+func (m buildModule) saveBuildReview(c *gin.Context, dbBuild *database.Build, reqReview request.BuildReview, approval database.BuildApprovalStatus) bool {
+	now := nowUTC(m.Clock)
+	statusBefore := dbBuild.StatusID
+	if approval == database.BuildApprovalApproved {
+		dbBuild.StatusID = database.BuildScheduling
+		// Builds approved before their scheduled time wait for it.
+		if dbBuild.ScheduleAt.Valid && dbBuild.ScheduleAt.Time.After(now) {
+			dbBuild.StatusID = database.BuildPending
+		}
+	} else {
+		dbBuild.StatusID = database.BuildFailed
+		dbBuild.CompletedOn = null.TimeFrom(now)
+	}
+	dbBuild.ApprovalStatus = approval
+	dbBuild.ReviewedByType, dbBuild.ReviewedByName, dbBuild.ReviewedBySubject = getUserFromContext(c)
+	dbBuild.ReviewedOn = null.TimeFrom(now)
+	dbBuild.ReviewComment = reqReview.Comment
+
+	// Only updated if still awaiting approval, so the same build cannot be
+	// both approved and rejected by concurrent requests.
+	var updated bool
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(dbBuild).
+			Where(database.BuildColumns.StatusID+" = ?", database.BuildAwaitingApproval).
+			Select(
+				database.BuildFields.StatusID,
+				database.BuildFields.CompletedOn,
+				database.BuildFields.ApprovalStatus,
+				database.BuildFields.ReviewedByType,
+				database.BuildFields.ReviewedByName,
+				database.BuildFields.ReviewedBySubject,
+				database.BuildFields.ReviewedOn,
+				database.BuildFields.ReviewComment,
+			).
+			Updates(dbBuild)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		updated = true
+		actor := buildStatusActor{
+			actorType: dbBuild.ReviewedByType,
+			name:      dbBuild.ReviewedByName,
+			subject:   dbBuild.ReviewedBySubject,
+		}
+		return saveBuildStatusTransition(tx, *dbBuild, null.IntFrom(int64(statusBefore)),
+			actor, dbBuild.ReviewComment, now)
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed saving review of build with ID %d in database.",
+			dbBuild.BuildID))
+		return false
+	}
+	if !updated {
+		ginutil.WriteProblem(c, problems.BuildApprovalInvalidStatus.Newf(
+			"Build with ID %d was approved or rejected by someone else in the meantime.",
+			dbBuild.BuildID))
+		return false
+	}
+
+	requestLog(c).Info().
+		WithUint("build", dbBuild.BuildID).
+		WithString("approval", string(approval)).
+		WithString("reviewedBy", dbBuild.ReviewedByName).
+		Message("Reviewed build.")
+	submitBuildStatusEvent(*dbBuild, statusBefore, "")
+	return true
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+type buildCommentModule struct {
+	Database *gorm.DB
+	// Idempotent is the middleware that handles the Idempotency-Key header on
+	// new comments.
+	Idempotent gin.HandlerFunc
+}
+
+func (m buildCommentModule) Register(g *gin.RouterGroup) {
+	g.GET("/comment", m.getBuildCommentListHandler)
+	g.POST("/comment", m.Idempotent, m.createBuildCommentHandler)
+	g.DELETE("/comment/:commentId", m.deleteBuildCommentHandler)
+}
+
+// getBuildCommentListHandler godoc
+// @id getBuildCommentList
+// @summary Get list of comments on a build.
+// @description Ordered by oldest first.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} []response.BuildComment
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/comment [get]
+func (m buildCommentModule) getBuildCommentListHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	if !validateBuildExistsByID(c, m.Database, buildID, "when fetching comments") {
+		return
+	}
+	var dbComments []database.BuildComment
+	err := m.Database.
+		Where(&database.BuildComment{BuildID: buildID}, database.BuildCommentFields.BuildID).
+		Order(database.BuildCommentColumns.BuildCommentID).
+		Find(&dbComments).
+		Error
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching comments of build with ID %d from database.",
+			buildID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildCommentsToResponses(dbComments))
+}
+
+// createBuildCommentHandler godoc
+// @id createBuildComment
+// @summary Add a comment to a build.
+// @description The author of the comment is based on how the request was
+// @description authenticated.
+// @description Added in v5.3.0.
+// @tags build
+// @accept json
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param comment body request.BuildComment true "Comment to add"
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 201 {object} response.BuildComment
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/comment [post]
+func (m buildCommentModule) createBuildCommentHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	var reqComment request.BuildComment
+	if err := c.ShouldBindJSON(&reqComment); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for build comment.")
+		return
+	}
+	if !validateBuildExistsByID(c, m.Database, buildID, "when adding comment") {
+		return
+	}
+	dbComment := database.BuildComment{
+		BuildID: buildID,
+		Text:    reqComment.Text,
+	}
+	dbComment.AuthorType, dbComment.AuthorName, dbComment.AuthorSubject = getUserFromContext(c)
+	if err := m.Database.Create(&dbComment).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed adding comment to build with ID %d in database.",
+			buildID))
+		return
+	}
+	requestLog(c).Info().
+		WithUint("build", buildID).
+		WithUint("comment", dbComment.BuildCommentID).
+		WithString("author", dbComment.AuthorName).
+		Message("Added comment to build.")
+	ginrender.Response(c, http.StatusCreated, modelconv.DBBuildCommentToResponse(dbComment))
+}
+
+// deleteBuildCommentHandler godoc
+// @id deleteBuildComment
+// @summary Delete a comment on a build.
+// @description Added in v5.3.0.
+// @tags build
+// @param buildId path uint true "Build ID" minimum(0)
+// @param commentId path uint true "Comment ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Comment not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/comment/{commentId} [delete]
+func (m buildCommentModule) deleteBuildCommentHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	commentID, ok := ginutil.ParseParamUint(c, "commentId")
+	if !ok {
+		return
+	}
+	var dbComment database.BuildComment
+	err := m.Database.
+		Where(&database.BuildComment{BuildID: buildID}, database.BuildCommentFields.BuildID).
+		First(&dbComment, commentID).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Comment with ID %d was not found on build with ID %d.",
+			commentID, buildID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching comment with ID %d on build with ID %d.",
+			commentID, buildID))
+		return
+	}
+	if err := m.Database.Delete(&dbComment).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting comment with ID %d from database.",
+			commentID))
+		return
+	}
+	requestLog(c).Info().
+		WithUint("build", buildID).
+		WithUint("comment", commentID).
+		Message("Deleted comment from build.")
+	c.Status(http.StatusNoContent)
+}
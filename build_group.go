@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+)
+
+// maxBuildMatrixSize is the maximum number of builds that can be started in a
+// single build matrix.
+const maxBuildMatrixSize = 100
+
+var errBuildMatrixAborted = errors.New("creating the rest of the build matrix failed")
+
+// startProjectBuildMatrixHandler godoc
+// @id startProjectBuildMatrix
+// @summary Start a matrix of builds of a project
+// @description Starts one build per combination of the given branches and
+// @description environments, all sharing the same build group. The builds are
+// @description triggered in the background, and their aggregated status can be
+// @description polled using the URL in the Location response header.
+// @description Builds targeting protected environments are not triggered until
+// @description they have been approved.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "Project ID" minimum(0)
+// @param stage query string false "Name of stage to run, or specify `ALL` to run all stages." default(ALL)
+// @param branch query []string false "Branch names. Uses project's default branch if omitted."
+// @param environment query []string false "Environment names. If omitted, each branch is built once without any environment filters."
+// @param engine query string false "Execution engine ID. Must be one of the project's allowed execution engines, if it has any."
+// @param inputs body request.BuildInputs _ "Input variable values, shared by all builds in the matrix. Map of variable names (as defined in the project's `.wharf-ci.yml` file) as keys paired with their string, boolean, or numeric value."
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 202 {object} response.BuildGroup "Builds created, and will be triggered in the background"
+// @header 202 {string} Location "URL of the build group"
+// @failure 400 {object} problem.Response "Bad request, such as invalid body JSON or input variables, an execution engine that the project is not allowed to use, or too many builds in the matrix"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project was not found"
+// @failure 409 {object} problem.Response "Request with the same idempotency key is still being handled"
+// @failure 422 {object} problem.Response "Idempotency key was already used for a different request"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/build/matrix [post]
+func (m buildModule) startProjectBuildMatrixHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var params = struct {
+		Stage        string   `form:"stage"`
+		Branches     []string `form:"branch"`
+		Environments []string `form:"environment"`
+		Engine       string   `form:"engine"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	if params.Stage == "" {
+		params.Stage = "ALL"
+	}
+
+	engine, ok := m.lookupEngineOrWriteProblem(c, params.Engine)
+	if !ok {
+		return
+	}
+
+	dbProject, ok := fetchProjectByID(c, m.Database, projectID, "when starting a new build matrix")
+	if !ok || !validateProjectNotArchived(c, dbProject) ||
+		!validateProjectEngineAllowed(c, m.Database, projectID, engine) {
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		ginutil.WriteBodyReadError(c, err, fmt.Sprintf(
+			"Failed to read the input variables body when starting a new build matrix for project with ID %d.",
+			projectID))
+		return
+	}
+
+	branches := uniqueStrings(params.Branches)
+	if len(branches) == 0 {
+		b, ok := findDefaultBranch(dbProject.Branches)
+		if !ok {
+			ginutil.WriteDBNotFound(c, fmt.Sprintf(
+				"No branches to build for project with ID %d were specified, and no default branch was found on the project.",
+				projectID))
+			return
+		}
+		branches = []string{b.Name}
+	}
+	environments := []null.String{{}}
+	if envNames := uniqueStrings(params.Environments); len(envNames) > 0 {
+		environments = make([]null.String, len(envNames))
+		for i, envName := range envNames {
+			environments[i] = null.StringFrom(envName)
+		}
+	}
+	if size := len(branches) * len(environments); size > maxBuildMatrixSize {
+		err := fmt.Errorf("build matrix too large: max %d builds, but was: %d", maxBuildMatrixSize, size)
+		ginutil.WriteInvalidParamError(c, err, "branch", fmt.Sprintf(
+			"The build matrix of %d branches and %d environments would start %d builds, but must not start more than %d builds.",
+			len(branches), len(environments), size, maxBuildMatrixSize))
+		return
+	}
+
+	dbBuildGroup := database.BuildGroup{ProjectID: projectID}
+	if err := m.Database.Create(&dbBuildGroup).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed creating build group for project with ID %d in database.",
+			projectID))
+		return
+	}
+
+	var jobs []buildTriggerJob
+	for _, branch := range branches {
+		for _, env := range environments {
+			dbBuild, dbJobParams, ok := m.createBuild(c, dbProject, newBuildOptions{
+				stage:        params.Stage,
+				branch:       branch,
+				environment:  env,
+				engine:       engine,
+				buildGroupID: null.IntFrom(int64(dbBuildGroup.BuildGroupID)),
+				inputs:       body,
+			})
+			if !ok {
+				for _, job := range jobs {
+					if saveErr := saveBuildTriggerFailed(m.Database, &job.dbBuild, errBuildMatrixAborted); saveErr != nil {
+						c.Error(saveErr)
+					}
+				}
+				return
+			}
+			jobs = append(jobs, buildTriggerJob{dbBuild, dbJobParams, engine, getRequestID(c)})
+		}
+	}
+
+	if m.Config.CI.MockTriggerResponse {
+		requestLog(c).Info().Message("Setting for mocking build triggers was true, mocking CI response.")
+	} else {
+		for _, job := range jobs {
+			if job.dbBuild.StatusID == database.BuildAwaitingApproval {
+				continue
+			}
+			if err := m.Dispatcher.enqueue(job); err != nil {
+				if saveErr := saveBuildTriggerFailed(m.Database, &job.dbBuild, err); saveErr != nil {
+					c.Error(saveErr)
+				}
+			}
+		}
+	}
+
+	dbBuilds, err := m.getBuildGroupBuilds(dbBuildGroup.BuildGroupID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching builds of build group with ID %d from database.",
+			dbBuildGroup.BuildGroupID))
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/build-group/%d", dbBuildGroup.BuildGroupID))
+	ginrender.Response(c, http.StatusAccepted, modelconv.DBBuildGroupToResponse(dbBuildGroup, dbBuilds, m.engineLookup))
+}
+
+// getBuildGroupHandler godoc
+// @id getBuildGroup
+// @summary Get a build group and its builds
+// @description The status of the build group is aggregated from the statuses
+// @description of its builds.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param buildGroupId path uint true "Build group ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildGroup
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build group not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build-group/{buildGroupId} [get]
+func (m buildModule) getBuildGroupHandler(c *gin.Context) {
+	buildGroupID, ok := ginutil.ParseParamUint(c, "buildGroupId")
+	if !ok {
+		return
+	}
+	var dbBuildGroup database.BuildGroup
+	if !fetchDatabaseObjByID(c, m.Database, &dbBuildGroup, buildGroupID, "build group", "when fetching build group") {
+		return
+	}
+	dbBuilds, err := m.getBuildGroupBuilds(buildGroupID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching builds of build group with ID %d from database.",
+			buildGroupID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildGroupToResponse(dbBuildGroup, dbBuilds, m.engineLookup))
+}
+
+func (m buildModule) getBuildGroupBuilds(buildGroupID uint) ([]database.Build, error) {
+	var dbBuilds []database.Build
+	err := databaseBuildPreloaded(m.Database).
+		Where(&database.Build{BuildGroupID: null.IntFrom(int64(buildGroupID))},
+			database.BuildFields.BuildGroupID).
+		Order(database.BuildColumns.BuildID).
+		Find(&dbBuilds).
+		Error
+	return dbBuilds, err
+}
+
+// uniqueStrings returns the non-empty strings without duplicates, in the order
+// they first appear.
+func uniqueStrings(values []string) []string {
+	var unique []string
+	seen := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok || v == "" {
+			continue
+		}
+		seen[v] = struct{}{}
+		unique = append(unique, v)
+	}
+	return unique
+}
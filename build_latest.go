@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// latestBuildQueryParams are the query parameters shared by the
+// latest-build endpoints.
+type latestBuildQueryParams struct {
+	Stage  *string `form:"stage"`
+	Status *string `form:"status"`
+}
+
+// getLatestProjectBuildHandler godoc
+// @id getLatestProjectBuild
+// @summary Get the latest build of a project.
+// @description Finds the most recent build of the project, optionally filtered
+// @description by branch, stage, and status.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param branch query string false "Filter by verbatim build Git branch."
+// @param stage query string false "Filter by verbatim build stage."
+// @param status query string false "Filter by build status name" enums(Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Build
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project or build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/build/latest [get]
+func (m buildModule) getLatestProjectBuildHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var params = struct {
+		latestBuildQueryParams
+		Branch *string `form:"branch"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	query, ok := m.whereLatestBuilds(c, databaseBuildPreloaded(m.Database), projectID, params.Branch, params.latestBuildQueryParams)
+	if !ok {
+		return
+	}
+
+	var dbBuild database.Build
+	err := query.
+		Order(database.BuildColumns.BuildID + " DESC").
+		First(&dbBuild).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Found no matching build for project with ID %d.",
+			projectID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching latest build of project with ID %d from database.",
+			projectID))
+		return
+	}
+
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildToResponse(dbBuild, m.engineLookup))
+}
+
+// getLatestProjectBuildPerBranchHandler godoc
+// @id getLatestProjectBuildPerBranch
+// @summary Get the latest build of each branch of a project.
+// @description Finds the most recent build of each Git branch of the project,
+// @description optionally filtered by stage and status. Ordered by branch name.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param stage query string false "Filter by verbatim build stage."
+// @param status query string false "Filter by build status name" enums(Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} []response.Build
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/build/latest-per-branch [get]
+func (m buildModule) getLatestProjectBuildPerBranchHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var params latestBuildQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	query, ok := m.whereLatestBuilds(c, m.Database.Model(&database.Build{}), projectID, nil, params)
+	if !ok {
+		return
+	}
+	latestBuildIDs := query.
+		Select(fmt.Sprintf("MAX(%s)", database.BuildColumns.BuildID)).
+		Group(database.BuildColumns.GitBranch)
+
+	var dbBuilds []database.Build
+	err := databaseBuildPreloaded(m.Database).
+		Where(fmt.Sprintf("%s IN (?)", database.BuildColumns.BuildID), latestBuildIDs).
+		Order(database.BuildColumns.GitBranch).
+		Find(&dbBuilds).
+		Error
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching latest builds per branch of project with ID %d from database.",
+			projectID))
+		return
+	}
+
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildsToResponses(dbBuilds, m.engineLookup))
+}
+
+// whereLatestBuilds adds conditions to the query to only match builds of the
+// project that match the branch, if not nil, and the parameters. Writes a
+// problem response and returns false if the project does not exist or the
+// parameters are invalid.
+func (m buildModule) whereLatestBuilds(c *gin.Context, query *gorm.DB, projectID uint, branch *string, params latestBuildQueryParams) (*gorm.DB, bool) {
+	var where wherefields.Collection
+	filter := database.Build{
+		ProjectID: projectID,
+		GitBranch: where.String(database.BuildFields.GitBranch, branch),
+		Stage:     where.String(database.BuildFields.Stage, params.Stage),
+	}
+	where.AddFieldName(database.BuildFields.ProjectID)
+	if params.Status != nil {
+		statusID, ok := parseBuildStatusOrWriteError(c, *params.Status, "status")
+		if !ok {
+			return nil, false
+		}
+		filter.StatusID = statusID
+		where.AddFieldName(database.BuildFields.StatusID)
+	}
+	if !validateDatabaseObjExistsByID(c, m.Database, &database.Project{}, projectID, "project", "when getting latest builds") {
+		return nil, false
+	}
+	return query.
+		Where(&filter, where.NonNilFieldNames()...).
+		Scopes(whereBuildInstanceScope(c)), true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+type buildLinkModule struct {
+	Database *gorm.DB
+	// Idempotent is the middleware that handles the Idempotency-Key header on
+	// new links.
+	Idempotent gin.HandlerFunc
+}
+
+func (m buildLinkModule) Register(g *gin.RouterGroup) {
+	g.POST("/link", m.Idempotent, m.createBuildLinkHandler)
+}
+
+// createBuildLinkHandler godoc
+// @id createBuildLink
+// @summary Add an external link to a build.
+// @description Such as a link to a test report, a deployment dashboard, or an
+// @description issue tracker. The links are included in the build response.
+// @description Added in v5.3.0.
+// @tags build
+// @accept json
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param link body request.BuildLink true "Link to add"
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 201 {object} response.BuildLink
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/link [post]
+func (m buildLinkModule) createBuildLinkHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	var reqLink request.BuildLink
+	if err := c.ShouldBindJSON(&reqLink); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for build link.")
+		return
+	}
+	if !validateBuildExistsByID(c, m.Database, buildID, "when adding link") {
+		return
+	}
+	dbLink := database.BuildLink{
+		BuildID: buildID,
+		Name:    reqLink.Name,
+		URL:     reqLink.URL,
+	}
+	if err := m.Database.Create(&dbLink).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed adding link to build with ID %d in database.",
+			buildID))
+		return
+	}
+	requestLog(c).Info().
+		WithUint("build", buildID).
+		WithUint("link", dbLink.BuildLinkID).
+		WithString("name", dbLink.Name).
+		Message("Added link to build.")
+	ginrender.Response(c, http.StatusCreated, modelconv.DBBuildLinkToResponse(dbLink))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"gorm.io/gorm"
+)
+
+// notificationRuleMatches returns true if the notification rule applies to the
+// build, which has stayed in its current status since the given time.
+func notificationRuleMatches(dbRule database.NotificationRule, dbBuild database.Build, since, now time.Time) bool {
+	if dbRule.ProjectID != nil && *dbRule.ProjectID != dbBuild.ProjectID {
+		return false
+	}
+	if dbRule.StatusID != dbBuild.StatusID {
+		return false
+	}
+	return now.Sub(since) >= time.Duration(dbRule.Threshold)*time.Second
+}
+
+// newBuildNotification returns the notification sent by the notification rule
+// about the build, which has stayed in its current status since the given
+// time.
+func newBuildNotification(dbRule database.NotificationRule, dbBuild database.Build, projectName string, since, now time.Time) response.BuildNotification {
+	duration := now.Sub(since)
+	threshold := time.Duration(dbRule.Threshold) * time.Second
+	status := modelconv.DBBuildStatusToResponse(dbBuild.StatusID)
+	return response.BuildNotification{
+		NotificationRuleID: dbRule.NotificationRuleID,
+		RuleName:           dbRule.Name,
+		BuildID:            dbBuild.BuildID,
+		ProjectID:          dbBuild.ProjectID,
+		ProjectName:        projectName,
+		Status:             status,
+		Since:              since,
+		Duration:           duration.Milliseconds(),
+		Threshold:          dbRule.Threshold,
+		Message: fmt.Sprintf(
+			"Build %d of project %s has had the status %s for %s, exceeding the threshold of %s of notification rule %q.",
+			dbBuild.BuildID, projectName, status, duration.Round(time.Second), threshold, dbRule.Name),
+	}
+}
+
+// notifyLongRunningBuilds sends the notifications of all notification rules
+// whose thresholds have been exceeded at the given time by builds that are
+// still in the Scheduling or Running status. Each build is notified about at
+// most once per notification rule.
+func (w *buildWatchdog) notifyLongRunningBuilds(now time.Time) error {
+	var dbRules []database.NotificationRule
+	if err := w.db.
+		Order(database.NotificationRuleColumns.NotificationRuleID).
+		Find(&dbRules).
+		Error; err != nil {
+		return fmt.Errorf("fetch notification rules: %w", err)
+	}
+	if len(dbRules) == 0 {
+		return nil
+	}
+	var dbBuilds []database.Build
+	if err := w.db.
+		Where(fmt.Sprintf("%s IN ?", database.BuildColumns.StatusID),
+			[]int{int(database.BuildScheduling), int(database.BuildRunning)}).
+		Order(database.BuildColumns.BuildID).
+		Find(&dbBuilds).
+		Error; err != nil {
+		return fmt.Errorf("fetch scheduling and running builds: %w", err)
+	}
+	if len(dbBuilds) == 0 {
+		return nil
+	}
+	buildIDs := make([]uint, len(dbBuilds))
+	for i, dbBuild := range dbBuilds {
+		buildIDs[i] = dbBuild.BuildID
+	}
+	var dbSent []database.BuildNotification
+	if err := w.db.
+		Where(fmt.Sprintf("%s IN ?", database.BuildNotificationColumns.BuildID), buildIDs).
+		Find(&dbSent).
+		Error; err != nil {
+		return fmt.Errorf("fetch sent build notifications: %w", err)
+	}
+	type ruleBuildPair struct{ ruleID, buildID uint }
+	sent := make(map[ruleBuildPair]struct{}, len(dbSent))
+	for _, dbNotification := range dbSent {
+		sent[ruleBuildPair{dbNotification.NotificationRuleID, dbNotification.BuildID}] = struct{}{}
+	}
+
+	projectNames := map[uint]string{}
+	for _, dbBuild := range dbBuilds {
+		since, ok := buildStatusSince(dbBuild)
+		if !ok {
+			continue
+		}
+		for _, dbRule := range dbRules {
+			if _, ok := sent[ruleBuildPair{dbRule.NotificationRuleID, dbBuild.BuildID}]; ok {
+				continue
+			}
+			if !notificationRuleMatches(dbRule, dbBuild, since, now) {
+				continue
+			}
+			projectName, ok := projectNames[dbBuild.ProjectID]
+			if !ok {
+				var err error
+				projectName, err = findProjectName(w.db, dbBuild.ProjectID)
+				if err != nil {
+					return fmt.Errorf("fetch name of project with ID %d: %w", dbBuild.ProjectID, err)
+				}
+				projectNames[dbBuild.ProjectID] = projectName
+			}
+			n := newBuildNotification(dbRule, dbBuild, projectName, since, now)
+			if err := w.sendBuildNotification(dbRule, n, now); err != nil {
+				log.Warn().
+					WithError(err).
+					WithUint("build", dbBuild.BuildID).
+					WithUint("notificationRule", dbRule.NotificationRuleID).
+					Message("Failed to send build notification.")
+				continue
+			}
+			log.Info().
+				WithUint("build", dbBuild.BuildID).
+				WithUint("notificationRule", dbRule.NotificationRuleID).
+				WithString("notifier", string(dbRule.Notifier)).
+				Message("Sent build notification.")
+		}
+	}
+	return nil
+}
+
+// sendBuildNotification records the notification as sent before sending it,
+// so that notifications are not sent twice, such as by other wharf-api
+// instances. Notifications that fail to send are not retried, but their error
+// is recorded.
+func (w *buildWatchdog) sendBuildNotification(dbRule database.NotificationRule, n response.BuildNotification, now time.Time) error {
+	dbNotification := database.BuildNotification{
+		NotificationRuleID: dbRule.NotificationRuleID,
+		BuildID:            n.BuildID,
+		SentOn:             now,
+	}
+	if err := w.db.Create(&dbNotification).Error; err != nil {
+		return fmt.Errorf("record build notification: %w", err)
+	}
+	sendErr := w.notify(dbRule, n)
+	if sendErr == nil {
+		return nil
+	}
+	dbNotification.Error = truncateString(sendErr.Error(), database.BuildNotificationSizes.Error)
+	if err := w.db.
+		Model(&dbNotification).
+		Select(database.BuildNotificationFields.Error).
+		Updates(&dbNotification).
+		Error; err != nil {
+		log.Warn().
+			WithError(err).
+			WithUint("build", n.BuildID).
+			WithUint("notificationRule", dbRule.NotificationRuleID).
+			Message("Failed to record error of build notification.")
+	}
+	return sendErr
+}
+
+func (w *buildWatchdog) notify(dbRule database.NotificationRule, n response.BuildNotification) error {
+	notifier, ok := w.notifiers[dbRule.Notifier]
+	if !ok {
+		return fmt.Errorf("notifier %q is not configured", dbRule.Notifier)
+	}
+	return notifier.notify(dbRule.Target, n)
+}
+
+func findProjectName(db *gorm.DB, projectID uint) (string, error) {
+	var dbProject database.Project
+	if err := db.
+		Select(database.ProjectFields.ProjectID, database.ProjectFields.Name).
+		First(&dbProject, projectID).
+		Error; err != nil {
+		return "", err
+	}
+	return dbProject.Name, nil
+}
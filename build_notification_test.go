@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+type fakeNotifier struct {
+	targets []string
+	sent    []response.BuildNotification
+	err     error
+}
+
+func (n *fakeNotifier) notify(target string, notification response.BuildNotification) error {
+	n.targets = append(n.targets, target)
+	n.sent = append(n.sent, notification)
+	return n.err
+}
+
+func TestBuildWatchdog_notifyLongRunningBuilds(t *testing.T) {
+	m, dbProject := newTestInsertBuildModule(t)
+	dbOtherProject := database.Project{Name: "other"}
+	require.NoError(t, m.Database.Create(&dbOtherProject).Error)
+	startedOn := time.Date(2022, 5, 10, 10, 0, 0, 0, time.UTC)
+	dbBuild := database.Build{
+		ProjectID: dbProject.ProjectID,
+		StatusID:  database.BuildRunning,
+		StartedOn: null.TimeFrom(startedOn),
+	}
+	require.NoError(t, m.Database.Create(&dbBuild).Error)
+
+	dbRules := []database.NotificationRule{
+		{Name: "all projects", StatusID: database.BuildRunning, Threshold: 3600, Notifier: database.NotifierWebhook, Target: "https://example.com/all"},
+		{Name: "this project", ProjectID: &dbProject.ProjectID, StatusID: database.BuildRunning, Threshold: 60, Notifier: database.NotifierSlack, Target: "https://example.com/this"},
+		{Name: "other project", ProjectID: &dbOtherProject.ProjectID, StatusID: database.BuildRunning, Threshold: 60, Notifier: database.NotifierWebhook, Target: "https://example.com/other"},
+		{Name: "scheduling", StatusID: database.BuildScheduling, Threshold: 60, Notifier: database.NotifierWebhook, Target: "https://example.com/scheduling"},
+	}
+	require.NoError(t, m.Database.Create(&dbRules).Error)
+
+	webhook := &fakeNotifier{}
+	slack := &fakeNotifier{err: errors.New("slack is down")}
+	w := newBuildWatchdog(m.Database, CIConfig{}, map[database.NotifierType]notifier{
+		database.NotifierWebhook: webhook,
+		database.NotifierSlack:   slack,
+	}, m)
+
+	require.NoError(t, w.notifyLongRunningBuilds(startedOn.Add(30*time.Minute)))
+	assert.Empty(t, webhook.sent)
+	require.Len(t, slack.sent, 1)
+	assert.Equal(t, []string{"https://example.com/this"}, slack.targets)
+	n := slack.sent[0]
+	assert.Equal(t, dbBuild.BuildID, n.BuildID)
+	assert.Equal(t, "wharf-api", n.ProjectName)
+	assert.Equal(t, response.BuildRunning, n.Status)
+	assert.Equal(t, (30 * time.Minute).Milliseconds(), n.Duration)
+	assert.Equal(t, `Build 1 of project wharf-api has had the status Running for 30m0s, exceeding the threshold of 1m0s of notification rule "this project".`, n.Message)
+
+	require.NoError(t, w.notifyLongRunningBuilds(startedOn.Add(2*time.Hour)))
+	require.Len(t, webhook.sent, 1)
+	assert.Equal(t, []string{"https://example.com/all"}, webhook.targets)
+	assert.Len(t, slack.sent, 1, "failed notifications must not be retried")
+
+	var dbNotifications []database.BuildNotification
+	require.NoError(t, m.Database.Order(database.BuildNotificationColumns.BuildID).Find(&dbNotifications).Error)
+	require.Len(t, dbNotifications, 2)
+	errorsByRuleID := map[uint]string{}
+	for _, dbNotification := range dbNotifications {
+		errorsByRuleID[dbNotification.NotificationRuleID] = dbNotification.Error
+	}
+	assert.Equal(t, map[uint]string{
+		dbRules[0].NotificationRuleID: "",
+		dbRules[1].NotificationRuleID: "slack is down",
+	}, errorsByRuleID)
+}
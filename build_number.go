@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// nextBuildNumber increments and returns the last build number of the
+// project. Must be called in the same transaction that creates the build, as
+// the update locks the project's row until the transaction ends, so that
+// concurrent builds of the same project get different numbers.
+func nextBuildNumber(tx *gorm.DB, projectID uint) (uint, error) {
+	// Raw SQL, as the column is read-only to GORM after the project is
+	// created.
+	result := tx.Exec(fmt.Sprintf("UPDATE %[1]s SET %[2]s = %[2]s + 1 WHERE %[3]s = ?",
+		database.ProjectTable, database.ProjectColumns.LastBuildNumber, database.ProjectColumns.ProjectID),
+		projectID)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, fmt.Errorf("project with ID %d: %w", projectID, gorm.ErrRecordNotFound)
+	}
+	var number uint
+	if err := tx.
+		Model(&database.Project{}).
+		Select(database.ProjectColumns.LastBuildNumber).
+		Where(&database.Project{ProjectID: projectID}).
+		Row().
+		Scan(&number); err != nil {
+		return 0, err
+	}
+	return number, nil
+}
+
+// getProjectBuildByNumberHandler godoc
+// @id getProjectBuildByNumber
+// @summary Finds build by its number within the project.
+// @description Build numbers start at 1 for each project, and increase by one
+// @description with each build of the project, as opposed to build IDs, which
+// @description are shared by all projects.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param buildNumber path uint true "build number" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Build
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project or build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/build/number/{buildNumber} [get]
+func (m buildModule) getProjectBuildByNumberHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	buildNumber, ok := ginutil.ParseParamUint(c, "buildNumber")
+	if !ok {
+		return
+	}
+	if !validateDatabaseObjExistsByID(c, m.Database, &database.Project{}, projectID, "project", "when getting build by number") {
+		return
+	}
+
+	var dbBuild database.Build
+	err := databaseBuildPreloaded(m.Database).
+		Where(&database.Build{ProjectID: projectID, BuildNumber: buildNumber},
+			database.BuildFields.ProjectID, database.BuildFields.BuildNumber).
+		Scopes(whereBuildInstanceScope(c)).
+		First(&dbBuild).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Build with number %d was not found in project with ID %d.",
+			buildNumber, projectID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching build with number %d of project with ID %d from database.",
+			buildNumber, projectID))
+		return
+	}
+
+	expiries, err := findBuildExpiries(m.Database, m.Config.CI, []database.Build{dbBuild})
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching timeouts of build with ID %d from database.",
+			dbBuild.BuildID))
+		return
+	}
+
+	resBuild := modelconv.DBBuildToResponse(dbBuild, m.engineLookup)
+	setBuildExpiresOn(&resBuild, expiries)
+	ginrender.Response(c, http.StatusOK, resBuild)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertBuild_assignsBuildNumberPerProject(t *testing.T) {
+	m, dbProject := newTestInsertBuildModule(t)
+	dbOtherProject := database.Project{Name: "wharf-web"}
+	require.NoError(t, m.Database.Create(&dbOtherProject).Error)
+
+	insert := func(dbProject database.Project) (database.Build, []database.Param) {
+		dbBuild := database.Build{ProjectID: dbProject.ProjectID, GitBranch: "master", Stage: "ALL"}
+		dbJobParams, err := m.insertBuild(&dbBuild, dbProject, CIEngineConfig{ID: "jenkins"}, nil)
+		require.NoError(t, err)
+		return dbBuild, dbJobParams
+	}
+
+	first, _ := insert(dbProject)
+	other, _ := insert(dbOtherProject)
+	// Saving the project loaded before the builds were started must not
+	// reset its build number.
+	require.NoError(t, m.Database.Save(&dbProject).Error)
+	second, dbJobParams := insert(dbProject)
+
+	assert.Equal(t, uint(1), first.BuildNumber)
+	assert.Equal(t, uint(1), other.BuildNumber)
+	assert.Equal(t, uint(2), second.BuildNumber)
+	assert.Contains(t, dbJobParams, database.Param{Type: "string", Name: "BUILD_NUMBER", Value: "2"})
+}
+
+func TestMigrateAddBuildNumber(t *testing.T) {
+	db := newTestSqliteDB(t)
+	dbProjects := []database.Project{{Name: "wharf-api"}, {Name: "wharf-web"}, {Name: "wharf-cmd"}}
+	require.NoError(t, db.Create(&dbProjects).Error)
+	dbBuilds := []database.Build{
+		{ProjectID: dbProjects[0].ProjectID},
+		{ProjectID: dbProjects[1].ProjectID},
+		{ProjectID: dbProjects[0].ProjectID},
+	}
+	require.NoError(t, db.Create(&dbBuilds).Error)
+	require.NoError(t, db.Migrator().DropColumn(&database.Build{}, database.BuildFields.BuildNumber))
+	require.NoError(t, db.Migrator().DropColumn(&database.Project{}, database.ProjectFields.LastBuildNumber))
+
+	require.NoError(t, migrateAddBuildNumber(db))
+
+	var gotBuilds []database.Build
+	require.NoError(t, db.Order(database.BuildColumns.BuildID).Find(&gotBuilds).Error)
+	require.Len(t, gotBuilds, 3)
+	assert.Equal(t, uint(1), gotBuilds[0].BuildNumber)
+	assert.Equal(t, uint(1), gotBuilds[1].BuildNumber)
+	assert.Equal(t, uint(2), gotBuilds[2].BuildNumber)
+
+	var gotProjects []database.Project
+	require.NoError(t, db.Order(database.ProjectColumns.ProjectID).Find(&gotProjects).Error)
+	require.Len(t, gotProjects, 3)
+	assert.Equal(t, uint(2), gotProjects[0].LastBuildNumber)
+	assert.Equal(t, uint(1), gotProjects[1].LastBuildNumber)
+	assert.Equal(t, uint(0), gotProjects[2].LastBuildNumber)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+// cancelBuildHandler godoc
+// @id cancelBuild
+// @summary Cancel a build that is waiting for its scheduled time.
+// @description Deletes a build that was started with a scheduled time, before
+// @description it has been sent to the execution engine. Only builds with the
+// @description `Pending` status can be cancelled.
+// @description Added in v5.3.0.
+// @tags build
+// @param buildId path uint true "Build ID" minimum(0)
+// @success 204 "Cancelled"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 409 {object} problem.Response "Build is not pending, such as when it has already been sent to the execution engine"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId} [delete]
+func (m buildModule) cancelBuildHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+
+	var dbBuild database.Build
+	if err := m.Database.
+		Scopes(whereBuildInstanceScope(c)).
+		First(&dbBuild, buildID).
+		Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Build with ID %d was not found.",
+			buildID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching build with ID %d from database.",
+			buildID))
+		return
+	}
+
+	if dbBuild.StatusID != database.BuildPending {
+		ginutil.WriteProblem(c, problems.BuildCancelInvalidStatus.Newf(
+			"Only builds with the status %q can be cancelled, but build with ID %d has the status %q.",
+			response.BuildPending, buildID, modelconv.DBBuildStatusToResponse(dbBuild.StatusID)))
+		return
+	}
+
+	// Only deleted if still pending, so that builds that were sent to the
+	// execution engine in the meantime are kept.
+	result := m.Database.
+		Where(database.BuildColumns.StatusID+" = ?", database.BuildPending).
+		Delete(&dbBuild)
+	if result.Error != nil {
+		ginutil.WriteDBWriteError(c, result.Error, fmt.Sprintf(
+			"Failed deleting build with ID %d from database.",
+			buildID))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ginutil.WriteProblem(c, problems.BuildCancelInvalidStatus.Newf(
+			"Build with ID %d was sent to the execution engine in the meantime.",
+			buildID))
+		return
+	}
+	busEvents.publishBuildEvent(response.BusEventBuildDeleted, dbBuild)
+
+	requestLog(c).Info().
+		WithUint("build", buildID).
+		WithTime("scheduleAt", dbBuild.ScheduleAt.Time).
+		Message("Cancelled pending build.")
+	c.Status(http.StatusNoContent)
+}
+
+// buildScheduler sends builds that were started with a scheduled time to the
+// execution engine once their time has come.
+type buildScheduler struct {
+	db       *gorm.DB
+	interval time.Duration
+	builds   buildModule
+	clock    Clock
+}
+
+// newBuildScheduler creates a new build scheduler, using the same clock as the
+// build module.
+func newBuildScheduler(db *gorm.DB, config CIConfig, builds buildModule) *buildScheduler {
+	return &buildScheduler{
+		db:       db,
+		interval: config.ScheduleCheckInterval,
+		builds:   builds,
+		clock:    builds.Clock,
+	}
+}
+
+// start starts the background worker. The worker runs for the remaining
+// lifetime of the process.
+func (s *buildScheduler) start() {
+	go s.work()
+}
+
+func (s *buildScheduler) work() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.dispatchDueBuilds(nowUTC(s.clock)); err != nil {
+			log.Warn().
+				WithError(err).
+				Message("Failed to check for scheduled builds.")
+		}
+	}
+}
+
+// dispatchDueBuilds sends all pending builds whose scheduled time has passed
+// at the given time to the execution engine.
+func (s *buildScheduler) dispatchDueBuilds(now time.Time) error {
+	var dbBuilds []database.Build
+	if err := s.db.
+		Preload(database.BuildFields.Params).
+		Where(fmt.Sprintf("%s = ? AND %s <= ?",
+			database.BuildColumns.StatusID, database.BuildColumns.ScheduleAt),
+			database.BuildPending, now).
+		Order(database.BuildColumns.ScheduleAt).
+		Find(&dbBuilds).
+		Error; err != nil {
+		return fmt.Errorf("fetch pending builds: %w", err)
+	}
+	for _, dbBuild := range dbBuilds {
+		if err := s.dispatchBuild(dbBuild, now); err != nil {
+			log.Warn().
+				WithError(err).
+				WithUint("build", dbBuild.BuildID).
+				Message("Failed to dispatch scheduled build.")
+		}
+	}
+	return nil
+}
+
+// dispatchBuild sets the build's status to Scheduling, and then triggers the
+// build in the background. The build is skipped if it is no longer pending,
+// such as when it was cancelled or dispatched by another wharf-api instance
+// in the meantime. Builds whose job cannot be loaded get the TriggerFailed
+// status, so that they can be retriggered.
+func (s *buildScheduler) dispatchBuild(dbBuild database.Build, now time.Time) error {
+	var dbProject database.Project
+	if err := s.db.First(&dbProject, dbBuild.ProjectID).Error; err != nil {
+		return fmt.Errorf("fetch project: %w", err)
+	}
+	engine, dbJobParams, jobErr := s.builds.storedBuildJob(dbBuild, dbProject)
+
+	statusBefore := dbBuild.StatusID
+	dbBuild.StatusID = database.BuildScheduling
+	var updated bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&dbBuild).
+			Where(database.BuildColumns.StatusID+" = ?", database.BuildPending).
+			Select(database.BuildFields.StatusID).
+			Updates(&dbBuild)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		updated = true
+		return saveBuildStatusTransition(tx, dbBuild, null.IntFrom(int64(statusBefore)),
+			buildStatusActor{}, "", now)
+	})
+	if err != nil {
+		return fmt.Errorf("update status: %w", err)
+	}
+	if !updated {
+		return nil
+	}
+	submitBuildStatusEvent(dbBuild, statusBefore, "")
+
+	if jobErr != nil {
+		if err := saveBuildTriggerFailed(s.db, &dbBuild, jobErr); err != nil {
+			return fmt.Errorf("save trigger failure: %w", err)
+		}
+		return fmt.Errorf("load job: %w", jobErr)
+	}
+	log.Info().
+		WithUint("build", dbBuild.BuildID).
+		WithTime("scheduleAt", dbBuild.ScheduleAt.Time).
+		Message("Dispatching scheduled build.")
+	if s.builds.Config.CI.MockTriggerResponse {
+		log.Info().Message("Setting for mocking build triggers was true, mocking CI response.")
+		return nil
+	}
+	if err := s.builds.Dispatcher.enqueue(buildTriggerJob{
+		dbBuild:     dbBuild,
+		dbJobParams: dbJobParams,
+		engine:      engine,
+	}); err != nil {
+		if saveErr := saveBuildTriggerFailed(s.db, &dbBuild, err); saveErr != nil {
+			return fmt.Errorf("save trigger failure: %w", saveErr)
+		}
+		return err
+	}
+	return nil
+}
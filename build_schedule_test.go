@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func newTestScheduledBuild(t *testing.T, m buildModule, dbProject database.Project, scheduleAt time.Time) database.Build {
+	dbBuild := database.Build{
+		ProjectID:  dbProject.ProjectID,
+		GitBranch:  "master",
+		Stage:      "ALL",
+		ScheduleAt: null.TimeFrom(scheduleAt),
+	}
+	_, err := m.insertBuild(&dbBuild, dbProject, CIEngineConfig{ID: "mock"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, database.BuildPending, dbBuild.StatusID)
+	return dbBuild
+}
+
+func TestBuildScheduler_dispatchDueBuilds(t *testing.T) {
+	m, dbProject := newTestInsertBuildModule(t)
+	m.Config.CI.MockTriggerResponse = true
+	clock := &fakeClock{now: time.Date(2022, 5, 10, 10, 0, 0, 0, time.UTC)}
+	m.Clock = clock
+	dbBuild := newTestScheduledBuild(t, m, dbProject, clock.now.Add(time.Hour))
+	scheduler := newBuildScheduler(m.Database, m.Config.CI, m)
+
+	require.NoError(t, scheduler.dispatchDueBuilds(clock.now.Add(time.Minute)))
+	got, err := m.getBuild(dbBuild.BuildID)
+	require.NoError(t, err)
+	assert.Equal(t, database.BuildPending, got.StatusID, "must not be dispatched before its time")
+
+	dispatchedAt := clock.now.Add(time.Hour + time.Second)
+	require.NoError(t, scheduler.dispatchDueBuilds(dispatchedAt))
+	got, err = m.getBuild(dbBuild.BuildID)
+	require.NoError(t, err)
+	assert.Equal(t, database.BuildScheduling, got.StatusID)
+
+	dbTransitions, err := findBuildStatusTransitions(m.Database, dbBuild.BuildID)
+	require.NoError(t, err)
+	require.Len(t, dbTransitions, 2)
+	assert.Equal(t, null.IntFrom(int64(database.BuildPending)), dbTransitions[1].PreviousStatusID)
+	assert.Equal(t, dispatchedAt, dbTransitions[1].Timestamp.UTC())
+
+	// Already dispatched builds are not dispatched again.
+	require.NoError(t, scheduler.dispatchDueBuilds(dispatchedAt.Add(time.Minute)))
+	dbTransitions, err = findBuildStatusTransitions(m.Database, dbBuild.BuildID)
+	require.NoError(t, err)
+	assert.Len(t, dbTransitions, 2)
+}
+
+func TestCancelBuildHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, dbProject := newTestInsertBuildModule(t)
+	clock := &fakeClock{now: time.Date(2022, 5, 10, 10, 0, 0, 0, time.UTC)}
+	m.Clock = clock
+	dbPending := newTestScheduledBuild(t, m, dbProject, clock.now.Add(time.Hour))
+	dbScheduling := database.Build{ProjectID: dbProject.ProjectID, GitBranch: "master", Stage: "ALL"}
+	_, err := m.insertBuild(&dbScheduling, dbProject, CIEngineConfig{ID: "mock"}, nil)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.DELETE("/build/:buildId", m.cancelBuildHandler)
+	del := func(buildID uint) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/build/%d", buildID), nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusConflict, del(dbScheduling.BuildID).Code)
+	assert.Equal(t, http.StatusNoContent, del(dbPending.BuildID).Code)
+	assert.Contains(t, del(dbPending.BuildID).Body.String(), "/prob/api/record-not-found")
+}
+
+func TestBuildTimeoutsExpiresOn_countsFromScheduleAt(t *testing.T) {
+	scheduledOn := time.Date(2022, 5, 10, 10, 0, 0, 0, time.UTC)
+	dbBuild := database.Build{
+		StatusID:    database.BuildScheduling,
+		ScheduledOn: null.TimeFrom(scheduledOn),
+		ScheduleAt:  null.TimeFrom(scheduledOn.Add(24 * time.Hour)),
+	}
+	expiresOn, ok := buildTimeouts{scheduling: time.Minute}.expiresOn(dbBuild)
+	require.True(t, ok)
+	assert.Equal(t, scheduledOn.Add(24*time.Hour+time.Minute), expiresOn)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// setBuildStageResultStatusDate sets when the stage started or completed,
+// based on its status, as well as its duration once it has both started and
+// completed. The start date is kept if already set, so that repeated
+// "Running" updates do not move it.
+func setBuildStageResultStatusDate(dbResult *database.BuildStageResult, now time.Time) {
+	switch dbResult.StatusID {
+	case database.BuildRunning:
+		if !dbResult.StartedOn.Valid {
+			dbResult.StartedOn.SetValid(now)
+		}
+	case database.BuildCompleted, database.BuildFailed:
+		dbResult.CompletedOn.SetValid(now)
+	}
+	if dbResult.StartedOn.Valid && dbResult.CompletedOn.Valid {
+		dbResult.Duration.SetValid(dbResult.CompletedOn.Time.Sub(dbResult.StartedOn.Time).Milliseconds())
+	} else {
+		dbResult.Duration.Valid = false
+	}
+}
+
+// updateBuildStageStatusHandler godoc
+// @id updateBuildStageStatus
+// @summary Update the status of a stage of a build.
+// @description Used by execution engines to report when each stage of the
+// @description build starts and finishes, such as when finding the stage
+// @description markers in the build logs, so that the stages' start and
+// @description completion dates and durations can be shown as a timeline.
+// @description The stage is added to the build's `stageResults` on its first
+// @description update. Does not change the status of the build itself.
+// @description Added in v5.3.0.
+// @tags build
+// @accept json
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param stageName path string true "Stage name"
+// @param data body request.BuildStageStatusUpdate true "Stage status update"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildStageResult "Updated stage"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/stage/{stageName}/status [put]
+func (m buildModule) updateBuildStageStatusHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	stageName := c.Param("stageName")
+	if utf8.RuneCountInString(stageName) > database.BuildStageResultSizes.Name {
+		err := fmt.Errorf("stage name too long: %d characters", utf8.RuneCountInString(stageName))
+		ginutil.WriteInvalidParamError(c, err, "stageName", fmt.Sprintf(
+			"The stage name must not be longer than %d characters.",
+			database.BuildStageResultSizes.Name))
+		return
+	}
+	var reqUpdate request.BuildStageStatusUpdate
+	if err := c.ShouldBindJSON(&reqUpdate); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for build stage status update.")
+		return
+	}
+	dbStatus, ok := modelconv.ReqBuildStatusToDatabase(reqUpdate.Status)
+	if !ok || dbStatus == database.BuildTriggerFailed || dbStatus == database.BuildAwaitingApproval ||
+		dbStatus == database.BuildPending {
+		err := errors.New("invalid build stage status value")
+		ginutil.WriteInvalidParamError(c, err, "status", fmt.Sprintf(
+			"The new stage status %q is not a valid stage status value.",
+			reqUpdate.Status))
+		return
+	}
+	if utf8.RuneCountInString(reqUpdate.StatusMessage) > database.BuildStageResultSizes.StatusMessage {
+		err := fmt.Errorf("stage status message too long: %d characters", utf8.RuneCountInString(reqUpdate.StatusMessage))
+		writeInvalidBindError(c, err, fmt.Sprintf(
+			"The stage status message must not be longer than %d characters.",
+			database.BuildStageResultSizes.StatusMessage))
+		return
+	}
+	if !validateBuildExistsByID(c, m.Database, buildID, "when updating build stage status") {
+		return
+	}
+	now := nowUTC(m.Clock)
+	if reqUpdate.Timestamp != nil {
+		now = reqUpdate.Timestamp.UTC()
+	}
+
+	var dbResult database.BuildStageResult
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Where(&database.BuildStageResult{BuildID: buildID, Name: stageName},
+				database.BuildStageResultFields.BuildID, database.BuildStageResultFields.Name).
+			First(&dbResult).
+			Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		dbResult.BuildID = buildID
+		dbResult.Name = stageName
+		dbResult.StatusID = dbStatus
+		dbResult.StatusMessage = reqUpdate.StatusMessage
+		setBuildStageResultStatusDate(&dbResult, now)
+		return tx.Save(&dbResult).Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed updating status of stage %q on build with ID %d.",
+			stageName, buildID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildStageResultToResponse(dbResult))
+}
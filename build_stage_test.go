@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetBuildStageResultStatusDate(t *testing.T) {
+	start := time.Date(2022, 5, 10, 10, 0, 0, 0, time.UTC)
+	dbResult := database.BuildStageResult{StatusID: database.BuildRunning}
+	setBuildStageResultStatusDate(&dbResult, start)
+	setBuildStageResultStatusDate(&dbResult, start.Add(time.Second))
+	assert.Equal(t, start, dbResult.StartedOn.Time, "repeated running updates must keep the start date")
+	assert.False(t, dbResult.CompletedOn.Valid)
+	assert.False(t, dbResult.Duration.Valid)
+
+	dbResult.StatusID = database.BuildFailed
+	setBuildStageResultStatusDate(&dbResult, start.Add(90*time.Second))
+	assert.Equal(t, start.Add(90*time.Second), dbResult.CompletedOn.Time)
+	assert.Equal(t, int64(90000), dbResult.Duration.Int64)
+}
+
+func TestUpdateBuildStageStatusHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, dbProject := newTestInsertBuildModule(t)
+	clock := &fakeClock{now: time.Date(2022, 5, 10, 10, 0, 0, 0, time.UTC)}
+	m.Clock = clock
+	dbBuild := database.Build{ProjectID: dbProject.ProjectID, GitBranch: "master", Stage: "ALL"}
+	_, err := m.insertBuild(&dbBuild, dbProject, CIEngineConfig{ID: "jenkins"}, nil)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.PUT("/build/:buildId/stage/:stageName/status", m.updateBuildStageStatusHandler)
+	put := func(buildID uint, stageName, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut,
+			fmt.Sprintf("/build/%d/stage/%s/status", buildID, stageName),
+			strings.NewReader(body)))
+		return w
+	}
+
+	w := put(dbBuild.BuildID, "test", `{"status":"Running"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = put(dbBuild.BuildID, "test", `{"status":"Completed","timestamp":"2022-05-10T10:01:30Z"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resResult response.BuildStageResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resResult))
+	assert.Equal(t, response.BuildCompleted, resResult.Status)
+	assert.Equal(t, clock.now, resResult.StartedOn.Time.UTC())
+	assert.Equal(t, int64(90000), resResult.Duration.Int64)
+
+	gotBuild, err := m.getBuild(dbBuild.BuildID)
+	require.NoError(t, err)
+	require.Len(t, gotBuild.StageResults, 1)
+	assert.Equal(t, "test", gotBuild.StageResults[0].Name)
+	assert.Equal(t, database.BuildScheduling, gotBuild.StatusID, "build status must not change")
+
+	assert.Equal(t, http.StatusBadRequest, put(dbBuild.BuildID, "test", `{"status":"AwaitingApproval"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(dbBuild.BuildID, strings.Repeat("x", 41), `{"status":"Running"}`).Code)
+	assert.NotEqual(t, http.StatusOK, put(999, "test", `{"status":"Running"}`).Code)
+}
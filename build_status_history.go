@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+// buildStatusActor is who or what changed a build's status. Left empty when
+// the status is changed by the wharf-api itself.
+type buildStatusActor struct {
+	actorType database.BuildTriggerType
+	name      string
+	subject   string
+}
+
+func getBuildStatusActorFromContext(c *gin.Context) buildStatusActor {
+	actorType, name, subject := getUserFromContext(c)
+	return buildStatusActor{actorType: actorType, name: name, subject: subject}
+}
+
+// saveBuildStatusTransition records the change of the build's status from
+// statusBefore into its current status. Nothing is recorded if the status did
+// not change. The statusBefore should be null when the build was just created.
+func saveBuildStatusTransition(tx *gorm.DB, dbBuild database.Build, statusBefore null.Int, actor buildStatusActor, message string, now time.Time) error {
+	if statusBefore.Valid && database.BuildStatus(statusBefore.Int64) == dbBuild.StatusID {
+		return nil
+	}
+	return tx.Create(&database.BuildStatusTransition{
+		BuildID:          dbBuild.BuildID,
+		PreviousStatusID: statusBefore,
+		StatusID:         dbBuild.StatusID,
+		Timestamp:        now,
+		ActorType:        actor.actorType,
+		ActorName:        actor.name,
+		ActorSubject:     actor.subject,
+		Message:          truncateString(message, database.BuildStatusTransitionSizes.Message),
+	}).Error
+}
+
+func findBuildStatusTransitions(db *gorm.DB, buildID uint) ([]database.BuildStatusTransition, error) {
+	var dbTransitions []database.BuildStatusTransition
+	err := db.
+		Where(&database.BuildStatusTransition{BuildID: buildID}, database.BuildStatusTransitionFields.BuildID).
+		Order(database.BuildStatusTransitionColumns.Timestamp).
+		Order(database.BuildStatusTransitionColumns.BuildStatusTransitionID).
+		Find(&dbTransitions).
+		Error
+	return dbTransitions, err
+}
+
+// getBuildStatusHistoryHandler godoc
+// @id getBuildStatusHistory
+// @summary Get the status history of a build.
+// @description Lists every change of the build's status, ordered by when they
+// @description happened, including who or what changed the status.
+// @description Also responds with the total time the build has spent queued,
+// @description in the Scheduling status, and running, in the Running status,
+// @description counting each time the build went back to the same status.
+// @description Builds started before v5.3.0 have no status history.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildStatusHistory
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/status-history [get]
+func (m buildModule) getBuildStatusHistoryHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	if !validateBuildExistsByID(c, m.Database, buildID, "when fetching status history") {
+		return
+	}
+	dbTransitions, err := findBuildStatusTransitions(m.Database, buildID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching status history of build with ID %d from database.",
+			buildID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, newBuildStatusHistory(buildID, dbTransitions))
+}
+
+// newBuildStatusHistory converts the build's status transitions, ordered by
+// when they happened, and sums up the time spent between the transitions into
+// and out of the Scheduling and Running statuses. The time spent in the
+// build's current status is not included, as it has not ended yet.
+func newBuildStatusHistory(buildID uint, dbTransitions []database.BuildStatusTransition) response.BuildStatusHistory {
+	resHistory := response.BuildStatusHistory{
+		BuildID: buildID,
+		List:    make([]response.BuildStatusTransition, len(dbTransitions)),
+	}
+	for i, dbTransition := range dbTransitions {
+		resTransition := response.BuildStatusTransition{
+			BuildStatusTransitionID: dbTransition.BuildStatusTransitionID,
+			Status:                  modelconv.DBBuildStatusToResponse(dbTransition.StatusID),
+			Timestamp:               dbTransition.Timestamp.UTC(),
+			Actor: response.BuildTriggeredBy{
+				Type:    response.BuildTriggerType(dbTransition.ActorType),
+				Name:    dbTransition.ActorName,
+				Subject: dbTransition.ActorSubject,
+			},
+			Message: dbTransition.Message,
+		}
+		if dbTransition.PreviousStatusID.Valid {
+			resTransition.PreviousStatus.SetValid(string(modelconv.DBBuildStatusToResponse(
+				database.BuildStatus(dbTransition.PreviousStatusID.Int64))))
+		}
+		resHistory.List[i] = resTransition
+
+		if i == 0 {
+			continue
+		}
+		prev := dbTransitions[i-1]
+		elapsed := dbTransition.Timestamp.Sub(prev.Timestamp).Milliseconds()
+		switch prev.StatusID {
+		case database.BuildScheduling:
+			resHistory.QueueDuration.SetValid(resHistory.QueueDuration.Int64 + elapsed)
+		case database.BuildRunning:
+			resHistory.RunDuration.SetValid(resHistory.RunDuration.Int64 + elapsed)
+		}
+	}
+	return resHistory
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestNewBuildStatusHistory_sumsDurations(t *testing.T) {
+	start := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	dbTransitions := []database.BuildStatusTransition{
+		{BuildStatusTransitionID: 1, StatusID: database.BuildScheduling, Timestamp: at(0)},
+		{BuildStatusTransitionID: 2, PreviousStatusID: null.IntFrom(int64(database.BuildScheduling)), StatusID: database.BuildRunning, Timestamp: at(10)},
+		{BuildStatusTransitionID: 3, PreviousStatusID: null.IntFrom(int64(database.BuildRunning)), StatusID: database.BuildScheduling, Timestamp: at(40)},
+		{BuildStatusTransitionID: 4, PreviousStatusID: null.IntFrom(int64(database.BuildScheduling)), StatusID: database.BuildRunning, Timestamp: at(45)},
+		{BuildStatusTransitionID: 5, PreviousStatusID: null.IntFrom(int64(database.BuildRunning)), StatusID: database.BuildCompleted, Timestamp: at(105)},
+	}
+
+	history := newBuildStatusHistory(1, dbTransitions)
+	require.Len(t, history.List, 5)
+	assert.Equal(t, null.IntFrom(15000), history.QueueDuration)
+	assert.Equal(t, null.IntFrom(90000), history.RunDuration)
+	assert.False(t, history.List[0].PreviousStatus.Valid)
+	assert.Equal(t, null.StringFrom(string(response.BuildRunning)), history.List[4].PreviousStatus)
+	assert.Equal(t, response.BuildCompleted, history.List[4].Status)
+}
+
+func TestNewBuildStatusHistory_excludesCurrentStatus(t *testing.T) {
+	start := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	dbTransitions := []database.BuildStatusTransition{
+		{BuildStatusTransitionID: 1, StatusID: database.BuildScheduling, Timestamp: start},
+	}
+
+	history := newBuildStatusHistory(1, dbTransitions)
+	assert.False(t, history.QueueDuration.Valid)
+	assert.False(t, history.RunDuration.Valid)
+}
+
+func TestUpdateBuildStatus_recordsTransitions(t *testing.T) {
+	m, dbProject := newTestInsertBuildModule(t)
+	clock := &fakeClock{now: time.Date(2022, 5, 10, 10, 0, 0, 0, time.UTC)}
+	m.Clock = clock
+	dbBuild := database.Build{ProjectID: dbProject.ProjectID, GitBranch: "master", Stage: "ALL"}
+	_, err := m.insertBuild(&dbBuild, dbProject, CIEngineConfig{ID: "jenkins"}, nil)
+	require.NoError(t, err)
+
+	clock.now = clock.now.Add(time.Minute)
+	actor := buildStatusActor{actorType: database.BuildTriggerOIDC, name: "alice", subject: "alice-sub"}
+	_, err = m.updateBuildStatus(dbBuild.BuildID, buildStatusUpdate{status: database.BuildRunning, actor: actor})
+	require.NoError(t, err)
+	// Updating into the same status again is not recorded.
+	_, err = m.updateBuildStatus(dbBuild.BuildID, buildStatusUpdate{status: database.BuildRunning})
+	require.NoError(t, err)
+	clock.now = clock.now.Add(time.Minute)
+	_, err = m.updateBuildStatus(dbBuild.BuildID, buildStatusUpdate{status: database.BuildFailed, statusMessage: "tests failed"})
+	require.NoError(t, err)
+
+	dbTransitions, err := findBuildStatusTransitions(m.Database, dbBuild.BuildID)
+	require.NoError(t, err)
+	require.Len(t, dbTransitions, 3)
+
+	assert.False(t, dbTransitions[0].PreviousStatusID.Valid)
+	assert.Equal(t, database.BuildScheduling, dbTransitions[0].StatusID)
+
+	assert.Equal(t, null.IntFrom(int64(database.BuildScheduling)), dbTransitions[1].PreviousStatusID)
+	assert.Equal(t, database.BuildRunning, dbTransitions[1].StatusID)
+	assert.Equal(t, "alice", dbTransitions[1].ActorName)
+	assert.Equal(t, "alice-sub", dbTransitions[1].ActorSubject)
+
+	assert.Equal(t, database.BuildFailed, dbTransitions[2].StatusID)
+	assert.Equal(t, "tests failed", dbTransitions[2].Message)
+
+	history := newBuildStatusHistory(dbBuild.BuildID, dbTransitions)
+	assert.Equal(t, null.IntFrom(60000), history.QueueDuration)
+	assert.Equal(t, null.IntFrom(60000), history.RunDuration)
+}
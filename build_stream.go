@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+)
+
+var errBuildStreamTooManyListeners = errors.New("too many listeners on build stream")
+
+// buildStreams is the hub of all build event streams. Replaced in serve with
+// a hub using the configured settings.
+var buildStreams = newBuildStreamHub(DefaultConfig.HTTP.BuildStream)
+
+// buildStreamListenerBufferSize is the number of events buffered for each
+// listener. Events submitted to a listener with a full buffer are dropped, so
+// that a slow listener cannot stall the submitter.
+const buildStreamListenerBufferSize = 100
+
+// buildStream is the event stream of a single build.
+type buildStream struct {
+	buildID   uint
+	listeners map[chan any]struct{}
+	idleSince time.Time
+}
+
+// buildStreamHub holds the event streams of all builds that have listeners,
+// such as clients of the GET /api/build/{buildId}/stream endpoint.
+//
+// Streams are removed when they have been without listeners for longer than
+// the configured idle timeout.
+type buildStreamHub struct {
+	config  BuildStreamConfig
+	mu      sync.Mutex
+	streams map[uint]*buildStream
+
+	evictions         metrics.Counter
+	rejectedListeners metrics.Counter
+	droppedEvents     metrics.Counter
+}
+
+func newBuildStreamHub(config BuildStreamConfig) *buildStreamHub {
+	return &buildStreamHub{
+		config:  config,
+		streams: map[uint]*buildStream{},
+	}
+}
+
+// registerMetrics adds the hub's metrics to the registry.
+func (h *buildStreamHub) registerMetrics(r *metrics.Registry) {
+	r.AddGaugeFunc("wharf_api_build_stream_hub_streams",
+		"Number of build event streams, including idle ones.",
+		func() float64 {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			return float64(len(h.streams))
+		})
+	r.AddGaugeFunc("wharf_api_build_stream_hub_listeners",
+		"Number of listeners on all build event streams.",
+		func() float64 {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			var listeners int
+			for _, s := range h.streams {
+				listeners += len(s.listeners)
+			}
+			return float64(listeners)
+		})
+	r.AddCounter("wharf_api_build_stream_hub_evictions_total",
+		"Number of idle build event streams that have been removed.",
+		&h.evictions)
+	r.AddCounter("wharf_api_build_stream_hub_rejected_listeners_total",
+		"Number of listeners rejected because the build event stream was full.",
+		&h.rejectedListeners)
+	r.AddCounter("wharf_api_build_stream_hub_dropped_events_total",
+		"Number of events dropped because a listener's buffer was full.",
+		&h.droppedEvents)
+}
+
+// start starts the background worker that removes idle streams. The worker
+// runs for the remaining lifetime of the process.
+func (h *buildStreamHub) start() {
+	if h.config.IdleTimeout <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(h.config.IdleTimeout)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			h.evictIdle(now)
+		}
+	}()
+}
+
+// buildStreamListener receives the events of a build's event stream. Must be
+// closed using close.
+type buildStreamListener struct {
+	C <-chan any
+
+	hub     *buildStreamHub
+	stream  *buildStream
+	channel chan any
+}
+
+// listen adds a listener to the event stream of the build, or returns
+// errBuildStreamTooManyListeners if the stream already has the maximum number
+// of listeners.
+func (h *buildStreamHub) listen(buildID uint) (*buildStreamListener, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.streams[buildID]
+	if !ok {
+		s = &buildStream{
+			buildID:   buildID,
+			listeners: map[chan any]struct{}{},
+		}
+		h.streams[buildID] = s
+	} else if limit := h.config.MaxListenersPerBuild; limit > 0 && len(s.listeners) >= limit {
+		h.rejectedListeners.Inc()
+		return nil, errBuildStreamTooManyListeners
+	}
+	channel := make(chan any, buildStreamListenerBufferSize)
+	s.listeners[channel] = struct{}{}
+	return &buildStreamListener{
+		C:       channel,
+		hub:     h,
+		stream:  s,
+		channel: channel,
+	}, nil
+}
+
+// close removes the listener from the build's event stream.
+func (l *buildStreamListener) close() {
+	l.hub.mu.Lock()
+	defer l.hub.mu.Unlock()
+	delete(l.stream.listeners, l.channel)
+	close(l.channel)
+	l.hub.releaseLocked(l.stream)
+}
+
+// submit sends the event to all listeners of the build's event stream. Does
+// nothing if the build does not have an event stream.
+//
+// Never blocks on slow listeners. Listeners whose buffer is full miss the
+// event instead.
+func (h *buildStreamHub) submit(buildID uint, event any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.streams[buildID]
+	if !ok {
+		return
+	}
+	for channel := range s.listeners {
+		select {
+		case channel <- event:
+		default:
+			h.droppedEvents.Inc()
+		}
+	}
+}
+
+func (h *buildStreamHub) releaseLocked(s *buildStream) {
+	if len(s.listeners) > 0 {
+		return
+	}
+	s.idleSince = time.Now()
+	if h.config.IdleTimeout <= 0 {
+		h.removeLocked(s)
+	}
+}
+
+func (h *buildStreamHub) evictIdle(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.streams {
+		if len(s.listeners) == 0 && now.Sub(s.idleSince) >= h.config.IdleTimeout {
+			h.removeLocked(s)
+		}
+	}
+}
+
+func (h *buildStreamHub) removeLocked(s *buildStream) {
+	delete(h.streams, s.buildID)
+	h.evictions.Inc()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildStreamHub_submit(t *testing.T) {
+	hub := newBuildStreamHub(BuildStreamConfig{})
+	listener, err := hub.listen(1)
+	require.NoError(t, err)
+	defer listener.close()
+
+	go hub.submit(1, "hello")
+	select {
+	case event := <-listener.C:
+		assert.Equal(t, "hello", event)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestBuildStreamHub_submitDropsForSlowListener(t *testing.T) {
+	hub := newBuildStreamHub(BuildStreamConfig{})
+	slow, err := hub.listen(1)
+	require.NoError(t, err)
+	defer slow.close()
+
+	// would block forever if submit waited for the listener
+	for i := 0; i < buildStreamListenerBufferSize+1; i++ {
+		hub.submit(1, i)
+	}
+	assert.Len(t, slow.C, buildStreamListenerBufferSize)
+	assert.Equal(t, uint64(1), hub.droppedEvents.Value())
+}
+
+func TestBuildStreamHub_submitWithoutListeners(t *testing.T) {
+	hub := newBuildStreamHub(BuildStreamConfig{})
+	hub.submit(1, "hello")
+	assert.Empty(t, hub.streams)
+}
+
+func TestBuildStreamHub_maxListeners(t *testing.T) {
+	hub := newBuildStreamHub(BuildStreamConfig{MaxListenersPerBuild: 1})
+	listener, err := hub.listen(1)
+	require.NoError(t, err)
+
+	_, err = hub.listen(1)
+	assert.ErrorIs(t, err, errBuildStreamTooManyListeners)
+	assert.Equal(t, uint64(1), hub.rejectedListeners.Value())
+
+	other, err := hub.listen(2)
+	require.NoError(t, err, "other build")
+	other.close()
+
+	listener.close()
+	listener, err = hub.listen(1)
+	require.NoError(t, err, "after closing first listener")
+	listener.close()
+}
+
+func TestBuildStreamHub_removesWithoutIdleTimeout(t *testing.T) {
+	hub := newBuildStreamHub(BuildStreamConfig{})
+	listener, err := hub.listen(1)
+	require.NoError(t, err)
+	assert.Len(t, hub.streams, 1)
+
+	listener.close()
+	assert.Empty(t, hub.streams)
+	assert.Equal(t, uint64(1), hub.evictions.Value())
+}
+
+func TestBuildStreamHub_evictIdle(t *testing.T) {
+	hub := newBuildStreamHub(BuildStreamConfig{IdleTimeout: time.Minute})
+	idle, err := hub.listen(1)
+	require.NoError(t, err)
+	idle.close()
+	active, err := hub.listen(2)
+	require.NoError(t, err)
+	defer active.close()
+
+	hub.evictIdle(time.Now())
+	assert.Len(t, hub.streams, 2, "before idle timeout")
+
+	hub.evictIdle(time.Now().Add(time.Minute))
+	assert.Len(t, hub.streams, 1, "after idle timeout")
+	assert.Contains(t, hub.streams, uint(2))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+// getBuildTimelineHandler godoc
+// @id getBuildTimeline
+// @summary Get the timeline of a build.
+// @description Lists the events of a build in a single list, ordered by when
+// @description they happened, so that they can be shown as a timeline.
+// @description This includes the build's status changes, its approval,
+// @description the starts and finishes of its stages, its uploaded artifacts,
+// @description and its added test results.
+// @description The status changes are taken from the build's status history.
+// @description Builds without a status history, such as builds started before
+// @description v5.3.0, instead have their status changes based on the build's
+// @description scheduled, started, and finished dates, which only include the
+// @description latest change into each status.
+// @description All timestamps are in UTC.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildTimeline
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/timeline [get]
+func (m buildModule) getBuildTimelineHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+
+	dbBuild, err := m.getBuild(buildID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Build with ID %d was not found.",
+			buildID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching build with ID %d from database.",
+			buildID))
+		return
+	}
+
+	dbTransitions, err := findBuildStatusTransitions(m.Database, buildID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching status history of build with ID %d from database.",
+			buildID))
+		return
+	}
+
+	// The artifacts' data is not needed, and may be large.
+	var dbArtifacts []database.Artifact
+	if err := m.Database.
+		Select(
+			database.ArtifactColumns.ArtifactID,
+			database.ArtifactColumns.FileName,
+			database.TimeMetadataColumns.CreatedAt).
+		Where(&database.Artifact{BuildID: buildID}, database.ArtifactFields.BuildID).
+		Find(&dbArtifacts).
+		Error; err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching artifacts for build with ID %d from database.",
+			buildID))
+		return
+	}
+
+	ginrender.Response(c, http.StatusOK, newBuildTimeline(dbBuild, dbTransitions, dbArtifacts))
+}
+
+// newBuildTimeline merges the events of the build, its status transitions,
+// stage results, and test result summaries, and the given artifacts, ordered
+// by when they happened. Events that happened at the same time keep the order
+// they were added in.
+func newBuildTimeline(dbBuild database.Build, dbTransitions []database.BuildStatusTransition, dbArtifacts []database.Artifact) response.BuildTimeline {
+	events := []response.BuildTimelineEvent{}
+	add := func(t time.Time, event response.BuildTimelineEvent) {
+		event.Timestamp = t.UTC()
+		events = append(events, event)
+	}
+
+	for _, dbTransition := range dbTransitions {
+		add(dbTransition.Timestamp, response.BuildTimelineEvent{
+			Type:    response.BuildTimelineEventStatus,
+			Status:  modelconv.DBBuildStatusToResponse(dbTransition.StatusID),
+			Message: dbTransition.Message,
+		})
+	}
+	if len(dbTransitions) == 0 {
+		addBuildTimelineStatusEventsFromDates(dbBuild, add)
+	}
+	if dbBuild.ReviewedOn.Valid {
+		add(dbBuild.ReviewedOn.Time, response.BuildTimelineEvent{
+			Type:           response.BuildTimelineEventApproval,
+			ApprovalStatus: response.BuildApprovalStatus(dbBuild.ApprovalStatus),
+			Message:        dbBuild.ReviewComment,
+		})
+	}
+
+	for _, dbStage := range dbBuild.StageResults {
+		if dbStage.StartedOn.Valid {
+			add(dbStage.StartedOn.Time, response.BuildTimelineEvent{
+				Type:      response.BuildTimelineEventStage,
+				Status:    response.BuildRunning,
+				StageName: dbStage.Name,
+			})
+		}
+		if dbStage.CompletedOn.Valid {
+			add(dbStage.CompletedOn.Time, response.BuildTimelineEvent{
+				Type:      response.BuildTimelineEventStage,
+				Status:    modelconv.DBBuildStatusToResponse(dbStage.StatusID),
+				StageName: dbStage.Name,
+				Message:   dbStage.StatusMessage,
+			})
+		}
+	}
+
+	for _, dbArtifact := range dbArtifacts {
+		if dbArtifact.CreatedAt == nil {
+			continue
+		}
+		add(*dbArtifact.CreatedAt, response.BuildTimelineEvent{
+			Type:       response.BuildTimelineEventArtifact,
+			ArtifactID: null.IntFrom(int64(dbArtifact.ArtifactID)),
+			FileName:   dbArtifact.FileName,
+		})
+	}
+
+	for _, dbSummary := range dbBuild.TestResultSummaries {
+		if dbSummary.CreatedAt == nil {
+			continue
+		}
+		add(*dbSummary.CreatedAt, response.BuildTimelineEvent{
+			Type:                response.BuildTimelineEventTestResult,
+			ArtifactID:          ptrconv.UintPtrNullInt(dbSummary.ArtifactID),
+			TestResultSummaryID: null.IntFrom(int64(dbSummary.TestResultSummaryID)),
+			FileName:            dbSummary.FileName,
+			Message: fmt.Sprintf("%d passed, %d failed, %d skipped",
+				dbSummary.Passed, dbSummary.Failed, dbSummary.Skipped),
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return response.BuildTimeline{
+		BuildID: dbBuild.BuildID,
+		List:    events,
+	}
+}
+
+// addBuildTimelineStatusEventsFromDates adds the build's status changes based
+// on its scheduled, started, and finished dates, for builds that have no
+// status history.
+func addBuildTimelineStatusEventsFromDates(dbBuild database.Build, add func(time.Time, response.BuildTimelineEvent)) {
+	if dbBuild.ScheduledOn.Valid {
+		add(dbBuild.ScheduledOn.Time, response.BuildTimelineEvent{
+			Type:   response.BuildTimelineEventStatus,
+			Status: response.BuildScheduling,
+		})
+	}
+	if dbBuild.StartedOn.Valid {
+		add(dbBuild.StartedOn.Time, response.BuildTimelineEvent{
+			Type:   response.BuildTimelineEventStatus,
+			Status: response.BuildRunning,
+		})
+	}
+	switch {
+	case dbBuild.CompletedOn.Valid:
+		add(dbBuild.CompletedOn.Time, response.BuildTimelineEvent{
+			Type:    response.BuildTimelineEventStatus,
+			Status:  modelconv.DBBuildStatusToResponse(dbBuild.StatusID),
+			Message: dbBuild.StatusMessage,
+		})
+	case dbBuild.StatusID == database.BuildTriggerFailed && dbBuild.UpdatedAt != nil:
+		add(*dbBuild.UpdatedAt, response.BuildTimelineEvent{
+			Type:    response.BuildTimelineEventStatus,
+			Status:  response.BuildTriggerFailed,
+			Message: dbBuild.TriggerError,
+		})
+	}
+}
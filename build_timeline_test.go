@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestNewBuildTimeline(t *testing.T) {
+	start := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	atPtr := func(minutes int) *time.Time {
+		t := at(minutes)
+		return &t
+	}
+	artifactID := uint(7)
+	dbBuild := database.Build{
+		BuildID:       1,
+		StatusID:      database.BuildFailed,
+		StatusMessage: "tests failed",
+		ScheduledOn:   null.TimeFrom(at(0)),
+		StartedOn:     null.TimeFrom(at(2)),
+		// Not in UTC, to check that all timestamps are converted to UTC.
+		CompletedOn: null.TimeFrom(at(10).In(time.FixedZone("CEST", 2*60*60))),
+		StageResults: []database.BuildStageResult{
+			{Name: "build", StatusID: database.BuildCompleted, StartedOn: null.TimeFrom(at(3)), CompletedOn: null.TimeFrom(at(5))},
+			{Name: "test", StatusID: database.BuildRunning, StartedOn: null.TimeFrom(at(5))},
+		},
+		TestResultSummaries: []database.TestResultSummary{
+			{
+				TimeMetadata:        database.TimeMetadata{CreatedAt: atPtr(8)},
+				TestResultSummaryID: 3,
+				ArtifactID:          &artifactID,
+				FileName:            "results.trx",
+				Passed:              2,
+				Failed:              1,
+			},
+		},
+	}
+	dbArtifacts := []database.Artifact{
+		{TimeMetadata: database.TimeMetadata{CreatedAt: atPtr(8)}, ArtifactID: artifactID, FileName: "results.trx"},
+	}
+
+	timeline := newBuildTimeline(dbBuild, nil, dbArtifacts)
+	assert.Equal(t, uint(1), timeline.BuildID)
+	require.Len(t, timeline.List, 8)
+
+	type event struct {
+		minutes int
+		typ     response.BuildTimelineEventType
+		status  response.BuildStatus
+		stage   string
+	}
+	var got []event
+	for _, e := range timeline.List {
+		assert.Equal(t, time.UTC, e.Timestamp.Location())
+		got = append(got, event{int(e.Timestamp.Sub(start).Minutes()), e.Type, e.Status, e.StageName})
+	}
+	assert.Equal(t, []event{
+		{0, response.BuildTimelineEventStatus, response.BuildScheduling, ""},
+		{2, response.BuildTimelineEventStatus, response.BuildRunning, ""},
+		{3, response.BuildTimelineEventStage, response.BuildRunning, "build"},
+		{5, response.BuildTimelineEventStage, response.BuildCompleted, "build"},
+		{5, response.BuildTimelineEventStage, response.BuildRunning, "test"},
+		{8, response.BuildTimelineEventArtifact, "", ""},
+		{8, response.BuildTimelineEventTestResult, "", ""},
+		{10, response.BuildTimelineEventStatus, response.BuildFailed, ""},
+	}, got)
+
+	assert.Equal(t, null.IntFrom(7), timeline.List[6].ArtifactID)
+	assert.Equal(t, null.IntFrom(3), timeline.List[6].TestResultSummaryID)
+	assert.Equal(t, "2 passed, 1 failed, 0 skipped", timeline.List[6].Message)
+	assert.Equal(t, "tests failed", timeline.List[7].Message)
+}
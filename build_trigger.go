@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+var (
+	errBuildTriggerQueueFull = errors.New("build trigger queue is full")
+	errBuildTriggerStopped   = errors.New("wharf-api was shut down before the build was triggered")
+)
+
+// buildTriggerJob is a build waiting to be triggered by the
+// buildTriggerDispatcher.
+type buildTriggerJob struct {
+	dbBuild     database.Build
+	dbJobParams []database.Param
+	engine      CIEngineConfig
+	// requestID is the ID of the HTTP request that started the build, which
+	// is forwarded to the execution engine.
+	requestID string
+}
+
+// buildTriggerDispatcher triggers builds in the background, so that clients
+// starting new builds does not have to wait for the execution engine.
+type buildTriggerDispatcher struct {
+	db      *gorm.DB
+	engines *engineClient
+	config  CIAsyncTriggerConfig
+	queue   chan buildTriggerJob
+	// ctx is canceled when stopping the dispatcher, which aborts the
+	// workers' retry backoffs.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+func newBuildTriggerDispatcher(db *gorm.DB, engines *engineClient, config CIAsyncTriggerConfig) *buildTriggerDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &buildTriggerDispatcher{
+		db:      db,
+		engines: engines,
+		config:  config,
+		queue:   make(chan buildTriggerJob, config.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// start starts the background workers. The workers run until stop is called.
+func (d *buildTriggerDispatcher) start() {
+	d.workers.Add(d.config.Workers)
+	for i := 0; i < d.config.Workers; i++ {
+		go d.work()
+	}
+}
+
+// stop stops the background workers, and blocks until they have stopped.
+// Builds that are waiting for a retry, or are still in the queue, are marked
+// with the TriggerFailed status instead of being triggered, so that they are
+// not left in the Scheduling status forever. Builds enqueued after stop has
+// been called are never triggered.
+func (d *buildTriggerDispatcher) stop() {
+	d.cancel()
+	d.workers.Wait()
+}
+
+// enqueue adds a build to the queue of builds to trigger, or returns
+// errBuildTriggerQueueFull if the queue is full.
+func (d *buildTriggerDispatcher) enqueue(job buildTriggerJob) error {
+	select {
+	case d.queue <- job:
+		return nil
+	default:
+		return errBuildTriggerQueueFull
+	}
+}
+
+func (d *buildTriggerDispatcher) work() {
+	defer d.workers.Done()
+	for {
+		select {
+		case job := <-d.queue:
+			if d.ctx.Err() != nil {
+				d.fail(job, errBuildTriggerStopped)
+				continue
+			}
+			d.dispatch(job)
+		case <-d.ctx.Done():
+			d.drain()
+			return
+		}
+	}
+}
+
+// drain marks the builds still in the queue as failed to be triggered.
+func (d *buildTriggerDispatcher) drain() {
+	for {
+		select {
+		case job := <-d.queue:
+			d.fail(job, errBuildTriggerStopped)
+		default:
+			return
+		}
+	}
+}
+
+// dispatch triggers the build, with retries. Timeouts are not retried, as the
+// engine may still have received the request and started the build.
+func (d *buildTriggerDispatcher) dispatch(job buildTriggerJob) {
+	reqLog := logWithRequestID(job.requestID)
+	backoff := d.config.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		workerID, err := d.engines.triggerBuild(d.ctx, job.dbJobParams, job.engine, job.requestID)
+		if err == nil {
+			if workerID != "" {
+				job.dbBuild.WorkerID = workerID
+				if err := saveBuildWorkerID(d.db, job.dbBuild); err != nil {
+					reqLog.Error().
+						WithError(err).
+						WithUint("build", job.dbBuild.BuildID).
+						WithString("workerId", workerID).
+						Message("Failed saving worker ID for build.")
+				}
+			}
+			return
+		}
+		if d.ctx.Err() != nil {
+			d.fail(job, errBuildTriggerStopped)
+			return
+		}
+		if attempt >= d.config.MaxAttempts || isTimeoutError(err) {
+			reqLog.Warn().
+				WithError(err).
+				WithUint("build", job.dbBuild.BuildID).
+				WithString("engine", job.engine.ID).
+				WithInt("attempt", attempt).
+				Message("Failed triggering build, giving up.")
+			d.fail(job, err)
+			return
+		}
+		reqLog.Warn().
+			WithError(err).
+			WithUint("build", job.dbBuild.BuildID).
+			WithString("engine", job.engine.ID).
+			WithInt("attempt", attempt).
+			WithDuration("backoff", backoff).
+			Message("Failed triggering build, retrying.")
+		select {
+		case <-d.ctx.Done():
+			d.fail(job, errBuildTriggerStopped)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// fail marks the build as failed to be triggered.
+func (d *buildTriggerDispatcher) fail(job buildTriggerJob, triggerErr error) {
+	if err := saveBuildTriggerFailed(d.db, &job.dbBuild, triggerErr); err != nil {
+		logWithRequestID(job.requestID).Error().
+			WithError(err).
+			WithUint("build", job.dbBuild.BuildID).
+			Message("Failed saving build trigger failure.")
+	}
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// saveBuildTriggerFailed marks the build as invalid with the TriggerFailed
+// status, and notifies any listeners on the build's event stream.
+//
+// Only the affected columns are updated, so that any other changes made to the
+// build since it was created are not overwritten.
+func saveBuildTriggerFailed(db *gorm.DB, dbBuild *database.Build, triggerErr error) error {
+	statusBefore := dbBuild.StatusID
+	dbBuild.IsInvalid = true
+	dbBuild.StatusID = database.BuildTriggerFailed
+	dbBuild.TriggerError = truncateString(triggerErr.Error(), database.BuildSizes.TriggerError)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Model(dbBuild).
+			Select(
+				database.BuildFields.IsInvalid,
+				database.BuildFields.StatusID,
+				database.BuildFields.TriggerError).
+			Updates(dbBuild).Error; err != nil {
+			return err
+		}
+		return saveBuildStatusTransition(tx, *dbBuild, null.IntFrom(int64(statusBefore)),
+			buildStatusActor{}, dbBuild.TriggerError, nowUTC(nil))
+	})
+	if err != nil {
+		return err
+	}
+	submitBuildStatusEvent(*dbBuild, statusBefore, triggerErr.Error())
+	return nil
+}
+
+// saveBuildWorkerID updates the worker ID of the build. Only the worker ID is
+// updated, as the execution engine may already have updated the build's status
+// after being triggered.
+func saveBuildWorkerID(db *gorm.DB, dbBuild database.Build) error {
+	return db.
+		Model(&dbBuild).
+		Select(database.BuildFields.WorkerID).
+		Updates(&dbBuild).Error
+}
+
+// submitBuildStatusEvent notifies any listeners on the build's event stream
+// that the build's status has changed, and publishes the change to the
+// message bus, if configured.
+func submitBuildStatusEvent(dbBuild database.Build, statusBefore database.BuildStatus, message string) {
+	buildStreams.submit(dbBuild.BuildID, response.BuildStatusEvent{
+		BuildID:      dbBuild.BuildID,
+		StatusBefore: modelconv.DBBuildStatusToResponse(statusBefore),
+		Status:       modelconv.DBBuildStatusToResponse(dbBuild.StatusID),
+		IsInvalid:    dbBuild.IsInvalid,
+		Message:      message,
+	})
+	busEvents.publishBuildStatusEvent(dbBuild, statusBefore, message)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTriggerDispatcher_stop(t *testing.T) {
+	db := newTestSqliteDB(t)
+	dbProject := database.Project{Name: "wharf-api"}
+	require.NoError(t, db.Create(&dbProject).Error)
+	server, _ := newTestEngineServer(t, http.StatusServiceUnavailable)
+	engine := CIEngineConfig{ID: "primary", URL: server.URL}
+
+	d := newBuildTriggerDispatcher(db, newEngineClient(CIConfig{}), CIAsyncTriggerConfig{
+		Workers:      1,
+		QueueSize:    10,
+		MaxAttempts:  5,
+		RetryBackoff: time.Hour,
+	})
+	d.start()
+	dbBuilds := make([]database.Build, 2)
+	for i := range dbBuilds {
+		dbBuilds[i] = database.Build{ProjectID: dbProject.ProjectID, StatusID: database.BuildScheduling}
+		require.NoError(t, db.Create(&dbBuilds[i]).Error)
+		require.NoError(t, d.enqueue(buildTriggerJob{dbBuild: dbBuilds[i], engine: engine}))
+	}
+	// The first build is being retried, while the second waits in the queue.
+	require.Eventually(t, func() bool { return len(d.queue) == 1 },
+		5*time.Second, time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		d.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not abort the retry backoff")
+	}
+
+	for _, dbBuild := range dbBuilds {
+		var got database.Build
+		require.NoError(t, db.First(&got, dbBuild.BuildID).Error)
+		assert.Equal(t, database.BuildTriggerFailed, got.StatusID, "build %d", dbBuild.BuildID)
+		assert.Equal(t, errBuildTriggerStopped.Error(), got.TriggerError, "build %d", dbBuild.BuildID)
+	}
+}
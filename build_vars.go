@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ghodss/yaml"
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+const (
+	// buildVarsJobParamName is the name of the job parameter holding all of
+	// the build's input variables, serialized as YAML.
+	buildVarsJobParamName = "VARS"
+	// buildVarsRefJobParamName is the name of the job parameter holding the ID
+	// of the build, sent instead of the VARS job parameter when the input
+	// variables are too large to be sent inline. The execution engine then
+	// fetches the input variables via GET /build/{buildId}/param.
+	buildVarsRefJobParamName = "VARS_REF"
+)
+
+// buildVarsTooLargeError is returned when the build's input variables, as
+// serialized into the VARS job parameter, are larger than the
+// ci.maxVarsSize config.
+type buildVarsTooLargeError struct {
+	size    int
+	maxSize int
+}
+
+func (err buildVarsTooLargeError) Error() string {
+	return fmt.Sprintf("serialized input variables are too large: max %d bytes, but was: %d",
+		err.maxSize, err.size)
+}
+
+// marshalBuildVars serializes the build's input variables as YAML, as sent in
+// the VARS job parameter.
+func marshalBuildVars(dbBuildParams []database.BuildParam) ([]byte, error) {
+	if len(dbBuildParams) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]any)
+	for _, input := range dbBuildParams {
+		m[input.Name] = input.Value
+	}
+	return yaml.Marshal(m)
+}
+
+// canFetchBuildVars returns true if the engine can fetch the build's input
+// variables by reference, instead of being sent them in the VARS job
+// parameter.
+func canFetchBuildVars(engine CIEngineConfig) bool {
+	return engine.API == CIEngineAPIWharfCMDv1
+}
+
+// isBuildVarsTooLarge returns true if the serialized input variables are too
+// large to be sent to the engine in the URL. Parameters sent in the request
+// body are never too large.
+func isBuildVarsTooLarge(ciConf CIConfig, engine CIEngineConfig, vars []byte) bool {
+	return ciConf.MaxVarsSize > 0 &&
+		engine.ParamsFormat != CIEngineParamsFormatJSON &&
+		len(vars) > ciConf.MaxVarsSize
+}
+
+// validateBuildVarsSize returns a buildVarsTooLargeError if the build's input
+// variables are too large to be sent to the engine, and the engine cannot
+// fetch them by reference instead.
+func validateBuildVarsSize(ciConf CIConfig, engine CIEngineConfig, dbBuildParams []database.BuildParam) error {
+	if canFetchBuildVars(engine) {
+		return nil
+	}
+	vars, err := marshalBuildVars(dbBuildParams)
+	if err != nil {
+		return err
+	}
+	if isBuildVarsTooLarge(ciConf, engine, vars) {
+		return buildVarsTooLargeError{size: len(vars), maxSize: ciConf.MaxVarsSize}
+	}
+	return nil
+}
+
+// getEngineJobParams returns the job parameters to send to the execution
+// engine. The VARS job parameter is replaced with the VARS_REF job parameter
+// if the input variables are too large, and the engine can fetch them by
+// reference instead.
+func getEngineJobParams(
+	ciConf CIConfig,
+	engine CIEngineConfig,
+	dbProject database.Project,
+	dbBuild database.Build,
+	dbBuildParams []database.BuildParam,
+	wharfInstanceID string,
+) ([]database.Param, error) {
+	dbJobParams, err := getDBJobParams(dbProject, dbBuild, dbBuildParams, wharfInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	if !canFetchBuildVars(engine) {
+		return dbJobParams, nil
+	}
+	for i, dbJobParam := range dbJobParams {
+		if dbJobParam.Name != buildVarsJobParamName ||
+			!isBuildVarsTooLarge(ciConf, engine, []byte(dbJobParam.Value)) {
+			continue
+		}
+		dbJobParams[i].Value = ""
+		dbJobParams = append(dbJobParams, database.Param{
+			Type:  "string",
+			Name:  buildVarsRefJobParamName,
+			Value: strconv.FormatUint(uint64(dbBuild.BuildID), 10),
+		})
+		break
+	}
+	return dbJobParams, nil
+}
+
+// getBuildParamListHandler godoc
+// @id getBuildParamList
+// @summary Get the input variables of a build.
+// @description Lists the values of the build's input variables, as sent to the execution engine in the `VARS` job parameter.
+// @description Execution engines that are sent the `VARS_REF` job parameter instead, when the input variables were too large to be sent inline, use this endpoint to fetch them.
+// @description Values of secret inputs are redacted, unless `revealSecrets` is set, which requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param name query []string false "Filter by verbatim input variable name. Can be specified multiple times to match any of the names."
+// @param revealSecrets query bool false "Include the values of secret inputs."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} []response.BuildParam
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 409 {object} problem.Response "Values of secret inputs were not stored"
+// @failure 500 {object} problem.Response "Failed decrypting values of secret inputs"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/param [get]
+func (m buildModule) getBuildParamListHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	var params = struct {
+		Name          []string `form:"name"`
+		RevealSecrets bool     `form:"revealSecrets"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	if params.RevealSecrets && !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	if !validateBuildExistsByID(c, m.Database, buildID, "when fetching build params") {
+		return
+	}
+	query := m.Database.
+		Where(&database.BuildParam{BuildID: buildID}).
+		Order(database.BuildParamColumns.BuildParamID)
+	if len(params.Name) > 0 {
+		query = query.Where(fmt.Sprintf("%s IN ?", database.BuildParamColumns.Name), params.Name)
+	}
+	var dbBuildParams []database.BuildParam
+	if err := query.
+		Find(&dbBuildParams).
+		Error; err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching params of build with ID %d from database.",
+			buildID))
+		return
+	}
+	resBuildParams := modelconv.DBBuildParamsToResponses(dbBuildParams)
+	if params.RevealSecrets {
+		decrypted, err := m.decryptSecretBuildParams(dbBuildParams)
+		if errors.Is(err, errSecretBuildParamNotStored) {
+			ginutil.WriteProblemError(c, err, problems.BuildParamSecretsNotStored.Newf(
+				"The values of the secret inputs of build with ID %d were not stored, as no database encryption key is configured.",
+				buildID))
+			return
+		} else if err != nil {
+			ginutil.WriteProblemError(c, err, problems.BuildParamDecrypt.Newf(
+				"Failed to decrypt the values of the secret inputs of build with ID %d.",
+				buildID))
+			return
+		}
+		for i, dbBuildParam := range decrypted {
+			resBuildParams[i].Value = dbBuildParam.Value
+		}
+	}
+	ginrender.Response(c, http.StatusOK, resBuildParams)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBuildVarsSize(t *testing.T) {
+	ciConf := CIConfig{MaxVarsSize: 16}
+	dbBuildParams := []database.BuildParam{
+		{Name: "message", Value: strings.Repeat("a", 32)},
+	}
+
+	err := validateBuildVarsSize(ciConf, CIEngineConfig{}, dbBuildParams)
+	var tooLargeErr buildVarsTooLargeError
+	require.ErrorAs(t, err, &tooLargeErr)
+	assert.Equal(t, 16, tooLargeErr.maxSize)
+
+	jsonEngine := CIEngineConfig{ParamsFormat: CIEngineParamsFormatJSON}
+	assert.NoError(t, validateBuildVarsSize(ciConf, jsonEngine, dbBuildParams))
+
+	wharfCmdEngine := CIEngineConfig{API: CIEngineAPIWharfCMDv1}
+	assert.NoError(t, validateBuildVarsSize(ciConf, wharfCmdEngine, dbBuildParams))
+
+	assert.NoError(t, validateBuildVarsSize(CIConfig{}, CIEngineConfig{}, dbBuildParams))
+}
+
+func TestGetEngineJobParams_varsRef(t *testing.T) {
+	ciConf := CIConfig{MaxVarsSize: 16}
+	engine := CIEngineConfig{API: CIEngineAPIWharfCMDv1}
+	dbBuild := database.Build{BuildID: 123}
+	dbBuildParams := []database.BuildParam{
+		{Name: "message", Value: strings.Repeat("a", 32)},
+	}
+
+	dbJobParams, err := getEngineJobParams(ciConf, engine, database.Project{}, dbBuild, dbBuildParams, "")
+	require.NoError(t, err)
+
+	values := make(map[string]string)
+	for _, dbJobParam := range dbJobParams {
+		values[dbJobParam.Name] = dbJobParam.Value
+	}
+	assert.Equal(t, "", values["VARS"])
+	assert.Equal(t, "123", values["VARS_REF"])
+}
+
+func TestGetEngineJobParams_varsInline(t *testing.T) {
+	ciConf := CIConfig{MaxVarsSize: 1024}
+	engine := CIEngineConfig{API: CIEngineAPIWharfCMDv1}
+	dbBuildParams := []database.BuildParam{
+		{Name: "message", Value: "hello"},
+	}
+
+	dbJobParams, err := getEngineJobParams(ciConf, engine, database.Project{}, database.Build{}, dbBuildParams, "")
+	require.NoError(t, err)
+
+	for _, dbJobParam := range dbJobParams {
+		assert.NotEqual(t, "VARS_REF", dbJobParam.Name)
+		if dbJobParam.Name == "VARS" {
+			assert.Equal(t, "message: hello\n", dbJobParam.Value)
+		}
+	}
+}
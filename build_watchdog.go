@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"gorm.io/gorm"
+)
+
+// buildTimedOutMessage is the status message set on builds that are marked as
+// failed by the build watchdog.
+const buildTimedOutMessage = "timed out"
+
+// buildTimeouts holds how long builds may stay in the Scheduling and Running
+// statuses before being marked as failed, where zero (0) means no timeout.
+type buildTimeouts struct {
+	scheduling time.Duration
+	running    time.Duration
+}
+
+// newBuildTimeouts returns the build timeouts of a project, where the
+// project's own timeouts take precedence over the ones from the config.
+func newBuildTimeouts(config CIConfig, dbProject database.Project) buildTimeouts {
+	timeouts := buildTimeouts{
+		scheduling: config.SchedulingTimeout,
+		running:    config.RunningTimeout,
+	}
+	if dbProject.SchedulingTimeout.Valid {
+		timeouts.scheduling = time.Duration(dbProject.SchedulingTimeout.Int64) * time.Second
+	}
+	if dbProject.RunningTimeout.Valid {
+		timeouts.running = time.Duration(dbProject.RunningTimeout.Int64) * time.Second
+	}
+	return timeouts
+}
+
+// expiresOn returns when the build times out in its current status, or false
+// if the build does not time out.
+func (t buildTimeouts) expiresOn(dbBuild database.Build) (time.Time, bool) {
+	var timeout time.Duration
+	switch dbBuild.StatusID {
+	case database.BuildScheduling:
+		timeout = t.scheduling
+	case database.BuildRunning:
+		timeout = t.running
+	}
+	if timeout <= 0 {
+		return time.Time{}, false
+	}
+	since, ok := buildStatusSince(dbBuild)
+	if !ok {
+		return time.Time{}, false
+	}
+	return since.Add(timeout), true
+}
+
+// buildStatusSince returns when the build entered its current Scheduling or
+// Running status, or false if the build has any other status.
+//
+// Scheduling builds count from when they were last scheduled, approved,
+// retriggered, sent at their scheduled time, or otherwise updated, while
+// running builds count from when they started.
+func buildStatusSince(dbBuild database.Build) (time.Time, bool) {
+	switch dbBuild.StatusID {
+	case database.BuildScheduling:
+		since := dbBuild.ScheduledOn.Time
+		if dbBuild.ReviewedOn.Valid && dbBuild.ReviewedOn.Time.After(since) {
+			since = dbBuild.ReviewedOn.Time
+		}
+		if dbBuild.ScheduleAt.Valid && dbBuild.ScheduleAt.Time.After(since) {
+			since = dbBuild.ScheduleAt.Time
+		}
+		if dbBuild.UpdatedAt != nil && dbBuild.UpdatedAt.After(since) {
+			since = *dbBuild.UpdatedAt
+		}
+		return since, true
+	case database.BuildRunning:
+		since := dbBuild.StartedOn.Time
+		if !dbBuild.StartedOn.Valid && dbBuild.UpdatedAt != nil {
+			since = *dbBuild.UpdatedAt
+		}
+		return since, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// findBuildExpiries returns when each of the builds time out in their current
+// status, mapped by build ID. Builds that do not time out are left out.
+func findBuildExpiries(db *gorm.DB, config CIConfig, dbBuilds []database.Build) (map[uint]time.Time, error) {
+	var projectIDs []uint
+	for _, dbBuild := range dbBuilds {
+		if dbBuild.StatusID == database.BuildScheduling || dbBuild.StatusID == database.BuildRunning {
+			projectIDs = append(projectIDs, dbBuild.ProjectID)
+		}
+	}
+	if len(projectIDs) == 0 {
+		return nil, nil
+	}
+	var dbProjects []database.Project
+	if err := db.
+		Select(database.ProjectFields.ProjectID,
+			database.ProjectFields.SchedulingTimeout,
+			database.ProjectFields.RunningTimeout).
+		Where(fmt.Sprintf("%s IN ?", database.ProjectColumns.ProjectID), projectIDs).
+		Find(&dbProjects).
+		Error; err != nil {
+		return nil, err
+	}
+	timeoutsByProjectID := make(map[uint]buildTimeouts, len(dbProjects))
+	for _, dbProject := range dbProjects {
+		timeoutsByProjectID[dbProject.ProjectID] = newBuildTimeouts(config, dbProject)
+	}
+	expiries := make(map[uint]time.Time)
+	for _, dbBuild := range dbBuilds {
+		timeouts, ok := timeoutsByProjectID[dbBuild.ProjectID]
+		if !ok {
+			continue
+		}
+		if expiresOn, ok := timeouts.expiresOn(dbBuild); ok {
+			expiries[dbBuild.BuildID] = expiresOn
+		}
+	}
+	return expiries, nil
+}
+
+// whereBuildStaleScope filters on builds that will time out unless they leave
+// their current Scheduling or Running status in time, or on the builds that
+// will not time out if stale is false. Uses subqueries instead of joins, so
+// that the columns used in the rest of the query stay unambiguous.
+func whereBuildStaleScope(config CIConfig, stale *bool) func(*gorm.DB) *gorm.DB {
+	if stale == nil {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		projectsWithTimeout := func(column database.SafeSQLName) string {
+			return fmt.Sprintf("SELECT %[1]s.%[2]s FROM %[1]s WHERE COALESCE(%[1]s.%[3]s, ?) > 0",
+				database.ProjectTable, database.ProjectColumns.ProjectID, column)
+		}
+		condition := fmt.Sprintf("(%[1]s.%[2]s = ? AND %[1]s.%[3]s IN (%[4]s)) OR (%[1]s.%[2]s = ? AND %[1]s.%[3]s IN (%[5]s))",
+			database.BuildTable, database.BuildColumns.StatusID, database.BuildColumns.ProjectID,
+			projectsWithTimeout(database.ProjectColumns.SchedulingTimeout),
+			projectsWithTimeout(database.ProjectColumns.RunningTimeout))
+		if !*stale {
+			condition = "NOT (" + condition + ")"
+		}
+		return db.Where(condition,
+			int(database.BuildScheduling), int64(config.SchedulingTimeout/time.Second),
+			int(database.BuildRunning), int64(config.RunningTimeout/time.Second))
+	}
+}
+
+// buildWatchdog marks builds as failed when they have been stuck in the
+// Scheduling or Running statuses for longer than their timeouts, such as when
+// the worker running the build crashed, and sends the notifications of the
+// notification rules whose thresholds the builds have exceeded.
+type buildWatchdog struct {
+	db        *gorm.DB
+	config    CIConfig
+	builds    buildModule
+	notifiers map[database.NotifierType]notifier
+	clock     Clock
+	timedOut  metrics.Counter
+}
+
+// newBuildWatchdog creates a new build watchdog, using the same clock as the
+// build module.
+func newBuildWatchdog(db *gorm.DB, config CIConfig, notifiers map[database.NotifierType]notifier, builds buildModule) *buildWatchdog {
+	return &buildWatchdog{
+		db:        db,
+		config:    config,
+		builds:    builds,
+		notifiers: notifiers,
+		clock:     builds.Clock,
+	}
+}
+
+// registerMetrics adds the watchdog's metrics to the registry.
+func (w *buildWatchdog) registerMetrics(r *metrics.Registry) {
+	r.AddCounter("wharf_api_build_timed_out_total",
+		"Number of builds marked as failed for exceeding their scheduling or running timeout.",
+		&w.timedOut)
+}
+
+// start starts the background worker. The worker runs for the remaining
+// lifetime of the process.
+func (w *buildWatchdog) start() {
+	go w.work()
+}
+
+func (w *buildWatchdog) work() {
+	ticker := time.NewTicker(w.config.TimeoutCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := w.failTimedOutBuilds(nowUTC(w.clock)); err != nil {
+			log.Warn().
+				WithError(err).
+				Message("Failed to check for timed out builds.")
+		}
+		if err := w.notifyLongRunningBuilds(nowUTC(w.clock)); err != nil {
+			log.Warn().
+				WithError(err).
+				Message("Failed to check for builds to notify about.")
+		}
+	}
+}
+
+// failTimedOutBuilds marks all builds that have exceeded their timeout at the
+// given time as failed.
+func (w *buildWatchdog) failTimedOutBuilds(now time.Time) error {
+	var dbBuilds []database.Build
+	if err := w.db.
+		Where(fmt.Sprintf("%s IN ?", database.BuildColumns.StatusID),
+			[]int{int(database.BuildScheduling), int(database.BuildRunning)}).
+		Find(&dbBuilds).
+		Error; err != nil {
+		return fmt.Errorf("fetch scheduling and running builds: %w", err)
+	}
+	expiries, err := findBuildExpiries(w.db, w.config, dbBuilds)
+	if err != nil {
+		return fmt.Errorf("fetch build timeouts: %w", err)
+	}
+	for _, dbBuild := range dbBuilds {
+		expiresOn, ok := expiries[dbBuild.BuildID]
+		if !ok || expiresOn.After(now) {
+			continue
+		}
+		_, err := w.builds.updateBuildStatus(dbBuild.BuildID, buildStatusUpdate{
+			status:        database.BuildFailed,
+			statusMessage: buildTimedOutMessage,
+		})
+		if err != nil {
+			log.Warn().
+				WithError(err).
+				WithUint("build", dbBuild.BuildID).
+				Message("Failed to mark timed out build as failed.")
+			continue
+		}
+		w.timedOut.Inc()
+		log.Info().
+			WithUint("build", dbBuild.BuildID).
+			WithString("status", string(modelconv.DBBuildStatusToResponse(dbBuild.StatusID))).
+			WithTime("expiredOn", expiresOn).
+			Message("Marked timed out build as failed.")
+	}
+	return nil
+}
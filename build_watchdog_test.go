@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestNewBuildTimeouts(t *testing.T) {
+	config := CIConfig{SchedulingTimeout: time.Hour, RunningTimeout: 2 * time.Hour}
+
+	timeouts := newBuildTimeouts(config, database.Project{})
+	assert.Equal(t, buildTimeouts{scheduling: time.Hour, running: 2 * time.Hour}, timeouts)
+
+	timeouts = newBuildTimeouts(config, database.Project{
+		SchedulingTimeout: null.IntFrom(0),
+		RunningTimeout:    null.IntFrom(90),
+	})
+	assert.Equal(t, buildTimeouts{scheduling: 0, running: 90 * time.Second}, timeouts)
+}
+
+func TestBuildTimeouts_expiresOn(t *testing.T) {
+	scheduledOn := time.Date(2022, 10, 16, 12, 0, 0, 0, time.UTC)
+	reviewedOn := scheduledOn.Add(10 * time.Minute)
+	startedOn := scheduledOn.Add(20 * time.Minute)
+	timeouts := buildTimeouts{scheduling: time.Hour, running: 2 * time.Hour}
+
+	testCases := []struct {
+		name     string
+		timeouts buildTimeouts
+		build    database.Build
+		want     time.Time
+		wantOK   bool
+	}{
+		{
+			name:     "scheduling",
+			timeouts: timeouts,
+			build: database.Build{
+				StatusID:    database.BuildScheduling,
+				ScheduledOn: null.TimeFrom(scheduledOn),
+			},
+			want:   scheduledOn.Add(time.Hour),
+			wantOK: true,
+		},
+		{
+			name:     "scheduling after approval",
+			timeouts: timeouts,
+			build: database.Build{
+				StatusID:    database.BuildScheduling,
+				ScheduledOn: null.TimeFrom(scheduledOn),
+				ReviewedOn:  null.TimeFrom(reviewedOn),
+			},
+			want:   reviewedOn.Add(time.Hour),
+			wantOK: true,
+		},
+		{
+			name:     "running",
+			timeouts: timeouts,
+			build: database.Build{
+				StatusID:    database.BuildRunning,
+				ScheduledOn: null.TimeFrom(scheduledOn),
+				StartedOn:   null.TimeFrom(startedOn),
+			},
+			want:   startedOn.Add(2 * time.Hour),
+			wantOK: true,
+		},
+		{
+			name:     "running without timeout",
+			timeouts: buildTimeouts{scheduling: time.Hour},
+			build: database.Build{
+				StatusID:  database.BuildRunning,
+				StartedOn: null.TimeFrom(startedOn),
+			},
+		},
+		{
+			name:     "completed",
+			timeouts: timeouts,
+			build: database.Build{
+				StatusID:  database.BuildCompleted,
+				StartedOn: null.TimeFrom(startedOn),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.timeouts.expiresOn(tc.build)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import "time"
+
+// Clock tells the current time. Used instead of calling time.Now directly, so
+// that tests can control the time seen by time-sensitive logic.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock used when none is set, telling the time of the
+// system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// nowUTC returns the current UTC time of the clock, or of the system if the
+// clock is nil.
+func nowUTC(clock Clock) time.Time {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return clock.Now().UTC()
+}
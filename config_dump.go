@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+// redactedConfigValue replaces secrets in the config dump. It is the same
+// value as used by net/url.URL.Redacted.
+const redactedConfigValue = "xxxxx"
+
+type configModule struct {
+	Config *Config
+}
+
+func (m configModule) Register(g *gin.RouterGroup) {
+	g.GET("/admin/config", m.getConfigHandler)
+	g.POST("/admin/config/reload", m.reloadConfigHandler)
+}
+
+// getConfigHandler godoc
+// @id getConfig
+// @summary Get the effective configuration of this wharf-api instance.
+// @description Returns the configuration after merging the config files,
+// @description environment variables, and default values, for debugging
+// @description deployments. Passwords, tokens, and keys are redacted, as well
+// @description as passwords and query parameters in URLs.
+// @description The field names are the same as in the YAML config files.
+// @description Includes the values applied by the latest config reload.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce json
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} object "Effective configuration"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @router /admin/config [get]
+func (m configModule) getConfigHandler(c *gin.Context) {
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	ginrender.Response(c, http.StatusOK, configJSONValue(reflect.ValueOf(m.Config.current().redacted())))
+}
+
+// reloadConfigHandler godoc
+// @id reloadConfig
+// @summary Reload the configuration of this wharf-api instance.
+// @description Reads the config files and environment variables again, and
+// @description applies the config values that can be changed without
+// @description restarting wharf-api: `ci.engine`, `ci.engine2`, `http.cors`,
+// @description and `log.level`. Changes to any other config values are ignored
+// @description until wharf-api is restarted, as told by the `restartRequired`
+// @description field. The previous config is kept if the new config is invalid.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce json
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ConfigReload "Applied config changes"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 422 {object} problem.Response "Invalid config"
+// @router /admin/config/reload [post]
+func (m configModule) reloadConfigHandler(c *gin.Context) {
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	if m.Config.reloadable == nil {
+		ginrender.Response(c, http.StatusOK, response.ConfigReload{Applied: []string{}})
+		return
+	}
+	res, err := m.Config.reloadable.reload()
+	if err != nil {
+		ginutil.WriteProblemError(c, err, problems.AdminConfigInvalid.Newf(
+			"Failed to reload config: %s", err))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, res)
+}
+
+// redacted returns a copy of the config with all secrets redacted. Secrets
+// that are not set are left empty, so that it is still visible that they are
+// missing.
+func (cfg Config) redacted() Config {
+	cfg.CI.TriggerURL = redactURL(cfg.CI.TriggerURL)
+	cfg.CI.TriggerToken = redactString(cfg.CI.TriggerToken)
+	cfg.CI.Engine = cfg.CI.Engine.redacted()
+	cfg.CI.Engine2 = cfg.CI.Engine2.redacted()
+	cfg.HTTP.BasicAuth = redactBasicAuth(cfg.HTTP.BasicAuth)
+	cfg.DB.Password = redactString(cfg.DB.Password)
+	cfg.DB.EncryptionKey = redactString(cfg.DB.EncryptionKey)
+	cfg.Notification.SMTP.Password = redactString(cfg.Notification.SMTP.Password)
+	cfg.Events.NATS.URL = redactURL(cfg.Events.NATS.URL)
+	cfg.Events.NATS.Token = redactString(cfg.Events.NATS.Token)
+	cfg.Events.Kafka.RESTProxyURL = redactURL(cfg.Events.Kafka.RESTProxyURL)
+	cfg.Providers.GitHubWebhookSecret = redactString(cfg.Providers.GitHubWebhookSecret)
+	cfg.Providers.GitLabWebhookToken = redactString(cfg.Providers.GitLabWebhookToken)
+	if cfg.Providers.SyncURLs != nil {
+		syncURLs := make(map[string]string, len(cfg.Providers.SyncURLs))
+		for name, syncURL := range cfg.Providers.SyncURLs {
+			syncURLs[name] = redactURL(syncURL)
+		}
+		cfg.Providers.SyncURLs = syncURLs
+	}
+	return cfg
+}
+
+func (c CIEngineConfig) redacted() CIEngineConfig {
+	c.URL = redactURL(c.URL)
+	c.Token = redactString(c.Token)
+	return c
+}
+
+func redactString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redactedConfigValue
+}
+
+// redactURL redacts the password and all query parameter values of the URL,
+// as webhook URLs, such as for Jenkins, often contain tokens in the query.
+func redactURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedConfigValue
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{redactedConfigValue}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+// redactBasicAuth redacts the passwords of the comma-separated list of
+// username:password pairs, but keeps the usernames.
+func redactBasicAuth(basicAuth string) string {
+	if basicAuth == "" {
+		return ""
+	}
+	pairs := strings.Split(basicAuth, ",")
+	for i, pair := range pairs {
+		username, _, _ := strings.Cut(pair, ":")
+		pairs[i] = username + ":" + redactedConfigValue
+	}
+	return strings.Join(pairs, ",")
+}
+
+// configJSONValue converts the config into maps, slices, and plain values
+// that marshal into JSON with the same field names as in the YAML config
+// files, and with durations formatted like "1h30m0s" instead of as a number
+// of nanoseconds.
+func configJSONValue(v reflect.Value) any {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		obj := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			obj[configFieldName(field.Name)] = configJSONValue(v.Field(i))
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = configJSONValue(v.Index(i))
+		}
+		return list
+	case reflect.Map:
+		obj := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			obj[fmt.Sprint(iter.Key().Interface())] = configJSONValue(iter.Value())
+		}
+		return obj
+	default:
+		return v.Interface()
+	}
+}
+
+// configFieldName lowercases the leading uppercase letters of the Go field
+// name, such as "HTTP" to "http", "AllowOrigins" to "allowOrigins", and
+// "OIDCClaim" to "oidcClaim".
+func configFieldName(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/logger"
+)
+
+// reloadableConfig holds the config values that are applied when reloading
+// the config while wharf-api is running, as described by ReloadConfig. The
+// values are guarded by a mutex, as they are read concurrently by the HTTP
+// handlers.
+type reloadableConfig struct {
+	load func() (Config, error)
+	// reloadMu makes sure only one reload runs at a time.
+	reloadMu sync.Mutex
+
+	mu     sync.RWMutex
+	config Config
+	cors   gin.HandlerFunc
+}
+
+// setupConfigReloads makes the config reloadable, where the load function is
+// used to load the new config on each reload. All copies of the config made
+// after this call share the reloaded values.
+func setupConfigReloads(cfg *Config, load func() (Config, error)) error {
+	cors, _, err := newCORSMiddleware(cfg.HTTP.CORS)
+	if err != nil {
+		return err
+	}
+	live := &reloadableConfig{
+		load:   load,
+		config: *cfg,
+		cors:   cors,
+	}
+	live.config.reloadable = nil
+	cfg.reloadable = live
+	return nil
+}
+
+// current returns the config with the values from the latest reload applied,
+// or the config itself if reloads are not set up.
+func (cfg *Config) current() Config {
+	if cfg.reloadable == nil {
+		return *cfg
+	}
+	cfg.reloadable.mu.RLock()
+	defer cfg.reloadable.mu.RUnlock()
+	current := cfg.reloadable.config
+	current.reloadable = cfg.reloadable
+	return current
+}
+
+// corsMiddleware handles CORS using the CORS config from the latest reload,
+// and does nothing if CORS is disabled.
+func (r *reloadableConfig) corsMiddleware(c *gin.Context) {
+	r.mu.RLock()
+	cors := r.cors
+	r.mu.RUnlock()
+	if cors != nil {
+		cors(c)
+	}
+}
+
+// reload loads the config again and applies its reloadable values. The
+// previous config is kept if the new config is invalid.
+func (r *reloadableConfig) reload() (response.ConfigReload, error) {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	loaded, err := r.load()
+	if err != nil {
+		return response.ConfigReload{}, err
+	}
+	r.mu.RLock()
+	prev := r.config
+	r.mu.RUnlock()
+
+	next := prev
+	next.CI.Engine = loaded.CI.Engine
+	next.CI.Engine2 = loaded.CI.Engine2
+	next.HTTP.CORS = loaded.HTTP.CORS
+	next.Log = loaded.Log
+
+	res := response.ConfigReload{
+		Applied:         []string{},
+		RestartRequired: !reflect.DeepEqual(next, loaded),
+	}
+	if next.CI.Engine != prev.CI.Engine {
+		res.Applied = append(res.Applied, "ci.engine")
+	}
+	if next.CI.Engine2 != prev.CI.Engine2 {
+		res.Applied = append(res.Applied, "ci.engine2")
+	}
+	cors := r.cors
+	if !reflect.DeepEqual(next.HTTP.CORS, prev.HTTP.CORS) {
+		cors, _, err = newCORSMiddleware(next.HTTP.CORS)
+		if err != nil {
+			return response.ConfigReload{}, err
+		}
+		res.Applied = append(res.Applied, "http.cors")
+	}
+	if next.Log != prev.Log {
+		applyLogConfig(next.Log)
+		res.Applied = append(res.Applied, "log.level")
+	}
+
+	r.mu.Lock()
+	r.config = next
+	r.cors = cors
+	r.mu.Unlock()
+
+	log.Info().
+		WithStringf("applied", "%v", res.Applied).
+		Message("Reloaded config.")
+	if res.RestartRequired {
+		log.Warn().Message("Config values that cannot be reloaded were changed, and are ignored until wharf-api is restarted.")
+	}
+	return res, nil
+}
+
+// configFileState is used to detect changes to a config file. The zero value
+// means that the file does not exist.
+type configFileState struct {
+	modTime int64
+	size    int64
+}
+
+// watch reloads the config whenever any of the config files have changed,
+// checking the files once per interval, until the program exits. Meant to be
+// run in a separate goroutine.
+//
+// The files are polled instead of watched via file system events, as config
+// files mounted from Kubernetes ConfigMaps are replaced via symlinks, which
+// file system events do not reliably report.
+func (r *reloadableConfig) watch(files []string, interval time.Duration) {
+	states := statConfigFiles(files)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		newStates := statConfigFiles(files)
+		if reflect.DeepEqual(newStates, states) {
+			continue
+		}
+		states = newStates
+		log.Info().Message("Config files changed. Reloading config.")
+		if _, err := r.reload(); err != nil {
+			log.Warn().
+				WithError(err).
+				Message("Failed to reload config. Keeping the previous config.")
+		}
+	}
+}
+
+func statConfigFiles(files []string) []configFileState {
+	states := make([]configFileState, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		states[i] = configFileState{
+			modTime: info.ModTime().UnixNano(),
+			size:    info.Size(),
+		}
+	}
+	return states
+}
+
+// applyLogConfig sets the global minimum level of all logs. The level must
+// already have been validated.
+func applyLogConfig(config LogConfig) {
+	level, err := logger.ParseLevel(config.Level)
+	if err != nil {
+		return
+	}
+	logLevels.setGlobal(level)
+}
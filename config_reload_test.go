@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReloadableConfig(t *testing.T, load func() (Config, error)) *Config {
+	cfg := DefaultConfig
+	cfg.CI.Engine.URL = "http://jenkins-old"
+	require.NoError(t, setupConfigReloads(&cfg, load))
+	return &cfg
+}
+
+func TestConfigReload_appliesReloadableValues(t *testing.T) {
+	cfg := newTestReloadableConfig(t, func() (Config, error) {
+		loaded := DefaultConfig
+		loaded.CI.Engine.URL = "http://jenkins-new"
+		loaded.HTTP.CORS.AllowOrigins = []string{"https://wharf.example.com"}
+		return loaded, nil
+	})
+	copied := *cfg
+
+	res, err := cfg.reloadable.reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci.engine", "http.cors"}, res.Applied)
+	assert.False(t, res.RestartRequired)
+
+	assert.Equal(t, "http://jenkins-new", copied.current().CI.Engine.URL,
+		"copies of the config share the reloaded values")
+	assert.Equal(t, "http://jenkins-old", cfg.CI.Engine.URL,
+		"the config itself is not modified")
+}
+
+func TestConfigReload_ignoresNonReloadableValues(t *testing.T) {
+	cfg := newTestReloadableConfig(t, func() (Config, error) {
+		loaded := DefaultConfig
+		loaded.CI.Engine.URL = "http://jenkins-old"
+		loaded.HTTP.BindAddress = "0.0.0.0:9090"
+		return loaded, nil
+	})
+
+	res, err := cfg.reloadable.reload()
+	require.NoError(t, err)
+	assert.Empty(t, res.Applied)
+	assert.True(t, res.RestartRequired)
+	assert.Equal(t, "0.0.0.0:8080", cfg.current().HTTP.BindAddress)
+}
+
+func TestConfigReload_keepsConfigOnError(t *testing.T) {
+	cfg := newTestReloadableConfig(t, func() (Config, error) {
+		return Config{}, errors.New("invalid config")
+	})
+
+	_, err := cfg.reloadable.reload()
+	assert.Error(t, err)
+	assert.Equal(t, "http://jenkins-old", cfg.current().CI.Engine.URL)
+}
+
+func TestConfigReload_cors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := newTestReloadableConfig(t, func() (Config, error) {
+		loaded := DefaultConfig
+		loaded.CI.Engine.URL = "http://jenkins-old"
+		loaded.HTTP.CORS.AllowOrigins = []string{"https://*.corp.example.com"}
+		return loaded, nil
+	})
+	r := gin.New()
+	r.Use(cfg.reloadable.corsMiddleware)
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	preflight := func() string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", "https://wharf.corp.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		r.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Empty(t, preflight())
+	_, err := cfg.reloadable.reload()
+	require.NoError(t, err)
+	assert.Equal(t, "https://wharf.corp.example.com", preflight())
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// corsWildcardPattern is the regular expression that each "*" in the
+// CORSConfig.AllowOrigins config is replaced with. It matches one or more
+// subdomains, or a port number, but never a scheme or a path, so that
+// "https://*.example.com" does not match "https://evil.com/.example.com".
+const corsWildcardPattern = `[a-zA-Z0-9.-]+`
+
+// corsOriginMatcher matches request origins against the origins of the
+// CORSConfig that use wildcards or regular expressions.
+type corsOriginMatcher []*regexp.Regexp
+
+// newCORSOriginMatcher compiles the origins from the AllowOrigins config that
+// contain wildcards, and the AllowOriginRegex config. Returns nil if there are
+// no such origins.
+func newCORSOriginMatcher(config CORSConfig) (corsOriginMatcher, error) {
+	var matcher corsOriginMatcher
+	for _, origin := range config.AllowOrigins {
+		if !isCORSWildcardOrigin(origin) {
+			continue
+		}
+		parts := strings.Split(origin, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		re, err := regexp.Compile("^" + strings.Join(parts, corsWildcardPattern) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("CORS origin %q: %w", origin, err)
+		}
+		matcher = append(matcher, re)
+	}
+	for _, pattern := range config.AllowOriginRegex {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("CORS origin regex %q: %w", pattern, err)
+		}
+		matcher = append(matcher, re)
+	}
+	return matcher, nil
+}
+
+// matches returns true if any of the wildcard origins or regular expressions
+// match the entire origin.
+func (m corsOriginMatcher) matches(origin string) bool {
+	for _, re := range m {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCORSWildcardOrigin(origin string) bool {
+	return origin != "*" && strings.Contains(origin, "*")
+}
+
+// newCORSMiddleware creates a Gin middleware that responds to CORS preflight
+// requests and sets the CORS headers, as configured by the CORSConfig, or
+// returns false if CORS is disabled.
+func newCORSMiddleware(config CORSConfig) (gin.HandlerFunc, bool, error) {
+	corsConfig := cors.DefaultConfig()
+	if len(config.AllowMethods) > 0 {
+		corsConfig.AllowMethods = config.AllowMethods
+	}
+	if config.MaxAge > 0 {
+		corsConfig.MaxAge = config.MaxAge
+	}
+	corsConfig.AddAllowHeaders(requestIDHeader)
+	corsConfig.AddAllowHeaders(config.AllowHeaders...)
+	corsConfig.AddExposeHeaders(requestIDHeader)
+	corsConfig.AddExposeHeaders(config.ExposeHeaders...)
+
+	if len(config.AllowOrigins) > 0 || len(config.AllowOriginRegex) > 0 {
+		log.Info().
+			WithStringf("origin", "%v", config.AllowOrigins).
+			WithStringf("originRegex", "%v", config.AllowOriginRegex).
+			Message("Allowing origins in CORS.")
+		matcher, err := newCORSOriginMatcher(config)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, origin := range config.AllowOrigins {
+			if !isCORSWildcardOrigin(origin) {
+				corsConfig.AllowOrigins = append(corsConfig.AllowOrigins, origin)
+			}
+		}
+		if len(matcher) > 0 {
+			corsConfig.AllowOriginFunc = matcher.matches
+		}
+		corsConfig.AddAllowHeaders("Authorization")
+		corsConfig.AllowCredentials = true
+	} else if config.AllowAllOrigins {
+		log.Info().Message("Allowing all origins in CORS.")
+		corsConfig.AllowAllOrigins = true
+	} else {
+		return nil, false, nil
+	}
+	// Validates a copy, as Validate may modify the config, which would then
+	// fail the validation in cors.New.
+	validated := corsConfig
+	if err := validated.Validate(); err != nil {
+		return nil, false, err
+	}
+	return cors.New(corsConfig), true, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORSOriginMatcher(t *testing.T) {
+	matcher, err := newCORSOriginMatcher(CORSConfig{
+		AllowOrigins: []string{
+			"https://wharf.example.com",
+			"https://*.corp.example.com",
+			"http://localhost:*",
+		},
+		AllowOriginRegex: []string{`https://pr-[0-9]+\.preview\.example\.com`},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://wharf.corp.example.com", want: true},
+		{origin: "https://a.b.corp.example.com", want: true},
+		{origin: "https://corp.example.com", want: false},
+		{origin: "http://wharf.corp.example.com", want: false},
+		{origin: "https://evil.com/.corp.example.com", want: false},
+		{origin: "https://evil.com?.corp.example.com", want: false},
+		{origin: "http://localhost:8080", want: true},
+		{origin: "https://pr-123.preview.example.com", want: true},
+		{origin: "https://pr-123.preview.example.com.evil.com", want: false},
+		{origin: "https://xpr-123.preview.example.com", want: false},
+		// Exact origins are matched by the CORS middleware itself.
+		{origin: "https://wharf.example.com", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.origin, func(t *testing.T) {
+			assert.Equal(t, tc.want, matcher.matches(tc.origin))
+		})
+	}
+}
+
+func TestCORSOriginMatcher_invalidRegex(t *testing.T) {
+	_, err := newCORSOriginMatcher(CORSConfig{
+		AllowOriginRegex: []string{`https://(`},
+	})
+	assert.Error(t, err)
+}
+
+func TestCORSMiddleware_preflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mw, ok, err := newCORSMiddleware(CORSConfig{
+		AllowOrigins:  []string{"https://wharf.example.com", "https://*.corp.example.com"},
+		AllowMethods:  []string{http.MethodGet, http.MethodPost},
+		AllowHeaders:  []string{"X-Custom"},
+		ExposeHeaders: []string{"ETag"},
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	r := gin.New()
+	r.Use(mw)
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	testCases := []struct {
+		name       string
+		origin     string
+		wantOrigin string
+	}{
+		{
+			name:       "exact origin",
+			origin:     "https://wharf.example.com",
+			wantOrigin: "https://wharf.example.com",
+		},
+		{
+			name:       "wildcard origin",
+			origin:     "https://wharf.corp.example.com",
+			wantOrigin: "https://wharf.corp.example.com",
+		},
+		{
+			name:       "disallowed origin",
+			origin:     "https://evil.com",
+			wantOrigin: "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodOptions, "/", nil)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.wantOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			if tc.wantOrigin != "" {
+				assert.Equal(t, "GET,POST", w.Header().Get("Access-Control-Allow-Methods"))
+				assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-Custom")
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_disabled(t *testing.T) {
+	_, ok, err := newCORSMiddleware(CORSConfig{})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+// csvExportBatchSize is the number of rows fetched from the database at a
+// time when streaming a list as CSV.
+const csvExportBatchSize = 500
+
+// writeDBQueryCSV streams the results of the query as a CSV response, using
+// the same filters and ordering as the JSON response. The results are fetched
+// and sent in batches, so that exports of for example the entire build history
+// are never held in memory in their entirety.
+//
+// Preloaded associations are skipped, as the CSV rows only contain the
+// top-level fields of each result.
+//
+// Errors after the first batch has been sent cannot be written as problem
+// responses, and instead cut the response short.
+func writeDBQueryCSV[T any](
+	c *gin.Context,
+	dbQuery *gorm.DB,
+	limit, offset int,
+	filename string,
+	header []string,
+	toRecord func(T) []string,
+	errorMessage string,
+) {
+	if offset < 0 {
+		offset = 0
+	}
+	dbQuery = dbQuery.Session(&gorm.Session{}).Model(new(T))
+	dbQuery.Statement.Preloads = nil
+	// Each batch starts from a new session, so that the limit and offset are
+	// not added to the statement that is reused by the next batch.
+	dbQuery = dbQuery.Session(&gorm.Session{})
+
+	var csvWriter *ginrender.CSVWriter
+	written := 0
+	for {
+		batchSize := csvExportBatchSize
+		if limit > 0 {
+			if written >= limit {
+				break
+			}
+			if remaining := limit - written; remaining < batchSize {
+				batchSize = remaining
+			}
+		}
+		var batch []T
+		if err := dbQuery.
+			Limit(batchSize).
+			Offset(offset + written).
+			Find(&batch).
+			Error; err != nil {
+			if csvWriter == nil {
+				ginutil.WriteDBReadError(c, err, errorMessage)
+				return
+			}
+			c.Error(err)
+			log.Error().WithError(err).Message(errorMessage)
+			return
+		}
+		if csvWriter == nil {
+			csvWriter = ginrender.NewCSVWriter(c, filename, header)
+		}
+		for _, row := range batch {
+			csvWriter.Write(toRecord(row))
+		}
+		if err := csvWriter.Flush(); err != nil {
+			c.Error(err)
+			log.Warn().WithError(err).Message("Failed writing CSV response.")
+			return
+		}
+		written += len(batch)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+}
+
+var buildCSVHeader = []string{
+	"buildId", "projectId", "status", "environment", "stage",
+	"gitBranch", "gitCommit", "gitTag", "isInvalid",
+	"scheduledOn", "startedOn", "finishedOn", "duration",
+	"workerId", "engineId", "triggeredByType", "triggeredByName",
+	"statusMessage",
+}
+
+func resBuildToCSVRecord(resBuild response.Build) []string {
+	var engineID string
+	if resBuild.Engine != nil {
+		engineID = resBuild.Engine.ID
+	}
+	return []string{
+		formatCSVUint(resBuild.BuildID),
+		formatCSVUint(resBuild.ProjectID),
+		string(resBuild.Status),
+		resBuild.Environment.String,
+		resBuild.Stage,
+		resBuild.GitBranch,
+		resBuild.GitCommit,
+		resBuild.GitTag,
+		strconv.FormatBool(resBuild.IsInvalid),
+		formatCSVTime(resBuild.ScheduledOn),
+		formatCSVTime(resBuild.StartedOn),
+		formatCSVTime(resBuild.CompletedOn),
+		formatCSVInt(resBuild.Duration),
+		resBuild.WorkerID,
+		engineID,
+		string(resBuild.TriggeredBy.Type),
+		resBuild.TriggeredBy.Name,
+		resBuild.StatusMessage,
+	}
+}
+
+var projectCSVHeader = []string{
+	"projectId", "name", "groupName", "description", "gitUrl",
+	"providerId", "tokenId", "isArchived",
+}
+
+func resProjectToCSVRecord(resProject response.Project) []string {
+	return []string{
+		formatCSVUint(resProject.ProjectID),
+		resProject.Name,
+		resProject.GroupName,
+		resProject.Description,
+		resProject.GitURL,
+		formatCSVUint(resProject.ProviderID),
+		formatCSVUint(resProject.TokenID),
+		strconv.FormatBool(resProject.IsArchived),
+	}
+}
+
+var testResultDetailCSVHeader = []string{
+	"testResultDetailId", "buildId", "artifactId", "name", "status",
+	"message", "startedOn", "completedOn",
+	"className", "filePath", "lineNumber",
+}
+
+func dbTestResultDetailToCSVRecord(dbDetail database.TestResultDetail) []string {
+	resDetail := modelconv.DBTestResultDetailToResponse(dbDetail)
+	return []string{
+		formatCSVUint(resDetail.TestResultDetailID),
+		formatCSVUint(resDetail.BuildID),
+		formatCSVInt(resDetail.ArtifactID),
+		resDetail.Name,
+		string(resDetail.Status),
+		resDetail.Message.String,
+		formatCSVTime(resDetail.StartedOn),
+		formatCSVTime(resDetail.CompletedOn),
+		resDetail.ClassName,
+		resDetail.FilePath,
+		formatCSVInt(resDetail.LineNumber),
+	}
+}
+
+var testResultSummaryCSVHeader = []string{
+	"testResultSummaryId", "buildId", "artifactId", "fileName",
+	"total", "failed", "passed", "skipped",
+}
+
+func dbTestResultSummaryToCSVRecord(dbSummary database.TestResultSummary) []string {
+	resSummary := modelconv.DBTestResultSummaryToResponse(dbSummary)
+	return []string{
+		formatCSVUint(resSummary.TestResultSummaryID),
+		formatCSVUint(resSummary.BuildID),
+		formatCSVInt(resSummary.ArtifactID),
+		resSummary.FileName,
+		formatCSVUint(resSummary.Total),
+		formatCSVUint(resSummary.Failed),
+		formatCSVUint(resSummary.Passed),
+		formatCSVUint(resSummary.Skipped),
+	}
+}
+
+func formatCSVUint(u uint) string {
+	return strconv.FormatUint(uint64(u), 10)
+}
+
+func formatCSVInt(i null.Int) string {
+	if !i.Valid {
+		return ""
+	}
+	return strconv.FormatInt(i.Int64, 10)
+}
+
+func formatCSVTime(t null.Time) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
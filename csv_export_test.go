@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestResBuildToCSVRecord(t *testing.T) {
+	scheduledOn := time.Date(2022, 5, 10, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	resBuild := response.Build{
+		BuildID:     12,
+		ProjectID:   3,
+		Status:      response.BuildCompleted,
+		Environment: null.StringFrom("prod"),
+		GitBranch:   "main",
+		ScheduledOn: null.TimeFrom(scheduledOn),
+		Duration:    null.IntFrom(93500),
+		Engine:      &response.Engine{ID: "primary"},
+		TriggeredBy: response.BuildTriggeredBy{Type: "oidc", Name: "john.doe@example.com"},
+	}
+
+	record := resBuildToCSVRecord(resBuild)
+
+	assert.Len(t, record, len(buildCSVHeader))
+	want := []string{
+		"12", "3", "Completed", "prod", "",
+		"main", "", "", "false",
+		"2022-05-10T10:30:00Z", "", "", "93500",
+		"", "primary", "oidc", "john.doe@example.com",
+		"",
+	}
+	assert.Equal(t, want, record)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/internal/cache"
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"gopkg.in/typ.v4/slices"
+	"gorm.io/gorm"
+)
+
+// dbCacheInvalidatingTables are the tables that the cached projects and branch
+// lists are read from. Any write to these tables clears the caches.
+var dbCacheInvalidatingTables = []string{
+	database.ProjectTable,
+	database.BranchTable,
+	database.ProjectLabelTable,
+	database.ProjectOverridesTable,
+	database.ProviderTable,
+	database.TokenTable,
+}
+
+// dbCache caches responses that are read from the database far more often
+// than they are written to, such as the projects that are polled by
+// dashboards. All caches are cleared on any write to the tables they are read
+// from, which makes sure responses are never outdated when the writes go via
+// this process.
+//
+// A nil *dbCache, or one with nil caches, never caches anything.
+type dbCache struct {
+	// projects caches projects by project ID.
+	projects *cache.Memory[uint, response.Project]
+	// branches caches the branch lists of projects by project ID.
+	branches *cache.Memory[uint, response.PaginatedBranches]
+
+	hits   metrics.Counter
+	misses metrics.Counter
+}
+
+func newDBCache(config CacheConfig) *dbCache {
+	if config.Backend != CacheBackendMemory {
+		return &dbCache{}
+	}
+	log.Info().
+		WithDuration("ttl", config.TTL).
+		Message("Caching projects and branch lists in memory.")
+	return &dbCache{
+		projects: cache.New[uint, response.Project](config.TTL),
+		branches: cache.New[uint, response.PaginatedBranches](config.TTL),
+	}
+}
+
+// registerMetrics adds the cache's metrics to the registry.
+func (dc *dbCache) registerMetrics(r *metrics.Registry) {
+	r.AddCounter("wharf_api_cache_hits_total",
+		"Number of responses served from the cache.",
+		&dc.hits)
+	r.AddCounter("wharf_api_cache_misses_total",
+		"Number of responses not found in the cache, and read from the database.",
+		&dc.misses)
+}
+
+// registerInvalidation adds GORM callbacks that clear the caches after any
+// write to the tables that the cached responses are read from. Raw SQL
+// statements clear the caches regardless of table, as their table is unknown.
+func (dc *dbCache) registerInvalidation(db *gorm.DB) error {
+	if dc == nil || dc.projects == nil {
+		return nil
+	}
+	const name = "wharf:clear_cache"
+	if err := db.Callback().Create().After("gorm:create").Register(name, dc.clearAfterWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register(name, dc.clearAfterWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register(name, dc.clearAfterWrite); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("gorm:raw").Register(name, dc.clearAfterWrite)
+}
+
+func (dc *dbCache) clearAfterWrite(db *gorm.DB) {
+	table := db.Statement.Table
+	if table != "" && !slices.Contains(dbCacheInvalidatingTables, table) {
+		return
+	}
+	dc.projects.Clear()
+	dc.branches.Clear()
+}
+
+func (dc *dbCache) getProject(projectID uint) (response.Project, bool) {
+	if dc == nil || dc.projects == nil {
+		return response.Project{}, false
+	}
+	return countCacheHit(dc, dc.projects, projectID)
+}
+
+func (dc *dbCache) setProject(resProject response.Project) {
+	if dc == nil {
+		return
+	}
+	dc.projects.Set(resProject.ProjectID, resProject)
+}
+
+func (dc *dbCache) getBranchList(projectID uint) (response.PaginatedBranches, bool) {
+	if dc == nil || dc.branches == nil {
+		return response.PaginatedBranches{}, false
+	}
+	return countCacheHit(dc, dc.branches, projectID)
+}
+
+func (dc *dbCache) setBranchList(projectID uint, resBranches response.PaginatedBranches) {
+	if dc == nil {
+		return
+	}
+	dc.branches.Set(projectID, resBranches)
+}
+
+func countCacheHit[V any](dc *dbCache, c *cache.Memory[uint, V], key uint) (V, bool) {
+	value, ok := c.Get(key)
+	if ok {
+		dc.hits.Inc()
+	} else {
+		dc.misses.Inc()
+	}
+	return value, ok
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDBCache_clearAfterWrite(t *testing.T) {
+	testCases := []struct {
+		name      string
+		table     string
+		wantClear bool
+	}{
+		{name: "project", table: database.ProjectTable, wantClear: true},
+		{name: "branch", table: database.BranchTable, wantClear: true},
+		{name: "raw SQL", table: "", wantClear: true},
+		{name: "unrelated", table: database.LogTable, wantClear: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dc := newDBCache(CacheConfig{Backend: CacheBackendMemory, TTL: time.Minute})
+			dc.setProject(response.Project{ProjectID: 1})
+			dc.setBranchList(1, response.PaginatedBranches{})
+
+			dc.clearAfterWrite(&gorm.DB{Statement: &gorm.Statement{Table: tc.table}})
+
+			_, hasProject := dc.getProject(1)
+			_, hasBranches := dc.getBranchList(1)
+			assert.Equal(t, !tc.wantClear, hasProject)
+			assert.Equal(t, !tc.wantClear, hasBranches)
+		})
+	}
+}
+
+func TestDBCache_disabled(t *testing.T) {
+	dc := newDBCache(CacheConfig{Backend: CacheBackendNone, TTL: time.Minute})
+	dc.setProject(response.Project{ProjectID: 1})
+	_, ok := dc.getProject(1)
+	assert.False(t, ok)
+}
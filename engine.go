@@ -1,23 +1,44 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
 )
 
 type engineModule struct {
-	CIConfig *CIConfig
+	Config       *Config
+	HTTPConfig   *HTTPConfig
+	EngineClient *engineClient
 }
 
 func (m engineModule) Register(r *gin.RouterGroup) {
 	r.GET("/engine", m.getEngineList)
+	r.POST("/engine/:engineId/ping", m.pingEngine)
+	r.PUT("/engine/:engineId/token", m.updateEngineToken)
+	r.DELETE("/engine/:engineId/token", m.deleteEngineToken)
 }
 
 // getEngineList godoc
 // @id getEngineList
 // @summary Get list of engines.
+// @description The state of each engine, such as its circuit breaker state and
+// @description the latency of the latest request, is only included after
+// @description the first request has been sent to the engine.
+// @description The status of each engine is the result of the latest
+// @description health check, which are sent periodically in the background.
+// @description The capabilities of each engine are based on the engine's API,
+// @description such as that only `wharf-cmd.v1` engines support canceling
+// @description builds. The resolution order tells the order in which the
+// @description engines are considered when choosing the default engine.
 // @description Added in v5.1.0.
 // @tags engine
 // @produce json
@@ -26,19 +47,176 @@ func (m engineModule) Register(r *gin.RouterGroup) {
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @router /engine [get]
 func (m engineModule) getEngineList(c *gin.Context) {
-	if m.CIConfig == nil {
-		c.JSON(200, response.EngineList{})
+	if m.Config == nil {
+		ginrender.Response(c, http.StatusOK, response.EngineList{})
 		return
 	}
-	conf := *m.CIConfig
+	conf := m.Config.current().CI
 	var res response.EngineList
 	if defaultEng, hasDefault := getDefaultEngineFromConfig(conf); hasDefault {
-		resDefaultEng := convCIEngineToResponse(defaultEng)
+		resDefaultEng := m.convCIEngineToResponseWithState(defaultEng)
 		res.DefaultEngine = &resDefaultEng
 	}
 	engines := getEnginesFromConfig(conf)
-	res.List = convCIEnginesToResponses(engines)
-	renderJSON(c, 200, res)
+	res.List = make([]response.Engine, len(engines))
+	res.ResolutionOrder = make([]string, len(engines))
+	for i, engine := range engines {
+		res.List[i] = m.convCIEngineToResponseWithState(engine)
+		res.ResolutionOrder[i] = engine.ID
+	}
+	ginrender.Response(c, 200, res)
+}
+
+// pingEngine godoc
+// @id pingEngine
+// @summary Check the health of an engine.
+// @description Sends a health check to the engine right away, instead of
+// @description waiting for the next periodic health check. The health check
+// @description is a HEAD request to the engine's URL and never starts a build.
+// @description Added in v5.3.0.
+// @tags engine
+// @produce json
+// @param engineId path string true "Engine ID" example(primary)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Engine "Engine, with the status of the health check"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Engine not found"
+// @router /engine/{engineId}/ping [post]
+func (m engineModule) pingEngine(c *gin.Context) {
+	engine, ok := m.fetchEngineFromParam(c)
+	if !ok {
+		return
+	}
+	m.EngineClient.checkHealth(engine)
+	ginrender.Response(c, 200, m.convCIEngineToResponseWithState(engine))
+}
+
+// updateEngineToken godoc
+// @id updateEngineToken
+// @summary Update the token of an engine.
+// @description Stores the token of the engine in the database, encrypted, which is then used instead of the token from the wharf-api configuration.
+// @description Allows rotating engine tokens without redeploying the wharf-api.
+// @description Requires the `db.encryptionKey` config to be set, and the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags engine
+// @accept json
+// @param engineId path string true "Engine ID" example(primary)
+// @param token body request.EngineTokenUpdate _ "New engine token"
+// @success 204 "Updated"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 404 {object} problem.Response "Engine not found"
+// @failure 409 {object} problem.Response "No database encryption key configured"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /engine/{engineId}/token [put]
+func (m engineModule) updateEngineToken(c *gin.Context) {
+	if !validateIsAdmin(c, *m.HTTPConfig) {
+		return
+	}
+	engine, ok := m.fetchEngineFromParam(c)
+	if !ok {
+		return
+	}
+	var reqTokenUpdate request.EngineTokenUpdate
+	if err := c.ShouldBindJSON(&reqTokenUpdate); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
+		return
+	}
+	err := m.EngineClient.tokens.save(engine.ID, reqTokenUpdate.Token)
+	if errors.Is(err, errEngineTokenNoEncryptionKey) {
+		ginutil.WriteProblemError(c, err, problems.EngineTokenNoEncryptionKey.Newf(
+			"Cannot store the token of engine with ID %q, as no database encryption key is configured.",
+			engine.ID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed writing token of engine with ID %q to database.",
+			engine.ID))
+		return
+	}
+	log.Info().
+		WithString("engine", engine.ID).
+		Message("Updated engine token.")
+	c.Status(http.StatusNoContent)
+}
+
+// deleteEngineToken godoc
+// @id deleteEngineToken
+// @summary Delete the stored token of an engine.
+// @description Deletes the token of the engine that is stored in the database, so that the token from the wharf-api configuration is used again.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags engine
+// @param engineId path string true "Engine ID" example(primary)
+// @success 204 "Deleted"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 404 {object} problem.Response "Engine, or stored engine token, not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /engine/{engineId}/token [delete]
+func (m engineModule) deleteEngineToken(c *gin.Context) {
+	if !validateIsAdmin(c, *m.HTTPConfig) {
+		return
+	}
+	engine, ok := m.fetchEngineFromParam(c)
+	if !ok {
+		return
+	}
+	deleted, err := m.EngineClient.tokens.delete(engine.ID)
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting token of engine with ID %q from database.",
+			engine.ID))
+		return
+	}
+	if !deleted {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Engine with ID %q does not have a token stored in the database.",
+			engine.ID))
+		return
+	}
+	log.Info().
+		WithString("engine", engine.ID).
+		Message("Deleted engine token. Using the token from the config instead.")
+	c.Status(http.StatusNoContent)
+}
+
+func (m engineModule) fetchEngineFromParam(c *gin.Context) (CIEngineConfig, bool) {
+	engineID := c.Param("engineId")
+	var engine CIEngineConfig
+	var ok bool
+	if m.Config != nil {
+		engine, ok = lookupEngineFromConfig(m.Config.current().CI, engineID)
+	}
+	if !ok || engineID == "" {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Engine with ID %q was not found.",
+			engineID))
+		return CIEngineConfig{}, false
+	}
+	return engine, true
+}
+
+func (m engineModule) convCIEngineToResponseWithState(engine CIEngineConfig) response.Engine {
+	resEngine := convCIEngineToResponse(engine)
+	resEngine.State = m.EngineClient.responseState(engine.ID)
+	resCapabilities := getEngineCapabilities(engine.API)
+	resEngine.Capabilities = &resCapabilities
+	m.EngineClient.setResponseStatus(&resEngine)
+	return resEngine
+}
+
+// getEngineCapabilities returns which features an engine supports based on its
+// API. Only wharf-cmd engines report back to wharf-api, such as with the
+// worker ID and logs, and only they can cancel builds.
+func getEngineCapabilities(api CIEngineAPI) response.EngineCapabilities {
+	isWharfCMD := api == CIEngineAPIWharfCMDv1
+	return response.EngineCapabilities{
+		SupportsCancel:           isWharfCMD,
+		SupportsWorkerID:         isWharfCMD,
+		SupportsLogStreamingBack: isWharfCMD,
+	}
 }
 
 func getEnginesFromConfig(ciConf CIConfig) []CIEngineConfig {
@@ -100,14 +278,6 @@ func convCIEngineToResponse(engine CIEngineConfig) response.Engine {
 	}
 }
 
-func convCIEnginesToResponses(engines []CIEngineConfig) []response.Engine {
-	resEngines := make([]response.Engine, len(engines))
-	for i, e := range engines {
-		resEngines[i] = convCIEngineToResponse(e)
-	}
-	return resEngines
-}
-
 func lookupResponseEngineFromConfig(ciConf CIConfig, id string) *response.Engine {
 	engine, ok := lookupEngineFromConfig(ciConf, id)
 	if !ok {
@@ -116,3 +286,4 @@ func lookupResponseEngineFromConfig(ciConf CIConfig, id string) *response.Engine
 	resEngine := convCIEngineToResponse(engine)
 	return &resEngine
 }
+
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gopkg.in/guregu/null.v4"
+)
+
+// engineCircuitOpenError is returned by engineClient.post when the circuit
+// breaker of the engine is open, meaning the engine has failed too many times
+// in a row and no requests are sent to it until the cooldown has passed.
+type engineCircuitOpenError struct {
+	engineID   string
+	retryAfter time.Duration
+}
+
+func (err engineCircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for engine %q is open, retry after %s",
+		err.engineID, err.retryAfter.Round(time.Second))
+}
+
+// engineClient sends requests to the execution engines, with timeouts, bounded
+// retries, and a circuit breaker per engine.
+type engineClient struct {
+	config      CIEngineClientConfig
+	logRequests bool
+	client      *http.Client
+	// tokens holds engine tokens that take precedence over the ones from the
+	// config. Only the config tokens are used if nil.
+	tokens *engineTokenStore
+
+	mu     sync.Mutex
+	states map[string]*engineState
+}
+
+// engineState holds the circuit breaker state and latest request statistics of
+// a single execution engine.
+type engineState struct {
+	consecutiveFailures int
+	openedAt            time.Time
+	isHalfOpenTrial     bool
+
+	lastRequestOn  time.Time
+	lastLatency    time.Duration
+	lastStatusCode int
+	lastError      string
+
+	health engineHealth
+}
+
+func newEngineClient(ciConf CIConfig) *engineClient {
+	return &engineClient{
+		config:      ciConf.EngineClient,
+		logRequests: ciConf.LogEngineRequests,
+		client: &http.Client{
+			Transport: http.DefaultClient.Transport,
+			Timeout:   ciConf.EngineClient.Timeout,
+		},
+		states: make(map[string]*engineState),
+	}
+}
+
+// post sends a POST request to an execution engine. The redacted URL is used in
+// all logging instead of the real URL, as the real URL may contain secrets such
+// as the engine token.
+//
+// Failures where the request never reached the engine, such as refused
+// connections or 502, 503, and 504 responses, are retried with exponential
+// backoff. Other failures are not retried, as the build may already have been
+// triggered.
+//
+// The request is always logged on the debug level. If enabled via
+// CIConfig.LogEngineRequests, the response status and latency is logged on the
+// info level as well.
+//
+// The body, if not nil, is sent as JSON. The request ID, if not empty, is
+// forwarded in the X-Request-ID header.
+//
+// Canceling the context aborts the retry backoff, and returns the context's
+// error. Requests already sent are not aborted, as the engine may already have
+// received them.
+func (c *engineClient) post(ctx context.Context, engine CIEngineConfig, u *url.URL, body []byte, redactedURL, requestID string) (*http.Response, error) {
+	if err := c.allow(engine.ID); err != nil {
+		return nil, err
+	}
+	reqLog := logWithRequestID(requestID)
+	backoff := c.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		reqLog.Debug().
+			WithString("engine", engine.ID).
+			WithString("method", http.MethodPost).
+			WithString("url", redactedURL).
+			WithInt("attempt", attempt+1).
+			Message("Sending request to execution engine.")
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(http.MethodPost, u.String(), reqBody)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if requestID != "" {
+			req.Header.Set(requestIDHeader, requestID)
+		}
+		start := time.Now()
+		resp, err := c.client.Do(req)
+		latency := time.Since(start)
+
+		if c.logRequests {
+			ev := reqLog.Info()
+			if err != nil {
+				ev = reqLog.Warn().WithError(err)
+			} else {
+				ev = ev.WithInt("status", resp.StatusCode)
+			}
+			ev.WithString("engine", engine.ID).
+				WithString("method", http.MethodPost).
+				WithString("url", redactedURL).
+				WithInt("attempt", attempt+1).
+				WithDuration("latency", latency).
+				Message("Execution engine request completed.")
+		}
+
+		if attempt < c.config.MaxRetries && isRetryableEngineFailure(resp, err) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			select {
+			case <-ctx.Done():
+				c.record(engine.ID, latency, resp, err)
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			continue
+		}
+		c.record(engine.ID, latency, resp, err)
+		return resp, err
+	}
+}
+
+func isRetryableEngineFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *engineClient) state(engineID string) *engineState {
+	state, ok := c.states[engineID]
+	if !ok {
+		state = &engineState{}
+		c.states[engineID] = state
+	}
+	return state
+}
+
+// allow returns an error if the circuit breaker of the engine is open. After
+// the cooldown has passed, a single trial request is let through. The circuit
+// is closed again if the trial request succeeds.
+func (c *engineClient) allow(engineID string) error {
+	if c.config.CircuitBreakerThreshold <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.state(engineID)
+	if state.openedAt.IsZero() {
+		return nil
+	}
+	openFor := time.Since(state.openedAt)
+	if openFor < c.config.CircuitBreakerCooldown || state.isHalfOpenTrial {
+		return engineCircuitOpenError{
+			engineID:   engineID,
+			retryAfter: c.config.CircuitBreakerCooldown - openFor,
+		}
+	}
+	state.isHalfOpenTrial = true
+	return nil
+}
+
+func (c *engineClient) record(engineID string, latency time.Duration, resp *http.Response, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.state(engineID)
+	state.lastRequestOn = time.Now()
+	state.lastLatency = latency
+	state.lastStatusCode = 0
+	state.lastError = ""
+	if resp != nil {
+		state.lastStatusCode = resp.StatusCode
+	}
+	if err != nil {
+		state.lastError = err.Error()
+	}
+
+	failed := err != nil || resp.StatusCode >= 500
+	wasHalfOpenTrial := state.isHalfOpenTrial
+	state.isHalfOpenTrial = false
+	if !failed {
+		state.consecutiveFailures = 0
+		state.openedAt = time.Time{}
+		return
+	}
+	state.consecutiveFailures++
+	if c.config.CircuitBreakerThreshold > 0 &&
+		(wasHalfOpenTrial || state.consecutiveFailures >= c.config.CircuitBreakerThreshold) {
+		if state.openedAt.IsZero() || wasHalfOpenTrial {
+			log.Warn().
+				WithString("engine", engineID).
+				WithInt("failures", state.consecutiveFailures).
+				WithDuration("cooldown", c.config.CircuitBreakerCooldown).
+				Message("Opening circuit breaker for execution engine.")
+		}
+		state.openedAt = time.Now()
+	}
+}
+
+// responseState returns the circuit breaker state and latest request
+// statistics of the engine, or nil if no request has been sent to it yet.
+func (c *engineClient) responseState(engineID string) *response.EngineState {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.states[engineID]
+	if !ok || state.lastRequestOn.IsZero() {
+		return nil
+	}
+	circuit := response.EngineCircuitClosed
+	switch {
+	case state.isHalfOpenTrial:
+		circuit = response.EngineCircuitHalfOpen
+	case !state.openedAt.IsZero():
+		circuit = response.EngineCircuitOpen
+	}
+	return &response.EngineState{
+		Circuit:             circuit,
+		ConsecutiveFailures: state.consecutiveFailures,
+		LastRequestOn:       null.NewTime(state.lastRequestOn, !state.lastRequestOn.IsZero()),
+		LastLatency:         state.lastLatency.Milliseconds(),
+		LastStatusCode:      state.lastStatusCode,
+		LastError:           state.lastError,
+	}
+}
+
+// engineToken returns the token of the engine that is stored in the database,
+// or the token from the config if none is stored.
+func (c *engineClient) engineToken(engine CIEngineConfig) (string, error) {
+	if c.tokens == nil {
+		return engine.Token, nil
+	}
+	token, ok, err := c.tokens.lookup(engine.ID)
+	if err != nil {
+		return "", fmt.Errorf("lookup engine token: %w", err)
+	}
+	if !ok {
+		return engine.Token, nil
+	}
+	return token, nil
+}
+
+// triggerBuild sends a request to the execution engine to start a new build,
+// and returns the ID of the worker running the build, if the engine supports it.
+func (c *engineClient) triggerBuild(ctx context.Context, dbJobParams []database.Param, engine CIEngineConfig, requestID string) (string, error) {
+	u, err := url.Parse(engine.URL)
+	if err != nil {
+		return "", fmt.Errorf("parse engine URL: %w", err)
+	}
+	params := make(map[string]string)
+	for _, dbJobParam := range dbJobParams {
+		if dbJobParam.Value != "" {
+			params[dbJobParam.Name] = dbJobParam.Value
+		}
+	}
+	q := url.Values{}
+	var body []byte
+	if engine.ParamsFormat == CIEngineParamsFormatJSON {
+		body, err = json.Marshal(params)
+		if err != nil {
+			return "", fmt.Errorf("encode params as JSON: %w", err)
+		}
+	} else {
+		for name, value := range params {
+			q.Set(name, value)
+		}
+	}
+	token, err := c.engineToken(engine)
+	if err != nil {
+		return "", err
+	}
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	resp, err := c.post(ctx, engine, u, body, redactEngineTriggerURL(*u, dbJobParams), requestID)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+	switch engine.API {
+	case CIEngineAPIWharfCMDv1:
+		if problem.IsHTTPResponse(resp) {
+			prob, err := problem.ParseHTTPResponse(resp)
+			if err != nil {
+				return "", fmt.Errorf("parse response as problem: %w", err)
+			}
+			return "", prob
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return "", fmt.Errorf("non-2xx response: %s", resp.Status)
+		}
+		var worker struct {
+			WorkerID string `json:"workerId"`
+		}
+		dec := json.NewDecoder(resp.Body)
+		if err := dec.Decode(&worker); err != nil {
+			return "", fmt.Errorf("decode wharf-cmd.v1 response: %w", err)
+		}
+		return worker.WorkerID, nil
+
+	default:
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("non-2xx response: %s: %q", resp.Status, string(body))
+		}
+		return "", nil
+	}
+}
+
+// redactEngineTriggerURL returns the URL as a string, but with all secrets
+// redacted so that it can be logged. This includes the user info, the engine
+// token, and all job parameters of type "password".
+func redactEngineTriggerURL(u url.URL, dbJobParams []database.Param) string {
+	u.User = nil
+	q := u.Query()
+	if q.Has("token") {
+		q.Set("token", response.RedactedValue)
+	}
+	for _, dbJobParam := range dbJobParams {
+		if dbJobParam.Type == "password" && q.Has(dbJobParam.Name) {
+			q.Set(dbJobParam.Name, response.RedactedValue)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngineServer(t *testing.T, statusCodes ...int) (*httptest.Server, *int) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statusCodes[len(statusCodes)-1]
+		if calls < len(statusCodes) {
+			status = statusCodes[calls]
+		}
+		calls++
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestEngineClient_retriesUnavailable(t *testing.T) {
+	server, calls := newTestEngineServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	client := newEngineClient(CIConfig{EngineClient: CIEngineClientConfig{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	}})
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	resp, err := client.post(context.Background(), CIEngineConfig{ID: "primary"}, u, nil, server.URL, "")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestEngineClient_retryCanceled(t *testing.T) {
+	server, calls := newTestEngineServer(t, http.StatusServiceUnavailable)
+	client := newEngineClient(CIConfig{EngineClient: CIEngineClientConfig{
+		MaxRetries:   2,
+		RetryBackoff: time.Hour,
+	}})
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = client.post(ctx, CIEngineConfig{ID: "primary"}, u, nil, server.URL, "")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestEngineClient_forwardsRequestID(t *testing.T) {
+	var gotRequestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequestID = r.Header.Get(requestIDHeader)
+	}))
+	t.Cleanup(server.Close)
+	client := newEngineClient(CIConfig{})
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	resp, err := client.post(context.Background(), CIEngineConfig{ID: "primary"}, u, nil, server.URL, "abc-123")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "abc-123", gotRequestID)
+}
+
+func TestEngineClient_doesNotRetryInternalServerError(t *testing.T) {
+	server, calls := newTestEngineServer(t, http.StatusInternalServerError)
+	client := newEngineClient(CIConfig{EngineClient: CIEngineClientConfig{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	}})
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	resp, err := client.post(context.Background(), CIEngineConfig{ID: "primary"}, u, nil, server.URL, "")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, 1, *calls)
+}
+
+func TestEngineClient_circuitBreaker(t *testing.T) {
+	server, calls := newTestEngineServer(t, http.StatusInternalServerError)
+	client := newEngineClient(CIConfig{EngineClient: CIEngineClientConfig{
+		CircuitBreakerThreshold: 2,
+		CircuitBreakerCooldown:  time.Hour,
+	}})
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	engine := CIEngineConfig{ID: "primary"}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.post(context.Background(), engine, u, nil, server.URL, "")
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	_, err = client.post(context.Background(), engine, u, nil, server.URL, "")
+
+	var circuitErr engineCircuitOpenError
+	assert.ErrorAs(t, err, &circuitErr)
+	assert.Equal(t, 2, *calls)
+
+	state := client.responseState(engine.ID)
+	require.NotNil(t, state)
+	assert.Equal(t, response.EngineCircuitOpen, state.Circuit)
+	assert.Equal(t, 2, state.ConsecutiveFailures)
+	assert.Equal(t, http.StatusInternalServerError, state.LastStatusCode)
+}
+
+func TestEngineClient_triggerBuild(t *testing.T) {
+	testCases := []struct {
+		name      string
+		format    CIEngineParamsFormat
+		wantQuery url.Values
+		wantBody  string
+	}{
+		{
+			name:      "query",
+			format:    CIEngineParamsFormatQuery,
+			wantQuery: url.Values{"token": {"secret"}, "REPO_NAME": {"wharf-api"}},
+			wantBody:  "",
+		},
+		{
+			name:      "json",
+			format:    CIEngineParamsFormatJSON,
+			wantQuery: url.Values{"token": {"secret"}},
+			wantBody:  `{"REPO_NAME":"wharf-api"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotQuery url.Values
+			var gotBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotQuery = r.URL.Query()
+				gotBody, _ = io.ReadAll(r.Body)
+			}))
+			t.Cleanup(server.Close)
+			client := newEngineClient(CIConfig{})
+			engine := CIEngineConfig{ID: "primary", URL: server.URL, Token: "secret", ParamsFormat: tc.format}
+			dbJobParams := []database.Param{
+				{Name: "REPO_NAME", Value: "wharf-api"},
+				{Name: "GIT_TAG", Value: ""},
+			}
+
+			_, err := client.triggerBuild(context.Background(), dbJobParams, engine, "")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantQuery, gotQuery)
+			assert.Equal(t, tc.wantBody, string(gotBody))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"gopkg.in/guregu/null.v4"
+)
+
+// engineHealth holds the result of the latest health check sent to an
+// execution engine.
+type engineHealth struct {
+	status    response.EngineStatus
+	checkedOn time.Time
+	err       string
+}
+
+// monitorHealth checks the health of the engines once per interval, until the
+// program exits. The engines are fetched on each check, as they may change
+// when the config is reloaded. Meant to be run in a separate goroutine.
+func (c *engineClient) monitorHealth(engines func() []CIEngineConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, engine := range engines() {
+			c.checkHealth(engine)
+		}
+		<-ticker.C
+	}
+}
+
+// checkHealth sends a HEAD request to the execution engine's URL, including
+// its token, and records the resulting status. As with the build triggers,
+// the real URL is never logged as it may contain secrets.
+//
+// Server errors and failed requests mark the engine as unreachable, while 401
+// and 403 responses mark it as unauthorized. Any other response, such as 405
+// "Method Not Allowed", means the engine is reachable.
+//
+// Health checks are not counted by the circuit breaker.
+func (c *engineClient) checkHealth(engine CIEngineConfig) engineHealth {
+	health := engineHealth{
+		status:    response.EngineStatusUnreachable,
+		checkedOn: time.Now(),
+	}
+	resp, err := c.sendHealthCheck(engine)
+	switch {
+	case err != nil:
+		health.err = err.Error()
+	case resp.StatusCode >= 500:
+		health.err = fmt.Sprintf("server error response: %s", resp.Status)
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		health.status = response.EngineStatusUnauthorized
+		health.err = fmt.Sprintf("unauthorized response: %s", resp.Status)
+	default:
+		health.status = response.EngineStatusReachable
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	c.recordHealth(engine.ID, health)
+	return health
+}
+
+func (c *engineClient) sendHealthCheck(engine CIEngineConfig) (*http.Response, error) {
+	u, err := url.Parse(engine.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse engine URL: %w", err)
+	}
+	token, err := c.engineToken(engine)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return c.client.Head(u.String())
+}
+
+func (c *engineClient) recordHealth(engineID string, health engineHealth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.state(engineID)
+	prevStatus := state.health.status
+	state.health = health
+	if health.status == prevStatus {
+		return
+	}
+	if health.status == response.EngineStatusReachable {
+		log.Info().
+			WithString("engine", engineID).
+			Message("Execution engine is reachable.")
+		return
+	}
+	log.Warn().
+		WithString("engine", engineID).
+		WithString("status", string(health.status)).
+		WithString("error", health.err).
+		Message("Execution engine health check failed.")
+}
+
+// setResponseStatus sets the status fields of the response engine to the
+// result of the latest health check sent to the engine.
+func (c *engineClient) setResponseStatus(resEngine *response.Engine) {
+	resEngine.Status = response.EngineStatusUnknown
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.states[resEngine.ID]
+	if !ok || state.health.status == "" {
+		return
+	}
+	resEngine.Status = state.health.status
+	resEngine.StatusCheckedOn = null.TimeFrom(state.health.checkedOn)
+	resEngine.StatusError = state.health.err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineClient_checkHealth(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		want       response.EngineStatus
+	}{
+		{name: "ok", statusCode: http.StatusOK, want: response.EngineStatusReachable},
+		{name: "method not allowed", statusCode: http.StatusMethodNotAllowed, want: response.EngineStatusReachable},
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, want: response.EngineStatusUnauthorized},
+		{name: "forbidden", statusCode: http.StatusForbidden, want: response.EngineStatusUnauthorized},
+		{name: "server error", statusCode: http.StatusBadGateway, want: response.EngineStatusUnreachable},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, calls := newTestEngineServer(t, tc.statusCode)
+			client := newEngineClient(CIConfig{})
+			engine := CIEngineConfig{ID: "primary", URL: server.URL}
+
+			health := client.checkHealth(engine)
+
+			assert.Equal(t, tc.want, health.status)
+			assert.Equal(t, 1, *calls)
+			resEngine := response.Engine{ID: engine.ID}
+			client.setResponseStatus(&resEngine)
+			assert.Equal(t, tc.want, resEngine.Status)
+			assert.True(t, resEngine.StatusCheckedOn.Valid)
+			assert.Nil(t, client.responseState(engine.ID), "health checks should not count as requests")
+		})
+	}
+}
+
+func TestEngineClient_checkHealthUnreachable(t *testing.T) {
+	server, _ := newTestEngineServer(t, http.StatusOK)
+	server.Close()
+	client := newEngineClient(CIConfig{})
+
+	health := client.checkHealth(CIEngineConfig{ID: "primary", URL: server.URL})
+
+	assert.Equal(t, response.EngineStatusUnreachable, health.status)
+	assert.NotEmpty(t, health.err)
+}
+
+func TestEngineClient_setResponseStatusUnknown(t *testing.T) {
+	client := newEngineClient(CIConfig{})
+	resEngine := response.Engine{ID: "primary"}
+	client.setResponseStatus(&resEngine)
+	assert.Equal(t, response.EngineStatusUnknown, resEngine.Status)
+	assert.False(t, resEngine.StatusCheckedOn.Valid)
+}
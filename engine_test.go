@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngineModule_getEngineListCapabilities(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := DefaultConfig
+	config.CI.Engine = CIEngineConfig{ID: "jenkins", URL: "http://jenkins", API: CIEngineAPIJenkinsGenericWebhookTrigger}
+	config.CI.Engine2 = CIEngineConfig{ID: "wharf-cmd", URL: "http://wharf-cmd", API: CIEngineAPIWharfCMDv1}
+	m := engineModule{Config: &config, EngineClient: newEngineClient(config.CI)}
+
+	r := gin.New()
+	r.GET("/engine", m.getEngineList)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engine", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resEngines response.EngineList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resEngines))
+	assert.Equal(t, []string{"jenkins", "wharf-cmd"}, resEngines.ResolutionOrder)
+	require.Len(t, resEngines.List, 2)
+	require.NotNil(t, resEngines.List[0].Capabilities)
+	assert.Equal(t, response.EngineCapabilities{}, *resEngines.List[0].Capabilities)
+	require.NotNil(t, resEngines.List[1].Capabilities)
+	assert.Equal(t, response.EngineCapabilities{
+		SupportsCancel:           true,
+		SupportsWorkerID:         true,
+		SupportsLogStreamingBack: true,
+	}, *resEngines.List[1].Capabilities)
+	require.NotNil(t, resEngines.DefaultEngine)
+	assert.Equal(t, "jenkins", resEngines.DefaultEngine.ID)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/iver-wharf/wharf-api/v5/internal/secretbox"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errEngineTokenNoEncryptionKey = errors.New("no database encryption key configured")
+
+// engineTokenStore stores the tokens of the execution engines in the database,
+// encrypted, which take precedence over the tokens from the config.
+type engineTokenStore struct {
+	db *gorm.DB
+	// box is nil if no database encryption key is configured, in which case
+	// no engine tokens can be stored.
+	box *secretbox.Box
+}
+
+func newEngineTokenStore(db *gorm.DB, encryptionKey string) (*engineTokenStore, error) {
+	store := &engineTokenStore{db: db}
+	if encryptionKey != "" {
+		box, err := secretbox.New(encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		store.box = box
+	}
+	return store, nil
+}
+
+// lookup returns the stored token of the engine, or false if no token is
+// stored for the engine.
+func (s *engineTokenStore) lookup(engineID string) (string, bool, error) {
+	var dbEngineTokens []database.EngineToken
+	if err := s.db.
+		Where(&database.EngineToken{EngineID: engineID}, database.EngineTokenFields.EngineID).
+		Limit(1).
+		Find(&dbEngineTokens).
+		Error; err != nil {
+		return "", false, err
+	}
+	if len(dbEngineTokens) == 0 {
+		return "", false, nil
+	}
+	if s.box == nil {
+		return "", false, fmt.Errorf("decrypt engine token: %w", errEngineTokenNoEncryptionKey)
+	}
+	token, err := s.box.Decrypt(dbEngineTokens[0].Value)
+	if err != nil {
+		return "", false, fmt.Errorf("decrypt engine token: %w", err)
+	}
+	return token, true, nil
+}
+
+// save encrypts and stores the token of the engine, replacing any previously
+// stored token.
+func (s *engineTokenStore) save(engineID, token string) error {
+	if s.box == nil {
+		return errEngineTokenNoEncryptionKey
+	}
+	value, err := s.box.Encrypt(token)
+	if err != nil {
+		return fmt.Errorf("encrypt engine token: %w", err)
+	}
+	return s.db.
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: database.EngineTokenColumns.EngineID}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				database.EngineTokenColumns.Value,
+				database.EngineTokenColumns.UpdatedAt,
+			}),
+		}).
+		Create(&database.EngineToken{EngineID: engineID, Value: value}).
+		Error
+}
+
+// delete removes the stored token of the engine, so that the token from the
+// config is used again. Returns false if no token was stored for the engine.
+func (s *engineTokenStore) delete(engineID string) (bool, error) {
+	result := s.db.
+		Where(&database.EngineToken{EngineID: engineID}, database.EngineTokenFields.EngineID).
+		Delete(&database.EngineToken{})
+	return result.RowsAffected > 0, result.Error
+}
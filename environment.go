@@ -0,0 +1,435 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/builddef"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gorm.io/gorm"
+)
+
+type environmentModule struct {
+	Database *gorm.DB
+}
+
+func (m environmentModule) Register(g *gin.RouterGroup) {
+	projectEnvironment := g.Group("/project/:projectId/environment")
+	{
+		projectEnvironment.GET("", m.getProjectEnvironmentListHandler)
+		projectEnvironment.POST("", m.createProjectEnvironmentHandler)
+
+		environmentByID := projectEnvironment.Group("/:environmentId")
+		{
+			environmentByID.GET("", m.getProjectEnvironmentHandler)
+			environmentByID.PUT("", m.updateProjectEnvironmentHandler)
+			environmentByID.DELETE("", m.deleteProjectEnvironmentHandler)
+		}
+	}
+}
+
+// getProjectEnvironmentListHandler godoc
+// @id getProjectEnvironmentList
+// @summary Get list of environments.
+// @description List all environments of a project, ordered by name.
+// @description Each environment includes a summary of the latest build that targeted it, if any,
+// @description which is useful for deployment dashboards.
+// @description Added in v5.3.0.
+// @tags environment
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedEnvironments "Environments"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/environment [get]
+func (m environmentModule) getProjectEnvironmentListHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching list of environments for project") {
+		return
+	}
+	var dbEnvs []database.Environment
+	err := m.Database.
+		Where(&database.Environment{ProjectID: projectID}, database.EnvironmentFields.ProjectID).
+		Order(database.EnvironmentColumns.Name).
+		Find(&dbEnvs).Error
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching list of environments for project with ID %d.",
+			projectID))
+		return
+	}
+	envNames := make([]string, len(dbEnvs))
+	for i, dbEnv := range dbEnvs {
+		envNames[i] = dbEnv.Name
+	}
+	dbLastBuilds, err := findLastBuildPerEnvironment(m.Database, projectID, envNames)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching latest builds per environment for project with ID %d.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, response.PaginatedEnvironments{
+		List:       modelconv.DBEnvironmentsToResponses(dbEnvs, dbLastBuilds),
+		TotalCount: int64(len(dbEnvs)),
+	})
+}
+
+// getProjectEnvironmentHandler godoc
+// @id getProjectEnvironment
+// @summary Get environment.
+// @description Added in v5.3.0.
+// @tags environment
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param environmentId path uint true "environment ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Environment "Environment"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Environment not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/environment/{environmentId} [get]
+func (m environmentModule) getProjectEnvironmentHandler(c *gin.Context) {
+	dbEnv, ok := m.fetchEnvironmentFromParams(c, "")
+	if !ok {
+		return
+	}
+	m.renderEnvironmentWithLastBuild(c, http.StatusOK, dbEnv)
+}
+
+// createProjectEnvironmentHandler godoc
+// @id createProjectEnvironment
+// @summary Add environment to project.
+// @description Added in v5.3.0.
+// @tags environment
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param environment body request.Environment true "Environment object"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 201 {object} response.Environment "Created environment"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 409 {object} problem.Response "Environment name already exists in project"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/environment [post]
+func (m environmentModule) createProjectEnvironmentHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqEnv request.Environment
+	if err := c.ShouldBindJSON(&reqEnv); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for environment object to create.")
+		return
+	}
+	if !validateEnvironmentFields(c, reqEnv.Name, reqEnv.Description) {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when creating environment for project") {
+		return
+	}
+	if !m.validateEnvironmentNameIsFree(c, projectID, reqEnv.Name, 0) {
+		return
+	}
+	dbEnv := modelconv.ReqEnvironmentToDatabase(projectID, reqEnv)
+	if err := m.Database.Create(&dbEnv).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed creating environment with name %q for project with ID %d.",
+			reqEnv.Name, projectID))
+		return
+	}
+	m.renderEnvironmentWithLastBuild(c, http.StatusCreated, dbEnv)
+}
+
+// updateProjectEnvironmentHandler godoc
+// @id updateProjectEnvironment
+// @summary Update environment.
+// @description Updates an environment by replacing all of its fields.
+// @description Renaming an environment does not affect the environment name of existing builds.
+// @description Added in v5.3.0.
+// @tags environment
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param environmentId path uint true "environment ID" minimum(0)
+// @param environment body request.EnvironmentUpdate true "New environment values"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Environment "Updated environment"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Environment not found"
+// @failure 409 {object} problem.Response "Environment name already exists in project"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/environment/{environmentId} [put]
+func (m environmentModule) updateProjectEnvironmentHandler(c *gin.Context) {
+	var reqEnvUpdate request.EnvironmentUpdate
+	if err := c.ShouldBindJSON(&reqEnvUpdate); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for environment object to update.")
+		return
+	}
+	if !validateEnvironmentFields(c, reqEnvUpdate.Name, reqEnvUpdate.Description) {
+		return
+	}
+	dbEnv, ok := m.fetchEnvironmentFromParams(c, "when updating environment")
+	if !ok {
+		return
+	}
+	if !m.validateEnvironmentNameIsFree(c, dbEnv.ProjectID, reqEnvUpdate.Name, dbEnv.EnvironmentID) {
+		return
+	}
+	dbEnv.Name = reqEnvUpdate.Name
+	dbEnv.Description = reqEnvUpdate.Description
+	dbEnv.IsProtected = reqEnvUpdate.IsProtected
+	if err := m.Database.Save(&dbEnv).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed writing environment with ID %d to database.",
+			dbEnv.EnvironmentID))
+		return
+	}
+	m.renderEnvironmentWithLastBuild(c, http.StatusOK, dbEnv)
+}
+
+// deleteProjectEnvironmentHandler godoc
+// @id deleteProjectEnvironment
+// @summary Delete environment.
+// @description Existing builds that targeted the environment are left untouched.
+// @description Environments declared in the project's build definition will be
+// @description rediscovered on the next project update.
+// @description Added in v5.3.0.
+// @tags environment
+// @param projectId path uint true "project ID" minimum(0)
+// @param environmentId path uint true "environment ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Environment not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/environment/{environmentId} [delete]
+func (m environmentModule) deleteProjectEnvironmentHandler(c *gin.Context) {
+	dbEnv, ok := m.fetchEnvironmentFromParams(c, "when deleting environment")
+	if !ok {
+		return
+	}
+	if err := m.Database.Delete(&dbEnv).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting environment with ID %d from database.",
+			dbEnv.EnvironmentID))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (m environmentModule) renderEnvironmentWithLastBuild(c *gin.Context, code int, dbEnv database.Environment) {
+	dbLastBuilds, err := findLastBuildPerEnvironment(m.Database, dbEnv.ProjectID, []string{dbEnv.Name})
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching latest build for environment with ID %d.",
+			dbEnv.EnvironmentID))
+		return
+	}
+	var dbLastBuildPtr *database.Build
+	if dbLastBuild, ok := dbLastBuilds[dbEnv.Name]; ok {
+		dbLastBuildPtr = &dbLastBuild
+	}
+	ginrender.Response(c, code, modelconv.DBEnvironmentToResponse(dbEnv, dbLastBuildPtr))
+}
+
+func (m environmentModule) fetchEnvironmentFromParams(c *gin.Context, whenMsg string) (database.Environment, bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return database.Environment{}, false
+	}
+	envID, ok := ginutil.ParseParamUint(c, "environmentId")
+	if !ok {
+		return database.Environment{}, false
+	}
+	var dbEnv database.Environment
+	err := m.Database.
+		Where(&database.Environment{ProjectID: projectID}, database.EnvironmentFields.ProjectID).
+		First(&dbEnv, envID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Environment with ID %d was not found on project with ID %d%s.",
+			envID, projectID, spaceWhenMessage(whenMsg)))
+		return database.Environment{}, false
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching environment with ID %d on project with ID %d%s.",
+			envID, projectID, spaceWhenMessage(whenMsg)))
+		return database.Environment{}, false
+	}
+	return dbEnv, true
+}
+
+func (m environmentModule) validateEnvironmentNameIsFree(c *gin.Context, projectID uint, name string, ignoreEnvID uint) bool {
+	var dbEnvs []database.Environment
+	err := m.Database.
+		Where(&database.Environment{ProjectID: projectID, Name: name},
+			database.EnvironmentFields.ProjectID,
+			database.EnvironmentFields.Name).
+		Find(&dbEnvs).Error
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed checking if environment name %q is taken in project with ID %d.",
+			name, projectID))
+		return false
+	}
+	for _, dbEnv := range dbEnvs {
+		if dbEnv.EnvironmentID != ignoreEnvID {
+			ginutil.WriteProblem(c, problems.EnvironmentNameConflict.With(problem.Response{
+				Detail: fmt.Sprintf(
+					"An environment named %q already exists in project with ID %d, with environment ID %d.",
+					name, projectID, dbEnv.EnvironmentID),
+				Instance: c.Request.RequestURI + "#name",
+			}))
+			return false
+		}
+	}
+	return true
+}
+
+func validateEnvironmentFields(c *gin.Context, name, description string) bool {
+	if len(name) > database.EnvironmentSizes.Name {
+		err := fmt.Errorf("environment name too long: %d characters", len(name))
+		writeInvalidBindError(c, err, fmt.Sprintf(
+			"The environment name must not be longer than %d characters.",
+			database.EnvironmentSizes.Name))
+		return false
+	}
+	if len(description) > database.EnvironmentSizes.Description {
+		err := fmt.Errorf("environment description too long: %d characters", len(description))
+		writeInvalidBindError(c, err, fmt.Sprintf(
+			"The environment description must not be longer than %d characters.",
+			database.EnvironmentSizes.Description))
+		return false
+	}
+	return true
+}
+
+// isEnvironmentProtected returns true if the project has a protected
+// environment by the given name.
+func isEnvironmentProtected(db *gorm.DB, projectID uint, name string) (bool, error) {
+	var count int64
+	err := db.
+		Model(&database.Environment{}).
+		Where(&database.Environment{ProjectID: projectID, Name: name, IsProtected: true},
+			database.EnvironmentFields.ProjectID,
+			database.EnvironmentFields.Name,
+			database.EnvironmentFields.IsProtected).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func findLastBuildPerEnvironment(db *gorm.DB, projectID uint, envNames []string) (map[string]database.Build, error) {
+	dbLastBuilds := make(map[string]database.Build, len(envNames))
+	if len(envNames) == 0 {
+		return dbLastBuilds, nil
+	}
+	lastBuildIDs := db.
+		Model(&database.Build{}).
+		Select("MAX("+database.BuildColumns.BuildID+")").
+		Where(&database.Build{ProjectID: projectID}, database.BuildFields.ProjectID).
+		Where(database.BuildColumns.Environment+" IN ?", asAnySlice(envNames)).
+		Group(database.BuildColumns.Environment)
+	var dbBuilds []database.Build
+	if err := db.
+		Where(database.BuildColumns.BuildID+" IN (?)", lastBuildIDs).
+		Find(&dbBuilds).Error; err != nil {
+		return nil, err
+	}
+	for _, dbBuild := range dbBuilds {
+		dbLastBuilds[dbBuild.Environment.String] = dbBuild
+	}
+	return dbLastBuilds, nil
+}
+
+// discoverProjectEnvironments adds all environments declared in the build
+// definition that are missing from the project. Existing environments are
+// left untouched, so manually added environments are never removed.
+func discoverProjectEnvironments(db *gorm.DB, projectID uint, buildDef string) error {
+	envNames, err := parseBuildDefinitionEnvironmentNames(buildDef)
+	if err != nil {
+		log.Warn().
+			WithError(err).
+			WithUint("project", projectID).
+			Message("Failed to parse build-definition when discovering environments.")
+		return nil
+	}
+	if len(envNames) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var dbOldEnvs []database.Environment
+		if err := tx.
+			Where(&database.Environment{ProjectID: projectID}, database.EnvironmentFields.ProjectID).
+			Find(&dbOldEnvs).Error; err != nil {
+			return err
+		}
+		hasEnvNames := make(map[string]struct{}, len(dbOldEnvs))
+		for _, dbEnv := range dbOldEnvs {
+			hasEnvNames[dbEnv.Name] = struct{}{}
+		}
+		var dbNewEnvs []database.Environment
+		for _, name := range envNames {
+			if _, ok := hasEnvNames[name]; ok {
+				continue
+			}
+			dbNewEnvs = append(dbNewEnvs, database.Environment{
+				ProjectID:    projectID,
+				Name:         name,
+				IsDiscovered: true,
+			})
+		}
+		if len(dbNewEnvs) == 0 {
+			return nil
+		}
+		if err := tx.Create(dbNewEnvs).Error; err != nil {
+			return err
+		}
+		log.Info().
+			WithInt("environmentsAdded", len(dbNewEnvs)).
+			WithUint("project", projectID).
+			Message("Discovered environments from project build definition.")
+		return nil
+	})
+}
+
+// parseBuildDefinitionEnvironmentNames returns the sorted names of the
+// top-level environments declared in a .wharf-ci.yml build definition.
+// Names that do not fit in the database are skipped.
+func parseBuildDefinitionEnvironmentNames(buildDef string) ([]string, error) {
+	def, err := builddef.Parse(buildDef)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(def.Environments))
+	for _, env := range def.Environments {
+		if env.Name == "" || len(env.Name) > database.EnvironmentSizes.Name {
+			continue
+		}
+		names = append(names, env.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
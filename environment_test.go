@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBuildDefinitionEnvironmentNames(t *testing.T) {
+	testCases := []struct {
+		name     string
+		buildDef string
+		want     []string
+	}{
+		{
+			name:     "empty",
+			buildDef: "",
+			want:     []string{},
+		},
+		{
+			name: "no environments",
+			buildDef: `
+myStage:
+  myStep:
+    container:
+      image: alpine
+`,
+			want: []string{},
+		},
+		{
+			name: "sorted names",
+			buildDef: `
+environments:
+  prod:
+    foo: bar
+  dev:
+    foo: moo
+  stage: {}
+`,
+			want: []string{"dev", "prod", "stage"},
+		},
+		{
+			name: "skips too long names",
+			buildDef: `
+environments:
+  dev: {}
+  this-environment-name-is-way-too-long-to-fit-in-db: {}
+`,
+			want: []string{"dev"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseBuildDefinitionEnvironmentNames(tc.buildDef)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// formatETag returns the entity tag (ETag) of a database object version, such
+// as the Project.Version field.
+func formatETag(version uint) string {
+	return strconv.Quote(strconv.FormatUint(uint64(version), 10))
+}
+
+func setETagHeader(c *gin.Context, version uint) {
+	c.Header("ETag", formatETag(version))
+}
+
+func hasIfMatchHeader(c *gin.Context) bool {
+	return c.GetHeader("If-Match") != ""
+}
+
+// validateIfMatch writes a "precondition failed" problem and returns false if
+// the request has an If-Match header that does not match the entity tag of the
+// given version.
+func validateIfMatch(c *gin.Context, version uint, what string) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || ifMatchHeaderMatches(header, version) {
+		return true
+	}
+	writePreconditionFailedProblem(c, what)
+	return false
+}
+
+// ifMatchHeaderMatches returns true if any of the comma-separated entity tags
+// in the If-Match header value matches the version, or if the value is "*".
+// Weak entity tags never match, as If-Match uses strong comparison.
+func ifMatchHeaderMatches(header string, version uint) bool {
+	etag := formatETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func writePreconditionFailedProblem(c *gin.Context, what string) {
+	ginutil.WriteProblem(c, problems.PreconditionFailed.Newf(
+		"The %s has been modified since it was fetched, as its entity tag no longer matches the If-Match header. Fetch the %s again and retry the update.",
+		what, what))
+}
+
+// saveVersioned increments the version and saves the database object. If
+// onlyIfUnmodified is true, then the object is only saved if its version in
+// the database is still the previous version, and false is returned
+// otherwise, such as when another request updated the same object in the
+// meantime.
+func saveVersioned(db *gorm.DB, modelPtr any, version *uint, versionColumn database.SafeSQLName, onlyIfUnmodified bool) (bool, error) {
+	prevVersion := *version
+	*version++
+	if !onlyIfUnmodified {
+		if err := db.Save(modelPtr).Error; err != nil {
+			*version = prevVersion
+			return false, err
+		}
+		return true, nil
+	}
+	// Selecting all fields makes GORM skip creating the object when no rows
+	// were updated.
+	result := db.Select("*").
+		Where(versionColumn+" = ?", prevVersion).
+		Save(modelPtr)
+	if result.Error != nil || result.RowsAffected == 0 {
+		*version = prevVersion
+		return false, result.Error
+	}
+	return true, nil
+}
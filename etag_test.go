@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatchHeaderMatches(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "same version", header: `"3"`, want: true},
+		{name: "other version", header: `"2"`, want: false},
+		{name: "wildcard", header: `*`, want: true},
+		{name: "list with match", header: `"1", "3"`, want: true},
+		{name: "list without match", header: `"1","2"`, want: false},
+		{name: "weak tag", header: `W/"3"`, want: false},
+		{name: "unquoted", header: `3`, want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ifMatchHeaderMatches(tc.header, 3))
+		})
+	}
+}
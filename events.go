@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	v5 "github.com/iver-wharf/wharf-api/v5/api/wharfapi/v5"
+	"github.com/iver-wharf/wharf-api/v5/internal/eventbus"
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gorm.io/gorm"
+)
+
+// busEvents publishes build and project events to the configured message bus.
+// Replaced in main with a publisher using the configured settings, and nil
+// while publishing events is disabled.
+var busEvents *busEventPublisher
+
+// busEventPublisher publishes events to a message bus in the background, so
+// that a slow or unreachable message bus never slows down the API. Events are
+// dropped while the queue is full.
+//
+// All methods do nothing when called on a nil publisher.
+type busEventPublisher struct {
+	publisher   eventbus.Publisher
+	topicPrefix string
+	format      EventsFormat
+	clock       Clock
+	queue       chan response.BusEvent
+
+	published metrics.Counter
+	failed    metrics.Counter
+	dropped   metrics.Counter
+}
+
+// newBusEventPublisher creates a new publisher of the configured message bus,
+// or returns nil if publishing events is disabled.
+func newBusEventPublisher(config EventsConfig) (*busEventPublisher, error) {
+	var publisher eventbus.Publisher
+	switch config.Publisher {
+	case EventsPublisherNATS:
+		nats, err := eventbus.NewNATSPublisher(eventbus.NATSConfig{
+			URL:     config.NATS.URL,
+			Token:   config.NATS.Token,
+			Timeout: config.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		publisher = nats
+	case EventsPublisherKafka:
+		kafka, err := eventbus.NewKafkaRESTPublisher(config.Kafka.RESTProxyURL,
+			&http.Client{Timeout: config.Timeout}, config.Format == EventsFormatProtobuf)
+		if err != nil {
+			return nil, err
+		}
+		publisher = kafka
+	default:
+		return nil, nil
+	}
+	return &busEventPublisher{
+		publisher:   publisher,
+		topicPrefix: config.TopicPrefix,
+		format:      config.Format,
+		queue:       make(chan response.BusEvent, config.QueueSize),
+	}, nil
+}
+
+// registerMetrics adds the publisher's metrics to the registry.
+func (p *busEventPublisher) registerMetrics(r *metrics.Registry) {
+	if p == nil {
+		return
+	}
+	r.AddGaugeFunc("wharf_api_events_queued",
+		"Number of events waiting to be published to the message bus.",
+		func() float64 {
+			return float64(len(p.queue))
+		})
+	r.AddCounter("wharf_api_events_published_total",
+		"Number of events published to the message bus.",
+		&p.published)
+	r.AddCounter("wharf_api_events_failed_total",
+		"Number of events that failed to be published to the message bus.",
+		&p.failed)
+	r.AddCounter("wharf_api_events_dropped_total",
+		"Number of events dropped because the queue was full.",
+		&p.dropped)
+}
+
+// start starts the background worker that publishes the queued events. The
+// worker runs for the remaining lifetime of the process.
+func (p *busEventPublisher) start() {
+	if p == nil {
+		return
+	}
+	go p.work()
+}
+
+func (p *busEventPublisher) work() {
+	for event := range p.queue {
+		p.publish(event)
+	}
+}
+
+func (p *busEventPublisher) publish(event response.BusEvent) {
+	payload, err := p.marshal(event)
+	if err == nil {
+		err = p.publisher.Publish(p.topic(event.Type), busEventKey(event), payload)
+	}
+	if err != nil {
+		p.failed.Inc()
+		log.Warn().
+			WithError(err).
+			WithString("type", string(event.Type)).
+			WithUint("project", event.ProjectID).
+			WithUint("build", event.BuildID).
+			Message("Failed to publish event to message bus.")
+		return
+	}
+	p.published.Inc()
+}
+
+func (p *busEventPublisher) topic(eventType response.BusEventType) string {
+	return p.topicPrefix + "." + string(eventType)
+}
+
+// busEventKey returns the key that keeps the events of the same build, or
+// the same project for project events, in order on partitioned topics.
+func busEventKey(event response.BusEvent) string {
+	if event.BuildID != 0 {
+		return strconv.FormatUint(uint64(event.BuildID), 10)
+	}
+	return strconv.FormatUint(uint64(event.ProjectID), 10)
+}
+
+// marshal serializes the event using the configured format. In the protobuf
+// format, build status events are serialized as the UpdateBuildStatusRequest
+// message of the gRPC API, and all other events as a google.protobuf.Struct
+// with the same fields as the JSON format.
+func (p *busEventPublisher) marshal(event response.BusEvent) ([]byte, error) {
+	eventJSON, err := json.Marshal(event)
+	if err != nil || p.format != EventsFormatProtobuf {
+		return eventJSON, err
+	}
+	if event.Type == response.BusEventBuildStatus {
+		return proto.Marshal(&v5.UpdateBuildStatusRequest{
+			BuildID:       uint64(event.BuildID),
+			Status:        string(event.Status),
+			StatusMessage: event.Message,
+		})
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(eventJSON, &fields); err != nil {
+		return nil, err
+	}
+	eventStruct, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("convert event to protobuf struct: %w", err)
+	}
+	return proto.Marshal(eventStruct)
+}
+
+// enqueue adds the event to the queue of events to publish, or drops it if
+// the queue is full. Sets the event's timestamp if unset.
+func (p *busEventPublisher) enqueue(event response.BusEvent) {
+	if p == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = nowUTC(p.clock)
+	}
+	select {
+	case p.queue <- event:
+	default:
+		p.dropped.Inc()
+		log.Warn().
+			WithString("type", string(event.Type)).
+			WithUint("project", event.ProjectID).
+			WithUint("build", event.BuildID).
+			Message("Dropped event, as the message bus event queue is full.")
+	}
+}
+
+// publishBuildEvent publishes an event about the build, with the build's
+// current status.
+func (p *busEventPublisher) publishBuildEvent(eventType response.BusEventType, dbBuild database.Build) {
+	p.enqueue(response.BusEvent{
+		Type:      eventType,
+		ProjectID: dbBuild.ProjectID,
+		BuildID:   dbBuild.BuildID,
+		Status:    modelconv.DBBuildStatusToResponse(dbBuild.StatusID),
+	})
+}
+
+// publishBuildStatusEvent publishes an event about the build's status having
+// changed.
+func (p *busEventPublisher) publishBuildStatusEvent(dbBuild database.Build, statusBefore database.BuildStatus, message string) {
+	p.enqueue(response.BusEvent{
+		Type:         response.BusEventBuildStatus,
+		ProjectID:    dbBuild.ProjectID,
+		BuildID:      dbBuild.BuildID,
+		StatusBefore: modelconv.DBBuildStatusToResponse(statusBefore),
+		Status:       modelconv.DBBuildStatusToResponse(dbBuild.StatusID),
+		Message:      message,
+	})
+}
+
+// registerProjectCallbacks adds GORM callbacks that publish an event after any
+// project is created, updated, or deleted. Only writes of a single project
+// with a known ID are published, and not bulk updates, such as when bumping a
+// project's build number. Events are published even if the surrounding
+// transaction is later rolled back.
+func (p *busEventPublisher) registerProjectCallbacks(db *gorm.DB) error {
+	if p == nil {
+		return nil
+	}
+	const name = "wharf:publish_project_event"
+	if err := db.Callback().Create().After("gorm:create").Register(name,
+		p.projectCallback(response.BusEventProjectCreated)); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register(name,
+		p.projectCallback(response.BusEventProjectUpdated)); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register(name,
+		p.projectCallback(response.BusEventProjectDeleted))
+}
+
+func (p *busEventPublisher) projectCallback(eventType response.BusEventType) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.RowsAffected == 0 {
+			return
+		}
+		dbProject, ok := db.Statement.Model.(*database.Project)
+		if !ok || dbProject.ProjectID == 0 {
+			return
+		}
+		p.enqueue(response.BusEvent{
+			Type:      eventType,
+			ProjectID: dbProject.ProjectID,
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	v5 "github.com/iver-wharf/wharf-api/v5/api/wharfapi/v5"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type fakeEventbusMessage struct {
+	topic   string
+	key     string
+	payload []byte
+}
+
+type fakeEventbusPublisher struct {
+	messages []fakeEventbusMessage
+}
+
+func (p *fakeEventbusPublisher) Publish(topic, key string, payload []byte) error {
+	p.messages = append(p.messages, fakeEventbusMessage{topic, key, payload})
+	return nil
+}
+
+func (p *fakeEventbusPublisher) Close() error {
+	return nil
+}
+
+func newTestBusEventPublisher(format EventsFormat, queueSize int) (*busEventPublisher, *fakeEventbusPublisher) {
+	fake := &fakeEventbusPublisher{}
+	return &busEventPublisher{
+		publisher:   fake,
+		topicPrefix: "wharf",
+		format:      format,
+		clock:       &fakeClock{time.Date(2022, 10, 16, 12, 0, 0, 0, time.UTC)},
+		queue:       make(chan response.BusEvent, queueSize),
+	}, fake
+}
+
+// drain publishes all queued events, as the worker would.
+func (p *busEventPublisher) drain() {
+	for len(p.queue) > 0 {
+		p.publish(<-p.queue)
+	}
+}
+
+func TestBusEventPublisher_jsonFormat(t *testing.T) {
+	p, fake := newTestBusEventPublisher(EventsFormatJSON, 10)
+	p.publishBuildStatusEvent(database.Build{
+		BuildID:   5,
+		ProjectID: 2,
+		StatusID:  database.BuildFailed,
+	}, database.BuildRunning, "oh no")
+	p.drain()
+
+	require.Len(t, fake.messages, 1)
+	assert.Equal(t, "wharf.build.status", fake.messages[0].topic)
+	assert.Equal(t, "5", fake.messages[0].key)
+	assert.JSONEq(t, `{
+		"type": "build.status",
+		"timestamp": "2022-10-16T12:00:00Z",
+		"projectId": 2,
+		"buildId": 5,
+		"statusBefore": "Running",
+		"status": "Failed",
+		"message": "oh no"
+	}`, string(fake.messages[0].payload))
+}
+
+func TestBusEventPublisher_protobufFormat(t *testing.T) {
+	p, fake := newTestBusEventPublisher(EventsFormatProtobuf, 10)
+	p.publishBuildStatusEvent(database.Build{
+		BuildID:   5,
+		ProjectID: 2,
+		StatusID:  database.BuildCompleted,
+	}, database.BuildRunning, "done")
+	p.enqueue(response.BusEvent{Type: response.BusEventProjectDeleted, ProjectID: 2})
+	p.drain()
+
+	require.Len(t, fake.messages, 2)
+	var statusReq v5.UpdateBuildStatusRequest
+	require.NoError(t, proto.Unmarshal(fake.messages[0].payload, &statusReq))
+	assert.Equal(t, uint64(5), statusReq.BuildID)
+	assert.Equal(t, "Completed", statusReq.Status)
+	assert.Equal(t, "done", statusReq.StatusMessage)
+
+	assert.Equal(t, "wharf.project.deleted", fake.messages[1].topic)
+	assert.Equal(t, "2", fake.messages[1].key)
+	var projectEvent structpb.Struct
+	require.NoError(t, proto.Unmarshal(fake.messages[1].payload, &projectEvent))
+	assert.Equal(t, map[string]any{
+		"type":      "project.deleted",
+		"timestamp": "2022-10-16T12:00:00Z",
+		"projectId": 2.0,
+	}, projectEvent.AsMap())
+}
+
+func TestBusEventPublisher_dropsWhenQueueIsFull(t *testing.T) {
+	p, fake := newTestBusEventPublisher(EventsFormatJSON, 1)
+	p.publishBuildEvent(response.BusEventBuildCreated, database.Build{BuildID: 1})
+	p.publishBuildEvent(response.BusEventBuildCreated, database.Build{BuildID: 2})
+	p.drain()
+
+	require.Len(t, fake.messages, 1)
+	assert.Equal(t, "1", fake.messages[0].key)
+	assert.Equal(t, uint64(1), p.published.Value())
+	assert.Equal(t, uint64(1), p.dropped.Value())
+}
+
+func TestBusEventPublisher_nilIsDisabled(t *testing.T) {
+	var p *busEventPublisher
+	assert.NotPanics(t, func() {
+		p.publishBuildEvent(response.BusEventBuildCreated, database.Build{BuildID: 1})
+		p.start()
+	})
+}
+
+func TestBusEventPublisher_projectCallbacks(t *testing.T) {
+	db := newTestSqliteDB(t)
+	p, fake := newTestBusEventPublisher(EventsFormatJSON, 10)
+	require.NoError(t, p.registerProjectCallbacks(db))
+
+	dbProject := database.Project{Name: "wharf-api"}
+	require.NoError(t, db.Create(&dbProject).Error)
+	dbProject.Description = "Updated"
+	require.NoError(t, db.Save(&dbProject).Error)
+	_, err := nextBuildNumber(db, dbProject.ProjectID)
+	require.NoError(t, err)
+	require.NoError(t, db.Delete(&dbProject).Error)
+	require.NoError(t, db.Create(&database.Branch{ProjectID: dbProject.ProjectID, Name: "master"}).Error)
+	p.drain()
+
+	var gotTypes []string
+	for _, msg := range fake.messages {
+		var event response.BusEvent
+		require.NoError(t, json.Unmarshal(msg.payload, &event))
+		assert.Equal(t, dbProject.ProjectID, event.ProjectID)
+		gotTypes = append(gotTypes, string(event.Type))
+	}
+	assert.Equal(t, []string{"project.created", "project.updated", "project.deleted"}, gotTypes)
+}
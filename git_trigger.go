@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gorm.io/gorm"
+)
+
+type gitTriggerModule struct {
+	Database *gorm.DB
+}
+
+func (m gitTriggerModule) Register(g *gin.RouterGroup) {
+	gitTrigger := g.Group("/project/:projectId/git-trigger")
+	{
+		gitTrigger.GET("", m.getGitTriggerListHandler)
+		gitTrigger.POST("", m.createGitTriggerHandler)
+
+		gitTriggerByID := gitTrigger.Group("/:gitTriggerId")
+		{
+			gitTriggerByID.GET("", m.getGitTriggerHandler)
+			gitTriggerByID.PUT("", m.updateGitTriggerHandler)
+			gitTriggerByID.DELETE("", m.deleteGitTriggerHandler)
+		}
+	}
+}
+
+// getGitTriggerListHandler godoc
+// @id getGitTriggerList
+// @summary Get list of Git triggers.
+// @description List all Git triggers that start builds of the project when
+// @description its Git repository receives a push or merge request, as
+// @description reported by the POST /webhook/github and /webhook/gitlab
+// @description endpoints.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedGitTriggers "Git triggers"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/git-trigger [get]
+func (m gitTriggerModule) getGitTriggerListHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching list of Git triggers for project") {
+		return
+	}
+	var dbTriggers []database.GitTrigger
+	err := m.Database.
+		Where(&database.GitTrigger{ProjectID: projectID}, database.GitTriggerFields.ProjectID).
+		Order(database.GitTriggerColumns.GitTriggerID).
+		Find(&dbTriggers).Error
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching list of Git triggers for project with ID %d.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, response.PaginatedGitTriggers{
+		List:       modelconv.DBGitTriggersToResponses(dbTriggers),
+		TotalCount: int64(len(dbTriggers)),
+	})
+}
+
+// getGitTriggerHandler godoc
+// @id getGitTrigger
+// @summary Get Git trigger.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param gitTriggerId path uint true "Git trigger ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.GitTrigger "Git trigger"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Git trigger not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/git-trigger/{gitTriggerId} [get]
+func (m gitTriggerModule) getGitTriggerHandler(c *gin.Context) {
+	dbTrigger, ok := m.fetchGitTriggerFromParams(c, "")
+	if !ok {
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBGitTriggerToResponse(dbTrigger))
+}
+
+// createGitTriggerHandler godoc
+// @id createGitTrigger
+// @summary Add Git trigger to project.
+// @description Adds a Git trigger that starts a build of the project whenever
+// @description commits are pushed to a branch matching the branch filter, or
+// @description whenever a merge request, or pull request in GitHub, into a
+// @description branch matching the branch filter is opened, reopened, or
+// @description receives new commits. Builds of merge requests run on the
+// @description merge request's source branch and latest commit.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param trigger body request.GitTrigger true "Git trigger object"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 201 {object} response.GitTrigger "Created Git trigger"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/git-trigger [post]
+func (m gitTriggerModule) createGitTriggerHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqTrigger request.GitTrigger
+	if err := c.ShouldBindJSON(&reqTrigger); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for Git trigger object to create.")
+		return
+	}
+	if !validateGitTrigger(c, reqTrigger) {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when creating Git trigger for project") {
+		return
+	}
+	dbTrigger := modelconv.ReqGitTriggerToDatabase(projectID, reqTrigger)
+	if err := m.Database.Create(&dbTrigger).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed creating Git trigger for project with ID %d.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusCreated, modelconv.DBGitTriggerToResponse(dbTrigger))
+}
+
+// updateGitTriggerHandler godoc
+// @id updateGitTrigger
+// @summary Update Git trigger.
+// @description Updates a Git trigger by replacing all of its fields.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param gitTriggerId path uint true "Git trigger ID" minimum(0)
+// @param trigger body request.GitTrigger true "New Git trigger values"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.GitTrigger "Updated Git trigger"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Git trigger not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/git-trigger/{gitTriggerId} [put]
+func (m gitTriggerModule) updateGitTriggerHandler(c *gin.Context) {
+	var reqTrigger request.GitTrigger
+	if err := c.ShouldBindJSON(&reqTrigger); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for Git trigger object to update.")
+		return
+	}
+	if !validateGitTrigger(c, reqTrigger) {
+		return
+	}
+	dbTrigger, ok := m.fetchGitTriggerFromParams(c, "when updating Git trigger")
+	if !ok {
+		return
+	}
+	dbTriggerUpdated := modelconv.ReqGitTriggerToDatabase(dbTrigger.ProjectID, reqTrigger)
+	dbTriggerUpdated.TimeMetadata = dbTrigger.TimeMetadata
+	dbTriggerUpdated.GitTriggerID = dbTrigger.GitTriggerID
+	if err := m.Database.Save(&dbTriggerUpdated).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed writing Git trigger with ID %d to database.",
+			dbTrigger.GitTriggerID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBGitTriggerToResponse(dbTriggerUpdated))
+}
+
+// deleteGitTriggerHandler godoc
+// @id deleteGitTrigger
+// @summary Delete Git trigger.
+// @description Added in v5.3.0.
+// @tags project
+// @param projectId path uint true "project ID" minimum(0)
+// @param gitTriggerId path uint true "Git trigger ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Git trigger not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/git-trigger/{gitTriggerId} [delete]
+func (m gitTriggerModule) deleteGitTriggerHandler(c *gin.Context) {
+	dbTrigger, ok := m.fetchGitTriggerFromParams(c, "when deleting Git trigger")
+	if !ok {
+		return
+	}
+	if err := m.Database.Delete(&dbTrigger).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting Git trigger with ID %d from database.",
+			dbTrigger.GitTriggerID))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (m gitTriggerModule) fetchGitTriggerFromParams(c *gin.Context, whenMsg string) (database.GitTrigger, bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return database.GitTrigger{}, false
+	}
+	triggerID, ok := ginutil.ParseParamUint(c, "gitTriggerId")
+	if !ok {
+		return database.GitTrigger{}, false
+	}
+	var dbTrigger database.GitTrigger
+	err := m.Database.
+		Where(&database.GitTrigger{ProjectID: projectID}, database.GitTriggerFields.ProjectID).
+		First(&dbTrigger, triggerID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Git trigger with ID %d was not found on project with ID %d%s.",
+			triggerID, projectID, spaceWhenMessage(whenMsg)))
+		return database.GitTrigger{}, false
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching Git trigger with ID %d on project with ID %d%s.",
+			triggerID, projectID, spaceWhenMessage(whenMsg)))
+		return database.GitTrigger{}, false
+	}
+	return dbTrigger, true
+}
+
+func validateGitTrigger(c *gin.Context, reqTrigger request.GitTrigger) bool {
+	writeInvalid := func(field, detail string) bool {
+		ginutil.WriteProblem(c, problems.InvalidParam.With(problem.Response{
+			Detail:   detail,
+			Instance: c.Request.RequestURI + "#" + field,
+		}))
+		return false
+	}
+	if reqTrigger.Event != request.GitTriggerPush && reqTrigger.Event != request.GitTriggerMergeRequest {
+		return writeInvalid("event", fmt.Sprintf(
+			"The event must be either %q or %q, but was %q.",
+			request.GitTriggerPush, request.GitTriggerMergeRequest, reqTrigger.Event))
+	}
+	if _, err := path.Match(reqTrigger.BranchFilter, ""); err != nil {
+		return writeInvalid("branchFilter", fmt.Sprintf(
+			"The branch filter %q is not a valid glob pattern.",
+			reqTrigger.BranchFilter))
+	}
+	return true
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// gitWebhookModule holds the GitHub and GitLab webhook receivers, which are
+// authenticated by the webhook secrets in the providers config instead of the
+// wharf-api's regular authentication.
+type gitWebhookModule struct {
+	Builds buildModule
+}
+
+func (m gitWebhookModule) Register(g *gin.RouterGroup) {
+	g.POST("/webhook/github", m.githubWebhookHandler)
+	g.POST("/webhook/gitlab", m.gitlabWebhookHandler)
+}
+
+// gitWebhookEvent is a push or merge request event of a Git repository, as
+// parsed from the payload of a GitHub or GitLab webhook call.
+type gitWebhookEvent struct {
+	event    database.GitTriggerEvent
+	provider request.ProviderName
+	// repoID is the provider's ID of the repository, matched against the
+	// remote project IDs of the projects of the same provider.
+	repoID string
+	// repoURLs are the URLs of the repository, matched against the Git URLs
+	// of the projects.
+	repoURLs []string
+	// branch is the branch to build, which is the pushed branch, or the
+	// source branch of merge requests.
+	branch string
+	// filterBranch is the branch matched against the branch filters of the
+	// Git triggers, which is the pushed branch, or the target branch of merge
+	// requests.
+	filterBranch string
+	commit       string
+	// sender is the provider's username of who pushed the commits or updated
+	// the merge request.
+	sender string
+}
+
+// githubWebhookHandler godoc
+// @id githubWebhook
+// @summary Start builds from a GitHub webhook.
+// @description Receives push and pull request events from GitHub webhooks,
+// @description and starts builds of the projects of the repository, according
+// @description to the projects' Git triggers. Projects are matched by their
+// @description Git URL, or by their remote project ID if their provider is
+// @description named `github`. Pull requests from forks, deleted branches,
+// @description and all other events, such as GitHub's ping event, are ignored.
+// @description Does not use the wharf-api's regular authentication. Instead,
+// @description the body must be signed using the `providers.gitHubWebhookSecret`
+// @description config, as done by GitHub in the `X-Hub-Signature-256` header.
+// @description The builds are triggered in the background, using the default
+// @description execution engine. Builds that fail to start are logged and
+// @description left out of the response.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param X-GitHub-Event header string true "GitHub event name, such as `push` or `pull_request`"
+// @param X-Hub-Signature-256 header string true "HMAC-SHA256 signature of the body, as `sha256=<hex>`"
+// @param payload body object true "GitHub webhook payload"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.GitWebhookResult "Started builds"
+// @failure 400 {object} problem.Response "Bad request, such as invalid body JSON"
+// @failure 401 {object} problem.Response "Missing or invalid signature"
+// @failure 403 {object} problem.Response "No GitHub webhook secret is configured"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /webhook/github [post]
+func (m gitWebhookModule) githubWebhookHandler(c *gin.Context) {
+	secret := m.Builds.Config.current().Providers.GitHubWebhookSecret
+	if secret == "" {
+		ginutil.WriteProblem(c, problems.WebhookNotConfigured.New(
+			"No GitHub webhook secret is configured in wharf-api, via the providers.gitHubWebhookSecret config."))
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		ginutil.WriteBodyReadError(c, err,
+			"Failed to read the body of the GitHub webhook call.")
+		return
+	}
+	if !verifyWebhookSignature(secret, body, c.GetHeader("X-Hub-Signature-256")) {
+		ginutil.WriteProblem(c, problems.WebhookInvalidSignature.New(
+			"The X-Hub-Signature-256 header is missing, or does not hold the signature of the body using the GitHub webhook secret."))
+		return
+	}
+	event, ok, err := parseGitHubWebhookEvent(c.GetHeader("X-GitHub-Event"), body)
+	if err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body of the GitHub webhook call.")
+		return
+	}
+	m.startGitTriggeredBuildsAndRespond(c, event, ok)
+}
+
+// gitlabWebhookHandler godoc
+// @id gitlabWebhook
+// @summary Start builds from a GitLab webhook.
+// @description Receives push and merge request events from GitLab webhooks,
+// @description and starts builds of the projects of the repository, according
+// @description to the projects' Git triggers. Projects are matched by their
+// @description Git URL, or by their remote project ID if their provider is
+// @description named `gitlab`. Merge requests from forks, deleted branches,
+// @description and all other events are ignored.
+// @description Does not use the wharf-api's regular authentication. Instead,
+// @description the `X-Gitlab-Token` header must hold the
+// @description `providers.gitLabWebhookToken` config, as sent by GitLab when
+// @description the webhook's secret token is set.
+// @description The builds are triggered in the background, using the default
+// @description execution engine. Builds that fail to start are logged and
+// @description left out of the response.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param X-Gitlab-Event header string true "GitLab event name, such as `Push Hook` or `Merge Request Hook`"
+// @param X-Gitlab-Token header string true "Secret token of the webhook"
+// @param payload body object true "GitLab webhook payload"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.GitWebhookResult "Started builds"
+// @failure 400 {object} problem.Response "Bad request, such as invalid body JSON"
+// @failure 401 {object} problem.Response "Missing or invalid token"
+// @failure 403 {object} problem.Response "No GitLab webhook token is configured"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /webhook/gitlab [post]
+func (m gitWebhookModule) gitlabWebhookHandler(c *gin.Context) {
+	token := m.Builds.Config.current().Providers.GitLabWebhookToken
+	if token == "" {
+		ginutil.WriteProblem(c, problems.WebhookNotConfigured.New(
+			"No GitLab webhook token is configured in wharf-api, via the providers.gitLabWebhookToken config."))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Gitlab-Token")), []byte(token)) != 1 {
+		ginutil.WriteProblem(c, problems.WebhookInvalidToken.New(
+			"The X-Gitlab-Token header is missing, or does not match the GitLab webhook token."))
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		ginutil.WriteBodyReadError(c, err,
+			"Failed to read the body of the GitLab webhook call.")
+		return
+	}
+	event, ok, err := parseGitLabWebhookEvent(c.GetHeader("X-Gitlab-Event"), body)
+	if err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body of the GitLab webhook call.")
+		return
+	}
+	m.startGitTriggeredBuildsAndRespond(c, event, ok)
+}
+
+// startGitTriggeredBuildsAndRespond starts builds of all projects of the
+// event's repository that have Git triggers matching the event, and responds
+// with the started builds. Responds without starting any builds if the event
+// is to be ignored, as signaled by ok being false.
+func (m gitWebhookModule) startGitTriggeredBuildsAndRespond(c *gin.Context, event gitWebhookEvent, ok bool) {
+	result := response.GitWebhookResult{Builds: []response.GitWebhookBuild{}}
+	if !ok {
+		ginrender.Response(c, http.StatusOK, result)
+		return
+	}
+	result.Event = response.GitTriggerEvent(event.event)
+	db := m.Builds.Database
+	dbProjects, err := findGitWebhookProjects(db, event)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching projects of the %s repository with ID %q.",
+			event.provider, event.repoID))
+		return
+	}
+	if len(dbProjects) == 0 {
+		ginrender.Response(c, http.StatusOK, result)
+		return
+	}
+	dbProjectsByID := make(map[uint]database.Project, len(dbProjects))
+	projectIDs := make([]uint, len(dbProjects))
+	for i, dbProject := range dbProjects {
+		dbProjectsByID[dbProject.ProjectID] = dbProject
+		projectIDs[i] = dbProject.ProjectID
+	}
+	var dbTriggers []database.GitTrigger
+	if err := db.
+		Where(fmt.Sprintf("%s IN ?", database.GitTriggerColumns.ProjectID), projectIDs).
+		Where(&database.GitTrigger{Event: event.event}, database.GitTriggerFields.Event).
+		Order(database.GitTriggerColumns.GitTriggerID).
+		Find(&dbTriggers).Error; err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching Git triggers of the projects of the %s repository with ID %q.",
+			event.provider, event.repoID))
+		return
+	}
+	for _, dbTrigger := range dbTriggers {
+		if !buildTriggerMatchesBranch(dbTrigger.BranchFilter, event.filterBranch) {
+			continue
+		}
+		dbBuild, err := m.startGitTriggeredBuild(dbProjectsByID[dbTrigger.ProjectID], dbTrigger, event)
+		if err != nil {
+			requestLog(c).Warn().
+				WithError(err).
+				WithUint("gitTrigger", dbTrigger.GitTriggerID).
+				WithUint("project", dbTrigger.ProjectID).
+				Message("Failed to start build from Git trigger.")
+			continue
+		}
+		requestLog(c).Info().
+			WithUint("gitTrigger", dbTrigger.GitTriggerID).
+			WithUint("project", dbTrigger.ProjectID).
+			WithUint("build", dbBuild.BuildID).
+			Message("Started build from Git trigger.")
+		result.Builds = append(result.Builds, response.GitWebhookBuild{
+			BuildID:      dbBuild.BuildID,
+			ProjectID:    dbBuild.ProjectID,
+			GitTriggerID: dbTrigger.GitTriggerID,
+		})
+	}
+	ginrender.Response(c, http.StatusOK, result)
+}
+
+func (m gitWebhookModule) startGitTriggeredBuild(dbProject database.Project, dbTrigger database.GitTrigger, event gitWebhookEvent) (database.Build, error) {
+	if dbProject.IsArchived {
+		return database.Build{}, errors.New("project is archived")
+	}
+	engine, ok := lookupEngineOrDefaultFromConfig(m.Builds.Config.current().CI, "")
+	if !ok {
+		return database.Build{}, errors.New("no default execution engine configured")
+	}
+	allowed, err := isProjectEngineAllowed(m.Builds.Database, dbProject.ProjectID, engine)
+	if err != nil {
+		return database.Build{}, err
+	}
+	if !allowed {
+		return database.Build{}, fmt.Errorf("project is not allowed to start builds on the execution engine %q", engine.ID)
+	}
+	stage := dbTrigger.Stage
+	if stage == "" {
+		stage = "ALL"
+	}
+	name := event.sender
+	if name == "" {
+		name = string(event.provider)
+	}
+	if len(name) > database.BuildSizes.TriggeredByName {
+		name = name[:database.BuildSizes.TriggeredByName]
+	}
+	dbBuild := database.Build{
+		ProjectID:          dbProject.ProjectID,
+		GitBranch:          event.branch,
+		GitCommit:          event.commit,
+		Environment:        dbTrigger.Environment,
+		Stage:              stage,
+		EngineID:           engine.ID,
+		TriggeredByType:    database.BuildTriggerWebhook,
+		TriggeredByName:    name,
+		TriggeredBySubject: strconv.FormatUint(uint64(dbTrigger.GitTriggerID), 10),
+	}
+	if err := m.Builds.startBuildInBackground(&dbBuild, dbProject, engine, nil); err != nil {
+		return database.Build{}, err
+	}
+	return dbBuild, nil
+}
+
+// findGitWebhookProjects returns the projects of the event's repository,
+// matched by their Git URL, or by their remote project ID if their provider
+// has the same name as the event's provider.
+func findGitWebhookProjects(db *gorm.DB, event gitWebhookEvent) ([]database.Project, error) {
+	urls := gitURLVariants(event.repoURLs)
+	query := db.Preload(database.ProjectFields.Provider)
+	if event.repoID != "" {
+		query = query.Where(fmt.Sprintf("%s IN ? OR %s = ?",
+			database.ProjectColumns.GitURL, database.ProjectColumns.RemoteProjectID),
+			urls, event.repoID)
+	} else {
+		query = query.Where(fmt.Sprintf("%s IN ?", database.ProjectColumns.GitURL), urls)
+	}
+	var dbProjects []database.Project
+	if err := query.
+		Order(database.ProjectColumns.ProjectID).
+		Find(&dbProjects).Error; err != nil {
+		return nil, err
+	}
+	urlSet := make(map[string]struct{}, len(urls))
+	for _, u := range urls {
+		urlSet[u] = struct{}{}
+	}
+	matched := dbProjects[:0]
+	for _, dbProject := range dbProjects {
+		_, urlMatches := urlSet[dbProject.GitURL]
+		if urlMatches ||
+			(dbProject.Provider != nil && strings.EqualFold(dbProject.Provider.Name, string(event.provider))) {
+			matched = append(matched, dbProject)
+		}
+	}
+	return matched, nil
+}
+
+// gitURLVariants returns the non-empty URLs, both with and without the ".git"
+// suffix, as Git URLs are often stored without it.
+func gitURLVariants(urls []string) []string {
+	var variants []string
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+		u = strings.TrimSuffix(u, ".git")
+		variants = append(variants, u, u+".git")
+	}
+	return uniqueStrings(variants)
+}
+
+// gitBranchFromRef returns the branch name of a Git ref, such as "master" from
+// "refs/heads/master", or false if the ref is not a branch, such as for tags.
+func gitBranchFromRef(ref string) (string, bool) {
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	return branch, branch != ref && branch != ""
+}
+
+// isZeroGitCommit returns true for empty commit SHAs, and for the all-zeros
+// SHA that providers use as the new commit of deleted branches.
+func isZeroGitCommit(commit string) bool {
+	return strings.Trim(commit, "0") == ""
+}
+
+type githubWebhookRepository struct {
+	ID       int64  `json:"id"`
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+	GitURL   string `json:"git_url"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type githubWebhookPayload struct {
+	Ref         string `json:"ref"`
+	After       string `json:"after"`
+	Deleted     bool   `json:"deleted"`
+	Action      string `json:"action"`
+	PullRequest struct {
+		Head struct {
+			Ref  string                   `json:"ref"`
+			SHA  string                   `json:"sha"`
+			Repo *githubWebhookRepository `json:"repo"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+	Repository githubWebhookRepository `json:"repository"`
+	Sender     struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// parseGitHubWebhookEvent parses the payload of a GitHub webhook call, or
+// returns false if the event is to be ignored.
+func parseGitHubWebhookEvent(eventName string, body []byte) (gitWebhookEvent, bool, error) {
+	if eventName != "push" && eventName != "pull_request" {
+		return gitWebhookEvent{}, false, nil
+	}
+	var payload githubWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return gitWebhookEvent{}, false, err
+	}
+	repo := payload.Repository
+	event := gitWebhookEvent{
+		provider: request.ProviderGitHub,
+		repoID:   strconv.FormatInt(repo.ID, 10),
+		repoURLs: []string{repo.CloneURL, repo.SSHURL, repo.GitURL, repo.HTMLURL},
+		sender:   payload.Sender.Login,
+	}
+	if repo.ID == 0 {
+		event.repoID = ""
+	}
+	if eventName == "push" {
+		branch, ok := gitBranchFromRef(payload.Ref)
+		if !ok || payload.Deleted || isZeroGitCommit(payload.After) {
+			return gitWebhookEvent{}, false, nil
+		}
+		event.event = database.GitTriggerPush
+		event.branch = branch
+		event.filterBranch = branch
+		event.commit = payload.After
+		return event, true, nil
+	}
+	switch payload.Action {
+	case "opened", "reopened", "synchronize":
+	default:
+		return gitWebhookEvent{}, false, nil
+	}
+	head := payload.PullRequest.Head
+	if head.Repo == nil || head.Repo.ID != repo.ID {
+		// Pull requests from forks cannot be built from the project's own
+		// repository.
+		return gitWebhookEvent{}, false, nil
+	}
+	event.event = database.GitTriggerMergeRequest
+	event.branch = head.Ref
+	event.filterBranch = payload.PullRequest.Base.Ref
+	event.commit = head.SHA
+	return event, true, nil
+}
+
+type gitlabWebhookPayload struct {
+	Ref          string  `json:"ref"`
+	CheckoutSHA  *string `json:"checkout_sha"`
+	UserUsername string  `json:"user_username"`
+	User         struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		ID         int64  `json:"id"`
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		WebURL     string `json:"web_url"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		Action          string `json:"action"`
+		SourceBranch    string `json:"source_branch"`
+		TargetBranch    string `json:"target_branch"`
+		SourceProjectID int64  `json:"source_project_id"`
+		TargetProjectID int64  `json:"target_project_id"`
+		OldRev          string `json:"oldrev"`
+		LastCommit      struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+}
+
+// parseGitLabWebhookEvent parses the payload of a GitLab webhook call, or
+// returns false if the event is to be ignored.
+func parseGitLabWebhookEvent(eventName string, body []byte) (gitWebhookEvent, bool, error) {
+	if eventName != "Push Hook" && eventName != "Merge Request Hook" {
+		return gitWebhookEvent{}, false, nil
+	}
+	var payload gitlabWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return gitWebhookEvent{}, false, err
+	}
+	project := payload.Project
+	event := gitWebhookEvent{
+		provider: request.ProviderGitLab,
+		repoID:   strconv.FormatInt(project.ID, 10),
+		repoURLs: []string{project.GitHTTPURL, project.GitSSHURL, project.WebURL},
+	}
+	if project.ID == 0 {
+		event.repoID = ""
+	}
+	if eventName == "Push Hook" {
+		branch, ok := gitBranchFromRef(payload.Ref)
+		if !ok || payload.CheckoutSHA == nil || isZeroGitCommit(*payload.CheckoutSHA) {
+			return gitWebhookEvent{}, false, nil
+		}
+		event.event = database.GitTriggerPush
+		event.branch = branch
+		event.filterBranch = branch
+		event.commit = *payload.CheckoutSHA
+		event.sender = payload.UserUsername
+		return event, true, nil
+	}
+	attrs := payload.ObjectAttributes
+	switch {
+	case attrs.Action == "open", attrs.Action == "reopen":
+	case attrs.Action == "update" && attrs.OldRev != "":
+		// Only updates that push new commits have the "oldrev" field.
+	default:
+		return gitWebhookEvent{}, false, nil
+	}
+	if attrs.SourceProjectID != attrs.TargetProjectID {
+		// Merge requests from forks cannot be built from the project's own
+		// repository.
+		return gitWebhookEvent{}, false, nil
+	}
+	event.event = database.GitTriggerMergeRequest
+	event.branch = attrs.SourceBranch
+	event.filterBranch = attrs.TargetBranch
+	event.commit = attrs.LastCommit.ID
+	event.sender = payload.User.Username
+	return event, true, nil
+}
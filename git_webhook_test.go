@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func newTestGitWebhookRouter(t *testing.T) (*gin.Engine, buildModule, database.Project) {
+	gin.SetMode(gin.TestMode)
+	builds, dbProject := newTestInsertBuildModule(t)
+	builds.Config.CI.MockTriggerResponse = true
+	builds.Config.Providers.GitHubWebhookSecret = "github secret"
+	builds.Config.Providers.GitLabWebhookToken = "gitlab token"
+	r := gin.New()
+	gitWebhookModule{Builds: builds}.Register(r.Group("/api"))
+	return r, builds, dbProject
+}
+
+func parseTestGitWebhookResult(t *testing.T, body []byte) response.GitWebhookResult {
+	var result response.GitWebhookResult
+	require.NoError(t, json.Unmarshal(body, &result))
+	return result
+}
+
+func TestGitHubWebhookHandler_push(t *testing.T) {
+	r, builds, dbProject := newTestGitWebhookRouter(t)
+	dbProject.GitURL = "git@github.com:iver-wharf/wharf-api.git"
+	require.NoError(t, builds.Database.Save(&dbProject).Error)
+	dbTriggers := []database.GitTrigger{
+		{ProjectID: dbProject.ProjectID, Event: database.GitTriggerPush, BranchFilter: "master", Environment: null.StringFrom("stage")},
+		{ProjectID: dbProject.ProjectID, Event: database.GitTriggerPush, BranchFilter: "release/*"},
+		{ProjectID: dbProject.ProjectID, Event: database.GitTriggerMergeRequest},
+	}
+	require.NoError(t, builds.Database.Create(&dbTriggers).Error)
+
+	body := `{
+		"ref": "refs/heads/master",
+		"after": "2f1ebd2b70b5d7ad0c9b9c9e6b4cd1a1d3f6d2b1",
+		"repository": {
+			"id": 1234,
+			"clone_url": "https://github.com/iver-wharf/wharf-api.git",
+			"ssh_url": "git@github.com:iver-wharf/wharf-api.git"
+		},
+		"sender": {"login": "octocat"}
+	}`
+	headers := map[string]string{"X-GitHub-Event": "push"}
+	w := serveTestWebhookRequest(r, http.MethodPost, "/api/webhook/github", body, headers)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "must require signature")
+
+	headers["X-Hub-Signature-256"] = signTestWebhookBody("github secret", body)
+	w = serveTestWebhookRequest(r, http.MethodPost, "/api/webhook/github", body, headers)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	result := parseTestGitWebhookResult(t, w.Body.Bytes())
+	assert.Equal(t, response.GitTriggerPush, result.Event)
+	require.Len(t, result.Builds, 1)
+	assert.Equal(t, dbTriggers[0].GitTriggerID, result.Builds[0].GitTriggerID)
+
+	var dbBuild database.Build
+	require.NoError(t, builds.Database.First(&dbBuild, result.Builds[0].BuildID).Error)
+	assert.Equal(t, "master", dbBuild.GitBranch)
+	assert.Equal(t, "2f1ebd2b70b5d7ad0c9b9c9e6b4cd1a1d3f6d2b1", dbBuild.GitCommit)
+	assert.Equal(t, "stage", dbBuild.Environment.String)
+	assert.Equal(t, "ALL", dbBuild.Stage)
+	assert.Equal(t, database.BuildTriggerWebhook, dbBuild.TriggeredByType)
+	assert.Equal(t, "octocat", dbBuild.TriggeredByName)
+
+	pingBody := `{"zen":"Keep it logically awesome."}`
+	w = serveTestWebhookRequest(r, http.MethodPost, "/api/webhook/github", pingBody, map[string]string{
+		"X-GitHub-Event":      "ping",
+		"X-Hub-Signature-256": signTestWebhookBody("github secret", pingBody),
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, parseTestGitWebhookResult(t, w.Body.Bytes()).Builds)
+}
+
+func TestGitLabWebhookHandler_mergeRequest(t *testing.T) {
+	r, builds, dbProject := newTestGitWebhookRouter(t)
+	dbProvider := database.Provider{Name: "gitlab", URL: "https://gitlab.example.com"}
+	require.NoError(t, builds.Database.Create(&dbProvider).Error)
+	dbProject.RemoteProjectID = "15"
+	dbProject.ProviderID = &dbProvider.ProviderID
+	require.NoError(t, builds.Database.Save(&dbProject).Error)
+	dbOtherProject := database.Project{Name: "other", RemoteProjectID: "15"}
+	require.NoError(t, builds.Database.Create(&dbOtherProject).Error)
+	for _, projectID := range []uint{dbProject.ProjectID, dbOtherProject.ProjectID} {
+		require.NoError(t, builds.Database.Create(&database.GitTrigger{
+			ProjectID:    projectID,
+			Event:        database.GitTriggerMergeRequest,
+			BranchFilter: "master",
+			Stage:        "test",
+		}).Error)
+	}
+
+	body := `{
+		"object_kind": "merge_request",
+		"user": {"username": "jsmith"},
+		"project": {"id": 15, "git_http_url": "https://gitlab.example.com/wharf/wharf-api.git"},
+		"object_attributes": {
+			"action": "open",
+			"source_branch": "feature/foo",
+			"target_branch": "master",
+			"source_project_id": 15,
+			"target_project_id": 15,
+			"last_commit": {"id": "da1560886d4f094c3e6c9ef40349f7d38b5d27d7"}
+		}
+	}`
+	headers := map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "wrong"}
+	w := serveTestWebhookRequest(r, http.MethodPost, "/api/webhook/gitlab", body, headers)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "/prob/api/webhook/invalid-token")
+
+	headers["X-Gitlab-Token"] = "gitlab token"
+	w = serveTestWebhookRequest(r, http.MethodPost, "/api/webhook/gitlab", body, headers)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	result := parseTestGitWebhookResult(t, w.Body.Bytes())
+	require.Len(t, result.Builds, 1, "must not match projects of other providers by remote project ID")
+	assert.Equal(t, dbProject.ProjectID, result.Builds[0].ProjectID)
+
+	var dbBuild database.Build
+	require.NoError(t, builds.Database.First(&dbBuild, result.Builds[0].BuildID).Error)
+	assert.Equal(t, "feature/foo", dbBuild.GitBranch)
+	assert.Equal(t, "da1560886d4f094c3e6c9ef40349f7d38b5d27d7", dbBuild.GitCommit)
+	assert.Equal(t, "test", dbBuild.Stage)
+	assert.Equal(t, "jsmith", dbBuild.TriggeredByName)
+}
+
+func TestGitWebhookHandler_notConfigured(t *testing.T) {
+	r, builds, _ := newTestGitWebhookRouter(t)
+	builds.Config.Providers.GitHubWebhookSecret = ""
+	w := serveTestWebhookRequest(r, http.MethodPost, "/api/webhook/github", "{}", map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": signTestWebhookBody("", "{}"),
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "/prob/api/webhook/not-configured")
+}
+
+func TestParseGitWebhookEvent_ignored(t *testing.T) {
+	testCases := []struct {
+		name      string
+		github    bool
+		eventName string
+		body      string
+	}{
+		{
+			name: "github tag push", github: true, eventName: "push",
+			body: `{"ref":"refs/tags/v1.0.0","after":"2f1ebd2b"}`,
+		},
+		{
+			name: "github deleted branch", github: true, eventName: "push",
+			body: `{"ref":"refs/heads/foo","after":"0000000000000000000000000000000000000000","deleted":true}`,
+		},
+		{
+			name: "github closed pull request", github: true, eventName: "pull_request",
+			body: `{"action":"closed","repository":{"id":1},"pull_request":{"head":{"repo":{"id":1}}}}`,
+		},
+		{
+			name: "github pull request from fork", github: true, eventName: "pull_request",
+			body: `{"action":"opened","repository":{"id":1},"pull_request":{"head":{"repo":{"id":2}}}}`,
+		},
+		{
+			name: "github issue", github: true, eventName: "issues",
+			body: `{"action":"opened"}`,
+		},
+		{
+			name: "gitlab deleted branch", eventName: "Push Hook",
+			body: `{"ref":"refs/heads/foo","checkout_sha":null}`,
+		},
+		{
+			name: "gitlab merge request updated without commits", eventName: "Merge Request Hook",
+			body: `{"object_attributes":{"action":"update","source_project_id":1,"target_project_id":1}}`,
+		},
+		{
+			name: "gitlab merge request from fork", eventName: "Merge Request Hook",
+			body: `{"object_attributes":{"action":"open","source_project_id":2,"target_project_id":1}}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parse := parseGitLabWebhookEvent
+			if tc.github {
+				parse = parseGitHubWebhookEvent
+			}
+			_, ok, err := parse(tc.eventName, []byte(tc.body))
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestGitURLVariants(t *testing.T) {
+	got := gitURLVariants([]string{"https://github.com/iver-wharf/wharf-api.git", "", "https://github.com/iver-wharf/wharf-api"})
+	assert.ElementsMatch(t, []string{"https://github.com/iver-wharf/wharf-api", "https://github.com/iver-wharf/wharf-api.git"}, got)
+}
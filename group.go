@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+type projectGroupModule struct {
+	Database *gorm.DB
+}
+
+func (m projectGroupModule) Register(g *gin.RouterGroup) {
+	group := g.Group("/group")
+	{
+		group.GET("", m.getProjectGroupListHandler)
+
+		groupByID := group.Group("/:groupId")
+		{
+			groupByID.GET("", m.getProjectGroupHandler)
+			groupByID.GET("/project", m.getProjectGroupProjectListHandler)
+		}
+	}
+}
+
+var projectGroupJSONToColumns = map[string]database.SafeSQLName{
+	response.ProjectGroupJSONFields.GroupID: database.ProjectGroupColumns.ProjectGroupID,
+	response.ProjectGroupJSONFields.Name:    database.ProjectGroupColumns.Name,
+	response.ProjectGroupJSONFields.Path:    database.ProjectGroupColumns.Path,
+}
+
+var defaultGetProjectGroupsOrderBy = orderby.Column{Name: database.ProjectGroupColumns.Path, Direction: orderby.Asc}
+
+// getProjectGroupListHandler godoc
+// @id getProjectGroupList
+// @summary Returns all project groups from database
+// @description List all project groups, or a window of project groups using the `limit` and `offset` query parameters.
+// @description Groups are derived from the projects' group names, where slashes separate nested groups.
+// @description Each group includes the number of projects and builds in the group and all of its subgroups.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Casing, prefixes, and commas added in v5.3.0. Defaults to `?orderby=path asc`"
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
+// @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param parentId query uint false "Filter by parent group ID. Zero (0) will search for top-level groups." minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedProjectGroups
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /group [get]
+func (m projectGroupModule) getProjectGroupListHandler(c *gin.Context) {
+	var params = struct {
+		commonGetQueryParams
+
+		ParentID *uint `form:"parentId"`
+	}{
+		commonGetQueryParams: defaultCommonGetQueryParams,
+	}
+	if !bindCommonGetQueryParams(c, &params) {
+		return
+	}
+	orderBySlice, ok := parseCommonOrderBySlice(c, params.OrderBy, projectGroupJSONToColumns)
+	if !ok {
+		return
+	}
+
+	var where wherefields.Collection
+	query := m.Database.
+		Clauses(orderBySlice.ClauseIfNone(defaultGetProjectGroupsOrderBy)).
+		Where(&database.ProjectGroup{
+			ParentID: where.UintPtrZeroNil(database.ProjectGroupFields.ParentID, params.ParentID),
+		}, where.NonNilFieldNames()...)
+
+	var dbGroups []database.ProjectGroup
+	var totalCount int64
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbGroups, &totalCount)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching list of project groups from database.")
+		return
+	}
+	counts, err := findProjectGroupNameCounts(m.Database, whereProjectInstanceScope(c))
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching project and build counts for project groups from database.")
+		return
+	}
+
+	resGroups := modelconv.DBProjectGroupsToResponses(dbGroups)
+	for i := range resGroups {
+		counts.applyTo(&resGroups[i])
+	}
+	ginrender.Response(c, http.StatusOK, response.PaginatedProjectGroups{
+		List:       resGroups,
+		TotalCount: totalCount,
+	})
+}
+
+// getProjectGroupHandler godoc
+// @id getProjectGroup
+// @summary Returns project group with selected group ID
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param groupId path uint true "project group ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectGroup
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project group not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /group/{groupId} [get]
+func (m projectGroupModule) getProjectGroupHandler(c *gin.Context) {
+	dbGroup, ok := m.fetchProjectGroupFromParams(c, "")
+	if !ok {
+		return
+	}
+	counts, err := findProjectGroupNameCounts(m.Database, whereProjectInstanceScope(c))
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching project and build counts for project group with ID %d.",
+			dbGroup.ProjectGroupID))
+		return
+	}
+	resGroup := modelconv.DBProjectGroupToResponse(dbGroup)
+	counts.applyTo(&resGroup)
+	ginrender.Response(c, http.StatusOK, resGroup)
+}
+
+// getProjectGroupProjectListHandler godoc
+// @id getProjectGroupProjectList
+// @summary Returns all projects in a project group
+// @description List all projects directly in the project group, or also the projects in all subgroups using `?recursive=true`.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param groupId path uint true "project group ID" minimum(0)
+// @param recursive query bool false "Include projects in all subgroups."
+// @param archived query bool false "Filter by archived projects instead of non-archived projects. Added in v5.3.0." default(false)
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Casing, prefixes, and commas added in v5.3.0. Defaults to `?orderby=projectId desc`"
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
+// @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedProjects
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project group not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /group/{groupId}/project [get]
+func (m projectGroupModule) getProjectGroupProjectListHandler(c *gin.Context) {
+	var params = struct {
+		commonGetQueryParams
+
+		Recursive bool `form:"recursive"`
+		Archived  bool `form:"archived"`
+	}{
+		commonGetQueryParams: defaultCommonGetQueryParams,
+	}
+	if !bindCommonGetQueryParams(c, &params) {
+		return
+	}
+	orderBySlice, ok := parseCommonOrderBySlice(c, params.OrderBy, projectJSONToColumns)
+	if !ok {
+		return
+	}
+	dbGroup, ok := m.fetchProjectGroupFromParams(c, "when fetching list of projects in project group")
+	if !ok {
+		return
+	}
+
+	query := databaseProjectPreloaded(m.Database).
+		Clauses(orderBySlice.ClauseIfNone(defaultGetProjectsOrderBy)).
+		Where(&database.Project{IsArchived: params.Archived}, database.ProjectFields.IsArchived).
+		Scopes(whereProjectInstanceScope(c))
+	if params.Recursive {
+		query = query.Where(
+			m.Database.
+				Where(database.ProjectColumns.GroupName+" = ?", dbGroup.Path).
+				Or(database.ProjectColumns.GroupName+` LIKE ? ESCAPE '\'`, sqlLikeEscaper.Replace(dbGroup.Path)+"/%"))
+	} else {
+		query = query.Where(&database.Project{GroupName: dbGroup.Path}, database.ProjectFields.GroupName)
+	}
+
+	var dbProjects []database.Project
+	var totalCount int64
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbProjects, &totalCount)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching list of projects in project group with ID %d from database.",
+			dbGroup.ProjectGroupID))
+		return
+	}
+
+	ginrender.Response(c, http.StatusOK, response.PaginatedProjects{
+		List:       modelconv.DBProjectsToResponses(dbProjects),
+		TotalCount: totalCount,
+	})
+}
+
+func (m projectGroupModule) fetchProjectGroupFromParams(c *gin.Context, whenMsg string) (database.ProjectGroup, bool) {
+	groupID, ok := ginutil.ParseParamUint(c, "groupId")
+	if !ok {
+		return database.ProjectGroup{}, false
+	}
+	var dbGroup database.ProjectGroup
+	ok = fetchDatabaseObjByID(c, m.Database, &dbGroup, groupID, "project group", whenMsg)
+	return dbGroup, ok
+}
+
+// projectGroupNameCounts holds the number of projects and builds per verbatim
+// project group name.
+type projectGroupNameCounts map[string]projectGroupNameCount
+
+type projectGroupNameCount struct {
+	GroupName    string
+	ProjectCount int64
+	BuildCount   int64
+}
+
+func findProjectGroupNameCounts(db *gorm.DB, projectScope func(*gorm.DB) *gorm.DB) (projectGroupNameCounts, error) {
+	var projectCounts []projectGroupNameCount
+	if err := db.
+		Model(&database.Project{}).
+		Scopes(projectScope).
+		Select(database.ProjectColumns.GroupName + " AS group_name, COUNT(*) AS project_count").
+		Group(database.ProjectColumns.GroupName).
+		Scan(&projectCounts).Error; err != nil {
+		return nil, err
+	}
+	var buildCounts []projectGroupNameCount
+	if err := db.
+		Model(&database.Build{}).
+		Select(database.ProjectTable + "." + database.ProjectColumns.GroupName + " AS group_name, COUNT(*) AS build_count").
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.%[2]s = %[3]s.%[4]s",
+			database.ProjectTable, database.ProjectColumns.ProjectID,
+			database.BuildTable, database.BuildColumns.ProjectID)).
+		Scopes(projectScope).
+		Group(database.ProjectTable + "." + database.ProjectColumns.GroupName).
+		Scan(&buildCounts).Error; err != nil {
+		return nil, err
+	}
+	counts := make(projectGroupNameCounts, len(projectCounts))
+	for _, count := range projectCounts {
+		counts[count.GroupName] = count
+	}
+	for _, count := range buildCounts {
+		c := counts[count.GroupName]
+		c.GroupName = count.GroupName
+		c.BuildCount = count.BuildCount
+		counts[count.GroupName] = c
+	}
+	return counts, nil
+}
+
+// applyTo sets the project and build counts of the group, where the total
+// counts also include all subgroups.
+func (counts projectGroupNameCounts) applyTo(resGroup *response.ProjectGroup) {
+	resGroup.ProjectCount = counts[resGroup.Path].ProjectCount
+	resGroup.TotalProjectCount = 0
+	resGroup.TotalBuildCount = 0
+	subgroupPrefix := resGroup.Path + "/"
+	for groupName, count := range counts {
+		if groupName != resGroup.Path && !strings.HasPrefix(groupName, subgroupPrefix) {
+			continue
+		}
+		resGroup.TotalProjectCount += count.ProjectCount
+		resGroup.TotalBuildCount += count.BuildCount
+	}
+}
+
+// projectGroupPaths returns the paths of all groups, including parent groups,
+// that a project group name belongs to. For example, the group name
+// "My Group/Stuff" results in the paths "My Group" and "My Group/Stuff".
+func projectGroupPaths(groupName string) []string {
+	var paths []string
+	for i, r := range groupName {
+		if r == '/' && i > 0 && groupName[i-1] != '/' {
+			paths = append(paths, groupName[:i])
+		}
+	}
+	if groupName != "" && !strings.HasSuffix(groupName, "/") {
+		paths = append(paths, groupName)
+	}
+	return paths
+}
+
+// ensureProjectGroups adds the project group, and all of its parent groups,
+// for the given project group name if they do not already exist.
+func ensureProjectGroups(db *gorm.DB, groupName string) error {
+	paths := projectGroupPaths(groupName)
+	if len(paths) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var parentID *uint
+		for _, path := range paths {
+			dbGroup := database.ProjectGroup{
+				ParentID: parentID,
+				Name:     path[strings.LastIndexByte(path, '/')+1:],
+				Path:     path,
+			}
+			if err := tx.
+				Where(&database.ProjectGroup{Path: path}, database.ProjectGroupFields.Path).
+				FirstOrCreate(&dbGroup).Error; err != nil {
+				return err
+			}
+			parentID = &dbGroup.ProjectGroupID
+		}
+		return nil
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectGroupPaths(t *testing.T) {
+	testCases := []struct {
+		name      string
+		groupName string
+		want      []string
+	}{
+		{
+			name:      "empty",
+			groupName: "",
+			want:      nil,
+		},
+		{
+			name:      "single",
+			groupName: "default",
+			want:      []string{"default"},
+		},
+		{
+			name:      "nested",
+			groupName: "My Group/Stuff/Things",
+			want:      []string{"My Group", "My Group/Stuff", "My Group/Stuff/Things"},
+		},
+		{
+			name:      "redundant slashes",
+			groupName: "/foo//bar/",
+			want:      []string{"/foo", "/foo//bar"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, projectGroupPaths(tc.groupName))
+		})
+	}
+}
+
+func TestProjectGroupNameCounts_applyTo(t *testing.T) {
+	counts := projectGroupNameCounts{
+		"foo":         {GroupName: "foo", ProjectCount: 1, BuildCount: 10},
+		"foo/bar":     {GroupName: "foo/bar", ProjectCount: 2, BuildCount: 20},
+		"foo/bar/baz": {GroupName: "foo/bar/baz", ProjectCount: 4, BuildCount: 40},
+		"foobar":      {GroupName: "foobar", ProjectCount: 8, BuildCount: 80},
+	}
+	resGroup := response.ProjectGroup{Path: "foo"}
+	counts.applyTo(&resGroup)
+
+	assert.Equal(t, int64(1), resGroup.ProjectCount)
+	assert.Equal(t, int64(7), resGroup.TotalProjectCount)
+	assert.Equal(t, int64(70), resGroup.TotalBuildCount)
+}
@@ -1,32 +1,64 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io"
 	"math"
 	"net"
+	"net/url"
+	"sync"
+	"unicode/utf8"
 
 	v5 "github.com/iver-wharf/wharf-api/v5/api/wharfapi/v5"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
 )
 
 type grpcWharfServer struct {
 	v5.UnimplementedBuildsServer
-	db *gorm.DB
+	db          *gorm.DB
+	logIngester *logIngester
+	// builds is used to update the status of builds the same way as via the
+	// REST API, including starting any triggered builds.
+	builds buildModule
 }
 
-func serveGRPC(listener net.Listener, db *gorm.DB) {
+func serveGRPC(listener net.Listener, builds buildModule) {
 	grpcServer := grpc.NewServer()
-	grpcWharf := &grpcWharfServer{db: db}
+	grpcWharf := &grpcWharfServer{db: builds.Database, logIngester: builds.LogIngester, builds: builds}
 	v5.RegisterBuildsServer(grpcServer, grpcWharf)
 	grpcServer.Serve(listener)
 }
 
+// CreateLogStream adds the received log lines to the log ingestion queue, and
+// waits for all of them to be inserted before responding. Log lines that are
+// dropped because the queue is full are skipped, and are not included in the
+// number of inserted lines in the response.
 func (s *grpcWharfServer) CreateLogStream(stream v5.Builds_CreateLogStreamServer) error {
-	var logsInserted uint64
+	var (
+		wg            sync.WaitGroup
+		mu            sync.Mutex
+		linesInserted uint64
+		insertErr     error
+	)
+	onInserted := func(dbLog database.Log, err error) {
+		mu.Lock()
+		if err != nil {
+			insertErr = err
+		} else {
+			linesInserted++
+		}
+		mu.Unlock()
+		wg.Done()
+	}
 	for {
 		line, err := stream.Recv()
 		if err == io.EOF {
@@ -53,25 +85,290 @@ func (s *grpcWharfServer) CreateLogStream(stream v5.Builds_CreateLogStreamServer
 				"received build ID is too big: %d (build ID) > %d (max)",
 				line.BuildID, uint(math.MaxUint))
 		}
-		createdLog, err := saveLog(s.db.WithContext(stream.Context()),
-			uint(line.BuildID),
-			line.Message,
-			line.Timestamp.AsTime(),
-		)
+		wg.Add(1)
+		err = s.logIngester.enqueue(stream.Context(), database.Log{
+			BuildID:   uint(line.BuildID),
+			Message:   line.Message,
+			Timestamp: line.Timestamp.AsTime(),
+		}, onInserted)
 		if err != nil {
-			return status.Errorf(codes.Internal, "insert logs: %v", err)
-		}
-		log.Debug().WithUint("logId", createdLog.LogID).
-			Message("Inserted log into database.")
-		build(createdLog.BuildID).Submit(response.Log{
-			LogID:     createdLog.LogID,
-			BuildID:   createdLog.BuildID,
-			Message:   createdLog.Message,
-			Timestamp: createdLog.Timestamp,
-		})
-		logsInserted++
+			wg.Done()
+			log.Warn().WithError(err).WithUint64("buildId", line.BuildID).
+				Message("Failed to queue log for insertion, skipping.")
+		}
+	}
+	wg.Wait()
+	if insertErr != nil {
+		return status.Errorf(codes.Internal, "insert logs: %v", insertErr)
 	}
 	return stream.SendAndClose(&v5.CreateLogStreamResponse{
-		LinesInserted: logsInserted,
+		LinesInserted: linesInserted,
+	})
+}
+
+// CreateBuildLink adds an external link to a build. Responds with
+// InvalidArgument if the link is invalid, and with NotFound if the build does
+// not exist.
+func (s *grpcWharfServer) CreateBuildLink(ctx context.Context, req *v5.CreateBuildLinkRequest) (*v5.CreateBuildLinkResponse, error) {
+	if req.BuildID == 0 || req.BuildID > math.MaxUint {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid build ID: %d", req.BuildID)
+	}
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "link name must not be empty")
+	}
+	if utf8.RuneCountInString(req.Name) > database.BuildLinkSizes.Name {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"link name must not be longer than %d characters", database.BuildLinkSizes.Name)
+	}
+	if utf8.RuneCountInString(req.URL) > database.BuildLinkSizes.URL {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"link URL must not be longer than %d characters", database.BuildLinkSizes.URL)
+	}
+	if u, err := url.ParseRequestURI(req.URL); err != nil || !u.IsAbs() {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"link URL must be an absolute URL: %q", req.URL)
+	}
+	buildID := uint(req.BuildID)
+	db := s.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&database.Build{}).Where(buildID).Count(&count).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "fetch build: %v", err)
+	}
+	if count == 0 {
+		return nil, status.Errorf(codes.NotFound, "build with ID %d was not found", buildID)
+	}
+	dbLink := database.BuildLink{
+		BuildID: buildID,
+		Name:    req.Name,
+		URL:     req.URL,
+	}
+	if err := db.Create(&dbLink).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "insert link: %v", err)
+	}
+	log.Info().
+		WithUint("build", buildID).
+		WithUint("link", dbLink.BuildLinkID).
+		WithString("name", dbLink.Name).
+		Message("Added link to build.")
+	return &v5.CreateBuildLinkResponse{
+		BuildLinkID: uint64(dbLink.BuildLinkID),
+	}, nil
+}
+
+// StreamLogs sends the already added log lines of a build, and then follows
+// the log lines as they are inserted, until the client closes the stream.
+// Responds with InvalidArgument if the build ID is invalid, with NotFound if
+// the build does not exist, and with ResourceExhausted if the build's event
+// stream already has the maximum number of listeners.
+func (s *grpcWharfServer) StreamLogs(req *v5.StreamLogsRequest, stream v5.Builds_StreamLogsServer) error {
+	if req.BuildID == 0 || req.BuildID > math.MaxUint {
+		return status.Errorf(codes.InvalidArgument,
+			"invalid build ID: %d", req.BuildID)
+	}
+	if req.AfterLogID > math.MaxUint {
+		return status.Errorf(codes.InvalidArgument,
+			"invalid log ID: %d", req.AfterLogID)
+	}
+	buildID := uint(req.BuildID)
+	ctx := stream.Context()
+	db := s.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&database.Build{}).Where(buildID).Count(&count).Error; err != nil {
+		return status.Errorf(codes.Internal, "fetch build: %v", err)
+	}
+	if count == 0 {
+		return status.Errorf(codes.NotFound, "build with ID %d was not found", buildID)
+	}
+
+	lastLogID := uint(req.AfterLogID)
+	sendAddedLogs := func() error {
+		for {
+			dbLogs, err := getBuildLogsChunk(db, buildID, lastLogID)
+			if err != nil {
+				return status.Errorf(codes.Internal, "fetch logs: %v", err)
+			}
+			for _, dbLog := range dbLogs {
+				if err := stream.Send(dbLogToGRPCLogLine(dbLog)); err != nil {
+					return err
+				}
+				lastLogID = dbLog.LogID
+			}
+			if len(dbLogs) < buildLogDownloadChunkSize {
+				return nil
+			}
+		}
+	}
+
+	// The bulk of the added logs are sent before listening, as a slow replay
+	// would otherwise block the log ingestion while the listener is not read.
+	if err := sendAddedLogs(); err != nil {
+		return err
+	}
+	listener, err := buildStreams.listen(buildID)
+	if errors.Is(err, errBuildStreamTooManyListeners) {
+		return status.Errorf(codes.ResourceExhausted,
+			"the event stream of build with ID %d already has the maximum of %d listeners",
+			buildID, buildStreams.config.MaxListenersPerBuild)
+	} else if err != nil {
+		return status.Errorf(codes.Internal, "listen to build: %v", err)
+	}
+	defer listener.close()
+	// Catch up on the logs inserted before the listener was added.
+	if err := sendAddedLogs(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case message := <-listener.C:
+			resLog, ok := message.(response.Log)
+			if !ok || resLog.LogID <= lastLogID {
+				continue
+			}
+			if err := stream.Send(resLogToGRPCLogLine(resLog)); err != nil {
+				return err
+			}
+			lastLogID = resLog.LogID
+		}
+	}
+}
+
+func dbLogToGRPCLogLine(dbLog database.Log) *v5.LogLine {
+	return &v5.LogLine{
+		LogID:     uint64(dbLog.LogID),
+		BuildID:   uint64(dbLog.BuildID),
+		Timestamp: timestamppb.New(dbLog.Timestamp),
+		Message:   dbLog.Message,
+	}
+}
+
+func resLogToGRPCLogLine(resLog response.Log) *v5.LogLine {
+	return &v5.LogLine{
+		LogID:     uint64(resLog.LogID),
+		BuildID:   uint64(resLog.BuildID),
+		Timestamp: timestamppb.New(resLog.Timestamp),
+		Message:   resLog.Message,
+	}
+}
+
+// artifactDownloadChunkSize is the maximum number of bytes sent per message by
+// the DownloadArtifact RPC. Kept well below gRPC's default maximum message size
+// of 4 MiB.
+const artifactDownloadChunkSize = 64 * 1024
+
+// DownloadArtifact sends the data of a build artifact in chunks, with the
+// artifact's metadata in the first message. Responds with InvalidArgument if
+// the build or artifact ID is invalid, and with NotFound if the artifact does
+// not exist on the build.
+func (s *grpcWharfServer) DownloadArtifact(req *v5.DownloadArtifactRequest, stream v5.Builds_DownloadArtifactServer) error {
+	if req.BuildID == 0 || req.BuildID > math.MaxUint {
+		return status.Errorf(codes.InvalidArgument,
+			"invalid build ID: %d", req.BuildID)
+	}
+	if req.ArtifactID == 0 || req.ArtifactID > math.MaxUint {
+		return status.Errorf(codes.InvalidArgument,
+			"invalid artifact ID: %d", req.ArtifactID)
+	}
+	buildID := uint(req.BuildID)
+	artifactID := uint(req.ArtifactID)
+	var dbArtifact database.Artifact
+	err := s.db.WithContext(stream.Context()).
+		Where(&database.Artifact{
+			BuildID:    buildID,
+			ArtifactID: artifactID,
+		}).
+		First(&dbArtifact).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status.Errorf(codes.NotFound,
+			"artifact with ID %d was not found on build with ID %d", artifactID, buildID)
+	} else if err != nil {
+		return status.Errorf(codes.Internal, "fetch artifact: %v", err)
+	}
+
+	res := &v5.DownloadArtifactResponse{
+		Metadata: &v5.ArtifactMetadata{
+			ArtifactID: uint64(dbArtifact.ArtifactID),
+			BuildID:    uint64(dbArtifact.BuildID),
+			Name:       dbArtifact.Name,
+			FileName:   dbArtifact.FileName,
+			Size:       uint64(len(dbArtifact.Data)),
+		},
+	}
+	data := dbArtifact.Data
+	for {
+		n := len(data)
+		if n > artifactDownloadChunkSize {
+			n = artifactDownloadChunkSize
+		}
+		res.Chunk = data[:n]
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+		data = data[n:]
+		if len(data) == 0 {
+			return nil
+		}
+		res = &v5.DownloadArtifactResponse{}
+	}
+}
+
+// UpdateBuildStatus updates the status of a build, together with its status
+// message and stage results. Responds with InvalidArgument if any of the
+// values are invalid, and with NotFound if the build does not exist.
+func (s *grpcWharfServer) UpdateBuildStatus(ctx context.Context, req *v5.UpdateBuildStatusRequest) (*v5.UpdateBuildStatusResponse, error) {
+	if req.BuildID == 0 || req.BuildID > math.MaxUint {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid build ID: %d", req.BuildID)
+	}
+	dbStatus, ok := modelconv.ReqBuildStatusToDatabase(request.BuildStatus(req.Status))
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid build status: %q", req.Status)
+	}
+	if utf8.RuneCountInString(req.StatusMessage) > database.BuildSizes.StatusMessage {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"status message must not be longer than %d characters", database.BuildSizes.StatusMessage)
+	}
+	dbResults := make([]database.BuildStageResult, len(req.StageResults))
+	for i, result := range req.StageResults {
+		if result.Name == "" {
+			return nil, status.Error(codes.InvalidArgument, "stage name must not be empty")
+		}
+		if utf8.RuneCountInString(result.Name) > database.BuildStageResultSizes.Name {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"stage name must not be longer than %d characters", database.BuildStageResultSizes.Name)
+		}
+		if utf8.RuneCountInString(result.StatusMessage) > database.BuildStageResultSizes.StatusMessage {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"stage status message must not be longer than %d characters", database.BuildStageResultSizes.StatusMessage)
+		}
+		dbStageStatus, ok := modelconv.ReqBuildStatusToDatabase(request.BuildStatus(result.Status))
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"invalid status of stage %q: %q", result.Name, result.Status)
+		}
+		dbResults[i] = database.BuildStageResult{
+			Name:          result.Name,
+			StatusID:      dbStageStatus,
+			StatusMessage: result.StatusMessage,
+		}
+	}
+	buildID := uint(req.BuildID)
+	builds := s.builds
+	builds.Database = s.db.WithContext(ctx)
+	_, err := builds.updateBuildStatus(buildID, buildStatusUpdate{
+		status:        dbStatus,
+		statusMessage: req.StatusMessage,
+		stageResults:  dbResults,
 	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.NotFound, "build with ID %d was not found", buildID)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "update build status: %v", err)
+	}
+	return &v5.UpdateBuildStatusResponse{}, nil
 }
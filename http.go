@@ -2,86 +2,139 @@ package main
 
 import (
 	"net"
+	"net/http"
 	"os"
 	"strings"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"github.com/iver-wharf/wharf-api/v5/internal/deprecated"
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
-	ginSwagger "github.com/swaggo/gin-swagger"
-	"github.com/swaggo/gin-swagger/swaggerFiles"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"gopkg.in/typ.v4/slices"
 	"gorm.io/gorm"
 )
 
-func serveHTTP(listener net.Listener, config Config, db *gorm.DB) {
+func serveHTTP(listener net.Listener, config Config, db *gorm.DB, dbCache *dbCache, builds buildModule, metricsRegistry *metrics.Registry) {
+	r := newHTTPRouter(config, db, dbCache, builds, metricsRegistry)
+	if config.HTTP.TLS.enabled() {
+		// The listener is already decrypted, so HTTP/2 requests negotiated
+		// via TLS ALPN arrive as HTTP/2 requests with prior knowledge.
+		http.Serve(listener, h2c.NewHandler(r, &http2.Server{}))
+	} else {
+		r.RunListener(listener)
+	}
+}
+
+// newHTTPRouter returns the router with all middlewares and endpoints of the
+// HTTP API registered.
+func newHTTPRouter(config Config, db *gorm.DB, dbCache *dbCache, builds buildModule, metricsRegistry *metrics.Registry) *gin.Engine {
 	gin.DefaultWriter = ginutil.DefaultLoggerWriter
 	gin.DefaultErrorWriter = ginutil.DefaultLoggerWriter
+	registerRequestValidations(binding.Validator.Engine().(*validator.Validate))
 
 	r := gin.New()
 	r.Use(
-		ginutil.LoggerWithConfig(ginutil.LoggerConfig{
-			//disable GIN logs for path "/health". Probes won't clog up logs now.
-			SkipPaths: []string{"/health"},
-		}),
+		requestIDMiddleware,
+		//disable GIN logs for path "/health". Probes won't clog up logs now.
+		newAccessLogMiddleware(config.HTTP.AccessLog, []string{"/health", "/api/metrics"}),
 		ginutil.RecoverProblem,
 	)
 
-	if len(config.HTTP.CORS.AllowOrigins) > 0 {
-		log.Info().
-			WithStringf("origin", "%v", config.HTTP.CORS.AllowOrigins).
-			Message("Allowing origins in CORS.")
-		corsConfig := cors.DefaultConfig()
-		corsConfig.AllowOrigins = config.HTTP.CORS.AllowOrigins
-		corsConfig.AddAllowHeaders("Authorization")
-		corsConfig.AllowCredentials = true
-		r.Use(cors.New(corsConfig))
-	} else if config.HTTP.CORS.AllowAllOrigins {
-		log.Info().Message("Allowing all origins in CORS.")
-		corsConfig := cors.DefaultConfig()
-		corsConfig.AllowAllOrigins = true
-		r.Use(cors.New(corsConfig))
-	}
+	// The CORS config is reloadable, and is therefore always registered.
+	r.Use(config.reloadable.corsMiddleware)
+
+	repos := newGormRepositories(db)
 
 	healthModule{}.DeprecatedRegister(r)
 	healthModule{}.Register(r.Group("/api"))
+	problemsModule{}.Register(r.Group("/api"))
+	metricsModule{Registry: metricsRegistry}.Register(r.Group("/api"))
+	if config.HTTP.PublicBadges {
+		log.Info().Message("Allowing unauthenticated access to project build badges.")
+		badgeModule{Projects: repos.Projects, Builds: repos.Builds}.Register(r.Group("/api"))
+	}
+	// Authenticated by the secret token in the URL instead.
+	webhookTriggerModule{Builds: builds}.Register(r.Group("/api"))
+	gitWebhookModule{Builds: builds}.Register(r.Group("/api"))
+	if !config.HTTP.Swagger.Enable {
+		log.Info().Message("Swagger disabled, skipping API documentation endpoints.")
+	} else if !config.HTTP.Swagger.RequireAuth {
+		log.Info().Message("Allowing unauthenticated access to the API documentation.")
+		registerAPIDocs(r.Group("/api"))
+	}
 
 	if config.HTTP.OIDC.Enable {
-		rsaKeys, err := GetOIDCPublicKeys(config.HTTP.OIDC.KeysURL)
-		if err != nil {
+		m := newOIDCMiddleware(config.HTTP.OIDC)
+		if err := m.refreshKeys(); err != nil {
 			log.Error().WithError(err).Message("Failed to obtain OIDC public keys.")
 			os.Exit(1)
 		}
-		m := newOIDCMiddleware(rsaKeys, config.HTTP.OIDC)
 		r.Use(m.VerifyTokenMiddleware)
 		m.SubscribeToKeyURLUpdates()
 	}
 
 	setupBasicAuth(r, config)
 
+	if config.Tenancy.Enable {
+		log.Info().
+			WithString("instanceId", config.InstanceID).
+			WithString("header", config.Tenancy.Header).
+			WithString("oidcClaim", config.Tenancy.OIDCClaim).
+			Message("Scoping projects and builds by instance ID.")
+		r.Use(newTenancyMiddleware(config, db))
+	}
+
 	modules := []httpModule{
-		engineModule{CIConfig: &config.CI},
-		branchModule{Database: db},
-		buildModule{Database: db, Config: &config},
-		projectModule{Database: db},
-		providerModule{Database: db},
+		engineModule{Config: &config, HTTPConfig: &config.HTTP, EngineClient: builds.EngineClient},
+		branchModule{Database: db, Cache: dbCache},
+		configModule{Config: &config},
+		builds,
+		environmentModule{Database: db},
+		gitTriggerModule{Database: db},
+		labelModule{Database: db},
+		logLevelModule{Config: &config},
+		notificationRuleModule{Database: db, Config: &config},
+		projectModule{Database: db, Cache: dbCache, Config: &config},
+		projectArtifactModule{Projects: repos.Projects, Artifacts: repos.Artifacts},
+		logModule{Database: db},
+		projectGroupModule{Database: db},
+		projectTriggerModule{Database: db},
+		projectWebhookModule{Database: db, Config: &config},
+		releaseArtifactModule{Database: db},
+		providerModule{Database: db, Config: &config.Providers},
+		providerSyncModule{Database: db, Config: &config.Providers},
+		settingsModule{Database: db, Config: &config},
+		statsModule{Database: db, Config: &config},
+		tenancyModule{Database: db, Config: &config},
 		tokenModule{Database: db},
-		deprecated.BranchModule{Database: db},
+		userSubscriptionModule{Database: db, Config: &config},
+		deprecated.BranchModule{Database: db, InstanceID: getRequestInstanceID},
 		deprecated.BuildModule{Database: db},
-		deprecated.ProjectModule{Database: db},
+		deprecated.ProjectModule{Database: db, InstanceID: getRequestInstanceID},
 		deprecated.ProviderModule{Database: db},
 		deprecated.TokenModule{Database: db},
 	}
 
+	if !config.HTTP.PublicBadges {
+		modules = append(modules, badgeModule{Projects: repos.Projects, Builds: repos.Builds})
+	}
+
 	api := r.Group("/api")
+	api.Use(newPaginationMiddleware(config.HTTP.Pagination))
 	for _, module := range modules {
 		module.Register(api)
 	}
 
 	api.GET("/version", getVersionHandler)
-	api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	r.RunListener(listener)
+	if config.HTTP.Swagger.Enable && config.HTTP.Swagger.RequireAuth {
+		registerAPIDocs(api)
+	}
+	return r
 }
 
 func setupBasicAuth(router *gin.Engine, config Config) {
@@ -106,3 +159,25 @@ func setupBasicAuth(router *gin.Engine, config Config) {
 
 	router.Use(gin.BasicAuth(accounts))
 }
+
+// isAdmin returns true if the authenticated user is listed in the
+// HTTPConfig.Admins config, or if no authentication is configured.
+func isAdmin(c *gin.Context, config HTTPConfig) bool {
+	if claims, ok := getOIDCClaims(c); ok {
+		subject := getOIDCClaimString(claims, "sub")
+		return subject != "" && slices.Contains(config.Admins, subject)
+	}
+	if user := c.GetString(gin.AuthUserKey); user != "" {
+		return slices.Contains(config.Admins, user)
+	}
+	return !config.OIDC.Enable && config.BasicAuth == ""
+}
+
+func validateIsAdmin(c *gin.Context, config HTTPConfig) bool {
+	if isAdmin(c, config) {
+		return true
+	}
+	ginutil.WriteProblem(c, problems.AdminForbidden.New(
+		"The authenticated user is not listed in the wharf-api http.admins config, and is therefore not allowed to use admin endpoints."))
+	return false
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyKeyLockTimeout = 5 * time.Minute
+)
+
+type idempotencyKeyState byte
+
+const (
+	idempotencyKeyAcquired idempotencyKeyState = iota
+	idempotencyKeyInProgress
+	idempotencyKeyMismatch
+	idempotencyKeyCompleted
+)
+
+// idempotencyKeyMiddleware stores the responses of requests sent with an
+// Idempotency-Key HTTP header, so that retries of the same request are
+// responded to with the original response instead of being handled again.
+//
+// Only successful responses are stored. Failed requests may be retried using
+// the same key.
+type idempotencyKeyMiddleware struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func newIdempotencyKeyMiddleware(db *gorm.DB, ttl time.Duration) idempotencyKeyMiddleware {
+	return idempotencyKeyMiddleware{db: db, ttl: ttl}
+}
+
+// handle is a gin middleware function. Requests without an Idempotency-Key
+// header are passed through as-is.
+func (m idempotencyKeyMiddleware) handle(c *gin.Context) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return
+	}
+	if len(key) > database.IdempotencyKeySizes.Key {
+		err := fmt.Errorf("idempotency key too long: max %d chars, but was: %d", database.IdempotencyKeySizes.Key, len(key))
+		ginutil.WriteInvalidParamError(c, err, idempotencyKeyHeader, fmt.Sprintf(
+			"The %s header must not be longer than %d characters.",
+			idempotencyKeyHeader, database.IdempotencyKeySizes.Key))
+		c.Abort()
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if writeUploadTooLargeIfExceeded(c) {
+			c.Abort()
+			return
+		}
+		ginutil.WriteBodyReadError(c, err, fmt.Sprintf(
+			"Failed to read the request body when checking the %s header.",
+			idempotencyKeyHeader))
+		c.Abort()
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	subject := getRequestSubject(c)
+	dbKey, state, err := m.acquire(subject, key, hashIdempotentRequest(c.Request, body))
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed storing %s header value in database.",
+			idempotencyKeyHeader))
+		c.Abort()
+		return
+	}
+	switch state {
+	case idempotencyKeyInProgress:
+		ginutil.WriteProblem(c, problems.IdempotencyKeyInProgress.Newf(
+			"A request with the %s header value %q is still being handled.",
+			idempotencyKeyHeader, key))
+		c.Abort()
+		return
+	case idempotencyKeyMismatch:
+		ginutil.WriteProblem(c, problems.IdempotencyKeyMismatch.Newf(
+			"The %s header value %q was already used for a request with a different method, URL, or body.",
+			idempotencyKeyHeader, key))
+		c.Abort()
+		return
+	case idempotencyKeyCompleted:
+		c.Header(idempotentReplayedHeader, "true")
+		if dbKey.ResponseContentType == "" {
+			c.Status(dbKey.ResponseStatus)
+		} else {
+			c.Data(dbKey.ResponseStatus, dbKey.ResponseContentType, dbKey.ResponseBody)
+		}
+		c.Abort()
+		return
+	}
+
+	writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+
+	if status := writer.Status(); status < 200 || status >= 300 {
+		if err := m.db.Delete(&dbKey).Error; err != nil {
+			requestLog(c).Warn().WithError(err).
+				WithString("key", key).
+				Message("Failed to release idempotency key after failed request.")
+		}
+		return
+	}
+	if err := m.db.Model(&dbKey).Updates(database.IdempotencyKey{
+		IsCompleted:         true,
+		ResponseStatus:      writer.Status(),
+		ResponseContentType: writer.Header().Get("Content-Type"),
+		ResponseBody:        writer.body.Bytes(),
+	}).Error; err != nil {
+		requestLog(c).Warn().WithError(err).
+			WithString("key", key).
+			Message("Failed to store response for idempotency key.")
+	}
+}
+
+// acquire stores the idempotency key as in progress, or returns the state of
+// a previous request using the same key. Expired and abandoned keys are
+// replaced.
+func (m idempotencyKeyMiddleware) acquire(subject, key, requestHash string) (database.IdempotencyKey, idempotencyKeyState, error) {
+	now := time.Now().UTC()
+	if err := m.db.
+		Where(database.IdempotencyKeyColumns.CreatedAt+" < ?", now.Add(-m.ttl)).
+		Delete(&database.IdempotencyKey{}).
+		Error; err != nil {
+		return database.IdempotencyKey{}, 0, err
+	}
+
+	existing, err := m.find(subject, key)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return database.IdempotencyKey{}, 0, err
+	}
+	if err == nil {
+		switch {
+		case existing.RequestHash != requestHash:
+			return existing, idempotencyKeyMismatch, nil
+		case existing.IsCompleted:
+			return existing, idempotencyKeyCompleted, nil
+		case now.Sub(existing.CreatedAt) < idempotencyKeyLockTimeout:
+			return existing, idempotencyKeyInProgress, nil
+		}
+		if err := m.db.Delete(&existing).Error; err != nil {
+			return database.IdempotencyKey{}, 0, err
+		}
+	}
+
+	dbKey := database.IdempotencyKey{
+		CreatedAt:   now,
+		Subject:     subject,
+		Key:         key,
+		RequestHash: requestHash,
+	}
+	if err := m.db.Create(&dbKey).Error; err != nil {
+		// Most likely a concurrent request with the same key, which is
+		// caught by the unique index.
+		if _, findErr := m.find(subject, key); findErr == nil {
+			return dbKey, idempotencyKeyInProgress, nil
+		}
+		return database.IdempotencyKey{}, 0, err
+	}
+	return dbKey, idempotencyKeyAcquired, nil
+}
+
+func (m idempotencyKeyMiddleware) find(subject, key string) (database.IdempotencyKey, error) {
+	var dbKey database.IdempotencyKey
+	err := m.db.
+		Where(&database.IdempotencyKey{Subject: subject, Key: key},
+			database.IdempotencyKeyFields.Subject, database.IdempotencyKeyFields.Key).
+		First(&dbKey).
+		Error
+	return dbKey, err
+}
+
+// hashIdempotentRequest returns the hex-encoded SHA-256 hash of the request
+// method, URL, and body.
+func hashIdempotentRequest(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// getRequestSubject returns the OIDC subject or HTTP basic authentication
+// username of the user that sent the request, or an empty string if no
+// authentication is used.
+func getRequestSubject(c *gin.Context) string {
+	if claims, ok := getOIDCClaims(c); ok {
+		return getOIDCClaimString(claims, "sub")
+	}
+	return c.GetString(gin.AuthUserKey)
+}
+
+// idempotencyResponseWriter keeps a copy of the response body, so it can be
+// stored and replayed on retries.
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
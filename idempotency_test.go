@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashIdempotentRequest(t *testing.T) {
+	hash := func(method, target, body string) string {
+		return hashIdempotentRequest(httptest.NewRequest(method, target, nil), []byte(body))
+	}
+	original := hash("POST", "/api/project/1/build?stage=deploy", `{"foo":"bar"}`)
+
+	assert.Equal(t, original, hash("POST", "/api/project/1/build?stage=deploy", `{"foo":"bar"}`))
+	assert.NotEqual(t, original, hash("POST", "/api/project/2/build?stage=deploy", `{"foo":"bar"}`), "path")
+	assert.NotEqual(t, original, hash("POST", "/api/project/1/build?stage=test", `{"foo":"bar"}`), "query")
+	assert.NotEqual(t, original, hash("POST", "/api/project/1/build?stage=deploy", `{"foo":"baz"}`), "body")
+	assert.NotEqual(t, original, hash("PUT", "/api/project/1/build?stage=deploy", `{"foo":"bar"}`), "method")
+}
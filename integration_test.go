@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// updateGolden rewrites the golden files of the integration tests with the
+// actual responses, via:
+//
+//	go test -run TestIntegration -update
+var updateGolden = flag.Bool("update", false, "Update the golden files of the integration tests.")
+
+const integrationGoldenDir = "testdata/integration"
+
+// testAPI is the full HTTP API of wharf-api, backed by an in-memory SQLite
+// database with all migrations applied.
+type testAPI struct {
+	router *gin.Engine
+	db     *gorm.DB
+}
+
+func newTestAPI(t *testing.T) testAPI {
+	gin.SetMode(gin.TestMode)
+	config := DefaultConfig
+	config.CI.MockTriggerResponse = true
+	config.CI.Engine = CIEngineConfig{
+		ID:   "primary",
+		Name: "Primary",
+		API:  CIEngineAPIWharfCMDv1,
+		URL:  "http://wharf-cmd-provisioner.example.com",
+	}
+	config.DB = DBConfig{
+		Driver: DBDriverSqlite,
+		Path:   ":memory:",
+		// base64 of "0123456789abcdef0123456789abcdef"
+		EncryptionKey: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+	}
+	require.NoError(t, setupConfigReloads(&config, func() (Config, error) { return config, nil }))
+
+	db, err := openDatabase(config.DB)
+	require.NoError(t, err, "open database")
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Each connection to ":memory:" gets its own database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, runDatabaseMigrations(db, config.DB.Driver), "migrate database")
+
+	metricsRegistry := &metrics.Registry{}
+	dbCache := newDBCache(config.Cache)
+	require.NoError(t, dbCache.registerInvalidation(db), "register cache invalidation")
+	engines := newEngineClient(config.CI)
+	engines.tokens, err = newEngineTokenStore(db, config.DB.EncryptionKey)
+	require.NoError(t, err, "create engine token store")
+	logIngester := newLogIngester(db, config.LogIngest)
+	logIngester.start()
+	builds := buildModule{
+		Database:     db,
+		Config:       &config,
+		EngineClient: engines,
+		Dispatcher:   newBuildTriggerDispatcher(db, engines, config.CI.AsyncTrigger),
+		LogIngester:  logIngester,
+	}
+	return testAPI{
+		router: newHTTPRouter(config, db, dbCache, builds, metricsRegistry),
+		db:     db,
+	}
+}
+
+// integrationTestCase is a request sent to the testAPI. The cases are sent in
+// order, so later cases may depend on the objects created by earlier cases.
+type integrationTestCase struct {
+	name   string
+	method string
+	path   string
+	body   string
+	// files are uploaded as the "files" multipart form field, by file name,
+	// instead of sending the body.
+	files map[string]string
+	// canceled cancels the request before sending it, for streaming endpoints
+	// that otherwise never respond.
+	canceled   bool
+	wantStatus int
+	// anyStatus skips checking the status code, for responses that depend on
+	// the generated Swagger document, which is only available after running
+	// swag.
+	anyStatus bool
+	// noGolden skips comparing the response body with the golden file, for
+	// responses that change on every run or with every API change.
+	noGolden bool
+}
+
+const integrationBuildDefinition = `inputs:
+- name: message
+  type: string
+  default: hello
+environments:
+  staging: {}
+  production: {}
+build:
+  steps:
+    echo:
+      container:
+        image: alpine
+        cmd: [echo, hello]
+`
+
+const integrationTRX = `<?xml version="1.0" encoding="UTF-8"?>
+<TestRun>
+  <TestDefinitions>
+    <UnitTest id="1"><TestMethod className="Wharf.Api.Tests.PassTests" name="TestPass" /></UnitTest>
+    <UnitTest id="2"><TestMethod className="Wharf.Api.Tests.FailTests" name="TestFail" /></UnitTest>
+  </TestDefinitions>
+  <Results>
+    <UnitTestResult testId="1" testName="TestPass" duration="00:00:01" startTime="2022-05-10T10:00:00+00:00" endTime="2022-05-10T10:00:01+00:00" outcome="Passed" />
+    <UnitTestResult testId="2" testName="TestFail" duration="00:00:01" startTime="2022-05-10T10:00:00+00:00" endTime="2022-05-10T10:00:01+00:00" outcome="Failed">
+      <Output><ErrorInfo><Message>expected 1, got 2</Message></ErrorInfo></Output>
+    </UnitTestResult>
+  </Results>
+  <ResultSummary outcome="Failed">
+    <Counters total="2" executed="2" passed="1" failed="1" />
+  </ResultSummary>
+</TestRun>
+`
+
+var integrationTestCases = []integrationTestCase{
+	// Meta
+	{name: "health-deprecated", method: http.MethodGet, path: "/health", wantStatus: http.StatusOK},
+	{name: "root", method: http.MethodGet, path: "/", wantStatus: http.StatusOK},
+	{name: "health", method: http.MethodGet, path: "/api/health", wantStatus: http.StatusOK},
+	{name: "ping", method: http.MethodGet, path: "/api/ping", wantStatus: http.StatusOK},
+	{name: "version", method: http.MethodGet, path: "/api/version", wantStatus: http.StatusOK},
+	{name: "problems", method: http.MethodGet, path: "/api/problems", wantStatus: http.StatusOK},
+	{name: "problem", method: http.MethodGet, path: "/api/problems/admin-forbidden", wantStatus: http.StatusOK},
+	{name: "problem-not-found", method: http.MethodGet, path: "/api/problems/no-such-problem", wantStatus: http.StatusBadGateway},
+	{name: "metrics", method: http.MethodGet, path: "/api/metrics", wantStatus: http.StatusOK, noGolden: true},
+	{name: "openapi", method: http.MethodGet, path: "/api/openapi.json", anyStatus: true, noGolden: true},
+	{name: "swagger-json", method: http.MethodGet, path: "/api/swagger.json", wantStatus: http.StatusOK, noGolden: true},
+	{name: "swagger-ui", method: http.MethodGet, path: "/api/swagger/index.html", wantStatus: http.StatusOK, noGolden: true},
+
+	// Admin
+	{name: "admin-config", method: http.MethodGet, path: "/api/admin/config", wantStatus: http.StatusOK, noGolden: true},
+	{name: "admin-config-reload", method: http.MethodPost, path: "/api/admin/config/reload", wantStatus: http.StatusOK},
+	{name: "admin-log-level-update", method: http.MethodPut, path: "/api/admin/log-level", body: `{"level":"debug","scopes":{"GORM":"warn"}}`, wantStatus: http.StatusOK},
+	{name: "admin-log-level-update-invalid", method: http.MethodPut, path: "/api/admin/log-level", body: `{"level":"loud"}`, wantStatus: http.StatusBadRequest},
+	{name: "admin-log-level", method: http.MethodGet, path: "/api/admin/log-level", wantStatus: http.StatusOK},
+	{name: "admin-settings-update", method: http.MethodPut, path: "/api/admin/settings", body: `{"bannerMessage":"Maintenance on Friday"}`, wantStatus: http.StatusOK},
+	{name: "admin-settings", method: http.MethodGet, path: "/api/admin/settings", wantStatus: http.StatusOK},
+	{name: "user-settings-update", method: http.MethodPut, path: "/api/user/settings", body: `{"theme":"light"}`, wantStatus: http.StatusUnauthorized},
+	{name: "user-settings", method: http.MethodGet, path: "/api/user/settings", wantStatus: http.StatusUnauthorized},
+	{name: "user-subscriptions-update", method: http.MethodPut, path: "/api/user/subscriptions", body: `{"projectIds":[1]}`, wantStatus: http.StatusUnauthorized},
+	{name: "user-subscriptions", method: http.MethodGet, path: "/api/user/subscriptions", wantStatus: http.StatusUnauthorized},
+
+	// Engines
+	{name: "engine-list", method: http.MethodGet, path: "/api/engine", wantStatus: http.StatusOK},
+	{name: "engine-token-update", method: http.MethodPut, path: "/api/engine/primary/token", body: `{"token":"engine-secret"}`, wantStatus: http.StatusNoContent},
+	{name: "engine-token-delete", method: http.MethodDelete, path: "/api/engine/primary/token", wantStatus: http.StatusNoContent},
+	{name: "engine-ping-unknown", method: http.MethodPost, path: "/api/engine/unknown/ping", wantStatus: http.StatusBadGateway},
+
+	// Tokens
+	{name: "token-create", method: http.MethodPost, path: "/api/token", body: `{"token":"secret","userName":"wharf"}`, wantStatus: http.StatusCreated},
+	{name: "token-create-invalid", method: http.MethodPost, path: "/api/token", body: `{"token":`, wantStatus: http.StatusBadRequest},
+	{name: "token-get", method: http.MethodGet, path: "/api/token/1", wantStatus: http.StatusOK},
+	{name: "token-get-not-found", method: http.MethodGet, path: "/api/token/999", wantStatus: http.StatusBadGateway},
+	{name: "token-update", method: http.MethodPut, path: "/api/token/1", body: `{"token":"secret2","userName":"wharf"}`, wantStatus: http.StatusOK},
+	{name: "token-patch", method: http.MethodPatch, path: "/api/token/1", body: `{"userName":"wharf-bot"}`, wantStatus: http.StatusOK},
+	{name: "token-list", method: http.MethodGet, path: "/api/token", wantStatus: http.StatusOK},
+	{name: "token-put-deprecated", method: http.MethodPut, path: "/api/token", body: `{"token":"other","userName":"other"}`, wantStatus: http.StatusOK},
+	{name: "token-search-deprecated", method: http.MethodPost, path: "/api/tokens/search", body: `{"userName":"other"}`, wantStatus: http.StatusOK},
+	{name: "token-list-deprecated", method: http.MethodGet, path: "/api/tokens", wantStatus: http.StatusOK},
+
+	// Providers
+	{name: "provider-create", method: http.MethodPost, path: "/api/provider", body: `{"name":"gitlab","url":"https://gitlab.example.com","tokenId":1}`, wantStatus: http.StatusCreated},
+	{name: "provider-create-invalid", method: http.MethodPost, path: "/api/provider", body: `{"name":"gitlab","url":"not a url"}`, wantStatus: http.StatusBadRequest},
+	{name: "provider-get", method: http.MethodGet, path: "/api/provider/1", wantStatus: http.StatusOK},
+	{name: "provider-update", method: http.MethodPut, path: "/api/provider/1", body: `{"name":"gitlab","url":"https://gitlab.example.com/","tokenId":1}`, wantStatus: http.StatusOK},
+	{name: "provider-patch", method: http.MethodPatch, path: "/api/provider/1", body: `{"uploadUrl":"https://uploads.gitlab.example.com"}`, wantStatus: http.StatusOK},
+	{name: "provider-list", method: http.MethodGet, path: "/api/provider", wantStatus: http.StatusOK},
+	{name: "provider-put-deprecated", method: http.MethodPut, path: "/api/provider", body: `{"name":"github","url":"https://github.example.com"}`, wantStatus: http.StatusOK},
+	{name: "provider-search-deprecated", method: http.MethodPost, path: "/api/providers/search", body: `{"name":"github"}`, wantStatus: http.StatusOK},
+	{name: "provider-list-deprecated", method: http.MethodGet, path: "/api/providers", wantStatus: http.StatusOK},
+	{name: "provider-sync", method: http.MethodPost, path: "/api/provider/1/sync", wantStatus: http.StatusBadRequest},
+	{name: "provider-sync-get-not-found", method: http.MethodGet, path: "/api/provider/1/sync/1", wantStatus: http.StatusBadGateway},
+
+	// Projects
+	{name: "project-create", method: http.MethodPost, path: "/api/project", body: `{"name":"wharf-api","groupName":"iver-wharf","providerId":1,"tokenId":1,"gitUrl":"git@gitlab.example.com:iver-wharf/wharf-api.git","buildDefinition":` + jsonString(integrationBuildDefinition) + `}`, wantStatus: http.StatusCreated},
+	{name: "project-create-other", method: http.MethodPost, path: "/api/project", body: `{"name":"wharf-web","groupName":"iver-wharf","providerId":1,"tokenId":1}`, wantStatus: http.StatusCreated},
+	{name: "project-create-invalid", method: http.MethodPost, path: "/api/project", body: `{"groupName":"iver-wharf"}`, wantStatus: http.StatusBadRequest},
+	{name: "project-get", method: http.MethodGet, path: "/api/project/1", wantStatus: http.StatusOK},
+	{name: "project-get-not-found", method: http.MethodGet, path: "/api/project/999", wantStatus: http.StatusBadGateway},
+	{name: "project-update", method: http.MethodPut, path: "/api/project/1", body: `{"name":"wharf-api","groupName":"iver-wharf","description":"The Wharf API","providerId":1,"tokenId":1,"gitUrl":"git@gitlab.example.com:iver-wharf/wharf-api.git","buildDefinition":` + jsonString(integrationBuildDefinition) + `}`, wantStatus: http.StatusOK},
+	{name: "project-patch", method: http.MethodPatch, path: "/api/project/1", body: `{"avatarUrl":"https://gitlab.example.com/avatar.png"}`, wantStatus: http.StatusOK},
+	{name: "project-list", method: http.MethodGet, path: "/api/project?orderby=projectId+asc", wantStatus: http.StatusOK},
+	{name: "project-put-deprecated", method: http.MethodPut, path: "/api/project", body: `{"name":"wharf-cmd","groupName":"iver-wharf","providerId":1,"tokenId":1}`, wantStatus: http.StatusCreated},
+	{name: "project-search-deprecated", method: http.MethodPost, path: "/api/projects/search", body: `{"name":"wharf-cmd"}`, wantStatus: http.StatusOK},
+	{name: "project-list-deprecated", method: http.MethodGet, path: "/api/projects", wantStatus: http.StatusOK},
+	{name: "project-build-definition", method: http.MethodGet, path: "/api/project/1/build-definition", wantStatus: http.StatusOK},
+	{name: "project-build-definition-validate", method: http.MethodPost, path: "/api/project/1/build-definition/validate", body: integrationBuildDefinition, wantStatus: http.StatusOK},
+	{name: "project-override-update", method: http.MethodPut, path: "/api/project/1/override", body: `{"description":"Overridden description"}`, wantStatus: http.StatusOK},
+	{name: "project-override", method: http.MethodGet, path: "/api/project/1/override", wantStatus: http.StatusOK},
+	{name: "project-override-delete", method: http.MethodDelete, path: "/api/project/1/override", wantStatus: http.StatusNoContent},
+	{name: "project-label-update", method: http.MethodPut, path: "/api/project/1/label", body: `{"team":"platform"}`, wantStatus: http.StatusOK},
+	{name: "project-input-defaults-update", method: http.MethodPut, path: "/api/project/1/input-defaults", body: `{"inputs":{"message":"from defaults"},"environments":{"production":{"message":"from production"}}}`, wantStatus: http.StatusOK},
+	{name: "project-input-defaults", method: http.MethodGet, path: "/api/project/1/input-defaults", wantStatus: http.StatusOK},
+	{name: "project-engines-update", method: http.MethodPut, path: "/api/project/2/engines", body: `{"engineIds":["PRIMARY","primary"]}`, wantStatus: http.StatusOK},
+	{name: "project-engines-update-unknown", method: http.MethodPut, path: "/api/project/2/engines", body: `{"engineIds":["experimental"]}`, wantStatus: http.StatusBadRequest},
+	{name: "project-engines", method: http.MethodGet, path: "/api/project/2/engines", wantStatus: http.StatusOK},
+	{name: "project-archive", method: http.MethodPost, path: "/api/project/2/archive", wantStatus: http.StatusOK},
+	{name: "project-build-archived", method: http.MethodPost, path: "/api/project/2/build", wantStatus: http.StatusConflict},
+	{name: "project-unarchive", method: http.MethodPost, path: "/api/project/2/unarchive", wantStatus: http.StatusOK},
+	{name: "project-transfer", method: http.MethodPost, path: "/api/project/2/transfer", body: `{"groupName":"iver-wharf","providerId":1,"tokenId":1}`, wantStatus: http.StatusOK},
+	{name: "project-transfer-empty", method: http.MethodPost, path: "/api/project/2/transfer", body: `{}`, wantStatus: http.StatusBadRequest},
+	{name: "admin-project-instance", method: http.MethodPut, path: "/api/admin/project/3/instance", body: `{"instanceId":"other"}`, wantStatus: http.StatusOK},
+
+	// Groups
+	{name: "group-list", method: http.MethodGet, path: "/api/group", wantStatus: http.StatusOK},
+	{name: "group-get", method: http.MethodGet, path: "/api/group/1", wantStatus: http.StatusOK},
+	{name: "group-projects", method: http.MethodGet, path: "/api/group/1/project", wantStatus: http.StatusOK},
+
+	// Branches
+	{name: "branch-create", method: http.MethodPost, path: "/api/project/1/branch", body: `{"name":"master","default":true}`, wantStatus: http.StatusCreated},
+	{name: "branch-create-feature", method: http.MethodPost, path: "/api/project/1/branch", body: `{"name":"feature/login"}`, wantStatus: http.StatusCreated},
+	{name: "branch-create-other", method: http.MethodPost, path: "/api/project/2/branch", body: `{"name":"main","default":true}`, wantStatus: http.StatusCreated},
+	{name: "branch-create-deprecated", method: http.MethodPost, path: "/api/branch", body: `{"projectId":1,"name":"develop"}`, wantStatus: http.StatusCreated},
+	{name: "branch-list", method: http.MethodGet, path: "/api/project/1/branch", wantStatus: http.StatusOK},
+	{name: "branch-get", method: http.MethodGet, path: "/api/project/1/branch/feature/login", wantStatus: http.StatusOK},
+	{name: "branch-get-deprecated", method: http.MethodGet, path: "/api/branch/1", wantStatus: http.StatusNotImplemented},
+	{name: "branch-list-deprecated", method: http.MethodGet, path: "/api/branches", wantStatus: http.StatusNotImplemented},
+	{name: "branch-put-deprecated", method: http.MethodPut, path: "/api/branches", body: `[{"projectId":3,"name":"main","default":true}]`, wantStatus: http.StatusOK},
+	{name: "branch-delete", method: http.MethodDelete, path: "/api/project/1/branch/develop", wantStatus: http.StatusNoContent},
+	{name: "branch-list-update", method: http.MethodPut, path: "/api/project/1/branch", body: `{"defaultBranch":"master","branches":[{"name":"master"},{"name":"feature/login"}]}`, wantStatus: http.StatusOK},
+
+	// Environments
+	// The production and staging environments are discovered from the build
+	// definition when creating the project, as ID 1 and 2.
+	{name: "environment-create", method: http.MethodPost, path: "/api/project/1/environment", body: `{"name":"qa","description":"QA"}`, wantStatus: http.StatusCreated},
+	{name: "environment-create-temp", method: http.MethodPost, path: "/api/project/1/environment", body: `{"name":"temp"}`, wantStatus: http.StatusCreated},
+	{name: "environment-get", method: http.MethodGet, path: "/api/project/1/environment/1", wantStatus: http.StatusOK},
+	{name: "environment-update", method: http.MethodPut, path: "/api/project/1/environment/1", body: `{"name":"production","description":"Production","isProtected":true}`, wantStatus: http.StatusOK},
+	{name: "environment-delete", method: http.MethodDelete, path: "/api/project/1/environment/4", wantStatus: http.StatusNoContent},
+	{name: "environment-list", method: http.MethodGet, path: "/api/project/1/environment", wantStatus: http.StatusOK},
+
+	// Project triggers
+	{name: "trigger-create", method: http.MethodPost, path: "/api/project/1/trigger", body: `{"targetProjectId":2,"branchFilter":"master"}`, wantStatus: http.StatusCreated},
+	{name: "trigger-create-temp", method: http.MethodPost, path: "/api/project/1/trigger", body: `{"targetProjectId":3}`, wantStatus: http.StatusCreated},
+	{name: "trigger-get", method: http.MethodGet, path: "/api/project/1/trigger/1", wantStatus: http.StatusOK},
+	{name: "trigger-update", method: http.MethodPut, path: "/api/project/1/trigger/1", body: `{"targetProjectId":2,"branchFilter":"master","stage":"ALL"}`, wantStatus: http.StatusOK},
+	{name: "trigger-delete", method: http.MethodDelete, path: "/api/project/1/trigger/2", wantStatus: http.StatusNoContent},
+	{name: "trigger-list", method: http.MethodGet, path: "/api/project/1/trigger", wantStatus: http.StatusOK},
+
+	// Builds
+	{name: "build-start", method: http.MethodPost, path: "/api/project/1/build?branch=master&environment=staging", body: `{"message":"hi"}`, wantStatus: http.StatusOK},
+	{name: "build-start-invalid-inputs", method: http.MethodPost, path: "/api/project/1/build?branch=master", body: `{"unknown":"value"}`, wantStatus: http.StatusBadRequest},
+	{name: "build-start-deprecated", method: http.MethodPost, path: "/api/project/1/ALL/run?branch=feature/login", wantStatus: http.StatusOK},
+	{name: "build-start-protected", method: http.MethodPost, path: "/api/project/1/build?branch=master&environment=production", wantStatus: http.StatusAccepted},
+	{name: "build-start-protected-rejected", method: http.MethodPost, path: "/api/project/1/build?branch=master&environment=production", wantStatus: http.StatusAccepted},
+	{name: "build-matrix", method: http.MethodPost, path: "/api/project/1/build/matrix?branch=master&branch=feature/login", wantStatus: http.StatusAccepted},
+	{name: "build-get", method: http.MethodGet, path: "/api/build/1", wantStatus: http.StatusOK},
+	{name: "build-get-not-found", method: http.MethodGet, path: "/api/build/999", wantStatus: http.StatusBadGateway},
+	{name: "build-params", method: http.MethodGet, path: "/api/build/1/param", wantStatus: http.StatusOK},
+	{name: "build-status-running", method: http.MethodPut, path: "/api/build/1/status", body: `{"status":"Running"}`, wantStatus: http.StatusOK},
+	{name: "build-stage-running", method: http.MethodPut, path: "/api/build/1/stage/test/status", body: `{"status":"Running","timestamp":"2022-05-10T10:00:00Z"}`, wantStatus: http.StatusOK},
+	{name: "build-stage-completed", method: http.MethodPut, path: "/api/build/1/stage/test/status", body: `{"status":"Completed","timestamp":"2022-05-10T10:01:30Z"}`, wantStatus: http.StatusOK},
+	{name: "build-stage-invalid", method: http.MethodPut, path: "/api/build/1/stage/test/status", body: `{"status":"AwaitingApproval"}`, wantStatus: http.StatusBadRequest},
+	{name: "build-log-create", method: http.MethodPost, path: "/api/build/1/log", body: `{"message":"Compiling wharf-api","timestamp":"2022-05-10T10:00:00Z"}`, wantStatus: http.StatusCreated},
+	{name: "build-status-completed", method: http.MethodPut, path: "/api/build/1/status", body: `{"status":"Completed"}`, wantStatus: http.StatusOK},
+	{name: "build-status-deprecated", method: http.MethodPut, path: "/api/build/2?status=TriggerFailed", wantStatus: http.StatusOK},
+	{name: "build-log-list", method: http.MethodGet, path: "/api/build/1/log", wantStatus: http.StatusOK},
+	{name: "build-log-download", method: http.MethodGet, path: "/api/build/1/log/download", wantStatus: http.StatusOK},
+	{name: "build-stream", method: http.MethodGet, path: "/api/build/1/stream", canceled: true, wantStatus: http.StatusOK},
+	{name: "log-search", method: http.MethodGet, path: "/api/log/search?match=compiling", wantStatus: http.StatusOK},
+	{name: "build-label-update", method: http.MethodPut, path: "/api/build/1/label", body: `{"release":"v5.3.0"}`, wantStatus: http.StatusOK},
+	{name: "build-link-create", method: http.MethodPost, path: "/api/build/1/link", body: `{"name":"Report","url":"https://reports.example.com/1"}`, wantStatus: http.StatusCreated},
+	{name: "build-comment-create", method: http.MethodPost, path: "/api/build/1/comment", body: `{"text":"Looks good."}`, wantStatus: http.StatusCreated},
+	{name: "build-comment-create-temp", method: http.MethodPost, path: "/api/build/1/comment", body: `{"text":"Oops."}`, wantStatus: http.StatusCreated},
+	{name: "build-comment-delete", method: http.MethodDelete, path: "/api/build/1/comment/2", wantStatus: http.StatusNoContent},
+	{name: "build-comment-list", method: http.MethodGet, path: "/api/build/1/comment", wantStatus: http.StatusOK},
+	{name: "build-artifact-create", method: http.MethodPost, path: "/api/build/1/artifact", files: map[string]string{"report.txt": "hello"}, wantStatus: http.StatusCreated},
+	{name: "build-artifact-list", method: http.MethodGet, path: "/api/build/1/artifact", wantStatus: http.StatusOK},
+	{name: "build-artifact-get", method: http.MethodGet, path: "/api/build/1/artifact/1", wantStatus: http.StatusOK},
+	{name: "build-artifact-list-deprecated", method: http.MethodGet, path: "/api/build/1/artifacts", wantStatus: http.StatusOK},
+	{name: "build-test-result-create", method: http.MethodPost, path: "/api/build/1/test-result/", files: map[string]string{"results.trx": integrationTRX}, wantStatus: http.StatusOK},
+	{name: "build-test-result-list-summary", method: http.MethodGet, path: "/api/build/1/test-result/list-summary", wantStatus: http.StatusOK},
+	{name: "build-test-result-summary", method: http.MethodGet, path: "/api/build/1/test-result/summary", wantStatus: http.StatusOK},
+	{name: "build-test-result-summary-by-artifact", method: http.MethodGet, path: "/api/build/1/test-result/summary/2", wantStatus: http.StatusOK},
+	{name: "build-test-result-details-by-artifact", method: http.MethodGet, path: "/api/build/1/test-result/summary/2/detail", wantStatus: http.StatusOK},
+	{name: "build-test-result-details", method: http.MethodGet, path: "/api/build/1/test-result/detail", wantStatus: http.StatusOK},
+	{name: "build-test-result-details-filtered", method: http.MethodGet, path: "/api/build/1/test-result/detail?classNameMatch=FailTests", wantStatus: http.StatusOK},
+	{name: "build-tests-results-deprecated", method: http.MethodGet, path: "/api/build/1/tests-results", wantStatus: http.StatusOK},
+	{name: "build-timeline", method: http.MethodGet, path: "/api/build/1/timeline", wantStatus: http.StatusOK},
+	{name: "build-status-history", method: http.MethodGet, path: "/api/build/1/status-history", wantStatus: http.StatusOK},
+	{name: "build-test-result-reparse", method: http.MethodPost, path: "/api/build/1/test-result/reparse", wantStatus: http.StatusOK},
+	{name: "build-test-result-reparse-invalid", method: http.MethodPost, path: "/api/build/1/test-result/reparse?fileName=[", wantStatus: http.StatusBadRequest},
+	{name: "admin-test-result-reparse", method: http.MethodPost, path: "/api/admin/test-result/reparse?fileName=*.trx", wantStatus: http.StatusOK},
+	{name: "build-artifact-delete", method: http.MethodDelete, path: "/api/build/1/artifact/2", wantStatus: http.StatusNoContent},
+	{name: "build-test-result-details-after-artifact-delete", method: http.MethodGet, path: "/api/build/1/test-result/detail?testResultSummaryId=1", wantStatus: http.StatusOK},
+	{name: "build-artifact-promote-build", method: http.MethodPost, path: "/api/build/1/artifact/1/promote", body: `{"targetBuildId":2}`, wantStatus: http.StatusCreated},
+	{name: "build-artifact-promote-release", method: http.MethodPost, path: "/api/build/1/artifact/1/promote", body: `{"release":"v1.0.0"}`, wantStatus: http.StatusCreated},
+	{name: "build-artifact-promote-release-conflict", method: http.MethodPost, path: "/api/build/1/artifact/1/promote", body: `{"release":"v1.0.0"}`, wantStatus: http.StatusConflict},
+	{name: "build-artifact-promote-invalid", method: http.MethodPost, path: "/api/build/1/artifact/1/promote", body: `{"targetBuildId":2,"release":"v1.0.0"}`, wantStatus: http.StatusBadRequest},
+	{name: "release-artifact-list", method: http.MethodGet, path: "/api/project/1/release-artifact?release=v1.0.0", wantStatus: http.StatusOK},
+	{name: "release-artifact-get", method: http.MethodGet, path: "/api/project/1/release-artifact/1", wantStatus: http.StatusOK},
+	{name: "release-artifact-delete", method: http.MethodDelete, path: "/api/project/1/release-artifact/1", wantStatus: http.StatusNoContent},
+	{name: "project-artifact-list", method: http.MethodGet, path: "/api/project/1/artifact?name=files&fileNameMatch=report&limit=10", wantStatus: http.StatusOK},
+	{name: "project-artifact-list-not-found", method: http.MethodGet, path: "/api/project/999/artifact", wantStatus: http.StatusBadGateway},
+	{name: "build-retrigger", method: http.MethodPost, path: "/api/build/2/retrigger", wantStatus: http.StatusOK},
+	{name: "approval-list", method: http.MethodGet, path: "/api/approval", wantStatus: http.StatusOK},
+	{name: "build-approve", method: http.MethodPost, path: "/api/build/3/approve", body: `{"comment":"Ship it."}`, wantStatus: http.StatusOK},
+	{name: "build-reject", method: http.MethodPost, path: "/api/build/4/reject", body: `{"comment":"Not today."}`, wantStatus: http.StatusOK},
+	{name: "build-group", method: http.MethodGet, path: "/api/build-group/1", wantStatus: http.StatusOK},
+	{name: "build-list", method: http.MethodGet, path: "/api/build?orderby=buildId+asc", wantStatus: http.StatusOK},
+	{name: "build-list-filtered", method: http.MethodGet, path: "/api/build?projectId=1&status=Completed", wantStatus: http.StatusOK},
+	{name: "build-list-invalid", method: http.MethodGet, path: "/api/build?status=Unknown", wantStatus: http.StatusBadRequest},
+	{name: "build-search-deprecated", method: http.MethodPost, path: "/api/builds/search", wantStatus: http.StatusNotImplemented},
+	{name: "project-builds-deprecated", method: http.MethodGet, path: "/api/projects/1/builds?limit=10&offset=0", wantStatus: http.StatusOK},
+	{name: "build-latest", method: http.MethodGet, path: "/api/project/1/build/latest?branch=master", wantStatus: http.StatusOK},
+	{name: "build-latest-per-branch", method: http.MethodGet, path: "/api/project/1/build/latest-per-branch", wantStatus: http.StatusOK},
+	{name: "build-by-number", method: http.MethodGet, path: "/api/project/1/build/number/2", wantStatus: http.StatusOK},
+	{name: "build-by-number-not-found", method: http.MethodGet, path: "/api/project/1/build/number/999", wantStatus: http.StatusBadGateway},
+	{name: "badge-json", method: http.MethodGet, path: "/api/project/1/badge.json?branch=master", wantStatus: http.StatusOK},
+	{name: "badge-svg", method: http.MethodGet, path: "/api/project/1/badge.svg?branch=master", wantStatus: http.StatusOK},
+	{name: "build-start-scheduled", method: http.MethodPost, path: "/api/project/1/build?branch=master&scheduleAt=2099-01-01T03:00:00Z", wantStatus: http.StatusAccepted},
+	{name: "build-start-scheduled-in-past", method: http.MethodPost, path: "/api/project/1/build?branch=master&scheduleAt=2020-01-01T03:00:00Z", wantStatus: http.StatusBadRequest},
+	{name: "build-cancel-not-pending", method: http.MethodDelete, path: "/api/build/1", wantStatus: http.StatusConflict},
+	{name: "build-cancel", method: http.MethodDelete, path: "/api/build/8", wantStatus: http.StatusNoContent},
+
+	// Statistics
+	{name: "stats-backfill", method: http.MethodPost, path: "/api/admin/stats/builds/backfill?from=2022-05-01&to=2022-05-10", wantStatus: http.StatusNoContent},
+	{name: "stats-builds", method: http.MethodGet, path: "/api/stats/builds?from=2022-05-01&to=2022-05-10", wantStatus: http.StatusOK},
+	{name: "stats-workers", method: http.MethodGet, path: "/api/stats/workers", wantStatus: http.StatusOK, noGolden: true},
+
+	// Inbound webhooks
+	{name: "project-webhook-get-missing", method: http.MethodGet, path: "/api/project/1/webhook", wantStatus: http.StatusBadGateway},
+	// Token and secret are random.
+	{name: "project-webhook-create", method: http.MethodPut, path: "/api/project/1/webhook", body: `{"isEnabled":true,"requireSignature":true}`, wantStatus: http.StatusCreated, noGolden: true},
+	{name: "project-webhook-update", method: http.MethodPut, path: "/api/project/1/webhook", body: `{"isEnabled":false,"requireSignature":true}`, wantStatus: http.StatusOK},
+	{name: "project-webhook-get", method: http.MethodGet, path: "/api/project/1/webhook", wantStatus: http.StatusOK},
+	{name: "project-webhook-regenerate", method: http.MethodPost, path: "/api/project/1/webhook/regenerate", wantStatus: http.StatusOK, noGolden: true},
+	{name: "webhook-trigger-unknown-token", method: http.MethodPost, path: "/api/webhook/trigger/does-not-exist", body: `{"branch":"master"}`, wantStatus: http.StatusNotFound},
+	{name: "project-webhook-delete", method: http.MethodDelete, path: "/api/project/1/webhook", wantStatus: http.StatusNoContent},
+
+	// Git triggers
+	{name: "git-trigger-create", method: http.MethodPost, path: "/api/project/1/git-trigger", body: `{"event":"push","branchFilter":"master"}`, wantStatus: http.StatusCreated},
+	{name: "git-trigger-create-invalid-event", method: http.MethodPost, path: "/api/project/1/git-trigger", body: `{"event":"tag"}`, wantStatus: http.StatusBadRequest},
+	{name: "git-trigger-list", method: http.MethodGet, path: "/api/project/1/git-trigger", wantStatus: http.StatusOK},
+	{name: "git-trigger-get", method: http.MethodGet, path: "/api/project/1/git-trigger/1", wantStatus: http.StatusOK},
+	{name: "git-trigger-update", method: http.MethodPut, path: "/api/project/1/git-trigger/1", body: `{"event":"merge-request","branchFilter":"release/*","stage":"deploy","environment":"stage"}`, wantStatus: http.StatusOK},
+	{name: "webhook-github-not-configured", method: http.MethodPost, path: "/api/webhook/github", body: `{}`, wantStatus: http.StatusForbidden},
+	{name: "webhook-gitlab-not-configured", method: http.MethodPost, path: "/api/webhook/gitlab", body: `{}`, wantStatus: http.StatusForbidden},
+	{name: "git-trigger-delete", method: http.MethodDelete, path: "/api/project/1/git-trigger/1", wantStatus: http.StatusNoContent},
+
+	// Notification rules
+	{name: "notification-rule-create", method: http.MethodPost, path: "/api/notification-rule", body: `{"name":"Slow builds","projectId":1,"status":"Running","threshold":3600,"notifier":"slack","target":"https://hooks.example.com/services/T000/B000/XXXX"}`, wantStatus: http.StatusCreated},
+	{name: "notification-rule-create-temp", method: http.MethodPost, path: "/api/notification-rule", body: `{"status":"Scheduling","threshold":600,"notifier":"webhook","target":"https://example.com/hook"}`, wantStatus: http.StatusCreated},
+	{name: "notification-rule-create-email-no-smtp", method: http.MethodPost, path: "/api/notification-rule", body: `{"status":"Running","threshold":600,"notifier":"email","target":"alice@example.com"}`, wantStatus: http.StatusConflict},
+	{name: "notification-rule-get", method: http.MethodGet, path: "/api/notification-rule/1", wantStatus: http.StatusOK},
+	{name: "notification-rule-update", method: http.MethodPut, path: "/api/notification-rule/1", body: `{"name":"Slow builds","projectId":1,"status":"Running","threshold":7200,"notifier":"teams","target":"https://example.webhook.office.com/webhookb2/XXXX"}`, wantStatus: http.StatusOK},
+	{name: "notification-rule-delete", method: http.MethodDelete, path: "/api/notification-rule/2", wantStatus: http.StatusNoContent},
+	{name: "notification-rule-list", method: http.MethodGet, path: "/api/notification-rule?projectId=1", wantStatus: http.StatusOK},
+
+	// Cleanup
+	{name: "project-delete", method: http.MethodDelete, path: "/api/project/2", wantStatus: http.StatusNoContent},
+	{name: "project-get-deleted", method: http.MethodGet, path: "/api/project/2", wantStatus: http.StatusBadGateway},
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestIntegration(t *testing.T) {
+	api := newTestAPI(t)
+	for _, tc := range integrationTestCases {
+		// Not run as subtests, as the cases depend on each other.
+		req := newIntegrationRequest(t, tc)
+		w := httptest.NewRecorder()
+		api.router.ServeHTTP(w, req)
+
+		if !tc.anyStatus && !assert.Equal(t, tc.wantStatus, w.Code, "%s: %s %s\n%s", tc.name, tc.method, tc.path, w.Body.String()) {
+			continue
+		}
+		if tc.noGolden {
+			continue
+		}
+		assertIntegrationGolden(t, tc.name, w)
+	}
+}
+
+// TestIntegration_coversAllRoutes makes sure that new endpoints are added to
+// the integration test cases.
+func TestIntegration_coversAllRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	covered := make(map[string]bool)
+	for _, tc := range integrationTestCases {
+		covered[tc.method+" "+matchIntegrationRoute(api.router.Routes(), tc)] = true
+	}
+	for _, route := range api.router.Routes() {
+		assert.True(t, covered[route.Method+" "+route.Path],
+			"route %s %s has no integration test case", route.Method, route.Path)
+	}
+}
+
+func matchIntegrationRoute(routes gin.RoutesInfo, tc integrationTestCase) string {
+	path, _, _ := strings.Cut(tc.path, "?")
+	for _, route := range routes {
+		if route.Method != tc.method {
+			continue
+		}
+		pattern := regexp.MustCompile(`:[^/]+`).ReplaceAllString(regexp.QuoteMeta(route.Path), `[^/]+`)
+		pattern = regexp.MustCompile(`\\\*[^/]+`).ReplaceAllString(pattern, `.*`)
+		if regexp.MustCompile("^" + pattern + "$").MatchString(path) {
+			return route.Path
+		}
+	}
+	return ""
+}
+
+func newIntegrationRequest(t *testing.T, tc integrationTestCase) *http.Request {
+	req := newIntegrationRequestWithBody(t, tc)
+	// Request IDs are otherwise random, and are included in problem responses.
+	req.Header.Set(requestIDHeader, tc.name)
+	if tc.canceled {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancel()
+		req = req.WithContext(ctx)
+	}
+	return req
+}
+
+func newIntegrationRequestWithBody(t *testing.T, tc integrationTestCase) *http.Request {
+	if tc.files == nil {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for fileName, content := range tc.files {
+		fw, err := mw.CreateFormFile("files", fileName)
+		require.NoError(t, err)
+		_, err = io.WriteString(fw, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+	req := httptest.NewRequest(tc.method, tc.path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// integrationGolden is the content of the golden files.
+type integrationGolden struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	// Body is the response body if it is JSON, as normalized by
+	// normalizeIntegrationJSON.
+	Body any `json:"body,omitempty"`
+	// Text is the response body if it is not JSON.
+	Text string `json:"text,omitempty"`
+}
+
+func assertIntegrationGolden(t *testing.T, name string, w *httptest.ResponseRecorder) {
+	got := integrationGolden{
+		Status:      w.Code,
+		ContentType: w.Header().Get("Content-Type"),
+	}
+	var body any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err == nil {
+		got.Body = normalizeIntegrationJSON(body)
+	} else {
+		got.Text = w.Body.String()
+	}
+	var gotBuf bytes.Buffer
+	enc := json.NewEncoder(&gotBuf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	require.NoError(t, enc.Encode(got))
+	gotBytes := gotBuf.Bytes()
+
+	fileName := filepath.Join(integrationGoldenDir, name+".json")
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(integrationGoldenDir, 0755))
+		require.NoError(t, os.WriteFile(fileName, gotBytes, 0644))
+		return
+	}
+	wantBytes, err := os.ReadFile(fileName)
+	if !assert.NoError(t, err, "%s: missing golden file, run the tests with -update to create it", name) {
+		return
+	}
+	assert.Equal(t, string(wantBytes), string(gotBytes), "%s: response differs from golden file %s", name, fileName)
+}
+
+// normalizeIntegrationJSON replaces all dates and build durations with
+// placeholders, as they differ on every run.
+func normalizeIntegrationJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if _, ok := value.(float64); ok && isIntegrationDurationKey(key) {
+				v[key] = "<duration>"
+				continue
+			}
+			v[key] = normalizeIntegrationJSON(value)
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = normalizeIntegrationJSON(value)
+		}
+		return v
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return "<date>"
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isIntegrationDurationKey(key string) bool {
+	switch key {
+	case "duration", "queueDuration", "runDuration":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,249 @@
+package builddef
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Reserved top-level and stage-level keys in a .wharf-ci.yml file. All other
+// top-level keys are stages, and all other stage-level keys are steps.
+const (
+	keyInputs       = "inputs"
+	keyEnvironments = "environments"
+)
+
+// Definition is a parsed .wharf-ci.yml build definition. Stages, steps, and
+// environments keep the order in which they were declared.
+type Definition struct {
+	Inputs       []Input
+	Environments []Environment
+	Stages       []Stage
+}
+
+// Input is a variable that can be set when starting a new build.
+type Input struct {
+	Name    string
+	Type    string
+	Default any
+	// Values holds the allowed values for inputs of type "choice".
+	Values []any
+	Line   int
+}
+
+// Environment is a named set of variables that a stage can be run in.
+type Environment struct {
+	Name string
+	Vars map[string]any
+	Line int
+}
+
+// Stage is a named group of steps that are run in parallel.
+type Stage struct {
+	Name         string
+	Environments []string
+	Steps        []Step
+	Line         int
+}
+
+// Step is a single named action in a stage, such as running a container or
+// building a Docker image.
+type Step struct {
+	Name string
+	// Type is the step type, such as "container" or "kaniko".
+	Type   string
+	Fields map[string]any
+	Line   int
+}
+
+// Parse parses a YAML-formatted .wharf-ci.yml build definition. Parts of the
+// definition that do not have the expected shape are skipped.
+//
+// An error is only returned if the YAML syntax is invalid.
+func Parse(buildDef string) (Definition, error) {
+	var p parser
+	err := p.parse(buildDef)
+	return p.def, err
+}
+
+type parser struct {
+	def     Definition
+	issues  []Issue
+	varRefs []varReference
+}
+
+func (p *parser) parse(buildDef string) error {
+	if buildDef == "" {
+		return nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(buildDef), &doc); err != nil {
+		return err
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		return nil
+	}
+	if !p.expectKind(root, yaml.MappingNode, "", "a mapping of stages") {
+		return nil
+	}
+	forEachMappingPair(root, func(key, value *yaml.Node) {
+		switch key.Value {
+		case keyInputs:
+			p.def.Inputs = p.parseInputs(value)
+		case keyEnvironments:
+			p.def.Environments = p.parseEnvironments(value)
+		default:
+			p.def.Stages = append(p.def.Stages, p.parseStage(key, value))
+		}
+	})
+	return nil
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+func forEachMappingPair(node *yaml.Node, f func(key, value *yaml.Node)) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		f(node.Content[i], node.Content[i+1])
+	}
+}
+
+func (p *parser) parseInputs(node *yaml.Node) []Input {
+	if !p.expectKind(node, yaml.SequenceNode, keyInputs, "a list of inputs") {
+		return nil
+	}
+	inputs := make([]Input, 0, len(node.Content))
+	for i, inputNode := range node.Content {
+		path := fmt.Sprintf("%s[%d]", keyInputs, i)
+		if !p.expectKind(inputNode, yaml.MappingNode, path, "an input mapping") {
+			continue
+		}
+		input := Input{Line: inputNode.Line}
+		forEachMappingPair(inputNode, func(key, value *yaml.Node) {
+			switch key.Value {
+			case "name":
+				input.Name = value.Value
+			case "type":
+				input.Type = value.Value
+			case "default":
+				input.Default = decodeAny(value)
+			case "values":
+				if !p.expectKind(value, yaml.SequenceNode, path+".values", "a list of values") {
+					return
+				}
+				if values, ok := decodeAny(value).([]any); ok {
+					input.Values = values
+				}
+			default:
+				p.warnf(key, path+"."+key.Value, "unknown input field %q", key.Value)
+			}
+		})
+		inputs = append(inputs, input)
+	}
+	return inputs
+}
+
+func (p *parser) parseEnvironments(node *yaml.Node) []Environment {
+	if !p.expectKind(node, yaml.MappingNode, keyEnvironments, "a mapping of environments") {
+		return nil
+	}
+	var envs []Environment
+	forEachMappingPair(node, func(key, value *yaml.Node) {
+		path := keyEnvironments + "." + key.Value
+		env := Environment{
+			Name: key.Value,
+			Vars: map[string]any{},
+			Line: key.Line,
+		}
+		if !isNull(value) {
+			p.expectKind(value, yaml.MappingNode, path, "a mapping of variables")
+		}
+		forEachMappingPair(value, func(varKey, varValue *yaml.Node) {
+			if p.expectKind(varValue, yaml.ScalarNode, path+"."+varKey.Value, "a scalar value") {
+				env.Vars[varKey.Value] = decodeAny(varValue)
+			}
+		})
+		envs = append(envs, env)
+	})
+	return envs
+}
+
+func (p *parser) parseStage(key, node *yaml.Node) Stage {
+	stage := Stage{
+		Name: key.Value,
+		Line: key.Line,
+	}
+	if !p.expectKind(node, yaml.MappingNode, key.Value, "a mapping of steps") {
+		return stage
+	}
+	forEachMappingPair(node, func(stepKey, stepValue *yaml.Node) {
+		path := key.Value + "." + stepKey.Value
+		if stepKey.Value == keyEnvironments {
+			stage.Environments = p.parseStageEnvironments(stepValue, path)
+			return
+		}
+		stage.Steps = append(stage.Steps, p.parseStep(stepKey, stepValue, path))
+	})
+	return stage
+}
+
+func (p *parser) parseStageEnvironments(node *yaml.Node, path string) []string {
+	if !p.expectKind(node, yaml.SequenceNode, path, "a list of environment names") {
+		return nil
+	}
+	envs := make([]string, 0, len(node.Content))
+	for i, envNode := range node.Content {
+		if p.expectKind(envNode, yaml.ScalarNode, fmt.Sprintf("%s[%d]", path, i), "an environment name") {
+			envs = append(envs, envNode.Value)
+		}
+	}
+	return envs
+}
+
+func (p *parser) parseStep(key, node *yaml.Node, path string) Step {
+	step := Step{
+		Name: key.Value,
+		Line: key.Line,
+	}
+	if !p.expectKind(node, yaml.MappingNode, path, "a mapping with a single step type") {
+		return step
+	}
+	if len(node.Content) != 2 {
+		p.errorf(key, path, "expected exactly one step type, but got %d", len(node.Content)/2)
+	}
+	forEachMappingPair(node, func(typeKey, typeValue *yaml.Node) {
+		if step.Type != "" {
+			return
+		}
+		step.Type = typeKey.Value
+		typePath := path + "." + typeKey.Value
+		if !p.expectKind(typeValue, yaml.MappingNode, typePath, "a mapping of step fields") {
+			return
+		}
+		if fields, ok := decodeAny(typeValue).(map[string]any); ok {
+			step.Fields = fields
+		}
+		p.checkVarReferences(typeValue, typePath)
+	})
+	return step
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func decodeAny(node *yaml.Node) any {
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package builddef
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	buildDef := `
+inputs:
+  - name: message
+    type: string
+    default: hello
+  - name: level
+    type: choice
+    default: info
+    values: [debug, info]
+
+environments:
+  prod:
+    replicas: 3
+  dev:
+    replicas: 1
+
+build:
+  myStep:
+    kaniko:
+      file: Dockerfile
+
+deploy:
+  environments: [dev, prod]
+  myStep:
+    helm:
+      chart: my-chart
+`
+	got, err := Parse(buildDef)
+	require.NoError(t, err)
+
+	want := Definition{
+		Inputs: []Input{
+			{Name: "message", Type: "string", Default: "hello", Line: 3},
+			{Name: "level", Type: "choice", Default: "info", Values: []any{"debug", "info"}, Line: 6},
+		},
+		Environments: []Environment{
+			{Name: "prod", Vars: map[string]any{"replicas": 3}, Line: 12},
+			{Name: "dev", Vars: map[string]any{"replicas": 1}, Line: 14},
+		},
+		Stages: []Stage{
+			{
+				Name: "build",
+				Steps: []Step{
+					{Name: "myStep", Type: "kaniko", Fields: map[string]any{"file": "Dockerfile"}, Line: 18},
+				},
+				Line: 17,
+			},
+			{
+				Name:         "deploy",
+				Environments: []string{"dev", "prod"},
+				Steps: []Step{
+					{Name: "myStep", Type: "helm", Fields: map[string]any{"chart": "my-chart"}, Line: 24},
+				},
+				Line: 22,
+			},
+		},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestParse_empty(t *testing.T) {
+	got, err := Parse("")
+	require.NoError(t, err)
+	assert.Equal(t, Definition{}, got)
+}
+
+func TestParse_invalidYAML(t *testing.T) {
+	_, err := Parse("foo: [")
+	assert.Error(t, err)
+}
@@ -0,0 +1,280 @@
+package builddef
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is an enum of how severe a validation issue is.
+type Severity string
+
+const (
+	// SeverityError means the build definition will produce an invalid build.
+	SeverityError Severity = "error"
+	// SeverityWarning means the build definition may not work as intended,
+	// but will not necessarily produce an invalid build.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a single problem found when validating a build definition.
+type Issue struct {
+	Severity Severity
+	// Path is a dot-separated path to the offending node, such as
+	// "myStage.myStep.container", or empty if it refers to the whole document.
+	Path    string
+	Message string
+	Line    int
+	Column  int
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("line %d, column %d: %s: %s", i.Line, i.Column, i.Severity, i.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s: %s", i.Line, i.Column, i.Severity, i.Path, i.Message)
+}
+
+// HasErrors returns true if any of the issues has the error severity.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// InputTypes is the list of valid input types.
+var InputTypes = []string{"string", "password", "number", "boolean", "choice"}
+
+// StepTypes is the list of step types supported by the Wharf execution
+// engines.
+var StepTypes = []string{
+	"container",
+	"docker",
+	"helm",
+	"helm-package",
+	"kaniko",
+	"kubectl",
+	"nuget-package",
+}
+
+// BuiltinVars is the list of variables that are always available to a build
+// without having to be declared as an input or environment variable.
+var BuiltinVars = []string{
+	"BUILD_REF",
+	"CHART_REPO",
+	"ENVIRONMENT",
+	"GIT_BRANCH",
+	"GIT_COMMIT",
+	"GIT_COMMIT_AUTHOR_DATE",
+	"GIT_COMMIT_COMMITTER_DATE",
+	"GIT_COMMIT_SUBJECT",
+	"GIT_FULLURL",
+	"GIT_SAFEBRANCH",
+	"GIT_TAG",
+	"REG_SECRET",
+	"REG_URL",
+	"REPO_BRANCH",
+	"REPO_GROUP",
+	"REPO_NAME",
+	"WHARF_INSTANCE",
+	"WHARF_PROJECT_ID",
+}
+
+var (
+	varReferenceRegex = regexp.MustCompile(`\$\{\s*([^}\s]+)\s*\}`)
+	yamlErrorRegex    = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+)
+
+type varReference struct {
+	name string
+	path string
+	node *yaml.Node
+}
+
+// Validate parses and validates a YAML-formatted .wharf-ci.yml build
+// definition, returning the parsed definition and all issues found, sorted by
+// line number.
+//
+// Validation covers the YAML syntax, the shape of the document, unknown input
+// and step types, references to undeclared environments, and references to
+// undeclared variables. References to undeclared variables are only reported
+// as warnings, as variables may also be provided by the execution engine.
+func Validate(buildDef string) (Definition, []Issue) {
+	var p parser
+	if err := p.parse(buildDef); err != nil {
+		return p.def, []Issue{newYAMLSyntaxIssue(err)}
+	}
+	p.validateInputs()
+	p.validateStages()
+	p.validateVarReferences()
+	sort.SliceStable(p.issues, func(i, j int) bool {
+		return p.issues[i].Line < p.issues[j].Line
+	})
+	return p.def, p.issues
+}
+
+func newYAMLSyntaxIssue(err error) Issue {
+	issue := Issue{
+		Severity: SeverityError,
+		Message:  err.Error(),
+	}
+	if m := yamlErrorRegex.FindStringSubmatch(err.Error()); m != nil {
+		issue.Line, _ = strconv.Atoi(m[1])
+		issue.Message = m[2]
+	}
+	return issue
+}
+
+func (p *parser) validateInputs() {
+	names := make(map[string]struct{}, len(p.def.Inputs))
+	for i, input := range p.def.Inputs {
+		path := fmt.Sprintf("%s[%d]", keyInputs, i)
+		if input.Name == "" {
+			p.addIssue(SeverityError, input.Line, 0, path, "missing input name")
+		} else if _, ok := names[input.Name]; ok {
+			p.addIssue(SeverityError, input.Line, 0, path, fmt.Sprintf("duplicate input name %q", input.Name))
+		}
+		names[input.Name] = struct{}{}
+		if !containsString(InputTypes, input.Type) {
+			p.addIssue(SeverityError, input.Line, 0, path, fmt.Sprintf(
+				"unknown input type %q, must be one of: %v", input.Type, InputTypes))
+		}
+		if input.Type == "choice" {
+			if len(input.Values) == 0 {
+				p.addIssue(SeverityError, input.Line, 0, path, "input of type \"choice\" must have at least one value")
+			} else if input.Default != nil && !containsAny(input.Values, input.Default) {
+				p.addIssue(SeverityError, input.Line, 0, path, fmt.Sprintf(
+					"default value %q is not one of the input's values", fmt.Sprint(input.Default)))
+			}
+		}
+	}
+}
+
+func (p *parser) validateStages() {
+	envNames := make(map[string]struct{}, len(p.def.Environments))
+	for _, env := range p.def.Environments {
+		envNames[env.Name] = struct{}{}
+	}
+	for _, stage := range p.def.Stages {
+		if len(stage.Steps) == 0 {
+			p.addIssue(SeverityError, stage.Line, 0, stage.Name, "stage must have at least one step")
+		}
+		for _, env := range stage.Environments {
+			if _, ok := envNames[env]; !ok {
+				p.addIssue(SeverityError, stage.Line, 0, stage.Name+"."+keyEnvironments, fmt.Sprintf(
+					"undeclared environment %q", env))
+			}
+		}
+		for _, step := range stage.Steps {
+			if step.Type != "" && !containsString(StepTypes, step.Type) {
+				p.addIssue(SeverityError, step.Line, 0, stage.Name+"."+step.Name, fmt.Sprintf(
+					"unknown step type %q, must be one of: %v", step.Type, StepTypes))
+			}
+		}
+	}
+}
+
+func (p *parser) validateVarReferences() {
+	declared := make(map[string]struct{})
+	for _, name := range BuiltinVars {
+		declared[name] = struct{}{}
+	}
+	for _, input := range p.def.Inputs {
+		declared[input.Name] = struct{}{}
+	}
+	for _, env := range p.def.Environments {
+		for name := range env.Vars {
+			declared[name] = struct{}{}
+		}
+	}
+	for _, ref := range p.varRefs {
+		if _, ok := declared[ref.name]; !ok {
+			p.warnf(ref.node, ref.path, "reference to undeclared variable %q", ref.name)
+		}
+	}
+}
+
+func (p *parser) checkVarReferences(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		for _, m := range varReferenceRegex.FindAllStringSubmatch(node.Value, -1) {
+			p.varRefs = append(p.varRefs, varReference{name: m[1], path: path, node: node})
+		}
+	case yaml.MappingNode:
+		forEachMappingPair(node, func(key, value *yaml.Node) {
+			p.checkVarReferences(value, path+"."+key.Value)
+		})
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			p.checkVarReferences(child, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (p *parser) expectKind(node *yaml.Node, kind yaml.Kind, path, want string) bool {
+	if node.Kind == kind {
+		return true
+	}
+	p.errorf(node, path, "expected %s, but got %s", want, kindName(node))
+	return false
+}
+
+func (p *parser) errorf(node *yaml.Node, path, format string, args ...any) {
+	p.addIssue(SeverityError, node.Line, node.Column, path, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) warnf(node *yaml.Node, path, format string, args ...any) {
+	p.addIssue(SeverityWarning, node.Line, node.Column, path, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) addIssue(severity Severity, line, column int, path, message string) {
+	p.issues = append(p.issues, Issue{
+		Severity: severity,
+		Path:     path,
+		Message:  message,
+		Line:     line,
+		Column:   column,
+	})
+}
+
+func kindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		if isNull(node) {
+			return "null"
+		}
+		return "a scalar value"
+	case yaml.AliasNode:
+		return "an alias"
+	default:
+		return "an unknown node"
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []any, value any) bool {
+	for _, v := range values {
+		if fmt.Sprint(v) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
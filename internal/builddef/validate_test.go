@@ -0,0 +1,91 @@
+package builddef
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		buildDef string
+		want     []Issue
+	}{
+		{
+			name: "valid",
+			buildDef: `
+inputs:
+  - name: image
+    type: string
+environments:
+  dev:
+    tag: latest
+build:
+  environments: [dev]
+  myStep:
+    container:
+      image: ${image}:${tag}
+      cmds:
+        - echo ${GIT_BRANCH}
+`,
+			want: nil,
+		},
+		{
+			name:     "syntax error",
+			buildDef: "build:\n  myStep: [\n",
+			want: []Issue{
+				{Severity: SeverityError, Line: 2, Message: "did not find expected node content"},
+			},
+		},
+		{
+			name: "unknown types",
+			buildDef: `
+inputs:
+  - name: foo
+    type: bool
+build:
+  myStep:
+    dockerr:
+      file: Dockerfile
+`,
+			want: []Issue{
+				{Severity: SeverityError, Path: "inputs[0]", Line: 3, Message: `unknown input type "bool", must be one of: [string password number boolean choice]`},
+				{Severity: SeverityError, Path: "build.myStep", Line: 6, Message: `unknown step type "dockerr", must be one of: [container docker helm helm-package kaniko kubectl nuget-package]`},
+			},
+		},
+		{
+			name: "undeclared references",
+			buildDef: `
+deploy:
+  environments: [prod]
+  myStep:
+    helm:
+      chart: ${chart}
+`,
+			want: []Issue{
+				{Severity: SeverityError, Path: "deploy.environments", Line: 2, Message: `undeclared environment "prod"`},
+				{Severity: SeverityWarning, Path: "deploy.myStep.helm.chart", Line: 6, Column: 14, Message: `reference to undeclared variable "chart"`},
+			},
+		},
+		{
+			name: "wrong shapes",
+			buildDef: `
+inputs:
+  name: foo
+build:
+  myStep: echo
+`,
+			want: []Issue{
+				{Severity: SeverityError, Path: "inputs", Line: 3, Column: 3, Message: "expected a list of inputs, but got a mapping"},
+				{Severity: SeverityError, Path: "build.myStep", Line: 5, Column: 11, Message: "expected a mapping with a single step type, but got a scalar value"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, got := Validate(tc.buildDef)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
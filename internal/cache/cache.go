@@ -0,0 +1,115 @@
+// Package cache holds values in memory for a limited time, such as database
+// objects that are read far more often than they are written.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory cache, where each value expires after the
+// time-to-live (TTL) of the cache. A nil *Memory is a valid cache that never
+// holds any values, which is used when caching is disabled.
+type Memory[K comparable, V any] struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu          sync.Mutex
+	entries     map[K]entry[V]
+	lastRemoved time.Time
+}
+
+type entry[V any] struct {
+	value     V
+	expiresOn time.Time
+}
+
+// New creates a new in-memory cache where values expire after the given
+// time-to-live.
+func New[K comparable, V any](ttl time.Duration) *Memory[K, V] {
+	return &Memory[K, V]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[K]entry[V]),
+	}
+}
+
+// Get returns the cached value of the key, or false if the key is not cached
+// or the value has expired.
+//
+// The returned value is shared with other callers, and must not be modified.
+func (m *Memory[K, V]) Get(key K) (V, bool) {
+	var zero V
+	if m == nil {
+		return zero, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return zero, false
+	}
+	if !m.now().Before(e.expiresOn) {
+		delete(m.entries, key)
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set caches the value of the key, replacing any previously cached value.
+func (m *Memory[K, V]) Set(key K, value V) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.removeExpired(now)
+	m.entries[key] = entry[V]{value: value, expiresOn: now.Add(m.ttl)}
+}
+
+// Delete removes the cached value of the key, if any.
+func (m *Memory[K, V]) Delete(key K) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+// Clear removes all cached values.
+func (m *Memory[K, V]) Clear() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = make(map[K]entry[V])
+}
+
+// Len returns the number of cached values, including expired values that
+// have not yet been removed.
+func (m *Memory[K, V]) Len() int {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// removeExpired removes all expired values, at most once per TTL, so that
+// values that are never read again do not stay in memory forever. The lock
+// must be held.
+func (m *Memory[K, V]) removeExpired(now time.Time) {
+	if now.Sub(m.lastRemoved) < m.ttl {
+		return
+	}
+	m.lastRemoved = now
+	for key, e := range m.entries {
+		if !now.Before(e.expiresOn) {
+			delete(m.entries, key)
+		}
+	}
+}
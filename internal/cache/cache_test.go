@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_expires(t *testing.T) {
+	now := time.Date(2022, 10, 16, 12, 0, 0, 0, time.UTC)
+	c := New[uint, string](time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(1, "foo")
+	got, ok := c.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, "foo", got)
+
+	now = now.Add(time.Minute)
+	_, ok = c.Get(1)
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestMemory_removesExpiredOnSet(t *testing.T) {
+	now := time.Date(2022, 10, 16, 12, 0, 0, 0, time.UTC)
+	c := New[uint, string](time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(1, "foo")
+	c.Set(2, "bar")
+	now = now.Add(2 * time.Minute)
+	c.Set(3, "moo")
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestMemory_deleteAndClear(t *testing.T) {
+	c := New[uint, string](time.Minute)
+	c.Set(1, "foo")
+	c.Set(2, "bar")
+
+	c.Delete(1)
+	_, ok := c.Get(1)
+	assert.False(t, ok)
+	_, ok = c.Get(2)
+	assert.True(t, ok)
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestMemory_nil(t *testing.T) {
+	var c *Memory[uint, string]
+	c.Set(1, "foo")
+	_, ok := c.Get(1)
+	assert.False(t, ok)
+	c.Delete(1)
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+}
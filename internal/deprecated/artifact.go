@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
@@ -59,5 +60,5 @@ func (m artifactModule) getBuildArtifactListHandler(c *gin.Context) {
 		resArtifacts[i] = modelconv.DBArtifactToResponse(dbArtifact)
 	}
 
-	c.JSON(http.StatusOK, resArtifacts)
+	ginrender.Response(c, http.StatusOK, resArtifacts)
 }
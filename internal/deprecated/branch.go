@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
@@ -14,7 +15,8 @@ import (
 
 // BranchModule holds deprecated endpoint handlers for /branch
 type BranchModule struct {
-	Database *gorm.DB
+	Database   *gorm.DB
+	InstanceID InstanceIDFunc
 }
 
 // Register adds all deprecated endpoints to a given Gin router group.
@@ -38,6 +40,7 @@ func (m BranchModule) Register(g *gin.RouterGroup) {
 // @summary Get a branch by ID
 // @description This endpoint has not been implemented!
 // @description Deprecated since v4.3.0. Planned for removal in v6.0.0.
+// @description Use `GET /project/{projectId}/branch/{branchName}` instead.
 // @description Added in v0.2.0.
 // @tags branch
 // @param branchId path uint true "branch ID" minimum(0)
@@ -89,6 +92,7 @@ type Branch struct {
 // @success 201 {object} response.Branch "Added new branch"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
 // @failure 502 {object} problem.Response "Database is unreachable"
 // @router /branch [post]
 func (m BranchModule) createBranchHandler(c *gin.Context) {
@@ -98,6 +102,9 @@ func (m BranchModule) createBranchHandler(c *gin.Context) {
 			"One or more parameters failed to parse when reading the request body for branch object to update.")
 		return
 	}
+	if !m.validateProjectsInstance(c, reqBranch.ProjectID) {
+		return
+	}
 
 	dbBranch := database.Branch{
 		ProjectID: reqBranch.ProjectID,
@@ -118,7 +125,7 @@ func (m BranchModule) createBranchHandler(c *gin.Context) {
 				dbBranch.Name, dbBranch.TokenID, dbBranch.ProjectID))
 			return
 		}
-		c.JSON(http.StatusCreated, modelconv.DBBranchToResponse(dbBranch))
+		ginrender.Response(c, http.StatusCreated, modelconv.DBBranchToResponse(dbBranch))
 		return
 	} else if err != nil {
 		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
@@ -129,7 +136,7 @@ func (m BranchModule) createBranchHandler(c *gin.Context) {
 
 	dbExistingBranch.Default = reqBranch.Default
 	m.Database.Save(dbExistingBranch)
-	c.JSON(http.StatusOK, modelconv.DBBranchToResponse(dbExistingBranch))
+	ginrender.Response(c, http.StatusOK, modelconv.DBBranchToResponse(dbExistingBranch))
 }
 
 // updateProjectBranchListHandler godoc
@@ -147,6 +154,7 @@ func (m BranchModule) createBranchHandler(c *gin.Context) {
 // @success 200 {object} []response.Branch "Updated branches"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
 // @failure 502 {object} problem.Response "Database is unreachable"
 // @router /branches [put]
 func (m BranchModule) updateProjectBranchListHandler(c *gin.Context) {
@@ -156,13 +164,54 @@ func (m BranchModule) updateProjectBranchListHandler(c *gin.Context) {
 			"One or more parameters failed to parse when reading the request body for branch object array to update.")
 		return
 	}
+	projectIDs := make([]uint, len(reqBranches))
+	for i, reqBranch := range reqBranches {
+		projectIDs[i] = reqBranch.ProjectID
+	}
+	if !m.validateProjectsInstance(c, projectIDs...) {
+		return
+	}
 	dbBranches, err := m.replaceBranchList(reqBranches)
 	if err != nil {
 		ginutil.WriteDBWriteError(c, err, "Failed to update branches in database.")
 		return
 	}
 	resBranches := modelconv.DBBranchesToResponses(dbBranches)
-	c.JSON(http.StatusOK, resBranches)
+	ginrender.Response(c, http.StatusOK, resBranches)
+}
+
+// validateProjectsInstance writes a problem response and returns false if any
+// of the projects does not exist in the request's instance. Does nothing if
+// projects are not scoped by instance ID.
+func (m BranchModule) validateProjectsInstance(c *gin.Context, projectIDs ...uint) bool {
+	if m.InstanceID == nil {
+		return true
+	}
+	if _, ok := m.InstanceID(c); !ok {
+		return true
+	}
+	for _, projectID := range projectIDs {
+		var count int64
+		err := m.Database.
+			Model(&database.Project{}).
+			Where(database.ProjectColumns.ProjectID+" = ?", projectID).
+			Scopes(whereProjectInstanceScope(c, m.InstanceID)).
+			Count(&count).
+			Error
+		if err != nil {
+			ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+				"Failed fetching project with ID %d from database.",
+				projectID))
+			return false
+		}
+		if count == 0 {
+			ginutil.WriteDBNotFound(c, fmt.Sprintf(
+				"Project with ID %d was not found in the database.",
+				projectID))
+			return false
+		}
+	}
+	return true
 }
 
 func (m BranchModule) replaceBranchList(reqBranches []Branch) ([]database.Branch, error) {
@@ -193,7 +242,7 @@ func (m BranchModule) replaceBranchList(reqBranches []Branch) ([]database.Branch
 
 			branchNamesSet[reqBranch.Name] = struct{}{}
 			var count int64
-			err := m.Database.
+			err := tx.
 				Model(&database.Branch{}).
 				Where(&database.Branch{
 					ProjectID: reqBranch.ProjectID,
@@ -241,7 +290,9 @@ func (m BranchModule) replaceBranchList(reqBranches []Branch) ([]database.Branch
 			}
 		}
 
-		return m.Database.Find(&dbNewBranches).Error
+		return tx.
+			Where(&database.Branch{ProjectID: projectID}, database.BranchFields.ProjectID).
+			Find(&dbNewBranches).Error
 	})
 
 	if err != nil {
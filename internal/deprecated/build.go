@@ -7,10 +7,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
 	"gorm.io/gorm"
 )
 
@@ -102,7 +104,7 @@ func (m BuildModule) updateBuildHandler(c *gin.Context) {
 	}
 
 	resBuild := modelconv.DBBuildToResponse(dbBuild, nilEngineLookup)
-	c.JSON(http.StatusOK, resBuild)
+	ginrender.Response(c, http.StatusOK, resBuild)
 }
 
 func (m BuildModule) updateBuildStatus(buildID uint, statusID database.BuildStatus) (database.Build, error) {
@@ -129,7 +131,21 @@ func (m BuildModule) updateBuildStatus(buildID uint, statusID database.BuildStat
 
 	message.Build = dbBuild
 
-	if err := m.Database.Save(&dbBuild).Error; err != nil {
+	err = m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&dbBuild).Error; err != nil {
+			return err
+		}
+		if message.StatusBefore == statusID {
+			return nil
+		}
+		return tx.Create(&database.BuildStatusTransition{
+			BuildID:          buildID,
+			PreviousStatusID: null.IntFrom(int64(message.StatusBefore)),
+			StatusID:         statusID,
+			Timestamp:        time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
 		return database.Build{}, err
 	}
 
@@ -156,5 +172,8 @@ func setStatusDate(build *database.Build, statusID database.BuildStatus) {
 		build.StartedOn.SetValid(now)
 	case database.BuildCompleted, database.BuildFailed:
 		build.CompletedOn.SetValid(now)
+		if build.StartedOn.Valid {
+			build.Duration.SetValid(now.Sub(build.StartedOn.Time).Milliseconds())
+		}
 	}
 }
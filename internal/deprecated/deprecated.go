@@ -1,7 +1,32 @@
 package deprecated
 
 import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-core/pkg/logger"
+	"gorm.io/gorm"
 )
 
 var log = logger.NewScoped("WHARF")
+
+// InstanceIDFunc returns the instance ID of the request, or false if projects
+// are not scoped by instance ID.
+type InstanceIDFunc func(c *gin.Context) (string, bool)
+
+// whereProjectInstanceScope filters projects on the request's instance ID, if
+// any. The InstanceIDFunc may be nil.
+func whereProjectInstanceScope(c *gin.Context, getInstanceID InstanceIDFunc) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if getInstanceID == nil {
+			return db
+		}
+		instanceID, ok := getInstanceID(c)
+		if !ok {
+			return db
+		}
+		return db.Where(fmt.Sprintf("%s.%s = ?",
+			database.ProjectTable, database.ProjectColumns.InstanceID), instanceID)
+	}
+}
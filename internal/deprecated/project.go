@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
@@ -47,7 +48,8 @@ type ProjectSearch struct {
 
 // ProjectModule holds deprecated endpoint handlers for /project
 type ProjectModule struct {
-	Database *gorm.DB
+	Database   *gorm.DB
+	InstanceID InstanceIDFunc
 }
 
 // Register adds all deprecated endpoints to a given Gin router group.
@@ -80,6 +82,7 @@ func (m ProjectModule) Register(g *gin.RouterGroup) {
 func (m ProjectModule) getProjectListHandler(c *gin.Context) {
 	var dbProjects []database.Project
 	err := m.databaseProjectPreloaded().
+		Scopes(whereProjectInstanceScope(c, m.InstanceID)).
 		Find(&dbProjects).
 		Error
 	if err != nil {
@@ -87,7 +90,7 @@ func (m ProjectModule) getProjectListHandler(c *gin.Context) {
 		return
 	}
 	resProjects := modelconv.DBProjectsToResponses(dbProjects)
-	c.JSON(http.StatusOK, resProjects)
+	ginrender.Response(c, http.StatusOK, resProjects)
 }
 
 // searchProjectListHandler godoc
@@ -122,6 +125,7 @@ func (m ProjectModule) searchProjectListHandler(c *gin.Context) {
 			BuildDefinition: reqProjectSearch.BuildDefinition,
 			GitURL:          reqProjectSearch.GitURL,
 		}).
+		Scopes(whereProjectInstanceScope(c, m.InstanceID)).
 		Preload(database.ProjectFields.Provider).
 		Find(&dbProjects).
 		Error
@@ -130,7 +134,7 @@ func (m ProjectModule) searchProjectListHandler(c *gin.Context) {
 		return
 	}
 	resProjects := modelconv.DBProjectsToResponses(dbProjects)
-	c.JSON(http.StatusOK, resProjects)
+	ginrender.Response(c, http.StatusOK, resProjects)
 }
 
 var buildJSONToColumns = map[string]database.SafeSQLName{
@@ -211,7 +215,7 @@ func (m ProjectModule) getProjectBuildListHandler(c *gin.Context) {
 		Builds:     modelconv.DBBuildsToResponses(dbBuilds, nilEngineLookup),
 		TotalCount: count,
 	}
-	c.JSON(http.StatusOK, resPaginated)
+	ginrender.Response(c, http.StatusOK, resPaginated)
 }
 
 // updateProjectHandler godoc
@@ -245,7 +249,7 @@ func (m ProjectModule) updateProjectHandler(c *gin.Context) {
 
 	var dbExistingProject database.Project
 	if reqProjectUpdate.ProjectID != 0 {
-		dbExistingProject, err = m.findProjectByID(reqProjectUpdate.ProjectID)
+		dbExistingProject, err = m.findProjectByID(c, reqProjectUpdate.ProjectID)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			ginutil.WriteDBNotFound(c, fmt.Sprintf(
 				"Project with ID %d was not found in the database.",
@@ -263,6 +267,7 @@ func (m ProjectModule) updateProjectHandler(c *gin.Context) {
 				Name:      reqProjectUpdate.Name,
 				GroupName: reqProjectUpdate.GroupName,
 			}, database.ProjectFields.Name, database.ProjectFields.GroupName).
+			Scopes(whereProjectInstanceScope(c, m.InstanceID)).
 			First(&dbExistingProject).
 			Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -277,13 +282,16 @@ func (m ProjectModule) updateProjectHandler(c *gin.Context) {
 				BuildDefinition: reqProjectUpdate.BuildDefinition,
 				GitURL:          reqProjectUpdate.GitURL,
 			}
+			if m.InstanceID != nil {
+				dbNewProject.InstanceID, _ = m.InstanceID(c)
+			}
 			if err := m.Database.Create(&dbNewProject).Error; err != nil {
 				ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 					"Failed creating new project with group %q, token ID %d, and name %q in database.",
 					reqProjectUpdate.GroupName, reqProjectUpdate.TokenID, reqProjectUpdate.Name))
 			} else {
 				resProject := modelconv.DBProjectToResponse(dbNewProject)
-				c.JSON(http.StatusCreated, resProject)
+				ginrender.Response(c, http.StatusCreated, resProject)
 			}
 			return
 		} else if err != nil {
@@ -311,7 +319,7 @@ func (m ProjectModule) updateProjectHandler(c *gin.Context) {
 	}
 
 	resProject := modelconv.DBProjectToResponse(dbExistingProject)
-	c.JSON(http.StatusOK, resProject)
+	ginrender.Response(c, http.StatusOK, resProject)
 }
 
 var defaultGetBuildsOrderBy = orderby.Column{Name: database.BuildColumns.BuildID, Direction: orderby.Desc}
@@ -343,10 +351,11 @@ func (m ProjectModule) getBuildsCount(projectID uint) (int64, error) {
 	return count, nil
 }
 
-func (m ProjectModule) findProjectByID(id uint) (database.Project, error) {
+func (m ProjectModule) findProjectByID(c *gin.Context, id uint) (database.Project, error) {
 	var dbProject database.Project
 	err := m.databaseProjectPreloaded().
 		Where(&database.Project{ProjectID: id}).
+		Scopes(whereProjectInstanceScope(c, m.InstanceID)).
 		First(&dbProject).
 		Error
 	return dbProject, err
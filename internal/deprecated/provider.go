@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
@@ -62,7 +63,7 @@ func (m ProviderModule) getProviderListHandler(c *gin.Context) {
 		return
 	}
 	resProviders := modelconv.DBProvidersToResponses(dbProviders)
-	c.JSON(http.StatusOK, resProviders)
+	ginrender.Response(c, http.StatusOK, resProviders)
 }
 
 // searchProviderListHandler godoc
@@ -135,7 +136,7 @@ func (m ProviderModule) searchProviderListHandler(c *gin.Context) {
 	}
 
 	resProviders := modelconv.DBProvidersToResponses(dbProviders)
-	c.JSON(http.StatusOK, resProviders)
+	ginrender.Response(c, http.StatusOK, resProviders)
 }
 
 // updateProviderHandler godoc
@@ -179,7 +180,7 @@ func (m ProviderModule) updateProviderHandler(c *gin.Context) {
 		return
 	}
 	resProvider := modelconv.DBProviderToResponse(dbProvider)
-	c.JSON(http.StatusOK, resProvider)
+	ginrender.Response(c, http.StatusOK, resProvider)
 }
 
 func writeInvalidProviderNameProblem(c *gin.Context, actual request.ProviderName) {
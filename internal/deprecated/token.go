@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
@@ -62,7 +63,7 @@ func (m TokenModule) getTokenListHandler(c *gin.Context) {
 	}
 
 	resTokens := modelconv.DBTokensToResponses(dbTokens)
-	c.JSON(http.StatusOK, resTokens)
+	ginrender.Response(c, http.StatusOK, resTokens)
 }
 
 // searchTokenListHandler godoc
@@ -107,7 +108,7 @@ func (m TokenModule) searchTokenListHandler(c *gin.Context) {
 	}
 
 	resTokens := modelconv.DBTokensToResponses(dbTokens)
-	c.JSON(http.StatusOK, resTokens)
+	ginrender.Response(c, http.StatusOK, resTokens)
 }
 
 // updateTokenHandler godoc
@@ -171,5 +172,5 @@ func (m TokenModule) updateTokenHandler(c *gin.Context) {
 	}
 
 	resToken := modelconv.DBTokenToResponse(dbToken)
-	c.JSON(http.StatusOK, resToken)
+	ginrender.Response(c, http.StatusOK, resToken)
 }
@@ -0,0 +1,15 @@
+// Package eventbus publishes messages to message buses, such as NATS or Kafka,
+// without depending on their client libraries. Only the small subset of each
+// protocol that is needed to publish messages is implemented.
+package eventbus
+
+// Publisher publishes messages to topics of a message bus. Implementations are
+// safe for concurrent use.
+type Publisher interface {
+	// Publish publishes the payload to the topic, and waits for the message
+	// bus to acknowledge it. The key is used by message buses that partition
+	// topics, such as Kafka, to keep messages of the same key in order.
+	Publish(topic, key string, payload []byte) error
+	// Close closes any open connections.
+	Close() error
+}
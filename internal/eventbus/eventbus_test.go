@@ -0,0 +1,159 @@
+package eventbus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type natsMessage struct {
+	subject string
+	payload string
+}
+
+// serveFakeNATS accepts connections and answers the publisher's subset of the
+// NATS protocol, sending each published message on the channel. Connections
+// are closed after closeAfter messages, if positive.
+func serveFakeNATS(t *testing.T, closeAfter int) (string, <-chan natsMessage, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan natsMessage, 10)
+	connects := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeNATSConn(conn, closeAfter, messages, connects)
+		}
+	}()
+	return "nats://" + listener.Addr().String(), messages, connects
+}
+
+func serveFakeNATSConn(conn net.Conn, closeAfter int, messages chan<- natsMessage, connects chan<- string) {
+	defer conn.Close()
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	reader := bufio.NewReader(conn)
+	published := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+			if closeAfter > 0 && published >= closeAfter {
+				return
+			}
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if fields[1] == "forbidden" {
+				fmt.Fprint(conn, "-ERR 'Permissions Violation for Publish to forbidden'\r\n")
+				continue
+			}
+			messages <- natsMessage{subject: fields[1], payload: string(payload[:size])}
+			published++
+		}
+	}
+}
+
+func TestNATSPublisher(t *testing.T) {
+	addr, messages, connects := serveFakeNATS(t, 0)
+	u := strings.Replace(addr, "nats://", "nats://alice:secret@", 1)
+	p, err := NewNATSPublisher(NATSConfig{URL: u, Timeout: time.Second})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Publish("wharf.build.status", "1", []byte(`{"buildId":1}`)))
+	require.NoError(t, p.Publish("wharf.build.status", "2", []byte(`{"buildId":2}`)))
+	assert.Equal(t, natsMessage{"wharf.build.status", `{"buildId":1}`}, <-messages)
+	assert.Equal(t, natsMessage{"wharf.build.status", `{"buildId":2}`}, <-messages)
+
+	var opts map[string]any
+	require.NoError(t, json.Unmarshal([]byte(<-connects), &opts))
+	assert.Equal(t, "alice", opts["user"])
+	assert.Equal(t, "secret", opts["pass"])
+	assert.Empty(t, connects, "must reuse the connection")
+
+	err = p.Publish("forbidden", "", []byte(`{}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Permissions Violation")
+	err = p.Publish("has space", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid NATS subject")
+}
+
+func TestNATSPublisher_reconnects(t *testing.T) {
+	addr, messages, connects := serveFakeNATS(t, 1)
+	p, err := NewNATSPublisher(NATSConfig{URL: addr, Timeout: time.Second})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Publish("wharf.project.created", "", []byte("a")))
+	<-messages
+	// Gives the fake server time to close the connection.
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, p.Publish("wharf.project.created", "", []byte("b")))
+	assert.Equal(t, natsMessage{"wharf.project.created", "b"}, <-messages)
+	assert.Len(t, connects, 2)
+}
+
+func TestNewNATSPublisher_invalidURL(t *testing.T) {
+	_, err := NewNATSPublisher(NATSConfig{URL: "http://localhost:4222"})
+	assert.Error(t, err)
+	_, err = NewNATSPublisher(NATSConfig{URL: "nats://"})
+	assert.Error(t, err)
+}
+
+func TestKafkaRESTPublisher(t *testing.T) {
+	var gotPath, gotContentType, gotBody string
+	respBody := `{"offsets":[{"partition":0,"offset":1,"error_code":null,"error":null}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotContentType, gotBody = r.URL.Path, r.Header.Get("Content-Type"), string(body)
+		w.Header().Set("Content-Type", kafkaRESTAcceptContentType)
+		io.WriteString(w, respBody)
+	}))
+	defer server.Close()
+
+	p, err := NewKafkaRESTPublisher(server.URL+"/", server.Client(), false)
+	require.NoError(t, err)
+	require.NoError(t, p.Publish("wharf.build.status", "5", []byte(`{"buildId":5}`)))
+	assert.Equal(t, "/topics/wharf.build.status", gotPath)
+	assert.Equal(t, kafkaRESTJSONContentType, gotContentType)
+	assert.JSONEq(t, `{"records":[{"key":"5","value":{"buildId":5}}]}`, gotBody)
+
+	p, err = NewKafkaRESTPublisher(server.URL, server.Client(), true)
+	require.NoError(t, err)
+	require.NoError(t, p.Publish("wharf.build.status", "5", []byte{0x08, 0x05}))
+	assert.Equal(t, kafkaRESTBinaryContentType, gotContentType)
+	assert.JSONEq(t, `{"records":[{"key":"NQ==","value":"CAU="}]}`, gotBody)
+
+	respBody = `{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"Kafka error"}]}`
+	err = p.Publish("wharf.build.status", "", []byte{})
+	assert.EqualError(t, err, "produce record: error code 50002: Kafka error")
+}
@@ -0,0 +1,119 @@
+package eventbus
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Content types of the Kafka REST Proxy v2 API.
+const (
+	kafkaRESTJSONContentType   = "application/vnd.kafka.json.v2+json"
+	kafkaRESTBinaryContentType = "application/vnd.kafka.binary.v2+json"
+	kafkaRESTAcceptContentType = "application/vnd.kafka.v2+json"
+)
+
+// KafkaRESTPublisher publishes messages to Kafka topics via the v2 API of a
+// Kafka REST Proxy, such as the Confluent REST Proxy, instead of via the Kafka
+// protocol.
+type KafkaRESTPublisher struct {
+	baseURL string
+	client  *http.Client
+	binary  bool
+}
+
+// NewKafkaRESTPublisher creates a new Kafka REST Proxy publisher. If binary is
+// false, the payloads must be JSON, and are embedded as JSON in the records.
+// Otherwise the payloads may be any bytes, and are base64-encoded.
+func NewKafkaRESTPublisher(baseURL string, client *http.Client, binary bool) (*KafkaRESTPublisher, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse Kafka REST Proxy URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("Kafka REST Proxy URL must be an absolute HTTP or HTTPS URL")
+	}
+	return &KafkaRESTPublisher{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+		binary:  binary,
+	}, nil
+}
+
+type kafkaRESTRecord struct {
+	Key   any `json:"key,omitempty"`
+	Value any `json:"value"`
+}
+
+type kafkaRESTProduceRequest struct {
+	Records []kafkaRESTRecord `json:"records"`
+}
+
+type kafkaRESTProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish produces a single record with the key and payload to the topic.
+func (p *KafkaRESTPublisher) Publish(topic, key string, payload []byte) error {
+	record := kafkaRESTRecord{}
+	contentType := kafkaRESTJSONContentType
+	if p.binary {
+		contentType = kafkaRESTBinaryContentType
+		record.Value = base64.StdEncoding.EncodeToString(payload)
+		if key != "" {
+			record.Key = base64.StdEncoding.EncodeToString([]byte(key))
+		}
+	} else {
+		record.Value = json.RawMessage(payload)
+		if key != "" {
+			record.Key = key
+		}
+	}
+	body, err := json.Marshal(kafkaRESTProduceRequest{Records: []kafkaRESTRecord{record}})
+	if err != nil {
+		return fmt.Errorf("marshal records: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, p.baseURL+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", kafkaRESTAcceptContentType)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+	var produceResp kafkaRESTProduceResponse
+	if err := json.Unmarshal(respBody, &produceResp); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	for _, offset := range produceResp.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("produce record: error code %d: %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
+
+// Close does nothing, as no connections are kept open other than the ones
+// pooled by the HTTP client.
+func (p *KafkaRESTPublisher) Close() error {
+	return nil
+}
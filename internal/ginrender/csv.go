@@ -0,0 +1,62 @@
+package ginrender
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MIMECSV is the content type of CSV responses.
+const MIMECSV = "text/csv"
+
+// IsCSVRequested returns true if the request asks for a CSV response, either
+// via the "format=csv" query parameter, or via the Accept header, such as
+// "Accept: text/csv". JSON is still preferred when the Accept header allows
+// both, such as "Accept: */*".
+func IsCSVRequested(c *gin.Context) bool {
+	if format, ok := c.GetQuery("format"); ok {
+		return strings.EqualFold(format, "csv")
+	}
+	return c.NegotiateFormat(gin.MIMEJSON, MIMECSV) == MIMECSV
+}
+
+// CSVWriter streams rows of a CSV response, flushing them to the client in
+// batches so that large lists are never held in memory in their entirety.
+type CSVWriter struct {
+	c *gin.Context
+	w *csv.Writer
+}
+
+// NewCSVWriter writes the response headers and the header row of a CSV
+// response. The response is sent as an attachment with the given file name,
+// so that browsers download it instead of displaying it.
+//
+// The response status is always 200 OK, as it is sent before any rows are
+// written, so errors must be checked before calling NewCSVWriter.
+func NewCSVWriter(c *gin.Context, filename string, header []string) *CSVWriter {
+	c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	w := &CSVWriter{c: c, w: csv.NewWriter(c.Writer)}
+	w.Write(header)
+	return w
+}
+
+// Write adds a row to the CSV response. The rows are buffered until Flush is
+// called.
+func (w *CSVWriter) Write(record []string) error {
+	return w.w.Write(record)
+}
+
+// Flush sends all buffered rows to the client.
+func (w *CSVWriter) Flush() error {
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+	return nil
+}
@@ -0,0 +1,82 @@
+package ginrender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCSVRequested(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testCases := []struct {
+		name   string
+		url    string
+		accept string
+		want   bool
+	}{
+		{
+			name: "JSON by default",
+			url:  "/",
+			want: false,
+		},
+		{
+			name:   "JSON for any type",
+			url:    "/",
+			accept: "*/*",
+			want:   false,
+		},
+		{
+			name:   "CSV via Accept header",
+			url:    "/",
+			accept: "text/csv",
+			want:   true,
+		},
+		{
+			name:   "CSV preferred via Accept header",
+			url:    "/",
+			accept: "text/csv, application/json;q=0.9",
+			want:   true,
+		},
+		{
+			name: "CSV via format query",
+			url:  "/?format=CSV",
+			want: true,
+		},
+		{
+			name:   "format query overrides Accept header",
+			url:    "/?format=json",
+			accept: "text/csv",
+			want:   false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.accept != "" {
+				c.Request.Header.Set("Accept", tc.accept)
+			}
+			assert.Equal(t, tc.want, IsCSVRequested(c))
+		})
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	csvWriter := NewCSVWriter(c, "projects.csv", []string{"projectId", "description"})
+	require.NoError(t, csvWriter.Write([]string{"1", `quoted "description", with comma`}))
+	require.NoError(t, csvWriter.Flush())
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="projects.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "projectId,description\n1,\"quoted \"\"description\"\", with comma\"\n", w.Body.String())
+}
@@ -0,0 +1,76 @@
+// Package ginrender contains the shared response writer used by all HTTP
+// handlers, so that all endpoints honor the same output formatting options.
+package ginrender
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	ua "github.com/mileusna/useragent"
+)
+
+// MIMEYAML is the content type of YAML responses.
+const MIMEYAML = "application/yaml"
+
+// offeredFormats are the response formats supported by Response, where the
+// first one is used when the request does not specify any preference.
+var offeredFormats = []string{
+	gin.MIMEJSON,
+	MIMEYAML,
+	gin.MIMEYAML,
+	"text/yaml",
+}
+
+// Response writes the response object in the format negotiated from the
+// request's Accept header. JSON is used by default, and YAML is used if
+// requested, such as via "Accept: application/yaml".
+//
+// The JSON output is indented when the "pretty" query parameter is set, or
+// when the request is sent by curl.
+//
+// Field names are the same in both formats, as the YAML output is converted
+// from the JSON output and therefore uses the same struct tags.
+func Response(c *gin.Context, code int, obj any) {
+	switch c.NegotiateFormat(offeredFormats...) {
+	case MIMEYAML, gin.MIMEYAML, "text/yaml":
+		writeYAML(c, code, obj)
+	default:
+		if shouldIndentJSONResponse(c) {
+			c.IndentedJSON(code, obj)
+		} else {
+			c.JSON(code, obj)
+		}
+	}
+}
+
+func writeYAML(c *gin.Context, code int, obj any) {
+	jsonBytes, err := json.Marshal(obj)
+	if err != nil {
+		writeSerializeError(c, err)
+		return
+	}
+	yamlBytes, err := yaml.JSONToYAML(jsonBytes)
+	if err != nil {
+		writeSerializeError(c, err)
+		return
+	}
+	c.Data(code, MIMEYAML+"; charset=utf-8", yamlBytes)
+}
+
+func writeSerializeError(c *gin.Context, err error) {
+	ginutil.WriteProblemError(c, err, problems.InternalServerError.New(
+		"Failed to serialize the response as YAML."))
+}
+
+func shouldIndentJSONResponse(c *gin.Context) bool {
+	prettyQuery, ok := c.GetQuery("pretty")
+	if ok {
+		return prettyQuery == "" || strings.EqualFold(prettyQuery, "true")
+	}
+	agent := ua.Parse(c.Request.UserAgent())
+	return agent.Name == "curl"
+}
@@ -0,0 +1,94 @@
+package ginrender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testResponse struct {
+	ProjectID uint   `json:"projectId"`
+	GitURL    string `json:"gitUrl"`
+}
+
+func TestResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testCases := []struct {
+		name            string
+		url             string
+		accept          string
+		userAgent       string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "JSON by default",
+			url:             "/",
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"projectId":1,"gitUrl":"git@example.com"}`,
+		},
+		{
+			name:            "pretty JSON",
+			url:             "/?pretty",
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        "{\n    \"projectId\": 1,\n    \"gitUrl\": \"git@example.com\"\n}",
+		},
+		{
+			name:            "pretty JSON for curl",
+			url:             "/",
+			userAgent:       "curl/7.79.1",
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        "{\n    \"projectId\": 1,\n    \"gitUrl\": \"git@example.com\"\n}",
+		},
+		{
+			name:            "not pretty JSON for curl if disabled",
+			url:             "/?pretty=false",
+			userAgent:       "curl/7.79.1",
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"projectId":1,"gitUrl":"git@example.com"}`,
+		},
+		{
+			name:            "JSON for any type",
+			url:             "/",
+			accept:          "*/*",
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"projectId":1,"gitUrl":"git@example.com"}`,
+		},
+		{
+			name:            "YAML with same field names",
+			url:             "/",
+			accept:          "application/yaml",
+			wantContentType: "application/yaml; charset=utf-8",
+			wantBody:        "gitUrl: git@example.com\nprojectId: 1\n",
+		},
+		{
+			name:            "YAML using legacy content type",
+			url:             "/",
+			accept:          "application/x-yaml",
+			wantContentType: "application/yaml; charset=utf-8",
+			wantBody:        "gitUrl: git@example.com\nprojectId: 1\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.accept != "" {
+				c.Request.Header.Set("Accept", tc.accept)
+			}
+			if tc.userAgent != "" {
+				c.Request.Header.Set("User-Agent", tc.userAgent)
+			}
+
+			Response(c, http.StatusOK, testResponse{ProjectID: 1, GitURL: "git@example.com"})
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package metrics contains a minimal registry of metrics about the wharf-api's
+// internal state, such as queue sizes, that are written in the Prometheus text
+// exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Type is the kind of a metric.
+type Type string
+
+const (
+	// TypeCounter is a metric whose value only ever increases.
+	TypeCounter Type = "counter"
+	// TypeGauge is a metric whose value may go up and down.
+	TypeGauge Type = "gauge"
+)
+
+// Counter is a counter metric that is safe for concurrent use. The zero value
+// is ready to use.
+type Counter struct {
+	value uint64
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// Add increments the counter by n.
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.value, n)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+type metric struct {
+	name  string
+	help  string
+	typ   Type
+	value func() float64
+}
+
+// Registry is a set of metrics. The zero value is ready to use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// AddCounter registers a counter. Panics if a metric of the same name is
+// already registered.
+func (r *Registry) AddCounter(name, help string, c *Counter) {
+	r.add(metric{name, help, TypeCounter, func() float64 {
+		return float64(c.Value())
+	}})
+}
+
+// AddGaugeFunc registers a gauge whose value is obtained by calling the
+// function each time the metrics are written. Panics if a metric of the same
+// name is already registered.
+func (r *Registry) AddGaugeFunc(name, help string, value func() float64) {
+	r.add(metric{name, help, TypeGauge, value})
+}
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[m.name]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric name: %q", m.name))
+	}
+	if r.metrics == nil {
+		r.metrics = map[string]metric{}
+	}
+	r.metrics[m.name] = m
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format,
+// sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	metrics := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name < metrics[j].name
+	})
+	for _, m := range metrics {
+		value := strconv.FormatFloat(m.value(), 'g', -1, 64)
+		if _, err := fmt.Fprintf(w, "# HELP %[1]s %[2]s\n# TYPE %[1]s %[3]s\n%[1]s %[4]s\n",
+			m.name, m.help, m.typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	var r Registry
+	var counter Counter
+	counter.Add(3)
+	counter.Inc()
+	r.AddGaugeFunc("b_depth", "Number of queued items.", func() float64 { return 2.5 })
+	r.AddCounter("a_total", "Number of handled items.", &counter)
+
+	var sb strings.Builder
+	require.NoError(t, r.WriteText(&sb))
+
+	want := `# HELP a_total Number of handled items.
+# TYPE a_total counter
+a_total 4
+# HELP b_depth Number of queued items.
+# TYPE b_depth gauge
+b_depth 2.5
+`
+	assert.Equal(t, want, sb.String())
+}
+
+func TestRegistry_duplicateName(t *testing.T) {
+	var r Registry
+	r.AddCounter("a_total", "", &Counter{})
+	assert.Panics(t, func() {
+		r.AddGaugeFunc("a_total", "", func() float64 { return 0 })
+	})
+}
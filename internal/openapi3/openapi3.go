@@ -0,0 +1,537 @@
+// Package openapi3 converts Swagger 2.0 documents, such as the ones generated
+// by swaggo/swag, to OpenAPI 3.0 documents.
+//
+// The conversion only moves things around to fit the OpenAPI 3.0 structure.
+// Operation IDs, tags, and schema names are kept as-is, so that clients
+// generated from either document use the same names.
+package openapi3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Version is the OpenAPI version of the converted documents.
+const Version = "3.0.3"
+
+var defaultMediaTypes = []any{"application/json"}
+
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// parameterSchemaKeys are the Swagger 2.0 parameter and header fields that are
+// moved into the "schema" field in OpenAPI 3.0.
+var parameterSchemaKeys = []string{
+	"type", "format", "items", "default", "enum", "multipleOf",
+	"maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+	"maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems",
+}
+
+var refPrefixes = map[string]string{
+	"#/definitions/": "#/components/schemas/",
+	"#/parameters/":  "#/components/parameters/",
+	"#/responses/":   "#/components/responses/",
+}
+
+// ConvertSwagger2 converts a Swagger 2.0 JSON document to an OpenAPI 3.0 JSON
+// document.
+func ConvertSwagger2(swaggerJSON []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(swaggerJSON, &doc); err != nil {
+		return nil, fmt.Errorf("parse swagger document: %w", err)
+	}
+	if version, _ := doc["swagger"].(string); version != "2.0" {
+		return nil, fmt.Errorf("unsupported swagger version: %q", version)
+	}
+	return json.Marshal(convertDocument(doc))
+}
+
+func convertDocument(doc map[string]any) map[string]any {
+	out := map[string]any{
+		"openapi": Version,
+		"info":    doc["info"],
+		"paths":   map[string]any{},
+	}
+	for _, key := range []string{"tags", "security", "externalDocs"} {
+		if v, ok := doc[key]; ok {
+			out[key] = v
+		}
+	}
+	copyExtensions(out, doc)
+	if servers := convertServers(doc); len(servers) > 0 {
+		out["servers"] = servers
+	}
+
+	consumes := anySliceOrDefault(doc["consumes"], defaultMediaTypes)
+	produces := anySliceOrDefault(doc["produces"], defaultMediaTypes)
+	if paths, ok := doc["paths"].(map[string]any); ok {
+		outPaths := make(map[string]any, len(paths))
+		for path, pathItem := range paths {
+			if pathItemMap, ok := pathItem.(map[string]any); ok {
+				outPaths[path] = convertPathItem(pathItemMap, consumes, produces)
+			}
+		}
+		out["paths"] = outPaths
+	}
+
+	components := map[string]any{}
+	if definitions, ok := doc["definitions"].(map[string]any); ok {
+		schemas := make(map[string]any, len(definitions))
+		for name, schema := range definitions {
+			schemas[name] = convertSchema(schema)
+		}
+		components["schemas"] = schemas
+	}
+	if params, ok := doc["parameters"].(map[string]any); ok {
+		outParams := map[string]any{}
+		requestBodies := map[string]any{}
+		for name, param := range params {
+			paramMap, ok := param.(map[string]any)
+			if !ok {
+				continue
+			}
+			if paramMap["in"] == "body" {
+				requestBodies[name] = convertBodyParameter(paramMap, consumes)
+			} else {
+				outParams[name] = convertParameter(paramMap)
+			}
+		}
+		if len(outParams) > 0 {
+			components["parameters"] = outParams
+		}
+		if len(requestBodies) > 0 {
+			components["requestBodies"] = requestBodies
+		}
+	}
+	if responses, ok := doc["responses"].(map[string]any); ok {
+		components["responses"] = convertResponses(responses, produces)
+	}
+	if securityDefs, ok := doc["securityDefinitions"].(map[string]any); ok {
+		schemes := make(map[string]any, len(securityDefs))
+		for name, def := range securityDefs {
+			if defMap, ok := def.(map[string]any); ok {
+				schemes[name] = convertSecurityScheme(defMap)
+			}
+		}
+		components["securitySchemes"] = schemes
+	}
+	if len(components) > 0 {
+		out["components"] = components
+	}
+
+	rewriteRefs(out)
+	return out
+}
+
+func convertServers(doc map[string]any) []any {
+	host, _ := doc["host"].(string)
+	basePath, _ := doc["basePath"].(string)
+	if host == "" {
+		if basePath == "" {
+			return nil
+		}
+		return []any{map[string]any{"url": basePath}}
+	}
+	schemes := anySliceOrDefault(doc["schemes"], nil)
+	if len(schemes) == 0 {
+		return []any{map[string]any{"url": "//" + host + basePath}}
+	}
+	servers := make([]any, 0, len(schemes))
+	for _, scheme := range schemes {
+		servers = append(servers, map[string]any{
+			"url": fmt.Sprintf("%s://%s%s", scheme, host, basePath),
+		})
+	}
+	return servers
+}
+
+func convertPathItem(pathItem map[string]any, consumes, produces []any) map[string]any {
+	out := make(map[string]any, len(pathItem))
+	for key, value := range pathItem {
+		switch {
+		case key == "parameters":
+			params, _ := convertParameters(value, consumes)
+			out[key] = params
+		case containsString(operationMethods, key):
+			if op, ok := value.(map[string]any); ok {
+				out[key] = convertOperation(op, consumes, produces)
+			}
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+func convertOperation(op map[string]any, consumes, produces []any) map[string]any {
+	consumes = anySliceOrDefault(op["consumes"], consumes)
+	produces = anySliceOrDefault(op["produces"], produces)
+	out := make(map[string]any, len(op))
+	for key, value := range op {
+		switch key {
+		case "consumes", "produces", "schemes":
+			// Moved into the request body and response content types.
+		case "parameters":
+			params, requestBody := convertParameters(value, consumes)
+			if len(params) > 0 {
+				out["parameters"] = params
+			}
+			if requestBody != nil {
+				out["requestBody"] = requestBody
+			}
+		case "responses":
+			if responses, ok := value.(map[string]any); ok {
+				out["responses"] = convertResponses(responses, produces)
+			}
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// convertParameters converts the parameters of a path item or operation. Body
+// and form data parameters are merged into a single request body, as OpenAPI
+// 3.0 does not have any body parameters.
+func convertParameters(value any, consumes []any) ([]any, map[string]any) {
+	params, _ := value.([]any)
+	var out []any
+	var requestBody map[string]any
+	var formParams []map[string]any
+	for _, param := range params {
+		paramMap, ok := param.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch paramMap["in"] {
+		case "body":
+			requestBody = convertBodyParameter(paramMap, consumes)
+		case "formData":
+			formParams = append(formParams, paramMap)
+		default:
+			out = append(out, convertParameter(paramMap))
+		}
+	}
+	if len(formParams) > 0 {
+		requestBody = convertFormDataParameters(formParams, consumes)
+	}
+	return out, requestBody
+}
+
+func convertParameter(param map[string]any) map[string]any {
+	if _, ok := param["$ref"]; ok {
+		return param
+	}
+	out := map[string]any{}
+	schema := map[string]any{}
+	for key, value := range param {
+		switch {
+		case key == "collectionFormat":
+			setParameterStyle(out, param["in"], value)
+		case containsString(parameterSchemaKeys, key):
+			schema[key] = value
+		case key == "x-nullable":
+			schema["nullable"] = value
+		default:
+			out[key] = value
+		}
+	}
+	if len(schema) > 0 {
+		out["schema"] = convertSchema(schema)
+	}
+	return out
+}
+
+func setParameterStyle(param map[string]any, in, collectionFormat any) {
+	switch collectionFormat {
+	case "multi":
+		param["style"] = "form"
+		param["explode"] = true
+	case "ssv":
+		param["style"] = "spaceDelimited"
+		param["explode"] = false
+	case "pipes":
+		param["style"] = "pipeDelimited"
+		param["explode"] = false
+	default:
+		if in == "query" || in == "cookie" {
+			param["style"] = "form"
+		} else {
+			param["style"] = "simple"
+		}
+		param["explode"] = false
+	}
+}
+
+func convertBodyParameter(param map[string]any, consumes []any) map[string]any {
+	out := map[string]any{
+		"content": mediaTypeContent(consumes, convertSchema(param["schema"]), nil),
+	}
+	if description, ok := param["description"]; ok {
+		out["description"] = description
+	}
+	if required, ok := param["required"]; ok {
+		out["required"] = required
+	}
+	copyExtensions(out, param)
+	return out
+}
+
+func convertFormDataParameters(params []map[string]any, consumes []any) map[string]any {
+	properties := map[string]any{}
+	var required []any
+	hasFile := false
+	for _, param := range params {
+		name, _ := param["name"].(string)
+		property := map[string]any{}
+		for _, key := range parameterSchemaKeys {
+			if value, ok := param[key]; ok {
+				property[key] = value
+			}
+		}
+		if description, ok := param["description"]; ok {
+			property["description"] = description
+		}
+		if property["type"] == "file" {
+			hasFile = true
+		}
+		properties[name] = convertSchema(property)
+		if isRequired, _ := param["required"].(bool); isRequired {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	var mediaTypes []any
+	for _, mediaType := range consumes {
+		if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	if len(mediaTypes) == 0 {
+		if hasFile {
+			mediaTypes = []any{"multipart/form-data"}
+		} else {
+			mediaTypes = []any{"application/x-www-form-urlencoded"}
+		}
+	}
+	return map[string]any{
+		"content": mediaTypeContent(mediaTypes, schema, nil),
+	}
+}
+
+func convertResponses(responses map[string]any, produces []any) map[string]any {
+	out := make(map[string]any, len(responses))
+	for code, resp := range responses {
+		respMap, ok := resp.(map[string]any)
+		if !ok {
+			continue
+		}
+		out[code] = convertResponse(respMap, produces)
+	}
+	return out
+}
+
+func convertResponse(resp map[string]any, produces []any) map[string]any {
+	if _, ok := resp["$ref"]; ok {
+		return resp
+	}
+	out := map[string]any{
+		// Description is required in both Swagger 2.0 and OpenAPI 3.0, but is
+		// sometimes left out by generators.
+		"description": "",
+	}
+	examples, _ := resp["examples"].(map[string]any)
+	for key, value := range resp {
+		switch key {
+		case "schema":
+			out["content"] = mediaTypeContent(produces, convertSchema(value), examples)
+		case "examples":
+			// Moved into the response content.
+		case "headers":
+			if headers, ok := value.(map[string]any); ok {
+				out["headers"] = convertHeaders(headers)
+			}
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+func convertHeaders(headers map[string]any) map[string]any {
+	out := make(map[string]any, len(headers))
+	for name, header := range headers {
+		headerMap, ok := header.(map[string]any)
+		if !ok {
+			continue
+		}
+		converted := convertParameter(headerMap)
+		delete(converted, "style")
+		delete(converted, "explode")
+		out[name] = converted
+	}
+	return out
+}
+
+func mediaTypeContent(mediaTypes []any, schema any, examples map[string]any) map[string]any {
+	content := make(map[string]any, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		name, ok := mediaType.(string)
+		if !ok {
+			continue
+		}
+		media := map[string]any{"schema": schema}
+		if example, ok := examples[name]; ok {
+			media["example"] = example
+		}
+		content[name] = media
+	}
+	return content
+}
+
+func convertSecurityScheme(def map[string]any) map[string]any {
+	out := map[string]any{}
+	if description, ok := def["description"]; ok {
+		out["description"] = description
+	}
+	copyExtensions(out, def)
+	switch def["type"] {
+	case "basic":
+		out["type"] = "http"
+		out["scheme"] = "basic"
+	case "oauth2":
+		out["type"] = "oauth2"
+		flow := map[string]any{"scopes": anyMapOrEmpty(def["scopes"])}
+		for _, key := range []string{"authorizationUrl", "tokenUrl"} {
+			if value, ok := def[key]; ok {
+				flow[key] = value
+			}
+		}
+		flowName, _ := def["flow"].(string)
+		switch flowName {
+		case "application":
+			flowName = "clientCredentials"
+		case "accessCode":
+			flowName = "authorizationCode"
+		}
+		out["flows"] = map[string]any{flowName: flow}
+	default:
+		for _, key := range []string{"type", "name", "in"} {
+			if value, ok := def[key]; ok {
+				out[key] = value
+			}
+		}
+	}
+	return out
+}
+
+// convertSchema converts the Swagger 2.0 specific parts of a schema, such as
+// the "x-nullable" extension and the "file" type, recursively.
+func convertSchema(schema any) any {
+	switch schema := schema.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(schema))
+		for key, value := range schema {
+			switch key {
+			case "x-nullable":
+				out["nullable"] = value
+			case "discriminator":
+				if propertyName, ok := value.(string); ok {
+					out[key] = map[string]any{"propertyName": propertyName}
+				} else {
+					out[key] = value
+				}
+			case "properties", "definitions", "patternProperties":
+				if props, ok := value.(map[string]any); ok {
+					outProps := make(map[string]any, len(props))
+					for name, prop := range props {
+						outProps[name] = convertSchema(prop)
+					}
+					out[key] = outProps
+				} else {
+					out[key] = value
+				}
+			default:
+				out[key] = convertSchema(value)
+			}
+		}
+		if out["type"] == "file" {
+			out["type"] = "string"
+			out["format"] = "binary"
+		}
+		return out
+	case []any:
+		out := make([]any, len(schema))
+		for i, value := range schema {
+			out[i] = convertSchema(value)
+		}
+		return out
+	default:
+		return schema
+	}
+}
+
+// rewriteRefs changes all "$ref" values pointing to the Swagger 2.0 locations
+// to instead point to the OpenAPI 3.0 components, recursively.
+func rewriteRefs(value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				value[key] = rewriteRef(ref)
+				continue
+			}
+			rewriteRefs(child)
+		}
+	case []any:
+		for _, child := range value {
+			rewriteRefs(child)
+		}
+	}
+}
+
+func rewriteRef(ref string) string {
+	for oldPrefix, newPrefix := range refPrefixes {
+		if strings.HasPrefix(ref, oldPrefix) {
+			return newPrefix + strings.TrimPrefix(ref, oldPrefix)
+		}
+	}
+	return ref
+}
+
+func copyExtensions(dst, src map[string]any) {
+	for key, value := range src {
+		if strings.HasPrefix(key, "x-") {
+			dst[key] = value
+		}
+	}
+}
+
+func anySliceOrDefault(value any, defaultValue []any) []any {
+	if slice, ok := value.([]any); ok && len(slice) > 0 {
+		return slice
+	}
+	return defaultValue
+}
+
+func anyMapOrEmpty(value any) map[string]any {
+	if m, ok := value.(map[string]any); ok {
+		return m
+	}
+	return map[string]any{}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package openapi3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const swaggerDoc = `{
+  "swagger": "2.0",
+  "info": {"title": "Sample API", "version": "1.0.0"},
+  "host": "example.com",
+  "basePath": "/api",
+  "schemes": ["https"],
+  "paths": {
+    "/project/{projectId}": {
+      "put": {
+        "operationId": "updateProject",
+        "tags": ["project"],
+        "consumes": ["application/json"],
+        "produces": ["application/json"],
+        "parameters": [
+          {"name": "projectId", "in": "path", "required": true, "type": "integer", "minimum": 0},
+          {"name": "branch", "in": "query", "type": "array", "items": {"type": "string"}, "collectionFormat": "multi"},
+          {"name": "project", "in": "body", "required": true, "schema": {"$ref": "#/definitions/request.Project"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Updated project",
+            "schema": {"$ref": "#/definitions/response.Project"},
+            "headers": {"ETag": {"type": "string", "description": "Entity tag"}}
+          }
+        }
+      }
+    },
+    "/project/{projectId}/artifact": {
+      "post": {
+        "operationId": "createArtifact",
+        "parameters": [
+          {"name": "files", "in": "formData", "required": true, "type": "file"}
+        ],
+        "responses": {"201": {"description": "Created"}}
+      }
+    }
+  },
+  "definitions": {
+    "request.Project": {"type": "object", "properties": {"name": {"type": "string"}}},
+    "response.Project": {
+      "type": "object",
+      "properties": {
+        "parentId": {"type": "integer", "x-nullable": true},
+        "owner": {"$ref": "#/definitions/response.Owner"}
+      }
+    },
+    "response.Owner": {"type": "object"}
+  },
+  "securityDefinitions": {
+    "BasicAuth": {"type": "basic"},
+    "ApiKey": {"type": "apiKey", "name": "Authorization", "in": "header"}
+  }
+}`
+
+func TestConvertSwagger2(t *testing.T) {
+	outJSON, err := ConvertSwagger2([]byte(swaggerDoc))
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(outJSON, &got))
+
+	var want map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "openapi": "3.0.3",
+  "info": {"title": "Sample API", "version": "1.0.0"},
+  "servers": [{"url": "https://example.com/api"}],
+  "paths": {
+    "/project/{projectId}": {
+      "put": {
+        "operationId": "updateProject",
+        "tags": ["project"],
+        "parameters": [
+          {"name": "projectId", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 0}},
+          {"name": "branch", "in": "query", "style": "form", "explode": true, "schema": {"type": "array", "items": {"type": "string"}}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/request.Project"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "Updated project",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/response.Project"}}},
+            "headers": {"ETag": {"description": "Entity tag", "schema": {"type": "string"}}}
+          }
+        }
+      }
+    },
+    "/project/{projectId}/artifact": {
+      "post": {
+        "operationId": "createArtifact",
+        "requestBody": {
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {"files": {"type": "string", "format": "binary"}},
+                "required": ["files"]
+              }
+            }
+          }
+        },
+        "responses": {"201": {"description": "Created"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "request.Project": {"type": "object", "properties": {"name": {"type": "string"}}},
+      "response.Project": {
+        "type": "object",
+        "properties": {
+          "parentId": {"type": "integer", "nullable": true},
+          "owner": {"$ref": "#/components/schemas/response.Owner"}
+        }
+      },
+      "response.Owner": {"type": "object"}
+    },
+    "securitySchemes": {
+      "BasicAuth": {"type": "http", "scheme": "basic"},
+      "ApiKey": {"type": "apiKey", "name": "Authorization", "in": "header"}
+    }
+  }
+}`), &want))
+
+	assert.Equal(t, want, got)
+}
+
+func TestConvertSwagger2_unsupportedVersion(t *testing.T) {
+	_, err := ConvertSwagger2([]byte(`{"openapi": "3.0.0"}`))
+	assert.Error(t, err)
+}
@@ -1,5 +1,7 @@
 package ptrconv
 
+import "gopkg.in/guregu/null.v4"
+
 // UintPtr converts a uint pointer to a uint value, where nil is
 // translated to zero (0).
 func UintPtr(value *uint) uint {
@@ -17,3 +19,22 @@ func UintZeroNil(value uint) *uint {
 	}
 	return &value
 }
+
+// UintPtrNullInt converts a uint pointer to a nullable int, where nil is
+// translated to null.
+func UintPtrNullInt(value *uint) null.Int {
+	if value == nil {
+		return null.Int{}
+	}
+	return null.IntFrom(int64(*value))
+}
+
+// NullIntUintPtr converts a nullable int to a uint pointer, where null is
+// translated to nil.
+func NullIntUintPtr(value null.Int) *uint {
+	if !value.Valid {
+		return nil
+	}
+	u := uint(value.Int64)
+	return &u
+}
@@ -0,0 +1,88 @@
+// Package secretbox encrypts and decrypts sensitive values, such as secret
+// build inputs, before they are stored in the database.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeySize is the required length of an encryption key, in bytes. Keys of this
+// size selects the AES-256 cipher.
+const KeySize = 32
+
+// prefix is prepended to all encrypted values, to be able to tell them apart
+// from plain text values and to allow changing the algorithm in the future.
+const prefix = "aesgcm:"
+
+// ErrNotEncrypted is returned when trying to decrypt a value that was not
+// encrypted by a Box.
+var ErrNotEncrypted = errors.New("value is not encrypted")
+
+// Box encrypts and decrypts values using AES-GCM.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a new Box from a base64-encoded key of KeySize bytes.
+func New(base64Key string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(base64Key)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64 key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key size: want %d bytes, but was: %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// IsEncrypted returns true if the value looks like it has been encrypted by a
+// Box.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts a plain text value. A new random nonce is used for each
+// call, so encrypting the same value twice gives different results.
+func (b *Box) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value previously encrypted using Encrypt.
+func (b *Box) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", ErrNotEncrypted
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", fmt.Errorf("decode base64 value: %w", err)
+	}
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("encrypted value is too short")
+	}
+	plaintext, err := b.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
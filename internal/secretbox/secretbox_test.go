@@ -0,0 +1,54 @@
+package secretbox
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", KeySize)))
+
+func TestBox_roundTrip(t *testing.T) {
+	box, err := New(testKey)
+	require.NoError(t, err)
+
+	encrypted, err := box.Encrypt("hunter2")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "hunter2")
+
+	encryptedAgain, err := box.Encrypt("hunter2")
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, encryptedAgain, "nonce reuse")
+
+	decrypted, err := box.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", decrypted)
+}
+
+func TestBox_Decrypt_invalid(t *testing.T) {
+	box, err := New(testKey)
+	require.NoError(t, err)
+
+	_, err = box.Decrypt("hunter2")
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+
+	encrypted, err := box.Encrypt("hunter2")
+	require.NoError(t, err)
+	otherKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", KeySize)))
+	otherBox, err := New(otherKey)
+	require.NoError(t, err)
+	_, err = otherBox.Decrypt(encrypted)
+	assert.Error(t, err)
+}
+
+func TestNew_invalidKey(t *testing.T) {
+	_, err := New("not base64!")
+	assert.Error(t, err)
+
+	_, err = New(base64.StdEncoding.EncodeToString([]byte("too short")))
+	assert.Error(t, err)
+}
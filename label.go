@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+type labelModule struct {
+	Database *gorm.DB
+}
+
+func (m labelModule) Register(g *gin.RouterGroup) {
+	g.PUT("/build/:buildId/label", m.updateBuildLabelsHandler)
+	g.PUT("/project/:projectId/label", m.updateProjectLabelsHandler)
+}
+
+// updateBuildLabelsHandler godoc
+// @id updateBuildLabels
+// @summary Set the labels of a build.
+// @description Replaces all labels of the build with the given labels.
+// @description Label names must not be empty, and must not contain `=`.
+// @description Added in v5.3.0.
+// @tags build
+// @accept json
+// @produce json
+// @param buildId path uint true "build ID" minimum(0)
+// @param labels body request.Labels true "Labels of the build"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Labels
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/label [put]
+func (m labelModule) updateBuildLabelsHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	var reqLabels request.Labels
+	if err := c.ShouldBindJSON(&reqLabels); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for the labels.")
+		return
+	}
+	if !validateReqLabels(c, reqLabels) {
+		return
+	}
+	if !validateBuildExistsByID(c, m.Database, buildID, "when updating labels") {
+		return
+	}
+	dbLabels := modelconv.ReqLabelsToDatabaseBuildLabels(reqLabels, buildID)
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(&database.BuildLabel{BuildID: buildID}, database.BuildLabelFields.BuildID).
+			Delete(&database.BuildLabel{}).
+			Error; err != nil {
+			return err
+		}
+		if len(dbLabels) == 0 {
+			return nil
+		}
+		return tx.Create(&dbLabels).Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed updating labels of build with ID %d in database.",
+			buildID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildLabelsToResponse(dbLabels))
+}
+
+// updateProjectLabelsHandler godoc
+// @id updateProjectLabels
+// @summary Set the labels of a project.
+// @description Replaces all labels of the project with the given labels.
+// @description Label names must not be empty, and must not contain `=`.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param labels body request.Labels true "Labels of the project"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Labels
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/label [put]
+func (m labelModule) updateProjectLabelsHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqLabels request.Labels
+	if err := c.ShouldBindJSON(&reqLabels); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for the labels.")
+		return
+	}
+	if !validateReqLabels(c, reqLabels) {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when updating labels") {
+		return
+	}
+	dbLabels := modelconv.ReqLabelsToDatabaseProjectLabels(reqLabels, projectID)
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(&database.ProjectLabel{ProjectID: projectID}, database.ProjectLabelFields.ProjectID).
+			Delete(&database.ProjectLabel{}).
+			Error; err != nil {
+			return err
+		}
+		if len(dbLabels) == 0 {
+			return nil
+		}
+		return tx.Create(&dbLabels).Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed updating labels of project with ID %d in database.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectLabelsToResponse(dbLabels))
+}
+
+// validateReqLabels writes an "invalid param" problem and returns false if any
+// of the labels have invalid names, or are too long to fit in the database.
+func validateReqLabels(c *gin.Context, reqLabels request.Labels) bool {
+	var errs []string
+	for key, value := range reqLabels {
+		switch {
+		case key == "":
+			errs = append(errs, "label name must not be empty")
+		case strings.Contains(key, "="):
+			errs = append(errs, fmt.Sprintf("%s: label name must not contain '='", key))
+		case utf8.RuneCountInString(key) > database.LabelSizes.Key:
+			errs = append(errs, fmt.Sprintf("%s: label name must not be longer than %d characters", key, database.LabelSizes.Key))
+		}
+		if utf8.RuneCountInString(value) > database.LabelSizes.Value {
+			errs = append(errs, fmt.Sprintf("%s: label value must not be longer than %d characters", key, database.LabelSizes.Value))
+		}
+	}
+	if len(errs) == 0 {
+		return true
+	}
+	sort.Strings(errs)
+	prob := problems.InvalidParam.New("One or more labels are invalid.")
+	prob.Errors = errs
+	c.Error(errors.New(strings.Join(errs, "; ")))
+	ginutil.WriteProblem(c, prob)
+	return false
+}
+
+// labelFilter is a parsed `?label=` query parameter.
+type labelFilter struct {
+	key string
+	// value is nil when matching on any value of the label.
+	value *string
+}
+
+// parseLabelFilters parses the `?label=` query parameters, which are either
+// on the format "key=value" or "key", where the latter matches any value.
+// Writes an "invalid param" problem and returns false if any are invalid.
+func parseLabelFilters(c *gin.Context, params []string, paramName string) ([]labelFilter, bool) {
+	filters := make([]labelFilter, 0, len(params))
+	for _, param := range params {
+		key, value, hasValue := strings.Cut(param, "=")
+		if key == "" {
+			err := fmt.Errorf("empty label name in filter: %q", param)
+			ginutil.WriteInvalidParamError(c, err, paramName,
+				fmt.Sprintf("Invalid label filter %q, expected either \"key=value\" or \"key\".", param))
+			return nil, false
+		}
+		filter := labelFilter{key: key}
+		if hasValue {
+			filter.value = &value
+		}
+		filters = append(filters, filter)
+	}
+	return filters, true
+}
+
+// labelTable holds the table and column names of a label DB table, and of
+// the table of the labeled objects.
+type labelTable struct {
+	ownerTable    string
+	ownerIDColumn database.SafeSQLName
+	table         string
+	keyColumn     database.SafeSQLName
+	valueColumn   database.SafeSQLName
+}
+
+var buildLabelTable = labelTable{
+	ownerTable:    database.BuildTable,
+	ownerIDColumn: database.BuildColumns.BuildID,
+	table:         database.BuildLabelTable,
+	keyColumn:     database.BuildLabelColumns.Key,
+	valueColumn:   database.BuildLabelColumns.Value,
+}
+
+var projectLabelTable = labelTable{
+	ownerTable:    database.ProjectTable,
+	ownerIDColumn: database.ProjectColumns.ProjectID,
+	table:         database.ProjectLabelTable,
+	keyColumn:     database.ProjectLabelColumns.Key,
+	valueColumn:   database.ProjectLabelColumns.Value,
+}
+
+// whereLabelsScope filters on objects that have all the labels. Uses
+// subqueries instead of joins, so that the columns used in the rest of the
+// query stay unambiguous.
+func whereLabelsScope(t labelTable, filters []labelFilter) func(*gorm.DB) *gorm.DB {
+	if len(filters) == 0 {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		for _, filter := range filters {
+			// The label table's foreign key column has the same name as the
+			// owner's primary key column.
+			subquery := fmt.Sprintf("SELECT %[1]s.%[2]s FROM %[1]s WHERE %[1]s.%[3]s = ?",
+				t.table, t.ownerIDColumn, t.keyColumn)
+			args := []any{filter.key}
+			if filter.value != nil {
+				subquery += fmt.Sprintf(" AND %s.%s = ?", t.table, t.valueColumn)
+				args = append(args, *filter.value)
+			}
+			db = db.Where(fmt.Sprintf("%s.%s IN (%s)",
+				t.ownerTable, t.ownerIDColumn, subquery), args...)
+		}
+		return db
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereLabelsScope(t *testing.T) {
+	db := newTestDryRunDB(t)
+	value := "payments"
+	filters := []labelFilter{
+		{key: "team", value: &value},
+		{key: "release-train"},
+	}
+
+	var dbProjects []database.Project
+	stmt := db.Scopes(whereLabelsScope(projectLabelTable, filters)).Find(&dbProjects).Statement
+
+	wantSQL := `SELECT * FROM "project" WHERE ` +
+		`(project.project_id IN (SELECT project_label.project_id FROM project_label WHERE project_label.key = $1 AND project_label.value = $2)) AND ` +
+		`project.project_id IN (SELECT project_label.project_id FROM project_label WHERE project_label.key = $3)`
+	assert.Equal(t, wantSQL, stmt.SQL.String())
+	assert.Equal(t, []any{"team", "payments", "release-train"}, stmt.Vars)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+type logModule struct {
+	Database *gorm.DB
+}
+
+func (m logModule) Register(g *gin.RouterGroup) {
+	g.GET("/log/search", m.searchLogsHandler)
+}
+
+// logSearchRow is a log line joined with the project ID of its build.
+type logSearchRow struct {
+	LogID     uint
+	BuildID   uint
+	ProjectID uint
+	Message   string
+	Timestamp time.Time
+}
+
+// searchLogsHandler godoc
+// @id searchLogs
+// @summary Search log lines across builds
+// @description Finds log lines matching all words in the `match` query
+// @description parameter, using full-text search when using PostgreSQL.
+// @description Results are ordered by newest first.
+// @description Added in v5.3.0.
+// @tags build
+// @produce json
+// @param match query string true "Words that the log lines must match."
+// @param projectId query uint false "Filter by project ID." minimum(0)
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
+// @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedLogSearchMatches
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /log/search [get]
+func (m logModule) searchLogsHandler(c *gin.Context) {
+	var params = struct {
+		paginationQueryParams
+
+		Match     string `form:"match" binding:"required"`
+		ProjectID *uint  `form:"projectId"`
+	}{
+		paginationQueryParams: defaultCommonGetQueryParams.paginationQueryParams,
+	}
+	if !bindCommonGetQueryParams(c, &params) {
+		return
+	}
+
+	query := m.Database.
+		Model(&database.Log{}).
+		Select(fmt.Sprintf("%[1]s.%[2]s, %[1]s.%[3]s, %[4]s.%[5]s, %[1]s.%[6]s, %[1]s.%[7]s",
+			database.LogTable, database.LogColumns.LogID, database.LogColumns.BuildID,
+			database.BuildTable, database.BuildColumns.ProjectID,
+			database.LogColumns.Message, database.LogColumns.Timestamp)).
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.%[2]s = %[3]s.%[4]s",
+			database.BuildTable, database.BuildColumns.BuildID,
+			database.LogTable, database.LogColumns.BuildID)).
+		Scopes(
+			whereFullTextMatchScope(&params.Match, database.LogColumns.Message),
+			whereBuildInstanceScope(c),
+		).
+		Order(fmt.Sprintf("%s.%s DESC", database.LogTable, database.LogColumns.LogID))
+	if params.ProjectID != nil {
+		query = query.Where(fmt.Sprintf("%s.%s = ?", database.BuildTable, database.BuildColumns.ProjectID), *params.ProjectID)
+	}
+
+	var rows []logSearchRow
+	var totalCount int64
+	if err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &rows, &totalCount); err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed searching logs matching %q.",
+			params.Match))
+		return
+	}
+
+	resMatches := make([]response.LogSearchMatch, len(rows))
+	for i, row := range rows {
+		var offset int64
+		if err := m.Database.
+			Model(&database.Log{}).
+			Where(database.LogColumns.BuildID+" = ?", row.BuildID).
+			Where(database.LogColumns.LogID+" < ?", row.LogID).
+			Count(&offset).
+			Error; err != nil {
+			ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+				"Failed counting preceding logs of log with ID %d.",
+				row.LogID))
+			return
+		}
+		resMatches[i] = response.LogSearchMatch{
+			LogID:     row.LogID,
+			BuildID:   row.BuildID,
+			ProjectID: row.ProjectID,
+			Message:   row.Message,
+			Timestamp: row.Timestamp,
+			Offset:    offset,
+		}
+	}
+
+	ginrender.Response(c, http.StatusOK, response.PaginatedLogSearchMatches{
+		List:       resMatches,
+		TotalCount: totalCount,
+	})
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"gorm.io/gorm"
+)
+
+var errLogIngestQueueFull = errors.New("log ingest queue is full")
+
+// logIngestItem is a log line waiting to be inserted by the logIngester.
+type logIngestItem struct {
+	dbLog database.Log
+	// done is called after the batch containing this log line has been
+	// inserted, or failed to be inserted. Called from the logIngester's
+	// goroutine, and must therefore not block.
+	done func(dbLog database.Log, err error)
+}
+
+// logIngester inserts build logs in batches in the background, so that many
+// builds logging at the same time does not lead to lock contention in the
+// database. Used by both the REST API and the gRPC API.
+type logIngester struct {
+	config      LogIngestConfig
+	queue       chan logIngestItem
+	insertBatch func(dbLogs []database.Log) error
+	stopCh      chan struct{}
+	stopped     chan struct{}
+
+	insertedLines metrics.Counter
+	failedLines   metrics.Counter
+	droppedLines  metrics.Counter
+}
+
+func newLogIngester(db *gorm.DB, config LogIngestConfig) *logIngester {
+	return &logIngester{
+		config:  config,
+		queue:   make(chan logIngestItem, config.QueueSize),
+		stopCh:  make(chan struct{}),
+		stopped: make(chan struct{}),
+		insertBatch: func(dbLogs []database.Log) error {
+			return db.Create(&dbLogs).Error
+		},
+	}
+}
+
+// registerMetrics adds the ingester's metrics to the registry.
+func (i *logIngester) registerMetrics(r *metrics.Registry) {
+	r.AddGaugeFunc("wharf_api_log_ingest_queue_depth",
+		"Number of build log lines waiting to be inserted.",
+		func() float64 { return float64(len(i.queue)) })
+	r.AddGaugeFunc("wharf_api_log_ingest_queue_capacity",
+		"Maximum number of build log lines waiting to be inserted.",
+		func() float64 { return float64(cap(i.queue)) })
+	r.AddCounter("wharf_api_log_ingest_inserted_lines_total",
+		"Number of build log lines inserted into the database.",
+		&i.insertedLines)
+	r.AddCounter("wharf_api_log_ingest_failed_lines_total",
+		"Number of build log lines that failed to be inserted into the database.",
+		&i.failedLines)
+	r.AddCounter("wharf_api_log_ingest_dropped_lines_total",
+		"Number of build log lines dropped because the queue was full.",
+		&i.droppedLines)
+}
+
+// start starts the background worker. The worker runs until stop is called.
+func (i *logIngester) start() {
+	go i.work()
+}
+
+// stop inserts the log lines remaining in the queue, and then stops the
+// background worker. Blocks until the worker has stopped. Log lines enqueued
+// after stop has been called are never inserted.
+func (i *logIngester) stop() {
+	close(i.stopCh)
+	<-i.stopped
+}
+
+// enqueue adds a log line to the queue of logs to insert. If the queue is
+// full, then it waits for room in the queue for up to the configured enqueue
+// timeout, after which the log line is dropped and errLogIngestQueueFull is
+// returned.
+//
+// The done function, if not nil, is called after the log line has been
+// inserted, or failed to be inserted, but only if enqueue returned nil.
+func (i *logIngester) enqueue(ctx context.Context, dbLog database.Log, done func(database.Log, error)) error {
+	item := logIngestItem{dbLog: dbLog, done: done}
+	select {
+	case i.queue <- item:
+		return nil
+	default:
+	}
+	timer := time.NewTimer(i.config.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case i.queue <- item:
+		return nil
+	case <-timer.C:
+		i.droppedLines.Inc()
+		return errLogIngestQueueFull
+	case <-ctx.Done():
+		i.droppedLines.Inc()
+		return ctx.Err()
+	}
+}
+
+// save adds a log line to the queue, and waits for it to be inserted.
+func (i *logIngester) save(ctx context.Context, dbLog database.Log) (database.Log, error) {
+	type result struct {
+		dbLog database.Log
+		err   error
+	}
+	resultCh := make(chan result, 1)
+	err := i.enqueue(ctx, dbLog, func(dbLog database.Log, err error) {
+		resultCh <- result{dbLog, err}
+	})
+	if err != nil {
+		return database.Log{}, err
+	}
+	select {
+	case res := <-resultCh:
+		return res.dbLog, res.err
+	case <-ctx.Done():
+		return database.Log{}, ctx.Err()
+	}
+}
+
+func (i *logIngester) work() {
+	defer close(i.stopped)
+	ticker := time.NewTicker(i.config.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]logIngestItem, 0, i.config.BatchSize)
+	for {
+		select {
+		case item := <-i.queue:
+			batch = append(batch, item)
+			if len(batch) < i.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-i.stopCh:
+			i.drain(batch)
+			return
+		}
+		i.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// drain flushes the batch together with all log lines remaining in the queue.
+func (i *logIngester) drain(batch []logIngestItem) {
+	for {
+		select {
+		case item := <-i.queue:
+			batch = append(batch, item)
+			if len(batch) < i.config.BatchSize {
+				continue
+			}
+		default:
+			if len(batch) > 0 {
+				i.flush(batch)
+			}
+			log.Info().Message("Drained build log ingest queue.")
+			return
+		}
+		i.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush inserts the batch of log lines, and notifies any listeners on the
+// builds' event streams about the inserted logs.
+//
+// If the batch fails to be inserted, then the log lines are retried one by
+// one, so that a bad log line, such as one referencing a build that does not
+// exist, only fails its own caller.
+func (i *logIngester) flush(batch []logIngestItem) {
+	dbLogs := make([]database.Log, len(batch))
+	for idx, item := range batch {
+		dbLogs[idx] = item.dbLog
+	}
+	errs := make([]error, len(batch))
+	if err := i.insertBatch(dbLogs); err != nil {
+		log.Warn().
+			WithError(err).
+			WithInt("lines", len(dbLogs)).
+			Message("Failed inserting batch of build logs, retrying lines one by one.")
+		for idx := range dbLogs {
+			errs[idx] = i.insertBatch(dbLogs[idx : idx+1])
+		}
+	}
+	var failed int
+	for idx, item := range batch {
+		dbLog, err := dbLogs[idx], errs[idx]
+		if err != nil {
+			log.Error().
+				WithError(err).
+				WithUint("build", dbLog.BuildID).
+				Message("Failed inserting build log.")
+			failed++
+		} else {
+			buildStreams.submit(dbLog.BuildID, response.Log{
+				LogID:     dbLog.LogID,
+				BuildID:   dbLog.BuildID,
+				Message:   dbLog.Message,
+				Timestamp: dbLog.Timestamp,
+			})
+		}
+		if item.done != nil {
+			item.done(dbLog, err)
+		}
+	}
+	log.Debug().
+		WithInt("lines", len(dbLogs)-failed).
+		Message("Inserted batch of build logs into database.")
+	i.insertedLines.Add(uint64(len(dbLogs) - failed))
+	i.failedLines.Add(uint64(failed))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLogInserter struct {
+	mu      sync.Mutex
+	batches [][]database.Log
+	err     error
+	// failBuildID fails any batch containing a log line of this build.
+	failBuildID uint
+}
+
+func (ti *testLogInserter) insertBatch(dbLogs []database.Log) error {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	if ti.err != nil {
+		return ti.err
+	}
+	for _, dbLog := range dbLogs {
+		if ti.failBuildID != 0 && dbLog.BuildID == ti.failBuildID {
+			return errors.New("build not found")
+		}
+	}
+	for i := range dbLogs {
+		dbLogs[i].LogID = uint(i + 1)
+	}
+	ti.batches = append(ti.batches, dbLogs)
+	return nil
+}
+
+func newTestLogIngester(inserter *testLogInserter, config LogIngestConfig) *logIngester {
+	ingester := newLogIngester(nil, config)
+	ingester.insertBatch = inserter.insertBatch
+	return ingester
+}
+
+func TestLogIngester_batchesBySize(t *testing.T) {
+	inserter := &testLogInserter{}
+	ingester := newTestLogIngester(inserter, LogIngestConfig{
+		QueueSize:     10,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	ingester.start()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	done := func(database.Log, error) { wg.Done() }
+	require.NoError(t, ingester.enqueue(context.Background(), database.Log{BuildID: 1, Message: "a"}, done))
+	require.NoError(t, ingester.enqueue(context.Background(), database.Log{BuildID: 1, Message: "b"}, done))
+	wg.Wait()
+
+	require.Len(t, inserter.batches, 1)
+	assert.Len(t, inserter.batches[0], 2)
+	assert.Equal(t, uint64(2), ingester.insertedLines.Value())
+}
+
+func TestLogIngester_batchesByTime(t *testing.T) {
+	inserter := &testLogInserter{}
+	ingester := newTestLogIngester(inserter, LogIngestConfig{
+		QueueSize:     10,
+		BatchSize:     100,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	ingester.start()
+
+	dbLog, err := ingester.save(context.Background(), database.Log{BuildID: 1, Message: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), dbLog.LogID)
+	assert.Equal(t, "a", dbLog.Message)
+}
+
+func TestLogIngester_insertError(t *testing.T) {
+	inserter := &testLogInserter{err: errors.New("database is down")}
+	ingester := newTestLogIngester(inserter, LogIngestConfig{
+		QueueSize:     10,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+	})
+	ingester.start()
+
+	_, err := ingester.save(context.Background(), database.Log{BuildID: 1})
+	assert.Equal(t, inserter.err, err)
+	assert.Equal(t, uint64(1), ingester.failedLines.Value())
+}
+
+func TestLogIngester_insertErrorOnlyFailsBadLines(t *testing.T) {
+	inserter := &testLogInserter{failBuildID: 2}
+	ingester := newTestLogIngester(inserter, LogIngestConfig{
+		QueueSize:     10,
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	})
+	ingester.start()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = map[string]error{}
+	)
+	wg.Add(3)
+	done := func(dbLog database.Log, err error) {
+		mu.Lock()
+		errs[dbLog.Message] = err
+		mu.Unlock()
+		wg.Done()
+	}
+	require.NoError(t, ingester.enqueue(context.Background(), database.Log{BuildID: 1, Message: "a"}, done))
+	require.NoError(t, ingester.enqueue(context.Background(), database.Log{BuildID: 2, Message: "b"}, done))
+	require.NoError(t, ingester.enqueue(context.Background(), database.Log{BuildID: 1, Message: "c"}, done))
+	wg.Wait()
+
+	assert.NoError(t, errs["a"])
+	assert.Error(t, errs["b"])
+	assert.NoError(t, errs["c"])
+	assert.Equal(t, uint64(2), ingester.insertedLines.Value())
+	assert.Equal(t, uint64(1), ingester.failedLines.Value())
+}
+
+func TestLogIngester_stopDrainsQueue(t *testing.T) {
+	inserter := &testLogInserter{}
+	ingester := newTestLogIngester(inserter, LogIngestConfig{
+		QueueSize:     10,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	for _, msg := range []string{"a", "b", "c"} {
+		require.NoError(t, ingester.enqueue(context.Background(), database.Log{BuildID: 1, Message: msg}, nil))
+	}
+	ingester.start()
+	ingester.stop()
+
+	assert.Empty(t, ingester.queue)
+	assert.Equal(t, uint64(3), ingester.insertedLines.Value())
+}
+
+func TestLogIngester_dropsWhenFull(t *testing.T) {
+	// not started, so nothing is consumed from the queue
+	ingester := newTestLogIngester(&testLogInserter{}, LogIngestConfig{
+		QueueSize:      1,
+		BatchSize:      1,
+		FlushInterval:  time.Hour,
+		EnqueueTimeout: time.Millisecond,
+	})
+
+	require.NoError(t, ingester.enqueue(context.Background(), database.Log{BuildID: 1}, nil))
+	err := ingester.enqueue(context.Background(), database.Log{BuildID: 1}, nil)
+	assert.ErrorIs(t, err, errLogIngestQueueFull)
+	assert.Equal(t, uint64(1), ingester.droppedLines.Value())
+	assert.Len(t, ingester.queue, 1)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/logger"
+)
+
+// logLevelNames are the names of the logging levels, as used in the log.level
+// config and by the /api/admin/log-level endpoints.
+var logLevelNames = map[logger.Level]string{
+	logger.LevelDebug: "debug",
+	logger.LevelInfo:  "info",
+	logger.LevelWarn:  "warn",
+	logger.LevelError: "error",
+	logger.LevelPanic: "panic",
+}
+
+// logLevels filters all logs written by wharf-api, and is changed by the
+// log.level config and the PUT /api/admin/log-level endpoint.
+var logLevels = newLogLevelFilter()
+
+// logLevelFilter filters the logs written to wharf-core logging sinks by their
+// level and scope. The wharf-core logger has its own filtering via
+// logger.SetLevel and logger.SetLevelScoped, but those are not safe to change
+// while logs are being written, which is needed to change the levels at
+// runtime.
+type logLevelFilter struct {
+	mu     sync.RWMutex
+	global logger.Level
+	scoped map[string]logger.Level
+}
+
+func newLogLevelFilter() *logLevelFilter {
+	return &logLevelFilter{
+		global: logger.LevelDebug,
+		scoped: make(map[string]logger.Level),
+	}
+}
+
+// minLevel returns the minimum level of logs to write for the scope. As with
+// the wharf-core logger, a scoped level only has an effect if it is higher
+// than the global level.
+func (f *logLevelFilter) minLevel(scope string) logger.Level {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if level, ok := f.scoped[scope]; ok && level > f.global {
+		return level
+	}
+	return f.global
+}
+
+func (f *logLevelFilter) setGlobal(level logger.Level) {
+	f.mu.Lock()
+	f.global = level
+	f.mu.Unlock()
+}
+
+// set replaces the global and all scoped levels. The scope names are
+// case-insensitive.
+func (f *logLevelFilter) set(global logger.Level, scoped map[string]logger.Level) {
+	upperScoped := make(map[string]logger.Level, len(scoped))
+	for scope, level := range scoped {
+		upperScoped[strings.ToUpper(scope)] = level
+	}
+	f.mu.Lock()
+	f.global = global
+	f.scoped = upperScoped
+	f.mu.Unlock()
+}
+
+func (f *logLevelFilter) response() response.LogLevel {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	res := response.LogLevel{
+		Level:  logLevelNames[f.global],
+		Scopes: make(map[string]string, len(f.scoped)),
+	}
+	for scope, level := range f.scoped {
+		res.Scopes[scope] = logLevelNames[level]
+	}
+	return res
+}
+
+// wrap returns a logging sink that only writes the logs that pass the filter.
+func (f *logLevelFilter) wrap(sink logger.Sink) logger.Sink {
+	return filteredLogSink{sink: sink, filter: f}
+}
+
+type filteredLogSink struct {
+	sink   logger.Sink
+	filter *logLevelFilter
+}
+
+func (s filteredLogSink) NewContext(scope string) logger.Context {
+	return filteredLogContext{
+		ctx:    s.sink.NewContext(scope),
+		scope:  strings.ToUpper(scope),
+		filter: s.filter,
+	}
+}
+
+// filteredLogContext wraps the logging context of another sink, and skips
+// writing the log if its level is below the filter's minimum level. The level
+// is only known when writing the log, so all other methods pass through to the
+// wrapped context.
+type filteredLogContext struct {
+	ctx    logger.Context
+	scope  string
+	filter *logLevelFilter
+}
+
+func (c filteredLogContext) WriteOut(level logger.Level, message string) {
+	if level < c.filter.minLevel(c.scope) {
+		return
+	}
+	c.ctx.WriteOut(level, message)
+}
+
+func (c filteredLogContext) with(ctx logger.Context) logger.Context {
+	c.ctx = ctx
+	return c
+}
+
+func (c filteredLogContext) SetCaller(file string, line int) logger.Context {
+	return c.with(c.ctx.SetCaller(file, line))
+}
+
+func (c filteredLogContext) SetError(value error) logger.Context {
+	return c.with(c.ctx.SetError(value))
+}
+
+func (c filteredLogContext) AppendString(key string, value string) logger.Context {
+	return c.with(c.ctx.AppendString(key, value))
+}
+
+func (c filteredLogContext) AppendRune(key string, value rune) logger.Context {
+	return c.with(c.ctx.AppendRune(key, value))
+}
+
+func (c filteredLogContext) AppendBool(key string, value bool) logger.Context {
+	return c.with(c.ctx.AppendBool(key, value))
+}
+
+func (c filteredLogContext) AppendInt(key string, value int) logger.Context {
+	return c.with(c.ctx.AppendInt(key, value))
+}
+
+func (c filteredLogContext) AppendInt32(key string, value int32) logger.Context {
+	return c.with(c.ctx.AppendInt32(key, value))
+}
+
+func (c filteredLogContext) AppendInt64(key string, value int64) logger.Context {
+	return c.with(c.ctx.AppendInt64(key, value))
+}
+
+func (c filteredLogContext) AppendUint(key string, value uint) logger.Context {
+	return c.with(c.ctx.AppendUint(key, value))
+}
+
+func (c filteredLogContext) AppendUint32(key string, value uint32) logger.Context {
+	return c.with(c.ctx.AppendUint32(key, value))
+}
+
+func (c filteredLogContext) AppendUint64(key string, value uint64) logger.Context {
+	return c.with(c.ctx.AppendUint64(key, value))
+}
+
+func (c filteredLogContext) AppendFloat32(key string, value float32) logger.Context {
+	return c.with(c.ctx.AppendFloat32(key, value))
+}
+
+func (c filteredLogContext) AppendFloat64(key string, value float64) logger.Context {
+	return c.with(c.ctx.AppendFloat64(key, value))
+}
+
+func (c filteredLogContext) AppendTime(key string, value time.Time) logger.Context {
+	return c.with(c.ctx.AppendTime(key, value))
+}
+
+func (c filteredLogContext) AppendDuration(key string, value time.Duration) logger.Context {
+	return c.with(c.ctx.AppendDuration(key, value))
+}
+
+type logLevelModule struct {
+	Config *Config
+}
+
+func (m logLevelModule) Register(g *gin.RouterGroup) {
+	g.GET("/admin/log-level", m.getLogLevelHandler)
+	g.PUT("/admin/log-level", m.updateLogLevelHandler)
+}
+
+// getLogLevelHandler godoc
+// @id getLogLevel
+// @summary Get the log levels of this wharf-api instance.
+// @description Returns the global minimum level of logs, and the minimum levels
+// @description of specific logging scopes, such as "GIN" or "WHARF".
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce json
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.LogLevel "Log levels"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @router /admin/log-level [get]
+func (m logLevelModule) getLogLevelHandler(c *gin.Context) {
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	ginrender.Response(c, http.StatusOK, logLevels.response())
+}
+
+// updateLogLevelHandler godoc
+// @id updateLogLevel
+// @summary Change the log levels of this wharf-api instance.
+// @description Replaces the global minimum level of logs, and the minimum
+// @description levels of specific logging scopes, without restarting wharf-api.
+// @description Scoped levels can only raise the level of a scope above the
+// @description global level, so to only get debug logs from one scope, set the
+// @description global level to "debug" and the other scopes to a higher level.
+// @description The changed levels are kept until wharf-api is restarted, or
+// @description until the `log.level` config is changed by a config reload.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags meta
+// @accept json
+// @produce json
+// @param logLevel body request.LogLevel _ "Log levels"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.LogLevel "Updated log levels"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @router /admin/log-level [put]
+func (m logLevelModule) updateLogLevelHandler(c *gin.Context) {
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	var reqLogLevel request.LogLevel
+	if err := c.ShouldBindJSON(&reqLogLevel); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for the log levels.")
+		return
+	}
+	// The levels are already validated by the binding tags.
+	global, _ := logger.ParseLevel(reqLogLevel.Level)
+	scoped := make(map[string]logger.Level, len(reqLogLevel.Scopes))
+	for scope, levelName := range reqLogLevel.Scopes {
+		scoped[scope], _ = logger.ParseLevel(levelName)
+	}
+	logLevels.set(global, scoped)
+	res := logLevels.response()
+	log.Info().
+		WithString("level", res.Level).
+		WithStringf("scopes", "%v", res.Scopes).
+		Message("Changed log levels.")
+	ginrender.Response(c, http.StatusOK, res)
+}
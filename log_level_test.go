@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/logger"
+	"github.com/iver-wharf/wharf-core/pkg/logger/consolepretty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevelFilter(t *testing.T) {
+	var sb strings.Builder
+	filter := newLogLevelFilter()
+	sink := filter.wrap(consolepretty.New(consolepretty.Config{
+		Writer:        &sb,
+		DisableDate:   true,
+		DisableCaller: true,
+	}))
+	write := func(scope string, level logger.Level, message string) {
+		sink.NewContext(scope).AppendString("key", "value").WriteOut(level, message)
+	}
+
+	filter.set(logger.LevelInfo, map[string]logger.Level{"gin": logger.LevelError})
+	write("WHARF", logger.LevelDebug, "wharf debug")
+	write("WHARF", logger.LevelInfo, "wharf info")
+	write("GIN", logger.LevelWarn, "gin warn")
+	write("GIN", logger.LevelError, "gin error")
+
+	filter.setGlobal(logger.LevelDebug)
+	write("WHARF", logger.LevelDebug, "wharf debug after change")
+
+	out := sb.String()
+	assert.NotContains(t, out, "wharf debug\n")
+	assert.Contains(t, out, "wharf info")
+	assert.NotContains(t, out, "gin warn")
+	assert.Contains(t, out, "gin error")
+	assert.Contains(t, out, "wharf debug after change")
+}
+
+func TestLogLevelFilter_scopedBelowGlobal(t *testing.T) {
+	filter := newLogLevelFilter()
+	filter.set(logger.LevelWarn, map[string]logger.Level{"GIN": logger.LevelDebug})
+	assert.Equal(t, logger.LevelWarn, filter.minLevel("GIN"))
+}
+
+func TestLogLevelHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prev := logLevels
+	logLevels = newLogLevelFilter()
+	t.Cleanup(func() { logLevels = prev })
+
+	r := gin.New()
+	logLevelModule{Config: &Config{}}.Register(r.Group("/api"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/log-level",
+		strings.NewReader(`{"level":"info","scopes":{"gin":"warn"}}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, logger.LevelWarn, logLevels.minLevel("GIN"))
+	assert.Equal(t, logger.LevelInfo, logLevels.minLevel("WHARF"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/log-level", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"info","scopes":{"GIN":"warn"}}`, w.Body.String())
+
+	for _, body := range []string{
+		`{"level":"verbose"}`,
+		`{"scopes":{"GIN":"warn"}}`,
+		`{"level":"info","scopes":{"GIN":"loud"}}`,
+	} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/log-level",
+			strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Equal(t, response.LogLevel{Level: "info", Scopes: map[string]string{"GIN": "warn"}},
+		logLevels.response(), "invalid requests must not change the levels")
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
 	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
 	"github.com/iver-wharf/wharf-core/pkg/cacertutil"
 	"github.com/iver-wharf/wharf-core/pkg/logger"
 	"github.com/iver-wharf/wharf-core/pkg/logger/consolepretty"
@@ -30,7 +36,10 @@ var log = logger.NewScoped("WHARF")
 // @basePath /api
 // @query.collection.format multi
 func main() {
-	logger.AddOutput(logger.LevelDebug, consolepretty.Default)
+	checkConfig := flag.Bool("check-config", false, "Validate the config and exit, without connecting to the database or serving the API.")
+	flag.Parse()
+
+	logger.AddOutput(logger.LevelDebug, logLevels.wrap(consolepretty.Default))
 	var (
 		config Config
 		err    error
@@ -44,8 +53,20 @@ func main() {
 		fmt.Println("Failed to read config:", err)
 		os.Exit(1)
 	}
+	if *checkConfig {
+		fmt.Println("Config is valid.")
+		return
+	}
+	applyLogConfig(config.Log)
+	if err := setupConfigReloads(&config, loadConfig); err != nil {
+		log.Error().WithError(err).Message("Failed to set up config reloads.")
+		os.Exit(1)
+	}
 
 	docs.SwaggerInfo.Version = AppVersion.Version
+	if err := applySwaggerPaginationDocs(docs.SwaggerInfo, config.HTTP.Pagination); err != nil {
+		log.Warn().WithError(err).Message("Failed to apply pagination config to the API documentation.")
+	}
 
 	if config.CA.CertsFile != "" {
 		client, err := cacertutil.NewHTTPClientWithCerts(config.CA.CertsFile)
@@ -72,15 +93,117 @@ func serve(config Config, db *gorm.DB) error {
 	if err != nil {
 		return err
 	}
+	if config.HTTP.TLS.enabled() {
+		tlsConfig, err := newTLSConfig(config.HTTP.TLS)
+		if err != nil {
+			return err
+		}
+		log.Info().
+			WithBool("mutualTLS", tlsConfig.ClientCAs != nil).
+			Message("Serving HTTP and gRPC over TLS.")
+		listener = tls.NewListener(listener, tlsConfig)
+	}
 	mux := cmux.New(listener)
 	grpcListener := mux.MatchWithWriters(
 		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
 	httpListener := mux.Match(cmux.Any())
 
-	go serveGRPC(grpcListener, db)
-	go serveHTTP(httpListener, config, db)
+	metricsRegistry := &metrics.Registry{}
+	buildStreams = newBuildStreamHub(config.HTTP.BuildStream)
+	buildStreams.registerMetrics(metricsRegistry)
+	buildStreams.start()
+	logIngester := newLogIngester(db, config.LogIngest)
+	logIngester.registerMetrics(metricsRegistry)
+	logIngester.start()
+
+	dbCache := newDBCache(config.Cache)
+	dbCache.registerMetrics(metricsRegistry)
+	if err := dbCache.registerInvalidation(db); err != nil {
+		return fmt.Errorf("register cache invalidation: %w", err)
+	}
 
-	return mux.Serve()
+	busEvents, err = newBusEventPublisher(config.Events)
+	if err != nil {
+		return fmt.Errorf("create event publisher: %w", err)
+	}
+	busEvents.registerMetrics(metricsRegistry)
+	if err := busEvents.registerProjectCallbacks(db); err != nil {
+		return fmt.Errorf("register project event callbacks: %w", err)
+	}
+	busEvents.start()
+
+	engines := newEngineClient(config.CI)
+	engines.tokens, err = newEngineTokenStore(db, config.DB.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("create engine token store: %w", err)
+	}
+	if interval := config.CI.EngineClient.HealthCheckInterval; interval > 0 && !config.CI.MockTriggerResponse {
+		go engines.monitorHealth(func() []CIEngineConfig {
+			return getEnginesFromConfig(config.current().CI)
+		}, interval)
+	}
+	dispatcher := newBuildTriggerDispatcher(db, engines, config.CI.AsyncTrigger)
+	dispatcher.start()
+	builds := buildModule{Database: db, Config: &config, EngineClient: engines, Dispatcher: dispatcher, LogIngester: logIngester, Email: newEmailNotifier(config.Notification)}
+	watchdog := newBuildWatchdog(db, config.CI, newNotifiers(config.Notification), builds)
+	watchdog.registerMetrics(metricsRegistry)
+	watchdog.start()
+	newBuildScheduler(db, config.CI, builds).start()
+	newBuildStatsRollup(db, config.Stats).start()
+	if interval := config.Reload.WatchInterval; interval > 0 {
+		go config.reloadable.watch(configFiles(), interval)
+	}
+
+	go serveGRPC(grpcListener, builds)
+	go serveHTTP(httpListener, config, db, dbCache, builds, metricsRegistry)
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- mux.Serve()
+	}()
+	select {
+	case err := <-serveErr:
+		dispatcher.stop()
+		logIngester.stop()
+		return err
+	case sig := <-shutdown:
+		log.Info().WithString("signal", sig.String()).Message("Shutting down.")
+		listener.Close()
+		// Marks the builds still waiting to be triggered as failed.
+		dispatcher.stop()
+		// Inserts the build logs still waiting in the queue.
+		logIngester.stop()
+		return nil
+	}
+}
+
+// newTLSConfig loads the certificates for serving TLS. The HTTP/2 protocol is
+// offered via ALPN, as it is required by gRPC clients.
+func newTLSConfig(config TLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS cert and key: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if config.ClientCAFile != "" {
+		pemBytes, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("no certificates found in TLS client CA file: %s", config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
 }
 
 func setupDB(dbConfig DBConfig) *gorm.DB {
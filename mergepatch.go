@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+// applyMergePatch applies a JSON Merge Patch, as defined in RFC 7396, to a
+// JSON document. Object fields in the patch replace the fields in the
+// document, and null values remove them. Any non-object patch replaces the
+// whole document.
+func applyMergePatch(doc, patch []byte) ([]byte, error) {
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	var docValue any
+	if err := json.Unmarshal(doc, &docValue); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatchValue(docValue, patchValue))
+}
+
+func mergePatchValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = mergePatchValue(targetObj[key], value)
+		}
+	}
+	return targetObj
+}
+
+// bindMergePatch applies the JSON Merge Patch from the request body onto obj,
+// which should be a pointer to a request model already populated with the
+// current values, and then validates the result the same way as
+// gin.Context.ShouldBindJSON does.
+//
+// Fields left out of the patch keep their current values, while fields set to
+// null are reset to their zero values.
+func bindMergePatch(c *gin.Context, obj any) bool {
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		ginutil.WriteBodyReadError(c, err, "Failed to read the merge patch from the request body.")
+		return false
+	}
+	doc, err := json.Marshal(obj)
+	if err != nil {
+		ginutil.WriteProblemError(c, err, problems.InternalServerError.New(
+			"Failed to serialize the current values before applying the merge patch."))
+		return false
+	}
+	patched, err := applyMergePatch(doc, patch)
+	if err != nil {
+		writeInvalidBindError(c, err, "Failed to parse the merge patch from the request body.")
+		return false
+	}
+	// Reset obj first, so fields removed by the patch end up as zero values.
+	objValue := reflect.ValueOf(obj).Elem()
+	objValue.Set(reflect.Zero(objValue.Type()))
+	if err := json.Unmarshal(patched, obj); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
+		return false
+	}
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed validation after applying the merge patch.")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/metrics"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+type metricsModule struct {
+	Registry *metrics.Registry
+}
+
+func (m metricsModule) Register(g *gin.RouterGroup) {
+	g.GET("/metrics", m.getMetricsHandler)
+}
+
+// getMetricsHandler godoc
+// @id getMetrics
+// @summary Get metrics about the wharf-api's internal state.
+// @description Metrics, such as the number of build logs waiting to be
+// @description inserted, in the Prometheus text exposition format.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce plain
+// @success 200 {string} string "Metrics"
+// @failure 500 {object} problem.Response "Failed to write metrics"
+// @router /metrics [get]
+func (m metricsModule) getMetricsHandler(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := m.Registry.WriteText(&buf); err != nil {
+		ginutil.WriteProblemError(c, err, problems.InternalServerError.New(
+			"Failed to write metrics."))
+		return
+	}
+	c.Data(http.StatusOK, metrics.ContentType, buf.Bytes())
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/go-gormigrate/gormigrate/v2"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"gorm.io/gorm"
@@ -20,7 +22,564 @@ var migrationOptions = gormigrate.Options{
 }
 
 var migrations = []*gormigrate.Migration{
-	// None yet.
+	{
+		ID:      "20221016-01-add-build-duration",
+		Migrate: migrateAddBuildDuration,
+	},
+	newAddColumnsMigration("20221016-02-add-build-git-commit-and-tag",
+		&database.Build{}, database.BuildFields.GitCommit, database.BuildFields.GitTag),
+	newAddColumnsMigration("20221016-03-add-build-triggered-by",
+		&database.Build{},
+		database.BuildFields.TriggeredByType,
+		database.BuildFields.TriggeredByName,
+		database.BuildFields.TriggeredBySubject),
+	newCreateTablesMigration("20221016-04-add-environment-table",
+		&database.Environment{}),
+	newAddColumnsMigration("20221016-05-add-build-param-is-secret",
+		&database.BuildParam{}, database.BuildParamFields.IsSecret),
+	newAddColumnsMigration("20221016-06-add-build-trigger-error",
+		&database.Build{}, database.BuildFields.TriggerError),
+	{
+		ID:       "20221016-07-add-project-group-table",
+		Migrate:  migrateAddProjectGroupTable,
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&database.ProjectGroup{})
+		},
+	},
+	newCreateTablesMigration("20221016-08-add-setting-table",
+		&database.Setting{}),
+	newCreateTablesMigration("20221016-09-add-idempotency-key-table",
+		&database.IdempotencyKey{}),
+	newAddColumnsMigration("20221016-10-add-project-version",
+		&database.Project{}, database.ProjectFields.Version),
+	newAddColumnsMigration("20221016-11-add-project-overrides-version",
+		&database.ProjectOverrides{}, database.ProjectOverridesFields.Version),
+	newAddColumnsMigration("20221016-12-add-provider-version",
+		&database.Provider{}, database.ProviderFields.Version),
+	newAddColumnsMigration("20221016-13-add-token-version",
+		&database.Token{}, database.TokenFields.Version),
+	{
+		ID:       "20221016-14-add-log-message-search-index",
+		Migrate:  migrateAddLogMessageSearchIndex,
+		Rollback: rollbackAddLogMessageSearchIndex,
+	},
+	{
+		ID:      "20221016-15-add-branch-last-build",
+		Migrate: migrateAddBranchLastBuild,
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&database.Branch{}, database.BranchFields.LastBuildID); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&database.Branch{}, database.BranchFields.LastBuiltOn)
+		},
+	},
+	newCreateTablesMigration("20221016-16-add-provider-sync-table",
+		&database.ProviderSync{}),
+	{
+		ID: "20221016-17-increase-provider-name-size",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AlterColumn(&database.Provider{}, database.ProviderFields.Name); err != nil {
+				return err
+			}
+			// The Sqlite migrator recreates the table, which drops its indexes.
+			return createMissingIndexes(tx, &database.Provider{}, "provider_idx_token_id")
+		},
+		// Not rolled back, as shrinking the column would fail for longer
+		// provider names.
+	},
+	newAddColumnsMigration("20221016-18-add-provider-upload-url-and-metadata",
+		&database.Provider{}, database.ProviderFields.UploadURL, database.ProviderFields.Metadata),
+	{
+		ID:      "20221016-19-add-build-group-table",
+		Migrate: migrateAddBuildGroupTable,
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&database.Build{}, database.BuildFields.BuildGroupID); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&database.BuildGroup{})
+		},
+	},
+	newCreateTablesMigration("20221016-20-add-build-trigger-table",
+		&database.BuildTrigger{}),
+	newAddColumnsMigration("20221016-21-add-environment-is-protected",
+		&database.Environment{}, database.EnvironmentFields.IsProtected),
+	newAddColumnsMigration("20221016-22-add-build-approval",
+		&database.Build{},
+		database.BuildFields.ApprovalStatus,
+		database.BuildFields.ReviewedByType,
+		database.BuildFields.ReviewedByName,
+		database.BuildFields.ReviewedBySubject,
+		database.BuildFields.ReviewedOn,
+		database.BuildFields.ReviewComment),
+	newCreateTablesMigration("20221016-23-add-label-tables",
+		&database.ProjectLabel{}, &database.BuildLabel{}),
+	newCreateTablesMigration("20221016-24-add-build-comment-table",
+		&database.BuildComment{}),
+	newCreateTablesMigration("20221016-25-add-build-link-table",
+		&database.BuildLink{}),
+	newCreateIndexesMigration("20221016-26-add-build-engine-id-index",
+		&database.Build{}, "build_idx_engine_id"),
+	{
+		ID:      "20221016-27-add-instance-id-columns",
+		Migrate: migrateAddInstanceIDColumns,
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&database.Build{}, database.BuildFields.InstanceID); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&database.Project{}, database.ProjectFields.InstanceID)
+		},
+	},
+	newAddColumnsMigration("20221016-28-add-project-is-archived",
+		&database.Project{}, database.ProjectFields.IsArchived),
+	newAddColumnsMigration("20221016-29-add-build-status-message",
+		&database.Build{}, database.BuildFields.StatusMessage),
+	newCreateTablesMigration("20221016-30-add-build-stage-result-table",
+		&database.BuildStageResult{}),
+	newAddColumnsMigration("20221016-31-add-project-build-timeouts",
+		&database.Project{}, database.ProjectFields.SchedulingTimeout,
+		database.ProjectFields.RunningTimeout),
+	newCreateTablesMigration("20221016-32-add-engine-token-table",
+		&database.EngineToken{}),
+	newCreateTablesMigration("20221016-33-add-project-input-default-table",
+		&database.ProjectInputDefault{}),
+	newCreateIndexesMigration("20221016-34-add-build-list-filter-indexes",
+		&database.Build{},
+		"build_idx_project_id_status_id",
+		"build_idx_project_id_scheduled_on"),
+	newCreateTablesMigration("20221016-35-add-build-stats-daily-table",
+		&database.BuildStatsDaily{}),
+	{
+		ID:      "20221016-36-add-build-number",
+		Migrate: migrateAddBuildNumber,
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&database.Build{}, database.BuildFields.BuildNumber); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&database.Project{}, database.ProjectFields.LastBuildNumber)
+		},
+	},
+	newAddColumnsMigration("20221016-37-add-build-stage-result-timing",
+		&database.BuildStageResult{},
+		database.BuildStageResultFields.StartedOn,
+		database.BuildStageResultFields.CompletedOn,
+		database.BuildStageResultFields.Duration),
+	{
+		ID:      "20221016-38-add-artifact-size-and-content-type",
+		Migrate: migrateAddArtifactSizeAndContentType,
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&database.Artifact{}, database.ArtifactFields.Size); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&database.Artifact{}, database.ArtifactFields.ContentType)
+		},
+	},
+	newCreateTablesMigration("20221016-39-add-project-engine-table",
+		&database.ProjectEngine{}),
+	newAddColumnsMigration("20221016-40-add-test-result-detail-source",
+		&database.TestResultDetail{},
+		database.TestResultDetailFields.ClassName,
+		database.TestResultDetailFields.FilePath,
+		database.TestResultDetailFields.LineNumber),
+	{
+		ID:      "20221016-41-make-test-results-independent-of-artifacts",
+		Migrate: migrateMakeTestResultsIndependentOfArtifacts,
+		// Not making the artifact_id columns not null again on rollback, as
+		// it would fail for test results whose artifacts have been deleted.
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&database.TestResultDetail{}, database.TestResultDetailFields.TestResultSummaryID)
+		},
+	},
+	newCreateTablesMigration("20221016-42-add-build-status-transition-table",
+		&database.BuildStatusTransition{}),
+	newAddColumnsMigration("20221016-43-add-build-schedule-at",
+		&database.Build{}, database.BuildFields.ScheduleAt),
+	newCreateIndexesMigration("20221016-44-add-build-schedule-at-index",
+		&database.Build{}, "build_idx_schedule_at"),
+	newCreateTablesMigration("20221016-45-add-notification-rule-tables",
+		&database.NotificationRule{}, &database.BuildNotification{}),
+	newCreateTablesMigration("20221016-46-add-project-subscription-table",
+		&database.ProjectSubscription{}),
+	newCreateTablesMigration("20221016-47-add-project-webhook-table",
+		&database.ProjectWebhook{}),
+	newCreateTablesMigration("20221016-48-add-git-trigger-table",
+		&database.GitTrigger{}),
+	newAddColumnsMigration("20221016-49-add-artifact-promoted-from",
+		&database.Artifact{}, database.ArtifactFields.PromotedFromBuildID,
+		database.ArtifactFields.PromotedFromArtifactID),
+	newCreateTablesMigration("20221016-50-add-release-artifact-table",
+		&database.ReleaseArtifact{}),
+	newCreateTablesMigration("20221016-51-add-project-transfer-table",
+		&database.ProjectTransfer{}),
+}
+
+// newCreateTablesMigration returns a migration that creates the tables of the
+// given models, and drops them on rollback.
+//
+// Only the given tables are created. Auto-migrating them instead would also
+// migrate the tables they reference, such as the project and build tables, to
+// their latest shape, making later migrations of those tables collide.
+func newCreateTablesMigration(id string, models ...any) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: id,
+		Migrate: func(tx *gorm.DB) error {
+			return createMissingTables(tx, models...)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(models...)
+		},
+	}
+}
+
+// newAddColumnsMigration returns a migration that adds the columns of the
+// given Go struct field names to the model's table, and drops them on
+// rollback.
+func newAddColumnsMigration(id string, model any, fieldNames ...string) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: id,
+		Migrate: func(tx *gorm.DB) error {
+			return addMissingColumns(tx, model, fieldNames...)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, fieldName := range fieldNames {
+				if err := tx.Migrator().DropColumn(model, fieldName); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// newCreateIndexesMigration returns a migration that creates the indexes of
+// the given names, as declared in the model's GORM struct tags, and drops
+// them on rollback.
+func newCreateIndexesMigration(id string, model any, indexNames ...string) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: id,
+		Migrate: func(tx *gorm.DB) error {
+			return createMissingIndexes(tx, model, indexNames...)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, indexName := range indexNames {
+				if err := tx.Migrator().DropIndex(model, indexName); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// createMissingTables creates the tables of the given models, skipping the
+// tables that already exist.
+func createMissingTables(tx *gorm.DB, models ...any) error {
+	for _, model := range models {
+		if tx.Migrator().HasTable(model) {
+			continue
+		}
+		if err := tx.Migrator().CreateTable(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMissingColumns adds the columns of the given Go struct field names to the
+// model's table, skipping the columns that already exist.
+func addMissingColumns(tx *gorm.DB, model any, fieldNames ...string) error {
+	for _, fieldName := range fieldNames {
+		if tx.Migrator().HasColumn(model, fieldName) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(model, fieldName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createMissingIndexes creates the indexes of the given names, as declared in
+// the model's GORM struct tags, skipping the indexes that already exist.
+func createMissingIndexes(tx *gorm.DB, model any, indexNames ...string) error {
+	for _, indexName := range indexNames {
+		if tx.Migrator().HasIndex(model, indexName) {
+			continue
+		}
+		if err := tx.Migrator().CreateIndex(model, indexName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAddBuildDuration adds the build.duration column and backfills it for
+// all builds that have both a start and completion date.
+//
+// Added in v5.3.0.
+func migrateAddBuildDuration(tx *gorm.DB) error {
+	if err := addMissingColumns(tx, &database.Build{}, database.BuildFields.Duration); err != nil {
+		return err
+	}
+	if err := createMissingIndexes(tx, &database.Build{}, "build_idx_duration"); err != nil {
+		return err
+	}
+	var dbBuilds []database.Build
+	return tx.
+		Select(database.BuildColumns.BuildID, database.BuildColumns.StartedOn, database.BuildColumns.CompletedOn).
+		Where(database.BuildColumns.StartedOn + " IS NOT NULL").
+		Where(database.BuildColumns.CompletedOn + " IS NOT NULL").
+		FindInBatches(&dbBuilds, 500, func(*gorm.DB, int) error {
+			for _, dbBuild := range dbBuilds {
+				duration := dbBuild.CompletedOn.Time.Sub(dbBuild.StartedOn.Time)
+				if err := tx.
+					Model(&database.Build{BuildID: dbBuild.BuildID}).
+					UpdateColumn(database.BuildColumns.Duration, duration.Milliseconds()).
+					Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// migrateAddArtifactSizeAndContentType adds the artifact.size and
+// artifact.content_type columns and backfills them from the artifacts' data.
+// The artifacts are read in small batches, as their data may be large.
+//
+// Added in v5.3.0.
+func migrateAddArtifactSizeAndContentType(tx *gorm.DB) error {
+	if err := addMissingColumns(tx, &database.Artifact{},
+		database.ArtifactFields.Size, database.ArtifactFields.ContentType); err != nil {
+		return err
+	}
+	var dbArtifacts []database.Artifact
+	return tx.
+		Select(database.ArtifactColumns.ArtifactID, database.ArtifactColumns.FileName, database.ArtifactColumns.Data).
+		FindInBatches(&dbArtifacts, 20, func(*gorm.DB, int) error {
+			for _, dbArtifact := range dbArtifacts {
+				if err := tx.
+					Model(&database.Artifact{ArtifactID: dbArtifact.ArtifactID}).
+					UpdateColumns(map[string]any{
+						database.ArtifactColumns.Size:        len(dbArtifact.Data),
+						database.ArtifactColumns.ContentType: detectArtifactContentType(dbArtifact.FileName, dbArtifact.Data),
+					}).
+					Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// migrateMakeTestResultsIndependentOfArtifacts makes the
+// test_result_summary.artifact_id and test_result_detail.artifact_id columns
+// nullable, so that artifacts can be deleted while keeping their parsed test
+// results, and adds the test_result_detail.test_result_summary_id column,
+// backfilled from the summaries of the same artifacts.
+//
+// Added in v5.3.0.
+func migrateMakeTestResultsIndependentOfArtifacts(tx *gorm.DB) error {
+	if err := migrateColumnToNullable(tx, &database.TestResultSummary{}, database.TestResultSummaryFields.ArtifactID,
+		"testresultsummary_idx_artifact_id", "testresultsummary_idx_build_id"); err != nil {
+		return err
+	}
+	if err := migrateColumnToNullable(tx, &database.TestResultDetail{}, database.TestResultDetailFields.ArtifactID,
+		"testresultdetail_idx_artifact_id", "testresultdetail_idx_build_id"); err != nil {
+		return err
+	}
+	if err := addMissingColumns(tx, &database.TestResultDetail{}, database.TestResultDetailFields.TestResultSummaryID); err != nil {
+		return err
+	}
+	if err := createMissingIndexes(tx, &database.TestResultDetail{}, "testresultdetail_idx_test_result_summary_id"); err != nil {
+		return err
+	}
+	return tx.Exec(fmt.Sprintf(
+		"UPDATE %[1]s SET %[2]s = (SELECT MIN(s.%[2]s) FROM %[3]s s WHERE s.%[4]s = %[1]s.%[5]s)",
+		database.TestResultDetailTable, database.TestResultDetailColumns.TestResultSummaryID,
+		database.TestResultSummaryTable, database.TestResultSummaryColumns.ArtifactID,
+		database.TestResultDetailColumns.ArtifactID,
+	)).Error
+}
+
+// migrateColumnToNullable drops the "not null" constraint of the column. The
+// Sqlite migrator recreates the table, which drops its indexes, so the given
+// indexes are created again afterwards.
+func migrateColumnToNullable(tx *gorm.DB, model any, fieldName string, indexes ...string) error {
+	switch DBDriver(tx.Dialector.Name()) {
+	case DBDriverPostgres:
+		stmt := tx.Model(model).Statement
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		field := stmt.Schema.LookUpField(fieldName)
+		if field == nil {
+			return fmt.Errorf("unknown field when changing to nullable: %q", fieldName)
+		}
+		return tx.Exec(fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" DROP NOT NULL`,
+			stmt.Schema.Table, field.DBName)).Error
+	case DBDriverSqlite:
+		if err := tx.Migrator().AlterColumn(model, fieldName); err != nil {
+			return err
+		}
+		for _, index := range indexes {
+			if tx.Migrator().HasIndex(model, index) {
+				continue
+			}
+			if err := tx.Migrator().CreateIndex(model, index); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("migrating column to nullable has not been implemented for this DB driver: %q", tx.Dialector.Name())
+	}
+}
+
+// logMessageSearchIndex is the name of the PostgreSQL full-text search index
+// on the log.message column.
+const logMessageSearchIndex = "log_idx_message_search"
+
+// migrateAddLogMessageSearchIndex adds an expression index for full-text
+// searches on the log.message column. Only PostgreSQL is supported, while
+// searches in other databases fall back to LIKE queries.
+//
+// Added in v5.3.0.
+func migrateAddLogMessageSearchIndex(tx *gorm.DB) error {
+	if DBDriver(tx.Dialector.Name()) != DBDriverPostgres {
+		return nil
+	}
+	return tx.Exec(fmt.Sprintf(
+		"CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (to_tsvector('%s', %s))",
+		logMessageSearchIndex, database.LogTable, fullTextSearchConfig, database.LogColumns.Message,
+	)).Error
+}
+
+func rollbackAddLogMessageSearchIndex(tx *gorm.DB) error {
+	if DBDriver(tx.Dialector.Name()) != DBDriverPostgres {
+		return nil
+	}
+	return tx.Exec("DROP INDEX IF EXISTS " + logMessageSearchIndex).Error
+}
+
+// migrateAddBranchLastBuild adds the branch.last_build_id and
+// branch.last_built_on columns and backfills them from the existing builds.
+//
+// Added in v5.3.0.
+func migrateAddBranchLastBuild(tx *gorm.DB) error {
+	if err := addMissingColumns(tx, &database.Branch{},
+		database.BranchFields.LastBuildID, database.BranchFields.LastBuiltOn); err != nil {
+		return err
+	}
+	return updateBranchesLastBuild(tx, nil)
+}
+
+// migrateAddBuildGroupTable adds the build_group table and the
+// build.build_group_id column.
+//
+// Added in v5.3.0.
+func migrateAddBuildGroupTable(tx *gorm.DB) error {
+	if err := createMissingTables(tx, &database.BuildGroup{}); err != nil {
+		return err
+	}
+	if err := addMissingColumns(tx, &database.Build{}, database.BuildFields.BuildGroupID); err != nil {
+		return err
+	}
+	return createMissingIndexes(tx, &database.Build{}, "build_idx_build_group_id")
+}
+
+// migrateAddInstanceIDColumns adds the project.instance_id and
+// build.instance_id columns. Existing projects and builds are left with an
+// empty instance ID.
+//
+// Added in v5.3.0.
+func migrateAddInstanceIDColumns(tx *gorm.DB) error {
+	if err := addMissingColumns(tx, &database.Project{}, database.ProjectFields.InstanceID); err != nil {
+		return err
+	}
+	if err := createMissingIndexes(tx, &database.Project{}, "project_idx_instance_id"); err != nil {
+		return err
+	}
+	if err := addMissingColumns(tx, &database.Build{}, database.BuildFields.InstanceID); err != nil {
+		return err
+	}
+	return createMissingIndexes(tx, &database.Build{}, "build_idx_instance_id")
+}
+
+// migrateAddProjectGroupTable adds the project_group table and populates it
+// from the group names of all existing projects.
+//
+// Added in v5.3.0.
+func migrateAddProjectGroupTable(tx *gorm.DB) error {
+	if err := createMissingTables(tx, &database.ProjectGroup{}); err != nil {
+		return err
+	}
+	var groupNames []string
+	if err := tx.
+		Model(&database.Project{}).
+		Distinct().
+		Pluck(database.ProjectColumns.GroupName, &groupNames).Error; err != nil {
+		return err
+	}
+	for _, groupName := range groupNames {
+		if err := ensureProjectGroups(tx, groupName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAddBuildNumber adds the build.build_number and
+// project.last_build_number columns, and numbers the existing builds of each
+// project in the order they were created. The builds are numbered even if the
+// columns already exist, as no builds have been numbered before this migration.
+//
+// Added in v5.3.0.
+func migrateAddBuildNumber(tx *gorm.DB) error {
+	if err := addMissingColumns(tx, &database.Project{}, database.ProjectFields.LastBuildNumber); err != nil {
+		return err
+	}
+	if err := addMissingColumns(tx, &database.Build{}, database.BuildFields.BuildNumber); err != nil {
+		return err
+	}
+	if err := tx.Exec(fmt.Sprintf(
+		"UPDATE %[1]s SET %[2]s = (SELECT COUNT(*) FROM %[1]s AS b WHERE b.%[3]s = %[1]s.%[3]s AND b.%[4]s <= %[1]s.%[4]s)",
+		database.BuildTable, database.BuildColumns.BuildNumber,
+		database.BuildColumns.ProjectID, database.BuildColumns.BuildID,
+	)).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(fmt.Sprintf(
+		"UPDATE %[1]s SET %[2]s = (SELECT COALESCE(MAX(%[3]s.%[4]s), 0) FROM %[3]s WHERE %[3]s.%[5]s = %[1]s.%[6]s)",
+		database.ProjectTable, database.ProjectColumns.LastBuildNumber,
+		database.BuildTable, database.BuildColumns.BuildNumber,
+		database.BuildColumns.ProjectID, database.ProjectColumns.ProjectID,
+	)).Error; err != nil {
+		return err
+	}
+	return createMissingIndexes(tx, &database.Build{}, "build_idx_project_id_build_number")
+}
+
+// schemaTables are the models of all tables in the database schema.
+var schemaTables = []any{
+	&database.Token{}, &database.Provider{},
+	&database.Project{}, &database.ProjectOverrides{},
+	&database.ProjectGroup{},
+	&database.Branch{}, &database.Environment{},
+	&database.Build{}, &database.Log{},
+	&database.Artifact{}, &database.BuildParam{}, &database.Param{},
+	&database.TestResultDetail{}, &database.TestResultSummary{},
+	&database.Setting{}, &database.IdempotencyKey{},
+	&database.ProviderSync{}, &database.BuildGroup{},
+	&database.BuildTrigger{},
+	&database.ProjectLabel{}, &database.BuildLabel{},
+	&database.BuildComment{}, &database.BuildLink{},
+	&database.BuildStageResult{}, &database.EngineToken{},
+	&database.ProjectInputDefault{}, &database.BuildStatsDaily{},
+	&database.ProjectEngine{}, &database.BuildStatusTransition{},
+	&database.NotificationRule{}, &database.BuildNotification{},
+	&database.ProjectSubscription{}, &database.ProjectWebhook{},
+	&database.GitTrigger{}, &database.ReleaseArtifact{},
+	&database.ProjectTransfer{},
 }
 
 // migrateInitSchema is called when no previous migrations were found, while
@@ -32,19 +591,15 @@ func migrateInitSchema(db *gorm.DB) error {
 	if err := migrateBeforeGormigrate(db); err != nil {
 		return err
 	}
-	tables := []any{
-		&database.Token{}, &database.Provider{},
-		&database.Project{}, &database.ProjectOverrides{},
-		&database.Branch{}, &database.Build{}, &database.Log{},
-		&database.Artifact{}, &database.BuildParam{}, &database.Param{},
-		&database.TestResultDetail{}, &database.TestResultSummary{},
-	}
 	db.DisableForeignKeyConstraintWhenMigrating = true
-	if err := db.AutoMigrate(tables...); err != nil {
+	if err := db.AutoMigrate(schemaTables...); err != nil {
 		return err
 	}
 	db.DisableForeignKeyConstraintWhenMigrating = false
-	return db.AutoMigrate(tables...)
+	if err := db.AutoMigrate(schemaTables...); err != nil {
+		return err
+	}
+	return migrateAddLogMessageSearchIndex(db)
 }
 
 func newMigrator(db *gorm.DB) *gormigrate.Gormigrate {
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRunDatabaseMigrations_upgradeFromBaseline(t *testing.T) {
+	db, err := openDatabase(DBConfig{Driver: DBDriverSqlite, Path: ":memory:"})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Each connection to ":memory:" gets its own database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// Schema as created by wharf-api v5.2.0, before any migrations were added.
+	schema, err := os.ReadFile("testdata/migrations/baseline-sqlite.sql")
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(string(schema)).Error)
+	require.NoError(t, db.Exec(`INSERT INTO project (project_id, name) VALUES (1, 'a'), (2, 'b')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO build (build_id, status_id, project_id, started_on, completed_on) VALUES
+		(1, 3, 1, '2022-05-10 10:00:00', '2022-05-10 10:01:00'),
+		(2, 3, 2, NULL, NULL),
+		(3, 3, 1, NULL, NULL)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO artifact (artifact_id, build_id, name, file_name, data) VALUES
+		(1, 1, 'report', 'report.txt', 'hello')`).Error)
+
+	require.NoError(t, runDatabaseMigrations(db, DBDriverSqlite))
+
+	var dbBuilds []database.Build
+	require.NoError(t, db.Order(database.BuildColumns.BuildID).Find(&dbBuilds).Error)
+	require.Len(t, dbBuilds, 3)
+	assert.Equal(t, uint(1), dbBuilds[0].BuildNumber, "build 1")
+	assert.Equal(t, uint(1), dbBuilds[1].BuildNumber, "build 2")
+	assert.Equal(t, uint(2), dbBuilds[2].BuildNumber, "build 3")
+	assert.Equal(t, int64(60000), dbBuilds[0].Duration.Int64, "build 1 duration")
+
+	var dbProjects []database.Project
+	require.NoError(t, db.Order(database.ProjectColumns.ProjectID).Find(&dbProjects).Error)
+	require.Len(t, dbProjects, 2)
+	assert.Equal(t, uint(2), dbProjects[0].LastBuildNumber, "project 1")
+	assert.Equal(t, uint(1), dbProjects[1].LastBuildNumber, "project 2")
+
+	var dbArtifact database.Artifact
+	require.NoError(t, db.First(&dbArtifact, 1).Error)
+	assert.Equal(t, int64(5), dbArtifact.Size)
+
+	assertSchemaUpToDate(t, db)
+}
+
+// assertSchemaUpToDate asserts that all tables, columns, and indexes of the
+// database schema exist, as when created by migrateInitSchema.
+func assertSchemaUpToDate(t *testing.T, db *gorm.DB) {
+	for _, model := range schemaTables {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		table := stmt.Schema.Table
+		if !assert.True(t, db.Migrator().HasTable(model), table) {
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", table, field.DBName)
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			assert.True(t, db.Migrator().HasIndex(model, index.Name), "%s: %s", table, index.Name)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gorm.io/gorm"
+)
+
+type notificationRuleModule struct {
+	Database *gorm.DB
+	Config   *Config
+}
+
+func (m notificationRuleModule) Register(g *gin.RouterGroup) {
+	notificationRule := g.Group("/notification-rule")
+	{
+		notificationRule.GET("", m.getNotificationRuleListHandler)
+		notificationRule.POST("", m.createNotificationRuleHandler)
+
+		ruleByID := notificationRule.Group("/:notificationRuleId")
+		{
+			ruleByID.GET("", m.getNotificationRuleHandler)
+			ruleByID.PUT("", m.updateNotificationRuleHandler)
+			ruleByID.DELETE("", m.deleteNotificationRuleHandler)
+		}
+	}
+}
+
+// getNotificationRuleListHandler godoc
+// @id getNotificationRuleList
+// @summary Get list of notification rules.
+// @description List all notification rules, which send notifications about
+// @description builds that have stayed in the Scheduling or Running status
+// @description for longer than the rules' thresholds.
+// @description Added in v5.3.0.
+// @tags notification-rule
+// @produce json
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
+// @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param projectId query uint false "Filter by project ID. Rules that apply to all projects are not included when filtering." minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedNotificationRules "Notification rules"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /notification-rule [get]
+func (m notificationRuleModule) getNotificationRuleListHandler(c *gin.Context) {
+	var params = struct {
+		commonGetQueryParams
+
+		ProjectID *uint `form:"projectId"`
+	}{
+		commonGetQueryParams: defaultCommonGetQueryParams,
+	}
+	if !bindCommonGetQueryParams(c, &params) {
+		return
+	}
+
+	query := m.Database.
+		Model(&database.NotificationRule{}).
+		Order(database.NotificationRuleColumns.NotificationRuleID)
+	if params.ProjectID != nil {
+		query = query.Where(database.NotificationRuleColumns.ProjectID+" = ?", *params.ProjectID)
+	}
+
+	var dbRules []database.NotificationRule
+	var totalCount int64
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbRules, &totalCount)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching list of notification rules from database.")
+		return
+	}
+	ginrender.Response(c, http.StatusOK, response.PaginatedNotificationRules{
+		List:       modelconv.DBNotificationRulesToResponses(dbRules),
+		TotalCount: totalCount,
+	})
+}
+
+// getNotificationRuleHandler godoc
+// @id getNotificationRule
+// @summary Get notification rule.
+// @description Added in v5.3.0.
+// @tags notification-rule
+// @produce json
+// @param notificationRuleId path uint true "notification rule ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.NotificationRule "Notification rule"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Notification rule not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /notification-rule/{notificationRuleId} [get]
+func (m notificationRuleModule) getNotificationRuleHandler(c *gin.Context) {
+	dbRule, ok := m.fetchNotificationRuleFromParams(c, "")
+	if !ok {
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBNotificationRuleToResponse(dbRule))
+}
+
+// createNotificationRuleHandler godoc
+// @id createNotificationRule
+// @summary Add notification rule.
+// @description Adds a notification rule, which sends a notification once per
+// @description build when a build, of the rule's project or of any project,
+// @description has stayed in the given status for longer than the threshold.
+// @description The builds are checked as often as the build timeouts, which
+// @description is configured by ci.timeoutCheckInterval.
+// @description Added in v5.3.0.
+// @tags notification-rule
+// @accept json
+// @produce json
+// @param rule body request.NotificationRule true "Notification rule object"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 201 {object} response.NotificationRule "Created notification rule"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 409 {object} problem.Response "Email notifier is used but no SMTP server is configured"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /notification-rule [post]
+func (m notificationRuleModule) createNotificationRuleHandler(c *gin.Context) {
+	var reqRule request.NotificationRule
+	if err := c.ShouldBindJSON(&reqRule); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for notification rule object to create.")
+		return
+	}
+	if !m.validateNotificationRule(c, reqRule) {
+		return
+	}
+	dbRule := modelconv.ReqNotificationRuleToDatabase(reqRule)
+	if err := m.Database.Create(&dbRule).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed creating notification rule with name %q.",
+			reqRule.Name))
+		return
+	}
+	ginrender.Response(c, http.StatusCreated, modelconv.DBNotificationRuleToResponse(dbRule))
+}
+
+// updateNotificationRuleHandler godoc
+// @id updateNotificationRule
+// @summary Update notification rule.
+// @description Updates a notification rule by replacing all of its fields.
+// @description Builds that have already been notified about by the rule are
+// @description not notified about again.
+// @description Added in v5.3.0.
+// @tags notification-rule
+// @accept json
+// @produce json
+// @param notificationRuleId path uint true "notification rule ID" minimum(0)
+// @param rule body request.NotificationRule true "New notification rule values"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.NotificationRule "Updated notification rule"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Notification rule or project not found"
+// @failure 409 {object} problem.Response "Email notifier is used but no SMTP server is configured"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /notification-rule/{notificationRuleId} [put]
+func (m notificationRuleModule) updateNotificationRuleHandler(c *gin.Context) {
+	var reqRule request.NotificationRule
+	if err := c.ShouldBindJSON(&reqRule); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for notification rule object to update.")
+		return
+	}
+	dbRule, ok := m.fetchNotificationRuleFromParams(c, "when updating notification rule")
+	if !ok {
+		return
+	}
+	if !m.validateNotificationRule(c, reqRule) {
+		return
+	}
+	dbRuleUpdate := modelconv.ReqNotificationRuleToDatabase(reqRule)
+	dbRule.Name = dbRuleUpdate.Name
+	dbRule.ProjectID = dbRuleUpdate.ProjectID
+	dbRule.StatusID = dbRuleUpdate.StatusID
+	dbRule.Threshold = dbRuleUpdate.Threshold
+	dbRule.Notifier = dbRuleUpdate.Notifier
+	dbRule.Target = dbRuleUpdate.Target
+	if err := m.Database.Save(&dbRule).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed writing notification rule with ID %d to database.",
+			dbRule.NotificationRuleID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBNotificationRuleToResponse(dbRule))
+}
+
+// deleteNotificationRuleHandler godoc
+// @id deleteNotificationRule
+// @summary Delete notification rule.
+// @description Added in v5.3.0.
+// @tags notification-rule
+// @param notificationRuleId path uint true "notification rule ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Notification rule not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /notification-rule/{notificationRuleId} [delete]
+func (m notificationRuleModule) deleteNotificationRuleHandler(c *gin.Context) {
+	dbRule, ok := m.fetchNotificationRuleFromParams(c, "when deleting notification rule")
+	if !ok {
+		return
+	}
+	if err := m.Database.Delete(&dbRule).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting notification rule with ID %d from database.",
+			dbRule.NotificationRuleID))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (m notificationRuleModule) fetchNotificationRuleFromParams(c *gin.Context, whenMsg string) (database.NotificationRule, bool) {
+	ruleID, ok := ginutil.ParseParamUint(c, "notificationRuleId")
+	if !ok {
+		return database.NotificationRule{}, false
+	}
+	var dbRule database.NotificationRule
+	err := m.Database.First(&dbRule, ruleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Notification rule with ID %d was not found%s.",
+			ruleID, spaceWhenMessage(whenMsg)))
+		return database.NotificationRule{}, false
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching notification rule with ID %d%s.",
+			ruleID, spaceWhenMessage(whenMsg)))
+		return database.NotificationRule{}, false
+	}
+	return dbRule, true
+}
+
+func (m notificationRuleModule) validateNotificationRule(c *gin.Context, reqRule request.NotificationRule) bool {
+	writeInvalid := func(field, detail string) bool {
+		ginutil.WriteProblem(c, problems.InvalidParam.With(problem.Response{
+			Detail:   detail,
+			Instance: c.Request.RequestURI + "#" + field,
+		}))
+		return false
+	}
+	if len(reqRule.Name) > database.NotificationRuleSizes.Name {
+		return writeInvalid("name", fmt.Sprintf(
+			"The name must not be longer than %d characters, but was %d characters long.",
+			database.NotificationRuleSizes.Name, len(reqRule.Name)))
+	}
+	if reqRule.Status != request.BuildScheduling && reqRule.Status != request.BuildRunning {
+		return writeInvalid("status", fmt.Sprintf(
+			"The status must be either %q or %q, but was %q.",
+			request.BuildScheduling, request.BuildRunning, reqRule.Status))
+	}
+	if reqRule.Threshold <= 0 {
+		return writeInvalid("threshold", fmt.Sprintf(
+			"The threshold must be a positive number of seconds, but was %d.",
+			reqRule.Threshold))
+	}
+	if len(reqRule.Target) > database.NotificationRuleSizes.Target {
+		return writeInvalid("target", fmt.Sprintf(
+			"The target must not be longer than %d characters, but was %d characters long.",
+			database.NotificationRuleSizes.Target, len(reqRule.Target)))
+	}
+	switch reqRule.Notifier {
+	case request.NotifierWebhook, request.NotifierSlack, request.NotifierTeams:
+		if u, err := url.Parse(reqRule.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return writeInvalid("target", fmt.Sprintf(
+				"The target of the %q notifier must be an absolute HTTP or HTTPS URL.",
+				reqRule.Notifier))
+		}
+	case request.NotifierEmail:
+		if _, err := mail.ParseAddressList(reqRule.Target); err != nil {
+			return writeInvalid("target", fmt.Sprintf(
+				"The target of the %q notifier must be a comma-separated list of email addresses: %s.",
+				reqRule.Notifier, err))
+		}
+		if m.Config.Notification.SMTP.Host == "" {
+			ginutil.WriteProblem(c, problems.NotificationNoSMTP.With(problem.Response{
+				Detail:   "Notification rules cannot send emails, as no SMTP server is configured in wharf-api.",
+				Instance: c.Request.RequestURI + "#notifier",
+			}))
+			return false
+		}
+	default:
+		return writeInvalid("notifier", fmt.Sprintf(
+			"The notifier must be one of %q, %q, %q, or %q, but was %q.",
+			request.NotifierWebhook, request.NotifierSlack, request.NotifierTeams, request.NotifierEmail,
+			reqRule.Notifier))
+	}
+	if reqRule.ProjectID != nil &&
+		!validateProjectExistsByID(c, m.Database, *reqRule.ProjectID, "when validating project of notification rule") {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateNotificationRuleHandler_validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testCases := []struct {
+		name     string
+		body     string
+		smtpHost string
+		want     int
+		wantType string
+	}{
+		{
+			name: "webhook",
+			body: `{"name":"slow","status":"Running","threshold":3600,"notifier":"webhook","target":"https://example.com/hook"}`,
+			want: http.StatusCreated,
+		},
+		{
+			name:     "invalid status",
+			body:     `{"status":"Completed","threshold":3600,"notifier":"webhook","target":"https://example.com/hook"}`,
+			want:     http.StatusBadRequest,
+			wantType: "/prob/api/invalid-param",
+		},
+		{
+			name:     "negative threshold",
+			body:     `{"status":"Running","threshold":-1,"notifier":"webhook","target":"https://example.com/hook"}`,
+			want:     http.StatusBadRequest,
+			wantType: "/prob/api/invalid-param",
+		},
+		{
+			name:     "relative URL",
+			body:     `{"status":"Running","threshold":3600,"notifier":"slack","target":"/hook"}`,
+			want:     http.StatusBadRequest,
+			wantType: "/prob/api/invalid-param",
+		},
+		{
+			name:     "unknown notifier",
+			body:     `{"status":"Running","threshold":3600,"notifier":"pager","target":"https://example.com/hook"}`,
+			want:     http.StatusBadRequest,
+			wantType: "/prob/api/invalid-param",
+		},
+		{
+			name:     "email without SMTP",
+			body:     `{"status":"Running","threshold":3600,"notifier":"email","target":"alice@example.com"}`,
+			want:     http.StatusConflict,
+			wantType: "/prob/api/notification/no-smtp",
+		},
+		{
+			name:     "email",
+			body:     `{"status":"Running","threshold":3600,"notifier":"email","target":"alice@example.com, bob@example.com"}`,
+			smtpHost: "smtp.example.com",
+			want:     http.StatusCreated,
+		},
+		{
+			name:     "invalid email",
+			body:     `{"status":"Running","threshold":3600,"notifier":"email","target":"alice"}`,
+			smtpHost: "smtp.example.com",
+			want:     http.StatusBadRequest,
+			wantType: "/prob/api/invalid-param",
+		},
+		{
+			name:     "unknown project",
+			body:     `{"projectId":123,"status":"Running","threshold":3600,"notifier":"webhook","target":"https://example.com/hook"}`,
+			want:     http.StatusBadGateway,
+			wantType: "/prob/api/record-not-found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{}
+			config.Notification.SMTP.Host = tc.smtpHost
+			m := notificationRuleModule{Database: newTestSqliteDB(t), Config: config}
+			r := gin.New()
+			r.POST("/notification-rule", m.createNotificationRuleHandler)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/notification-rule", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.want, w.Code, w.Body.String())
+			if tc.wantType != "" {
+				assert.Contains(t, w.Body.String(), tc.wantType)
+			}
+		})
+	}
+}
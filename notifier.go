@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// notifier sends build notifications to a target, such as a webhook URL or a
+// list of email addresses.
+type notifier interface {
+	notify(target string, n response.BuildNotification) error
+}
+
+// newNotifiers returns the notifiers of each notifier type. The email notifier
+// is left out if no SMTP server is configured.
+func newNotifiers(config NotificationConfig) map[database.NotifierType]notifier {
+	client := &http.Client{Timeout: config.Timeout}
+	notifiers := map[database.NotifierType]notifier{
+		database.NotifierWebhook: webhookNotifier{client: client, payload: webhookPayload},
+		database.NotifierSlack:   webhookNotifier{client: client, payload: slackPayload},
+		database.NotifierTeams:   webhookNotifier{client: client, payload: teamsPayload},
+	}
+	if email := newEmailNotifier(config); email != nil {
+		notifiers[database.NotifierEmail] = email
+	}
+	return notifiers
+}
+
+// webhookNotifier sends notifications as JSON in HTTP POST requests to the
+// target URL.
+type webhookNotifier struct {
+	client  *http.Client
+	payload func(n response.BuildNotification) any
+}
+
+func (w webhookNotifier) notify(target string, n response.BuildNotification) error {
+	body, err := json.Marshal(w.payload(n))
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	resp, err := w.client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
+
+func webhookPayload(n response.BuildNotification) any {
+	return n
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func slackPayload(n response.BuildNotification) any {
+	return slackMessage{Text: n.Message}
+}
+
+// teamsMessageCard is the legacy actionable message card format, which is
+// accepted by Microsoft Teams incoming webhooks.
+type teamsMessageCard struct {
+	Type    string `json:"@type"`
+	Context string `json:"@context"`
+	Summary string `json:"summary"`
+	Title   string `json:"title"`
+	Text    string `json:"text"`
+}
+
+func teamsPayload(n response.BuildNotification) any {
+	return teamsMessageCard{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+		Summary: n.Message,
+		Title:   n.RuleName,
+		Text:    n.Message,
+	}
+}
+
+// sendMailFunc sends an email, with the same signature as smtp.SendMail
+// without the server address.
+type sendMailFunc func(from string, to []string, msg []byte) error
+
+// emailNotifier sends notifications as plain text emails to the
+// comma-separated email addresses of the target.
+type emailNotifier struct {
+	config   SMTPConfig
+	sendMail sendMailFunc
+}
+
+// newEmailNotifier returns an email notifier that sends emails via the
+// configured SMTP server, or nil if no SMTP server is configured.
+func newEmailNotifier(config NotificationConfig) *emailNotifier {
+	if config.SMTP.Host == "" {
+		return nil
+	}
+	return &emailNotifier{
+		config:   config.SMTP,
+		sendMail: newSMTPSendMail(config.SMTP, config.Timeout),
+	}
+}
+
+func (e *emailNotifier) notify(target string, n response.BuildNotification) error {
+	subject := fmt.Sprintf("Build %d of %s is %s for too long",
+		n.BuildID, n.ProjectName, strings.ToLower(string(n.Status)))
+	return e.sendEmail(target, subject, n.Message)
+}
+
+// sendEmail sends a plain text email to the comma-separated email addresses.
+func (e *emailNotifier) sendEmail(to, subject, body string) error {
+	from, err := mail.ParseAddress(e.config.From)
+	if err != nil {
+		return fmt.Errorf("parse from address: %w", err)
+	}
+	toAddrs, err := mail.ParseAddressList(to)
+	if err != nil {
+		return fmt.Errorf("parse target email addresses: %w", err)
+	}
+	recipients := make([]string, len(toAddrs))
+	toHeader := make([]string, len(toAddrs))
+	for i, addr := range toAddrs {
+		recipients[i] = addr.Address
+		toHeader[i] = addr.String()
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(toHeader, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+	msg.WriteString("\r\n")
+	return e.sendMail(from.Address, recipients, msg.Bytes())
+}
+
+// newSMTPSendMail returns a function that sends emails via the SMTP server,
+// using STARTTLS if the server supports it. Same as smtp.SendMail, but the
+// whole exchange with the server is limited by the timeout.
+func newSMTPSendMail(config SMTPConfig, timeout time.Duration) sendMailFunc {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	return func(from string, to []string, msg []byte) error {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return err
+		}
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			conn.Close()
+			return err
+		}
+		client, err := smtp.NewClient(conn, config.Host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		defer client.Close()
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: config.Host}); err != nil {
+				return fmt.Errorf("starttls: %w", err)
+			}
+		}
+		if config.Username != "" {
+			if ok, _ := client.Extension("AUTH"); !ok {
+				return errors.New("SMTP server does not support authentication")
+			}
+			auth := smtp.PlainAuth("", config.Username, config.Password, config.Host)
+			if err := client.Auth(auth); err != nil {
+				return fmt.Errorf("auth: %w", err)
+			}
+		}
+		if err := client.Mail(from); err != nil {
+			return err
+		}
+		for _, addr := range to {
+			if err := client.Rcpt(addr); err != nil {
+				return err
+			}
+		}
+		w, err := client.Data()
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(msg); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return client.Quit()
+	}
+}
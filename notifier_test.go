@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var gotBody string
+	var gotContentType string
+	statusCode := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotContentType = r.Header.Get("Content-Type")
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+
+	notifiers := newNotifiers(DefaultConfig.Notification)
+	n := response.BuildNotification{RuleName: "slow", BuildID: 5, Message: "Build 5 is slow."}
+
+	require.NoError(t, notifiers[database.NotifierSlack].notify(server.URL, n))
+	assert.Equal(t, "application/json", gotContentType)
+	assert.JSONEq(t, `{"text":"Build 5 is slow."}`, gotBody)
+
+	require.NoError(t, notifiers[database.NotifierTeams].notify(server.URL, n))
+	assert.JSONEq(t, `{"@type":"MessageCard","@context":"https://schema.org/extensions","summary":"Build 5 is slow.","title":"slow","text":"Build 5 is slow."}`, gotBody)
+
+	statusCode = http.StatusNotFound
+	err := notifiers[database.NotifierWebhook].notify(server.URL, n)
+	assert.EqualError(t, err, "unexpected response status: 404 Not Found")
+}
+
+func TestNewNotifiers_emailRequiresSMTPHost(t *testing.T) {
+	config := DefaultConfig.Notification
+	assert.NotContains(t, newNotifiers(config), database.NotifierEmail)
+	config.SMTP.Host = "smtp.example.com"
+	assert.Contains(t, newNotifiers(config), database.NotifierEmail)
+}
+
+func TestEmailNotifier(t *testing.T) {
+	var gotFrom string
+	var gotTo []string
+	var gotMsg string
+	e := &emailNotifier{
+		config: SMTPConfig{From: "Wharf <wharf@example.com>"},
+		sendMail: func(from string, to []string, msg []byte) error {
+			gotFrom, gotTo, gotMsg = from, to, string(msg)
+			return nil
+		},
+	}
+	n := response.BuildNotification{
+		BuildID:     5,
+		ProjectName: "wharf-api",
+		Status:      response.BuildRunning,
+		Message:     "Build 5 is slow.",
+	}
+
+	require.NoError(t, e.notify("alice@example.com, Bob <bob@example.com>", n))
+	assert.Equal(t, "wharf@example.com", gotFrom)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, gotTo)
+	assert.Contains(t, gotMsg, "From: \"Wharf\" <wharf@example.com>\r\n")
+	assert.Contains(t, gotMsg, "To: <alice@example.com>, \"Bob\" <bob@example.com>\r\n")
+	assert.Contains(t, gotMsg, "Subject: Build 5 of wharf-api is running for too long\r\n")
+	assert.Contains(t, gotMsg, "\r\n\r\nBuild 5 is slow.\r\n")
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/typ.v4/slices"
+)
+
+// oidcKeysRefetchCooldown is the minimum duration between refetching the
+// keys when encountering an unknown key ID. This prevents clients from
+// flooding the OIDC provider by sending tokens with random key IDs.
+const oidcKeysRefetchCooldown = time.Minute
+
+// oidcSupportedAlgorithms are the JWT signing algorithms that are accepted in
+// access tokens, per JSON Web Key type.
+var oidcSupportedAlgorithms = map[string][]string{
+	"RSA": {"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"},
+	"EC":  {"ES256", "ES384", "ES512"},
+}
+
+var errOIDCUnknownKeyID = errors.New("unknown key ID")
+
+// oidcKey is a public key used to verify the signatures of access tokens.
+type oidcKey struct {
+	publicKey crypto.PublicKey
+	// alg is the only signing algorithm allowed with this key, or empty if
+	// the JSON Web Key did not declare any.
+	alg string
+}
+
+// oidcKeySet holds the public keys of an OIDC provider, fetched from its JSON
+// Web Key Set (JWKS) URL.
+type oidcKeySet struct {
+	issuerURL string
+	keysURL   string
+	client    *http.Client
+
+	fetchMu       sync.Mutex
+	lastFetchedAt time.Time
+
+	mu   sync.RWMutex
+	keys map[string]oidcKey
+}
+
+// newOIDCKeySet creates a new key set. If the keys URL is empty, then it is
+// discovered from the issuer's OIDC discovery document on each fetch.
+func newOIDCKeySet(issuerURL, keysURL string) *oidcKeySet {
+	return &oidcKeySet{
+		issuerURL: issuerURL,
+		keysURL:   keysURL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// refresh fetches the keys and replaces all previously fetched keys.
+func (s *oidcKeySet) refresh() error {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	return s.refreshLocked()
+}
+
+func (s *oidcKeySet) refreshLocked() error {
+	s.lastFetchedAt = time.Now()
+	keysURL := s.keysURL
+	if keysURL == "" {
+		var err error
+		keysURL, err = discoverOIDCKeysURL(s.client, s.issuerURL)
+		if err != nil {
+			return err
+		}
+	}
+	keys, err := fetchOIDCKeys(s.client, keysURL)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	log.Debug().
+		WithString("url", keysURL).
+		WithInt("keys", len(keys)).
+		Message("Updated keys for OIDC.")
+	return nil
+}
+
+// key returns the key with the given key ID. Unknown key IDs trigger a refetch
+// of the keys, to support the OIDC provider rotating its keys, unless the keys
+// were fetched within the refetch cooldown.
+func (s *oidcKeySet) key(kid string) (oidcKey, error) {
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(s.lastFetchedAt) < oidcKeysRefetchCooldown {
+		return oidcKey{}, errOIDCUnknownKeyID
+	}
+	log.Debug().
+		WithString("kid", kid).
+		Message("Refetching OIDC keys because of unknown key ID.")
+	if err := s.refreshLocked(); err != nil {
+		log.Warn().WithError(err).Message("Failed to refetch OIDC keys.")
+		return oidcKey{}, errOIDCUnknownKeyID
+	}
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return oidcKey{}, errOIDCUnknownKeyID
+}
+
+func (s *oidcKeySet) lookup(kid string) (oidcKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// discoverOIDCKeysURL returns the "jwks_uri" from the issuer's OIDC discovery
+// document.
+func discoverOIDCKeysURL(client *http.Client, issuerURL string) (string, error) {
+	discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	var body struct {
+		KeysURL string `json:"jwks_uri"`
+	}
+	if err := getJSON(client, discoveryURL, &body); err != nil {
+		return "", fmt.Errorf("OIDC discovery: %w", err)
+	}
+	if body.KeysURL == "" {
+		return "", fmt.Errorf("OIDC discovery: missing 'jwks_uri' field in %s", discoveryURL)
+	}
+	return body.KeysURL, nil
+}
+
+// jsonWebKey is a single key from a JSON Web Key Set, as defined in RFC 7517.
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA key fields
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC key fields
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// fetchOIDCKeys returns the signing keys from a JSON Web Key Set, mapped by
+// their key IDs. Keys that are not meant for signatures, or that use
+// unsupported key types or algorithms, are skipped.
+func fetchOIDCKeys(client *http.Client, keysURL string) (map[string]oidcKey, error) {
+	var body struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(client, keysURL, &body); err != nil {
+		return nil, fmt.Errorf("fetch OIDC keys: %w", err)
+	}
+	keys := make(map[string]oidcKey, len(body.Keys))
+	for _, jwk := range body.Keys {
+		if jwk.KeyID == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			log.Warn().
+				WithError(err).
+				WithString("kid", jwk.KeyID).
+				WithString("kty", jwk.KeyType).
+				Message("Skipping unsupported OIDC key.")
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("fetch OIDC keys: no supported signing keys found in %s", keysURL)
+	}
+	return keys, nil
+}
+
+func parseJSONWebKey(jwk jsonWebKey) (oidcKey, error) {
+	supportedAlgs, ok := oidcSupportedAlgorithms[jwk.KeyType]
+	if !ok {
+		return oidcKey{}, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+	if jwk.Algorithm != "" && !slices.Contains(supportedAlgs, jwk.Algorithm) {
+		return oidcKey{}, fmt.Errorf("unsupported algorithm %q for key type %q", jwk.Algorithm, jwk.KeyType)
+	}
+	var publicKey crypto.PublicKey
+	var err error
+	switch jwk.KeyType {
+	case "RSA":
+		publicKey, err = parseRSAJSONWebKey(jwk)
+	case "EC":
+		publicKey, err = parseECJSONWebKey(jwk)
+	}
+	if err != nil {
+		return oidcKey{}, err
+	}
+	return oidcKey{publicKey: publicKey, alg: jwk.Algorithm}, nil
+}
+
+func parseRSAJSONWebKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := decodeJSONWebKeyInt(jwk.N, "n")
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeJSONWebKeyInt(jwk.E, "e")
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid RSA exponent: %s", e)
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func parseECJSONWebKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Curve {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported elliptic curve %q", jwk.Curve)
+	}
+	x, err := decodeJSONWebKeyInt(jwk.X, "x")
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJSONWebKeyInt(jwk.Y, "y")
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("point is not on elliptic curve %q", jwk.Curve)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeJSONWebKeyInt(value, field string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing JWK %q field", field)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decode JWK %q field: %w", field, err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func getJSON(client *http.Client, url string, v any) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("http GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http GET %s: non-200 response: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response from %s: %w", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeJWKInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func newTestJWKSServer(t *testing.T, keys *[]jsonWebKey) (*httptest.Server, *int) {
+	var fetches int
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": *keys})
+	})
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func TestFetchOIDCKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := []jsonWebKey{
+		{KeyType: "RSA", KeyID: "rsa", Use: "sig", Algorithm: "RS256",
+			N: encodeJWKInt(rsaKey.N), E: encodeJWKInt(big.NewInt(3))},
+		{KeyType: "EC", KeyID: "ec", Curve: "P-256",
+			X: encodeJWKInt(ecKey.X), Y: encodeJWKInt(ecKey.Y)},
+		{KeyType: "RSA", KeyID: "enc", Use: "enc",
+			N: encodeJWKInt(rsaKey.N), E: encodeJWKInt(big.NewInt(65537))},
+		{KeyType: "RSA", KeyID: "hmac", Algorithm: "HS256",
+			N: encodeJWKInt(rsaKey.N), E: encodeJWKInt(big.NewInt(65537))},
+		{KeyType: "oct", KeyID: "oct"},
+	}
+	server, _ := newTestJWKSServer(t, &keys)
+
+	got, err := fetchOIDCKeys(server.Client(), server.URL+"/keys")
+	require.NoError(t, err)
+
+	require.Len(t, got, 2)
+	require.IsType(t, &rsa.PublicKey{}, got["rsa"].publicKey)
+	assert.Equal(t, 3, got["rsa"].publicKey.(*rsa.PublicKey).E)
+	assert.Equal(t, "RS256", got["rsa"].alg)
+	require.IsType(t, &ecdsa.PublicKey{}, got["ec"].publicKey)
+	assert.Equal(t, ecKey.X, got["ec"].publicKey.(*ecdsa.PublicKey).X)
+}
+
+func TestOIDCKeySet_discoveryAndRefetchOnUnknownKeyID(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := []jsonWebKey{
+		{KeyType: "EC", KeyID: "old", Curve: "P-256",
+			X: encodeJWKInt(ecKey.X), Y: encodeJWKInt(ecKey.Y)},
+	}
+	server, fetches := newTestJWKSServer(t, &keys)
+	keySet := newOIDCKeySet(server.URL+"/", "")
+	require.NoError(t, keySet.refresh())
+
+	keys[0].KeyID = "new"
+	_, err = keySet.key("new")
+	assert.ErrorIs(t, err, errOIDCUnknownKeyID, "within cooldown")
+	assert.Equal(t, 1, *fetches)
+
+	keySet.lastFetchedAt = time.Now().Add(-oidcKeysRefetchCooldown)
+	_, err = keySet.key("new")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *fetches)
+}
@@ -1,19 +1,15 @@
 package main
 
 import (
-	"crypto/rsa"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"math/big"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
-	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gopkg.in/typ.v4/slices"
 
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -37,103 +33,193 @@ import (
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// GetOIDCPublicKeys returns the public keys of the currently set WHARF_HTTP_OIDC_KEYSURL.
-func GetOIDCPublicKeys(keysURL string) (map[string]*rsa.PublicKey, error) {
-	rsaKeys := make(map[string]*rsa.PublicKey)
-	resp, err := http.Get(keysURL)
-	if err != nil {
-		return nil, fmt.Errorf("http GET keys URL: %w", err)
+func newOIDCMiddleware(config OIDCConfig) *oidcMiddleware {
+	var validMethods []string
+	for _, algs := range oidcSupportedAlgorithms {
+		validMethods = append(validMethods, algs...)
+	}
+	m := &oidcMiddleware{
+		config: config,
+		parser: &jwt.Parser{ValidMethods: validMethods},
+	}
+	if len(config.Issuers) == 0 {
+		m.issuers = []*oidcIssuer{{
+			config: OIDCIssuerConfig{
+				IssuerURL:   config.IssuerURL,
+				AudienceURL: config.AudienceURL,
+				KeysURL:     config.KeysURL,
+			},
+			keySet: newOIDCKeySet(config.IssuerURL, config.KeysURL),
+			// Kept for backward compatibility with the looser issuer check
+			// used before multiple issuers were supported.
+			matchContains: true,
+		}}
+		return m
+	}
+	for _, issuerConfig := range config.Issuers {
+		m.issuers = append(m.issuers, &oidcIssuer{
+			config: issuerConfig,
+			keySet: newOIDCKeySet(issuerConfig.IssuerURL, issuerConfig.KeysURL),
+		})
+	}
+	return m
+}
+
+type oidcMiddleware struct {
+	issuers []*oidcIssuer
+	config  OIDCConfig
+	parser  *jwt.Parser
+}
+
+// oidcIssuer is a trusted OIDC issuer, with its own audience and keys.
+type oidcIssuer struct {
+	config        OIDCIssuerConfig
+	keySet        *oidcKeySet
+	matchContains bool
+}
+
+func (iss *oidcIssuer) matches(claim string) bool {
+	if iss.matchContains {
+		return strings.Contains(claim, iss.config.IssuerURL)
 	}
-	var body struct {
-		Keys []struct {
-			KeyID  string `json:"kid"`
-			Number string `json:"n"`
-		} `json:"keys"`
+	return strings.TrimSuffix(claim, "/") == strings.TrimSuffix(iss.config.IssuerURL, "/")
+}
+
+func (iss *oidcIssuer) keyFunc(token *jwt.Token) (any, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("expected JWT to have string 'kid' field")
 	}
-	err = json.NewDecoder(resp.Body).Decode(&body)
+	key, err := iss.keySet.key(kid)
 	if err != nil {
-		return nil, fmt.Errorf("decode keys payload: %w", err)
-	}
-	log.Debug().Message("Updating keys for oidc.")
-	rsaExponent := 65537
-	for _, key := range body.Keys {
-		kid := key.KeyID
-		rsakey := new(rsa.PublicKey)
-		number, err := base64.RawURLEncoding.DecodeString(key.Number)
-		if err != nil {
-			return nil, fmt.Errorf("decode JWT 'n' field: %w", err)
-		}
-		rsakey.N = new(big.Int).SetBytes(number)
-		rsakey.E = rsaExponent
-		rsaKeys[kid] = rsakey
+		return nil, fmt.Errorf("kid %q: %w", kid, err)
+	}
+	if key.alg != "" && token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("kid %q: expected algorithm %q, but token used %q",
+			kid, key.alg, token.Method.Alg())
 	}
-	return rsaKeys, nil
+	return key.publicKey, nil
 }
 
-func newOIDCMiddleware(rsaKeys map[string]*rsa.PublicKey, config OIDCConfig) *oidcMiddleware {
-	return &oidcMiddleware{
-		rsaKeys: rsaKeys,
-		config:  config,
+// oidcProtectedReadRoutes are the routes that always require an access token,
+// even when anonymous reads are allowed via OIDCConfig.AllowAnonymousRead.
+var oidcProtectedReadRoutes = []string{
+	"/api/notification-rule",
+	"/api/notification-rule/:notificationRuleId",
+	"/api/token",
+	"/api/token/:tokenId",
+	"/api/tokens",
+	"/api/user/settings",
+}
+
+// allowsAnonymous returns true if the request may be sent without an access
+// token, based on the OIDCConfig.AllowAnonymousRead config and the route.
+func (m *oidcMiddleware) allowsAnonymous(c *gin.Context) bool {
+	if !m.config.AllowAnonymousRead {
+		return false
+	}
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
 	}
+	route := c.FullPath()
+	return !slices.Contains(oidcProtectedReadRoutes, route) &&
+		!slices.Contains(m.config.ProtectedReadRoutes, route)
 }
 
-type oidcMiddleware struct {
-	rsaKeys map[string]*rsa.PublicKey
-	config  OIDCConfig
+// refreshKeys fetches the keys of all issuers.
+func (m *oidcMiddleware) refreshKeys() error {
+	for _, iss := range m.issuers {
+		if err := iss.keySet.refresh(); err != nil {
+			return fmt.Errorf("issuer %q: %w", iss.config.IssuerURL, err)
+		}
+	}
+	return nil
+}
+
+// findIssuer returns the trusted issuer that matches the token's "iss" claim.
+// The token's signature is not validated.
+func (m *oidcMiddleware) findIssuer(tokenString string) (*oidcIssuer, string) {
+	token, _, err := m.parser.ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return nil, err.Error()
+	}
+	claim, ok := token.Claims.(jwt.MapClaims)["iss"].(string)
+	if !ok {
+		return nil, "invalid or missing 'iss' field: should be string."
+	}
+	for _, iss := range m.issuers {
+		if iss.matches(claim) {
+			return iss, ""
+		}
+	}
+	return nil, "invalid 'iss' field: disallowed issuer."
 }
 
 // VerifyTokenMiddleware is a gin middleware function that enforces validity of the access bearer token on every
 // request. This uses the environment vars WHARF_HTTP_OIDC_ISSUERURL and WHARF_HTTP_OIDC_AUDIENCEURL as limiters
-// that control the variety of tokens that pass validation.
+// that control the variety of tokens that pass validation, or the list of issuers in the http.oidc.issuers config.
 func (m *oidcMiddleware) VerifyTokenMiddleware(ginContext *gin.Context) {
-	if m.rsaKeys == nil {
-		ginutil.WriteProblem(ginContext, problem.Response{
-			Type:   "/prob/api/oidc/missing-rsa-keys",
-			Title:  "Missing OIDC public keys.",
-			Status: http.StatusInternalServerError,
-			Detail: "The OIDC RSA public keys were not properly set up during initialization of the wharf-api.",
-		})
-		ginContext.Abort()
+	tokenString := ginContext.Request.Header.Get("Authorization")
+	if tokenString == "" && m.allowsAnonymous(ginContext) {
 		return
 	}
-	isValid := false
-	errorMessage := ""
-	tokenString := ginContext.Request.Header.Get("Authorization")
 	if !strings.HasPrefix(tokenString, "Bearer ") {
 		ginutil.WriteUnauthorized(ginContext, "Expected authorization scheme to be 'Bearer' (case sensitive), but was not.")
 		ginContext.Abort()
 		return
 	}
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if kid, ok := token.Header["kid"].(string); ok {
-			return m.rsaKeys[kid], nil
+	iss, errorMessage := m.findIssuer(tokenString)
+	if iss != nil {
+		token, err := m.parser.Parse(tokenString, iss.keyFunc)
+		if err != nil {
+			errorMessage = err.Error()
+		} else if !token.Valid {
+			errorMessage = "invalid access bearer token."
+		} else if token.Header["alg"] == nil {
+			errorMessage = "missing 'alg' field."
+		} else if !token.Claims.(jwt.MapClaims).VerifyAudience(iss.config.AudienceURL, true) {
+			errorMessage = "invalid 'aud' field."
+		} else {
+			ginContext.Set(oidcClaimsContextKey, token.Claims.(jwt.MapClaims))
+			return
 		}
-		return nil, errors.New("expected JWT to have string 'kid' field")
-	})
-	if err != nil {
-		errorMessage = err.Error()
-	} else if !token.Valid {
-		errorMessage = "invalid access bearer token."
-	} else if token.Header["alg"] == nil {
-		errorMessage = "missing 'alg' field."
-	} else if token.Claims.(jwt.MapClaims)["aud"] != m.config.AudienceURL {
-		errorMessage = "invalid 'aud' field."
-	} else if iss, ok := token.Claims.(jwt.MapClaims)["iss"].(string); !ok {
-		errorMessage = "invalid or missing 'iss' field: should be string."
-	} else if !strings.Contains(iss, m.config.IssuerURL) {
-		errorMessage = "invalid 'iss' field: disallowed issuer."
-	} else {
-		isValid = true
 	}
-	if !isValid {
-		ginutil.WriteUnauthorized(ginContext, "Invalid JWT: "+errorMessage)
-		ginContext.Abort()
+	ginutil.WriteUnauthorized(ginContext, "Invalid JWT: "+errorMessage)
+	ginContext.Abort()
+}
+
+const oidcClaimsContextKey = "wharf-oidc-claims"
+
+// getOIDCClaims returns the claims of the access bearer token that was
+// validated by the VerifyTokenMiddleware, or false if none was found, such as
+// when OIDC is disabled.
+func getOIDCClaims(c *gin.Context) (jwt.MapClaims, bool) {
+	value, ok := c.Get(oidcClaimsContextKey)
+	if !ok {
+		return nil, false
 	}
+	claims, ok := value.(jwt.MapClaims)
+	return claims, ok
+}
+
+// getOIDCClaimString returns the first non-empty string claim out of the given
+// claim names.
+func getOIDCClaimString(claims jwt.MapClaims, names ...string) string {
+	for _, name := range names {
+		if value, ok := claims[name].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
 }
 
 // SubscribeToKeyURLUpdates ensures new keys are fetched as necessary.
 // As a standard OIDC login provider keys should be checked for updates ever 1 day 1 hour.
+//
+// Keys are also refetched when a token with an unknown key ID is encountered.
 func (m *oidcMiddleware) SubscribeToKeyURLUpdates() {
 	fetchOidcKeysTicker := time.NewTicker(m.config.UpdateInterval)
 	log.Debug().WithDuration("interval", m.config.UpdateInterval).
@@ -147,13 +233,11 @@ func (m *oidcMiddleware) SubscribeToKeyURLUpdates() {
 }
 
 func (m *oidcMiddleware) updateOIDCPublicKeys() {
-	newKeys, err := GetOIDCPublicKeys(m.config.KeysURL)
-	if err != nil {
+	if err := m.refreshKeys(); err != nil {
 		log.Warn().WithError(err).
 			WithDuration("interval", m.config.UpdateInterval).
 			Message("Failed to update OIDC public keys.")
 	} else {
-		m.rsaKeys = newKeys
 		log.Info().
 			WithDuration("interval", m.config.UpdateInterval).
 			Message("Successfully updated OIDC public keys.")
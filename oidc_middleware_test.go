@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOIDCIssuer struct {
+	url string
+	key *ecdsa.PrivateKey
+}
+
+func newTestOIDCIssuer(t *testing.T, kid string) testOIDCIssuer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := []jsonWebKey{
+		{KeyType: "EC", KeyID: kid, Algorithm: "ES256", Curve: "P-256",
+			X: encodeJWKInt(key.X), Y: encodeJWKInt(key.Y)},
+	}
+	server, _ := newTestJWKSServer(t, &keys)
+	return testOIDCIssuer{url: server.URL, key: key}
+}
+
+func (iss testOIDCIssuer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(iss.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestOIDCMiddleware_multipleIssuers(t *testing.T) {
+	issuerA := newTestOIDCIssuer(t, "a")
+	issuerB := newTestOIDCIssuer(t, "b")
+	m := newOIDCMiddleware(OIDCConfig{
+		Issuers: []OIDCIssuerConfig{
+			{IssuerURL: issuerA.url, AudienceURL: "api://a"},
+			{IssuerURL: issuerB.url + "/", AudienceURL: "api://b"},
+		},
+	})
+	require.NoError(t, m.refreshKeys())
+
+	testCases := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{
+			name:  "issuer A",
+			token: issuerA.sign(t, "a", jwt.MapClaims{"iss": issuerA.url, "aud": "api://a"}),
+			want:  http.StatusOK,
+		},
+		{
+			name:  "issuer B with audience list",
+			token: issuerB.sign(t, "b", jwt.MapClaims{"iss": issuerB.url, "aud": []string{"other", "api://b"}}),
+			want:  http.StatusOK,
+		},
+		{
+			name:  "audience of other issuer",
+			token: issuerB.sign(t, "b", jwt.MapClaims{"iss": issuerB.url, "aud": "api://a"}),
+			want:  http.StatusUnauthorized,
+		},
+		{
+			name:  "signed by other issuer",
+			token: issuerA.sign(t, "b", jwt.MapClaims{"iss": issuerB.url, "aud": "api://b"}),
+			want:  http.StatusUnauthorized,
+		},
+		{
+			name:  "unknown issuer",
+			token: issuerA.sign(t, "a", jwt.MapClaims{"iss": "https://evil.example.com", "aud": "api://a"}),
+			want:  http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			c.Request.Header.Set("Authorization", "Bearer "+tc.token)
+			m.VerifyTokenMiddleware(c)
+			c.Writer.WriteHeaderNow()
+			assert.Equal(t, tc.want, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestOIDCIssuer_keyFunc_algorithmMismatch(t *testing.T) {
+	issuer := newTestOIDCIssuer(t, "a")
+	m := newOIDCMiddleware(OIDCConfig{IssuerURL: issuer.url, KeysURL: issuer.url + "/keys"})
+	require.NoError(t, m.refreshKeys())
+
+	_, err := m.issuers[0].keyFunc(&jwt.Token{
+		Method: jwt.SigningMethodES384,
+		Header: map[string]any{"kid": "a"},
+	})
+	assert.Error(t, err)
+}
+
+func TestOIDCMiddleware_allowAnonymousRead(t *testing.T) {
+	issuer := newTestOIDCIssuer(t, "a")
+	m := newOIDCMiddleware(OIDCConfig{
+		IssuerURL:           issuer.url,
+		KeysURL:             issuer.url + "/keys",
+		AllowAnonymousRead:  true,
+		ProtectedReadRoutes: []string{"/api/build/:buildId/log"},
+	})
+	require.NoError(t, m.refreshKeys())
+	r := gin.New()
+	r.Use(m.VerifyTokenMiddleware)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/api/build/:buildId", ok)
+	r.PUT("/api/build/:buildId", ok)
+	r.GET("/api/build/:buildId/log", ok)
+	r.GET("/api/token", ok)
+	r.GET("/api/notification-rule", ok)
+	r.GET("/api/notification-rule/:notificationRuleId", ok)
+
+	testCases := []struct {
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{http.MethodGet, "/api/build/1", "", http.StatusOK},
+		{http.MethodPut, "/api/build/1", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/build/1/log", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/token", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/notification-rule", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/notification-rule/1", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/build/1", "Bearer invalid", http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", tc.token)
+			}
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.want, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/docs"
+	"github.com/iver-wharf/wharf-api/v5/internal/openapi3"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/swaggo/gin-swagger/swaggerFiles"
+)
+
+// openAPIDoc holds the OpenAPI 3.0 document, converted from the Swagger 2.0
+// document on first use, as the version in the Swagger document is set on
+// startup.
+var openAPIDoc struct {
+	once sync.Once
+	json []byte
+	err  error
+}
+
+// registerAPIDocs adds the Swagger UI and the API documents to the router
+// group.
+func registerAPIDocs(g *gin.RouterGroup) {
+	g.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	g.GET("/swagger.json", getSwaggerHandler)
+	g.GET("/openapi.json", getOpenAPIHandler)
+}
+
+// getSwaggerHandler godoc
+// @id getSwagger
+// @summary Returns the Swagger 2.0 document of this API
+// @description Same document as served by the Swagger UI at
+// @description `/api/swagger/doc.json`, but at a path that does not depend
+// @description on the Swagger UI, for use by tooling.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce json
+// @success 200 {object} object "Swagger 2.0 document"
+// @router /swagger.json [get]
+func getSwaggerHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(docs.SwaggerInfo.ReadDoc()))
+}
+
+// getOpenAPIHandler godoc
+// @id getOpenAPI
+// @summary Returns the OpenAPI 3.0 document of this API
+// @description Converted from the Swagger 2.0 document served at
+// @description `/api/swagger/doc.json`, using the same operation IDs.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce json
+// @success 200 {object} object "OpenAPI 3.0 document"
+// @failure 500 {object} problem.Response "Failed to convert the Swagger document"
+// @router /openapi.json [get]
+func getOpenAPIHandler(c *gin.Context) {
+	openAPIDoc.once.Do(func() {
+		openAPIDoc.json, openAPIDoc.err = openapi3.ConvertSwagger2([]byte(docs.SwaggerInfo.ReadDoc()))
+	})
+	if openAPIDoc.err != nil {
+		ginutil.WriteProblemError(c, openAPIDoc.err, problems.InternalServerError.New(
+			"Failed to convert the Swagger 2.0 document to OpenAPI 3.0."))
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPIDoc.json)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/swaggo/swag"
+)
+
+// paginationConfigContextKey is the gin.Context key of the pagination config
+// used when binding the "limit" query parameter.
+const paginationConfigContextKey = "wharf-pagination-config"
+
+// newPaginationMiddleware creates a Gin middleware that makes the pagination
+// config available to the list endpoints. Without it, the list endpoints fall
+// back on the default limit of DefaultConfig, and no max limit.
+func newPaginationMiddleware(config HTTPPaginationConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(paginationConfigContextKey, config)
+		c.Next()
+	}
+}
+
+// getRequestPaginationConfig returns the pagination config set by the
+// pagination middleware, or the pagination config of DefaultConfig if the
+// middleware is not used.
+func getRequestPaginationConfig(c *gin.Context) HTTPPaginationConfig {
+	if value, ok := c.Get(paginationConfigContextKey); ok {
+		if config, ok := value.(HTTPPaginationConfig); ok {
+			return config
+		}
+	}
+	return DefaultConfig.HTTP.Pagination
+}
+
+// applyPaginationConfig sets the limit to the configured default limit if the
+// "limit" query parameter is not set, and writes a problem response if the
+// limit exceeds the configured max limit.
+func applyPaginationConfig(c *gin.Context, params *paginationQueryParams) bool {
+	config := getRequestPaginationConfig(c)
+	if _, ok := c.GetQuery("limit"); !ok {
+		params.Limit = config.DefaultLimit
+	}
+	if config.MaxLimit > 0 && (params.Limit <= 0 || params.Limit > config.MaxLimit) {
+		err := fmt.Errorf("limit out of range: %d not in 1-%d", params.Limit, config.MaxLimit)
+		ginutil.WriteInvalidParamError(c, err, "limit", fmt.Sprintf(
+			"The limit must be between 1 and %d, but was: %d",
+			config.MaxLimit, params.Limit))
+		return false
+	}
+	return true
+}
+
+// applySwaggerPaginationDocs updates the "limit" query parameters in the
+// Swagger document to show the configured default and max limits, as the
+// values in the generated document are only the defaults of wharf-api.
+// Must be called after the other fields of the Swagger spec have been set,
+// such as the version, as the template is replaced with the rendered
+// document.
+func applySwaggerPaginationDocs(spec *swag.Spec, config HTTPPaginationConfig) error {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(spec.ReadDoc()), &doc); err != nil {
+		return fmt.Errorf("parse swagger document: %w", err)
+	}
+	paths, _ := doc["paths"].(map[string]any)
+	for _, path := range paths {
+		operations, _ := path.(map[string]any)
+		for _, operation := range operations {
+			operation, _ := operation.(map[string]any)
+			params, _ := operation["parameters"].([]any)
+			for _, param := range params {
+				param, _ := param.(map[string]any)
+				if param["name"] != "limit" || param["in"] != "query" || param["type"] != "integer" {
+					continue
+				}
+				param["default"] = config.DefaultLimit
+				if config.MaxLimit > 0 {
+					param["maximum"] = config.MaxLimit
+				} else {
+					delete(param, "maximum")
+				}
+			}
+		}
+	}
+	rendered, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("render swagger document: %w", err)
+	}
+	// The template is parsed by text/template, so any curly braces in the
+	// rendered document must be escaped.
+	spec.SwaggerTemplate = strings.ReplaceAll(string(rendered), "{{", `{{"{{"}}`)
+	return nil
+}
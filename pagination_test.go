@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggo/swag"
+)
+
+func TestBindCommonGetQueryParams_pagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testCases := []struct {
+		name       string
+		config     *HTTPPaginationConfig
+		query      string
+		wantStatus int
+		wantLimit  int
+	}{
+		{
+			name:       "no middleware uses default",
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantLimit:  100,
+		},
+		{
+			name:       "configured default",
+			config:     &HTTPPaginationConfig{DefaultLimit: 20},
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantLimit:  20,
+		},
+		{
+			name:       "no max allows no limit",
+			config:     &HTTPPaginationConfig{DefaultLimit: 20},
+			query:      "?limit=0",
+			wantStatus: http.StatusOK,
+			wantLimit:  0,
+		},
+		{
+			name:       "within max",
+			config:     &HTTPPaginationConfig{DefaultLimit: 20, MaxLimit: 50},
+			query:      "?limit=50",
+			wantStatus: http.StatusOK,
+			wantLimit:  50,
+		},
+		{
+			name:       "above max",
+			config:     &HTTPPaginationConfig{DefaultLimit: 20, MaxLimit: 50},
+			query:      "?limit=1000000",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no limit with max",
+			config:     &HTTPPaginationConfig{DefaultLimit: 20, MaxLimit: 50},
+			query:      "?limit=",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			if tc.config != nil {
+				r.Use(newPaginationMiddleware(*tc.config))
+			}
+			var gotLimit int
+			r.GET("/", func(c *gin.Context) {
+				params := defaultCommonGetQueryParams
+				if bindCommonGetQueryParams(c, &params) {
+					gotLimit = params.Limit
+					c.Status(http.StatusOK)
+				}
+			})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tc.query, nil))
+			require.Equal(t, tc.wantStatus, w.Code, w.Body.String())
+			assert.Equal(t, tc.wantLimit, gotLimit)
+		})
+	}
+}
+
+func TestApplySwaggerPaginationDocs(t *testing.T) {
+	spec := &swag.Spec{
+		Version: "v5.3.0",
+		SwaggerTemplate: `{"info":{"version":"{{.Version}}"},"paths":{"/build":{"get":{"parameters":[
+			{"type":"integer","default":100,"name":"limit","in":"query"},
+			{"type":"integer","name":"offset","in":"query"}]}}}}`,
+	}
+	require.NoError(t, applySwaggerPaginationDocs(spec, HTTPPaginationConfig{DefaultLimit: 20, MaxLimit: 50}))
+
+	var doc struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			Parameters []map[string]any `json:"parameters"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(spec.ReadDoc()), &doc))
+	assert.Equal(t, "v5.3.0", doc.Info.Version)
+	params := doc.Paths["/build"]["get"].Parameters
+	require.Len(t, params, 2)
+	assert.Equal(t, float64(20), params[0]["default"])
+	assert.Equal(t, float64(50), params[0]["maximum"])
+	assert.NotContains(t, params[1], "maximum")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 )
 
@@ -32,7 +33,7 @@ func (m healthModule) DeprecatedRegister(e *gin.Engine) {
 // @success 200 {object} response.Ping
 // @router /ping [get]
 func (m healthModule) pingHandler(c *gin.Context) {
-	renderJSON(c, 200, response.Ping{Message: "pong"})
+	ginrender.Response(c, 200, response.Ping{Message: "pong"})
 }
 
 // healthHandler godoc
@@ -46,5 +47,5 @@ func (m healthModule) pingHandler(c *gin.Context) {
 // @success 200 {object} response.HealthStatus
 // @router /health [get]
 func (m healthModule) healthHandler(c *gin.Context) {
-	renderJSON(c, 200, response.HealthStatus{Message: "API is healthy.", IsHealthy: true})
+	ginrender.Response(c, 200, response.HealthStatus{Message: "API is healthy.", IsHealthy: true})
 }
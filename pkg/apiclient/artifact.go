@@ -0,0 +1,48 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	v5 "github.com/iver-wharf/wharf-api/v5/api/wharfapi/v5"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// DownloadArtifact writes the data of a build artifact to w, and returns the
+// artifact's metadata. The TimeMetadata of the returned artifact is not set.
+// Requires the client's GRPCConn field to be set.
+//
+// Uses the DownloadArtifact RPC, which streams the data in chunks.
+func (c *Client) DownloadArtifact(ctx context.Context, buildID, artifactID uint, w io.Writer) (response.Artifact, error) {
+	if c.GRPCConn == nil {
+		return response.Artifact{}, ErrNoGRPCConn
+	}
+	stream, err := v5.NewBuildsClient(c.GRPCConn).DownloadArtifact(ctx, &v5.DownloadArtifactRequest{
+		BuildID:    uint64(buildID),
+		ArtifactID: uint64(artifactID),
+	})
+	if err != nil {
+		return response.Artifact{}, err
+	}
+	var artifact response.Artifact
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return artifact, nil
+		} else if err != nil {
+			return artifact, err
+		}
+		if res.Metadata != nil {
+			artifact = response.Artifact{
+				ArtifactID: uint(res.Metadata.ArtifactID),
+				BuildID:    uint(res.Metadata.BuildID),
+				Name:       res.Metadata.Name,
+				FileName:   res.Metadata.FileName,
+			}
+		}
+		if _, err := w.Write(res.Chunk); err != nil {
+			return artifact, err
+		}
+	}
+}
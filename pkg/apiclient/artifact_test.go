@@ -0,0 +1,43 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	v5 "github.com/iver-wharf/wharf-api/v5/api/wharfapi/v5"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *testBuildsServer) DownloadArtifact(req *v5.DownloadArtifactRequest, stream v5.Builds_DownloadArtifactServer) error {
+	if err := stream.Send(&v5.DownloadArtifactResponse{
+		Metadata: &v5.ArtifactMetadata{
+			ArtifactID: req.ArtifactID,
+			BuildID:    req.BuildID,
+			Name:       "cache",
+			FileName:   "cache.tar",
+			Size:       11,
+		},
+		Chunk: []byte("hello "),
+	}); err != nil {
+		return err
+	}
+	return stream.Send(&v5.DownloadArtifactResponse{Chunk: []byte("world")})
+}
+
+func TestClient_DownloadArtifact(t *testing.T) {
+	c := newTestGRPCClient(t, &testBuildsServer{})
+	var buf bytes.Buffer
+	artifact, err := c.DownloadArtifact(context.Background(), 3, 5, &buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, response.Artifact{ArtifactID: 5, BuildID: 3, Name: "cache", FileName: "cache.tar"}, artifact)
+	assert.Equal(t, "hello world", buf.String())
+}
+
+func TestClient_DownloadArtifact_noConn(t *testing.T) {
+	_, err := (&Client{}).DownloadArtifact(context.Background(), 3, 5, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNoGRPCConn)
+}
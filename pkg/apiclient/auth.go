@@ -0,0 +1,50 @@
+package apiclient
+
+import (
+	"net/http"
+)
+
+// AuthInjector adds credentials to the requests sent to the wharf-api.
+type AuthInjector interface {
+	InjectAuth(req *http.Request) error
+}
+
+// AuthInjectorFunc is a function that implements the AuthInjector interface.
+type AuthInjectorFunc func(req *http.Request) error
+
+// InjectAuth calls the function itself.
+func (f AuthInjectorFunc) InjectAuth(req *http.Request) error {
+	return f(req)
+}
+
+// BearerToken returns an AuthInjector that sends the token as a bearer token,
+// such as an OIDC access token.
+func BearerToken(token string) AuthInjector {
+	return AuthInjectorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// BearerTokenFunc returns an AuthInjector that sends the token returned by the
+// function as a bearer token. The function is called for every request, and
+// can be used to refresh expired tokens.
+func BearerTokenFunc(getToken func() (string, error)) AuthInjector {
+	return AuthInjectorFunc(func(req *http.Request) error {
+		token, err := getToken()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// BasicAuth returns an AuthInjector that sends the username and password
+// using HTTP basic authentication.
+func BasicAuth(username, password string) AuthInjector {
+	return AuthInjectorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
@@ -0,0 +1,220 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// BuildListParams are the query parameters of GetBuildList.
+type BuildListParams struct {
+	ListParams
+
+	ProjectID   *uint
+	Environment *string
+	GitBranch   *string
+	GitCommit   *string
+	GitTag      *string
+	Stage       *string
+	EngineID    *string
+	Status      []response.BuildStatus
+	// Match filters by matching on any supported fields.
+	Match *string
+	// ParamName filters by builds started with an input parameter of this
+	// name, and ParamValue additionally by the parameter's value.
+	ParamName  *string
+	ParamValue *string
+	// Labels filters by labels, either as "key=value" to match the label's
+	// value, or as "key" to match any value.
+	Labels []string
+	// Stale filters by builds that will, or will not, be marked as failed if
+	// they stay in their current Scheduling or Running status for too long.
+	Stale *bool
+}
+
+// GetBuildList returns a paginated list of builds.
+func (c *Client) GetBuildList(ctx context.Context, params BuildListParams) (response.PaginatedBuilds, error) {
+	q := url.Values{}
+	params.addTo(q)
+	addQueryUint(q, "projectId", params.ProjectID)
+	addQueryString(q, "environment", params.Environment)
+	addQueryString(q, "gitBranch", params.GitBranch)
+	addQueryString(q, "gitCommit", params.GitCommit)
+	addQueryString(q, "gitTag", params.GitTag)
+	addQueryString(q, "stage", params.Stage)
+	addQueryString(q, "engineId", params.EngineID)
+	for _, status := range params.Status {
+		q.Add("status", string(status))
+	}
+	addQueryString(q, "match", params.Match)
+	addQueryString(q, "param.name", params.ParamName)
+	addQueryString(q, "param.value", params.ParamValue)
+	for _, label := range params.Labels {
+		q.Add("label", label)
+	}
+	addQueryBool(q, "stale", params.Stale)
+	var builds response.PaginatedBuilds
+	err := c.get(ctx, "/build", q, &builds)
+	return builds, err
+}
+
+// ListBuilds returns an iterator over all builds matching the parameters,
+// fetching one page at a time using GetBuildList. The limit in the parameters
+// is used as the page size.
+func (c *Client) ListBuilds(params BuildListParams) *Iterator[response.Build] {
+	return newIterator(params.ListParams, func(ctx context.Context, limit, offset int) ([]response.Build, int64, error) {
+		pageParams := params
+		pageParams.Limit = &limit
+		pageParams.Offset = &offset
+		page, err := c.GetBuildList(ctx, pageParams)
+		return page.List, page.TotalCount, err
+	})
+}
+
+// GetBuild returns a build by its ID.
+func (c *Client) GetBuild(ctx context.Context, buildID uint) (response.Build, error) {
+	var build response.Build
+	err := c.get(ctx, fmt.Sprintf("/build/%d", buildID), nil, &build)
+	return build, err
+}
+
+// GetBuildByNumber returns a build by its number within the project.
+func (c *Client) GetBuildByNumber(ctx context.Context, projectID, buildNumber uint) (response.Build, error) {
+	var build response.Build
+	err := c.get(ctx, fmt.Sprintf("/project/%d/build/number/%d", projectID, buildNumber), nil, &build)
+	return build, err
+}
+
+// LatestBuildParams are the query parameters of GetLatestBuild and
+// GetLatestBuildPerBranch. Empty values are left out.
+type LatestBuildParams struct {
+	// Branch is ignored by GetLatestBuildPerBranch.
+	Branch string
+	Stage  string
+	Status response.BuildStatus
+}
+
+// GetLatestBuild returns the most recent build of a project that matches the
+// parameters.
+func (c *Client) GetLatestBuild(ctx context.Context, projectID uint, params LatestBuildParams) (response.Build, error) {
+	q := url.Values{}
+	addQueryNonEmpty(q, "branch", params.Branch)
+	addQueryNonEmpty(q, "stage", params.Stage)
+	addQueryNonEmpty(q, "status", string(params.Status))
+	var build response.Build
+	err := c.get(ctx, fmt.Sprintf("/project/%d/build/latest", projectID), q, &build)
+	return build, err
+}
+
+// GetLatestBuildPerBranch returns the most recent build of each branch of a
+// project that matches the parameters, ordered by branch name.
+func (c *Client) GetLatestBuildPerBranch(ctx context.Context, projectID uint, params LatestBuildParams) ([]response.Build, error) {
+	q := url.Values{}
+	addQueryNonEmpty(q, "stage", params.Stage)
+	addQueryNonEmpty(q, "status", string(params.Status))
+	var builds []response.Build
+	err := c.get(ctx, fmt.Sprintf("/project/%d/build/latest-per-branch", projectID), q, &builds)
+	return builds, err
+}
+
+// StartBuildParams are the query parameters of StartBuild. Empty
+// values are left out, to use the API's defaults.
+type StartBuildParams struct {
+	Stage       string
+	Branch      string
+	Commit      string
+	Tag         string
+	Environment string
+	Engine      string
+	// Async makes the API trigger the build in the background, and respond
+	// without waiting for the execution engine.
+	Async bool
+}
+
+// StartBuild starts a new build of a project. The inputs may be nil if the
+// project's build definition does not declare any inputs.
+//
+// Uses the startProjectBuild operation.
+func (c *Client) StartBuild(ctx context.Context, projectID uint, params StartBuildParams, inputs request.BuildInputs) (response.BuildReferenceWrapper, error) {
+	q := url.Values{}
+	addQueryNonEmpty(q, "stage", params.Stage)
+	addQueryNonEmpty(q, "branch", params.Branch)
+	addQueryNonEmpty(q, "commit", params.Commit)
+	addQueryNonEmpty(q, "tag", params.Tag)
+	addQueryNonEmpty(q, "environment", params.Environment)
+	addQueryNonEmpty(q, "engine", params.Engine)
+	if params.Async {
+		q.Set("async", strconv.FormatBool(params.Async))
+	}
+	if inputs == nil {
+		inputs = request.BuildInputs{}
+	}
+	var ref response.BuildReferenceWrapper
+	err := c.post(ctx, fmt.Sprintf("/project/%d/build", projectID), q, inputs, &ref)
+	return ref, err
+}
+
+// UpdateBuildStatus sets the status of a build.
+func (c *Client) UpdateBuildStatus(ctx context.Context, buildID uint, status request.BuildStatusUpdate) (response.Build, error) {
+	var build response.Build
+	err := c.put(ctx, fmt.Sprintf("/build/%d/status", buildID), status, &build)
+	return build, err
+}
+
+// UpdateBuildStageStatus sets the status of a stage of a build, such as when
+// the stage starts or finishes.
+func (c *Client) UpdateBuildStageStatus(ctx context.Context, buildID uint, stageName string, status request.BuildStageStatusUpdate) (response.BuildStageResult, error) {
+	var result response.BuildStageResult
+	err := c.put(ctx, fmt.Sprintf("/build/%d/stage/%s/status", buildID, url.PathEscape(stageName)), status, &result)
+	return result, err
+}
+
+// UpdateBuildLabels replaces all labels of a build.
+func (c *Client) UpdateBuildLabels(ctx context.Context, buildID uint, labels request.Labels) (response.Labels, error) {
+	var resLabels response.Labels
+	err := c.put(ctx, fmt.Sprintf("/build/%d/label", buildID), labels, &resLabels)
+	return resLabels, err
+}
+
+// GetBuildParamList returns the input variables of a build, with the values
+// of secret inputs redacted. Optionally filtered by the names of the inputs.
+func (c *Client) GetBuildParamList(ctx context.Context, buildID uint, names ...string) ([]response.BuildParam, error) {
+	q := url.Values{}
+	for _, name := range names {
+		q.Add("name", name)
+	}
+	var params []response.BuildParam
+	err := c.get(ctx, fmt.Sprintf("/build/%d/param", buildID), q, &params)
+	return params, err
+}
+
+// GetBuildCommentList returns all comments on a build, ordered by oldest
+// first.
+func (c *Client) GetBuildCommentList(ctx context.Context, buildID uint) ([]response.BuildComment, error) {
+	var comments []response.BuildComment
+	err := c.get(ctx, fmt.Sprintf("/build/%d/comment", buildID), nil, &comments)
+	return comments, err
+}
+
+// CreateBuildComment adds a comment to a build.
+func (c *Client) CreateBuildComment(ctx context.Context, buildID uint, comment request.BuildComment) (response.BuildComment, error) {
+	var resComment response.BuildComment
+	err := c.post(ctx, fmt.Sprintf("/build/%d/comment", buildID), nil, comment, &resComment)
+	return resComment, err
+}
+
+// CreateBuildLink adds an external link to a build.
+func (c *Client) CreateBuildLink(ctx context.Context, buildID uint, link request.BuildLink) (response.BuildLink, error) {
+	var resLink response.BuildLink
+	err := c.post(ctx, fmt.Sprintf("/build/%d/link", buildID), nil, link, &resLink)
+	return resLink, err
+}
+
+// CreateBuildLog adds a log line or status update to a build.
+func (c *Client) CreateBuildLog(ctx context.Context, buildID uint, log request.LogOrStatusUpdate) error {
+	return c.post(ctx, fmt.Sprintf("/build/%d/log", buildID), nil, log, nil)
+}
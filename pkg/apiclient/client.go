@@ -0,0 +1,253 @@
+// Package apiclient contains a Go client for the wharf-api, meant to be used
+// by other Wharf components such as wharf-cmd and the provider plugins instead
+// of writing the HTTP requests by hand.
+//
+// The client uses the request and response models from the
+// github.com/iver-wharf/wharf-api/v5/pkg/model packages, and its method names
+// follow the operation IDs in the API's Swagger and OpenAPI documents.
+//
+// Failed requests return an *Error, which holds the parsed problem response
+// if the API responded with one.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"google.golang.org/grpc"
+)
+
+// Client is a wharf-api client. The zero value is not usable, as the APIURL
+// field must be set.
+type Client struct {
+	// APIURL is the base URL of the wharf-api, including the "/api" path
+	// prefix, such as "https://wharf.example.com/api".
+	APIURL string
+	// Auth adds the credentials to every HTTP request. No credentials are sent
+	// if nil.
+	Auth AuthInjector
+	// HTTPClient is the HTTP client used to send the requests. Uses
+	// http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// Retry is the policy of retrying failed requests. Requests are not
+	// retried if left as the zero value.
+	Retry RetryPolicy
+	// GRPCConn is the connection to the wharf-api's gRPC server, used when
+	// streaming logs and artifacts. Only required by StreamLogs, FollowLogs,
+	// and DownloadArtifact.
+	GRPCConn grpc.ClientConnInterface
+}
+
+// RetryPolicy specifies how failed requests are retried.
+//
+// Only requests that are safe to send again are retried, meaning GET, PUT, and
+// DELETE requests. Requests are only retried if the connection was refused, or
+// if the API responded with 502, 503, or 504.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// Backoff is the delay before the first retry, which is doubled for every
+	// following retry.
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy is a suggested retry policy, retrying 3 times with a
+// total delay of 3.5 seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	Backoff:    500 * time.Millisecond,
+}
+
+// ListParams are the pagination and sorting parameters shared by all
+// endpoints that return paginated lists.
+type ListParams struct {
+	// Limit is the number of results to return. The API's default is used if
+	// nil, and no limiting is applied if zero.
+	Limit *int
+	// Offset is the number of skipped results.
+	Offset *int
+	// OrderBy is a list of sorting orders, such as "projectId desc".
+	OrderBy []string
+	// SkipTotal skips counting the total number of results, which makes the
+	// API respond with the total count set to -1.
+	SkipTotal bool
+}
+
+func (p ListParams) addTo(q url.Values) {
+	addQueryInt(q, "limit", p.Limit)
+	addQueryInt(q, "offset", p.Offset)
+	for _, orderBy := range p.OrderBy {
+		q.Add("orderby", orderBy)
+	}
+	if p.SkipTotal {
+		q.Set("skipTotal", "true")
+	}
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, respBody any) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, respBody)
+}
+
+func (c *Client) post(ctx context.Context, path string, query url.Values, reqBody, respBody any) error {
+	return c.do(ctx, http.MethodPost, path, query, reqBody, respBody)
+}
+
+func (c *Client) put(ctx context.Context, path string, reqBody, respBody any) error {
+	return c.do(ctx, http.MethodPut, path, nil, reqBody, respBody)
+}
+
+func (c *Client) delete(ctx context.Context, path string) error {
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+// do sends a request to the wharf-api, retrying it according to the client's
+// retry policy. The request body is encoded as JSON if non-nil, and the
+// response body is decoded as JSON into respBody if non-nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, reqBody, respBody any) error {
+	u := strings.TrimSuffix(c.APIURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBytes []byte
+	if reqBody != nil {
+		var err error
+		reqBytes, err = json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("encode request body: %w", err)
+		}
+	}
+
+	maxRetries := 0
+	if method != http.MethodPost {
+		maxRetries = c.Retry.MaxRetries
+	}
+	backoff := c.Retry.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u, reqBytes)
+		if attempt < maxRetries && isRetryableFailure(resp, err) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return readResponse(resp, method, u, respBody)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, reqBytes []byte) (*http.Response, error) {
+	var body io.Reader
+	if reqBytes != nil {
+		body = bytes.NewReader(reqBytes)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if reqBytes != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Auth != nil {
+		if err := c.Auth.InjectAuth(req); err != nil {
+			return nil, fmt.Errorf("add credentials to request: %w", err)
+		}
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+func isRetryableFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func readResponse(resp *http.Response, method, u string, respBody any) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &Error{
+			Method:     method,
+			URL:        u,
+			StatusCode: resp.StatusCode,
+		}
+		if problem.IsHTTPResponse(resp) {
+			prob, err := problem.ParseHTTPResponse(resp)
+			if err != nil {
+				return fmt.Errorf("%s %s: parse problem response: %w", method, u, err)
+			}
+			apiErr.Problem = &prob
+		} else {
+			respBytes, _ := ioutil.ReadAll(resp.Body)
+			apiErr.Body = string(respBytes)
+		}
+		return apiErr
+	}
+	if respBody == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+		return fmt.Errorf("%s %s: decode response body: %w", method, u, err)
+	}
+	return nil
+}
+
+func addQueryString(q url.Values, key string, value *string) {
+	if value != nil {
+		q.Set(key, *value)
+	}
+}
+
+func addQueryInt(q url.Values, key string, value *int) {
+	if value != nil {
+		q.Set(key, strconv.Itoa(*value))
+	}
+}
+
+func addQueryUint(q url.Values, key string, value *uint) {
+	if value != nil {
+		q.Set(key, strconv.FormatUint(uint64(*value), 10))
+	}
+}
+
+func addQueryBool(q url.Values, key string, value *bool) {
+	if value != nil {
+		q.Set(key, strconv.FormatBool(*value))
+	}
+}
+
+func addQueryNonEmpty(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
@@ -0,0 +1,185 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &Client{APIURL: server.URL + "/api", Auth: BearerToken("foo")}
+}
+
+func TestClient_GetProject(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api/project/12", r.URL.Path)
+		assert.Equal(t, "Bearer foo", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(response.Project{ProjectID: 12, Name: "foo"})
+	})
+
+	project, err := c.GetProject(context.Background(), 12)
+	require.NoError(t, err)
+	assert.Equal(t, response.Project{ProjectID: 12, Name: "foo"}, project)
+}
+
+func TestClient_StartBuild(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/project/3/build", r.URL.Path)
+		assert.Equal(t, "async=true&branch=main&stage=deploy", r.URL.RawQuery)
+		var inputs request.BuildInputs
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&inputs))
+		assert.Equal(t, request.BuildInputs{"foo": "bar"}, inputs)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(response.BuildReferenceWrapper{BuildReference: "7"})
+	})
+
+	ref, err := c.StartBuild(context.Background(), 3, StartBuildParams{
+		Stage:  "deploy",
+		Branch: "main",
+		Async:  true,
+	}, request.BuildInputs{"foo": "bar"})
+	require.NoError(t, err)
+	assert.Equal(t, "7", ref.BuildReference)
+}
+
+func TestClient_problemResponse(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", problem.HTTPContentType)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(problem.Response{
+			Type:   "/prob/api/record-not-found",
+			Status: http.StatusNotFound,
+			Title:  "Record not found.",
+		})
+	})
+
+	_, err := c.GetBuild(context.Background(), 1)
+	var prob problem.Response
+	require.True(t, errors.As(err, &prob), "want problem.Response error, got: %v", err)
+	assert.Equal(t, http.StatusNotFound, prob.Status)
+	assert.True(t, IsNotFound(err))
+	assert.True(t, IsProblemType(err, "/prob/api/record-not-found"))
+	assert.False(t, IsProblemType(err, "/prob/api/invalid-param"))
+}
+
+func TestClient_statusError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+
+	err := c.DeleteProject(context.Background(), 1)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), "want *Error, got: %v", err)
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Nil(t, apiErr.Problem)
+	assert.Equal(t, "bad gateway\n", apiErr.Body)
+}
+
+func TestClient_retry(t *testing.T) {
+	testCases := []struct {
+		name      string
+		call      func(c *Client) error
+		wantCalls int
+	}{
+		{
+			name: "retries GET",
+			call: func(c *Client) error {
+				_, err := c.GetBuild(context.Background(), 1)
+				return err
+			},
+			wantCalls: 3,
+		},
+		{
+			name: "does not retry POST",
+			call: func(c *Client) error {
+				_, err := c.StartBuild(context.Background(), 1, StartBuildParams{}, nil)
+				return err
+			},
+			wantCalls: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls < 3 {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				json.NewEncoder(w).Encode(response.Build{BuildID: 1})
+			})
+			c.Retry = RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond}
+
+			err := tc.call(c)
+			assert.Equal(t, tc.wantCalls, calls)
+			if tc.wantCalls == 3 {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestClient_ListBuilds(t *testing.T) {
+	const totalCount = 5
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/build", r.URL.Path)
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var page response.PaginatedBuilds
+		for i := offset; i < offset+2 && i < totalCount; i++ {
+			page.List = append(page.List, response.Build{BuildID: uint(i + 1)})
+		}
+		page.TotalCount = totalCount
+		json.NewEncoder(w).Encode(page)
+	})
+
+	limit := 2
+	it := c.ListBuilds(BuildListParams{ListParams: ListParams{Limit: &limit}})
+	var buildIDs []uint
+	for it.Next(context.Background()) {
+		buildIDs = append(buildIDs, it.Item().BuildID)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, buildIDs)
+}
+
+func TestClient_ListBuilds_skipTotal(t *testing.T) {
+	const totalCount = 5
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.URL.Query().Get("skipTotal"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var page response.PaginatedBuilds
+		for i := offset; i < offset+2 && i < totalCount; i++ {
+			page.List = append(page.List, response.Build{BuildID: uint(i + 1)})
+		}
+		page.TotalCount = -1
+		json.NewEncoder(w).Encode(page)
+	})
+
+	limit := 2
+	it := c.ListBuilds(BuildListParams{ListParams: ListParams{Limit: &limit, SkipTotal: true}})
+	var buildIDs []uint
+	for it.Next(context.Background()) {
+		buildIDs = append(buildIDs, it.Item().BuildID)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, buildIDs)
+}
@@ -0,0 +1,22 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+)
+
+// UpdateEngineToken stores the token of an execution engine in the database,
+// which is then used instead of the token from the wharf-api configuration.
+func (c *Client) UpdateEngineToken(ctx context.Context, engineID, token string) error {
+	return c.put(ctx, fmt.Sprintf("/engine/%s/token", url.PathEscape(engineID)),
+		request.EngineTokenUpdate{Token: token}, nil)
+}
+
+// DeleteEngineToken reverts UpdateEngineToken, so that the token from the
+// wharf-api configuration is used again.
+func (c *Client) DeleteEngineToken(ctx context.Context, engineID string) error {
+	return c.delete(ctx, fmt.Sprintf("/engine/%s/token", url.PathEscape(engineID)))
+}
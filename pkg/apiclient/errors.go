@@ -0,0 +1,70 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+)
+
+// Error is returned when the wharf-api responds with a non-2xx status code.
+type Error struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Problem is the parsed problem response, or nil if the response did not
+	// have a problem response body.
+	Problem *problem.Response
+	// Body is the raw response body, if the response did not have a problem
+	// response body.
+	Body string
+}
+
+func (err *Error) Error() string {
+	if err.Problem != nil {
+		return fmt.Sprintf("%s %s: %d: %s", err.Method, err.URL, err.StatusCode, err.Problem.Error())
+	}
+	return fmt.Sprintf("%s %s: unexpected status %d %s: %q",
+		err.Method, err.URL, err.StatusCode, http.StatusText(err.StatusCode), err.Body)
+}
+
+// Unwrap returns the problem response, if any, so that errors.As can be used
+// to get the problem.Response.
+func (err *Error) Unwrap() error {
+	if err.Problem == nil {
+		return nil
+	}
+	return *err.Problem
+}
+
+// IsNotFound returns true if the error is an *Error with the status code
+// 404 "Not Found".
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsConflict returns true if the error is an *Error with the status code
+// 409 "Conflict".
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
+
+// IsProblemType returns true if the error is an *Error with a problem response
+// of the given problem type, such as "/prob/api/record-not-found". The type
+// is compared without the documentation URL that the API prefixes the
+// problem types with.
+func IsProblemType(err error, problemType string) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Problem == nil {
+		return false
+	}
+	return apiErr.Problem.Type == problemType ||
+		strings.HasSuffix(apiErr.Problem.Type, "#"+problemType)
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
@@ -0,0 +1,84 @@
+package apiclient
+
+import (
+	"context"
+)
+
+// defaultPageSize is the number of items fetched per request by iterators,
+// unless the limit is set in the list parameters.
+const defaultPageSize = 100
+
+// Iterator iterates over all items of a paginated list, fetching the next page
+// when needed. Usage:
+//
+//	it := client.ListBuilds(apiclient.BuildListParams{})
+//	for it.Next(ctx) {
+//		build := it.Item()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type Iterator[T any] struct {
+	fetchPage func(ctx context.Context, limit, offset int) ([]T, int64, error)
+	pageSize  int
+	offset    int
+	page      []T
+	index     int
+	done      bool
+	err       error
+}
+
+func newIterator[T any](params ListParams, fetchPage func(ctx context.Context, limit, offset int) ([]T, int64, error)) *Iterator[T] {
+	it := &Iterator[T]{
+		fetchPage: fetchPage,
+		pageSize:  defaultPageSize,
+	}
+	if params.Limit != nil && *params.Limit > 0 {
+		it.pageSize = *params.Limit
+	}
+	if params.Offset != nil {
+		it.offset = *params.Offset
+	}
+	return it
+}
+
+// Next advances the iterator to the next item, fetching the next page if
+// needed. Returns false when there are no more items, or if fetching a page
+// failed, in which case the error is returned by Err.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index+1 < len(it.page) {
+		it.index++
+		return true
+	}
+	if it.done {
+		return false
+	}
+	page, totalCount, err := it.fetchPage(ctx, it.pageSize, it.offset)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page = page
+	it.index = 0
+	it.offset += len(page)
+	// The total count is negative when it was skipped, and then only the
+	// page size tells when there are no more pages.
+	if len(page) < it.pageSize || (totalCount >= 0 && int64(it.offset) >= totalCount) {
+		it.done = true
+	}
+	return len(page) > 0
+}
+
+// Item returns the current item. Only valid after Next has returned true.
+func (it *Iterator[T]) Item() T {
+	return it.page[it.index]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
@@ -0,0 +1,110 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v5 "github.com/iver-wharf/wharf-api/v5/api/wharfapi/v5"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrNoGRPCConn is returned by the methods using gRPC, such as StreamLogs,
+// when the client's GRPCConn field is not set.
+var ErrNoGRPCConn = errors.New("apiclient: no gRPC connection configured")
+
+// LogLine is a single log line of a build, sent via a LogStream.
+type LogLine struct {
+	BuildID uint
+	// WorkerLogID is the worker's own ID of the log line, unique per build
+	// step.
+	WorkerLogID uint64
+	// WorkerStepID is the worker's own ID of the build step, unique per build.
+	WorkerStepID uint64
+	Timestamp    time.Time
+	Message      string
+}
+
+// LogStream sends log lines to the wharf-api over a single gRPC stream. Must be
+// closed using CloseAndRecv.
+type LogStream struct {
+	stream v5.Builds_CreateLogStreamClient
+}
+
+// StreamLogs opens a gRPC stream for creating build logs. Requires the
+// client's GRPCConn field to be set.
+//
+// Uses the CreateLogStream RPC.
+func (c *Client) StreamLogs(ctx context.Context) (*LogStream, error) {
+	if c.GRPCConn == nil {
+		return nil, ErrNoGRPCConn
+	}
+	stream, err := v5.NewBuildsClient(c.GRPCConn).CreateLogStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &LogStream{stream: stream}, nil
+}
+
+// Send sends a log line.
+func (s *LogStream) Send(line LogLine) error {
+	return s.stream.Send(&v5.CreateLogStreamRequest{
+		BuildID:      uint64(line.BuildID),
+		WorkerLogID:  line.WorkerLogID,
+		WorkerStepID: line.WorkerStepID,
+		Timestamp:    timestamppb.New(line.Timestamp),
+		Message:      line.Message,
+	})
+}
+
+// CloseAndRecv closes the stream, and returns the number of log lines that
+// the wharf-api inserted.
+func (s *LogStream) CloseAndRecv() (uint64, error) {
+	resp, err := s.stream.CloseAndRecv()
+	if err != nil {
+		return 0, err
+	}
+	return resp.LinesInserted, nil
+}
+
+// LogFollower receives the log lines of a build over a single gRPC stream. The
+// stream is closed by cancelling the context given to FollowLogs.
+type LogFollower struct {
+	stream v5.Builds_StreamLogsClient
+}
+
+// FollowLogs opens a gRPC stream that receives the log lines of a build,
+// starting after the log line with the ID afterLogID, and then follows new log
+// lines as they are added. Pass the ID of the last received log line as
+// afterLogID to resume after a disconnect, or zero to receive all log lines.
+// Requires the client's GRPCConn field to be set.
+//
+// Uses the StreamLogs RPC.
+func (c *Client) FollowLogs(ctx context.Context, buildID, afterLogID uint) (*LogFollower, error) {
+	if c.GRPCConn == nil {
+		return nil, ErrNoGRPCConn
+	}
+	stream, err := v5.NewBuildsClient(c.GRPCConn).StreamLogs(ctx, &v5.StreamLogsRequest{
+		BuildID:    uint64(buildID),
+		AfterLogID: uint64(afterLogID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &LogFollower{stream: stream}, nil
+}
+
+// Recv waits for and returns the next log line.
+func (f *LogFollower) Recv() (response.Log, error) {
+	line, err := f.stream.Recv()
+	if err != nil {
+		return response.Log{}, err
+	}
+	return response.Log{
+		LogID:     uint(line.LogID),
+		BuildID:   uint(line.BuildID),
+		Message:   line.Message,
+		Timestamp: line.Timestamp.AsTime(),
+	}, nil
+}
@@ -0,0 +1,114 @@
+package apiclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	v5 "github.com/iver-wharf/wharf-api/v5/api/wharfapi/v5"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type testBuildsServer struct {
+	v5.UnimplementedBuildsServer
+	received []*v5.CreateLogStreamRequest
+	logLines []*v5.LogLine
+}
+
+func (s *testBuildsServer) CreateLogStream(stream v5.Builds_CreateLogStreamServer) error {
+	for {
+		line, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&v5.CreateLogStreamResponse{
+				LinesInserted: uint64(len(s.received)),
+			})
+		} else if err != nil {
+			return err
+		}
+		s.received = append(s.received, line)
+	}
+}
+
+func (s *testBuildsServer) StreamLogs(req *v5.StreamLogsRequest, stream v5.Builds_StreamLogsServer) error {
+	for _, line := range s.logLines {
+		if line.BuildID != req.BuildID || line.LogID <= req.AfterLogID {
+			continue
+		}
+		if err := stream.Send(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestGRPCClient(t *testing.T, buildsServer *testBuildsServer) *Client {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	v5.RegisterBuildsServer(server, buildsServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &Client{GRPCConn: conn}
+}
+
+func TestClient_StreamLogs(t *testing.T) {
+	buildsServer := &testBuildsServer{}
+	c := newTestGRPCClient(t, buildsServer)
+	stream, err := c.StreamLogs(context.Background())
+	require.NoError(t, err)
+	timestamp := time.Date(2022, 10, 16, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, stream.Send(LogLine{BuildID: 3, Message: "hello", Timestamp: timestamp}))
+	require.NoError(t, stream.Send(LogLine{BuildID: 3, Message: "world", Timestamp: timestamp}))
+	inserted, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(2), inserted)
+	require.Len(t, buildsServer.received, 2)
+	assert.Equal(t, uint64(3), buildsServer.received[0].BuildID)
+	assert.Equal(t, "hello", buildsServer.received[0].Message)
+	assert.Equal(t, timestamp, buildsServer.received[0].Timestamp.AsTime())
+}
+
+func TestClient_FollowLogs(t *testing.T) {
+	timestamp := time.Date(2022, 10, 16, 12, 0, 0, 0, time.UTC)
+	c := newTestGRPCClient(t, &testBuildsServer{
+		logLines: []*v5.LogLine{
+			{LogID: 1, BuildID: 3, Message: "hello", Timestamp: timestamppb.New(timestamp)},
+			{LogID: 2, BuildID: 3, Message: "world", Timestamp: timestamppb.New(timestamp)},
+			{LogID: 3, BuildID: 4, Message: "other build", Timestamp: timestamppb.New(timestamp)},
+		},
+	})
+	follower, err := c.FollowLogs(context.Background(), 3, 1)
+	require.NoError(t, err)
+
+	resLog, err := follower.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, response.Log{LogID: 2, BuildID: 3, Message: "world", Timestamp: timestamp}, resLog)
+	_, err = follower.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestClient_FollowLogs_noConn(t *testing.T) {
+	_, err := (&Client{}).FollowLogs(context.Background(), 1, 0)
+	assert.ErrorIs(t, err, ErrNoGRPCConn)
+}
+
+func TestClient_StreamLogs_noConn(t *testing.T) {
+	_, err := (&Client{}).StreamLogs(context.Background())
+	assert.ErrorIs(t, err, ErrNoGRPCConn)
+}
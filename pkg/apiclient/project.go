@@ -0,0 +1,160 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// ProjectListParams are the query parameters of GetProjectList.
+type ProjectListParams struct {
+	ListParams
+
+	Name        *string
+	GroupName   *string
+	Description *string
+	TokenID     *uint
+	ProviderID  *uint
+	GitURL      *string
+	// Match filters by matching on any supported fields.
+	Match *string
+	// Labels filters by labels, either as "key=value" to match the label's
+	// value, or as "key" to match any value.
+	Labels []string
+	// Archived lists archived projects instead of non-archived projects.
+	Archived bool
+}
+
+// GetProjectList returns a paginated list of projects.
+func (c *Client) GetProjectList(ctx context.Context, params ProjectListParams) (response.PaginatedProjects, error) {
+	q := url.Values{}
+	params.addTo(q)
+	addQueryString(q, "name", params.Name)
+	addQueryString(q, "groupName", params.GroupName)
+	addQueryString(q, "description", params.Description)
+	addQueryUint(q, "tokenId", params.TokenID)
+	addQueryUint(q, "providerId", params.ProviderID)
+	addQueryString(q, "gitUrl", params.GitURL)
+	addQueryString(q, "match", params.Match)
+	for _, label := range params.Labels {
+		q.Add("label", label)
+	}
+	if params.Archived {
+		q.Set("archived", strconv.FormatBool(params.Archived))
+	}
+	var projects response.PaginatedProjects
+	err := c.get(ctx, "/project", q, &projects)
+	return projects, err
+}
+
+// ListProjects returns an iterator over all projects matching the parameters,
+// fetching one page at a time using GetProjectList. The limit in the
+// parameters is used as the page size.
+func (c *Client) ListProjects(params ProjectListParams) *Iterator[response.Project] {
+	return newIterator(params.ListParams, func(ctx context.Context, limit, offset int) ([]response.Project, int64, error) {
+		pageParams := params
+		pageParams.Limit = &limit
+		pageParams.Offset = &offset
+		page, err := c.GetProjectList(ctx, pageParams)
+		return page.List, page.TotalCount, err
+	})
+}
+
+// GetProject returns a project by its ID.
+func (c *Client) GetProject(ctx context.Context, projectID uint) (response.Project, error) {
+	var project response.Project
+	err := c.get(ctx, fmt.Sprintf("/project/%d", projectID), nil, &project)
+	return project, err
+}
+
+// CreateProject adds a new project.
+func (c *Client) CreateProject(ctx context.Context, project request.Project) (response.Project, error) {
+	var created response.Project
+	err := c.post(ctx, "/project", nil, project, &created)
+	return created, err
+}
+
+// UpdateProject replaces all fields of a project.
+func (c *Client) UpdateProject(ctx context.Context, projectID uint, project request.ProjectUpdate) (response.Project, error) {
+	var updated response.Project
+	err := c.put(ctx, fmt.Sprintf("/project/%d", projectID), project, &updated)
+	return updated, err
+}
+
+// DeleteProject deletes a project by its ID.
+func (c *Client) DeleteProject(ctx context.Context, projectID uint) error {
+	return c.delete(ctx, fmt.Sprintf("/project/%d", projectID))
+}
+
+// UpdateProjectLabels replaces all labels of a project.
+func (c *Client) UpdateProjectLabels(ctx context.Context, projectID uint, labels request.Labels) (response.Labels, error) {
+	var resLabels response.Labels
+	err := c.put(ctx, fmt.Sprintf("/project/%d/label", projectID), labels, &resLabels)
+	return resLabels, err
+}
+
+// GetProjectInputDefaults returns the project's own default values of the
+// input variables declared in its build definition.
+func (c *Client) GetProjectInputDefaults(ctx context.Context, projectID uint) (response.ProjectInputDefaults, error) {
+	var resInputDefaults response.ProjectInputDefaults
+	err := c.get(ctx, fmt.Sprintf("/project/%d/input-defaults", projectID), nil, &resInputDefaults)
+	return resInputDefaults, err
+}
+
+// UpdateProjectInputDefaults replaces all of the project's own default values
+// of the input variables declared in its build definition.
+func (c *Client) UpdateProjectInputDefaults(ctx context.Context, projectID uint, inputDefaults request.ProjectInputDefaults) (response.ProjectInputDefaults, error) {
+	var resInputDefaults response.ProjectInputDefaults
+	err := c.put(ctx, fmt.Sprintf("/project/%d/input-defaults", projectID), inputDefaults, &resInputDefaults)
+	return resInputDefaults, err
+}
+
+// ArchiveProject hides a project from the project lists by default, and
+// blocks it from starting new builds.
+func (c *Client) ArchiveProject(ctx context.Context, projectID uint) (response.Project, error) {
+	var project response.Project
+	err := c.post(ctx, fmt.Sprintf("/project/%d/archive", projectID), nil, nil, &project)
+	return project, err
+}
+
+// UnarchiveProject reverts ArchiveProject.
+func (c *Client) UnarchiveProject(ctx context.Context, projectID uint) (response.Project, error) {
+	var project response.Project
+	err := c.post(ctx, fmt.Sprintf("/project/%d/unarchive", projectID), nil, nil, &project)
+	return project, err
+}
+
+// UpdateProjectInstance moves a project, together with all of its builds, to
+// another Wharf installation. Requires admin access.
+func (c *Client) UpdateProjectInstance(ctx context.Context, projectID uint, instance request.ProjectInstanceUpdate) (response.Project, error) {
+	var project response.Project
+	err := c.put(ctx, fmt.Sprintf("/admin/project/%d/instance", projectID), instance, &project)
+	return project, err
+}
+
+// GetProjectBranchList returns a paginated list of the branches of a project.
+func (c *Client) GetProjectBranchList(ctx context.Context, projectID uint, params ListParams) (response.PaginatedBranches, error) {
+	q := url.Values{}
+	params.addTo(q)
+	var branches response.PaginatedBranches
+	err := c.get(ctx, fmt.Sprintf("/project/%d/branch", projectID), q, &branches)
+	return branches, err
+}
+
+// CreateProjectBranch adds a new branch to a project.
+func (c *Client) CreateProjectBranch(ctx context.Context, projectID uint, branch request.Branch) (response.Branch, error) {
+	var created response.Branch
+	err := c.post(ctx, fmt.Sprintf("/project/%d/branch", projectID), nil, branch, &created)
+	return created, err
+}
+
+// UpdateProjectBranchList replaces all branches of a project.
+func (c *Client) UpdateProjectBranchList(ctx context.Context, projectID uint, branches request.BranchListUpdate) (response.BranchList, error) {
+	var updated response.BranchList
+	err := c.put(ctx, fmt.Sprintf("/project/%d/branch", projectID), branches, &updated)
+	return updated, err
+}
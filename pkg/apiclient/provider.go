@@ -0,0 +1,78 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/app"
+)
+
+// GetProviderList returns a paginated list of providers.
+func (c *Client) GetProviderList(ctx context.Context, params ListParams) (response.PaginatedProviders, error) {
+	q := url.Values{}
+	params.addTo(q)
+	var providers response.PaginatedProviders
+	err := c.get(ctx, "/provider", q, &providers)
+	return providers, err
+}
+
+// GetProvider returns a provider by its ID.
+func (c *Client) GetProvider(ctx context.Context, providerID uint) (response.Provider, error) {
+	var provider response.Provider
+	err := c.get(ctx, fmt.Sprintf("/provider/%d", providerID), nil, &provider)
+	return provider, err
+}
+
+// CreateProvider adds a new provider.
+func (c *Client) CreateProvider(ctx context.Context, provider request.Provider) (response.Provider, error) {
+	var created response.Provider
+	err := c.post(ctx, "/provider", nil, provider, &created)
+	return created, err
+}
+
+// UpdateProvider replaces all fields of a provider.
+func (c *Client) UpdateProvider(ctx context.Context, providerID uint, provider request.ProviderUpdate) (response.Provider, error) {
+	var updated response.Provider
+	err := c.put(ctx, fmt.Sprintf("/provider/%d", providerID), provider, &updated)
+	return updated, err
+}
+
+// GetTokenList returns a paginated list of tokens.
+func (c *Client) GetTokenList(ctx context.Context, params ListParams) (response.PaginatedTokens, error) {
+	q := url.Values{}
+	params.addTo(q)
+	var tokens response.PaginatedTokens
+	err := c.get(ctx, "/token", q, &tokens)
+	return tokens, err
+}
+
+// GetToken returns a token by its ID.
+func (c *Client) GetToken(ctx context.Context, tokenID uint) (response.Token, error) {
+	var token response.Token
+	err := c.get(ctx, fmt.Sprintf("/token/%d", tokenID), nil, &token)
+	return token, err
+}
+
+// CreateToken adds a new token.
+func (c *Client) CreateToken(ctx context.Context, token request.Token) (response.Token, error) {
+	var created response.Token
+	err := c.post(ctx, "/token", nil, token, &created)
+	return created, err
+}
+
+// UpdateToken replaces all fields of a token.
+func (c *Client) UpdateToken(ctx context.Context, tokenID uint, token request.TokenUpdate) (response.Token, error) {
+	var updated response.Token
+	err := c.put(ctx, fmt.Sprintf("/token/%d", tokenID), token, &updated)
+	return updated, err
+}
+
+// GetVersion returns the version of the wharf-api.
+func (c *Client) GetVersion(ctx context.Context) (app.Version, error) {
+	var version app.Version
+	err := c.get(ctx, "/version", nil, &version)
+	return version, err
+}
@@ -36,6 +36,17 @@ type TimeMetadata struct {
 	UpdatedAt *time.Time `gorm:"nullable"`
 }
 
+// TimeMetadataColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var TimeMetadataColumns = struct {
+	CreatedAt SafeSQLName
+	UpdatedAt SafeSQLName
+}{
+	CreatedAt: "created_at",
+	UpdatedAt: "updated_at",
+}
+
 // SafeSQLName represents a value that is safe to use as an SQL table or column
 // name without the need of escaping.
 //
@@ -50,12 +61,18 @@ var ProviderFields = struct {
 	ProviderID string
 	Name       string
 	URL        string
+	UploadURL  string
+	Metadata   string
 	TokenID    string
+	Version    string
 }{
 	ProviderID: "ProviderID",
 	Name:       "Name",
 	URL:        "URL",
+	UploadURL:  "UploadURL",
+	Metadata:   "Metadata",
 	TokenID:    "TokenID",
+	Version:    "Version",
 }
 
 // ProviderColumns holds the DB column names for each field.
@@ -66,23 +83,50 @@ var ProviderColumns = struct {
 	Name       SafeSQLName
 	URL        SafeSQLName
 	TokenID    SafeSQLName
+	Version    SafeSQLName
 }{
 	ProviderID: "provider_id",
 	Name:       "name",
 	URL:        "url",
 	TokenID:    "token_id",
+	Version:    "version",
+}
+
+// ProviderSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProviderSizes = struct {
+	Name      int
+	URL       int
+	UploadURL int
+}{
+	Name:      100,
+	URL:       500,
+	UploadURL: 500,
 }
 
+// ProviderTable is the name of the Provider DB table.
+const ProviderTable = "provider"
+
 // Provider holds metadata about a connection to a remote provider. Some of
 // importance are the URL field of where to find the remote, and the token field
 // used to authenticate.
 type Provider struct {
 	TimeMetadata
 	ProviderID uint   `gorm:"primaryKey"`
-	Name       string `gorm:"size:20;not null"`
+	Name       string `gorm:"size:100;not null"`
 	URL        string `gorm:"size:500;not null"`
 	TokenID    uint   `gorm:"nullable;default:NULL;index:provider_idx_token_id"`
 	Token      *Token `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT"`
+	// Version is incremented on each update, and is used as the provider's
+	// entity tag (ETag) in optimistic concurrency control.
+	Version uint `gorm:"not null;default:0"`
+	// UploadURL is the URL used when uploading files to the remote provider,
+	// such as GitHub Enterprise, where it differs from the API URL.
+	UploadURL string `gorm:"size:500;not null;default:''"`
+	// Metadata holds provider-specific settings, only used by the provider
+	// plugins.
+	Metadata JSONObject `gorm:"nullable"`
 }
 
 // TokenFields holds the Go struct field names for each field.
@@ -92,10 +136,12 @@ var TokenFields = struct {
 	TokenID  string
 	Token    string
 	UserName string
+	Version  string
 }{
 	TokenID:  "TokenID",
 	Token:    "Token",
 	UserName: "UserName",
+	Version:  "Version",
 }
 
 // TokenColumns holds the DB column names for each field.
@@ -105,73 +151,135 @@ var TokenColumns = struct {
 	TokenID  SafeSQLName
 	Token    SafeSQLName
 	UserName SafeSQLName
+	Version  SafeSQLName
 }{
 	TokenID:  "token_id",
 	Token:    "token",
 	UserName: "user_name",
+	Version:  "version",
+}
+
+// TokenSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var TokenSizes = struct {
+	Value    int
+	UserName int
+}{
+	Value:    500,
+	UserName: 500,
 }
 
+// TokenTable is the name of the Token DB table.
+const TokenTable = "token"
+
 // Token holds credentials for a remote provider.
 type Token struct {
 	TimeMetadata
 	TokenID  uint   `gorm:"primaryKey"`
 	Value    string `gorm:"size:500;not null"`
 	UserName string `gorm:"size:500;not null;default:''"`
+	// Version is incremented on each update, and is used as the token's
+	// entity tag (ETag) in optimistic concurrency control.
+	Version uint `gorm:"not null;default:0"`
 }
 
 // ProjectFields holds the Go struct field names for each field.
 // Useful in GORM .Where() statements to only select certain fields or in GORM
 // Preload statements to select the correct field to preload.
 var ProjectFields = struct {
-	ProjectID       string
-	RemoteProjectID string
-	Name            string
-	GroupName       string
-	Description     string
-	AvatarURL       string
-	TokenID         string
-	Token           string
-	ProviderID      string
-	Provider        string
-	BuildDefinition string
-	Branches        string
-	GitURL          string
-	Overrides       string
-}{
-	ProjectID:       "ProjectID",
-	Name:            "Name",
-	GroupName:       "GroupName",
-	Description:     "Description",
-	AvatarURL:       "AvatarURL",
-	TokenID:         "TokenID",
-	Token:           "Token",
-	ProviderID:      "ProviderID",
-	Provider:        "Provider",
-	BuildDefinition: "BuildDefinition",
-	Branches:        "Branches",
-	GitURL:          "GitURL",
-	Overrides:       "Overrides",
+	ProjectID         string
+	RemoteProjectID   string
+	Name              string
+	GroupName         string
+	Description       string
+	AvatarURL         string
+	TokenID           string
+	Token             string
+	ProviderID        string
+	Provider          string
+	BuildDefinition   string
+	Branches          string
+	Environments      string
+	GitURL            string
+	Overrides         string
+	Version           string
+	Labels            string
+	InstanceID        string
+	IsArchived        string
+	SchedulingTimeout string
+	RunningTimeout    string
+	LastBuildNumber   string
+}{
+	ProjectID:         "ProjectID",
+	Name:              "Name",
+	GroupName:         "GroupName",
+	Description:       "Description",
+	AvatarURL:         "AvatarURL",
+	TokenID:           "TokenID",
+	Token:             "Token",
+	ProviderID:        "ProviderID",
+	Provider:          "Provider",
+	BuildDefinition:   "BuildDefinition",
+	Branches:          "Branches",
+	Environments:      "Environments",
+	GitURL:            "GitURL",
+	Overrides:         "Overrides",
+	Version:           "Version",
+	Labels:            "Labels",
+	InstanceID:        "InstanceID",
+	IsArchived:        "IsArchived",
+	SchedulingTimeout: "SchedulingTimeout",
+	RunningTimeout:    "RunningTimeout",
+	LastBuildNumber:   "LastBuildNumber",
 }
 
 // ProjectColumns holds the DB column names for each field.
 // Useful in GORM .Order() statements to order the results based on a specific
 // column, which does not support the regular Go field names.
 var ProjectColumns = struct {
-	ProjectID       SafeSQLName
-	RemoteProjectID SafeSQLName
-	Name            SafeSQLName
-	GroupName       SafeSQLName
-	Description     SafeSQLName
-	TokenID         SafeSQLName
-	GitURL          SafeSQLName
-}{
-	ProjectID:       "project_id",
-	RemoteProjectID: "remote_project_id",
-	Name:            "name",
-	GroupName:       "group_name",
-	Description:     "description",
-	TokenID:         "token_id",
-	GitURL:          "git_url",
+	ProjectID         SafeSQLName
+	RemoteProjectID   SafeSQLName
+	Name              SafeSQLName
+	GroupName         SafeSQLName
+	Description       SafeSQLName
+	TokenID           SafeSQLName
+	GitURL            SafeSQLName
+	Version           SafeSQLName
+	InstanceID        SafeSQLName
+	SchedulingTimeout SafeSQLName
+	RunningTimeout    SafeSQLName
+	LastBuildNumber   SafeSQLName
+}{
+	ProjectID:         "project_id",
+	RemoteProjectID:   "remote_project_id",
+	Name:              "name",
+	GroupName:         "group_name",
+	Description:       "description",
+	TokenID:           "token_id",
+	GitURL:            "git_url",
+	Version:           "version",
+	InstanceID:        "instance_id",
+	SchedulingTimeout: "scheduling_timeout",
+	RunningTimeout:    "running_timeout",
+	LastBuildNumber:   "last_build_number",
+}
+
+// ProjectSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProjectSizes = struct {
+	Name        int
+	GroupName   int
+	Description int
+	AvatarURL   int
+	InstanceID  int
+}{
+	Name:        500,
+	GroupName:   500,
+	Description: 500,
+	AvatarURL:   500,
+	InstanceID:  100,
 }
 
 // Project holds data about an imported project. A lot of the data is expected
@@ -179,23 +287,174 @@ var ProjectColumns = struct {
 // and avatar.
 type Project struct {
 	TimeMetadata
-	ProjectID       uint      `gorm:"primaryKey"`
-	RemoteProjectID string    `gorm:"not null;default:''"`
-	Name            string    `gorm:"size:500;not null"`
-	GroupName       string    `gorm:"size:500;not null;default:''"`
-	Description     string    `gorm:"size:500;not null;default:''"`
-	AvatarURL       string    `gorm:"size:500;not null;default:''"`
-	TokenID         *uint     `gorm:"nullable;default:NULL;index:project_idx_token_id"`
-	Token           *Token    `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT"`
-	ProviderID      *uint     `gorm:"nullable;default:NULL;index:project_idx_provider_id"`
-	Provider        *Provider `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT"`
-	BuildDefinition string    `gorm:"not null;default:''"`
-	Branches        []Branch  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	GitURL          string    `gorm:"not null;default:''"`
+	ProjectID       uint          `gorm:"primaryKey"`
+	RemoteProjectID string        `gorm:"not null;default:''"`
+	Name            string        `gorm:"size:500;not null"`
+	GroupName       string        `gorm:"size:500;not null;default:''"`
+	Description     string        `gorm:"size:500;not null;default:''"`
+	AvatarURL       string        `gorm:"size:500;not null;default:''"`
+	TokenID         *uint         `gorm:"nullable;default:NULL;index:project_idx_token_id"`
+	Token           *Token        `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT"`
+	ProviderID      *uint         `gorm:"nullable;default:NULL;index:project_idx_provider_id"`
+	Provider        *Provider     `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT"`
+	BuildDefinition string        `gorm:"not null;default:''"`
+	Branches        []Branch      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Environments    []Environment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	GitURL          string        `gorm:"not null;default:''"`
+	// Version is incremented on each update, and is used as the project's
+	// entity tag (ETag) in optimistic concurrency control.
+	Version uint `gorm:"not null;default:0"`
+	// InstanceID is the ID of the Wharf installation this project belongs to,
+	// when multiple installations share the same database.
+	InstanceID string `gorm:"size:100;not null;default:'';index:project_idx_instance_id"`
+	// IsArchived means the project is hidden from the project lists by
+	// default, and cannot start new builds, while its history is kept.
+	IsArchived bool `gorm:"not null;default:false"`
+	// SchedulingTimeout is the number of seconds this project's builds may
+	// stay in the Scheduling status before being marked as failed. Null means
+	// the ci.schedulingTimeout config is used, while 0 means no timeout.
+	SchedulingTimeout null.Int `gorm:"nullable;default:NULL"`
+	// RunningTimeout is the number of seconds this project's builds may stay
+	// in the Running status before being marked as failed. Null means the
+	// ci.runningTimeout config is used, while 0 means no timeout.
+	RunningTimeout null.Int `gorm:"nullable;default:NULL"`
+	// LastBuildNumber is the BuildNumber of the project's latest build. It is
+	// only written when creating the project and when starting builds, so
+	// that saving a project cannot overwrite a build number assigned in the
+	// meantime.
+	LastBuildNumber uint `gorm:"<-:create;not null;default:0"`
 
 	Overrides ProjectOverrides `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Labels    []ProjectLabel   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// ProjectTable is the name of the Project DB table.
+const ProjectTable = "project"
+
+// LabelSizes holds the DB column size limits of both ProjectLabel and
+// BuildLabel.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var LabelSizes = struct {
+	Key   int
+	Value int
+}{
+	Key:   100,
+	Value: 300,
+}
+
+// ProjectLabelFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProjectLabelFields = struct {
+	ProjectID string
+}{
+	ProjectID: "ProjectID",
+}
+
+// ProjectLabelColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProjectLabelColumns = struct {
+	ProjectID SafeSQLName
+	Key       SafeSQLName
+	Value     SafeSQLName
+}{
+	ProjectID: "project_id",
+	Key:       "key",
+	Value:     "value",
+}
+
+// ProjectLabelTable is the name of the ProjectLabel DB table.
+const ProjectLabelTable = "project_label"
+
+// ProjectLabel is a key-value pair used to categorize projects, such as by
+// the team that owns them.
+type ProjectLabel struct {
+	ProjectLabelID uint     `gorm:"primaryKey"`
+	ProjectID      uint     `gorm:"not null;uniqueIndex:projectlabel_idx_project_id_key"`
+	Project        *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Key            string   `gorm:"size:100;not null;uniqueIndex:projectlabel_idx_project_id_key"`
+	Value          string   `gorm:"size:300;not null;default:''"`
+}
+
+// ProjectGroupFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProjectGroupFields = struct {
+	ParentID string
+	Path     string
+}{
+	ParentID: "ParentID",
+	Path:     "Path",
+}
+
+// ProjectGroupColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProjectGroupColumns = struct {
+	ProjectGroupID SafeSQLName
+	ParentID       SafeSQLName
+	Name           SafeSQLName
+	Path           SafeSQLName
+}{
+	ProjectGroupID: "project_group_id",
+	ParentID:       "parent_id",
+	Name:           "name",
+	Path:           "path",
+}
+
+// ProjectGroup holds data about a group of projects. Groups are derived from
+// the projects' group names, where slashes separate nested groups. For
+// example, the group name "My Group/Stuff" results in the group "My Group"
+// with the subgroup "Stuff".
+type ProjectGroup struct {
+	TimeMetadata
+	ProjectGroupID uint          `gorm:"primaryKey"`
+	ParentID       *uint         `gorm:"nullable;default:NULL;index:projectgroup_idx_parent_id"`
+	Parent         *ProjectGroup `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Name is the last segment of the group's path.
+	Name string `gorm:"size:500;not null"`
+	// Path is the full group name, as used in Project.GroupName.
+	Path string `gorm:"size:500;not null;uniqueIndex:projectgroup_idx_path"`
+}
+
+// ProjectOverridesFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProjectOverridesFields = struct {
+	ProjectID string
+	Version   string
+}{
+	ProjectID: "ProjectID",
+	Version:   "Version",
+}
+
+// ProjectOverridesColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProjectOverridesColumns = struct {
+	ProjectID SafeSQLName
+	Version   SafeSQLName
+}{
+	ProjectID: "project_id",
+	Version:   "version",
+}
+
+// ProjectOverridesSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProjectOverridesSizes = struct {
+	Description int
+	AvatarURL   int
+}{
+	Description: 500,
+	AvatarURL:   500,
 }
 
+// ProjectOverridesTable is the name of the ProjectOverrides DB table.
+const ProjectOverridesTable = "project_overrides"
+
 // ProjectOverrides holds data about a project's overridden values.
 type ProjectOverrides struct {
 	ProjectOverridesID uint   `gorm:"primaryKey"`
@@ -203,32 +462,128 @@ type ProjectOverrides struct {
 	Description        string `gorm:"size:500;not null;default:''"`
 	AvatarURL          string `gorm:"size:500;not null;default:''"`
 	GitURL             string `gorm:"not null;default:''"`
+	// Version is incremented on each update, and is used as the project
+	// overrides' entity tag (ETag) in optimistic concurrency control.
+	Version uint `gorm:"not null;default:0"`
 }
 
-// BranchFields holds the Go struct field names for each field.
+// ProjectInputDefaultFields holds the Go struct field names for each field.
 // Useful in GORM .Where() statements to only select certain fields or in GORM
 // Preload statements to select the correct field to preload.
-var BranchFields = struct {
+var ProjectInputDefaultFields = struct {
+	ProjectID   string
+	Environment string
+}{
+	ProjectID:   "ProjectID",
+	Environment: "Environment",
+}
+
+// ProjectInputDefaultColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProjectInputDefaultColumns = struct {
+	ProjectID   SafeSQLName
+	Environment SafeSQLName
+	Name        SafeSQLName
+}{
+	ProjectID:   "project_id",
+	Environment: "environment",
+	Name:        "name",
+}
+
+// ProjectInputDefaultSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProjectInputDefaultSizes = struct {
+	Environment int
+	Name        int
+}{
+	Environment: 40,
+	Name:        100,
+}
+
+// ProjectInputDefault is a project's own default value of an input variable
+// declared in the project's build definition, which takes precedence over the
+// default value from the build definition.
+type ProjectInputDefault struct {
+	ProjectInputDefaultID uint     `gorm:"primaryKey"`
+	ProjectID             uint     `gorm:"not null;uniqueIndex:projectinputdefault_idx_project_id_environment_name"`
+	Project               *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Environment is the name of the environment that the default value
+	// applies to, or empty if it applies to builds on any environment.
+	Environment string `gorm:"size:40;not null;default:'';uniqueIndex:projectinputdefault_idx_project_id_environment_name"`
+	Name        string `gorm:"size:100;not null;uniqueIndex:projectinputdefault_idx_project_id_environment_name"`
+	Value       string `gorm:"not null;default:''"`
+}
+
+// ProjectEngineFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProjectEngineFields = struct {
 	ProjectID string
-	Name      string
-	Default   string
-	TokenID   string
+	EngineID  string
 }{
 	ProjectID: "ProjectID",
-	Name:      "Name",
-	Default:   "Default",
-	TokenID:   "TokenID",
+	EngineID:  "EngineID",
+}
+
+// ProjectEngineColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProjectEngineColumns = struct {
+	ProjectID SafeSQLName
+	EngineID  SafeSQLName
+}{
+	ProjectID: "project_id",
+	EngineID:  "engine_id",
+}
+
+// ProjectEngine is an execution engine that a project is allowed to start
+// builds on. Projects without any project engines are allowed to start builds
+// on any execution engine.
+type ProjectEngine struct {
+	ProjectEngineID uint     `gorm:"primaryKey"`
+	ProjectID       uint     `gorm:"not null;uniqueIndex:projectengine_idx_project_id_engine_id"`
+	Project         *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// EngineID is the ID of the execution engine from the wharf-api
+	// configuration.
+	EngineID string `gorm:"size:32;not null;uniqueIndex:projectengine_idx_project_id_engine_id"`
+}
+
+// BranchFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BranchFields = struct {
+	ProjectID   string
+	Name        string
+	Default     string
+	TokenID     string
+	LastBuildID string
+	LastBuiltOn string
+}{
+	ProjectID:   "ProjectID",
+	Name:        "Name",
+	Default:     "Default",
+	TokenID:     "TokenID",
+	LastBuildID: "LastBuildID",
+	LastBuiltOn: "LastBuiltOn",
 }
 
 // BranchColumns holds the DB column names for each field.
 // Useful in GORM .Order() statements to order the results based on a specific
 // column, which does not support the regular Go field names.
 var BranchColumns = struct {
-	BranchID SafeSQLName
-	Name     SafeSQLName
+	BranchID    SafeSQLName
+	ProjectID   SafeSQLName
+	Name        SafeSQLName
+	LastBuildID SafeSQLName
+	LastBuiltOn SafeSQLName
 }{
-	BranchID: "branch_id",
-	Name:     "name",
+	BranchID:    "branch_id",
+	ProjectID:   "project_id",
+	Name:        "name",
+	LastBuildID: "last_build_id",
+	LastBuiltOn: "last_built_on",
 }
 
 // Branch is a single branch in the VCS that can be targeted during builds.
@@ -242,6 +597,73 @@ type Branch struct {
 	Default   bool     `gorm:"not null"`
 	TokenID   uint     `gorm:"nullable;default:NULL;index:branch_idx_token_id"`
 	Token     Token    `gorm:"constraint:OnUpdate:RESTRICT,OnDelete:RESTRICT"`
+	// LastBuildID is the ID of the latest build on this branch, or null if the
+	// branch has never been built.
+	LastBuildID null.Int `gorm:"nullable;default:NULL"`
+	// LastBuiltOn is when the latest build on this branch was scheduled, or
+	// null if the branch has never been built.
+	LastBuiltOn null.Time `gorm:"nullable;default:NULL"`
+}
+
+// BranchTable is the name of the Branch DB table.
+const BranchTable = "branch"
+
+// EnvironmentFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var EnvironmentFields = struct {
+	EnvironmentID string
+	ProjectID     string
+	Name          string
+	Description   string
+	IsDiscovered  string
+	IsProtected   string
+}{
+	EnvironmentID: "EnvironmentID",
+	ProjectID:     "ProjectID",
+	Name:          "Name",
+	Description:   "Description",
+	IsDiscovered:  "IsDiscovered",
+	IsProtected:   "IsProtected",
+}
+
+// EnvironmentColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var EnvironmentColumns = struct {
+	EnvironmentID SafeSQLName
+	ProjectID     SafeSQLName
+	Name          SafeSQLName
+}{
+	EnvironmentID: "environment_id",
+	ProjectID:     "project_id",
+	Name:          "name",
+}
+
+// EnvironmentSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var EnvironmentSizes = struct {
+	Name        int
+	Description int
+}{
+	Name:        40,
+	Description: 500,
+}
+
+// Environment is a named deployment target of a project, such as "stage" or
+// "prod". Builds refer to environments by name via Build.Environment.
+type Environment struct {
+	TimeMetadata
+	EnvironmentID uint     `gorm:"primaryKey"`
+	ProjectID     uint     `gorm:"not null;uniqueIndex:environment_idx_project_id_name"`
+	Project       *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Name          string   `gorm:"size:40;not null;uniqueIndex:environment_idx_project_id_name"`
+	Description   string   `gorm:"size:500;not null;default:''"`
+	IsDiscovered  bool     `gorm:"not null;default:false"`
+	// IsProtected means builds targeting this environment must be approved
+	// before they are sent to the execution engine.
+	IsProtected bool `gorm:"not null;default:false"`
 }
 
 // BuildFields holds the Go struct field names for each field.
@@ -249,24 +671,72 @@ type Branch struct {
 // Preload statements to select the correct field to preload.
 var BuildFields = struct {
 	ProjectID           string
+	BuildNumber         string
 	StatusID            string
 	GitBranch           string
+	GitCommit           string
+	GitTag              string
 	Environment         string
 	Stage               string
 	WorkerID            string
+	EngineID            string
+	InstanceID          string
 	IsInvalid           string
 	Params              string
+	Labels              string
+	Comments            string
+	Links               string
+	StageResults        string
 	TestResultSummaries string
+	Duration            string
+	TriggeredByType     string
+	TriggeredByName     string
+	TriggeredBySubject  string
+	TriggerError        string
+	StatusMessage       string
+	BuildGroupID        string
+	CompletedOn         string
+	ScheduleAt          string
+	ApprovalStatus      string
+	ReviewedByType      string
+	ReviewedByName      string
+	ReviewedBySubject   string
+	ReviewedOn          string
+	ReviewComment       string
 }{
 	ProjectID:           "ProjectID",
+	BuildNumber:         "BuildNumber",
 	StatusID:            "StatusID",
 	GitBranch:           "GitBranch",
+	GitCommit:           "GitCommit",
+	GitTag:              "GitTag",
 	Environment:         "Environment",
 	Stage:               "Stage",
 	WorkerID:            "WorkerID",
+	EngineID:            "EngineID",
+	InstanceID:          "InstanceID",
 	IsInvalid:           "IsInvalid",
 	Params:              "Params",
+	Labels:              "Labels",
+	Comments:            "Comments",
+	Links:               "Links",
+	StageResults:        "StageResults",
 	TestResultSummaries: "TestResultSummaries",
+	Duration:            "Duration",
+	TriggeredByType:     "TriggeredByType",
+	TriggeredByName:     "TriggeredByName",
+	TriggeredBySubject:  "TriggeredBySubject",
+	TriggerError:        "TriggerError",
+	StatusMessage:       "StatusMessage",
+	BuildGroupID:        "BuildGroupID",
+	CompletedOn:         "CompletedOn",
+	ScheduleAt:          "ScheduleAt",
+	ApprovalStatus:      "ApprovalStatus",
+	ReviewedByType:      "ReviewedByType",
+	ReviewedByName:      "ReviewedByName",
+	ReviewedBySubject:   "ReviewedBySubject",
+	ReviewedOn:          "ReviewedOn",
+	ReviewComment:       "ReviewComment",
 }
 
 // BuildColumns holds the DB column names for each field.
@@ -274,33 +744,69 @@ var BuildFields = struct {
 // column, which does not support the regular Go field names.
 var BuildColumns = struct {
 	BuildID     SafeSQLName
+	ProjectID   SafeSQLName
+	BuildNumber SafeSQLName
 	StatusID    SafeSQLName
 	ScheduledOn SafeSQLName
 	StartedOn   SafeSQLName
 	CompletedOn SafeSQLName
+	ScheduleAt  SafeSQLName
 	GitBranch   SafeSQLName
+	GitCommit   SafeSQLName
+	GitTag      SafeSQLName
 	Environment SafeSQLName
 	Stage       SafeSQLName
 	IsInvalid   SafeSQLName
+	Duration    SafeSQLName
+	InstanceID  SafeSQLName
+	WorkerID    SafeSQLName
+
+	TriggeredByType    SafeSQLName
+	TriggeredByName    SafeSQLName
+	TriggeredBySubject SafeSQLName
 }{
 	BuildID:     "build_id",
+	ProjectID:   "project_id",
+	BuildNumber: "build_number",
 	StatusID:    "status_id",
 	ScheduledOn: "scheduled_on",
 	StartedOn:   "started_on",
 	CompletedOn: "completed_on",
+	ScheduleAt:  "schedule_at",
 	GitBranch:   "git_branch",
+	GitCommit:   "git_commit",
+	GitTag:      "git_tag",
 	Environment: "environment",
 	Stage:       "stage",
 	IsInvalid:   "is_invalid",
+	Duration:    "duration",
+	InstanceID:  "instance_id",
+	WorkerID:    "worker_id",
+
+	TriggeredByType:    "triggered_by_type",
+	TriggeredByName:    "triggered_by_name",
+	TriggeredBySubject: "triggered_by_subject",
 }
 
 // BuildSizes holds the DB column size limits.
 // Useful when validating the fields attempting to insert values into the
 // database.
 var BuildSizes = struct {
-	EngineID int
+	EngineID        int
+	GitCommit       int
+	GitTag          int
+	TriggeredByName int
+	TriggerError    int
+	StatusMessage   int
+	ReviewComment   int
 }{
-	EngineID: 32,
+	EngineID:        32,
+	GitCommit:       64,
+	GitTag:          300,
+	TriggeredByName: 300,
+	TriggerError:    500,
+	StatusMessage:   500,
+	ReviewComment:   500,
 }
 
 // BuildTable is the name of the Build DB table.
@@ -311,22 +817,112 @@ const BuildTable = "build"
 type Build struct {
 	TimeMetadata
 	BuildID             uint         `gorm:"primaryKey"`
-	StatusID            BuildStatus  `gorm:"not null"`
-	ProjectID           uint         `gorm:"not null;index:build_idx_project_id"`
+	StatusID            BuildStatus  `gorm:"not null;index:build_idx_project_id_status_id,priority:2"`
+	ProjectID           uint         `gorm:"not null;index:build_idx_project_id;index:build_idx_project_id_status_id,priority:1;index:build_idx_project_id_scheduled_on,priority:1;index:build_idx_project_id_build_number,priority:1"`
 	Project             *Project     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	ScheduledOn         null.Time    `gorm:"nullable;default:NULL"`
+	ScheduledOn         null.Time    `gorm:"nullable;default:NULL;index:build_idx_project_id_scheduled_on,priority:2,sort:desc"`
 	StartedOn           null.Time    `gorm:"nullable;default:NULL"`
 	CompletedOn         null.Time    `gorm:"nullable;default:NULL"`
 	GitBranch           string       `gorm:"size:300;not null;default:''"`
+	GitCommit           string       `gorm:"size:64;not null;default:''"`
+	GitTag              string       `gorm:"size:300;not null;default:''"`
 	Environment         null.String  `gorm:"nullable;size:40" swaggertype:"string"`
 	Stage               string       `gorm:"size:40;not null;default:''"`
 	WorkerID            string       `gorm:"size:40;not null;default:'';index:build_idx_worker_id"`
 	Params              []BuildParam `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	IsInvalid           bool         `gorm:"not null;default:false"`
 	TestResultSummaries []TestResultSummary
-	EngineID            string `gorm:"size:32;not null;default:''"`
+	EngineID            string `gorm:"size:32;not null;default:'';index:build_idx_engine_id"`
+	// Duration is the number of milliseconds between StartedOn and
+	// CompletedOn, or null if either of them are unset.
+	Duration null.Int `gorm:"nullable;default:NULL;index:build_idx_duration"`
+	// InstanceID is the ID of the Wharf installation this build belongs to,
+	// which is always the same as the build's project.
+	InstanceID string `gorm:"size:100;not null;default:'';index:build_idx_instance_id"`
+	// BuildNumber is the number of the build within its project, starting at
+	// 1 and increasing by one with each build of the project.
+	BuildNumber uint `gorm:"not null;default:0;index:build_idx_project_id_build_number,priority:2"`
+	// ScheduleAt is when the build is sent to the execution engine, for builds
+	// that were started with a scheduled time, and that have the BuildPending
+	// status until then. Null if the build was sent right away.
+	ScheduleAt null.Time `gorm:"nullable;default:NULL;index:build_idx_schedule_at"`
+
+	TriggeredByType    BuildTriggerType `gorm:"size:20;not null;default:''"`
+	TriggeredByName    string           `gorm:"size:300;not null;default:''"`
+	TriggeredBySubject string           `gorm:"size:300;not null;default:''"`
+
+	// TriggerError is the error message from the latest failed attempt to
+	// trigger the build, if the build has the BuildTriggerFailed status.
+	TriggerError string `gorm:"size:500;not null;default:''"`
+
+	// StatusMessage is the reason of the build's latest status, as reported
+	// by the worker running the build, such as why the build failed.
+	StatusMessage string `gorm:"size:500;not null;default:''"`
+
+	// BuildGroupID is the ID of the build group this build was started as
+	// part of, such as when starting a matrix of builds, or null if the build
+	// was started on its own.
+	BuildGroupID null.Int `gorm:"nullable;default:NULL;index:build_idx_build_group_id"`
+
+	// ApprovalStatus is BuildApprovalNotRequired unless the build targets a
+	// protected environment, in which case the Reviewed fields hold who
+	// approved or rejected the build.
+	ApprovalStatus    BuildApprovalStatus `gorm:"size:20;not null;default:''"`
+	ReviewedByType    BuildTriggerType    `gorm:"size:20;not null;default:''"`
+	ReviewedByName    string              `gorm:"size:300;not null;default:''"`
+	ReviewedBySubject string              `gorm:"size:300;not null;default:''"`
+	ReviewedOn        null.Time           `gorm:"nullable;default:NULL"`
+	ReviewComment     string              `gorm:"size:500;not null;default:''"`
+
+	Labels   []BuildLabel   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Comments []BuildComment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Links    []BuildLink    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	StageResults []BuildStageResult `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
+// BuildApprovalStatus is an enum of the states of the manual approval of a
+// build that targets a protected environment.
+type BuildApprovalStatus string
+
+const (
+	// BuildApprovalNotRequired means the build does not need to be approved.
+	BuildApprovalNotRequired BuildApprovalStatus = ""
+	// BuildApprovalPending means the build is waiting to be approved or
+	// rejected.
+	BuildApprovalPending BuildApprovalStatus = "Pending"
+	// BuildApprovalApproved means the build was approved, and then sent to
+	// the execution engine.
+	BuildApprovalApproved BuildApprovalStatus = "Approved"
+	// BuildApprovalRejected means the build was rejected, and was never sent
+	// to the execution engine.
+	BuildApprovalRejected BuildApprovalStatus = "Rejected"
+)
+
+// BuildTriggerType is an enum of different sources that can start a build.
+type BuildTriggerType string
+
+const (
+	// BuildTriggerUnknown means it is unknown what started the build. Such as
+	// for builds started before v5.3.0, or when no authentication is
+	// configured.
+	BuildTriggerUnknown BuildTriggerType = ""
+	// BuildTriggerOIDC means the build was started by a user authenticated via
+	// an OIDC access token.
+	BuildTriggerOIDC BuildTriggerType = "oidc"
+	// BuildTriggerBasicAuth means the build was started by a user
+	// authenticated via HTTP basic authentication.
+	BuildTriggerBasicAuth BuildTriggerType = "basic-auth"
+	// BuildTriggerWebhook means the build was started by an inbound webhook.
+	BuildTriggerWebhook BuildTriggerType = "webhook"
+	// BuildTriggerSchedule means the build was started by a scheduled
+	// trigger.
+	BuildTriggerSchedule BuildTriggerType = "schedule"
+	// BuildTriggerBuild means the build was started by a build trigger, when
+	// a build of another project completed.
+	BuildTriggerBuild BuildTriggerType = "build"
+)
+
 // BuildStatus is an enum of different states for a build.
 type BuildStatus int
 
@@ -343,24 +939,55 @@ const (
 	// misconfiguration in the .wharf-ci.yml file, or perhaps a scripting error
 	// in some build step.
 	BuildFailed
+	// BuildTriggerFailed means that the execution engine could not be
+	// triggered to start the build, so the build was never started.
+	BuildTriggerFailed
+	// BuildAwaitingApproval means the build targets a protected environment,
+	// and will not be sent to the execution engine until it is approved.
+	BuildAwaitingApproval
+	// BuildPending means the build was started with a scheduled time, and
+	// will not be sent to the execution engine until that time.
+	BuildPending
 )
 
 // IsValid returns false if the underlying type is an unknown enum value.
 // 	BuildScheduling.IsValid()   // => true
 // 	(BuildStatus(-1)).IsValid() // => false
 func (buildStatus BuildStatus) IsValid() bool {
-	return buildStatus >= BuildScheduling && buildStatus <= BuildFailed
+	return buildStatus >= BuildScheduling && buildStatus <= BuildPending
 }
 
 // BuildParamFields holds the Go struct field names for each field.
 // Useful in GORM .Where() statements to only select certain fields or in GORM
 // Preload statements to select the correct field to preload.
 var BuildParamFields = struct {
-	Value string
+	Value    string
+	IsSecret string
+}{
+	Value:    "Value",
+	IsSecret: "IsSecret",
+}
+
+// BuildParamColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildParamColumns = struct {
+	BuildParamID SafeSQLName
+	BuildID      SafeSQLName
+	Name         SafeSQLName
+	Value        SafeSQLName
+	IsSecret     SafeSQLName
 }{
-	Value: "Value",
+	BuildParamID: "build_param_id",
+	BuildID:      "build_id",
+	Name:         "name",
+	Value:        "value",
+	IsSecret:     "is_secret",
 }
 
+// BuildParamTable is the name of the BuildParam DB table.
+const BuildParamTable = "build_param"
+
 // BuildParam holds the name and value of an input parameter fed into a build.
 type BuildParam struct {
 	BuildParamID uint   `gorm:"primaryKey"`
@@ -368,6 +995,232 @@ type BuildParam struct {
 	Build        *Build `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Name         string `gorm:"not null"`
 	Value        string `gorm:"not null;default:''"`
+	// IsSecret is true if the parameter comes from an input of type
+	// "password". The Value of secret parameters is stored encrypted.
+	IsSecret bool `gorm:"not null;default:false"`
+}
+
+// BuildLabelFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildLabelFields = struct {
+	BuildID string
+}{
+	BuildID: "BuildID",
+}
+
+// BuildLabelColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildLabelColumns = struct {
+	BuildID SafeSQLName
+	Key     SafeSQLName
+	Value   SafeSQLName
+}{
+	BuildID: "build_id",
+	Key:     "key",
+	Value:   "value",
+}
+
+// BuildLabelTable is the name of the BuildLabel DB table.
+const BuildLabelTable = "build_label"
+
+// BuildLabel is a key-value pair used to categorize builds, such as by the
+// release train they are part of.
+type BuildLabel struct {
+	BuildLabelID uint   `gorm:"primaryKey"`
+	BuildID      uint   `gorm:"not null;uniqueIndex:buildlabel_idx_build_id_key"`
+	Build        *Build `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Key          string `gorm:"size:100;not null;uniqueIndex:buildlabel_idx_build_id_key"`
+	Value        string `gorm:"size:300;not null;default:''"`
+}
+
+// BuildCommentFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildCommentFields = struct {
+	BuildID string
+}{
+	BuildID: "BuildID",
+}
+
+// BuildCommentColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildCommentColumns = struct {
+	BuildCommentID SafeSQLName
+	BuildID        SafeSQLName
+}{
+	BuildCommentID: "build_comment_id",
+	BuildID:        "build_id",
+}
+
+// BuildCommentSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var BuildCommentSizes = struct {
+	Text int
+}{
+	Text: 10000,
+}
+
+// BuildComment is a comment on a build, such as notes on why the build failed
+// and what was done about it.
+type BuildComment struct {
+	TimeMetadata
+	BuildCommentID uint             `gorm:"primaryKey"`
+	BuildID        uint             `gorm:"not null;index:buildcomment_idx_build_id"`
+	Build          *Build           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	AuthorType     BuildTriggerType `gorm:"size:20;not null;default:''"`
+	AuthorName     string           `gorm:"size:300;not null;default:''"`
+	AuthorSubject  string           `gorm:"size:300;not null;default:''"`
+	// Text is the comment's content, formatted as Markdown.
+	Text string `gorm:"size:10000;not null;default:''"`
+}
+
+// BuildLinkFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildLinkFields = struct {
+	BuildID string
+}{
+	BuildID: "BuildID",
+}
+
+// BuildLinkColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildLinkColumns = struct {
+	BuildLinkID SafeSQLName
+}{
+	BuildLinkID: "build_link_id",
+}
+
+// BuildLinkSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var BuildLinkSizes = struct {
+	Name int
+	URL  int
+}{
+	Name: 100,
+	URL:  500,
+}
+
+// BuildLink is an external link of a build, such as to a test report or a
+// deployment dashboard.
+type BuildLink struct {
+	TimeMetadata
+	BuildLinkID uint   `gorm:"primaryKey"`
+	BuildID     uint   `gorm:"not null;index:buildlink_idx_build_id"`
+	Build       *Build `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Name        string `gorm:"size:100;not null;default:''"`
+	URL         string `gorm:"size:500;not null;default:''"`
+}
+
+// BuildStageResultFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildStageResultFields = struct {
+	BuildID     string
+	Name        string
+	StartedOn   string
+	CompletedOn string
+	Duration    string
+}{
+	BuildID:     "BuildID",
+	Name:        "Name",
+	StartedOn:   "StartedOn",
+	CompletedOn: "CompletedOn",
+	Duration:    "Duration",
+}
+
+// BuildStageResultColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildStageResultColumns = struct {
+	BuildStageResultID SafeSQLName
+}{
+	BuildStageResultID: "build_stage_result_id",
+}
+
+// BuildStageResultSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var BuildStageResultSizes = struct {
+	Name          int
+	StatusMessage int
+}{
+	Name:          40,
+	StatusMessage: 500,
+}
+
+// BuildStageResult is the result of a single stage of a build, as reported by
+// the worker running the build.
+type BuildStageResult struct {
+	TimeMetadata
+	BuildStageResultID uint        `gorm:"primaryKey"`
+	BuildID            uint        `gorm:"not null;index:buildstageresult_idx_build_id"`
+	Build              *Build      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Name               string      `gorm:"size:40;not null;default:''"`
+	StatusID           BuildStatus `gorm:"not null"`
+	StatusMessage      string      `gorm:"size:500;not null;default:''"`
+	StartedOn          null.Time   `gorm:"nullable;default:NULL"`
+	CompletedOn        null.Time   `gorm:"nullable;default:NULL"`
+	// Duration is the number of milliseconds between StartedOn and
+	// CompletedOn, or null if either of them are unset.
+	Duration null.Int `gorm:"nullable;default:NULL"`
+}
+
+// BuildStatusTransitionFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildStatusTransitionFields = struct {
+	BuildID string
+}{
+	BuildID: "BuildID",
+}
+
+// BuildStatusTransitionColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildStatusTransitionColumns = struct {
+	BuildStatusTransitionID SafeSQLName
+	BuildID                 SafeSQLName
+	Timestamp               SafeSQLName
+}{
+	BuildStatusTransitionID: "build_status_transition_id",
+	BuildID:                 "build_id",
+	Timestamp:               "timestamp",
+}
+
+// BuildStatusTransitionSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var BuildStatusTransitionSizes = struct {
+	Message int
+}{
+	Message: 500,
+}
+
+// BuildStatusTransition is a change of a build's status, kept so that the
+// build's history is not lost when its status is overwritten.
+type BuildStatusTransition struct {
+	BuildStatusTransitionID uint   `gorm:"primaryKey"`
+	BuildID                 uint   `gorm:"not null;index:buildstatustransition_idx_build_id"`
+	Build                   *Build `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// PreviousStatusID is the status before the transition, or null for the
+	// transition into the build's initial status when the build was created.
+	PreviousStatusID null.Int    `gorm:"nullable;default:NULL"`
+	StatusID         BuildStatus `gorm:"not null"`
+	Timestamp        time.Time   `gorm:"not null"`
+	// Actor fields hold who or what changed the status, such as the user
+	// that approved the build. Left empty when changed by wharf-api itself,
+	// such as when the build timed out.
+	ActorType    BuildTriggerType `gorm:"size:20;not null;default:''"`
+	ActorName    string           `gorm:"size:300;not null;default:''"`
+	ActorSubject string           `gorm:"size:300;not null;default:''"`
+	Message      string           `gorm:"size:500;not null;default:''"`
 }
 
 // LogColumns holds the DB column names for each field.
@@ -421,26 +1274,53 @@ type Param struct {
 // Useful in GORM .Order() statements to order the results based on a specific
 // column, which does not support the regular Go field names.
 var ArtifactColumns = struct {
-	ArtifactID SafeSQLName
-	Name       SafeSQLName
-	FileName   SafeSQLName
+	ArtifactID  SafeSQLName
+	BuildID     SafeSQLName
+	Name        SafeSQLName
+	FileName    SafeSQLName
+	Size        SafeSQLName
+	ContentType SafeSQLName
+	Data        SafeSQLName
 }{
-	ArtifactID: "artifact_id",
-	Name:       "name",
-	FileName:   "file_name",
+	ArtifactID:  "artifact_id",
+	BuildID:     "build_id",
+	Name:        "name",
+	FileName:    "file_name",
+	Size:        "size",
+	ContentType: "content_type",
+	Data:        "data",
 }
 
 // ArtifactFields holds the Go struct field names for each field.
 // Useful in GORM .Where() statements to only select certain fields or in GORM
 // Preload statements to select the correct field to preload.
 var ArtifactFields = struct {
-	BuildID  string
-	Name     string
-	FileName string
+	BuildID                string
+	Build                  string
+	Name                   string
+	FileName               string
+	Size                   string
+	ContentType            string
+	PromotedFromBuildID    string
+	PromotedFromArtifactID string
+}{
+	BuildID:                "BuildID",
+	Build:                  "Build",
+	Name:                   "Name",
+	FileName:               "FileName",
+	Size:                   "Size",
+	ContentType:            "ContentType",
+	PromotedFromBuildID:    "PromotedFromBuildID",
+	PromotedFromArtifactID: "PromotedFromArtifactID",
+}
+
+// ArtifactSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ArtifactSizes = struct {
+	ContentType int
 }{
-	BuildID:  "BuildID",
-	Name:     "Name",
-	FileName: "FileName",
+	ContentType: 255,
 }
 
 // Artifact holds the binary data as well as metadata about that binary such as
@@ -452,34 +1332,189 @@ type Artifact struct {
 	Build      *Build `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Name       string `gorm:"not null"`
 	FileName   string `gorm:"not null;default:''"`
-	Data       []byte `gorm:"nullable"`
+	// Size is the number of bytes in Data.
+	Size        int64  `gorm:"not null;default:0"`
+	ContentType string `gorm:"size:255;not null;default:''"`
+	Data        []byte `gorm:"nullable"`
+	// PromotedFromBuildID and PromotedFromArtifactID are the IDs of the build
+	// and artifact that the artifact was promoted from, or null if the
+	// artifact was uploaded. Not foreign keys, so that the provenance is kept
+	// after the source build or artifact is deleted.
+	PromotedFromBuildID    null.Int `gorm:"nullable;default:NULL"`
+	PromotedFromArtifactID null.Int `gorm:"nullable;default:NULL"`
 }
 
-// TestResultSummaryFields holds the Go struct field names for each field.
+// ReleaseArtifactFields holds the Go struct field names for each field.
 // Useful in GORM .Where() statements to only select certain fields or in GORM
 // Preload statements to select the correct field to preload.
-var TestResultSummaryFields = struct {
-	FileName string
+var ReleaseArtifactFields = struct {
+	ReleaseArtifactID string
+	ProjectID         string
+	Release           string
+	FileName          string
 }{
-	FileName: "FileName",
+	ReleaseArtifactID: "ReleaseArtifactID",
+	ProjectID:         "ProjectID",
+	Release:           "Release",
+	FileName:          "FileName",
 }
 
-// TestResultSummary contains data about a single test result file.
-type TestResultSummary struct {
-	TimeMetadata
-	TestResultSummaryID uint      `gorm:"primaryKey"`
-	FileName            string    `gorm:"not null;default:''"`
-	ArtifactID          uint      `gorm:"not null;index:testresultsummary_idx_artifact_id"`
-	Artifact            *Artifact `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
-	BuildID             uint      `gorm:"not null;index:testresultsummary_idx_build_id"`
-	Build               *Build    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Total               uint      `gorm:"not null"`
-	Failed              uint      `gorm:"not null"`
-	Passed              uint      `gorm:"not null"`
-	Skipped             uint      `gorm:"not null"`
+// ReleaseArtifactColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ReleaseArtifactColumns = struct {
+	ReleaseArtifactID SafeSQLName
+	Data              SafeSQLName
+}{
+	ReleaseArtifactID: "release_artifact_id",
+	Data:              "data",
 }
 
-// TestResultStatus is an enum of different states a test result can be in.
+// ReleaseArtifactSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ReleaseArtifactSizes = struct {
+	Release           int
+	ContentType       int
+	PromotedByName    int
+	PromotedBySubject int
+}{
+	Release:           100,
+	ContentType:       255,
+	PromotedByName:    300,
+	PromotedBySubject: 300,
+}
+
+// ReleaseArtifact is a copy of a build artifact that has been promoted to one
+// of the project's releases, such as after the build's tests passed.
+type ReleaseArtifact struct {
+	TimeMetadata
+	ReleaseArtifactID uint     `gorm:"primaryKey"`
+	ProjectID         uint     `gorm:"not null;uniqueIndex:release_artifact_idx_project_id_release_file_name,priority:1"`
+	Project           *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Release is the name of the release, such as a version number.
+	Release     string `gorm:"size:100;not null;uniqueIndex:release_artifact_idx_project_id_release_file_name,priority:2"`
+	Name        string `gorm:"not null"`
+	FileName    string `gorm:"not null;default:'';uniqueIndex:release_artifact_idx_project_id_release_file_name,priority:3"`
+	Size        int64  `gorm:"not null;default:0"`
+	ContentType string `gorm:"size:255;not null;default:''"`
+	Data        []byte `gorm:"nullable"`
+	// PromotedFromBuildID and PromotedFromArtifactID are the IDs of the build
+	// and artifact that the artifact was promoted from. Not foreign keys, so
+	// that the provenance is kept after the source build or artifact is
+	// deleted.
+	PromotedFromBuildID    uint             `gorm:"not null"`
+	PromotedFromArtifactID uint             `gorm:"not null"`
+	PromotedByType         BuildTriggerType `gorm:"size:20;not null;default:''"`
+	PromotedByName         string           `gorm:"size:300;not null;default:''"`
+	PromotedBySubject      string           `gorm:"size:300;not null;default:''"`
+}
+
+// ProjectTransferFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProjectTransferFields = struct {
+	ProjectID string
+}{
+	ProjectID: "ProjectID",
+}
+
+// ProjectTransferColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProjectTransferColumns = struct {
+	ProjectTransferID SafeSQLName
+	ProjectID         SafeSQLName
+	Timestamp         SafeSQLName
+}{
+	ProjectTransferID: "project_transfer_id",
+	ProjectID:         "project_id",
+	Timestamp:         "timestamp",
+}
+
+// ProjectTransferSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProjectTransferSizes = struct {
+	ActorName    int
+	ActorSubject int
+}{
+	ActorName:    300,
+	ActorSubject: 300,
+}
+
+// ProjectTransfer is a transfer of a project to another group, provider, or
+// token, kept as an audit trail of who moved the project and when.
+type ProjectTransfer struct {
+	ProjectTransferID uint     `gorm:"primaryKey"`
+	ProjectID         uint     `gorm:"not null;index:projecttransfer_idx_project_id"`
+	Project           *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	FromGroupName     string   `gorm:"size:500;not null;default:''"`
+	ToGroupName       string   `gorm:"size:500;not null;default:''"`
+	// The provider and token IDs are 0 when the project had none. Not foreign
+	// keys, so that the audit trail is kept after the provider or token is
+	// deleted.
+	FromProviderID uint      `gorm:"not null;default:0"`
+	ToProviderID   uint      `gorm:"not null;default:0"`
+	FromTokenID    uint      `gorm:"not null;default:0"`
+	ToTokenID      uint      `gorm:"not null;default:0"`
+	Timestamp      time.Time `gorm:"not null"`
+	// Actor fields hold who transferred the project.
+	ActorType    BuildTriggerType `gorm:"size:20;not null;default:''"`
+	ActorName    string           `gorm:"size:300;not null;default:''"`
+	ActorSubject string           `gorm:"size:300;not null;default:''"`
+}
+
+// TestResultSummaryFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var TestResultSummaryFields = struct {
+	FileName   string
+	ArtifactID string
+}{
+	FileName:   "FileName",
+	ArtifactID: "ArtifactID",
+}
+
+// TestResultSummaryColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var TestResultSummaryColumns = struct {
+	TestResultSummaryID SafeSQLName
+	ArtifactID          SafeSQLName
+	BuildID             SafeSQLName
+	Total               SafeSQLName
+	Failed              SafeSQLName
+}{
+	TestResultSummaryID: "test_result_summary_id",
+	ArtifactID:          "artifact_id",
+	BuildID:             "build_id",
+	Total:               "total",
+	Failed:              "failed",
+}
+
+// TestResultSummaryTable is the name of the TestResultSummary DB table.
+const TestResultSummaryTable = "test_result_summary"
+
+// TestResultSummary contains data about a single test result file.
+type TestResultSummary struct {
+	TimeMetadata
+	TestResultSummaryID uint   `gorm:"primaryKey"`
+	FileName            string `gorm:"not null;default:''"`
+	// ArtifactID is the ID of the uploaded test result file, or nil if the
+	// artifact has since been deleted. The parsed test results are kept
+	// when the artifact is deleted.
+	ArtifactID *uint     `gorm:"nullable;index:testresultsummary_idx_artifact_id"`
+	Artifact   *Artifact `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	BuildID    uint      `gorm:"not null;index:testresultsummary_idx_build_id"`
+	Build      *Build    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Total      uint      `gorm:"not null"`
+	Failed     uint      `gorm:"not null"`
+	Passed     uint      `gorm:"not null"`
+	Skipped    uint      `gorm:"not null"`
+}
+
+// TestResultStatus is an enum of different states a test result can be in.
 type TestResultStatus string
 
 const (
@@ -491,17 +1526,690 @@ const (
 	TestResultStatusSkipped TestResultStatus = "Skipped"
 )
 
+// TestResultDetailFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var TestResultDetailFields = struct {
+	ArtifactID          string
+	TestResultSummaryID string
+	ClassName           string
+	FilePath            string
+	LineNumber          string
+}{
+	ArtifactID:          "ArtifactID",
+	TestResultSummaryID: "TestResultSummaryID",
+	ClassName:           "ClassName",
+	FilePath:            "FilePath",
+	LineNumber:          "LineNumber",
+}
+
+// TestResultDetailColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var TestResultDetailColumns = struct {
+	TestResultDetailID  SafeSQLName
+	ArtifactID          SafeSQLName
+	TestResultSummaryID SafeSQLName
+	ClassName           SafeSQLName
+	FilePath            SafeSQLName
+}{
+	TestResultDetailID:  "test_result_detail_id",
+	ArtifactID:          "artifact_id",
+	TestResultSummaryID: "test_result_summary_id",
+	ClassName:           "class_name",
+	FilePath:            "file_path",
+}
+
+// TestResultDetailSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var TestResultDetailSizes = struct {
+	ClassName int
+	FilePath  int
+}{
+	ClassName: 500,
+	FilePath:  500,
+}
+
+// TestResultDetailTable is the name of the TestResultDetail DB table.
+const TestResultDetailTable = "test_result_detail"
+
 // TestResultDetail contains data about a single test in a test result file.
 type TestResultDetail struct {
 	TimeMetadata
-	TestResultDetailID uint             `gorm:"primaryKey"`
-	ArtifactID         uint             `gorm:"not null;index:testresultdetail_idx_artifact_id"`
-	Artifact           *Artifact        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
-	BuildID            uint             `gorm:"not null;index:testresultdetail_idx_build_id"`
-	Build              *Build           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Name               string           `gorm:"not null"`
-	Message            null.String      `gorm:"nullable"`
-	StartedOn          null.Time        `gorm:"nullable;default:NULL;"`
-	CompletedOn        null.Time        `gorm:"nullable;default:NULL;"`
-	Status             TestResultStatus `gorm:"not null"`
+	TestResultDetailID uint `gorm:"primaryKey"`
+	// ArtifactID is the ID of the uploaded test result file, or nil if the
+	// artifact has since been deleted.
+	ArtifactID *uint     `gorm:"nullable;index:testresultdetail_idx_artifact_id"`
+	Artifact   *Artifact `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	// TestResultSummaryID is the ID of the summary of the test result file
+	// that the test was parsed from, which is kept after the artifact has
+	// been deleted.
+	TestResultSummaryID null.Int         `gorm:"nullable;default:NULL;index:testresultdetail_idx_test_result_summary_id"`
+	BuildID             uint             `gorm:"not null;index:testresultdetail_idx_build_id"`
+	Build               *Build           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Name                string           `gorm:"not null"`
+	Message             null.String      `gorm:"nullable"`
+	StartedOn           null.Time        `gorm:"nullable;default:NULL;"`
+	CompletedOn         null.Time        `gorm:"nullable;default:NULL;"`
+	Status              TestResultStatus `gorm:"not null"`
+	// ClassName is the fully qualified name of the class, or other grouping
+	// such as the package, that the test belongs to.
+	ClassName string `gorm:"size:500;not null;default:''"`
+	// FilePath is the path to the source file of the test, as reported by the
+	// test result file.
+	FilePath string `gorm:"size:500;not null;default:''"`
+	// LineNumber is the line in the source file of the test, if known.
+	LineNumber null.Int `gorm:"nullable;default:NULL"`
+}
+
+// SettingFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var SettingFields = struct {
+	Scope   string
+	Subject string
+	Key     string
+}{
+	Scope:   "Scope",
+	Subject: "Subject",
+	Key:     "Key",
+}
+
+// SettingColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var SettingColumns = struct {
+	Scope     SafeSQLName
+	Subject   SafeSQLName
+	Key       SafeSQLName
+	Value     SafeSQLName
+	UpdatedAt SafeSQLName
+}{
+	Scope:     "scope",
+	Subject:   "subject",
+	Key:       "key",
+	Value:     "value",
+	UpdatedAt: "updated_at",
+}
+
+// SettingScope is an enum of different scopes a setting can be stored in.
+type SettingScope string
+
+const (
+	// SettingScopeUser means the setting belongs to a single user, identified
+	// by the Setting.Subject field.
+	SettingScopeUser SettingScope = "user"
+	// SettingScopeInstance means the setting applies to the whole wharf-api
+	// instance. The Setting.Subject field is always empty.
+	SettingScopeInstance SettingScope = "instance"
+)
+
+// Setting holds a single user or instance-wide setting value.
+type Setting struct {
+	TimeMetadata
+	SettingID uint         `gorm:"primaryKey"`
+	Scope     SettingScope `gorm:"size:20;not null;uniqueIndex:setting_idx_scope_subject_key"`
+	// Subject is the OIDC subject of the user for user settings, or empty for
+	// instance-wide settings.
+	Subject string `gorm:"size:500;not null;default:'';uniqueIndex:setting_idx_scope_subject_key"`
+	Key     string `gorm:"size:100;not null;uniqueIndex:setting_idx_scope_subject_key"`
+	// Value is the JSON-encoded setting value.
+	Value string `gorm:"size:2000;not null"`
+}
+
+// IdempotencyKeyFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var IdempotencyKeyFields = struct {
+	Subject string
+	Key     string
+}{
+	Subject: "Subject",
+	Key:     "Key",
+}
+
+// IdempotencyKeyColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var IdempotencyKeyColumns = struct {
+	CreatedAt SafeSQLName
+}{
+	CreatedAt: "created_at",
+}
+
+// IdempotencyKeySizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var IdempotencyKeySizes = struct {
+	Key int
+}{
+	Key: 255,
+}
+
+// IdempotencyKey holds the response of a request that was sent with an
+// Idempotency-Key HTTP header, so that retries of the same request can be
+// responded to with the original response instead of being handled again.
+type IdempotencyKey struct {
+	IdempotencyKeyID uint      `gorm:"primaryKey"`
+	CreatedAt        time.Time `gorm:"not null;index:idempotency_key_idx_created_at"`
+	// Subject is the OIDC subject or HTTP basic authentication username of the
+	// user that sent the request, or empty if no authentication is used.
+	Subject string `gorm:"size:500;not null;default:'';uniqueIndex:idempotency_key_idx_subject_key"`
+	Key     string `gorm:"size:255;not null;uniqueIndex:idempotency_key_idx_subject_key"`
+	// RequestHash is the hex-encoded SHA-256 hash of the request method, URL,
+	// and body, used to reject reusing the same key for different requests.
+	RequestHash string `gorm:"size:64;not null"`
+	// IsCompleted is false while the original request is still being handled.
+	IsCompleted         bool   `gorm:"not null;default:false"`
+	ResponseStatus      int    `gorm:"not null;default:0"`
+	ResponseContentType string `gorm:"size:200;not null;default:''"`
+	ResponseBody        []byte `gorm:"nullable"`
+}
+
+// ProviderSyncFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProviderSyncFields = struct {
+	ProviderSyncID string
+	ProviderID     string
+	Status         string
+}{
+	ProviderSyncID: "ProviderSyncID",
+	ProviderID:     "ProviderID",
+	Status:         "Status",
+}
+
+// ProviderSyncColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProviderSyncColumns = struct {
+	StartedOn SafeSQLName
+}{
+	StartedOn: "started_on",
+}
+
+// ProviderSyncSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProviderSyncSizes = struct {
+	Error int
+}{
+	Error: 500,
+}
+
+// ProviderSyncStatus is an enum of different states a provider sync can be in.
+type ProviderSyncStatus string
+
+const (
+	// ProviderSyncRunning means the provider plugin has been asked to re-import
+	// the provider's projects and has not yet responded.
+	ProviderSyncRunning ProviderSyncStatus = "Running"
+	// ProviderSyncCompleted means the provider plugin successfully re-imported
+	// the provider's projects.
+	ProviderSyncCompleted ProviderSyncStatus = "Completed"
+	// ProviderSyncFailed means the request to the provider plugin failed, or
+	// the plugin responded with an error.
+	ProviderSyncFailed ProviderSyncStatus = "Failed"
+)
+
+// ProviderSync is a re-import of the projects and branches of a provider,
+// performed by the provider plugin, such as wharf-provider-github.
+type ProviderSync struct {
+	TimeMetadata
+	ProviderSyncID uint               `gorm:"primaryKey"`
+	ProviderID     uint               `gorm:"not null;index:provider_sync_idx_provider_id"`
+	Provider       *Provider          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Status         ProviderSyncStatus `gorm:"size:20;not null"`
+	StartedOn      null.Time          `gorm:"nullable;default:NULL"`
+	CompletedOn    null.Time          `gorm:"nullable;default:NULL"`
+	// Error is the error message of a failed sync.
+	Error string `gorm:"size:500;not null;default:''"`
+}
+
+// BuildGroupFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildGroupFields = struct {
+	BuildGroupID string
+	ProjectID    string
+}{
+	BuildGroupID: "BuildGroupID",
+	ProjectID:    "ProjectID",
+}
+
+// BuildGroupColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildGroupColumns = struct {
+	BuildGroupID SafeSQLName
+	ProjectID    SafeSQLName
+}{
+	BuildGroupID: "build_group_id",
+	ProjectID:    "project_id",
+}
+
+// BuildGroup is a set of builds that were started together, such as when
+// starting a matrix of builds over multiple branches and environments.
+type BuildGroup struct {
+	TimeMetadata
+	BuildGroupID uint     `gorm:"primaryKey"`
+	ProjectID    uint     `gorm:"not null;index:build_group_idx_project_id"`
+	Project      *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// BuildTriggerFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildTriggerFields = struct {
+	BuildTriggerID  string
+	SourceProjectID string
+	TargetProjectID string
+	BranchFilter    string
+	Stage           string
+}{
+	BuildTriggerID:  "BuildTriggerID",
+	SourceProjectID: "SourceProjectID",
+	TargetProjectID: "TargetProjectID",
+	BranchFilter:    "BranchFilter",
+	Stage:           "Stage",
+}
+
+// BuildTriggerColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildTriggerColumns = struct {
+	BuildTriggerID  SafeSQLName
+	SourceProjectID SafeSQLName
+	TargetProjectID SafeSQLName
+}{
+	BuildTriggerID:  "build_trigger_id",
+	SourceProjectID: "source_project_id",
+	TargetProjectID: "target_project_id",
+}
+
+// BuildTriggerSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var BuildTriggerSizes = struct {
+	BranchFilter int
+	Stage        int
+}{
+	BranchFilter: 300,
+	Stage:        40,
+}
+
+// BuildTrigger starts a build of the target project whenever a build of the
+// source project completes successfully.
+type BuildTrigger struct {
+	TimeMetadata
+	BuildTriggerID  uint     `gorm:"primaryKey"`
+	SourceProjectID uint     `gorm:"not null;index:build_trigger_idx_source_project_id"`
+	SourceProject   *Project `gorm:"foreignKey:SourceProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	TargetProjectID uint     `gorm:"not null;index:build_trigger_idx_target_project_id"`
+	TargetProject   *Project `gorm:"foreignKey:TargetProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// BranchFilter is a glob pattern, as matched by path.Match, of the Git
+	// branches of the source project's builds that starts the trigger. Empty
+	// matches all branches.
+	BranchFilter string `gorm:"size:300;not null;default:''"`
+	// Stage is the name of the stage to run in the target project's build.
+	Stage string `gorm:"size:40;not null;default:''"`
+}
+
+// NotificationRuleFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var NotificationRuleFields = struct {
+	NotificationRuleID string
+	ProjectID          string
+}{
+	NotificationRuleID: "NotificationRuleID",
+	ProjectID:          "ProjectID",
+}
+
+// NotificationRuleColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var NotificationRuleColumns = struct {
+	NotificationRuleID SafeSQLName
+	ProjectID          SafeSQLName
+}{
+	NotificationRuleID: "notification_rule_id",
+	ProjectID:          "project_id",
+}
+
+// NotificationRuleSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var NotificationRuleSizes = struct {
+	Name   int
+	Target int
+}{
+	Name:   100,
+	Target: 500,
+}
+
+// NotificationRule sends a notification whenever a build has stayed in the
+// Scheduling or Running status for longer than the rule's threshold.
+type NotificationRule struct {
+	TimeMetadata
+	NotificationRuleID uint   `gorm:"primaryKey"`
+	Name               string `gorm:"size:100;not null;default:''"`
+	// ProjectID is the ID of the project whose builds the rule applies to, or
+	// nil if the rule applies to the builds of all projects.
+	ProjectID *uint    `gorm:"nullable;index:notification_rule_idx_project_id"`
+	Project   *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// StatusID is the status the builds must have stayed in, which is either
+	// BuildScheduling or BuildRunning.
+	StatusID BuildStatus `gorm:"not null"`
+	// Threshold is the number of seconds the builds must have stayed in the
+	// status before the notification is sent.
+	Threshold int64 `gorm:"not null"`
+	// Notifier is how the notification is sent.
+	Notifier NotifierType `gorm:"size:20;not null"`
+	// Target is where the notification is sent, such as the URL of the
+	// webhook, or the comma-separated email addresses.
+	Target string `gorm:"size:500;not null"`
+}
+
+// NotifierType is an enum of ways to send notifications.
+type NotifierType string
+
+const (
+	// NotifierWebhook sends notifications as JSON in HTTP POST requests.
+	NotifierWebhook NotifierType = "webhook"
+	// NotifierSlack sends notifications to a Slack incoming webhook.
+	NotifierSlack NotifierType = "slack"
+	// NotifierTeams sends notifications to a Microsoft Teams incoming
+	// webhook.
+	NotifierTeams NotifierType = "teams"
+	// NotifierEmail sends notifications as emails, using the SMTP server
+	// configured in wharf-api.
+	NotifierEmail NotifierType = "email"
+)
+
+// BuildNotificationFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var BuildNotificationFields = struct {
+	NotificationRuleID string
+	BuildID            string
+	Error              string
+}{
+	NotificationRuleID: "NotificationRuleID",
+	BuildID:            "BuildID",
+	Error:              "Error",
+}
+
+// BuildNotificationColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildNotificationColumns = struct {
+	BuildID SafeSQLName
+}{
+	BuildID: "build_id",
+}
+
+// BuildNotificationSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var BuildNotificationSizes = struct {
+	Error int
+}{
+	Error: 500,
+}
+
+// BuildNotification is a notification that was sent for a build by a
+// notification rule, so that the same build is only notified about once per
+// rule.
+type BuildNotification struct {
+	BuildNotificationID uint              `gorm:"primaryKey"`
+	NotificationRuleID  uint              `gorm:"not null;uniqueIndex:buildnotification_idx_notification_rule_id_build_id,priority:1"`
+	NotificationRule    *NotificationRule `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	BuildID             uint              `gorm:"not null;uniqueIndex:buildnotification_idx_notification_rule_id_build_id,priority:2;index:buildnotification_idx_build_id"`
+	Build               *Build            `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	SentOn              time.Time         `gorm:"not null"`
+	// Error is the error message if sending the notification failed.
+	Error string `gorm:"size:500;not null;default:''"`
+}
+
+// ProjectSubscriptionFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProjectSubscriptionFields = struct {
+	Subject   string
+	ProjectID string
+}{
+	Subject:   "Subject",
+	ProjectID: "ProjectID",
+}
+
+// ProjectSubscriptionColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var ProjectSubscriptionColumns = struct {
+	ProjectID SafeSQLName
+}{
+	ProjectID: "project_id",
+}
+
+// ProjectSubscriptionSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProjectSubscriptionSizes = struct {
+	Subject int
+	Email   int
+}{
+	Subject: 255,
+	Email:   254,
+}
+
+// ProjectSubscription is a user's subscription to emails about the failed
+// builds of a project.
+type ProjectSubscription struct {
+	TimeMetadata
+	ProjectSubscriptionID uint `gorm:"primaryKey"`
+	// Subject is the OIDC subject ("sub" claim) of the subscribed user.
+	Subject   string   `gorm:"size:255;not null;uniqueIndex:projectsubscription_idx_subject_project_id,priority:1"`
+	ProjectID uint     `gorm:"not null;uniqueIndex:projectsubscription_idx_subject_project_id,priority:2;index:projectsubscription_idx_project_id"`
+	Project   *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Email is the email address that the emails are sent to. It is the same
+	// for all of the user's subscriptions.
+	Email string `gorm:"size:254;not null"`
+}
+
+// ProjectWebhookFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var ProjectWebhookFields = struct {
+	ProjectID string
+	TokenHash string
+}{
+	ProjectID: "ProjectID",
+	TokenHash: "TokenHash",
+}
+
+// ProjectWebhookSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var ProjectWebhookSizes = struct {
+	TokenHash int
+	Secret    int
+}{
+	TokenHash: 64,
+	Secret:    500,
+}
+
+// ProjectWebhook is an inbound webhook of a project, which lets external
+// systems start builds of the project by calling its secret URL.
+type ProjectWebhook struct {
+	TimeMetadata
+	ProjectWebhookID uint     `gorm:"primaryKey"`
+	ProjectID        uint     `gorm:"not null;uniqueIndex:projectwebhook_idx_project_id"`
+	Project          *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// TokenHash is the hex-encoded SHA-256 hash of the token in the webhook's
+	// URL. The token itself is not stored.
+	TokenHash string `gorm:"size:64;not null;uniqueIndex:projectwebhook_idx_token_hash"`
+	// Secret is the key of the HMAC-SHA256 signatures of the request bodies,
+	// encrypted using the db.encryptionKey config if set.
+	Secret string `gorm:"size:500;not null"`
+	// IsEnabled is false if calls to the webhook are rejected.
+	IsEnabled bool `gorm:"not null"`
+	// RequireSignature is true if calls to the webhook must be signed using
+	// the secret.
+	RequireSignature bool `gorm:"not null"`
+}
+
+// GitTriggerFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var GitTriggerFields = struct {
+	GitTriggerID string
+	ProjectID    string
+	Event        string
+}{
+	GitTriggerID: "GitTriggerID",
+	ProjectID:    "ProjectID",
+	Event:        "Event",
+}
+
+// GitTriggerColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var GitTriggerColumns = struct {
+	GitTriggerID SafeSQLName
+	ProjectID    SafeSQLName
+}{
+	GitTriggerID: "git_trigger_id",
+	ProjectID:    "project_id",
+}
+
+// GitTriggerSizes holds the DB column size limits.
+// Useful when validating the fields attempting to insert values into the
+// database.
+var GitTriggerSizes = struct {
+	BranchFilter int
+	Stage        int
+	Environment  int
+}{
+	BranchFilter: 300,
+	Stage:        40,
+	Environment:  40,
+}
+
+// GitTrigger starts a build of the project whenever the project's Git
+// repository receives a push or merge request, as reported by the GitHub or
+// GitLab webhooks.
+type GitTrigger struct {
+	TimeMetadata
+	GitTriggerID uint     `gorm:"primaryKey"`
+	ProjectID    uint     `gorm:"not null;index:git_trigger_idx_project_id"`
+	Project      *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Event is the kind of Git event that starts the trigger.
+	Event GitTriggerEvent `gorm:"size:20;not null"`
+	// BranchFilter is a glob pattern, as matched by path.Match, of the pushed
+	// branches, or of the target branches of merge requests, that starts the
+	// trigger. Empty matches all branches.
+	BranchFilter string `gorm:"size:300;not null;default:''"`
+	// Stage is the name of the stage to run in the build.
+	Stage string `gorm:"size:40;not null;default:''"`
+	// Environment is the environment to run the build in, or null to run all
+	// stages without any environment filters.
+	Environment null.String `gorm:"nullable;size:40" swaggertype:"string"`
+}
+
+// GitTriggerEvent is an enum of Git events that start Git triggers.
+type GitTriggerEvent string
+
+const (
+	// GitTriggerPush means the trigger starts a build of the pushed branch
+	// and commit, whenever commits are pushed to a branch.
+	GitTriggerPush GitTriggerEvent = "push"
+	// GitTriggerMergeRequest means the trigger starts a build of the source
+	// branch and latest commit of a merge request, or pull request in GitHub,
+	// whenever one is opened, reopened, or receives new commits.
+	GitTriggerMergeRequest GitTriggerEvent = "merge-request"
+)
+
+// EngineTokenFields holds the Go struct field names for each field.
+// Useful in GORM .Where() statements to only select certain fields or in GORM
+// Preload statements to select the correct field to preload.
+var EngineTokenFields = struct {
+	EngineID string
+	Value    string
+}{
+	EngineID: "EngineID",
+	Value:    "Value",
+}
+
+// EngineTokenColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var EngineTokenColumns = struct {
+	EngineID  SafeSQLName
+	Value     SafeSQLName
+	UpdatedAt SafeSQLName
+}{
+	EngineID:  "engine_id",
+	Value:     "value",
+	UpdatedAt: "updated_at",
+}
+
+// EngineToken is the token of an execution engine, which takes precedence over
+// the token of the engine from the wharf-api configuration. Allows rotating
+// engine tokens without redeploying the wharf-api.
+type EngineToken struct {
+	TimeMetadata
+	// EngineID is the ID of the execution engine from the wharf-api
+	// configuration.
+	EngineID string `gorm:"primaryKey;size:32"`
+	// Value is the token, encrypted using the db.encryptionKey config.
+	Value string `gorm:"not null"`
+}
+
+// BuildStatsDailyColumns holds the DB column names for each field.
+// Useful in GORM .Order() statements to order the results based on a specific
+// column, which does not support the regular Go field names.
+var BuildStatsDailyColumns = struct {
+	ProjectID     SafeSQLName
+	Day           SafeSQLName
+	Total         SafeSQLName
+	Completed     SafeSQLName
+	Failed        SafeSQLName
+	DurationSum   SafeSQLName
+	DurationCount SafeSQLName
+}{
+	ProjectID:     "project_id",
+	Day:           "day",
+	Total:         "total",
+	Completed:     "completed",
+	Failed:        "failed",
+	DurationSum:   "duration_sum",
+	DurationCount: "duration_count",
+}
+
+// BuildStatsDailyTable is the name of the BuildStatsDaily DB table.
+const BuildStatsDailyTable = "build_stats_daily"
+
+// BuildStatsDaily is the number of builds of a project that were scheduled on
+// a given day, aggregated from the builds by the build statistics rollup job,
+// so that statistics over long time ranges do not have to read every build.
+type BuildStatsDaily struct {
+	TimeMetadata
+	BuildStatsDailyID uint     `gorm:"primaryKey"`
+	ProjectID         uint     `gorm:"not null;uniqueIndex:buildstatsdaily_idx_project_id_day"`
+	Project           *Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Day is the UTC date that the builds were scheduled on, stored as the
+	// time at midnight UTC.
+	Day       time.Time `gorm:"not null;uniqueIndex:buildstatsdaily_idx_project_id_day;index:buildstatsdaily_idx_day"`
+	Total     uint      `gorm:"not null"`
+	Completed uint      `gorm:"not null"`
+	// Failed is the number of builds that failed, including the builds that
+	// failed to be triggered.
+	Failed uint `gorm:"not null"`
+	// DurationSum is the sum of the durations, in milliseconds, of the builds
+	// that have a duration. Divided by DurationCount it gives the average
+	// duration.
+	DurationSum   int64 `gorm:"not null"`
+	DurationCount uint  `gorm:"not null"`
 }
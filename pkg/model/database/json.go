@@ -0,0 +1,62 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// JSONObject is a JSON object stored as JSONB in PostgreSQL, and as JSON
+// encoded text in other databases. A nil JSONObject is stored as NULL.
+type JSONObject map[string]any
+
+// GormDataType implements gorm's schema.GormDataTypeInterface.
+func (JSONObject) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType implements gorm's migrator.GormDataTypeInterface, to use the
+// JSONB data type in PostgreSQL.
+func (JSONObject) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "JSONB"
+	}
+	return "TEXT"
+}
+
+// Value implements the driver.Valuer interface.
+func (obj JSONObject) Value() (driver.Value, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (obj *JSONObject) Scan(value any) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*obj = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("scan JSON object: unsupported type %T", value)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("scan JSON object: %w", err)
+	}
+	*obj = m
+	return nil
+}
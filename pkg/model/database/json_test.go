@@ -0,0 +1,31 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONObject_valueAndScan(t *testing.T) {
+	obj := JSONObject{"a": "b", "c": map[string]any{"d": true}}
+	value, err := obj.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":"b","c":{"d":true}}`, value)
+
+	var scanned JSONObject
+	assert.NoError(t, scanned.Scan([]byte(`{"a":"b","c":{"d":true}}`)))
+	assert.Equal(t, obj, scanned)
+
+	assert.NoError(t, scanned.Scan(`{"e":1}`))
+	assert.Equal(t, JSONObject{"e": float64(1)}, scanned)
+}
+
+func TestJSONObject_null(t *testing.T) {
+	value, err := JSONObject(nil).Value()
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	scanned := JSONObject{"a": "b"}
+	assert.NoError(t, scanned.Scan(nil))
+	assert.Nil(t, scanned)
+}
@@ -13,6 +13,7 @@ import (
 //  format:"date-time"   swaggo/swag              Swagger format
 //  validate:"required"  swaggo/swag              Mark Swagger field as required/non-nullable
 //  binding:"required"   go-playground/validator  Gin's Bind will error if nil or zero
+//  binding:"url"        go-playground/validator  Gin's Bind will error if not an absolute URL
 //
 // go-playground/validator uses the tag "validate" by default, but Gin overrides
 // changes that to "binding".
@@ -36,6 +37,12 @@ type TokenUpdate struct {
 	UserName string `json:"userName" validate:"required"`
 }
 
+// EngineTokenUpdate specifies fields when updating the token of an execution
+// engine.
+type EngineTokenUpdate struct {
+	Token string `json:"token" format:"password" validate:"required" binding:"required"`
+}
+
 // Branch specifies fields when adding a new branch to a project.
 type Branch struct {
 	Name    string `json:"name" validate:"required"`
@@ -53,6 +60,22 @@ type BranchListUpdate struct {
 	Branches      []BranchUpdate `json:"branches"`
 }
 
+// Environment specifies fields when adding a new environment to a project.
+type Environment struct {
+	Name        string `json:"name" validate:"required" binding:"required"`
+	Description string `json:"description"`
+	// IsProtected means builds targeting this environment must be approved
+	// before they are sent to the execution engine.
+	IsProtected bool `json:"isProtected"`
+}
+
+// EnvironmentUpdate specifies fields when updating an environment.
+type EnvironmentUpdate struct {
+	Name        string `json:"name" validate:"required" binding:"required"`
+	Description string `json:"description"`
+	IsProtected bool   `json:"isProtected"`
+}
+
 // LogOrStatusUpdate is a single log line, together with its timestamp of when
 // it was logged; or a build status update.
 //
@@ -61,7 +84,7 @@ type BranchListUpdate struct {
 type LogOrStatusUpdate struct {
 	Message   string      `json:"message"`
 	Timestamp time.Time   `json:"timestamp" format:"date-time"`
-	Status    BuildStatus `json:"status" enums:",Scheduling,Running,Completed,Failed"`
+	Status    BuildStatus `json:"status" enums:",Scheduling,Running,Completed,Failed,TriggerFailed"`
 }
 
 // BuildStatus is an enum of different states for a build.
@@ -80,24 +103,221 @@ const (
 	// misconfiguration in the .wharf-ci.yml file, or perhaps a scripting error
 	// in some build step.
 	BuildFailed BuildStatus = "Failed"
+	// BuildTriggerFailed means that the execution engine could not be
+	// triggered to start the build, so the build was never started.
+	BuildTriggerFailed BuildStatus = "TriggerFailed"
+	// BuildAwaitingApproval means the build targets a protected environment,
+	// and will not be sent to the execution engine until it is approved.
+	BuildAwaitingApproval BuildStatus = "AwaitingApproval"
+	// BuildPending means the build was started with a scheduled time, and
+	// will not be sent to the execution engine until that time.
+	BuildPending BuildStatus = "Pending"
 )
 
+// BuildReview specifies fields when approving or rejecting a build that
+// targets a protected environment.
+type BuildReview struct {
+	Comment string `json:"comment" example:"Approved for the release on Friday."`
+}
+
+// BuildComment specifies fields when adding a comment to a build.
+type BuildComment struct {
+	// Text is the comment's content, formatted as Markdown.
+	Text string `json:"text" example:"Failed due to a flaky test, retriggered." validate:"required" binding:"required"`
+}
+
+// BuildLink specifies fields when adding an external link to a build.
+type BuildLink struct {
+	Name string `json:"name" example:"SonarQube report" validate:"required" binding:"required"`
+	URL  string `json:"url" example:"https://sonarqube.example.com/dashboard?id=my-project" validate:"required" binding:"required,url"`
+}
+
+// ArtifactPromotion specifies where to promote a build artifact to. Exactly
+// one of TargetBuildID and Release must be set.
+type ArtifactPromotion struct {
+	// TargetBuildID is the ID of the build to copy the artifact to.
+	TargetBuildID *uint `json:"targetBuildId" minimum:"0" extensions:"x-nullable"`
+	// Release is the name of the release of the source build's project to
+	// copy the artifact to, such as a version number.
+	Release string `json:"release" example:"v1.2.0"`
+}
+
+// ProjectInstanceUpdate specifies the Wharf installation to move a project to.
+type ProjectInstanceUpdate struct {
+	InstanceID string `json:"instanceId" example:"prod"`
+}
+
 // BuildStatusUpdate allows you to update the status of a build.
 type BuildStatusUpdate struct {
-	Status BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed"`
+	Status BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed"`
+	// StatusMessage is the optional reason of the new status, such as why the
+	// build failed.
+	StatusMessage string `json:"statusMessage" example:"step \"unit-tests\" exited with code 1"`
+}
+
+// BuildStageStatusUpdate allows you to update the status of a single stage
+// of a build.
+type BuildStageStatusUpdate struct {
+	Status BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed" validate:"required" binding:"required"`
+	// StatusMessage is the optional reason of the new status, such as why the
+	// stage failed.
+	StatusMessage string `json:"statusMessage" example:"step \"unit-tests\" exited with code 1"`
+	// Timestamp is when the stage changed status, such as taken from the log
+	// markers of the stage. Defaults to when the update is received.
+	Timestamp *time.Time `json:"timestamp" format:"date-time" extensions:"x-nullable"`
+}
+
+// BuildTrigger specifies fields when adding a new build trigger to a project.
+type BuildTrigger struct {
+	TargetProjectID uint `json:"targetProjectId" minimum:"0" validate:"required" binding:"required"`
+	// BranchFilter is a glob pattern of the Git branches of the source
+	// project's builds that starts the trigger, where `*` matches any
+	// characters except slashes. Empty matches all branches.
+	BranchFilter string `json:"branchFilter" example:"release/*"`
+	// Stage is the name of the stage to run in the target project's build.
+	// Defaults to `ALL`.
+	Stage string `json:"stage" example:"deploy"`
 }
 
+// BuildTriggerUpdate specifies fields when updating a build trigger.
+type BuildTriggerUpdate struct {
+	TargetProjectID uint   `json:"targetProjectId" minimum:"0" validate:"required" binding:"required"`
+	BranchFilter    string `json:"branchFilter" example:"release/*"`
+	Stage           string `json:"stage" example:"deploy"`
+}
+
+// NotificationRule specifies fields when adding or updating a notification
+// rule.
+type NotificationRule struct {
+	Name string `json:"name" example:"Slow deploys"`
+	// ProjectID is the ID of the project whose builds the rule applies to.
+	// Null applies the rule to the builds of all projects.
+	ProjectID *uint `json:"projectId" minimum:"0" extensions:"x-nullable"`
+	// Status is the status the builds must have stayed in for longer than the
+	// threshold.
+	Status BuildStatus `json:"status" enums:"Scheduling,Running" validate:"required" binding:"required"`
+	// Threshold is the number of seconds the builds must have stayed in the
+	// status before the notification is sent.
+	Threshold int64 `json:"threshold" minimum:"1" example:"3600" validate:"required" binding:"required"`
+	// Notifier is how the notification is sent. The "email" notifier requires
+	// SMTP to be configured in wharf-api.
+	Notifier NotifierType `json:"notifier" enums:"webhook,slack,teams,email" validate:"required" binding:"required"`
+	// Target is the URL of the webhook for the "webhook", "slack", and
+	// "teams" notifiers, or a comma-separated list of email addresses for the
+	// "email" notifier.
+	Target string `json:"target" example:"https://hooks.slack.com/services/T000/B000/XXXX" validate:"required" binding:"required"`
+}
+
+// NotifierType is an enum of ways to send notifications.
+type NotifierType string
+
+const (
+	// NotifierWebhook sends notifications as JSON in HTTP POST requests.
+	NotifierWebhook NotifierType = "webhook"
+	// NotifierSlack sends notifications to a Slack incoming webhook.
+	NotifierSlack NotifierType = "slack"
+	// NotifierTeams sends notifications to a Microsoft Teams incoming
+	// webhook.
+	NotifierTeams NotifierType = "teams"
+	// NotifierEmail sends notifications as emails.
+	NotifierEmail NotifierType = "email"
+)
+
+// UserSubscriptions specifies fields when updating the current user's
+// subscriptions to emails about failed builds.
+type UserSubscriptions struct {
+	// Email is the email address that the emails are sent to. Defaults to the
+	// email address from the OIDC access token's "email" claim.
+	Email string `json:"email" example:"john.doe@example.com"`
+	// ProjectIDs are the IDs of the projects to receive emails about when
+	// their builds fail. Replaces all of the user's previous subscriptions,
+	// where an empty list unsubscribes from all projects.
+	ProjectIDs []uint `json:"projectIds" example:"1,2"`
+}
+
+// ProjectWebhook specifies fields when adding or updating the inbound webhook
+// of a project.
+type ProjectWebhook struct {
+	// IsEnabled is false if calls to the webhook are rejected, without
+	// removing the webhook or changing its URL.
+	IsEnabled bool `json:"isEnabled" example:"true"`
+	// RequireSignature is true if calls to the webhook must be signed using
+	// the webhook's secret.
+	RequireSignature bool `json:"requireSignature" example:"true"`
+}
+
+// WebhookTrigger is the optional body of calls to a project's inbound
+// webhook. Unknown fields are ignored, so that the webhook can be called with
+// the payloads of other systems.
+type WebhookTrigger struct {
+	// Branch is the name of the branch to build. Defaults to the project's
+	// default branch.
+	Branch string `json:"branch" example:"master"`
+	// Environment is the environment name filter. Runs all stages without any
+	// environment filters if left empty.
+	Environment string `json:"environment" example:"stage"`
+	// Stage is the name of the stage to run, or ALL to run all stages.
+	// Defaults to ALL.
+	Stage string `json:"stage" example:"ALL"`
+	// Commit is the Git commit SHA to build.
+	Commit string `json:"commit" example:"2f1ebd2b70b5d7ad0c9b9c9e6b4cd1a1d3f6d2b1"`
+	// Tag is the Git tag to build.
+	Tag string `json:"tag" example:"v1.0.0"`
+	// Inputs are the input variable values, as when starting a build via the
+	// POST /project/{projectId}/build endpoint.
+	Inputs BuildInputs `json:"inputs"`
+}
+
+// GitTrigger specifies fields when adding or updating a Git trigger of a
+// project.
+type GitTrigger struct {
+	// Event is the kind of Git event that starts the trigger. Merge requests
+	// are called pull requests in GitHub.
+	Event GitTriggerEvent `json:"event" enums:"push,merge-request" validate:"required" binding:"required"`
+	// BranchFilter is a glob pattern of the pushed branches, or of the target
+	// branches of merge requests, that starts the trigger, where `*` matches
+	// any characters except slashes. Empty matches all branches.
+	BranchFilter string `json:"branchFilter" example:"master"`
+	// Stage is the name of the stage to run in the build. Defaults to `ALL`.
+	Stage string `json:"stage" example:"ALL"`
+	// Environment is the environment name filter. Runs all stages without any
+	// environment filters if left empty.
+	Environment string `json:"environment" example:"stage"`
+}
+
+// GitTriggerEvent is an enum of Git events that start Git triggers.
+type GitTriggerEvent string
+
+const (
+	// GitTriggerPush means the trigger starts builds when commits are pushed
+	// to a branch.
+	GitTriggerPush GitTriggerEvent = "push"
+	// GitTriggerMergeRequest means the trigger starts builds when a merge
+	// request is opened, reopened, or receives new commits.
+	GitTriggerMergeRequest GitTriggerEvent = "merge-request"
+)
+
 // BuildInputs is a key-value object of input variables used when starting a new
 // build, where the key is the input variable name and the value is its string,
 // boolean, or numeric value.
 type BuildInputs map[string]any
 
+// Settings is a key-value object of user or instance-wide settings to update,
+// where the key is the setting name and the value is its string, boolean, or
+// numeric value. A null value resets the setting to its default value.
+type Settings map[string]any
+
+// Labels is a key-value object of labels used to categorize builds and
+// projects, such as by team or release train, where the key is the label name.
+// Setting the labels of a build or project replaces all its previous labels.
+type Labels map[string]string
+
 // Project specifies fields when creating a new project.
 type Project struct {
 	Name            string `json:"name" validate:"required" binding:"required"`
 	GroupName       string `json:"groupName"`
 	Description     string `json:"description"`
-	AvatarURL       string `json:"avatarUrl"`
+	AvatarURL       string `json:"avatarUrl" binding:"omitempty,url"`
 	TokenID         uint   `json:"tokenId" minimum:"0"`
 	ProviderID      uint   `json:"providerId" minimum:"0"`
 	BuildDefinition string `json:"buildDefinition"`
@@ -110,22 +330,69 @@ type ProjectUpdate struct {
 	Name            string `json:"name" validate:"required" binding:"required"`
 	GroupName       string `json:"groupName"`
 	Description     string `json:"description"`
-	AvatarURL       string `json:"avatarUrl"`
+	AvatarURL       string `json:"avatarUrl" binding:"omitempty,url"`
 	TokenID         uint   `json:"tokenId" minimum:"0"`
 	ProviderID      uint   `json:"providerId" minimum:"0"`
 	BuildDefinition string `json:"buildDefinition"`
 	GitURL          string `json:"gitUrl"`
+	// SchedulingTimeout is the number of seconds the project's builds may stay
+	// in the Scheduling status before being marked as failed. Null means the
+	// ci.schedulingTimeout config is used, while 0 means no timeout.
+	//
+	// Added in v5.3.0.
+	SchedulingTimeout *uint `json:"schedulingTimeout" minimum:"0" extensions:"x-nullable"`
+	// RunningTimeout is the number of seconds the project's builds may stay
+	// in the Running status before being marked as failed. Null means the
+	// ci.runningTimeout config is used, while 0 means no timeout.
+	//
+	// Added in v5.3.0.
+	RunningTimeout *uint `json:"runningTimeout" minimum:"0" extensions:"x-nullable"`
 }
 
 // ProjectOverridesUpdate specifies fields when updating a project's overrides.
 type ProjectOverridesUpdate struct {
 	Description string `json:"description"`
-	AvatarURL   string `json:"avatarUrl"`
+	AvatarURL   string `json:"avatarUrl" binding:"omitempty,url"`
 	GitURL      string `json:"gitUrl"`
 }
 
-// ProviderName is an enum of different providers that are available over at
-// https://github.com/iver-wharf
+// ProjectTransfer specifies the new group, provider, and token of a project
+// when transferring it. Fields that are left out or null are left unchanged,
+// while a provider or token ID of zero removes the project's provider or
+// token. At least one field must be set.
+type ProjectTransfer struct {
+	GroupName  *string `json:"groupName" extensions:"x-nullable"`
+	ProviderID *uint   `json:"providerId" minimum:"0" extensions:"x-nullable"`
+	TokenID    *uint   `json:"tokenId" minimum:"0" extensions:"x-nullable"`
+}
+
+// ProjectInputDefaults specifies a project's own default values of the input
+// variables declared in its build definition. Setting the input defaults of a
+// project replaces all its previous input defaults.
+type ProjectInputDefaults struct {
+	// Inputs holds default values that apply to builds on any environment,
+	// where the key is the input variable name.
+	Inputs map[string]string `json:"inputs"`
+	// Environments holds default values that only apply to builds on a given
+	// environment, where the key is the environment name. These take
+	// precedence over the default values in Inputs.
+	Environments map[string]map[string]string `json:"environments"`
+}
+
+// ProjectEngines specifies the execution engines that a project is allowed to
+// start builds on. Setting the engines of a project replaces all its previous
+// engines.
+type ProjectEngines struct {
+	// EngineIDs holds the IDs of the allowed execution engines, as configured
+	// in the wharf-api. An empty list allows builds on any execution engine.
+	EngineIDs []string `json:"engineIds"`
+}
+
+// ProviderName is the name of a provider plugin, such as one of the well-known
+// providers that are available over at https://github.com/iver-wharf.
+//
+// Since v5.3.0, any provider name is allowed, such as for custom provider
+// plugins, unless restricted by the wharf-api configuration.
 type ProviderName string
 
 const (
@@ -138,15 +405,25 @@ const (
 	// ProviderGitHub refers to the GitHub provider plugin,
 	// https://github.com/iver-wharf/wharf-provider-github
 	ProviderGitHub ProviderName = "github"
-	// ProviderNameValues is a concatenated list of the different provider names
-	// available. Useful in validation error messages.
+	// ProviderNameValues is a concatenated list of the well-known provider
+	// names. Useful in validation error messages.
 	ProviderNameValues = ProviderAzureDevOps + ", " + ProviderGitLab + ", " + ProviderGitHub
 )
 
-// IsValid returns false if the underlying type is an unknown enum value.
-// 	ProviderGitHub.IsValid()     // => true
-// 	(ProviderName("")).IsValid() // => false
+// IsValid returns false if the name is empty. Before v5.3.0, this only
+// returned true for the well-known provider names.
+// 	ProviderGitHub.IsValid()          // => true
+// 	(ProviderName("gitea")).IsValid() // => true
+// 	(ProviderName("")).IsValid()      // => false
 func (name ProviderName) IsValid() bool {
+	return name != ""
+}
+
+// IsWellKnown returns true if the name is one of the well-known provider
+// names, such as ProviderGitHub.
+// 	ProviderGitHub.IsWellKnown()          // => true
+// 	(ProviderName("gitea")).IsWellKnown() // => false
+func (name ProviderName) IsWellKnown() bool {
 	return name == ProviderAzureDevOps ||
 		name == ProviderGitLab ||
 		name == ProviderGitHub
@@ -165,21 +442,40 @@ func (name ProviderName) ValidString() (string, bool) {
 
 // ProviderSearch holds values used in verbatim searches for providers.
 type ProviderSearch struct {
-	Name    ProviderName `json:"name" enums:"azuredevops,gitlab,github"`
+	Name    ProviderName `json:"name" example:"github"`
 	URL     string       `json:"url"`
 	TokenID uint         `json:"tokenId" minimum:"0"`
 }
 
 // Provider specifies fields when creating a new provider.
 type Provider struct {
-	Name    ProviderName `json:"name" enums:"azuredevops,gitlab,github" validate:"required" binding:"required"`
-	URL     string       `json:"url" validate:"required" binding:"required"`
-	TokenID uint         `json:"tokenId" minimum:"0"`
+	// Name is the name of the provider plugin, such as one of the well-known
+	// names "azuredevops", "gitlab", or "github".
+	Name      ProviderName   `json:"name" example:"github" validate:"required" binding:"required"`
+	URL       string         `json:"url" validate:"required" binding:"required,url"`
+	UploadURL string         `json:"uploadUrl" example:"https://uploads.github.example.com" binding:"omitempty,url"`
+	TokenID   uint           `json:"tokenId" minimum:"0"`
+	Metadata  map[string]any `json:"metadata" extensions:"x-nullable"`
 }
 
 // ProviderUpdate specifies fields when updating a provider.
 type ProviderUpdate struct {
-	Name    ProviderName `json:"name" enums:"azuredevops,gitlab,github" validate:"required" binding:"required"`
-	URL     string       `json:"url" validate:"required" binding:"required"`
-	TokenID uint         `json:"tokenId" minimum:"0"`
+	// Name is the name of the provider plugin, such as one of the well-known
+	// names "azuredevops", "gitlab", or "github".
+	Name      ProviderName   `json:"name" example:"github" validate:"required" binding:"required"`
+	URL       string         `json:"url" validate:"required" binding:"required,url"`
+	UploadURL string         `json:"uploadUrl" example:"https://uploads.github.example.com" binding:"omitempty,url"`
+	TokenID   uint           `json:"tokenId" minimum:"0"`
+	Metadata  map[string]any `json:"metadata" extensions:"x-nullable"`
+}
+
+// LogLevel specifies the minimum levels of the logs written by wharf-api.
+type LogLevel struct {
+	// Level is the global minimum level of all logs.
+	Level string `json:"level" enums:"debug,info,warn,error,panic" example:"info" binding:"required,oneof=debug info warn error panic"`
+	// Scopes holds minimum levels of specific logging scopes, such as "GIN"
+	// or "WHARF", where the key is the scope name. Scoped levels can only
+	// raise the level of a scope above the global level. Scopes left out are
+	// reset to the global level.
+	Scopes map[string]string `json:"scopes" binding:"omitempty,dive,keys,required,endkeys,oneof=debug info warn error panic"`
 }
@@ -15,6 +15,17 @@ type TimeMetadata struct {
 	CreatedAt *time.Time `json:"createdAt" format:"date-time" extensions:"x-nullable"`
 }
 
+// TimeMetadataJSONFields holds the JSON field names for each field.
+// Useful in ordering statements to map the correct field to the correct
+// database column.
+var TimeMetadataJSONFields = struct {
+	UpdatedAt string
+	CreatedAt string
+}{
+	UpdatedAt: "updatedAt",
+	CreatedAt: "createdAt",
+}
+
 // ArtifactJSONFields holds the JSON field names for each field.
 // Useful in ordering statements to map the correct field to the correct
 // database column.
@@ -36,6 +47,53 @@ type Artifact struct {
 	BuildID    uint   `json:"buildId" minimum:"0"`
 	Name       string `json:"name"`
 	FileName   string `json:"fileName"`
+	// Size is the number of bytes in the artifact's file. Added in v5.3.0.
+	Size int64 `json:"size" example:"1024"`
+	// ContentType is the MIME type of the artifact's file, detected on
+	// upload. Added in v5.3.0.
+	ContentType string `json:"contentType" example:"application/xml"`
+	// PromotedFromBuildID is the ID of the build that the artifact was
+	// promoted from, or null if the artifact was uploaded. Added in v5.3.0.
+	PromotedFromBuildID null.Int `json:"promotedFromBuildId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	// PromotedFromArtifactID is the ID of the artifact that the artifact was
+	// promoted from, or null if the artifact was uploaded. Added in v5.3.0.
+	PromotedFromArtifactID null.Int `json:"promotedFromArtifactId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+}
+
+// ReleaseArtifact is a copy of a build artifact that has been promoted to one
+// of the project's releases.
+type ReleaseArtifact struct {
+	TimeMetadata
+	ReleaseArtifactID      uint             `json:"releaseArtifactId" minimum:"0"`
+	ProjectID              uint             `json:"projectId" minimum:"0"`
+	Release                string           `json:"release" example:"v1.2.0"`
+	Name                   string           `json:"name"`
+	FileName               string           `json:"fileName"`
+	Size                   int64            `json:"size" example:"1024"`
+	ContentType            string           `json:"contentType" example:"application/octet-stream"`
+	PromotedFromBuildID    uint             `json:"promotedFromBuildId" minimum:"0"`
+	PromotedFromArtifactID uint             `json:"promotedFromArtifactId" minimum:"0"`
+	PromotedBy             BuildTriggeredBy `json:"promotedBy"`
+}
+
+// ProjectArtifact holds an artifact together with a summary of the build it
+// belongs to, as listed across all builds of a project.
+type ProjectArtifact struct {
+	Artifact
+	Build ArtifactBuild `json:"build"`
+}
+
+// ArtifactBuild holds a summary of the build that an artifact belongs to.
+type ArtifactBuild struct {
+	BuildID     uint        `json:"buildId" minimum:"0"`
+	StatusID    int         `json:"statusId" enums:"0,1,2,3,4,5,6"`
+	Status      BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	GitBranch   string      `json:"gitBranch"`
+	GitCommit   string      `json:"gitCommit" example:"8f4e0e7a1c9b2d3e4f5a6b7c8d9e0f1a2b3c4d5e"`
+	Stage       string      `json:"stage"`
+	Environment null.String `json:"environment" swaggertype:"string" extensions:"x-nullable"`
+	ScheduledOn null.Time   `json:"scheduledOn" format:"date-time" extensions:"x-nullable"`
+	FinishedOn  null.Time   `json:"finishedOn" format:"date-time" extensions:"x-nullable"`
 }
 
 // ArtifactMetadata contains the file name and artifact ID of an Artifact.
@@ -53,6 +111,29 @@ type Branch struct {
 	Name      string `json:"name"`
 	Default   bool   `json:"default"`
 	TokenID   uint   `json:"tokenId" minimum:"0"`
+	// LastBuildID is the ID of the latest build on this branch, or null if the
+	// branch has never been built.
+	LastBuildID null.Int `json:"lastBuildId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	// LastBuiltOn is when the latest build on this branch was scheduled, or
+	// null if the branch has never been built.
+	LastBuiltOn null.Time `json:"lastBuiltOn" format:"date-time" extensions:"x-nullable"`
+}
+
+// BranchDetails holds details about a project's branch, together with the
+// status of its latest build.
+type BranchDetails struct {
+	Branch
+	LatestBuild *BranchLatestBuild `json:"latestBuild" extensions:"x-nullable"`
+}
+
+// BranchLatestBuild holds the status of the latest build of a branch.
+type BranchLatestBuild struct {
+	BuildID     uint        `json:"buildId" minimum:"0"`
+	StatusID    int         `json:"statusId" enums:"0,1,2,3,4,5,6"`
+	Status      BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	Stage       string      `json:"stage"`
+	ScheduledOn null.Time   `json:"scheduledOn" format:"date-time" extensions:"x-nullable"`
+	CompletedOn null.Time   `json:"finishedOn" format:"date-time" extensions:"x-nullable"`
 }
 
 // BranchList holds a list of branches, and a separate field for the default
@@ -62,6 +143,101 @@ type BranchList struct {
 	Branches      []Branch `json:"branches"`
 }
 
+// Environment holds details about a project's deployment environment.
+type Environment struct {
+	TimeMetadata
+	EnvironmentID uint                  `json:"environmentId" minimum:"0"`
+	ProjectID     uint                  `json:"projectId" minimum:"0"`
+	Name          string                `json:"name"`
+	Description   string                `json:"description"`
+	IsDiscovered  bool                  `json:"isDiscovered"`
+	IsProtected   bool                  `json:"isProtected"`
+	LastBuild     *EnvironmentLastBuild `json:"lastBuild" extensions:"x-nullable"`
+}
+
+// EnvironmentLastBuild holds a summary of the latest build that targeted an
+// environment.
+type EnvironmentLastBuild struct {
+	BuildID     uint        `json:"buildId" minimum:"0"`
+	Status      BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	StatusID    int         `json:"statusId" enums:"0,1,2,3,4,5,6"`
+	GitBranch   string      `json:"gitBranch"`
+	ScheduledOn null.Time   `json:"scheduledOn" format:"date-time" extensions:"x-nullable"`
+	FinishedOn  null.Time   `json:"finishedOn" format:"date-time" extensions:"x-nullable"`
+}
+
+// BuildDefinition is a parsed .wharf-ci.yml build definition of a project.
+// Stages, steps, and environments keep the order in which they were declared.
+type BuildDefinition struct {
+	ProjectID    uint                         `json:"projectId" minimum:"0"`
+	Inputs       []BuildDefinitionInput       `json:"inputs"`
+	Environments []BuildDefinitionEnvironment `json:"environments"`
+	Stages       []BuildDefinitionStage       `json:"stages"`
+}
+
+// BuildDefinitionInput is an input variable declared in a build definition.
+type BuildDefinitionInput struct {
+	Name    string `json:"name"`
+	Type    string `json:"type" enums:"string,password,number,boolean,choice"`
+	Default any    `json:"default" swaggertype:"string" extensions:"x-nullable"`
+	Values  []any  `json:"values" swaggertype:"array,string"`
+	Line    int    `json:"line"`
+}
+
+// BuildDefinitionEnvironment is an environment declared in a build
+// definition, together with its variables.
+type BuildDefinitionEnvironment struct {
+	Name string         `json:"name"`
+	Vars map[string]any `json:"vars" swaggertype:"object"`
+	Line int            `json:"line"`
+}
+
+// BuildDefinitionStage is a stage declared in a build definition.
+type BuildDefinitionStage struct {
+	Name         string                `json:"name"`
+	Environments []string              `json:"environments"`
+	Steps        []BuildDefinitionStep `json:"steps"`
+	Line         int                   `json:"line"`
+}
+
+// BuildDefinitionStep is a step declared in a stage of a build definition.
+type BuildDefinitionStep struct {
+	Name   string         `json:"name"`
+	Type   string         `json:"type" example:"container"`
+	Fields map[string]any `json:"fields" swaggertype:"object"`
+	Line   int            `json:"line"`
+}
+
+// BuildDefinitionValidation is the result of validating a build definition.
+type BuildDefinitionValidation struct {
+	// Valid is false if any of the issues has the "error" severity.
+	Valid  bool                   `json:"valid"`
+	Issues []BuildDefinitionIssue `json:"issues"`
+}
+
+// BuildDefinitionIssue is a single problem found when validating a build
+// definition.
+type BuildDefinitionIssue struct {
+	Severity BuildDefinitionIssueSeverity `json:"severity" enums:"error,warning"`
+	Path     string                       `json:"path" example:"myStage.myStep.container"`
+	Message  string                       `json:"message" example:"reference to undeclared variable \"myVar\""`
+	Line     int                          `json:"line"`
+	Column   int                          `json:"column"`
+}
+
+// BuildDefinitionIssueSeverity is an enum of how severe a build definition
+// validation issue is.
+type BuildDefinitionIssueSeverity string
+
+const (
+	// BuildDefinitionIssueError means the build definition will produce an
+	// invalid build.
+	BuildDefinitionIssueError BuildDefinitionIssueSeverity = "error"
+	// BuildDefinitionIssueWarning means the build definition may not work as
+	// intended, but will not necessarily produce an invalid build.
+	BuildDefinitionIssueWarning BuildDefinitionIssueSeverity = "warning"
+)
+
 // BuildJSONFields holds the JSON field names for each field.
 // Useful in ordering statements to map the correct field to the correct
 // database column.
@@ -74,6 +250,14 @@ var BuildJSONFields = struct {
 	Stage       string
 	StatusID    string
 	IsInvalid   string
+	Duration    string
+
+	// ProjectName, ProjectGroupName, TestsFailed, and TestsTotal are not
+	// fields of the build, but may be used when sorting builds.
+	ProjectName      string
+	ProjectGroupName string
+	TestsFailed      string
+	TestsTotal       string
 }{
 	BuildID:     "buildId",
 	Environment: "environment",
@@ -83,6 +267,12 @@ var BuildJSONFields = struct {
 	Stage:       "stage",
 	StatusID:    "statusId",
 	IsInvalid:   "isInvalid",
+	Duration:    "duration",
+
+	ProjectName:      "projectName",
+	ProjectGroupName: "projectGroupName",
+	TestsFailed:      "testsFailed",
+	TestsTotal:       "testsTotal",
 }
 
 // Build holds data about the state of a build. Which parameters was used to
@@ -90,13 +280,15 @@ var BuildJSONFields = struct {
 type Build struct {
 	TimeMetadata
 	BuildID               uint                  `json:"buildId" minimum:"0"`
-	StatusID              int                   `json:"statusId" enums:"0,1,2,3"`
-	Status                BuildStatus           `json:"status" enums:"Scheduling,Running,Completed,Failed"`
+	StatusID              int                   `json:"statusId" enums:"0,1,2,3,4,5,6"`
+	Status                BuildStatus           `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
 	ProjectID             uint                  `json:"projectId" minimum:"0"`
 	ScheduledOn           null.Time             `json:"scheduledOn" format:"date-time" extensions:"x-nullable"`
 	StartedOn             null.Time             `json:"startedOn" format:"date-time" extensions:"x-nullable"`
 	CompletedOn           null.Time             `json:"finishedOn" format:"date-time" extensions:"x-nullable"`
 	GitBranch             string                `json:"gitBranch"`
+	GitCommit             string                `json:"gitCommit" example:"8f4e0e7a1c9b2d3e4f5a6b7c8d9e0f1a2b3c4d5e"`
+	GitTag                string                `json:"gitTag" example:"v1.2.3"`
 	Environment           null.String           `json:"environment" swaggertype:"string" extensions:"x-nullable"`
 	Stage                 string                `json:"stage"`
 	WorkerID              string                `json:"workerId" example:"5d6bcf20-81fd-4ad8-a446-735aa8423dfe"`
@@ -104,16 +296,440 @@ type Build struct {
 	IsInvalid             bool                  `json:"isInvalid"`
 	TestResultSummaries   []TestResultSummary   `json:"testResultSummaries"`
 	TestResultListSummary TestResultListSummary `json:"testResultListSummary"`
-	Engine                *Engine               `json:"engine" extensions:"x-nullable"`
+	// Engine is the execution engine the build was started on. If the engine
+	// has since been removed from the configuration, then only the ID and
+	// name are set, where the name is the same as the ID.
+	Engine      *Engine          `json:"engine" extensions:"x-nullable"`
+	Duration    null.Int         `json:"duration" swaggertype:"integer" example:"93500" extensions:"x-nullable"`
+	TriggeredBy BuildTriggeredBy `json:"triggeredBy"`
+	// TriggerError is the error message from the latest failed attempt to
+	// trigger the build, if the build has the TriggerFailed status.
+	TriggerError string `json:"triggerError" example:"non-2xx response: 503 Service Unavailable"`
+	// StatusMessage is the reason of the build's latest status, as reported
+	// by the worker running the build, such as why the build failed.
+	StatusMessage string `json:"statusMessage" example:"step \"unit-tests\" exited with code 1"`
+	// BuildGroupID is the ID of the build group this build was started as
+	// part of, such as when starting a matrix of builds.
+	BuildGroupID null.Int `json:"buildGroupId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	// Approval is set if the build targets a protected environment, and
+	// therefore had to be approved before being sent to the execution engine.
+	Approval   *BuildApproval `json:"approval" extensions:"x-nullable"`
+	Labels     Labels         `json:"labels"`
+	InstanceID string         `json:"instanceId" example:"prod"`
+	// BuildNumber is the number of the build within its project, starting at
+	// 1 and increasing by one with each build of the project.
+	BuildNumber uint `json:"buildNumber" minimum:"0" example:"42"`
+	// CommentCount is the number of comments on the build. The comments are
+	// listed using the GET /build/{buildId}/comment endpoint.
+	CommentCount int `json:"commentCount" minimum:"0"`
+	// Links are external links of the build, such as to test reports or
+	// deployment dashboards.
+	Links []BuildLink `json:"links"`
+	// StageResults are the results of the build's stages, as reported by the
+	// worker running the build.
+	StageResults []BuildStageResult `json:"stageResults"`
+	// ExpiresOn is when the build will be marked as failed, unless it leaves
+	// its current Scheduling or Running status before then. Null if the build
+	// does not time out in its current status.
+	//
+	// Only set by the GET /build and GET /build/{buildId} endpoints.
+	ExpiresOn null.Time `json:"expiresOn" format:"date-time" extensions:"x-nullable"`
+	// ScheduleAt is when the build is sent to the execution engine, if the
+	// build was started with a scheduled time. The build has the Pending
+	// status until then.
+	ScheduleAt null.Time `json:"scheduleAt" format:"date-time" extensions:"x-nullable"`
+}
+
+// BuildStageResult is the result of a single stage of a build.
+type BuildStageResult struct {
+	TimeMetadata
+	Name          string      `json:"name" example:"test"`
+	Status        BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed"`
+	StatusMessage string      `json:"statusMessage" example:"step \"unit-tests\" exited with code 1"`
+	StartedOn     null.Time   `json:"startedOn" format:"date-time" extensions:"x-nullable"`
+	CompletedOn   null.Time   `json:"finishedOn" format:"date-time" extensions:"x-nullable"`
+	// Duration is the number of milliseconds between StartedOn and
+	// CompletedOn, or null if the stage has not both started and finished.
+	Duration null.Int `json:"duration" swaggertype:"integer" example:"93500" extensions:"x-nullable"`
+}
+
+// BuildComment is a comment on a build, such as notes on why the build failed
+// and what was done about it.
+type BuildComment struct {
+	TimeMetadata
+	BuildCommentID uint `json:"buildCommentId" minimum:"0"`
+	BuildID        uint `json:"buildId" minimum:"0"`
+	// Author is who wrote the comment, based on how they were authenticated.
+	Author BuildTriggeredBy `json:"author"`
+	// Text is the comment's content, formatted as Markdown.
+	Text string `json:"text" example:"Failed due to a flaky test, retriggered."`
+}
+
+// BuildLink is an external link of a build, such as to a test report or a
+// deployment dashboard.
+type BuildLink struct {
+	TimeMetadata
+	BuildLinkID uint   `json:"buildLinkId" minimum:"0"`
+	BuildID     uint   `json:"buildId" minimum:"0"`
+	Name        string `json:"name" example:"SonarQube report"`
+	URL         string `json:"url" example:"https://sonarqube.example.com/dashboard?id=my-project"`
+}
+
+// BuildApproval holds the state of the manual approval of a build that
+// targets a protected environment.
+type BuildApproval struct {
+	Status BuildApprovalStatus `json:"status" enums:"Pending,Approved,Rejected"`
+	// ReviewedBy is who approved or rejected the build, or null if the build
+	// is still pending approval.
+	ReviewedBy *BuildTriggeredBy `json:"reviewedBy" extensions:"x-nullable"`
+	ReviewedOn null.Time         `json:"reviewedOn" format:"date-time" extensions:"x-nullable"`
+	Comment    string            `json:"comment" example:"Approved for the release on Friday."`
+}
+
+// BuildApprovalStatus is an enum of the states of the manual approval of a
+// build.
+type BuildApprovalStatus string
+
+const (
+	// BuildApprovalPending means the build is waiting to be approved or
+	// rejected.
+	BuildApprovalPending BuildApprovalStatus = "Pending"
+	// BuildApprovalApproved means the build was approved, and then sent to
+	// the execution engine.
+	BuildApprovalApproved BuildApprovalStatus = "Approved"
+	// BuildApprovalRejected means the build was rejected, and was never sent
+	// to the execution engine.
+	BuildApprovalRejected BuildApprovalStatus = "Rejected"
+)
+
+// BuildGroup is a set of builds that were started together, such as when
+// starting a matrix of builds over multiple branches and environments.
+type BuildGroup struct {
+	TimeMetadata
+	BuildGroupID uint `json:"buildGroupId" minimum:"0"`
+	ProjectID    uint `json:"projectId" minimum:"0"`
+	// Status is the aggregated status of all builds in the group. The group is
+	// Failed or TriggerFailed if any build has that status, Completed if all
+	// builds completed, and Running, AwaitingApproval, or Scheduling
+	// otherwise.
+	Status       BuildStatus            `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	StatusCounts BuildGroupStatusCounts `json:"statusCounts"`
+	Builds       []Build                `json:"builds"`
+}
+
+// BuildGroupStatusCounts holds the number of builds in a build group per
+// build status.
+type BuildGroupStatusCounts struct {
+	Scheduling       uint `json:"scheduling"`
+	Running          uint `json:"running"`
+	Completed        uint `json:"completed"`
+	Failed           uint `json:"failed"`
+	TriggerFailed    uint `json:"triggerFailed"`
+	AwaitingApproval uint `json:"awaitingApproval"`
+}
+
+// BuildTrigger starts a build of the target project whenever a build of the
+// source project completes successfully.
+type BuildTrigger struct {
+	TimeMetadata
+	BuildTriggerID  uint   `json:"buildTriggerId" minimum:"0"`
+	SourceProjectID uint   `json:"sourceProjectId" minimum:"0"`
+	TargetProjectID uint   `json:"targetProjectId" minimum:"0"`
+	BranchFilter    string `json:"branchFilter" example:"release/*"`
+	Stage           string `json:"stage" example:"deploy"`
+}
+
+// NotificationRule sends a notification whenever a build has stayed in the
+// Scheduling or Running status for longer than the rule's threshold.
+type NotificationRule struct {
+	TimeMetadata
+	NotificationRuleID uint   `json:"notificationRuleId" minimum:"0"`
+	Name               string `json:"name" example:"Slow deploys"`
+	// ProjectID is the ID of the project whose builds the rule applies to, or
+	// null if the rule applies to the builds of all projects.
+	ProjectID null.Int     `json:"projectId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	Status    BuildStatus  `json:"status" enums:"Scheduling,Running"`
+	Threshold int64        `json:"threshold" minimum:"1" example:"3600"`
+	Notifier  NotifierType `json:"notifier" enums:"webhook,slack,teams,email"`
+	Target    string       `json:"target" example:"https://hooks.slack.com/services/T000/B000/XXXX"`
+}
+
+// NotifierType is an enum of ways to send notifications.
+type NotifierType string
+
+const (
+	// NotifierWebhook sends notifications as JSON in HTTP POST requests.
+	NotifierWebhook NotifierType = "webhook"
+	// NotifierSlack sends notifications to a Slack incoming webhook.
+	NotifierSlack NotifierType = "slack"
+	// NotifierTeams sends notifications to a Microsoft Teams incoming
+	// webhook.
+	NotifierTeams NotifierType = "teams"
+	// NotifierEmail sends notifications as emails.
+	NotifierEmail NotifierType = "email"
+)
+
+// BuildNotification is the JSON body sent by notification rules with the
+// "webhook" notifier, when a build has stayed in the Scheduling or Running
+// status for longer than the rule's threshold.
+type BuildNotification struct {
+	NotificationRuleID uint        `json:"notificationRuleId" minimum:"0"`
+	RuleName           string      `json:"ruleName" example:"Slow deploys"`
+	BuildID            uint        `json:"buildId" minimum:"0"`
+	ProjectID          uint        `json:"projectId" minimum:"0"`
+	ProjectName        string      `json:"projectName" example:"my-project"`
+	Status             BuildStatus `json:"status" enums:"Scheduling,Running"`
+	// Since is when the build entered its current status.
+	Since time.Time `json:"since" format:"date-time"`
+	// Duration is the number of milliseconds the build has stayed in its
+	// current status.
+	Duration int64 `json:"duration" example:"3600000"`
+	// Threshold is the number of seconds of the rule's threshold.
+	Threshold int64 `json:"threshold" example:"3600"`
+	// Message is a human-readable summary of the notification.
+	Message string `json:"message" example:"Build 123 of project my-project has been running for 1h0m0s, exceeding the threshold of 1h0m0s."`
+}
+
+// UserSubscriptions holds the current user's subscriptions to emails about
+// failed builds.
+type UserSubscriptions struct {
+	// Email is the email address that the emails are sent to, or empty if
+	// the user is not subscribed to any projects.
+	Email      string `json:"email" example:"john.doe@example.com"`
+	ProjectIDs []uint `json:"projectIds" example:"1,2"`
+}
+
+// ProjectWebhook is the inbound webhook of a project, which lets external
+// systems start builds of the project by calling its secret URL.
+type ProjectWebhook struct {
+	TimeMetadata
+	ProjectWebhookID uint `json:"projectWebhookId" minimum:"0"`
+	ProjectID        uint `json:"projectId" minimum:"0"`
+	IsEnabled        bool `json:"isEnabled"`
+	RequireSignature bool `json:"requireSignature"`
+	// Token is the secret token in the webhook's URL. Only included when the
+	// webhook is added or its secrets are regenerated, as it is not stored.
+	Token string `json:"token,omitempty" example:"3f0c0b2f9d2c4f7e8e6b1a5d4c3b2a1f3f0c0b2f9d2c4f7e8e6b1a5d4c3b2a1f"`
+	// URL is the path of the webhook's URL, relative to the wharf-api's base
+	// URL. Only included together with the token.
+	URL string `json:"url,omitempty" example:"/api/webhook/trigger/3f0c0b2f9d2c4f7e8e6b1a5d4c3b2a1f3f0c0b2f9d2c4f7e8e6b1a5d4c3b2a1f"`
+	// Secret is the key used to sign the request bodies of calls to the
+	// webhook using HMAC-SHA256. Only included together with the token.
+	Secret string `json:"secret,omitempty" example:"9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d"`
+}
+
+// GitTrigger starts a build of the project whenever the project's Git
+// repository receives a push or merge request.
+type GitTrigger struct {
+	TimeMetadata
+	GitTriggerID uint            `json:"gitTriggerId" minimum:"0"`
+	ProjectID    uint            `json:"projectId" minimum:"0"`
+	Event        GitTriggerEvent `json:"event" enums:"push,merge-request"`
+	BranchFilter string          `json:"branchFilter" example:"master"`
+	Stage        string          `json:"stage" example:"ALL"`
+	Environment  null.String     `json:"environment" swaggertype:"string" extensions:"x-nullable"`
+}
+
+// GitTriggerEvent is an enum of Git events that start Git triggers.
+type GitTriggerEvent string
+
+const (
+	// GitTriggerPush means the trigger starts builds when commits are pushed
+	// to a branch.
+	GitTriggerPush GitTriggerEvent = "push"
+	// GitTriggerMergeRequest means the trigger starts builds when a merge
+	// request is opened, reopened, or receives new commits.
+	GitTriggerMergeRequest GitTriggerEvent = "merge-request"
+)
+
+// GitWebhookResult is the response of the GitHub and GitLab webhook
+// receivers, with the builds started by the Git event.
+type GitWebhookResult struct {
+	// Event is the kind of Git event, or empty if the webhook call was
+	// ignored, such as for GitHub's ping event or for deleted branches.
+	Event GitTriggerEvent `json:"event" enums:",push,merge-request"`
+	// Builds are the builds that were started by the Git triggers of the
+	// projects of the repository.
+	Builds []GitWebhookBuild `json:"builds"`
+}
+
+// GitWebhookBuild is a build started by a Git trigger.
+type GitWebhookBuild struct {
+	BuildID      uint `json:"buildId" minimum:"0"`
+	ProjectID    uint `json:"projectId" minimum:"0"`
+	GitTriggerID uint `json:"gitTriggerId" minimum:"0"`
+}
+
+// BuildTriggeredBy holds metadata about who or what started a build.
+type BuildTriggeredBy struct {
+	Type    BuildTriggerType `json:"type" enums:",oidc,basic-auth,webhook,schedule,build"`
+	Name    string           `json:"name" example:"john.doe@example.com"`
+	Subject string           `json:"subject" example:"1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d"`
+}
+
+// BuildTriggerType is an enum of different sources that can start a build.
+type BuildTriggerType string
+
+const (
+	// BuildTriggerUnknown means it is unknown what started the build. Such as
+	// for builds started before v5.3.0, or when no authentication is
+	// configured.
+	BuildTriggerUnknown BuildTriggerType = ""
+	// BuildTriggerOIDC means the build was started by a user authenticated via
+	// an OIDC access token.
+	BuildTriggerOIDC BuildTriggerType = "oidc"
+	// BuildTriggerBasicAuth means the build was started by a user
+	// authenticated via HTTP basic authentication.
+	BuildTriggerBasicAuth BuildTriggerType = "basic-auth"
+	// BuildTriggerWebhook means the build was started by an inbound webhook.
+	BuildTriggerWebhook BuildTriggerType = "webhook"
+	// BuildTriggerSchedule means the build was started by a scheduled
+	// trigger.
+	BuildTriggerSchedule BuildTriggerType = "schedule"
+	// BuildTriggerBuild means the build was started by a build trigger, when
+	// a build of another project completed.
+	BuildTriggerBuild BuildTriggerType = "build"
+)
+
+// BuildStatusEvent is sent on a build's event stream when the build's status
+// changes.
+type BuildStatusEvent struct {
+	BuildID      uint        `json:"buildId" minimum:"0"`
+	StatusBefore BuildStatus `json:"statusBefore" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	Status       BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	IsInvalid    bool        `json:"isInvalid"`
+	// Message holds additional details about the status change, such as why
+	// the build failed to be triggered.
+	Message string `json:"message,omitempty"`
+}
+
+// BusEventType is the type of an event published to a message bus.
+type BusEventType string
+
+const (
+	// BusEventBuildCreated is published when a build is started.
+	BusEventBuildCreated BusEventType = "build.created"
+	// BusEventBuildStatus is published when a build's status changes.
+	BusEventBuildStatus BusEventType = "build.status"
+	// BusEventBuildDeleted is published when a pending build is cancelled.
+	BusEventBuildDeleted BusEventType = "build.deleted"
+	// BusEventProjectCreated is published when a project is created.
+	BusEventProjectCreated BusEventType = "project.created"
+	// BusEventProjectUpdated is published when a project is updated.
+	BusEventProjectUpdated BusEventType = "project.updated"
+	// BusEventProjectDeleted is published when a project is deleted.
+	BusEventProjectDeleted BusEventType = "project.deleted"
+)
+
+// BusEvent is published to a message bus, such as NATS or Kafka, when a build
+// or project changes. The topic is the configured topic prefix followed by a
+// dot and the event type, such as "wharf.build.status".
+type BusEvent struct {
+	Type      BusEventType `json:"type" enums:"build.created,build.status,build.deleted,project.created,project.updated,project.deleted"`
+	Timestamp time.Time    `json:"timestamp" format:"date-time"`
+	ProjectID uint         `json:"projectId" minimum:"0"`
+	// BuildID is only set for build events.
+	BuildID uint `json:"buildId,omitempty" minimum:"0"`
+	// StatusBefore is only set for build.status events.
+	StatusBefore BuildStatus `json:"statusBefore,omitempty" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	// Status is only set for build events.
+	Status BuildStatus `json:"status,omitempty" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	// Message holds additional details about build status changes, such as
+	// why the build failed to be triggered.
+	Message string `json:"message,omitempty"`
 }
 
+// BuildStatusHistory holds the status transitions of a build, together with
+// the time the build has spent in each status.
+type BuildStatusHistory struct {
+	BuildID uint                    `json:"buildId" minimum:"0"`
+	List    []BuildStatusTransition `json:"list"`
+	// QueueDuration is the total number of milliseconds the build has spent
+	// in the Scheduling status, counting each time the build was scheduled,
+	// or null if the build has never left the Scheduling status.
+	QueueDuration null.Int `json:"queueDuration" swaggertype:"integer" example:"4500" extensions:"x-nullable"`
+	// RunDuration is the total number of milliseconds the build has spent in
+	// the Running status, or null if the build has never left the Running
+	// status.
+	RunDuration null.Int `json:"runDuration" swaggertype:"integer" example:"93500" extensions:"x-nullable"`
+}
+
+// BuildStatusTransition is a change of a build's status.
+type BuildStatusTransition struct {
+	BuildStatusTransitionID uint `json:"buildStatusTransitionId" minimum:"0"`
+	// PreviousStatus is the status before the transition, or null for the
+	// transition into the build's initial status when the build was created.
+	PreviousStatus null.String `json:"previousStatus" swaggertype:"string" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending" extensions:"x-nullable"`
+	Status         BuildStatus `json:"status" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	Timestamp      time.Time   `json:"timestamp" format:"date-time"`
+	// Actor is who or what changed the status. Has an empty type and name if
+	// the status was changed by the wharf-api itself, such as when the build
+	// timed out, or if no authentication is configured.
+	Actor   BuildTriggeredBy `json:"actor"`
+	Message string           `json:"message" example:"step \"unit-tests\" exited with code 1"`
+}
+
+// BuildTimeline is a time-ordered list of the events of a build.
+type BuildTimeline struct {
+	BuildID uint                 `json:"buildId" minimum:"0"`
+	List    []BuildTimelineEvent `json:"list"`
+}
+
+// BuildTimelineEvent is a single event in a build's timeline. Which of the
+// fields are set depends on the type of the event.
+type BuildTimelineEvent struct {
+	Timestamp time.Time              `json:"timestamp" format:"date-time"`
+	Type      BuildTimelineEventType `json:"type" enums:"status,approval,stage,artifact,testResult"`
+	// Status is the new status of the build, for "status" events, or of the
+	// stage, for "stage" events.
+	Status BuildStatus `json:"status,omitempty" enums:"Scheduling,Running,Completed,Failed,TriggerFailed,AwaitingApproval,Pending"`
+	// ApprovalStatus is set for "approval" events.
+	ApprovalStatus BuildApprovalStatus `json:"approvalStatus,omitempty" enums:"Approved,Rejected"`
+	// StageName is set for "stage" events.
+	StageName string `json:"stageName,omitempty" example:"test"`
+	// ArtifactID is set for "artifact" events, as well as for "testResult"
+	// events unless the test results' artifact has been deleted.
+	ArtifactID          null.Int `json:"artifactId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	TestResultSummaryID null.Int `json:"testResultSummaryId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	FileName            string   `json:"fileName,omitempty" example:"results.trx"`
+	// Message holds additional details about the event, such as why the
+	// build failed, or the comment of an approval.
+	Message string `json:"message,omitempty"`
+}
+
+// BuildTimelineEventType is an enum of the types of events in a build's
+// timeline.
+type BuildTimelineEventType string
+
+const (
+	// BuildTimelineEventStatus means the build's status changed.
+	BuildTimelineEventStatus BuildTimelineEventType = "status"
+	// BuildTimelineEventApproval means the build was approved or rejected.
+	BuildTimelineEventApproval BuildTimelineEventType = "approval"
+	// BuildTimelineEventStage means a stage of the build started or finished.
+	BuildTimelineEventStage BuildTimelineEventType = "stage"
+	// BuildTimelineEventArtifact means an artifact was uploaded to the build.
+	BuildTimelineEventArtifact BuildTimelineEventType = "artifact"
+	// BuildTimelineEventTestResult means test results were added to the
+	// build.
+	BuildTimelineEventTestResult BuildTimelineEventType = "testResult"
+)
+
 // BuildParam holds the name and value of an input parameter fed into a build.
 type BuildParam struct {
 	BuildID uint   `json:"buildId" minimum:"0"`
 	Name    string `json:"name"`
-	Value   string `json:"value"`
+	// Value is the value of the input parameter. Redacted if the parameter is
+	// secret, unless revealed by an admin via GET /build/{buildId}/param.
+	Value string `json:"value"`
+	// IsSecret is true if the parameter comes from an input of type
+	// "password", where the value is redacted.
+	IsSecret bool `json:"isSecret"`
 }
 
+// RedactedValue is used in place of secret values in responses.
+const RedactedValue = "~~redacted~~"
+
 // BuildReferenceWrapper holds a build reference. A unique identifier to a
 // build.
 type BuildReferenceWrapper struct {
@@ -136,24 +752,120 @@ const (
 	// misconfiguration in the .wharf-ci.yml file, or perhaps a scripting error
 	// in some build step.
 	BuildFailed BuildStatus = "Failed"
+	// BuildTriggerFailed means that the execution engine could not be
+	// triggered to start the build, so the build was never started.
+	BuildTriggerFailed BuildStatus = "TriggerFailed"
+	// BuildAwaitingApproval means the build targets a protected environment,
+	// and will not be sent to the execution engine until it is approved.
+	BuildAwaitingApproval BuildStatus = "AwaitingApproval"
+	// BuildPending means the build was started with a scheduled time, and
+	// will not be sent to the execution engine until that time.
+	BuildPending BuildStatus = "Pending"
 )
 
 // Engine is an execution engine wharf-api uses to perform its builds.
 // Engines are configured in wharf-api's configuration, and cannot be changed
 // on a running instance of wharf-api.
 type Engine struct {
-	ID   string `json:"id" example:"primary"`
-	Name string `json:"name" example:"Primary"`
-	URL  string `json:"url" example:"http://wharf-cmd-provisioner/trigger"`
-	API  string `json:"api" example:"wharf-cmd.v1"`
+	ID    string       `json:"id" example:"primary"`
+	Name  string       `json:"name" example:"Primary"`
+	URL   string       `json:"url" example:"http://wharf-cmd-provisioner/trigger"`
+	API   string       `json:"api" example:"wharf-cmd.v1"`
+	State *EngineState `json:"state,omitempty" extensions:"x-nullable"`
+	// Status is the result of the latest health check of the engine. Only
+	// included in the engine endpoints.
+	Status          EngineStatus `json:"status,omitempty" enums:"unknown,reachable,unreachable,unauthorized"`
+	StatusCheckedOn null.Time    `json:"statusCheckedOn,omitempty" format:"date-time" extensions:"x-nullable"`
+	StatusError     string       `json:"statusError,omitempty"`
+	// Capabilities tells which features the engine supports, based on its
+	// API. Only included in the engine endpoints.
+	Capabilities *EngineCapabilities `json:"capabilities,omitempty" extensions:"x-nullable"`
 }
 
+// EngineCapabilities holds flags of which features an execution engine
+// supports, based on its API.
+type EngineCapabilities struct {
+	// SupportsCancel is true if builds on the engine can be canceled.
+	SupportsCancel bool `json:"supportsCancel"`
+	// SupportsWorkerID is true if the engine responds with the ID of the
+	// worker that runs the build, which is then set as the build's workerId.
+	SupportsWorkerID bool `json:"supportsWorkerId"`
+	// SupportsLogStreamingBack is true if the engine streams the build's logs
+	// back to wharf-api while the build is running.
+	SupportsLogStreamingBack bool `json:"supportsLogStreamingBack"`
+}
+
+// EngineStatus is an enum of results of the health checks sent to an
+// execution engine.
+type EngineStatus string
+
+const (
+	// EngineStatusUnknown means no health check has completed yet.
+	EngineStatusUnknown EngineStatus = "unknown"
+	// EngineStatusReachable means the engine responded to the latest health
+	// check without any server or authorization errors.
+	EngineStatusReachable EngineStatus = "reachable"
+	// EngineStatusUnreachable means the latest health check could not reach
+	// the engine, or the engine responded with a server error.
+	EngineStatusUnreachable EngineStatus = "unreachable"
+	// EngineStatusUnauthorized means the engine rejected the credentials of
+	// the latest health check, such as an invalid engine token.
+	EngineStatusUnauthorized EngineStatus = "unauthorized"
+)
+
+// EngineState holds the circuit breaker state and the statistics of the latest
+// request sent to an execution engine.
+type EngineState struct {
+	Circuit             EngineCircuit `json:"circuit" enums:"closed,open,half-open"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	LastRequestOn       null.Time     `json:"lastRequestOn" format:"date-time" extensions:"x-nullable"`
+	// LastLatency is the number of milliseconds the latest request took.
+	LastLatency    int64  `json:"lastLatency" example:"120"`
+	LastStatusCode int    `json:"lastStatusCode" example:"200"`
+	LastError      string `json:"lastError"`
+}
+
+// EngineCircuit is an enum of circuit breaker states of an execution engine.
+type EngineCircuit string
+
+const (
+	// EngineCircuitClosed means requests are sent to the engine as usual.
+	EngineCircuitClosed EngineCircuit = "closed"
+	// EngineCircuitOpen means the engine has failed too many times in a row,
+	// and no requests are sent to it until the cooldown has passed.
+	EngineCircuitOpen EngineCircuit = "open"
+	// EngineCircuitHalfOpen means a single trial request is being sent to the
+	// engine, to check if it has recovered.
+	EngineCircuitHalfOpen EngineCircuit = "half-open"
+)
+
 // EngineList contains a list of execution engines that the wharf-api is
 // configured with, as well as a declaration of which one is the default engine
 // that will be used on new builds if no engine is specified.
 type EngineList struct {
 	DefaultEngine *Engine  `json:"defaultEngine" extensions:"x-nullable"`
 	List          []Engine `json:"list"`
+	// ResolutionOrder is the IDs of the engines in the order they are
+	// considered when choosing the default engine, where the first engine is
+	// the default engine. Added in v5.3.0.
+	ResolutionOrder []string `json:"resolutionOrder" example:"primary,secondary"`
+}
+
+// ProblemType holds the documentation of a problem type that the wharf-api may
+// respond with, as well as its machine-readable code. Clients should branch on
+// the code or type URI of a problem, and not on its title or detail.
+type ProblemType struct {
+	Code        string `json:"code" example:"project-run-trigger"`
+	Type        string `json:"type" example:"https://iver-wharf.github.io/#/prob/api/project/run/trigger"`
+	Title       string `json:"title" example:"Triggering build failed."`
+	Status      int    `json:"status" example:"502"`
+	Description string `json:"description" example:"The execution engine failed to schedule the build."`
+}
+
+// ProblemTypeList contains a list of all problem types that the wharf-api may
+// respond with.
+type ProblemTypeList struct {
+	List []ProblemType `json:"list"`
 }
 
 // HealthStatus holds a human-readable string stating the health of the API and
@@ -171,6 +883,34 @@ type Log struct {
 	Timestamp time.Time `json:"timestamp" format:"date-time"`
 }
 
+// Badge is a build status badge in the format of the shields.io "endpoint"
+// badges: https://shields.io/endpoint
+type Badge struct {
+	SchemaVersion int    `json:"schemaVersion" example:"1"`
+	Label         string `json:"label" example:"build"`
+	Message       string `json:"message" example:"passing"`
+	Color         string `json:"color" example:"#4c1"`
+}
+
+// LogSearchMatch is a single logged line that matched a log search.
+type LogSearchMatch struct {
+	LogID     uint      `json:"logId" minimum:"0"`
+	BuildID   uint      `json:"buildId" minimum:"0"`
+	ProjectID uint      `json:"projectId" minimum:"0"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp" format:"date-time"`
+	// Offset is the number of log lines before this line in the build's log,
+	// which can be used to find the surrounding lines in the full log.
+	Offset int64 `json:"offset" minimum:"0"`
+}
+
+// PaginatedLogSearchMatches is a list of log search matches as well as an
+// explicit total count field.
+type PaginatedLogSearchMatches struct {
+	List       []LogSearchMatch `json:"list"`
+	TotalCount int64            `json:"totalCount"`
+}
+
 // PaginatedArtifacts is a list of artifacts as well as the explicit total count
 // field.
 type PaginatedArtifacts struct {
@@ -178,6 +918,20 @@ type PaginatedArtifacts struct {
 	TotalCount int64      `json:"totalCount"`
 }
 
+// PaginatedProjectArtifacts is a list of artifacts of a project's builds as
+// well as the explicit total count field.
+type PaginatedProjectArtifacts struct {
+	List       []ProjectArtifact `json:"list"`
+	TotalCount int64             `json:"totalCount"`
+}
+
+// PaginatedReleaseArtifacts is a list of release artifacts as well as the
+// explicit total count field.
+type PaginatedReleaseArtifacts struct {
+	List       []ReleaseArtifact `json:"list"`
+	TotalCount int64             `json:"totalCount"`
+}
+
 // PaginatedBranches is a list of branches as well as an explicit total count
 // field.
 type PaginatedBranches struct {
@@ -186,12 +940,47 @@ type PaginatedBranches struct {
 	DefaultBranch *Branch  `json:"defaultBranch"`
 }
 
+// PaginatedEnvironments is a list of environments as well as an explicit total
+// count field.
+type PaginatedEnvironments struct {
+	List       []Environment `json:"list"`
+	TotalCount int64         `json:"totalCount"`
+}
+
 // PaginatedBuilds is a list of builds as well as an explicit total count field.
 type PaginatedBuilds struct {
 	List       []Build `json:"list"`
 	TotalCount int64   `json:"totalCount"`
 }
 
+// PaginatedBuildTriggers is a list of build triggers as well as an explicit
+// total count field.
+type PaginatedBuildTriggers struct {
+	List       []BuildTrigger `json:"list"`
+	TotalCount int64          `json:"totalCount"`
+}
+
+// PaginatedGitTriggers is a list of Git triggers as well as an explicit total
+// count field.
+type PaginatedGitTriggers struct {
+	List       []GitTrigger `json:"list"`
+	TotalCount int64        `json:"totalCount"`
+}
+
+// PaginatedNotificationRules is a list of notification rules as well as an
+// explicit total count field.
+type PaginatedNotificationRules struct {
+	List       []NotificationRule `json:"list"`
+	TotalCount int64              `json:"totalCount"`
+}
+
+// PaginatedProjectGroups is a list of project groups as well as an explicit
+// total count field.
+type PaginatedProjectGroups struct {
+	List       []ProjectGroup `json:"list"`
+	TotalCount int64          `json:"totalCount"`
+}
+
 // PaginatedProjects is a list of projects as well as the explicit total count
 // field.
 type PaginatedProjects struct {
@@ -232,6 +1021,15 @@ type Ping struct {
 	Message string `json:"message" example:"pong"`
 }
 
+// Settings is a key-value object of user or instance-wide settings, where the
+// key is the setting name and the value is its string, boolean, or numeric
+// value. Settings that have not been set hold their default values.
+type Settings map[string]any
+
+// Labels is a key-value object of labels used to categorize builds and
+// projects, such as by team or release train, where the key is the label name.
+type Labels map[string]string
+
 // ProjectJSONFields holds the JSON field names for each field.
 // Useful in ordering statements to map the correct field to the correct
 // database column.
@@ -251,6 +1049,37 @@ var ProjectJSONFields = struct {
 	GitURL:          "gitUrl",
 }
 
+// ProjectGroupJSONFields holds the JSON field names for each field.
+// Useful in ordering statements to map the correct field to the correct
+// database column.
+var ProjectGroupJSONFields = struct {
+	GroupID string
+	Name    string
+	Path    string
+}{
+	GroupID: "groupId",
+	Name:    "name",
+	Path:    "path",
+}
+
+// ProjectGroup holds details about a group of projects. Groups are nested by
+// splitting the projects' group names on slashes.
+type ProjectGroup struct {
+	TimeMetadata
+	GroupID  uint   `json:"groupId" minimum:"0"`
+	ParentID *uint  `json:"parentId" minimum:"0" extensions:"x-nullable"`
+	Name     string `json:"name" example:"Stuff"`
+	Path     string `json:"path" example:"My Group/Stuff"`
+	// ProjectCount is the number of projects directly in this group.
+	ProjectCount int64 `json:"projectCount"`
+	// TotalProjectCount is the number of projects in this group and all of
+	// its subgroups.
+	TotalProjectCount int64 `json:"totalProjectCount"`
+	// TotalBuildCount is the number of builds of all projects in this group
+	// and all of its subgroups.
+	TotalBuildCount int64 `json:"totalBuildCount"`
+}
+
 // Project holds details about a project.
 type Project struct {
 	TimeMetadata
@@ -267,6 +1096,24 @@ type Project struct {
 	Branches              []Branch  `json:"branches"`
 	GitURL                string    `json:"gitUrl"`
 	ParsedBuildDefinition any       `json:"build" swaggertype:"object" extensions:"x-nullable"`
+	Version               uint      `json:"version" minimum:"0"`
+	Labels                Labels    `json:"labels"`
+	InstanceID            string    `json:"instanceId" example:"prod"`
+	// IsArchived means the project is hidden from the project lists by
+	// default, and cannot start new builds.
+	IsArchived bool `json:"isArchived"`
+	// SchedulingTimeout is the number of seconds the project's builds may stay
+	// in the Scheduling status before being marked as failed. Null means the
+	// ci.schedulingTimeout config is used, while 0 means no timeout.
+	//
+	// Added in v5.3.0.
+	SchedulingTimeout null.Int `json:"schedulingTimeout" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	// RunningTimeout is the number of seconds the project's builds may stay
+	// in the Running status before being marked as failed. Null means the
+	// ci.runningTimeout config is used, while 0 means no timeout.
+	//
+	// Added in v5.3.0.
+	RunningTimeout null.Int `json:"runningTimeout" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
 }
 
 // ProjectOverrides holds field overrides for a project.
@@ -275,6 +1122,28 @@ type ProjectOverrides struct {
 	Description string `json:"description"`
 	AvatarURL   string `json:"avatarUrl"`
 	GitURL      string `json:"gitUrl"`
+	Version     uint   `json:"version" minimum:"0"`
+}
+
+// ProjectInputDefaults holds a project's own default values of the input
+// variables declared in its build definition, which take precedence over the
+// default values from the build definition.
+type ProjectInputDefaults struct {
+	// Inputs holds default values that apply to builds on any environment,
+	// where the key is the input variable name.
+	Inputs map[string]string `json:"inputs"`
+	// Environments holds default values that only apply to builds on a given
+	// environment, where the key is the environment name. These take
+	// precedence over the default values in Inputs.
+	Environments map[string]map[string]string `json:"environments"`
+}
+
+// ProjectEngines holds the execution engines that a project is allowed to
+// start builds on.
+type ProjectEngines struct {
+	// EngineIDs holds the IDs of the allowed execution engines. An empty list
+	// means that builds are allowed on any execution engine.
+	EngineIDs []string `json:"engineIds"`
 }
 
 // ProviderJSONFields holds the JSON field names for each field.
@@ -297,14 +1166,20 @@ var ProviderJSONFields = struct {
 // used to authenticate.
 type Provider struct {
 	TimeMetadata
-	ProviderID uint         `json:"providerId" minimum:"0"`
-	Name       ProviderName `json:"name" enums:"azuredevops,gitlab,github"`
-	URL        string       `json:"url"`
-	TokenID    uint         `json:"tokenId" minimum:"0"`
+	ProviderID uint           `json:"providerId" minimum:"0"`
+	Name       ProviderName   `json:"name" example:"github"`
+	URL        string         `json:"url"`
+	UploadURL  string         `json:"uploadUrl" example:"https://uploads.github.example.com"`
+	TokenID    uint           `json:"tokenId" minimum:"0"`
+	Version    uint           `json:"version" minimum:"0"`
+	Metadata   map[string]any `json:"metadata" extensions:"x-nullable"`
 }
 
-// ProviderName is an enum of different providers that are available over at
-// https://github.com/iver-wharf
+// ProviderName is the name of a provider plugin, such as one of the well-known
+// providers that are available over at https://github.com/iver-wharf.
+//
+// Since v5.3.0, any provider name is allowed, such as for custom provider
+// plugins, unless restricted by the wharf-api configuration.
 type ProviderName string
 
 const (
@@ -319,6 +1194,33 @@ const (
 	ProviderGitHub ProviderName = "github"
 )
 
+// ProviderSync holds the status of a re-import of a provider's projects and
+// branches.
+type ProviderSync struct {
+	TimeMetadata
+	ProviderSyncID uint               `json:"providerSyncId" minimum:"0"`
+	ProviderID     uint               `json:"providerId" minimum:"0"`
+	Status         ProviderSyncStatus `json:"status" enums:"Running,Completed,Failed"`
+	StartedOn      null.Time          `json:"startedOn" format:"date-time" extensions:"x-nullable"`
+	CompletedOn    null.Time          `json:"finishedOn" format:"date-time" extensions:"x-nullable"`
+	Error          string             `json:"error" example:"non-2xx response: 401 Unauthorized"`
+}
+
+// ProviderSyncStatus is an enum of different states a provider sync can be in.
+type ProviderSyncStatus string
+
+const (
+	// ProviderSyncRunning means the provider plugin has been asked to re-import
+	// the provider's projects and has not yet responded.
+	ProviderSyncRunning ProviderSyncStatus = "Running"
+	// ProviderSyncCompleted means the provider plugin successfully re-imported
+	// the provider's projects.
+	ProviderSyncCompleted ProviderSyncStatus = "Completed"
+	// ProviderSyncFailed means the request to the provider plugin failed, or
+	// the plugin responded with an error.
+	ProviderSyncFailed ProviderSyncStatus = "Failed"
+)
+
 // TestStatus is an enum of different states a test run or test summary can be
 // in.
 type TestStatus string
@@ -341,14 +1243,38 @@ const (
 // TestResultDetail contains data about a single test in a test result file.
 type TestResultDetail struct {
 	TimeMetadata
-	TestResultDetailID uint             `json:"testResultDetailId" minimum:"0"`
-	ArtifactID         uint             `json:"artifactId" minimum:"0"`
-	BuildID            uint             `json:"buildId" minimum:"0"`
-	Name               string           `json:"name"`
-	Message            null.String      `json:"message" swaggertype:"string" extensions:"x-nullable"`
-	StartedOn          null.Time        `json:"startedOn" format:"date-time" extensions:"x-nullable"`
-	CompletedOn        null.Time        `json:"completedOn" format:"date-time" extensions:"x-nullable"`
-	Status             TestResultStatus `json:"status" enums:"Failed,Passed,Skipped"`
+	TestResultDetailID uint `json:"testResultDetailId" minimum:"0"`
+	// ArtifactID is the ID of the uploaded test result file, or null if the
+	// artifact has been deleted. Nullable since v5.3.0.
+	ArtifactID null.Int `json:"artifactId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	// TestResultSummaryID is the ID of the summary of the test result file
+	// that the test was parsed from, or null for tests uploaded before
+	// v5.3.0 whose summary could not be found.
+	//
+	// Added in v5.3.0.
+	TestResultSummaryID null.Int         `json:"testResultSummaryId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	BuildID             uint             `json:"buildId" minimum:"0"`
+	Name                string           `json:"name"`
+	Message             null.String      `json:"message" swaggertype:"string" extensions:"x-nullable"`
+	StartedOn           null.Time        `json:"startedOn" format:"date-time" extensions:"x-nullable"`
+	CompletedOn         null.Time        `json:"completedOn" format:"date-time" extensions:"x-nullable"`
+	Status              TestResultStatus `json:"status" enums:"Failed,Passed,Skipped"`
+	// ClassName is the fully qualified name of the class, or other grouping
+	// such as the package, that the test belongs to. Empty if not reported by
+	// the test result file.
+	//
+	// Added in v5.3.0.
+	ClassName string `json:"className" example:"Wharf.Api.Tests.BuildTests"`
+	// FilePath is the path to the source file of the test, as reported by the
+	// test result file. Empty if not reported by the test result file.
+	//
+	// Added in v5.3.0.
+	FilePath string `json:"filePath" example:"tests/BuildTests.cs"`
+	// LineNumber is the line in the source file of the test, if reported by
+	// the test result file.
+	//
+	// Added in v5.3.0.
+	LineNumber null.Int `json:"lineNumber" swaggertype:"integer" example:"42" extensions:"x-nullable"`
 }
 
 // TestResultListSummary contains data about several test result files.
@@ -377,12 +1303,30 @@ type TestResultSummary struct {
 	TimeMetadata
 	TestResultSummaryID uint   `json:"testResultSummaryId" minimum:"0"`
 	FileName            string `json:"fileName"`
-	ArtifactID          uint   `json:"artifactId" minimum:"0"`
-	BuildID             uint   `json:"buildId" minimum:"0"`
-	Total               uint   `json:"total"`
-	Failed              uint   `json:"failed"`
-	Passed              uint   `json:"passed"`
-	Skipped             uint   `json:"skipped"`
+	// ArtifactID is the ID of the uploaded test result file, or null if the
+	// artifact has been deleted. Nullable since v5.3.0.
+	ArtifactID null.Int `json:"artifactId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	BuildID    uint     `json:"buildId" minimum:"0"`
+	Total      uint     `json:"total"`
+	Failed     uint     `json:"failed"`
+	Passed     uint     `json:"passed"`
+	Skipped    uint     `json:"skipped"`
+}
+
+// TestResultReparse holds the artifacts that were re-parsed as test results.
+type TestResultReparse struct {
+	// Reparsed are the artifacts that had their test results replaced.
+	Reparsed []TestResultReparseArtifact `json:"reparsed"`
+	// Skipped are the artifacts that matched, but failed to parse as test
+	// results, and therefore kept any previous test results.
+	Skipped []TestResultReparseArtifact `json:"skipped"`
+}
+
+// TestResultReparseArtifact is an artifact that was re-parsed as test results.
+type TestResultReparseArtifact struct {
+	ArtifactID uint   `json:"artifactId" minimum:"0"`
+	BuildID    uint   `json:"buildId" minimum:"0"`
+	FileName   string `json:"fileName"`
 }
 
 // TestsResults holds how many builds has passed and failed. A test result has
@@ -414,4 +1358,76 @@ type Token struct {
 	TokenID  uint   `json:"tokenId" minimum:"0"`
 	Token    string `json:"token" format:"password"`
 	UserName string `json:"userName"`
+	Version  uint   `json:"version" minimum:"0"`
+}
+
+// BuildStatsList is a list of build statistics, where each item holds the
+// statistics of one group of builds, such as all builds of a project.
+type BuildStatsList struct {
+	List []BuildStats `json:"list"`
+}
+
+// BuildStats holds the number of builds in a group of builds, aggregated
+// from the daily build statistics. The fields of the grouping, such as
+// projectId and day, are only set when grouped by them.
+type BuildStats struct {
+	ProjectID null.Int    `json:"projectId" swaggertype:"integer" minimum:"0" extensions:"x-nullable"`
+	Day       null.String `json:"day" swaggertype:"string" format:"date" example:"2022-05-10" extensions:"x-nullable"`
+	Total     uint        `json:"total"`
+	Completed uint        `json:"completed"`
+	// Failed is the number of builds that failed, including the builds that
+	// failed to be triggered.
+	Failed uint `json:"failed"`
+	// SuccessRate is the number of completed builds divided by the number of
+	// completed and failed builds, or null if no builds have finished.
+	SuccessRate null.Float `json:"successRate" swaggertype:"number" example:"0.95" extensions:"x-nullable"`
+	// AverageDuration is the average duration of the builds in milliseconds,
+	// or null if no builds have a duration.
+	AverageDuration null.Int `json:"averageDuration" swaggertype:"integer" example:"93500" extensions:"x-nullable"`
+}
+
+// WorkerStatsList is a list of statistics of the workers that builds have run
+// on.
+type WorkerStatsList struct {
+	List []WorkerStats `json:"list"`
+}
+
+// WorkerStats holds the number of builds that have run on a worker, as
+// identified by the builds' workerId field.
+type WorkerStats struct {
+	WorkerID  string `json:"workerId" example:"5d6bcf20-81fd-4ad8-a446-735aa8423dfe"`
+	Total     uint   `json:"total"`
+	Completed uint   `json:"completed"`
+	// Failed is the number of builds that failed, including the builds that
+	// failed to be triggered.
+	Failed uint `json:"failed"`
+	// Running is the number of builds currently running on the worker,
+	// regardless of when they were scheduled.
+	Running uint `json:"running"`
+	// FailureRate is the number of failed builds divided by the number of
+	// completed and failed builds, or null if no builds have finished.
+	FailureRate null.Float `json:"failureRate" swaggertype:"number" example:"0.05" extensions:"x-nullable"`
+	// AverageDuration is the average duration of the builds in milliseconds,
+	// or null if no builds have a duration.
+	AverageDuration null.Int `json:"averageDuration" swaggertype:"integer" example:"93500" extensions:"x-nullable"`
+}
+
+// ConfigReload is the result of reloading the config of wharf-api.
+type ConfigReload struct {
+	// Applied is the list of reloadable config values that were changed by the
+	// reload, such as "ci.engine" or "http.cors".
+	Applied []string `json:"applied" example:"http.cors"`
+	// RestartRequired is true if config values that are not reloadable were
+	// changed, which only take effect after restarting wharf-api.
+	RestartRequired bool `json:"restartRequired"`
+}
+
+// LogLevel holds the minimum levels of the logs written by wharf-api.
+type LogLevel struct {
+	// Level is the global minimum level of all logs.
+	Level string `json:"level" enums:"debug,info,warn,error,panic" example:"info"`
+	// Scopes holds minimum levels of specific logging scopes, such as "GIN"
+	// or "WHARF", where the key is the uppercased scope name. Only scopes
+	// with a level above the global level have any effect.
+	Scopes map[string]string `json:"scopes"`
 }
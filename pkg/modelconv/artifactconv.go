@@ -13,6 +13,11 @@ func DBArtifactToResponse(dbArtifact database.Artifact) response.Artifact {
 		BuildID:      dbArtifact.BuildID,
 		Name:         dbArtifact.Name,
 		FileName:     dbArtifact.FileName,
+		Size:         dbArtifact.Size,
+		ContentType:  dbArtifact.ContentType,
+
+		PromotedFromBuildID:    dbArtifact.PromotedFromBuildID,
+		PromotedFromArtifactID: dbArtifact.PromotedFromArtifactID,
 	}
 }
 
@@ -25,3 +30,68 @@ func DBArtifactsToResponses(dbArtifacts []database.Artifact) []response.Artifact
 	}
 	return resArtifacts
 }
+
+// DBArtifactToResponseProjectArtifact converts a database artifact, with its
+// build preloaded, to a response project artifact.
+func DBArtifactToResponseProjectArtifact(dbArtifact database.Artifact) response.ProjectArtifact {
+	resArtifact := response.ProjectArtifact{
+		Artifact: DBArtifactToResponse(dbArtifact),
+	}
+	if dbArtifact.Build != nil {
+		resArtifact.Build = response.ArtifactBuild{
+			BuildID:     dbArtifact.Build.BuildID,
+			StatusID:    int(dbArtifact.Build.StatusID),
+			Status:      DBBuildStatusToResponse(dbArtifact.Build.StatusID),
+			GitBranch:   dbArtifact.Build.GitBranch,
+			GitCommit:   dbArtifact.Build.GitCommit,
+			Stage:       dbArtifact.Build.Stage,
+			Environment: dbArtifact.Build.Environment,
+			ScheduledOn: dbArtifact.Build.ScheduledOn,
+			FinishedOn:  dbArtifact.Build.CompletedOn,
+		}
+	}
+	return resArtifact
+}
+
+// DBArtifactsToResponseProjectArtifacts converts a slice of database
+// artifacts, with their builds preloaded, to a slice of response project
+// artifacts.
+func DBArtifactsToResponseProjectArtifacts(dbArtifacts []database.Artifact) []response.ProjectArtifact {
+	resArtifacts := make([]response.ProjectArtifact, len(dbArtifacts))
+	for i, dbArtifact := range dbArtifacts {
+		resArtifacts[i] = DBArtifactToResponseProjectArtifact(dbArtifact)
+	}
+	return resArtifacts
+}
+
+// DBReleaseArtifactToResponse converts a database release artifact to a
+// response release artifact.
+func DBReleaseArtifactToResponse(dbArtifact database.ReleaseArtifact) response.ReleaseArtifact {
+	return response.ReleaseArtifact{
+		TimeMetadata:           DBTimeMetadataToResponse(dbArtifact.TimeMetadata),
+		ReleaseArtifactID:      dbArtifact.ReleaseArtifactID,
+		ProjectID:              dbArtifact.ProjectID,
+		Release:                dbArtifact.Release,
+		Name:                   dbArtifact.Name,
+		FileName:               dbArtifact.FileName,
+		Size:                   dbArtifact.Size,
+		ContentType:            dbArtifact.ContentType,
+		PromotedFromBuildID:    dbArtifact.PromotedFromBuildID,
+		PromotedFromArtifactID: dbArtifact.PromotedFromArtifactID,
+		PromotedBy: response.BuildTriggeredBy{
+			Type:    response.BuildTriggerType(dbArtifact.PromotedByType),
+			Name:    dbArtifact.PromotedByName,
+			Subject: dbArtifact.PromotedBySubject,
+		},
+	}
+}
+
+// DBReleaseArtifactsToResponses converts a slice of database release
+// artifacts to a slice of response release artifacts.
+func DBReleaseArtifactsToResponses(dbArtifacts []database.ReleaseArtifact) []response.ReleaseArtifact {
+	resArtifacts := make([]response.ReleaseArtifact, len(dbArtifacts))
+	for i, dbArtifact := range dbArtifacts {
+		resArtifacts[i] = DBReleaseArtifactToResponse(dbArtifact)
+	}
+	return resArtifacts
+}
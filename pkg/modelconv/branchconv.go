@@ -53,9 +53,30 @@ func DBBranchToResponse(dbBranch database.Branch) response.Branch {
 		Name:         dbBranch.Name,
 		Default:      dbBranch.Default,
 		TokenID:      dbBranch.TokenID,
+		LastBuildID:  dbBranch.LastBuildID,
+		LastBuiltOn:  dbBranch.LastBuiltOn,
 	}
 }
 
+// DBBranchToDetailsResponse converts a database branch and its optional latest
+// build to a response branch with details.
+func DBBranchToDetailsResponse(dbBranch database.Branch, dbLatestBuild *database.Build) response.BranchDetails {
+	resBranch := response.BranchDetails{
+		Branch: DBBranchToResponse(dbBranch),
+	}
+	if dbLatestBuild != nil {
+		resBranch.LatestBuild = &response.BranchLatestBuild{
+			BuildID:     dbLatestBuild.BuildID,
+			StatusID:    int(dbLatestBuild.StatusID),
+			Status:      DBBuildStatusToResponse(dbLatestBuild.StatusID),
+			Stage:       dbLatestBuild.Stage,
+			ScheduledOn: dbLatestBuild.ScheduledOn,
+			CompletedOn: dbLatestBuild.CompletedOn,
+		}
+	}
+	return resBranch
+}
+
 // ReqBranchUpdatesToSetOfNames converts a slice of request branch updates to a
 // set of branch names.
 func ReqBranchUpdatesToSetOfNames(reqBranches []request.BranchUpdate) maps.Set[string] {
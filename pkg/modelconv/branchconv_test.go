@@ -0,0 +1,28 @@
+package modelconv
+
+import (
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBBranchToDetailsResponse(t *testing.T) {
+	dbBranch := database.Branch{BranchID: 1, ProjectID: 2, Name: "main"}
+
+	resBranch := DBBranchToDetailsResponse(dbBranch, nil)
+	assert.Equal(t, "main", resBranch.Name)
+	assert.Nil(t, resBranch.LatestBuild)
+
+	resBranch = DBBranchToDetailsResponse(dbBranch, &database.Build{
+		BuildID:  3,
+		StatusID: database.BuildFailed,
+		Stage:    "deploy",
+	})
+	if assert.NotNil(t, resBranch.LatestBuild) {
+		assert.Equal(t, uint(3), resBranch.LatestBuild.BuildID)
+		assert.Equal(t, response.BuildFailed, resBranch.LatestBuild.Status)
+		assert.Equal(t, "deploy", resBranch.LatestBuild.Stage)
+	}
+}
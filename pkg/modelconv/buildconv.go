@@ -21,10 +21,15 @@ func DBBuildParamsToResponses(dbParams []database.BuildParam) []response.BuildPa
 // DBBuildParamToResponse converts a database build parameter to a response
 // build parameter.
 func DBBuildParamToResponse(dbParam database.BuildParam) response.BuildParam {
+	value := dbParam.Value
+	if dbParam.IsSecret {
+		value = response.RedactedValue
+	}
 	return response.BuildParam{
-		BuildID: dbParam.BuildID,
-		Name:    dbParam.Name,
-		Value:   dbParam.Value,
+		BuildID:  dbParam.BuildID,
+		Name:     dbParam.Name,
+		Value:    value,
+		IsSecret: dbParam.IsSecret,
 	}
 }
 
@@ -60,6 +65,10 @@ func DBBuildToResponse(dbBuild database.Build, engineLookup EngineLookup) respon
 	var engine *response.Engine
 	if dbBuild.EngineID != "" {
 		engine = engineLookup(dbBuild.EngineID)
+		if engine == nil {
+			// The engine has since been removed from the configuration.
+			engine = &response.Engine{ID: dbBuild.EngineID, Name: dbBuild.EngineID}
+		}
 	}
 	return response.Build{
 		TimeMetadata:          DBTimeMetadataToResponse(dbBuild.TimeMetadata),
@@ -67,10 +76,13 @@ func DBBuildToResponse(dbBuild database.Build, engineLookup EngineLookup) respon
 		StatusID:              int(dbBuild.StatusID),
 		Status:                DBBuildStatusToResponse(dbBuild.StatusID),
 		ProjectID:             dbBuild.ProjectID,
+		BuildNumber:           dbBuild.BuildNumber,
 		ScheduledOn:           dbBuild.ScheduledOn,
 		StartedOn:             dbBuild.StartedOn,
 		CompletedOn:           dbBuild.CompletedOn,
 		GitBranch:             dbBuild.GitBranch,
+		GitCommit:             dbBuild.GitCommit,
+		GitTag:                dbBuild.GitTag,
 		Environment:           dbBuild.Environment,
 		Stage:                 dbBuild.Stage,
 		WorkerID:              dbBuild.WorkerID,
@@ -79,6 +91,166 @@ func DBBuildToResponse(dbBuild database.Build, engineLookup EngineLookup) respon
 		TestResultSummaries:   DBTestResultSummariesToResponses(dbBuild.TestResultSummaries),
 		TestResultListSummary: resListSummary,
 		Engine:                engine,
+		Duration:              dbBuild.Duration,
+		TriggeredBy: response.BuildTriggeredBy{
+			Type:    response.BuildTriggerType(dbBuild.TriggeredByType),
+			Name:    dbBuild.TriggeredByName,
+			Subject: dbBuild.TriggeredBySubject,
+		},
+		TriggerError:  dbBuild.TriggerError,
+		StatusMessage: dbBuild.StatusMessage,
+		BuildGroupID:  dbBuild.BuildGroupID,
+		Approval:      DBBuildToResponseBuildApproval(dbBuild),
+		Labels:        DBBuildLabelsToResponse(dbBuild.Labels),
+		InstanceID:    dbBuild.InstanceID,
+		CommentCount:  len(dbBuild.Comments),
+		Links:         DBBuildLinksToResponses(dbBuild.Links),
+		StageResults:  DBBuildStageResultsToResponses(dbBuild.StageResults),
+		ScheduleAt:    dbBuild.ScheduleAt,
+	}
+}
+
+// DBBuildStageResultsToResponses converts a slice of database build stage
+// results to a slice of response build stage results.
+func DBBuildStageResultsToResponses(dbResults []database.BuildStageResult) []response.BuildStageResult {
+	resResults := make([]response.BuildStageResult, len(dbResults))
+	for i, dbResult := range dbResults {
+		resResults[i] = DBBuildStageResultToResponse(dbResult)
+	}
+	return resResults
+}
+
+// DBBuildStageResultToResponse converts a database build stage result to a
+// response build stage result.
+func DBBuildStageResultToResponse(dbResult database.BuildStageResult) response.BuildStageResult {
+	return response.BuildStageResult{
+		TimeMetadata:  DBTimeMetadataToResponse(dbResult.TimeMetadata),
+		Name:          dbResult.Name,
+		Status:        DBBuildStatusToResponse(dbResult.StatusID),
+		StatusMessage: dbResult.StatusMessage,
+		StartedOn:     dbResult.StartedOn,
+		CompletedOn:   dbResult.CompletedOn,
+		Duration:      dbResult.Duration,
+	}
+}
+
+// DBBuildLinksToResponses converts a slice of database build links to a slice
+// of response build links.
+func DBBuildLinksToResponses(dbLinks []database.BuildLink) []response.BuildLink {
+	resLinks := make([]response.BuildLink, len(dbLinks))
+	for i, dbLink := range dbLinks {
+		resLinks[i] = DBBuildLinkToResponse(dbLink)
+	}
+	return resLinks
+}
+
+// DBBuildLinkToResponse converts a database build link to a response build
+// link.
+func DBBuildLinkToResponse(dbLink database.BuildLink) response.BuildLink {
+	return response.BuildLink{
+		TimeMetadata: DBTimeMetadataToResponse(dbLink.TimeMetadata),
+		BuildLinkID:  dbLink.BuildLinkID,
+		BuildID:      dbLink.BuildID,
+		Name:         dbLink.Name,
+		URL:          dbLink.URL,
+	}
+}
+
+// DBBuildCommentsToResponses converts a slice of database build comments to a
+// slice of response build comments.
+func DBBuildCommentsToResponses(dbComments []database.BuildComment) []response.BuildComment {
+	resComments := make([]response.BuildComment, len(dbComments))
+	for i, dbComment := range dbComments {
+		resComments[i] = DBBuildCommentToResponse(dbComment)
+	}
+	return resComments
+}
+
+// DBBuildCommentToResponse converts a database build comment to a response
+// build comment.
+func DBBuildCommentToResponse(dbComment database.BuildComment) response.BuildComment {
+	return response.BuildComment{
+		TimeMetadata:   DBTimeMetadataToResponse(dbComment.TimeMetadata),
+		BuildCommentID: dbComment.BuildCommentID,
+		BuildID:        dbComment.BuildID,
+		Author: response.BuildTriggeredBy{
+			Type:    response.BuildTriggerType(dbComment.AuthorType),
+			Name:    dbComment.AuthorName,
+			Subject: dbComment.AuthorSubject,
+		},
+		Text: dbComment.Text,
+	}
+}
+
+// DBBuildToResponseBuildApproval converts the approval fields of a database
+// build to a response build approval, or nil if the build does not need to be
+// approved.
+func DBBuildToResponseBuildApproval(dbBuild database.Build) *response.BuildApproval {
+	if dbBuild.ApprovalStatus == database.BuildApprovalNotRequired {
+		return nil
+	}
+	resApproval := response.BuildApproval{
+		Status:     response.BuildApprovalStatus(dbBuild.ApprovalStatus),
+		ReviewedOn: dbBuild.ReviewedOn,
+		Comment:    dbBuild.ReviewComment,
+	}
+	if dbBuild.ApprovalStatus != database.BuildApprovalPending {
+		resApproval.ReviewedBy = &response.BuildTriggeredBy{
+			Type:    response.BuildTriggerType(dbBuild.ReviewedByType),
+			Name:    dbBuild.ReviewedByName,
+			Subject: dbBuild.ReviewedBySubject,
+		}
+	}
+	return &resApproval
+}
+
+// DBBuildGroupToResponse converts a database build group and its builds to a
+// response build group, with the statuses of its builds aggregated.
+func DBBuildGroupToResponse(dbBuildGroup database.BuildGroup, dbBuilds []database.Build, engineLookup EngineLookup) response.BuildGroup {
+	var counts response.BuildGroupStatusCounts
+	for _, dbBuild := range dbBuilds {
+		switch dbBuild.StatusID {
+		case database.BuildScheduling:
+			counts.Scheduling++
+		case database.BuildRunning:
+			counts.Running++
+		case database.BuildCompleted:
+			counts.Completed++
+		case database.BuildFailed:
+			counts.Failed++
+		case database.BuildTriggerFailed:
+			counts.TriggerFailed++
+		case database.BuildAwaitingApproval:
+			counts.AwaitingApproval++
+		}
+	}
+	return response.BuildGroup{
+		TimeMetadata: DBTimeMetadataToResponse(dbBuildGroup.TimeMetadata),
+		BuildGroupID: dbBuildGroup.BuildGroupID,
+		ProjectID:    dbBuildGroup.ProjectID,
+		Status:       aggregateBuildGroupStatus(counts),
+		StatusCounts: counts,
+		Builds:       DBBuildsToResponses(dbBuilds, engineLookup),
+	}
+}
+
+func aggregateBuildGroupStatus(counts response.BuildGroupStatusCounts) response.BuildStatus {
+	switch {
+	case counts.Failed > 0:
+		return response.BuildFailed
+	case counts.TriggerFailed > 0:
+		return response.BuildTriggerFailed
+	case counts.Running > 0:
+		return response.BuildRunning
+	case counts.AwaitingApproval > 0:
+		return response.BuildAwaitingApproval
+	case counts.Scheduling > 0 && counts.Completed > 0:
+		// Some builds are done, so the group as a whole has started.
+		return response.BuildRunning
+	case counts.Scheduling > 0:
+		return response.BuildScheduling
+	default:
+		return response.BuildCompleted
 	}
 }
 
@@ -102,6 +274,12 @@ func DBBuildStatusToResponse(dbStatus database.BuildStatus) response.BuildStatus
 		return response.BuildCompleted
 	case database.BuildFailed:
 		return response.BuildFailed
+	case database.BuildTriggerFailed:
+		return response.BuildTriggerFailed
+	case database.BuildAwaitingApproval:
+		return response.BuildAwaitingApproval
+	case database.BuildPending:
+		return response.BuildPending
 	default:
 		return response.BuildScheduling
 	}
@@ -119,6 +297,12 @@ func ReqBuildStatusToDatabase(reqStatus request.BuildStatus) (database.BuildStat
 		return database.BuildCompleted, true
 	case request.BuildFailed:
 		return database.BuildFailed, true
+	case request.BuildTriggerFailed:
+		return database.BuildTriggerFailed, true
+	case request.BuildAwaitingApproval:
+		return database.BuildAwaitingApproval, true
+	case request.BuildPending:
+		return database.BuildPending, true
 	default:
 		return database.BuildScheduling, false
 	}
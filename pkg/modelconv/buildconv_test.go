@@ -0,0 +1,96 @@
+package modelconv
+
+import (
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBBuildGroupToResponse_status(t *testing.T) {
+	testCases := []struct {
+		name     string
+		statuses []database.BuildStatus
+		want     response.BuildStatus
+	}{
+		{
+			name:     "all scheduling",
+			statuses: []database.BuildStatus{database.BuildScheduling, database.BuildScheduling},
+			want:     response.BuildScheduling,
+		},
+		{
+			name:     "some running",
+			statuses: []database.BuildStatus{database.BuildScheduling, database.BuildRunning},
+			want:     response.BuildRunning,
+		},
+		{
+			name:     "some completed",
+			statuses: []database.BuildStatus{database.BuildScheduling, database.BuildCompleted},
+			want:     response.BuildRunning,
+		},
+		{
+			name:     "all completed",
+			statuses: []database.BuildStatus{database.BuildCompleted, database.BuildCompleted},
+			want:     response.BuildCompleted,
+		},
+		{
+			name:     "some failed",
+			statuses: []database.BuildStatus{database.BuildRunning, database.BuildFailed, database.BuildTriggerFailed},
+			want:     response.BuildFailed,
+		},
+		{
+			name:     "some failed to trigger",
+			statuses: []database.BuildStatus{database.BuildCompleted, database.BuildTriggerFailed},
+			want:     response.BuildTriggerFailed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbBuilds := make([]database.Build, len(tc.statuses))
+			for i, status := range tc.statuses {
+				dbBuilds[i] = database.Build{BuildID: uint(i + 1), StatusID: status}
+			}
+			resGroup := DBBuildGroupToResponse(database.BuildGroup{BuildGroupID: 1}, dbBuilds, nil)
+			assert.Equal(t, tc.want, resGroup.Status)
+			assert.Len(t, resGroup.Builds, len(dbBuilds))
+		})
+	}
+}
+
+func TestDBBuildToResponseBuildApproval(t *testing.T) {
+	assert.Nil(t, DBBuildToResponseBuildApproval(database.Build{}), "not required")
+
+	pending := DBBuildToResponseBuildApproval(database.Build{ApprovalStatus: database.BuildApprovalPending})
+	if assert.NotNil(t, pending, "pending") {
+		assert.Equal(t, response.BuildApprovalPending, pending.Status)
+		assert.Nil(t, pending.ReviewedBy)
+	}
+
+	approved := DBBuildToResponseBuildApproval(database.Build{
+		ApprovalStatus: database.BuildApprovalApproved,
+		ReviewedByType: database.BuildTriggerOIDC,
+		ReviewedByName: "Jane Doe",
+		ReviewComment:  "lgtm",
+	})
+	if assert.NotNil(t, approved, "approved") {
+		assert.Equal(t, response.BuildApprovalApproved, approved.Status)
+		assert.Equal(t, &response.BuildTriggeredBy{Type: response.BuildTriggerOIDC, Name: "Jane Doe"}, approved.ReviewedBy)
+		assert.Equal(t, "lgtm", approved.Comment)
+	}
+}
+
+func TestDBBuildToResponse_engine(t *testing.T) {
+	primary := &response.Engine{ID: "primary", Name: "Primary", URL: "http://primary"}
+	engineLookup := func(id string) *response.Engine {
+		if id == primary.ID {
+			return primary
+		}
+		return nil
+	}
+
+	assert.Nil(t, DBBuildToResponse(database.Build{}, engineLookup).Engine, "no engine")
+	assert.Equal(t, primary, DBBuildToResponse(database.Build{EngineID: "primary"}, engineLookup).Engine, "configured")
+	assert.Equal(t, &response.Engine{ID: "removed", Name: "removed"},
+		DBBuildToResponse(database.Build{EngineID: "removed"}, engineLookup).Engine, "removed from config")
+}
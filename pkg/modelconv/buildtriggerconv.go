@@ -0,0 +1,41 @@
+package modelconv
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// DBBuildTriggersToResponses converts a slice of database build triggers to a
+// slice of response build triggers.
+func DBBuildTriggersToResponses(dbTriggers []database.BuildTrigger) []response.BuildTrigger {
+	resTriggers := make([]response.BuildTrigger, len(dbTriggers))
+	for i, dbTrigger := range dbTriggers {
+		resTriggers[i] = DBBuildTriggerToResponse(dbTrigger)
+	}
+	return resTriggers
+}
+
+// DBBuildTriggerToResponse converts a database build trigger to a response
+// build trigger.
+func DBBuildTriggerToResponse(dbTrigger database.BuildTrigger) response.BuildTrigger {
+	return response.BuildTrigger{
+		TimeMetadata:    DBTimeMetadataToResponse(dbTrigger.TimeMetadata),
+		BuildTriggerID:  dbTrigger.BuildTriggerID,
+		SourceProjectID: dbTrigger.SourceProjectID,
+		TargetProjectID: dbTrigger.TargetProjectID,
+		BranchFilter:    dbTrigger.BranchFilter,
+		Stage:           dbTrigger.Stage,
+	}
+}
+
+// ReqBuildTriggerToDatabase converts a request build trigger to a database
+// build trigger.
+func ReqBuildTriggerToDatabase(sourceProjectID uint, reqTrigger request.BuildTrigger) database.BuildTrigger {
+	return database.BuildTrigger{
+		SourceProjectID: sourceProjectID,
+		TargetProjectID: reqTrigger.TargetProjectID,
+		BranchFilter:    reqTrigger.BranchFilter,
+		Stage:           reqTrigger.Stage,
+	}
+}
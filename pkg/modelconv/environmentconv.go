@@ -0,0 +1,66 @@
+package modelconv
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// DBEnvironmentsToResponses converts a slice of database environments to a
+// slice of response environments. The last builds are looked up by
+// environment name, and may be nil.
+func DBEnvironmentsToResponses(dbEnvs []database.Environment, dbLastBuilds map[string]database.Build) []response.Environment {
+	resEnvs := make([]response.Environment, len(dbEnvs))
+	for i, dbEnv := range dbEnvs {
+		var dbLastBuildPtr *database.Build
+		if dbLastBuild, ok := dbLastBuilds[dbEnv.Name]; ok {
+			dbLastBuildPtr = &dbLastBuild
+		}
+		resEnvs[i] = DBEnvironmentToResponse(dbEnv, dbLastBuildPtr)
+	}
+	return resEnvs
+}
+
+// DBEnvironmentToResponse converts a database environment to a response
+// environment, with an optional last build that targeted the environment.
+func DBEnvironmentToResponse(dbEnv database.Environment, dbLastBuild *database.Build) response.Environment {
+	var resLastBuildPtr *response.EnvironmentLastBuild
+	if dbLastBuild != nil {
+		resLastBuild := DBBuildToResponseEnvironmentLastBuild(*dbLastBuild)
+		resLastBuildPtr = &resLastBuild
+	}
+	return response.Environment{
+		TimeMetadata:  DBTimeMetadataToResponse(dbEnv.TimeMetadata),
+		EnvironmentID: dbEnv.EnvironmentID,
+		ProjectID:     dbEnv.ProjectID,
+		Name:          dbEnv.Name,
+		Description:   dbEnv.Description,
+		IsDiscovered:  dbEnv.IsDiscovered,
+		IsProtected:   dbEnv.IsProtected,
+		LastBuild:     resLastBuildPtr,
+	}
+}
+
+// DBBuildToResponseEnvironmentLastBuild converts a database build to a
+// response environment's last build summary.
+func DBBuildToResponseEnvironmentLastBuild(dbBuild database.Build) response.EnvironmentLastBuild {
+	return response.EnvironmentLastBuild{
+		BuildID:     dbBuild.BuildID,
+		Status:      DBBuildStatusToResponse(dbBuild.StatusID),
+		StatusID:    int(dbBuild.StatusID),
+		GitBranch:   dbBuild.GitBranch,
+		ScheduledOn: dbBuild.ScheduledOn,
+		FinishedOn:  dbBuild.CompletedOn,
+	}
+}
+
+// ReqEnvironmentToDatabase converts a request environment to a database
+// environment.
+func ReqEnvironmentToDatabase(projectID uint, reqEnv request.Environment) database.Environment {
+	return database.Environment{
+		ProjectID:   projectID,
+		Name:        reqEnv.Name,
+		Description: reqEnv.Description,
+		IsProtected: reqEnv.IsProtected,
+	}
+}
@@ -0,0 +1,44 @@
+package modelconv
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"gopkg.in/guregu/null.v4"
+)
+
+// DBGitTriggersToResponses converts a slice of database Git triggers to a
+// slice of response Git triggers.
+func DBGitTriggersToResponses(dbTriggers []database.GitTrigger) []response.GitTrigger {
+	resTriggers := make([]response.GitTrigger, len(dbTriggers))
+	for i, dbTrigger := range dbTriggers {
+		resTriggers[i] = DBGitTriggerToResponse(dbTrigger)
+	}
+	return resTriggers
+}
+
+// DBGitTriggerToResponse converts a database Git trigger to a response Git
+// trigger.
+func DBGitTriggerToResponse(dbTrigger database.GitTrigger) response.GitTrigger {
+	return response.GitTrigger{
+		TimeMetadata: DBTimeMetadataToResponse(dbTrigger.TimeMetadata),
+		GitTriggerID: dbTrigger.GitTriggerID,
+		ProjectID:    dbTrigger.ProjectID,
+		Event:        response.GitTriggerEvent(dbTrigger.Event),
+		BranchFilter: dbTrigger.BranchFilter,
+		Stage:        dbTrigger.Stage,
+		Environment:  dbTrigger.Environment,
+	}
+}
+
+// ReqGitTriggerToDatabase converts a request Git trigger to a database Git
+// trigger.
+func ReqGitTriggerToDatabase(projectID uint, reqTrigger request.GitTrigger) database.GitTrigger {
+	return database.GitTrigger{
+		ProjectID:    projectID,
+		Event:        database.GitTriggerEvent(reqTrigger.Event),
+		BranchFilter: reqTrigger.BranchFilter,
+		Stage:        reqTrigger.Stage,
+		Environment:  null.NewString(reqTrigger.Environment, reqTrigger.Environment != ""),
+	}
+}
@@ -0,0 +1,55 @@
+package modelconv
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// DBBuildLabelsToResponse converts a slice of database build labels to a
+// response labels object.
+func DBBuildLabelsToResponse(dbLabels []database.BuildLabel) response.Labels {
+	resLabels := make(response.Labels, len(dbLabels))
+	for _, dbLabel := range dbLabels {
+		resLabels[dbLabel.Key] = dbLabel.Value
+	}
+	return resLabels
+}
+
+// DBProjectLabelsToResponse converts a slice of database project labels to a
+// response labels object.
+func DBProjectLabelsToResponse(dbLabels []database.ProjectLabel) response.Labels {
+	resLabels := make(response.Labels, len(dbLabels))
+	for _, dbLabel := range dbLabels {
+		resLabels[dbLabel.Key] = dbLabel.Value
+	}
+	return resLabels
+}
+
+// ReqLabelsToDatabaseBuildLabels converts a request labels object to a slice
+// of database build labels.
+func ReqLabelsToDatabaseBuildLabels(reqLabels request.Labels, buildID uint) []database.BuildLabel {
+	dbLabels := make([]database.BuildLabel, 0, len(reqLabels))
+	for key, value := range reqLabels {
+		dbLabels = append(dbLabels, database.BuildLabel{
+			BuildID: buildID,
+			Key:     key,
+			Value:   value,
+		})
+	}
+	return dbLabels
+}
+
+// ReqLabelsToDatabaseProjectLabels converts a request labels object to a
+// slice of database project labels.
+func ReqLabelsToDatabaseProjectLabels(reqLabels request.Labels, projectID uint) []database.ProjectLabel {
+	dbLabels := make([]database.ProjectLabel, 0, len(reqLabels))
+	for key, value := range reqLabels {
+		dbLabels = append(dbLabels, database.ProjectLabel{
+			ProjectID: projectID,
+			Key:       key,
+			Value:     value,
+		})
+	}
+	return dbLabels
+}
@@ -0,0 +1,47 @@
+package modelconv
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// DBNotificationRulesToResponses converts a slice of database notification
+// rules to a slice of response notification rules.
+func DBNotificationRulesToResponses(dbRules []database.NotificationRule) []response.NotificationRule {
+	resRules := make([]response.NotificationRule, len(dbRules))
+	for i, dbRule := range dbRules {
+		resRules[i] = DBNotificationRuleToResponse(dbRule)
+	}
+	return resRules
+}
+
+// DBNotificationRuleToResponse converts a database notification rule to a
+// response notification rule.
+func DBNotificationRuleToResponse(dbRule database.NotificationRule) response.NotificationRule {
+	return response.NotificationRule{
+		TimeMetadata:       DBTimeMetadataToResponse(dbRule.TimeMetadata),
+		NotificationRuleID: dbRule.NotificationRuleID,
+		Name:               dbRule.Name,
+		ProjectID:          ptrconv.UintPtrNullInt(dbRule.ProjectID),
+		Status:             DBBuildStatusToResponse(dbRule.StatusID),
+		Threshold:          dbRule.Threshold,
+		Notifier:           response.NotifierType(dbRule.Notifier),
+		Target:             dbRule.Target,
+	}
+}
+
+// ReqNotificationRuleToDatabase converts a request notification rule to a
+// database notification rule. Invalid statuses are converted to Scheduling.
+func ReqNotificationRuleToDatabase(reqRule request.NotificationRule) database.NotificationRule {
+	dbStatus, _ := ReqBuildStatusToDatabase(reqRule.Status)
+	return database.NotificationRule{
+		Name:      reqRule.Name,
+		ProjectID: reqRule.ProjectID,
+		StatusID:  dbStatus,
+		Threshold: reqRule.Threshold,
+		Notifier:  database.NotifierType(reqRule.Notifier),
+		Target:    reqRule.Target,
+	}
+}
@@ -2,6 +2,7 @@ package modelconv
 
 import (
 	"github.com/ghodss/yaml"
+	"github.com/iver-wharf/wharf-api/v5/internal/builddef"
 	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
@@ -48,6 +49,12 @@ func DBProjectToResponse(dbProject database.Project) response.Project {
 		GitURL:                typ.Coal(dbProject.Overrides.GitURL, dbProject.GitURL),
 		RemoteProjectID:       dbProject.RemoteProjectID,
 		ParsedBuildDefinition: parsedBuildDef,
+		Version:               dbProject.Version,
+		Labels:                DBProjectLabelsToResponse(dbProject.Labels),
+		InstanceID:            dbProject.InstanceID,
+		IsArchived:            dbProject.IsArchived,
+		SchedulingTimeout:     dbProject.SchedulingTimeout,
+		RunningTimeout:        dbProject.RunningTimeout,
 	}
 }
 
@@ -66,6 +73,23 @@ func ReqProjectToDatabase(reqProject request.Project) database.Project {
 	}
 }
 
+// DBProjectToUpdateRequest converts a database project to a request project
+// update, such as when applying a partial update onto its current values.
+func DBProjectToUpdateRequest(dbProject database.Project) request.ProjectUpdate {
+	return request.ProjectUpdate{
+		Name:              dbProject.Name,
+		GroupName:         dbProject.GroupName,
+		Description:       dbProject.Description,
+		AvatarURL:         dbProject.AvatarURL,
+		TokenID:           ptrconv.UintPtr(dbProject.TokenID),
+		ProviderID:        ptrconv.UintPtr(dbProject.ProviderID),
+		BuildDefinition:   dbProject.BuildDefinition,
+		GitURL:            dbProject.GitURL,
+		SchedulingTimeout: ptrconv.NullIntUintPtr(dbProject.SchedulingTimeout),
+		RunningTimeout:    ptrconv.NullIntUintPtr(dbProject.RunningTimeout),
+	}
+}
+
 // ParseBuildDefinition parses a YAML-formatted build definition string to be
 // used in a project response.
 func ParseBuildDefinition(buildDef string) (any, error) {
@@ -88,5 +112,130 @@ func DBProjectOverridesToResponse(dbProjectOverrides database.ProjectOverrides)
 		Description: dbProjectOverrides.Description,
 		AvatarURL:   dbProjectOverrides.AvatarURL,
 		GitURL:      dbProjectOverrides.GitURL,
+		Version:     dbProjectOverrides.Version,
+	}
+}
+
+// DBProjectInputDefaultsToResponse converts a slice of database project input
+// defaults to a response project input defaults object.
+func DBProjectInputDefaultsToResponse(dbInputDefaults []database.ProjectInputDefault) response.ProjectInputDefaults {
+	resInputDefaults := response.ProjectInputDefaults{
+		Inputs:       map[string]string{},
+		Environments: map[string]map[string]string{},
+	}
+	for _, dbInputDefault := range dbInputDefaults {
+		if dbInputDefault.Environment == "" {
+			resInputDefaults.Inputs[dbInputDefault.Name] = dbInputDefault.Value
+			continue
+		}
+		inputs, ok := resInputDefaults.Environments[dbInputDefault.Environment]
+		if !ok {
+			inputs = map[string]string{}
+			resInputDefaults.Environments[dbInputDefault.Environment] = inputs
+		}
+		inputs[dbInputDefault.Name] = dbInputDefault.Value
+	}
+	return resInputDefaults
+}
+
+// ReqProjectInputDefaultsToDatabase converts a request project input defaults
+// object to a slice of database project input defaults.
+func ReqProjectInputDefaultsToDatabase(reqInputDefaults request.ProjectInputDefaults, projectID uint) []database.ProjectInputDefault {
+	var dbInputDefaults []database.ProjectInputDefault
+	for name, value := range reqInputDefaults.Inputs {
+		dbInputDefaults = append(dbInputDefaults, database.ProjectInputDefault{
+			ProjectID: projectID,
+			Name:      name,
+			Value:     value,
+		})
+	}
+	for env, inputs := range reqInputDefaults.Environments {
+		for name, value := range inputs {
+			dbInputDefaults = append(dbInputDefaults, database.ProjectInputDefault{
+				ProjectID:   projectID,
+				Environment: env,
+				Name:        name,
+				Value:       value,
+			})
+		}
+	}
+	return dbInputDefaults
+}
+
+// DBProjectEnginesToResponse converts a slice of database project engines to
+// a response project engines object.
+func DBProjectEnginesToResponse(dbEngines []database.ProjectEngine) response.ProjectEngines {
+	resEngines := response.ProjectEngines{
+		EngineIDs: make([]string, len(dbEngines)),
+	}
+	for i, dbEngine := range dbEngines {
+		resEngines.EngineIDs[i] = dbEngine.EngineID
+	}
+	return resEngines
+}
+
+// BuildDefinitionToResponse converts a parsed build definition to a response
+// build definition.
+func BuildDefinitionToResponse(projectID uint, def builddef.Definition) response.BuildDefinition {
+	resInputs := make([]response.BuildDefinitionInput, len(def.Inputs))
+	for i, input := range def.Inputs {
+		resInputs[i] = response.BuildDefinitionInput{
+			Name:    input.Name,
+			Type:    input.Type,
+			Default: input.Default,
+			Values:  input.Values,
+			Line:    input.Line,
+		}
+	}
+	resEnvs := make([]response.BuildDefinitionEnvironment, len(def.Environments))
+	for i, env := range def.Environments {
+		resEnvs[i] = response.BuildDefinitionEnvironment{
+			Name: env.Name,
+			Vars: env.Vars,
+			Line: env.Line,
+		}
+	}
+	resStages := make([]response.BuildDefinitionStage, len(def.Stages))
+	for i, stage := range def.Stages {
+		resSteps := make([]response.BuildDefinitionStep, len(stage.Steps))
+		for j, step := range stage.Steps {
+			resSteps[j] = response.BuildDefinitionStep{
+				Name:   step.Name,
+				Type:   step.Type,
+				Fields: step.Fields,
+				Line:   step.Line,
+			}
+		}
+		resStages[i] = response.BuildDefinitionStage{
+			Name:         stage.Name,
+			Environments: stage.Environments,
+			Steps:        resSteps,
+			Line:         stage.Line,
+		}
+	}
+	return response.BuildDefinition{
+		ProjectID:    projectID,
+		Inputs:       resInputs,
+		Environments: resEnvs,
+		Stages:       resStages,
+	}
+}
+
+// BuildDefinitionIssuesToValidationResponse converts a slice of build
+// definition validation issues to a response build definition validation.
+func BuildDefinitionIssuesToValidationResponse(issues []builddef.Issue) response.BuildDefinitionValidation {
+	resIssues := make([]response.BuildDefinitionIssue, len(issues))
+	for i, issue := range issues {
+		resIssues[i] = response.BuildDefinitionIssue{
+			Severity: response.BuildDefinitionIssueSeverity(issue.Severity),
+			Path:     issue.Path,
+			Message:  issue.Message,
+			Line:     issue.Line,
+			Column:   issue.Column,
+		}
+	}
+	return response.BuildDefinitionValidation{
+		Valid:  !builddef.HasErrors(issues),
+		Issues: resIssues,
 	}
 }
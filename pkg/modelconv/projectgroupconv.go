@@ -0,0 +1,29 @@
+package modelconv
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// DBProjectGroupsToResponses converts a slice of database project groups to a
+// slice of response project groups. The project and build counts are left as
+// zero.
+func DBProjectGroupsToResponses(dbGroups []database.ProjectGroup) []response.ProjectGroup {
+	resGroups := make([]response.ProjectGroup, len(dbGroups))
+	for i, dbGroup := range dbGroups {
+		resGroups[i] = DBProjectGroupToResponse(dbGroup)
+	}
+	return resGroups
+}
+
+// DBProjectGroupToResponse converts a database project group to a response
+// project group. The project and build counts are left as zero.
+func DBProjectGroupToResponse(dbGroup database.ProjectGroup) response.ProjectGroup {
+	return response.ProjectGroup{
+		TimeMetadata: DBTimeMetadataToResponse(dbGroup.TimeMetadata),
+		GroupID:      dbGroup.ProjectGroupID,
+		ParentID:     dbGroup.ParentID,
+		Name:         dbGroup.Name,
+		Path:         dbGroup.Path,
+	}
+}
@@ -0,0 +1,18 @@
+package modelconv
+
+import (
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+)
+
+// DBProjectWebhookToResponse converts a database project webhook to a response
+// project webhook, without its token and secret.
+func DBProjectWebhookToResponse(dbWebhook database.ProjectWebhook) response.ProjectWebhook {
+	return response.ProjectWebhook{
+		TimeMetadata:     DBTimeMetadataToResponse(dbWebhook.TimeMetadata),
+		ProjectWebhookID: dbWebhook.ProjectWebhookID,
+		ProjectID:        dbWebhook.ProjectID,
+		IsEnabled:        dbWebhook.IsEnabled,
+		RequireSignature: dbWebhook.RequireSignature,
+	}
+}
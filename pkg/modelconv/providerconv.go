@@ -2,6 +2,7 @@ package modelconv
 
 import (
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 )
 
@@ -22,6 +23,35 @@ func DBProviderToResponse(dbProvider database.Provider) response.Provider {
 		ProviderID:   dbProvider.ProviderID,
 		Name:         response.ProviderName(dbProvider.Name),
 		URL:          dbProvider.URL,
+		UploadURL:    dbProvider.UploadURL,
 		TokenID:      dbProvider.TokenID,
+		Version:      dbProvider.Version,
+		Metadata:     dbProvider.Metadata,
+	}
+}
+
+// DBProviderToUpdateRequest converts a database provider to a request provider
+// update, such as when applying a partial update onto its current values.
+func DBProviderToUpdateRequest(dbProvider database.Provider) request.ProviderUpdate {
+	return request.ProviderUpdate{
+		Name:      request.ProviderName(dbProvider.Name),
+		URL:       dbProvider.URL,
+		UploadURL: dbProvider.UploadURL,
+		TokenID:   dbProvider.TokenID,
+		Metadata:  dbProvider.Metadata,
+	}
+}
+
+// DBProviderSyncToResponse converts a database provider sync to a response
+// provider sync.
+func DBProviderSyncToResponse(dbSync database.ProviderSync) response.ProviderSync {
+	return response.ProviderSync{
+		TimeMetadata:   DBTimeMetadataToResponse(dbSync.TimeMetadata),
+		ProviderSyncID: dbSync.ProviderSyncID,
+		ProviderID:     dbSync.ProviderID,
+		Status:         response.ProviderSyncStatus(dbSync.Status),
+		StartedOn:      dbSync.StartedOn,
+		CompletedOn:    dbSync.CompletedOn,
+		Error:          dbSync.Error,
 	}
 }
@@ -1,6 +1,7 @@
 package modelconv
 
 import (
+	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 )
@@ -22,7 +23,7 @@ func DBTestResultSummaryToResponse(dbSummary database.TestResultSummary) respons
 		TimeMetadata:        DBTimeMetadataToResponse(dbSummary.TimeMetadata),
 		TestResultSummaryID: dbSummary.TestResultSummaryID,
 		FileName:            dbSummary.FileName,
-		ArtifactID:          dbSummary.ArtifactID,
+		ArtifactID:          ptrconv.UintPtrNullInt(dbSummary.ArtifactID),
 		BuildID:             dbSummary.BuildID,
 		Total:               dbSummary.Total,
 		Failed:              dbSummary.Failed,
@@ -45,15 +46,19 @@ func DBTestResultDetailsToResponses(dbDetails []database.TestResultDetail) []res
 // response test result detail.
 func DBTestResultDetailToResponse(dbDetail database.TestResultDetail) response.TestResultDetail {
 	return response.TestResultDetail{
-		TimeMetadata:       DBTimeMetadataToResponse(dbDetail.TimeMetadata),
-		TestResultDetailID: dbDetail.TestResultDetailID,
-		ArtifactID:         dbDetail.ArtifactID,
-		BuildID:            dbDetail.BuildID,
-		Name:               dbDetail.Name,
-		Message:            dbDetail.Message,
-		StartedOn:          dbDetail.StartedOn,
-		CompletedOn:        dbDetail.CompletedOn,
-		Status:             DBTestResultStatusToResponse(dbDetail.Status),
+		TimeMetadata:        DBTimeMetadataToResponse(dbDetail.TimeMetadata),
+		TestResultDetailID:  dbDetail.TestResultDetailID,
+		ArtifactID:          ptrconv.UintPtrNullInt(dbDetail.ArtifactID),
+		TestResultSummaryID: dbDetail.TestResultSummaryID,
+		BuildID:             dbDetail.BuildID,
+		Name:                dbDetail.Name,
+		Message:             dbDetail.Message,
+		StartedOn:           dbDetail.StartedOn,
+		CompletedOn:         dbDetail.CompletedOn,
+		Status:              DBTestResultStatusToResponse(dbDetail.Status),
+		ClassName:           dbDetail.ClassName,
+		FilePath:            dbDetail.FilePath,
+		LineNumber:          dbDetail.LineNumber,
 	}
 }
 
@@ -2,6 +2,7 @@ package modelconv
 
 import (
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 )
 
@@ -22,5 +23,15 @@ func DBTokenToResponse(dbToken database.Token) response.Token {
 		TokenID:      dbToken.TokenID,
 		Token:        dbToken.Value,
 		UserName:     dbToken.UserName,
+		Version:      dbToken.Version,
+	}
+}
+
+// DBTokenToUpdateRequest converts a database token to a request token update,
+// such as when applying a partial update onto its current values.
+func DBTokenToUpdateRequest(dbToken database.Token) request.TokenUpdate {
+	return request.TokenUpdate{
+		Token:    dbToken.Value,
+		UserName: dbToken.UserName,
 	}
 }
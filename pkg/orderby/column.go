@@ -3,7 +3,7 @@ package orderby
 import (
 	"errors"
 	"fmt"
-	"io"
+	"strings"
 
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"gorm.io/gorm/clause"
@@ -24,12 +24,57 @@ var (
 	// ErrNilParseMap is returned when parsing but the map that was passed was
 	// nil.
 	ErrNilParseMap = errors.New("field->column names map is nil")
+	// ErrMissingField is returned when parsing an orderby column with a
+	// direction prefix but no field name.
+	// 	"-myfield" // OK
+	// 	"-"        // err!
+	ErrMissingField = errors.New("missing ordering field after '+' or '-' prefix")
+	// ErrDirectionPrefixAndSuffix is returned when parsing an orderby column
+	// that has both a direction prefix and a direction suffix.
+	// 	"-myfield"      // OK
+	// 	"-myfield desc" // err!
+	ErrDirectionPrefixAndSuffix = errors.New("ordering direction given both as '+' or '-' prefix and as 'asc' or 'desc' suffix")
 )
 
+// ParseError is returned when parsing an orderby column fails, and tells which
+// column and which part of it was invalid.
+type ParseError struct {
+	// Index is the zero-based position of the column among all parsed
+	// columns, when parsed via ParseSlice.
+	Index int
+	// Query is the string of the whole column ordering, such as
+	// "buildId desc".
+	Query string
+	// Token is the invalid part of the query, such as "desc", or empty if
+	// the query as a whole was invalid.
+	Token string
+	// Err is the underlying error, such as ErrInvalidField.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("invalid ordering %q: %v", e.Query, e.Err)
+	}
+	return fmt.Sprintf("invalid token %q in ordering %q: %v", e.Token, e.Query, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // Column specifies a column or field to be sorted and its sorting direction.
 type Column struct {
 	Name      database.SafeSQLName
 	Direction Direction
+	// IsExpression is true if the name is a SQL expression instead of a
+	// column name, such as a subquery that looks up a column of a related
+	// table or computes an aggregate. Expressions are used as-is without
+	// quoting, and just as any other database.SafeSQLName they must never be
+	// constructed from user input.
+	IsExpression bool
 }
 
 // String converts an ordering to a string representation. The result is meant
@@ -42,6 +87,7 @@ func (o Column) clauseOrderByColumn() clause.OrderByColumn {
 	return clause.OrderByColumn{
 		Column: clause.Column{
 			Name: string(o.Name),
+			Raw:  o.IsExpression,
 		},
 		Desc: o.Direction == Desc,
 	}
@@ -59,48 +105,70 @@ func (o Column) Clause() clause.Expression {
 
 // Parse interprets an ordering/sorting definition and optionally translates the
 // inputted field name using a map.
+//
+// The definition is either the field name followed by the direction, such as
+// "buildId desc", or the field name prefixed with '+' for ascending or '-' for
+// descending, such as "-buildId". The direction is case-insensitive, while the
+// field name is not.
+//
+// Any error is of type *ParseError.
 func Parse(query string, fieldToColumnNames map[string]database.SafeSQLName) (Column, error) {
+	return parse(query, fieldToColumnNames, nil)
+}
+
+func parse(query string, fieldToColumnNames, fieldToExpressions map[string]database.SafeSQLName) (Column, error) {
+	if fieldToColumnNames == nil {
+		return Column{}, &ParseError{Query: query, Err: ErrNilParseMap}
+	}
 	field, direction, err := scanQueryForOrderBy(query)
 	if err != nil {
-		return Column{}, fmt.Errorf("failed scanning orderby string: %w", err)
+		return Column{}, err
+	}
+	if column, ok := fieldToColumnNames[field]; ok {
+		return Column{Name: column, Direction: direction}, nil
 	}
-	return parseFromFieldAndDirection(field, direction, fieldToColumnNames)
+	if expression, ok := fieldToExpressions[field]; ok {
+		return Column{Name: expression, Direction: direction, IsExpression: true}, nil
+	}
+	return Column{}, &ParseError{Query: query, Token: field, Err: ErrInvalidField}
 }
 
-func scanQueryForOrderBy(query string) (field, direction string, err error) {
-	var valuesRead int
-	valuesRead, err = fmt.Sscanf(query, "%s %s", &field, &direction)
-	if err == io.EOF {
-		if valuesRead == 0 {
-			err = ErrEmptyString
-		} else if valuesRead == 1 {
-			err = ErrMissingDirection
+func scanQueryForOrderBy(query string) (field string, direction Direction, err error) {
+	trimmed := strings.TrimSpace(query)
+	if trimmed == "" {
+		return "", 0, &ParseError{Query: query, Err: ErrEmptyString}
+	}
+	if prefix := trimmed[0]; prefix == '+' || prefix == '-' {
+		direction = Asc
+		if prefix == '-' {
+			direction = Desc
+		}
+		tokens := splitTokens(trimmed[1:])
+		switch len(tokens) {
+		case 0:
+			return "", 0, &ParseError{Query: query, Token: string(prefix), Err: ErrMissingField}
+		case 1:
+			return tokens[0], direction, nil
+		default:
+			return "", 0, &ParseError{Query: query, Token: tokens[1], Err: ErrDirectionPrefixAndSuffix}
 		}
 	}
-	return
-}
-
-func parseFromFieldAndDirection(field, directionStr string, fieldToColumnNames map[string]database.SafeSQLName) (Column, error) {
-	column, err := mapOrderByField(field, fieldToColumnNames)
-	if err != nil {
-		return Column{}, fmt.Errorf("failed mapping field name to column name: %w", err)
+	// Any excess tokens after the direction are ignored.
+	tokens := splitTokens(trimmed)
+	if len(tokens) == 1 {
+		return "", 0, &ParseError{Query: query, Token: tokens[0], Err: ErrMissingDirection}
 	}
-
-	direction, err := ParseDirection(directionStr)
+	direction, err = ParseDirection(tokens[1])
 	if err != nil {
-		return Column{}, fmt.Errorf("failed parsing ordering direction: %w", err)
+		return "", 0, &ParseError{Query: query, Token: tokens[1], Err: ErrInvalidDirection}
 	}
-
-	return Column{Name: column, Direction: direction}, nil
+	return tokens[0], direction, nil
 }
 
-func mapOrderByField(field string, fieldToColumnNames map[string]database.SafeSQLName) (database.SafeSQLName, error) {
-	if fieldToColumnNames == nil {
-		return "", ErrNilParseMap
-	}
-	column, ok := fieldToColumnNames[field]
-	if !ok {
-		return "", fmt.Errorf("%q: %w", field, ErrInvalidField)
-	}
-	return column, nil
+// splitTokens splits on spaces and tabs, but not on newlines, so that a
+// newline in a query parameter is not mistaken for a separator.
+func splitTokens(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t'
+	})
 }
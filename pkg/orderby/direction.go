@@ -3,6 +3,7 @@ package orderby
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -25,9 +26,9 @@ const (
 
 // ParseDirection interprets a string value as the equivalent direction enum
 // value, or an error if parsing failed.
-// Valid input values are 'asc' and 'desc'.
+// Valid input values are 'asc' and 'desc', in any casing.
 func ParseDirection(direction string) (Direction, error) {
-	switch direction {
+	switch strings.ToLower(direction) {
 	case "asc":
 		return Asc, nil
 	case "desc":
@@ -16,8 +16,8 @@ type Project struct {
 }
 
 func ExampleColumn_String() {
-	fmt.Printf("1: %q\n", orderby.Column{"build_id", orderby.Asc})
-	fmt.Printf("2: %q\n", orderby.Column{"build_id", orderby.Desc})
+	fmt.Printf("1: %q\n", orderby.Column{Name: "build_id", Direction: orderby.Asc})
+	fmt.Printf("2: %q\n", orderby.Column{Name: "build_id", Direction: orderby.Desc})
 	// Output:
 	// 1: "build_id asc"
 	// 2: "build_id desc"
@@ -27,13 +27,28 @@ func ExampleColumn_Clause() {
 	db := dryRunDB()
 	var projects []Project
 
-	orderBy := orderby.Column{"group_name", orderby.Asc}
+	orderBy := orderby.Column{Name: "group_name", Direction: orderby.Asc}
 	printStmt(db.Model(&Project{}).Clauses(orderBy.Clause()).Find(&projects))
 
 	// Output:
 	// SELECT * FROM "projects" ORDER BY "group_name"
 }
 
+func ExampleColumn_Clause_expression() {
+	db := dryRunDB()
+	var projects []Project
+
+	orderBy := orderby.Column{
+		Name:         "(SELECT COUNT(*) FROM builds WHERE builds.project_id = projects.project_id)",
+		Direction:    orderby.Desc,
+		IsExpression: true,
+	}
+	printStmt(db.Model(&Project{}).Clauses(orderBy.Clause()).Find(&projects))
+
+	// Output:
+	// SELECT * FROM "projects" ORDER BY (SELECT COUNT(*) FROM builds WHERE builds.project_id = projects.project_id) DESC
+}
+
 func ExampleParseDirection() {
 	for _, str := range []string{"asc", "desc", "foo"} {
 		if d, err := orderby.ParseDirection(str); err != nil {
@@ -54,7 +69,8 @@ func ExampleParse() {
 	}
 	fields := []string{
 		"buildId asc",
-		"  buildId   desc  ",
+		"  buildId   DESC  ",
+		"-buildId",
 		"foobar asc",
 		"buildId foo",
 	}
@@ -68,15 +84,16 @@ func ExampleParse() {
 	// Output:
 	// Sort by "build_id asc"
 	// Sort by "build_id desc"
-	// Invalid sort order: failed mapping field name to column name: "foobar": invalid or unsupported ordering field
-	// Invalid sort order: failed parsing ordering direction: "foo": invalid direction, only 'asc' or 'desc' supported
+	// Sort by "build_id desc"
+	// Invalid sort order: invalid token "foobar" in ordering "foobar asc": invalid or unsupported ordering field
+	// Invalid sort order: invalid token "foo" in ordering "buildId foo": invalid direction, only 'asc' or 'desc' supported
 }
 
 func ExampleSlice_Clause() {
 	db := dryRunDB()
 	var projects []Project
 
-	orderBySlice := orderby.Slice{{"group_name", orderby.Asc}, {"name", orderby.Desc}}
+	orderBySlice := orderby.Slice{{Name: "group_name", Direction: orderby.Asc}, {Name: "name", Direction: orderby.Desc}}
 	multiOrderByQuery := db.Model(&Project{}).Clauses(orderBySlice.Clause())
 	printStmt(multiOrderByQuery.Find(&projects))
 
@@ -88,7 +105,7 @@ func ExampleSlice_ClauseIfNone() {
 	db := dryRunDB()
 	var projects []Project
 
-	fallbackOrderBy := orderby.Column{"project_id", orderby.Asc}
+	fallbackOrderBy := orderby.Column{Name: "project_id", Direction: orderby.Asc}
 	orderBySlice := orderby.Slice{} // intentionally empty
 	fallbackQuery := db.Model(&Project{}).Clauses(orderBySlice.ClauseIfNone(fallbackOrderBy))
 	printStmt(fallbackQuery.Find(&projects))
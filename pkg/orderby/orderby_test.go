@@ -42,8 +42,12 @@ func TestParse_ErrorPath(t *testing.T) {
 			input: "buildId ",
 		},
 		{
-			name:  "uppercase direction",
-			input: "buildId ASC",
+			name:  "only prefix",
+			input: "-",
+		},
+		{
+			name:  "prefix and direction",
+			input: "-buildId desc",
 		},
 		{
 			name:  "invalid direction",
@@ -80,25 +84,49 @@ func TestParse_HappyPath(t *testing.T) {
 			name:     "valid mapped asc",
 			input:    "buildId asc",
 			namesMap: fieldToColumnNames,
-			want:     Column{"build_id", Asc},
+			want:     Column{Name: "build_id", Direction: Asc},
 		},
 		{
 			name:     "valid mapped desc",
 			input:    "buildId desc",
 			namesMap: fieldToColumnNames,
-			want:     Column{"build_id", Desc},
+			want:     Column{Name: "build_id", Direction: Desc},
 		},
 		{
 			name:     "excess whitespace",
 			input:    "   \t\t  buildId \t  \tdesc  \t  ",
 			namesMap: fieldToColumnNames,
-			want:     Column{"build_id", Desc},
+			want:     Column{Name: "build_id", Direction: Desc},
+		},
+		{
+			name:     "uppercase direction",
+			input:    "buildId ASC",
+			namesMap: fieldToColumnNames,
+			want:     Column{Name: "build_id", Direction: Asc},
+		},
+		{
+			name:     "mixed case direction",
+			input:    "buildId Desc",
+			namesMap: fieldToColumnNames,
+			want:     Column{Name: "build_id", Direction: Desc},
+		},
+		{
+			name:     "plus prefix",
+			input:    "+buildId",
+			namesMap: fieldToColumnNames,
+			want:     Column{Name: "build_id", Direction: Asc},
+		},
+		{
+			name:     "minus prefix",
+			input:    " -buildId\t",
+			namesMap: fieldToColumnNames,
+			want:     Column{Name: "build_id", Direction: Desc},
 		},
 		{
 			name:     "excess values",
 			input:    "buildId desc these values will be ignored",
 			namesMap: fieldToColumnNames,
-			want:     Column{"build_id", Desc},
+			want:     Column{Name: "build_id", Direction: Desc},
 		},
 	}
 	for _, tc := range testCases {
@@ -109,3 +137,92 @@ func TestParse_HappyPath(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_errorTokens(t *testing.T) {
+	fieldToColumnNames := map[string]database.SafeSQLName{
+		"buildId": "build_id",
+	}
+	testCases := []struct {
+		input     string
+		wantToken string
+		wantErr   error
+	}{
+		{input: "", wantToken: "", wantErr: ErrEmptyString},
+		{input: "buildId", wantToken: "buildId", wantErr: ErrMissingDirection},
+		{input: "buildId DESCC", wantToken: "DESCC", wantErr: ErrInvalidDirection},
+		{input: "tractorBeam asc", wantToken: "tractorBeam", wantErr: ErrInvalidField},
+		{input: "-", wantToken: "-", wantErr: ErrMissingField},
+		{input: "-buildId asc", wantToken: "asc", wantErr: ErrDirectionPrefixAndSuffix},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := Parse(tc.input, fieldToColumnNames)
+			var parseErr *ParseError
+			require.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, tc.input, parseErr.Query)
+			assert.Equal(t, tc.wantToken, parseErr.Token)
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestParseSlice_commaSeparated(t *testing.T) {
+	fieldToColumnNames := map[string]database.SafeSQLName{
+		"buildId": "build_id",
+		"name":    "name",
+	}
+	got, err := ParseSlice([]string{"name ASC, -buildId", "+buildId"}, fieldToColumnNames)
+	require.NoError(t, err)
+	assert.Equal(t, Slice{{Name: "name", Direction: Asc}, {Name: "build_id", Direction: Desc}, {Name: "build_id", Direction: Asc}}, got)
+}
+
+func TestParseSliceWithExpressions(t *testing.T) {
+	fieldToColumnNames := map[string]database.SafeSQLName{
+		"buildId": "build_id",
+		"weird":   "(not_an_expression",
+	}
+	fieldToExpressions := map[string]database.SafeSQLName{
+		"testsTotal": "(SELECT SUM(total) FROM test_result_summary)",
+	}
+	got, err := ParseSliceWithExpressions([]string{"-testsTotal,weird asc,buildId asc"}, fieldToColumnNames, fieldToExpressions)
+	require.NoError(t, err)
+	assert.Equal(t, Slice{
+		{Name: "(SELECT SUM(total) FROM test_result_summary)", Direction: Desc, IsExpression: true},
+		{Name: "(not_an_expression", Direction: Asc},
+		{Name: "build_id", Direction: Asc},
+	}, got)
+
+	_, err = ParseSlice([]string{"-testsTotal"}, fieldToColumnNames)
+	assert.ErrorIs(t, err, ErrInvalidField, "must not accept expressions unless given")
+}
+
+func TestParseSlice_errorIndex(t *testing.T) {
+	fieldToColumnNames := map[string]database.SafeSQLName{
+		"buildId": "build_id",
+	}
+	_, err := ParseSlice([]string{"buildId asc", "-buildId,buildId foo"}, fieldToColumnNames)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 2, parseErr.Index)
+	assert.Equal(t, "foo", parseErr.Token)
+
+	_, err = ParseSlice([]string{"buildId asc,"}, fieldToColumnNames)
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 1, parseErr.Index)
+	assert.ErrorIs(t, err, ErrEmptyString)
+}
+
+func TestParseSlice_maxColumns(t *testing.T) {
+	fieldToColumnNames := map[string]database.SafeSQLName{
+		"buildId": "build_id",
+	}
+	queries := make([]string, MaxColumns)
+	for i := range queries {
+		queries[i] = "-buildId"
+	}
+	_, err := ParseSlice(queries, fieldToColumnNames)
+	require.NoError(t, err)
+
+	_, err = ParseSlice(append(queries, "-buildId"), fieldToColumnNames)
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+}
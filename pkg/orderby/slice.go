@@ -1,12 +1,21 @@
 package orderby
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"gorm.io/gorm/clause"
 )
 
+// MaxColumns is the maximum number of column orderings accepted by ParseSlice.
+const MaxColumns = 10
+
+// ErrTooManyColumns is returned when parsing more than MaxColumns column
+// orderings.
+var ErrTooManyColumns = errors.New("too many orderings")
+
 // Slice is a Go slice of column orderings, meant to represent multiple
 // orderings to apply in order.
 type Slice []Column
@@ -49,11 +58,40 @@ func (slice Slice) ClauseIfNone(ifNone Column) clause.Expression {
 
 // ParseSlice returns a new slice where each element has been interpreted by the
 // Parse function, or the error of the first failed parsing.
+//
+// Each query may hold multiple comma-separated orderings, such as
+// "projectId asc,-buildId". No more than MaxColumns orderings are accepted in
+// total.
+//
+// Any error is of type *ParseError, where the index tells the position of the
+// ordering among all comma-separated orderings.
 func ParseSlice(queries []string, fieldToColumnNames map[string]database.SafeSQLName) (Slice, error) {
-	sqlOrderings := make([]Column, len(queries))
-	for i, qo := range queries {
-		orderBy, err := Parse(qo, fieldToColumnNames)
+	return ParseSliceWithExpressions(queries, fieldToColumnNames, nil)
+}
+
+// ParseSliceWithExpressions works the same as ParseSlice, but also accepts the
+// fields of the second map, which are translated into SQL expressions instead
+// of column names. The resulting columns have Column.IsExpression set.
+func ParseSliceWithExpressions(queries []string, fieldToColumnNames, fieldToExpressions map[string]database.SafeSQLName) (Slice, error) {
+	var parts []string
+	for _, query := range queries {
+		parts = append(parts, strings.Split(query, ",")...)
+	}
+	if len(parts) > MaxColumns {
+		return nil, &ParseError{
+			Index: MaxColumns,
+			Query: parts[MaxColumns],
+			Err:   fmt.Errorf("%w: %d > %d", ErrTooManyColumns, len(parts), MaxColumns),
+		}
+	}
+	sqlOrderings := make([]Column, len(parts))
+	for i, part := range parts {
+		orderBy, err := parse(part, fieldToColumnNames, fieldToExpressions)
 		if err != nil {
+			var parseErr *ParseError
+			if errors.As(err, &parseErr) {
+				parseErr.Index = i
+			}
 			return nil, err
 		}
 		sqlOrderings[i] = orderBy
@@ -0,0 +1,493 @@
+// Package problems is the catalog of problem types that the wharf-api responds
+// with, as defined in the IETF RFC-7807.
+//
+// Each problem type has a stable type URI, such as
+// "/prob/api/project/run/trigger", and a machine-readable code, such as
+// "project-run-trigger". Clients should branch on the type or the code of a
+// problem response, and never on its title or detail strings, as those are
+// meant for humans and may change between versions.
+package problems
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+)
+
+// TypePrefix is the prefix that wharf-core adds to all relative problem type
+// URIs when writing problem responses.
+const TypePrefix = "https://iver-wharf.github.io/#"
+
+// Code is a machine-readable identifier of a problem type. It is derived from
+// the problem type's URI by trimming the "/prob/api/" prefix and replacing any
+// remaining slashes with dashes.
+type Code string
+
+// Definition describes a single problem type.
+type Definition struct {
+	Code        Code
+	Type        string
+	Title       string
+	Status      int
+	Description string
+}
+
+// New creates a new problem response of this problem type, with the given
+// human-readable detail message.
+func (d Definition) New(detail string) problem.Response {
+	return d.With(problem.Response{Detail: detail})
+}
+
+// Newf creates a new problem response of this problem type, with a detail
+// message formatted using fmt.Sprintf.
+func (d Definition) Newf(format string, args ...any) problem.Response {
+	return d.New(fmt.Sprintf(format, args...))
+}
+
+// With returns the problem response with its Type, Title, and Status fields
+// set from this problem type. Used when also setting the Instance or Errors
+// fields of the problem response.
+func (d Definition) With(prob problem.Response) problem.Response {
+	prob.Type = d.Type
+	prob.Title = d.Title
+	prob.Status = d.Status
+	return prob
+}
+
+var definitions = map[Code]Definition{}
+
+func register(d Definition) Definition {
+	if _, ok := definitions[d.Code]; ok {
+		panic(fmt.Sprintf("problems: duplicate problem code %q", d.Code))
+	}
+	definitions[d.Code] = d
+	return d
+}
+
+// All returns all problem type definitions, sorted by their code.
+func All() []Definition {
+	all := make([]Definition, 0, len(definitions))
+	for _, d := range definitions {
+		all = append(all, d)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Code < all[j].Code
+	})
+	return all
+}
+
+// Lookup returns the problem type definition with the given code, or false if
+// none was found.
+func Lookup(code Code) (Definition, bool) {
+	d, ok := definitions[code]
+	return d, ok
+}
+
+// LookupType returns the problem type definition with the given type URI, or
+// false if none was found. The type URI may be prefixed with TypePrefix, as is
+// the case in problem responses.
+func LookupType(typ string) (Definition, bool) {
+	typ = strings.TrimPrefix(typ, TypePrefix)
+	for _, d := range definitions {
+		if d.Type == typ {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Problem types written by the wharf-core ginutil package.
+var (
+	InternalServerError = register(Definition{
+		Code:        "internal-server-error",
+		Type:        "/prob/api/internal-server-error",
+		Title:       "Internal server error.",
+		Status:      http.StatusInternalServerError,
+		Description: "An unexpected error occurred, such as a recovered panic.",
+	})
+	MissingParamString = register(Definition{
+		Code:        "missing-param-string",
+		Type:        "/prob/api/missing-param-string",
+		Title:       "Missing string value.",
+		Status:      http.StatusBadRequest,
+		Description: "A required string parameter was missing or empty.",
+	})
+	InvalidParamUint = register(Definition{
+		Code:        "invalid-param-uint",
+		Type:        "/prob/api/invalid-param-uint",
+		Title:       "Invalid positive integer value.",
+		Status:      http.StatusBadRequest,
+		Description: "A parameter could not be parsed as a positive integer, such as an ID in the URL path.",
+	})
+	InvalidParamInt = register(Definition{
+		Code:        "invalid-param-int",
+		Type:        "/prob/api/invalid-param-int",
+		Title:       "Invalid integer value.",
+		Status:      http.StatusBadRequest,
+		Description: "A parameter could not be parsed as an integer.",
+	})
+	InvalidParam = register(Definition{
+		Code:        "invalid-param",
+		Type:        "/prob/api/invalid-param",
+		Title:       "Invalid API parameter.",
+		Status:      http.StatusBadRequest,
+		Description: "A query, path, or request body parameter was invalid, such as a request body that failed to bind.",
+	})
+	UnexpectedBodyReadError = register(Definition{
+		Code:        "unexpected-body-read-error",
+		Type:        "/prob/api/unexpected-body-read-error",
+		Title:       "Error reading request body.",
+		Status:      http.StatusBadRequest,
+		Description: "The request body could not be read.",
+	})
+	UnexpectedMultipartReadError = register(Definition{
+		Code:        "unexpected-multipart-read-error",
+		Type:        "/prob/api/unexpected-multipart-read-error",
+		Title:       "Error reading multipart data.",
+		Status:      http.StatusBadRequest,
+		Description: "The multipart form data of the request could not be read, such as when uploading artifacts.",
+	})
+	UnexpectedDBReadError = register(Definition{
+		Code:        "unexpected-db-read-error",
+		Type:        "/prob/api/unexpected-db-read-error",
+		Title:       "Error reading from database.",
+		Status:      http.StatusBadGateway,
+		Description: "Reading from or writing to the database failed. Also used for database write errors, then with the title \"Error writing to database.\".",
+	})
+	RecordNotFound = register(Definition{
+		Code:        "record-not-found",
+		Type:        "/prob/api/record-not-found",
+		Title:       "Record not found.",
+		Status:      http.StatusBadGateway,
+		Description: "The requested record, such as a project or build, was not found.",
+	})
+	Unauthorized = register(Definition{
+		Code:        "unauthorized",
+		Type:        "/prob/api/unauthorized",
+		Title:       "Unauthorized.",
+		Status:      http.StatusUnauthorized,
+		Description: "The request was missing a valid access token or credentials.",
+	})
+)
+
+// Problem types specific to the wharf-api.
+var (
+	AdminConfigInvalid = register(Definition{
+		Code:        "admin-config-invalid",
+		Type:        "/prob/api/admin/config/invalid",
+		Title:       "Invalid config.",
+		Status:      http.StatusUnprocessableEntity,
+		Description: "The config could not be reloaded as the config files or environment variables contain invalid values. The previous config is still in use.",
+	})
+	AdminForbidden = register(Definition{
+		Code:        "admin-forbidden",
+		Type:        "/prob/api/admin/forbidden",
+		Title:       "Admin access required.",
+		Status:      http.StatusForbidden,
+		Description: "The endpoint is only available to users listed in the http.admins config.",
+	})
+	ArtifactReleaseConflict = register(Definition{
+		Code:        "artifact-release-conflict",
+		Type:        "/prob/api/artifact/release-conflict",
+		Title:       "Release already has artifact.",
+		Status:      http.StatusConflict,
+		Description: "The release of the project already has an artifact with the same file name. Delete the release's existing artifact before promoting another one in its place.",
+	})
+	BuildApprovalInvalidStatus = register(Definition{
+		Code:        "build-approval-invalid-status",
+		Type:        "/prob/api/build/approval/invalid-status",
+		Title:       "Build is not awaiting approval.",
+		Status:      http.StatusConflict,
+		Description: "Only builds with the AwaitingApproval status can be approved or rejected, such as when the build was already approved or rejected by someone else.",
+	})
+	BuildCancelInvalidStatus = register(Definition{
+		Code:        "build-cancel-invalid-status",
+		Type:        "/prob/api/build/cancel/invalid-status",
+		Title:       "Build cannot be cancelled.",
+		Status:      http.StatusConflict,
+		Description: "Only builds with the Pending status can be cancelled, such as when the build has already been sent to the execution engine.",
+	})
+	BuildLogQueueFull = register(Definition{
+		Code:        "build-log-queue-full",
+		Type:        "/prob/api/build/log/queue-full",
+		Title:       "Too many logs waiting to be inserted.",
+		Status:      http.StatusServiceUnavailable,
+		Description: "The queue of build logs waiting to be inserted into the database is full. The log was not inserted, and the request may be retried later.",
+	})
+	BuildParamDecrypt = register(Definition{
+		Code:        "build-param-decrypt",
+		Type:        "/prob/api/build/param/decrypt",
+		Title:       "Decrypting secret build inputs failed.",
+		Status:      http.StatusInternalServerError,
+		Description: "The stored values of the secret inputs of the build could not be decrypted, such as when the database encryption key has changed.",
+	})
+	BuildParamSecretsNotStored = register(Definition{
+		Code:        "build-param-secrets-not-stored",
+		Type:        "/prob/api/build/param/secrets-not-stored",
+		Title:       "Secret build inputs are missing.",
+		Status:      http.StatusConflict,
+		Description: "The values of the secret inputs of the build were not stored, as no database encryption key is configured.",
+	})
+	BuildRetriggerDecrypt = register(Definition{
+		Code:        "build-retrigger-decrypt",
+		Type:        "/prob/api/build/retrigger/decrypt",
+		Title:       "Decrypting secret build inputs failed.",
+		Status:      http.StatusInternalServerError,
+		Description: "The stored values of the secret inputs of the build could not be decrypted, such as when the database encryption key has changed.",
+	})
+	BuildRetriggerInvalidStatus = register(Definition{
+		Code:        "build-retrigger-invalid-status",
+		Type:        "/prob/api/build/retrigger/invalid-status",
+		Title:       "Build cannot be retriggered.",
+		Status:      http.StatusConflict,
+		Description: "Only builds that failed to be triggered can be retriggered.",
+	})
+	BuildRetriggerMissingSecrets = register(Definition{
+		Code:        "build-retrigger-missing-secrets",
+		Type:        "/prob/api/build/retrigger/missing-secrets",
+		Title:       "Secret build inputs are missing.",
+		Status:      http.StatusConflict,
+		Description: "The values of the secret inputs of the build were not stored, as no database encryption key is configured.",
+	})
+	BuildRetriggerUnknownEngine = register(Definition{
+		Code:        "build-retrigger-unknown-engine",
+		Type:        "/prob/api/build/retrigger/unknown-engine",
+		Title:       "Unknown execution engine.",
+		Status:      http.StatusConflict,
+		Description: "The execution engine of the build is no longer configured in the wharf-api.",
+	})
+	BuildStreamTooManyListeners = register(Definition{
+		Code:        "build-stream-too-many-listeners",
+		Type:        "/prob/api/build/stream/too-many-listeners",
+		Title:       "Too many listeners on build stream.",
+		Status:      http.StatusServiceUnavailable,
+		Description: "The event stream of the build already has the maximum number of listeners allowed by the wharf-api configuration. Retry the request later.",
+	})
+	BuildTriggerCycle = register(Definition{
+		Code:        "build-trigger-cycle",
+		Type:        "/prob/api/build-trigger/cycle",
+		Title:       "Build trigger would create a cycle.",
+		Status:      http.StatusConflict,
+		Description: "The build trigger would make builds of the target project trigger builds of the source project, directly or via other build triggers, which would start builds forever.",
+	})
+	BuildTriggerInvalidBranchFilter = register(Definition{
+		Code:        "build-trigger-invalid-branch-filter",
+		Type:        "/prob/api/build-trigger/invalid-branch-filter",
+		Title:       "Invalid branch filter.",
+		Status:      http.StatusBadRequest,
+		Description: "The branch filter of the build trigger is not a valid glob pattern.",
+	})
+	EngineCircuitOpen = register(Definition{
+		Code:        "engine-circuit-open",
+		Type:        "/prob/api/engine/circuit-open",
+		Title:       "Execution engine is unavailable.",
+		Status:      http.StatusServiceUnavailable,
+		Description: "The execution engine has failed too many times in a row, and does not receive any new builds until its circuit breaker closes again.",
+	})
+	EngineNoDefault = register(Definition{
+		Code:        "engine-no-default",
+		Type:        "/prob/api/engine/no-default",
+		Title:       "No default execution engine configured.",
+		Status:      http.StatusInternalServerError,
+		Description: "No execution engine was specified, and the wharf-api does not have any default execution engine configured.",
+	})
+	EngineTokenNoEncryptionKey = register(Definition{
+		Code:        "engine-token-no-encryption-key",
+		Type:        "/prob/api/engine/token/no-encryption-key",
+		Title:       "No database encryption key configured.",
+		Status:      http.StatusConflict,
+		Description: "Engine tokens are only stored in the database when encrypted, which requires the db.encryptionKey config to be set.",
+	})
+	EnvironmentNameConflict = register(Definition{
+		Code:        "environment-name-conflict",
+		Type:        "/prob/api/environment/name-conflict",
+		Title:       "Environment name already exists.",
+		Status:      http.StatusConflict,
+		Description: "Another environment with the same name already exists in the project.",
+	})
+	IdempotencyKeyInProgress = register(Definition{
+		Code:        "idempotency-key-in-progress",
+		Type:        "/prob/api/idempotency-key/in-progress",
+		Title:       "Request with the same idempotency key is in progress.",
+		Status:      http.StatusConflict,
+		Description: "Another request with the same Idempotency-Key header is still being handled. Retry the request later to receive its response.",
+	})
+	IdempotencyKeyMismatch = register(Definition{
+		Code:        "idempotency-key-mismatch",
+		Type:        "/prob/api/idempotency-key/mismatch",
+		Title:       "Idempotency key was reused for a different request.",
+		Status:      http.StatusUnprocessableEntity,
+		Description: "The Idempotency-Key header was already used for a request with a different method, URL, or body.",
+	})
+	NotificationNoSMTP = register(Definition{
+		Code:        "notification-no-smtp",
+		Type:        "/prob/api/notification/no-smtp",
+		Title:       "No SMTP server configured.",
+		Status:      http.StatusConflict,
+		Description: "Email notifications, such as from notification rules with the email notifier or from user subscriptions, are sent using the SMTP server from the wharf-api configuration, which requires the notification.smtp.host config to be set.",
+	})
+	PreconditionFailed = register(Definition{
+		Code:        "precondition-failed",
+		Type:        "/prob/api/precondition-failed",
+		Title:       "Precondition failed.",
+		Status:      http.StatusPreconditionFailed,
+		Description: "The If-Match header did not match the current entity tag (ETag) of the object, meaning it was modified since it was fetched.",
+	})
+	ProjectBuildDefinitionInvalid = register(Definition{
+		Code:        "project-build-definition-invalid",
+		Type:        "/prob/api/project/build-definition/invalid",
+		Title:       "Invalid build definition.",
+		Status:      http.StatusBadRequest,
+		Description: "The build definition has one or more validation errors, listed in the errors field.",
+	})
+	ProjectBuildDefinitionParse = register(Definition{
+		Code:        "project-build-definition-parse",
+		Type:        "/prob/api/project/build-definition/parse",
+		Title:       "Parsing build definition failed.",
+		Status:      http.StatusUnprocessableEntity,
+		Description: "The build definition of the project is not valid YAML.",
+	})
+	ProjectRunArchived = register(Definition{
+		Code:        "project-run-archived",
+		Type:        "/prob/api/project/run/archived",
+		Title:       "Project is archived.",
+		Status:      http.StatusConflict,
+		Description: "The project is archived, and cannot start new builds until it is unarchived.",
+	})
+	ProjectRunEngineNotAllowed = register(Definition{
+		Code:        "project-run-engine-not-allowed",
+		Type:        "/prob/api/project/run/engine-not-allowed",
+		Title:       "Execution engine not allowed for project.",
+		Status:      http.StatusBadRequest,
+		Description: "The project has a list of allowed execution engines, which does not contain the execution engine of the build. The allowed execution engines are listed in the detail field.",
+	})
+	ProjectRunInvalidInput = register(Definition{
+		Code:        "project-run-invalid-input",
+		Type:        "/prob/api/project/run/invalid-input",
+		Title:       "Invalid input variables for build.",
+		Status:      http.StatusBadRequest,
+		Description: "One or more input variables does not match the inputs declared in the build definition, listed in the errors field.",
+	})
+	ProjectRunParamsDeserialize = register(Definition{
+		Code:        "project-run-params-deserialize",
+		Type:        "/prob/api/project/run/params-deserialize",
+		Title:       "Parsing build parameters failed.",
+		Status:      http.StatusBadRequest,
+		Description: "The build parameters in the request body could not be deserialized.",
+	})
+	ProjectRunParamsSerialize = register(Definition{
+		Code:        "project-run-params-serialize",
+		Type:        "/prob/api/project/run/params-serialize",
+		Title:       "Serializing build parameters failed.",
+		Status:      http.StatusBadRequest,
+		Description: "The build parameters could not be serialized before sending them to the execution engine.",
+	})
+	ProjectRunParamsTooLarge = register(Definition{
+		Code:        "project-run-params-too-large",
+		Type:        "/prob/api/project/run/params-too-large",
+		Title:       "Build input variables are too large.",
+		Status:      http.StatusBadRequest,
+		Description: "The input variables of the build are too large to be sent to the execution engine, as limited by the ci.maxVarsSize config of the wharf-api.",
+	})
+	ProjectRunQueueFull = register(Definition{
+		Code:        "project-run-queue-full",
+		Type:        "/prob/api/project/run/queue-full",
+		Title:       "Too many builds waiting to be triggered.",
+		Status:      http.StatusServiceUnavailable,
+		Description: "The queue of builds waiting to be triggered is full. The build was created but not triggered, and may be retriggered later.",
+	})
+	ProjectRunTrigger = register(Definition{
+		Code:        "project-run-trigger",
+		Type:        "/prob/api/project/run/trigger",
+		Title:       "Triggering build failed.",
+		Status:      http.StatusBadGateway,
+		Description: "The execution engine failed to schedule the build. The build was created but not triggered, and may be retriggered later.",
+	})
+	ProviderInvalidName = register(Definition{
+		Code:        "provider-invalid-name",
+		Type:        "/prob/api/provider/invalid-name",
+		Title:       "Invalid provider name.",
+		Status:      http.StatusBadRequest,
+		Description: "The provider name is empty, or is not one of the provider names allowed by the wharf-api configuration.",
+	})
+	ProviderSyncInProgress = register(Definition{
+		Code:        "provider-sync-in-progress",
+		Type:        "/prob/api/provider/sync/in-progress",
+		Title:       "Provider sync is already in progress.",
+		Status:      http.StatusConflict,
+		Description: "Another sync of the same provider is still running. Wait for it to finish before starting a new sync.",
+	})
+	ProviderSyncNotConfigured = register(Definition{
+		Code:        "provider-sync-not-configured",
+		Type:        "/prob/api/provider/sync/not-configured",
+		Title:       "Provider sync is not configured.",
+		Status:      http.StatusBadRequest,
+		Description: "The wharf-api has no sync URL configured for the provider plugin of the provider's name.",
+	})
+	SettingsInvalid = register(Definition{
+		Code:        "settings-invalid",
+		Type:        "/prob/api/settings/invalid",
+		Title:       "Invalid settings.",
+		Status:      http.StatusBadRequest,
+		Description: "One or more settings are unknown or have invalid values, listed in the errors field.",
+	})
+	TenancyClaimMissing = register(Definition{
+		Code:        "tenancy-claim-missing",
+		Type:        "/prob/api/tenancy/claim-missing",
+		Title:       "Instance claim missing.",
+		Status:      http.StatusForbidden,
+		Description: "The request has no access token, or its access token does not have the claim set by the tenancy.oidcClaim config, so the Wharf instance that the request belongs to is unknown.",
+	})
+	TestResultsParse = register(Definition{
+		Code:        "test-results-parse",
+		Type:        "/prob/api/test-results-parse",
+		Title:       "Unexpected response format.",
+		Status:      http.StatusBadRequest,
+		Description: "An uploaded test results file could not be parsed, as it is not in a supported TRX or JUnit XML format.",
+	})
+	UploadTooLarge = register(Definition{
+		Code:        "upload-too-large",
+		Type:        "/prob/api/upload-too-large",
+		Title:       "Upload too large.",
+		Status:      http.StatusRequestEntityTooLarge,
+		Description: "The request body is larger than the maximum upload size set by the artifact.maxUploadSize config.",
+	})
+	WebhookDisabled = register(Definition{
+		Code:        "webhook-disabled",
+		Type:        "/prob/api/webhook/disabled",
+		Title:       "Webhook is disabled.",
+		Status:      http.StatusForbidden,
+		Description: "The project's inbound webhook exists, but has been disabled, and does not start any builds until it is enabled again.",
+	})
+	WebhookInvalidSignature = register(Definition{
+		Code:        "webhook-invalid-signature",
+		Type:        "/prob/api/webhook/invalid-signature",
+		Title:       "Invalid webhook signature.",
+		Status:      http.StatusUnauthorized,
+		Description: "The X-Wharf-Signature-256 or X-Hub-Signature-256 header is missing or does not hold the HMAC-SHA256 signature of the request body, signed using the webhook's secret, or using the GitHub webhook secret in the wharf-api configuration.",
+	})
+	WebhookInvalidToken = register(Definition{
+		Code:        "webhook-invalid-token",
+		Type:        "/prob/api/webhook/invalid-token",
+		Title:       "Invalid webhook token.",
+		Status:      http.StatusUnauthorized,
+		Description: "The X-Gitlab-Token header is missing or does not match the GitLab webhook token in the wharf-api configuration.",
+	})
+	WebhookNotConfigured = register(Definition{
+		Code:        "webhook-not-configured",
+		Type:        "/prob/api/webhook/not-configured",
+		Title:       "Webhook receiver is not configured.",
+		Status:      http.StatusForbidden,
+		Description: "No webhook secret or token is configured in wharf-api for the Git provider, so all of its webhook calls are rejected.",
+	})
+	WebhookNotFound = register(Definition{
+		Code:        "webhook-not-found",
+		Type:        "/prob/api/webhook/not-found",
+		Title:       "Webhook not found.",
+		Status:      http.StatusNotFound,
+		Description: "No inbound webhook exists with the token in the URL, such as after the webhook's token has been regenerated or the webhook has been deleted.",
+	})
+)
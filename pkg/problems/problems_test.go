@@ -0,0 +1,48 @@
+package problems
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefinitions_codeMatchesType(t *testing.T) {
+	types := map[string]Code{}
+	for _, d := range All() {
+		wantCode := strings.ReplaceAll(strings.TrimPrefix(d.Type, "/prob/api/"), "/", "-")
+		assert.Equal(t, Code(wantCode), d.Code, d.Type)
+		assert.NotEmpty(t, d.Title, d.Code)
+		assert.NotEmpty(t, d.Description, d.Code)
+		assert.NotEmpty(t, http.StatusText(d.Status), d.Code)
+		if other, ok := types[d.Type]; ok {
+			t.Errorf("type %q used by both %q and %q", d.Type, other, d.Code)
+		}
+		types[d.Type] = d.Code
+	}
+}
+
+func TestDefinition_With(t *testing.T) {
+	got := ProviderInvalidName.With(problem.Response{
+		Detail:   "Provider name was \"foo\".",
+		Instance: "/api/provider#name",
+	})
+	assert.Equal(t, problem.Response{
+		Type:     "/prob/api/provider/invalid-name",
+		Title:    "Invalid provider name.",
+		Status:   http.StatusBadRequest,
+		Detail:   "Provider name was \"foo\".",
+		Instance: "/api/provider#name",
+	}, got)
+}
+
+func TestLookupType(t *testing.T) {
+	d, ok := LookupType(TypePrefix + "/prob/api/project/run/trigger")
+	assert.True(t, ok)
+	assert.Equal(t, ProjectRunTrigger.Code, d.Code)
+
+	_, ok = LookupType("/prob/api/does-not-exist")
+	assert.False(t, ok)
+}
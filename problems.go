@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+type problemsModule struct{}
+
+func (m problemsModule) Register(g *gin.RouterGroup) {
+	g.GET("/problems", m.getProblemListHandler)
+	g.GET("/problems/:code", m.getProblemHandler)
+}
+
+// getProblemListHandler godoc
+// @id getProblemList
+// @summary Get list of problem types.
+// @description Lists all problem types that the wharf-api may respond with,
+// @description including their machine-readable codes.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce json
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProblemTypeList "Problem types"
+// @router /problems [get]
+func (m problemsModule) getProblemListHandler(c *gin.Context) {
+	all := problems.All()
+	res := response.ProblemTypeList{List: make([]response.ProblemType, len(all))}
+	for i, def := range all {
+		res.List[i] = convProblemDefinitionToResponse(def)
+	}
+	ginrender.Response(c, 200, res)
+}
+
+// getProblemHandler godoc
+// @id getProblem
+// @summary Get problem type by its code.
+// @description Added in v5.3.0.
+// @tags meta
+// @produce json
+// @param code path string true "Problem code" example(project-run-trigger)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProblemType "Problem type"
+// @failure 404 {object} problem.Response "Problem type not found"
+// @router /problems/{code} [get]
+func (m problemsModule) getProblemHandler(c *gin.Context) {
+	code := c.Param("code")
+	def, ok := problems.Lookup(problems.Code(code))
+	if !ok {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Problem type with code %q was not found.",
+			code))
+		return
+	}
+	ginrender.Response(c, 200, convProblemDefinitionToResponse(def))
+}
+
+func convProblemDefinitionToResponse(def problems.Definition) response.ProblemType {
+	return response.ProblemType{
+		Code:        string(def.Code),
+		Type:        problems.TypePrefix + def.Type,
+		Title:       def.Title,
+		Status:      def.Status,
+		Description: def.Description,
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
 
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/builddef"
 	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
 	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
@@ -16,11 +19,14 @@ import (
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
 	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
 	"gorm.io/gorm"
 )
 
 type projectModule struct {
 	Database *gorm.DB
+	Cache    *dbCache
+	Config   *Config
 }
 
 func (m projectModule) Register(g *gin.RouterGroup) {
@@ -34,6 +40,16 @@ func (m projectModule) Register(g *gin.RouterGroup) {
 			projectByID.GET("", m.getProjectHandler)
 			projectByID.DELETE("", m.deleteProjectHandler)
 			projectByID.PUT("", m.updateProjectHandler)
+			projectByID.PATCH("", m.patchProjectHandler)
+			projectByID.GET("/build-definition", m.getProjectBuildDefinitionHandler)
+			projectByID.POST("/build-definition/validate", m.validateProjectBuildDefinitionHandler)
+			projectByID.POST("/archive", m.archiveProjectHandler)
+			projectByID.POST("/unarchive", m.unarchiveProjectHandler)
+			projectByID.POST("/transfer", m.transferProjectHandler)
+			projectByID.GET("/input-defaults", m.getProjectInputDefaultsHandler)
+			projectByID.PUT("/input-defaults", m.updateProjectInputDefaultsHandler)
+			projectByID.GET("/engines", m.getProjectEnginesHandler)
+			projectByID.PUT("/engines", m.updateProjectEnginesHandler)
 
 			override := projectByID.Group("/override")
 			{
@@ -46,11 +62,13 @@ func (m projectModule) Register(g *gin.RouterGroup) {
 }
 
 var projectJSONToColumns = map[string]database.SafeSQLName{
-	response.ProjectJSONFields.ProjectID:   database.ProjectColumns.ProjectID,
-	response.ProjectJSONFields.Name:        database.ProjectColumns.Name,
-	response.ProjectJSONFields.GroupName:   database.ProjectColumns.GroupName,
-	response.ProjectJSONFields.Description: database.ProjectColumns.Description,
-	response.ProjectJSONFields.GitURL:      database.ProjectColumns.GitURL,
+	response.ProjectJSONFields.ProjectID:      database.ProjectColumns.ProjectID,
+	response.ProjectJSONFields.Name:           database.ProjectColumns.Name,
+	response.ProjectJSONFields.GroupName:      database.ProjectColumns.GroupName,
+	response.ProjectJSONFields.Description:    database.ProjectColumns.Description,
+	response.ProjectJSONFields.GitURL:         database.ProjectColumns.GitURL,
+	response.TimeMetadataJSONFields.CreatedAt: database.TimeMetadataColumns.CreatedAt,
+	response.TimeMetadataJSONFields.UpdatedAt: database.TimeMetadataColumns.UpdatedAt,
 }
 
 var defaultGetProjectsOrderBy = orderby.Column{Name: database.ProjectColumns.ProjectID, Direction: orderby.Desc}
@@ -62,11 +80,13 @@ var defaultGetProjectsOrderBy = orderby.Column{Name: database.ProjectColumns.Pro
 // @description Verbatim filters will match on the entire string used to find exact matches,
 // @description while the matching filters are meant for searches by humans where it tries to find soft matches and is therefore inaccurate by nature.
 // @description Added in v5.0.0.
+// @description Responds with CSV instead, using the same filters and ordering, if requested via the `Accept: text/csv` header or the `format=csv` query parameter. CSV added in v5.3.0.
 // @tags project
-// @produce json
-// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc'. Can be specified multiple times for more granular sorting. Defaults to `?orderby=projectId desc`"
-// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`). Required if `offset` is used." default(100)
+// @produce json,text/csv
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Casing, prefixes, and commas added in v5.3.0. Defaults to `?orderby=projectId desc`"
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
 // @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
 // @param name query string false "Filter by verbatim project name."
 // @param groupName query string false "Filter by verbatim project group."
 // @param description query string false "Filter by verbatim description."
@@ -78,6 +98,13 @@ var defaultGetProjectsOrderBy = orderby.Column{Name: database.ProjectColumns.Pro
 // @param descriptionMatch query string false "Filter by matching description. Cannot be used with `description`."
 // @param gitUrlMatch query string false "Filter by matching Git URL. Cannot be used with `gitUrl`."
 // @param match query string false "Filter by matching on any supported fields."
+// @param label query []string false "Filter by label, either as `key=value` to match the label's value, or as `key` to match any value. Can be specified multiple times to match on all the labels. Added in v5.3.0."
+// @param createdAfter query string false "Filter by projects with created date later than value. Added in v5.3.0." format(date-time)
+// @param createdBefore query string false "Filter by projects with created date earlier than value. Added in v5.3.0." format(date-time)
+// @param updatedAfter query string false "Filter by projects with last updated date later than value. Added in v5.3.0." format(date-time)
+// @param updatedBefore query string false "Filter by projects with last updated date earlier than value. Added in v5.3.0." format(date-time)
+// @param archived query bool false "Filter by archived projects instead of non-archived projects. Added in v5.3.0." default(false)
+// @param format query string false "Response format, where `csv` responds with CSV instead of JSON. Added in v5.3.0." Enums(json,csv)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedProjects
 // @failure 502 {object} problem.Response "Database is unreachable"
@@ -100,6 +127,12 @@ func (m projectModule) getProjectListHandler(c *gin.Context) {
 		GitURLMatch      *string `form:"gitUrlMatch" binding:"excluded_with=GitURL"`
 
 		Match *string `form:"match"`
+
+		Label []string `form:"label"`
+
+		Archived bool `form:"archived"`
+
+		timeMetadataQueryParams
 	}{
 		commonGetQueryParams: defaultCommonGetQueryParams,
 	}
@@ -110,6 +143,10 @@ func (m projectModule) getProjectListHandler(c *gin.Context) {
 	if !ok {
 		return
 	}
+	labelFilters, ok := parseLabelFilters(c, params.Label, "label")
+	if !ok {
+		return
+	}
 
 	var where wherefields.Collection
 	query := databaseProjectPreloaded(m.Database).
@@ -120,7 +157,8 @@ func (m projectModule) getProjectListHandler(c *gin.Context) {
 			TokenID:    where.UintPtrZeroNil(database.ProjectFields.TokenID, params.TokenID),
 			ProviderID: where.UintPtrZeroNil(database.ProjectFields.ProviderID, params.ProviderID),
 			GitURL:     where.String(database.ProjectFields.GitURL, params.GitURL),
-		}, where.NonNilFieldNames()...).
+			IsArchived: params.Archived,
+		}, append(where.NonNilFieldNames(), database.ProjectFields.IsArchived)...).
 		Scopes(
 			whereLikeScope(map[database.SafeSQLName]*string{
 				database.ProjectColumns.Name:        params.NameMatch,
@@ -135,17 +173,29 @@ func (m projectModule) getProjectListHandler(c *gin.Context) {
 				database.ProjectColumns.Description,
 				database.ProjectColumns.GitURL,
 			),
+			whereLabelsScope(projectLabelTable, labelFilters),
+			whereProjectInstanceScope(c),
+			optionalTimeRangeScope(database.TimeMetadataColumns.CreatedAt, params.CreatedAfter, params.CreatedBefore),
+			optionalTimeRangeScope(database.TimeMetadataColumns.UpdatedAt, params.UpdatedAfter, params.UpdatedBefore),
 		)
 
+	if ginrender.IsCSVRequested(c) {
+		writeDBQueryCSV(c, query, params.Limit, params.Offset, "projects.csv", projectCSVHeader,
+			func(dbProject database.Project) []string {
+				return resProjectToCSVRecord(modelconv.DBProjectToResponse(dbProject))
+			}, "Failed fetching list of projects from database.")
+		return
+	}
+
 	var dbProjects []database.Project
 	var totalCount int64
-	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, &dbProjects, &totalCount)
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbProjects, &totalCount)
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, "Failed fetching list of projects from database.")
 		return
 	}
 
-	renderJSON(c, http.StatusOK, response.PaginatedProjects{
+	ginrender.Response(c, http.StatusOK, response.PaginatedProjects{
 		List:       modelconv.DBProjectsToResponses(dbProjects),
 		TotalCount: totalCount,
 	})
@@ -160,6 +210,7 @@ func (m projectModule) getProjectListHandler(c *gin.Context) {
 // @param projectId path uint true "project ID" minimum(0)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.Project
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 404 {object} problem.Response "Project not found"
@@ -170,12 +221,19 @@ func (m projectModule) getProjectHandler(c *gin.Context) {
 	if !ok {
 		return
 	}
+	if resProject, ok := m.Cache.getProject(projectID); ok {
+		setETagHeader(c, resProject.Version)
+		ginrender.Response(c, http.StatusOK, resProject)
+		return
+	}
 	dbProject, ok := fetchProjectByID(c, m.Database, projectID, "")
 	if !ok {
 		return
 	}
 	resProject := modelconv.DBProjectToResponse(dbProject)
-	renderJSON(c, http.StatusOK, resProject)
+	m.Cache.setProject(resProject)
+	setETagHeader(c, dbProject.Version)
+	ginrender.Response(c, http.StatusOK, resProject)
 }
 
 // createProjectHandler godoc
@@ -187,6 +245,7 @@ func (m projectModule) getProjectHandler(c *gin.Context) {
 // @accept json
 // @produce json
 // @param project body request.Project true "Project to create"
+// @param validateBuildDefinition query bool false "Reject the project if the build definition has validation errors. Added in v5.3.0."
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 201 {object} response.Project
 // @failure 400 {object} problem.Response "Bad request"
@@ -197,21 +256,37 @@ func (m projectModule) getProjectHandler(c *gin.Context) {
 func (m projectModule) createProjectHandler(c *gin.Context) {
 	var reqProject request.Project
 	if err := c.ShouldBindJSON(&reqProject); err != nil {
-		ginutil.WriteInvalidBindError(c, err,
+		writeInvalidBindError(c, err,
 			"One or more parameters failed to parse when reading the request body for the project object to update.")
 		return
 	}
+	if !validateBuildDefinitionIfRequested(c, reqProject.BuildDefinition) {
+		return
+	}
 
 	dbProject := modelconv.ReqProjectToDatabase(reqProject)
+	dbProject.InstanceID, _ = getRequestInstanceID(c)
 	if err := m.Database.Create(&dbProject).Error; err != nil {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 			"Failed creating new project with group %q, token ID %d, and name %q in database.",
 			reqProject.GroupName, reqProject.TokenID, reqProject.Name))
 		return
 	}
+	if err := ensureProjectGroups(m.Database, dbProject.GroupName); err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed adding project groups for group name %q of project with ID %d.",
+			dbProject.GroupName, dbProject.ProjectID))
+		return
+	}
+	if err := discoverProjectEnvironments(m.Database, dbProject.ProjectID, dbProject.BuildDefinition); err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed adding environments discovered from build definition of project with ID %d.",
+			dbProject.ProjectID))
+		return
+	}
 
 	resProject := modelconv.DBProjectToResponse(dbProject)
-	renderJSON(c, http.StatusCreated, resProject)
+	ginrender.Response(c, http.StatusCreated, resProject)
 }
 
 // deleteProjectHandler godoc
@@ -247,17 +322,22 @@ func (m projectModule) deleteProjectHandler(c *gin.Context) {
 // @id updateProject
 // @summary Update project in database
 // @description Updates a project by replacing all of its fields.
+// @description Environments declared in the build definition that are missing from the project are added automatically.
 // @description Added in v5.0.0.
 // @tags project
 // @accept json
 // @produce json
 // @param projectId path uint true "project ID" minimum(0)
 // @param project body request.ProjectUpdate _ "New project values"
+// @param validateBuildDefinition query bool false "Reject the update if the build definition has validation errors. Added in v5.3.0."
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches. Added in v5.3.0."
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.Project
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request, such as invalid body JSON"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 404 {object} problem.Response "Project to update was not found"
+// @failure 412 {object} problem.Response "Project was modified since it was fetched, as the If-Match header did not match"
 // @failure 502 {object} problem.Response "Database is unreachable"
 // @router /project/{projectId} [put]
 func (m projectModule) updateProjectHandler(c *gin.Context) {
@@ -268,14 +348,69 @@ func (m projectModule) updateProjectHandler(c *gin.Context) {
 	var reqProjectUpdate request.ProjectUpdate
 	err := c.ShouldBindJSON(&reqProjectUpdate)
 	if err != nil {
-		ginutil.WriteInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
+		return
+	}
+	if !validateBuildDefinitionIfRequested(c, reqProjectUpdate.BuildDefinition) {
 		return
 	}
 	dbProject, ok := fetchProjectByID(c, m.Database, projectID, "when updating project")
 	if !ok {
 		return
 	}
+	if !validateIfMatch(c, dbProject.Version, "project") {
+		return
+	}
+	m.saveProjectUpdate(c, dbProject, reqProjectUpdate)
+}
 
+// patchProjectHandler godoc
+// @id patchProject
+// @summary Partially update project in database
+// @description Updates a project by applying a JSON Merge Patch (RFC 7396), where only the supplied fields are modified.
+// @description Fields set to null are reset to their zero values.
+// @description Environments declared in the build definition that are missing from the project are added automatically.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json,application/merge-patch+json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param project body request.ProjectUpdate _ "Project fields to update"
+// @param validateBuildDefinition query bool false "Reject the update if the build definition has validation errors."
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Project
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
+// @failure 400 {object} problem.Response "Bad request, such as invalid body JSON"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project to update was not found"
+// @failure 412 {object} problem.Response "Project was modified since it was fetched, as the If-Match header did not match"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId} [patch]
+func (m projectModule) patchProjectHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	dbProject, ok := fetchProjectByID(c, m.Database, projectID, "when updating project")
+	if !ok {
+		return
+	}
+	if !validateIfMatch(c, dbProject.Version, "project") {
+		return
+	}
+	reqProjectUpdate := modelconv.DBProjectToUpdateRequest(dbProject)
+	if !bindMergePatch(c, &reqProjectUpdate) {
+		return
+	}
+	if !validateBuildDefinitionIfRequested(c, reqProjectUpdate.BuildDefinition) {
+		return
+	}
+	m.saveProjectUpdate(c, dbProject, reqProjectUpdate)
+}
+
+func (m projectModule) saveProjectUpdate(c *gin.Context, dbProject database.Project, reqProjectUpdate request.ProjectUpdate) {
+	projectID := dbProject.ProjectID
 	dbProject.Name = reqProjectUpdate.Name
 	dbProject.GroupName = reqProjectUpdate.GroupName
 	dbProject.Description = reqProjectUpdate.Description
@@ -284,16 +419,202 @@ func (m projectModule) updateProjectHandler(c *gin.Context) {
 	dbProject.ProviderID = ptrconv.UintZeroNil(reqProjectUpdate.ProviderID)
 	dbProject.BuildDefinition = reqProjectUpdate.BuildDefinition
 	dbProject.GitURL = reqProjectUpdate.GitURL
+	dbProject.SchedulingTimeout = ptrconv.UintPtrNullInt(reqProjectUpdate.SchedulingTimeout)
+	dbProject.RunningTimeout = ptrconv.UintPtrNullInt(reqProjectUpdate.RunningTimeout)
 
-	if err := m.Database.Save(&dbProject).Error; err != nil {
+	saved, err := saveVersioned(m.Database, &dbProject, &dbProject.Version,
+		database.ProjectColumns.Version, hasIfMatchHeader(c))
+	if err != nil {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 			"Failed writing project with name %q and group name %q to database.",
 			reqProjectUpdate.Name, reqProjectUpdate.GroupName))
 		return
 	}
+	if !saved {
+		writePreconditionFailedProblem(c, "project")
+		return
+	}
+	if err := ensureProjectGroups(m.Database, dbProject.GroupName); err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed adding project groups for group name %q of project with ID %d.",
+			dbProject.GroupName, projectID))
+		return
+	}
+	if err := discoverProjectEnvironments(m.Database, projectID, dbProject.BuildDefinition); err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed adding environments discovered from build definition of project with ID %d.",
+			projectID))
+		return
+	}
 
 	resProject := modelconv.DBProjectToResponse(dbProject)
-	renderJSON(c, http.StatusOK, resProject)
+	setETagHeader(c, dbProject.Version)
+	ginrender.Response(c, http.StatusOK, resProject)
+}
+
+// getProjectBuildDefinitionHandler godoc
+// @id getProjectBuildDefinition
+// @summary Get parsed build definition of project
+// @description Parses the project's stored `.wharf-ci.yml` build definition and returns its structure,
+// @description including stages, steps, environments, and inputs with their default values.
+// @description Stages, steps, and environments keep the order in which they were declared.
+// @description A project without a build definition results in empty lists.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildDefinition
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 422 {object} problem.Response "Build definition is not valid YAML"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/build-definition [get]
+func (m projectModule) getProjectBuildDefinitionHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	dbProject, ok := fetchProjectByIDSlim(c, m.Database, projectID, "when fetching build definition")
+	if !ok {
+		return
+	}
+	def, err := builddef.Parse(dbProject.BuildDefinition)
+	if err != nil {
+		ginutil.WriteProblemError(c, err, problems.ProjectBuildDefinitionParse.Newf(
+			"The build definition of project with ID %d is not valid YAML.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.BuildDefinitionToResponse(projectID, def))
+}
+
+// validateProjectBuildDefinitionHandler godoc
+// @id validateProjectBuildDefinition
+// @summary Validate build definition
+// @description Validates a `.wharf-ci.yml` build definition, checking for YAML syntax errors, schema errors,
+// @description unknown input and step types, undeclared environments, and references to undeclared variables.
+// @description The build definition to validate is taken from the request body,
+// @description or from the project's stored build definition if the request body is empty.
+// @description Each issue contains the line and column of where it was found.
+// @description Added in v5.3.0.
+// @tags project
+// @accept plain
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param buildDefinition body string false "Build definition YAML to validate"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildDefinitionValidation
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/build-definition/validate [post]
+func (m projectModule) validateProjectBuildDefinitionHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		ginutil.WriteBodyReadError(c, err, "Failed to read build definition from request body.")
+		return
+	}
+	buildDef := string(body)
+	if len(body) == 0 {
+		dbProject, ok := fetchProjectByIDSlim(c, m.Database, projectID, "when validating build definition")
+		if !ok {
+			return
+		}
+		buildDef = dbProject.BuildDefinition
+	} else if !validateProjectExistsByID(c, m.Database, projectID, "when validating build definition") {
+		return
+	}
+	_, issues := builddef.Validate(buildDef)
+	ginrender.Response(c, http.StatusOK, modelconv.BuildDefinitionIssuesToValidationResponse(issues))
+}
+
+// archiveProjectHandler godoc
+// @id archiveProject
+// @summary Archive a project.
+// @description Hides the project from the project lists by default, and
+// @description blocks it from starting new builds. The project and its builds
+// @description can still be fetched, so its history is kept.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Project
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 412 {object} problem.Response "Project was modified since it was fetched, as the If-Match header did not match"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/archive [post]
+func (m projectModule) archiveProjectHandler(c *gin.Context) {
+	m.setProjectArchived(c, true)
+}
+
+// unarchiveProjectHandler godoc
+// @id unarchiveProject
+// @summary Unarchive a project.
+// @description Makes an archived project show up in the project lists again,
+// @description and allows it to start new builds.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Project
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 412 {object} problem.Response "Project was modified since it was fetched, as the If-Match header did not match"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/unarchive [post]
+func (m projectModule) unarchiveProjectHandler(c *gin.Context) {
+	m.setProjectArchived(c, false)
+}
+
+func (m projectModule) setProjectArchived(c *gin.Context, isArchived bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	dbProject, ok := fetchProjectByID(c, m.Database, projectID, "when archiving or unarchiving project")
+	if !ok {
+		return
+	}
+	if !validateIfMatch(c, dbProject.Version, "project") {
+		return
+	}
+	if dbProject.IsArchived != isArchived {
+		dbProject.IsArchived = isArchived
+		saved, err := saveVersioned(m.Database, &dbProject, &dbProject.Version,
+			database.ProjectColumns.Version, hasIfMatchHeader(c))
+		if err != nil {
+			ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+				"Failed writing archived state of project with ID %d to database.",
+				projectID))
+			return
+		}
+		if !saved {
+			writePreconditionFailedProblem(c, "project")
+			return
+		}
+		requestLog(c).Info().
+			WithUint("project", projectID).
+			WithBool("archived", isArchived).
+			Message("Changed archived state of project.")
+	}
+	setETagHeader(c, dbProject.Version)
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectToResponse(dbProject))
 }
 
 // getProjectOverridesHandler godoc
@@ -309,6 +630,7 @@ func (m projectModule) updateProjectHandler(c *gin.Context) {
 // @param projectId path uint true "project ID" minimum(0)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.ProjectOverrides
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request, such as invalid body JSON"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 404 {object} problem.Response "Project to update was not found"
@@ -337,7 +659,8 @@ func (m projectModule) getProjectOverridesHandler(c *gin.Context) {
 	}
 
 	resProject := modelconv.DBProjectOverridesToResponse(dbProjectOverrides)
-	renderJSON(c, http.StatusOK, resProject)
+	setETagHeader(c, dbProjectOverrides.Version)
+	ginrender.Response(c, http.StatusOK, resProject)
 }
 
 // updateProjectOverridesHandler godoc
@@ -353,11 +676,14 @@ func (m projectModule) getProjectOverridesHandler(c *gin.Context) {
 // @produce json
 // @param projectId path uint true "project ID" minimum(0)
 // @param overrides body request.ProjectOverridesUpdate _ "New project overrides"
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches. Added in v5.3.0."
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.ProjectOverrides
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request, such as invalid body JSON"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 404 {object} problem.Response "Project to update was not found"
+// @failure 412 {object} problem.Response "Project overrides was modified since it was fetched, as the If-Match header did not match"
 // @failure 502 {object} problem.Response "Database is unreachable"
 // @router /project/{projectId}/override [put]
 func (m projectModule) updateProjectOverridesHandler(c *gin.Context) {
@@ -368,7 +694,7 @@ func (m projectModule) updateProjectOverridesHandler(c *gin.Context) {
 	var reqOverridesUpdate request.ProjectOverridesUpdate
 	err := c.ShouldBindJSON(&reqOverridesUpdate)
 	if err != nil {
-		ginutil.WriteInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
 		return
 	}
 
@@ -384,20 +710,30 @@ func (m projectModule) updateProjectOverridesHandler(c *gin.Context) {
 			projectID))
 		return
 	}
+	if !validateIfMatch(c, dbProjectOverrides.Version, "project overrides") {
+		return
+	}
 
 	dbProjectOverrides.Description = reqOverridesUpdate.Description
 	dbProjectOverrides.AvatarURL = reqOverridesUpdate.AvatarURL
 	dbProjectOverrides.GitURL = reqOverridesUpdate.GitURL
 
-	if err := m.Database.Save(&dbProjectOverrides).Error; err != nil {
+	saved, err := saveVersioned(m.Database, &dbProjectOverrides, &dbProjectOverrides.Version,
+		database.ProjectOverridesColumns.Version, hasIfMatchHeader(c))
+	if err != nil {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 			"Failed writing project overrides for project with ID %d to database.",
 			projectID))
 		return
 	}
+	if !saved {
+		writePreconditionFailedProblem(c, "project overrides")
+		return
+	}
 
 	resProject := modelconv.DBProjectOverridesToResponse(dbProjectOverrides)
-	renderJSON(c, http.StatusOK, resProject)
+	setETagHeader(c, dbProjectOverrides.Version)
+	ginrender.Response(c, http.StatusOK, resProject)
 }
 
 // deleteProjectOverridesHandler godoc
@@ -431,6 +767,37 @@ func (m projectModule) deleteProjectOverridesHandler(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func validateBuildDefinitionIfRequested(c *gin.Context, buildDef string) bool {
+	var params = struct {
+		ValidateBuildDefinition bool `form:"validateBuildDefinition"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return false
+	}
+	if !params.ValidateBuildDefinition {
+		return true
+	}
+	_, issues := builddef.Validate(buildDef)
+	if !builddef.HasErrors(issues) {
+		return true
+	}
+	var errs []string
+	for _, issue := range issues {
+		if issue.Severity == builddef.SeverityError {
+			errs = append(errs, issue.String())
+		}
+	}
+	ginutil.WriteProblem(c, problems.ProjectBuildDefinitionInvalid.With(problem.Response{
+		Detail: fmt.Sprintf(
+			"The build definition has %d validation error(s). Use POST /api/project/{projectId}/build-definition/validate for the full list of issues.",
+			len(errs)),
+		Instance: c.Request.RequestURI + "#buildDefinition",
+		Errors:   errs,
+	}))
+	return false
+}
+
 func fetchProjectByID(c *gin.Context, db *gorm.DB, projectID uint, whenMsg string) (database.Project, bool) {
 	var dbProject database.Project
 	ok := fetchDatabaseObjByID(c, databaseProjectPreloaded(db), &dbProject, projectID, "project", whenMsg)
@@ -443,6 +810,18 @@ func fetchProjectByIDSlim(c *gin.Context, db *gorm.DB, projectID uint, whenMsg s
 	return dbProject, ok
 }
 
+// validateProjectNotArchived writes a problem response and returns false if
+// the project is archived, as archived projects cannot start new builds.
+func validateProjectNotArchived(c *gin.Context, dbProject database.Project) bool {
+	if !dbProject.IsArchived {
+		return true
+	}
+	ginutil.WriteProblemError(c, errors.New("project is archived"), problems.ProjectRunArchived.Newf(
+		"Project with ID %d is archived, and cannot start new builds. Unarchive the project to start builds again.",
+		dbProject.ProjectID))
+	return false
+}
+
 func validateProjectExistsByID(c *gin.Context, db *gorm.DB, projectID uint, whenMsg string) bool {
 	return validateDatabaseObjExistsByID(c, db, &database.Project{}, projectID, "project", whenMsg)
 }
@@ -454,7 +833,8 @@ func databaseProjectPreloaded(db *gorm.DB) *gorm.DB {
 			return db.Order(database.BranchColumns.BranchID)
 		}).
 		Preload(database.ProjectFields.Token).
-		Preload(database.ProjectFields.Overrides)
+		Preload(database.ProjectFields.Overrides).
+		Preload(database.ProjectFields.Labels)
 }
 
 func (m projectModule) getBuildsCount(projectID uint) (int64, error) {
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+type projectArtifactModule struct {
+	Projects  ProjectRepo
+	Artifacts ArtifactRepo
+}
+
+func (m projectArtifactModule) Register(g *gin.RouterGroup) {
+	g.GET("/project/:projectId/artifact", m.getProjectArtifactListHandler)
+}
+
+// getProjectArtifactListHandler godoc
+// @id getProjectArtifactList
+// @summary Get list of artifacts of all builds of a project
+// @description List the artifacts of all of the project's builds, together with a summary of the build each artifact belongs to,
+// @description such as to find the latest artifact with a given file name without first paging through the builds.
+// @description Takes the same filters as GET /build/{buildId}/artifact. The artifacts are downloaded via GET /build/{buildId}/artifact/{artifactId}.
+// @description Added in v5.3.0.
+// @tags artifact
+// @produce json
+// @param projectId path uint true "Project ID" minimum(0)
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable. Required if `offset` is used." default(100)
+// @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1."
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Defaults to `?orderby=artifactId desc`"
+// @param name query string false "Filter by verbatim artifact name."
+// @param fileName query string false "Filter by verbatim artifact file name."
+// @param nameMatch query string false "Filter by matching artifact name. Cannot be used with `name`."
+// @param fileNameMatch query string false "Filter by matching artifact file name. Cannot be used with `fileName`."
+// @param match query string false "Filter by matching on any supported fields."
+// @param minSize query int false "Filter by artifacts with at least this many bytes." minimum(0)
+// @param maxSize query int false "Filter by artifacts with at most this many bytes." minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedProjectArtifacts
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/artifact [get]
+func (m projectArtifactModule) getProjectArtifactListHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	opts, ok := bindArtifactListOptions(c)
+	if !ok {
+		return
+	}
+	if !validateRepoObjExistsByID(c, m.Projects.Exists, projectID, "project", "when listing artifacts of project") {
+		return
+	}
+
+	dbArtifacts, totalCount, err := m.Artifacts.ListByProject(projectID, opts)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching list of artifacts for project with ID %d from database.",
+			projectID,
+		))
+		return
+	}
+
+	ginrender.Response(c, http.StatusOK, response.PaginatedProjectArtifacts{
+		List:       modelconv.DBArtifactsToResponseProjectArtifacts(dbArtifacts),
+		TotalCount: totalCount,
+	})
+}
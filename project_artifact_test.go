@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProjectArtifactListHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbBuilds := []database.Build{
+		{BuildID: 1, ProjectID: 1, GitBranch: "master", StatusID: database.BuildCompleted},
+		{BuildID: 2, ProjectID: 1, GitBranch: "feature", StatusID: database.BuildFailed},
+		{BuildID: 3, ProjectID: 2, GitBranch: "master", StatusID: database.BuildCompleted},
+	}
+	artifacts := &fakeArtifactRepo{artifacts: []database.Artifact{
+		{ArtifactID: 1, BuildID: 1, Build: &dbBuilds[0], Name: "coverage", FileName: "coverage.html"},
+		{ArtifactID: 2, BuildID: 1, Build: &dbBuilds[0], Name: "results", FileName: "results.trx"},
+		{ArtifactID: 3, BuildID: 2, Build: &dbBuilds[1], Name: "coverage", FileName: "coverage.html"},
+		{ArtifactID: 4, BuildID: 3, Build: &dbBuilds[2], Name: "coverage", FileName: "coverage.html"},
+	}}
+	r := gin.New()
+	projectArtifactModule{
+		Projects:  &fakeProjectRepo{projects: []database.Project{{ProjectID: 1}, {ProjectID: 2}}},
+		Artifacts: artifacts,
+	}.Register(r.Group("/api"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/project/1/artifact?fileNameMatch=coverage&limit=1", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res response.PaginatedProjectArtifacts
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.List, 1)
+	assert.Equal(t, int64(2), res.TotalCount)
+	assert.Equal(t, uint(3), res.List[0].ArtifactID)
+	assert.Equal(t, "feature", res.List[0].Build.GitBranch)
+	assert.Equal(t, response.BuildFailed, res.List[0].Build.Status)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/project/3/artifact", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "/prob/api/record-not-found")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// getProjectEnginesHandler godoc
+// @id getProjectEngines
+// @summary Get the allowed execution engines of a project.
+// @description Returns the IDs of the execution engines that the project is allowed to start builds on.
+// @description An empty list means that the project is allowed to start builds on any execution engine.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectEngines
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/engines [get]
+func (m projectModule) getProjectEnginesHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching allowed engines") {
+		return
+	}
+	dbEngines, err := findProjectEngines(m.Database, projectID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching allowed engines of project with ID %d from database.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectEnginesToResponse(dbEngines))
+}
+
+// updateProjectEnginesHandler godoc
+// @id updateProjectEngines
+// @summary Set the allowed execution engines of a project.
+// @description Replaces the list of execution engines that the project is allowed to start builds on.
+// @description Starting a build on any other execution engine, including when it is the default execution engine, is rejected.
+// @description Use this to prevent a project's builds from accidentally running on an execution engine it is not meant for,
+// @description such as running production deployments on an experimental execution engine.
+// @description Setting an empty list allows the project to start builds on any execution engine.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param engines body request.ProjectEngines true "Allowed execution engines of the project"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectEngines
+// @failure 400 {object} problem.Response "Bad request, such as unknown execution engine IDs"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/engines [put]
+func (m projectModule) updateProjectEnginesHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqEngines request.ProjectEngines
+	if err := c.ShouldBindJSON(&reqEngines); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for the allowed engines.")
+		return
+	}
+	engineIDs, ok := m.validateReqProjectEngines(c, reqEngines)
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when updating allowed engines") {
+		return
+	}
+	dbEngines := make([]database.ProjectEngine, len(engineIDs))
+	for i, engineID := range engineIDs {
+		dbEngines[i] = database.ProjectEngine{ProjectID: projectID, EngineID: engineID}
+	}
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(&database.ProjectEngine{ProjectID: projectID}, database.ProjectEngineFields.ProjectID).
+			Delete(&database.ProjectEngine{}).
+			Error; err != nil {
+			return err
+		}
+		if len(dbEngines) == 0 {
+			return nil
+		}
+		return tx.Create(&dbEngines).Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed updating allowed engines of project with ID %d in database.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectEnginesToResponse(dbEngines))
+}
+
+// validateReqProjectEngines returns the sorted and deduplicated engine IDs,
+// as written in the wharf-api configuration, or writes an "invalid param"
+// problem and returns false if any of the engine IDs are not configured.
+func (m projectModule) validateReqProjectEngines(c *gin.Context, reqEngines request.ProjectEngines) ([]string, bool) {
+	ciConf := m.Config.current().CI
+	var (
+		engineIDs []string
+		unknown   []string
+	)
+	for _, engineID := range reqEngines.EngineIDs {
+		engine, ok := lookupEngineFromConfig(ciConf, engineID)
+		if engineID == "" || !ok {
+			unknown = append(unknown, fmt.Sprintf("%q", engineID))
+			continue
+		}
+		engineIDs = append(engineIDs, engine.ID)
+	}
+	if unknown = uniqueStrings(unknown); len(unknown) > 0 {
+		err := fmt.Errorf("unknown engines by ID: %s", strings.Join(unknown, ", "))
+		ginutil.WriteInvalidParamError(c, err, "engineIds", fmt.Sprintf(
+			"No execution engines were found by the IDs: %s.",
+			strings.Join(unknown, ", ")))
+		return nil, false
+	}
+	engineIDs = uniqueStrings(engineIDs)
+	sort.Strings(engineIDs)
+	return engineIDs, true
+}
+
+func findProjectEngines(db *gorm.DB, projectID uint) ([]database.ProjectEngine, error) {
+	var dbEngines []database.ProjectEngine
+	err := db.
+		Where(&database.ProjectEngine{ProjectID: projectID}, database.ProjectEngineFields.ProjectID).
+		Order(database.ProjectEngineColumns.EngineID).
+		Find(&dbEngines).
+		Error
+	return dbEngines, err
+}
+
+// isProjectEngineAllowed returns false if the project has a list of allowed
+// execution engines that does not contain the given engine.
+func isProjectEngineAllowed(db *gorm.DB, projectID uint, engine CIEngineConfig) (bool, error) {
+	dbEngines, err := findProjectEngines(db, projectID)
+	if err != nil {
+		return false, err
+	}
+	if len(dbEngines) == 0 {
+		return true, nil
+	}
+	for _, dbEngine := range dbEngines {
+		if strings.EqualFold(dbEngine.EngineID, engine.ID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validateProjectEngineAllowed writes a problem and returns false if the
+// project has a list of allowed execution engines that does not contain the
+// given engine.
+func validateProjectEngineAllowed(c *gin.Context, db *gorm.DB, projectID uint, engine CIEngineConfig) bool {
+	dbEngines, err := findProjectEngines(db, projectID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching allowed engines of project with ID %d from database.",
+			projectID))
+		return false
+	}
+	if len(dbEngines) == 0 {
+		return true
+	}
+	allowed := make([]string, len(dbEngines))
+	for i, dbEngine := range dbEngines {
+		if strings.EqualFold(dbEngine.EngineID, engine.ID) {
+			return true
+		}
+		allowed[i] = fmt.Sprintf("%q", dbEngine.EngineID)
+	}
+	ginutil.WriteProblemError(c, errors.New("engine not allowed for project"), problems.ProjectRunEngineNotAllowed.Newf(
+		"Project with ID %d is not allowed to start builds on the execution engine %q. Allowed execution engines: %s.",
+		projectID, engine.ID, strings.Join(allowed, ", ")))
+	return false
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateProjectEnginesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestSqliteDB(t)
+	dbProject := database.Project{Name: "wharf-api"}
+	require.NoError(t, db.Create(&dbProject).Error)
+	config := &Config{CI: CIConfig{
+		Engine:  CIEngineConfig{ID: "jenkins", URL: "http://jenkins.example.com"},
+		Engine2: CIEngineConfig{ID: "wharf-cmd", URL: "http://wharf-cmd.example.com"},
+	}}
+
+	r := gin.New()
+	projectModule{Database: db, Config: config}.Register(r.Group("/api"))
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut,
+			fmt.Sprintf("/api/project/%d/engines", dbProject.ProjectID), strings.NewReader(body)))
+		return w
+	}
+	get := func() response.ProjectEngines {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+			fmt.Sprintf("/api/project/%d/engines", dbProject.ProjectID), nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resEngines response.ProjectEngines
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resEngines))
+		return resEngines
+	}
+
+	assert.Empty(t, get().EngineIDs)
+
+	w := put(`{"engineIds":["WHARF-CMD","jenkins","wharf-cmd"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"jenkins", "wharf-cmd"}, get().EngineIDs)
+
+	assert.Equal(t, http.StatusBadRequest, put(`{"engineIds":["jenkins","experimental"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{"engineIds":[""]}`).Code)
+	assert.Equal(t, []string{"jenkins", "wharf-cmd"}, get().EngineIDs, "failed updates must keep the engines")
+
+	w = put(`{"engineIds":[]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, get().EngineIDs)
+}
+
+func TestValidateProjectEngineAllowed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestSqliteDB(t)
+	dbProject := database.Project{Name: "wharf-api"}
+	require.NoError(t, db.Create(&dbProject).Error)
+	jenkins := CIEngineConfig{ID: "jenkins"}
+	wharfCmd := CIEngineConfig{ID: "wharf-cmd"}
+
+	validate := func(engine CIEngineConfig) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		if validateProjectEngineAllowed(c, db, dbProject.ProjectID, engine) {
+			c.Status(http.StatusOK)
+		}
+		c.Writer.WriteHeaderNow()
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, validate(wharfCmd).Code, "no allowed engines must allow any engine")
+
+	require.NoError(t, db.Create(&database.ProjectEngine{ProjectID: dbProject.ProjectID, EngineID: "jenkins"}).Error)
+	assert.Equal(t, http.StatusOK, validate(jenkins).Code)
+
+	w := validate(wharfCmd)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), problems.ProjectRunEngineNotAllowed.Type)
+	assert.Contains(t, w.Body.String(), `Allowed execution engines: \"jenkins\".`)
+}
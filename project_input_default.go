@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+// getProjectInputDefaultsHandler godoc
+// @id getProjectInputDefaults
+// @summary Get the input defaults of a project.
+// @description Returns the project's own default values of the input variables declared in its build definition.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectInputDefaults
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/input-defaults [get]
+func (m projectModule) getProjectInputDefaultsHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching input defaults") {
+		return
+	}
+	var dbInputDefaults []database.ProjectInputDefault
+	if err := m.Database.
+		Where(&database.ProjectInputDefault{ProjectID: projectID}, database.ProjectInputDefaultFields.ProjectID).
+		Find(&dbInputDefaults).
+		Error; err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching input defaults of project with ID %d from database.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectInputDefaultsToResponse(dbInputDefaults))
+}
+
+// updateProjectInputDefaultsHandler godoc
+// @id updateProjectInputDefaults
+// @summary Set the input defaults of a project.
+// @description Replaces all the project's own default values of the input variables declared in its build definition.
+// @description When starting a build, values given in the request take precedence over the project's default values for the build's environment,
+// @description which take precedence over the project's default values for any environment, which in turn take precedence over the default values from the build definition.
+// @description Default values of input variables that are not declared in the build definition are ignored.
+// @description The default values are stored in plain text, and should not be used for secret inputs.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param inputDefaults body request.ProjectInputDefaults true "Input defaults of the project"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectInputDefaults
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/input-defaults [put]
+func (m projectModule) updateProjectInputDefaultsHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqInputDefaults request.ProjectInputDefaults
+	if err := c.ShouldBindJSON(&reqInputDefaults); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for the input defaults.")
+		return
+	}
+	if !validateReqProjectInputDefaults(c, reqInputDefaults) {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when updating input defaults") {
+		return
+	}
+	dbInputDefaults := modelconv.ReqProjectInputDefaultsToDatabase(reqInputDefaults, projectID)
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(&database.ProjectInputDefault{ProjectID: projectID}, database.ProjectInputDefaultFields.ProjectID).
+			Delete(&database.ProjectInputDefault{}).
+			Error; err != nil {
+			return err
+		}
+		if len(dbInputDefaults) == 0 {
+			return nil
+		}
+		return tx.Create(&dbInputDefaults).Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed updating input defaults of project with ID %d in database.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectInputDefaultsToResponse(dbInputDefaults))
+}
+
+// validateReqProjectInputDefaults writes an "invalid param" problem and
+// returns false if any of the input or environment names are empty, or are
+// too long to fit in the database.
+func validateReqProjectInputDefaults(c *gin.Context, reqInputDefaults request.ProjectInputDefaults) bool {
+	var errs []string
+	validateNames := func(prefix string, inputs map[string]string) {
+		for name := range inputs {
+			switch {
+			case name == "":
+				errs = append(errs, prefix+"input name must not be empty")
+			case utf8.RuneCountInString(name) > database.ProjectInputDefaultSizes.Name:
+				errs = append(errs, fmt.Sprintf("%s%s: input name must not be longer than %d characters",
+					prefix, name, database.ProjectInputDefaultSizes.Name))
+			}
+		}
+	}
+	validateNames("", reqInputDefaults.Inputs)
+	for env, inputs := range reqInputDefaults.Environments {
+		switch {
+		case env == "":
+			errs = append(errs, "environment name must not be empty")
+			continue
+		case utf8.RuneCountInString(env) > database.ProjectInputDefaultSizes.Environment:
+			errs = append(errs, fmt.Sprintf("%s: environment name must not be longer than %d characters",
+				env, database.ProjectInputDefaultSizes.Environment))
+			continue
+		}
+		validateNames(env+": ", inputs)
+	}
+	if len(errs) == 0 {
+		return true
+	}
+	sort.Strings(errs)
+	prob := problems.InvalidParam.New("One or more input defaults are invalid.")
+	prob.Errors = errs
+	c.Error(errors.New(strings.Join(errs, "; ")))
+	ginutil.WriteProblem(c, prob)
+	return false
+}
+
+// findProjectInputDefaults returns the project's own default values of its
+// input variables that apply to builds on the given environment, where the
+// environment's default values take precedence over the ones for any
+// environment.
+func findProjectInputDefaults(db *gorm.DB, projectID uint, environment null.String) (map[string]string, error) {
+	envNames := []string{""}
+	if environment.Valid && environment.String != "" {
+		envNames = append(envNames, environment.String)
+	}
+	var dbInputDefaults []database.ProjectInputDefault
+	if err := db.
+		Where(&database.ProjectInputDefault{ProjectID: projectID}, database.ProjectInputDefaultFields.ProjectID).
+		Where(database.ProjectInputDefaultColumns.Environment+" IN ?", asAnySlice(envNames)).
+		Find(&dbInputDefaults).
+		Error; err != nil {
+		return nil, err
+	}
+	// Sorts the environment specific default values last, so they take
+	// precedence when added to the map.
+	sort.SliceStable(dbInputDefaults, func(i, j int) bool {
+		return dbInputDefaults[i].Environment == "" && dbInputDefaults[j].Environment != ""
+	})
+	inputDefaults := make(map[string]string, len(dbInputDefaults))
+	for _, dbInputDefault := range dbInputDefaults {
+		inputDefaults[dbInputDefault.Name] = dbInputDefault.Value
+	}
+	return inputDefaults, nil
+}
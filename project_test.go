@@ -1,9 +1,13 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v4"
@@ -57,7 +61,7 @@ inputs:
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := parseDBBuildParams(tc.buildID, tc.buildDef, tc.params)
+			got, err := parseDBBuildParams(tc.buildID, tc.buildDef, nil, tc.params)
 			require.Nil(t, err)
 			assert.Equal(t, len(tc.want), len(got))
 
@@ -68,6 +72,112 @@ inputs:
 	}
 }
 
+func TestParseBuildParams_coercesTypes(t *testing.T) {
+	buildDef := []byte(`
+inputs:
+- name: count
+  type: number
+- name: dryRun
+  type: boolean
+  default: false
+- name: level
+  type: choice
+  values: [debug, info]
+  default: info
+`)
+	got, err := parseDBBuildParams(1, buildDef, nil, []byte(`{"count":"1.50","dryRun":"TRUE"}`))
+	require.NoError(t, err)
+
+	want := []database.BuildParam{
+		{BuildID: 1, Name: "count", Value: "1.5"},
+		{BuildID: 1, Name: "dryRun", Value: "true"},
+		{BuildID: 1, Name: "level", Value: "info"},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestParseBuildParams_invalidInputs(t *testing.T) {
+	buildDef := []byte(`
+inputs:
+- name: count
+  type: number
+- name: level
+  type: choice
+  values: [debug, info]
+`)
+	_, err := parseDBBuildParams(1, buildDef, nil, []byte(`{"count":"many","level":"trace","foo":"bar"}`))
+
+	var inputsErr invalidBuildInputsError
+	require.ErrorAs(t, err, &inputsErr)
+	want := []string{
+		`unknown input "foo", allowed inputs are: count, level`,
+		`input "count": expected a number, but got "many"`,
+		`input "level": value "trace" is not allowed, allowed values are: debug, info`,
+	}
+	assert.Equal(t, want, inputsErr.problems)
+}
+
+func TestParseBuildParams_projectInputDefaults(t *testing.T) {
+	buildDef := []byte(`
+inputs:
+- name: registryUrl
+  type: string
+  default: registry.example.com
+- name: replicas
+  type: number
+  default: 1
+- name: dryRun
+  type: boolean
+  default: false
+`)
+	inputDefaults := map[string]string{
+		"registryUrl": "prod.registry.example.com",
+		"replicas":    "3",
+		"unknown":     "ignored",
+	}
+	got, err := parseDBBuildParams(1, buildDef, inputDefaults, []byte(`{"replicas":5}`))
+	require.NoError(t, err)
+
+	want := []database.BuildParam{
+		{BuildID: 1, Name: "registryUrl", Value: "prod.registry.example.com"},
+		{BuildID: 1, Name: "replicas", Value: "5"},
+		{BuildID: 1, Name: "dryRun", Value: "false"},
+	}
+	assert.Equal(t, want, got)
+
+	_, err = parseDBBuildParams(1, buildDef, map[string]string{"dryRun": "maybe"}, nil)
+	var inputsErr invalidBuildInputsError
+	require.ErrorAs(t, err, &inputsErr)
+	assert.Equal(t, []string{`input "dryRun": project default: expected a boolean, but got "maybe"`}, inputsErr.problems)
+}
+
+func TestParseBuildParams_secretInputs(t *testing.T) {
+	buildDef := []byte(`
+inputs:
+- name: apiKey
+  type: password
+- name: user
+  type: string
+`)
+	got, err := parseDBBuildParams(1, buildDef, nil, []byte(`{"apiKey":"hunter2","user":"admin"}`))
+	require.NoError(t, err)
+
+	want := []database.BuildParam{
+		{BuildID: 1, Name: "apiKey", Value: "hunter2", IsSecret: true},
+		{BuildID: 1, Name: "user", Value: "admin"},
+	}
+	assert.Equal(t, want, got)
+
+	params, err := getDBJobParams(database.Project{}, database.Build{}, got, "")
+	require.NoError(t, err)
+	for _, param := range params {
+		if param.Name == "VARS" {
+			assert.Equal(t, "password", param.Type)
+			assert.Contains(t, param.Value, "hunter2")
+		}
+	}
+}
+
 func TestGetParamsWithOptionalEnvironment(t *testing.T) {
 	type testCase struct {
 		name        string
@@ -112,3 +222,60 @@ func TestGetParamsWithOptionalEnvironment(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCommonOrderBySlice_projects(t *testing.T) {
+	testCases := []struct {
+		order string
+		want  database.SafeSQLName
+	}{
+		{order: "projectId asc", want: database.ProjectColumns.ProjectID},
+		{order: "name asc", want: database.ProjectColumns.Name},
+		{order: "groupName desc", want: database.ProjectColumns.GroupName},
+		{order: "description asc", want: database.ProjectColumns.Description},
+		{order: "gitUrl desc", want: database.ProjectColumns.GitURL},
+		{order: "createdAt asc", want: database.TimeMetadataColumns.CreatedAt},
+		{order: "updatedAt desc", want: database.TimeMetadataColumns.UpdatedAt},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.order, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			orderBySlice, ok := parseCommonOrderBySlice(c, []string{tc.order}, projectJSONToColumns)
+			require.True(t, ok)
+			require.Len(t, orderBySlice, 1)
+			assert.Equal(t, tc.want, orderBySlice[0].Name)
+		})
+	}
+}
+
+func TestParseCommonOrderBySlice_rejectsFieldsOfOtherEndpoints(t *testing.T) {
+	for _, order := range []string{"url asc", "tokenId asc", "buildId asc"} {
+		t.Run(order, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/project", nil)
+			_, ok := parseCommonOrderBySlice(c, []string{order}, projectJSONToColumns)
+			assert.False(t, ok)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestParseCommonOrderBySlice_buildExpressions(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	orderBySlice, ok := parseCommonOrderBySliceWithExpressions(c, []string{"projectName asc", "testsFailed desc", "buildId desc"}, buildJSONToColumns, buildJSONToExpressions)
+	require.True(t, ok)
+	require.Len(t, orderBySlice, 3)
+	assert.True(t, orderBySlice[0].IsExpression, "projectName")
+	assert.True(t, orderBySlice[1].IsExpression, "testsFailed")
+	assert.Equal(t, orderby.Column{Name: database.BuildColumns.BuildID, Direction: orderby.Desc}, orderBySlice[2])
+}
+
+func TestParseCommonOrderBySlice_problemDetail(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/project", nil)
+	_, ok := parseCommonOrderBySlice(c, []string{"name asc,-projectId", "gitUrl DESCC"}, projectJSONToColumns)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `Invalid value \"DESCC\" in sort ordering number 3, \"gitUrl DESCC\"`)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// transferProjectHandler godoc
+// @id transferProject
+// @summary Transfer project to another group, provider, or token.
+// @description Moves the project to a new group, and/or a new provider and
+// @description token, in a single transaction. As opposed to updating the
+// @description project via PUT, all other fields of the project are kept,
+// @description and any project groups of the new group name are added.
+// @description Changing the provider clears the project's remote project ID,
+// @description as it identified the project in the previous provider, so that
+// @description the next provider sync does not mistake it for another project.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param transfer body request.ProjectTransfer true "New group, provider, and/or token"
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Project
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
+// @failure 400 {object} problem.Response "Bad request, such as no fields to transfer"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project, provider, or token not found"
+// @failure 412 {object} problem.Response "Project was modified since it was fetched, as the If-Match header did not match"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/transfer [post]
+func (m projectModule) transferProjectHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqTransfer request.ProjectTransfer
+	if err := c.ShouldBindJSON(&reqTransfer); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for project transfer.")
+		return
+	}
+	if reqTransfer.GroupName == nil && reqTransfer.ProviderID == nil && reqTransfer.TokenID == nil {
+		writeInvalidBindError(c, errors.New("no fields to transfer"),
+			"At least one of groupName, providerId, or tokenId must be set when transferring a project.")
+		return
+	}
+	if id := ptrconv.UintPtr(reqTransfer.ProviderID); id != 0 &&
+		!validateDatabaseObjExistsByID(c, m.Database, &database.Provider{}, id, "provider", "when transferring project") {
+		return
+	}
+	if id := ptrconv.UintPtr(reqTransfer.TokenID); id != 0 &&
+		!validateDatabaseObjExistsByID(c, m.Database, &database.Token{}, id, "token", "when transferring project") {
+		return
+	}
+	// Not preloaded, as saving the preloaded provider and token would reset
+	// the foreign keys to the previous provider and token.
+	dbProject, ok := fetchProjectByIDSlim(c, m.Database, projectID, "when transferring project")
+	if !ok {
+		return
+	}
+	if !validateIfMatch(c, dbProject.Version, "project") {
+		return
+	}
+
+	prevGroupName := dbProject.GroupName
+	prevProviderID := ptrconv.UintPtr(dbProject.ProviderID)
+	prevTokenID := ptrconv.UintPtr(dbProject.TokenID)
+	if reqTransfer.GroupName != nil {
+		dbProject.GroupName = *reqTransfer.GroupName
+	}
+	if reqTransfer.ProviderID != nil {
+		dbProject.ProviderID = ptrconv.UintZeroNil(*reqTransfer.ProviderID)
+		if *reqTransfer.ProviderID != prevProviderID {
+			dbProject.RemoteProjectID = ""
+		}
+	}
+	if reqTransfer.TokenID != nil {
+		dbProject.TokenID = ptrconv.UintZeroNil(*reqTransfer.TokenID)
+	}
+
+	var saved bool
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		saved, err = saveVersioned(tx, &dbProject, &dbProject.Version,
+			database.ProjectColumns.Version, hasIfMatchHeader(c))
+		if err != nil || !saved {
+			return err
+		}
+		if err := ensureProjectGroups(tx, dbProject.GroupName); err != nil {
+			return err
+		}
+		actorType, actorName, actorSubject := getUserFromContext(c)
+		return tx.Create(&database.ProjectTransfer{
+			ProjectID:      projectID,
+			FromGroupName:  prevGroupName,
+			ToGroupName:    dbProject.GroupName,
+			FromProviderID: prevProviderID,
+			ToProviderID:   ptrconv.UintPtr(dbProject.ProviderID),
+			FromTokenID:    prevTokenID,
+			ToTokenID:      ptrconv.UintPtr(dbProject.TokenID),
+			Timestamp:      time.Now().UTC(),
+			ActorType:      actorType,
+			ActorName:      truncateString(actorName, database.ProjectTransferSizes.ActorName),
+			ActorSubject:   truncateString(actorSubject, database.ProjectTransferSizes.ActorSubject),
+		}).Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed transferring project with ID %d to group %q in database.",
+			projectID, dbProject.GroupName))
+		return
+	}
+	if !saved {
+		writePreconditionFailedProblem(c, "project")
+		return
+	}
+	requestLog(c).Info().
+		WithUint("project", projectID).
+		WithString("fromGroup", prevGroupName).
+		WithString("toGroup", dbProject.GroupName).
+		WithUint("fromProvider", prevProviderID).
+		WithUint("toProvider", ptrconv.UintPtr(dbProject.ProviderID)).
+		WithUint("fromToken", prevTokenID).
+		WithUint("toToken", ptrconv.UintPtr(dbProject.TokenID)).
+		Message("Transferred project.")
+
+	dbProject, ok = fetchProjectByID(c, m.Database, projectID, "after transferring project")
+	if !ok {
+		return
+	}
+	setETagHeader(c, dbProject.Version)
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectToResponse(dbProject))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferProjectHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestSqliteDB(t)
+	dbOldProvider := database.Provider{Name: "gitlab", URL: "https://gitlab.example.com"}
+	dbNewProvider := database.Provider{Name: "github", URL: "https://github.com"}
+	require.NoError(t, db.Create(&dbOldProvider).Error)
+	require.NoError(t, db.Create(&dbNewProvider).Error)
+	dbToken := database.Token{Value: "secret", UserName: "wharf"}
+	require.NoError(t, db.Create(&dbToken).Error)
+	dbProject := database.Project{
+		Name:            "wharf-api",
+		GroupName:       "default",
+		ProviderID:      &dbOldProvider.ProviderID,
+		RemoteProjectID: "123",
+		Description:     "API",
+	}
+	require.NoError(t, db.Create(&dbProject).Error)
+	require.NoError(t, db.Create(&database.ProjectOverrides{ProjectID: dbProject.ProjectID, Description: "Overridden"}).Error)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(gin.AuthUserKey, "jane") })
+	projectModule{Database: db}.Register(r.Group("/api"))
+	transfer := func(body string, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost,
+			fmt.Sprintf("/api/project/%d/transfer", dbProject.ProjectID), strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, transfer(`{}`, "").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, transfer(`{"groupName":"iver-wharf"}`, `"999"`).Code)
+
+	w := transfer(fmt.Sprintf(`{"groupName":"iver/wharf","providerId":%d,"tokenId":%d}`,
+		dbNewProvider.ProviderID, dbToken.TokenID), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resProject response.Project
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resProject))
+	assert.Equal(t, "iver/wharf", resProject.GroupName)
+	assert.Equal(t, "Overridden", resProject.Description, "overrides must be kept")
+
+	var gotProject database.Project
+	require.NoError(t, db.First(&gotProject, dbProject.ProjectID).Error)
+	assert.Equal(t, "wharf-api", gotProject.Name)
+	assert.Equal(t, &dbNewProvider.ProviderID, gotProject.ProviderID)
+	assert.Equal(t, &dbToken.TokenID, gotProject.TokenID)
+	assert.Empty(t, gotProject.RemoteProjectID, "remote ID of previous provider must be cleared")
+
+	var groupCount int64
+	require.NoError(t, db.Model(&database.ProjectGroup{}).Count(&groupCount).Error)
+	assert.Equal(t, int64(2), groupCount, "groups iver and iver/wharf")
+
+	var dbTransfers []database.ProjectTransfer
+	require.NoError(t, db.Find(&dbTransfers).Error)
+	require.Len(t, dbTransfers, 1, "only the successful transfer must be recorded")
+	dbTransfer := dbTransfers[0]
+	assert.Equal(t, dbProject.ProjectID, dbTransfer.ProjectID)
+	assert.Equal(t, "default", dbTransfer.FromGroupName)
+	assert.Equal(t, "iver/wharf", dbTransfer.ToGroupName)
+	assert.Equal(t, dbOldProvider.ProviderID, dbTransfer.FromProviderID)
+	assert.Equal(t, dbNewProvider.ProviderID, dbTransfer.ToProviderID)
+	assert.Zero(t, dbTransfer.FromTokenID)
+	assert.Equal(t, dbToken.TokenID, dbTransfer.ToTokenID)
+	assert.Equal(t, database.BuildTriggerBasicAuth, dbTransfer.ActorType)
+	assert.Equal(t, "jane", dbTransfer.ActorName)
+	assert.WithinDuration(t, time.Now(), dbTransfer.Timestamp, time.Minute)
+
+	w = transfer(`{"providerId":999}`, "")
+	assert.NotEqual(t, http.StatusOK, w.Code, "unknown provider")
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gorm.io/gorm"
+)
+
+type projectTriggerModule struct {
+	Database *gorm.DB
+}
+
+func (m projectTriggerModule) Register(g *gin.RouterGroup) {
+	projectTrigger := g.Group("/project/:projectId/trigger")
+	{
+		projectTrigger.GET("", m.getProjectTriggerListHandler)
+		projectTrigger.POST("", m.createProjectTriggerHandler)
+
+		triggerByID := projectTrigger.Group("/:triggerId")
+		{
+			triggerByID.GET("", m.getProjectTriggerHandler)
+			triggerByID.PUT("", m.updateProjectTriggerHandler)
+			triggerByID.DELETE("", m.deleteProjectTriggerHandler)
+		}
+	}
+}
+
+// getProjectTriggerListHandler godoc
+// @id getProjectTriggerList
+// @summary Get list of build triggers.
+// @description List all build triggers that start builds of other projects
+// @description when a build of this project completes successfully.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.PaginatedBuildTriggers "Build triggers"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/trigger [get]
+func (m projectTriggerModule) getProjectTriggerListHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when fetching list of build triggers for project") {
+		return
+	}
+	var dbTriggers []database.BuildTrigger
+	err := m.Database.
+		Where(&database.BuildTrigger{SourceProjectID: projectID}, database.BuildTriggerFields.SourceProjectID).
+		Order(database.BuildTriggerColumns.BuildTriggerID).
+		Find(&dbTriggers).Error
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching list of build triggers for project with ID %d.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, response.PaginatedBuildTriggers{
+		List:       modelconv.DBBuildTriggersToResponses(dbTriggers),
+		TotalCount: int64(len(dbTriggers)),
+	})
+}
+
+// getProjectTriggerHandler godoc
+// @id getProjectTrigger
+// @summary Get build trigger.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param triggerId path uint true "build trigger ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildTrigger "Build trigger"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build trigger not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/trigger/{triggerId} [get]
+func (m projectTriggerModule) getProjectTriggerHandler(c *gin.Context) {
+	dbTrigger, ok := m.fetchTriggerFromParams(c, "")
+	if !ok {
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildTriggerToResponse(dbTrigger))
+}
+
+// createProjectTriggerHandler godoc
+// @id createProjectTrigger
+// @summary Add build trigger to project.
+// @description Adds a build trigger that starts a build of the target project,
+// @description on its default branch, whenever a build of this project
+// @description completes successfully on a branch matching the branch filter.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param trigger body request.BuildTrigger true "Build trigger object"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 201 {object} response.BuildTrigger "Created build trigger"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Source or target project not found"
+// @failure 409 {object} problem.Response "Build trigger would create a cycle"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/trigger [post]
+func (m projectTriggerModule) createProjectTriggerHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqTrigger request.BuildTrigger
+	if err := c.ShouldBindJSON(&reqTrigger); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for build trigger object to create.")
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when creating build trigger for project") {
+		return
+	}
+	dbTrigger := modelconv.ReqBuildTriggerToDatabase(projectID, reqTrigger)
+	if !m.validateTrigger(c, dbTrigger) {
+		return
+	}
+	if err := m.Database.Create(&dbTrigger).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed creating build trigger of project with ID %d for project with ID %d.",
+			reqTrigger.TargetProjectID, projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusCreated, modelconv.DBBuildTriggerToResponse(dbTrigger))
+}
+
+// updateProjectTriggerHandler godoc
+// @id updateProjectTrigger
+// @summary Update build trigger.
+// @description Updates a build trigger by replacing all of its fields.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param triggerId path uint true "build trigger ID" minimum(0)
+// @param trigger body request.BuildTriggerUpdate true "New build trigger values"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildTrigger "Updated build trigger"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build trigger or target project not found"
+// @failure 409 {object} problem.Response "Build trigger would create a cycle"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/trigger/{triggerId} [put]
+func (m projectTriggerModule) updateProjectTriggerHandler(c *gin.Context) {
+	var reqTriggerUpdate request.BuildTriggerUpdate
+	if err := c.ShouldBindJSON(&reqTriggerUpdate); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for build trigger object to update.")
+		return
+	}
+	dbTrigger, ok := m.fetchTriggerFromParams(c, "when updating build trigger")
+	if !ok {
+		return
+	}
+	dbTrigger.TargetProjectID = reqTriggerUpdate.TargetProjectID
+	dbTrigger.BranchFilter = reqTriggerUpdate.BranchFilter
+	dbTrigger.Stage = reqTriggerUpdate.Stage
+	if !m.validateTrigger(c, dbTrigger) {
+		return
+	}
+	if err := m.Database.Save(&dbTrigger).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed writing build trigger with ID %d to database.",
+			dbTrigger.BuildTriggerID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBBuildTriggerToResponse(dbTrigger))
+}
+
+// deleteProjectTriggerHandler godoc
+// @id deleteProjectTrigger
+// @summary Delete build trigger.
+// @description Added in v5.3.0.
+// @tags project
+// @param projectId path uint true "project ID" minimum(0)
+// @param triggerId path uint true "build trigger ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build trigger not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/trigger/{triggerId} [delete]
+func (m projectTriggerModule) deleteProjectTriggerHandler(c *gin.Context) {
+	dbTrigger, ok := m.fetchTriggerFromParams(c, "when deleting build trigger")
+	if !ok {
+		return
+	}
+	if err := m.Database.Delete(&dbTrigger).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting build trigger with ID %d from database.",
+			dbTrigger.BuildTriggerID))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (m projectTriggerModule) fetchTriggerFromParams(c *gin.Context, whenMsg string) (database.BuildTrigger, bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return database.BuildTrigger{}, false
+	}
+	triggerID, ok := ginutil.ParseParamUint(c, "triggerId")
+	if !ok {
+		return database.BuildTrigger{}, false
+	}
+	var dbTrigger database.BuildTrigger
+	err := m.Database.
+		Where(&database.BuildTrigger{SourceProjectID: projectID}, database.BuildTriggerFields.SourceProjectID).
+		First(&dbTrigger, triggerID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Build trigger with ID %d was not found on project with ID %d%s.",
+			triggerID, projectID, spaceWhenMessage(whenMsg)))
+		return database.BuildTrigger{}, false
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching build trigger with ID %d on project with ID %d%s.",
+			triggerID, projectID, spaceWhenMessage(whenMsg)))
+		return database.BuildTrigger{}, false
+	}
+	return dbTrigger, true
+}
+
+func (m projectTriggerModule) validateTrigger(c *gin.Context, dbTrigger database.BuildTrigger) bool {
+	if _, err := path.Match(dbTrigger.BranchFilter, ""); err != nil {
+		ginutil.WriteProblemError(c, err, problems.BuildTriggerInvalidBranchFilter.With(problem.Response{
+			Detail: fmt.Sprintf(
+				"The branch filter %q is not a valid glob pattern.",
+				dbTrigger.BranchFilter),
+			Instance: c.Request.RequestURI + "#branchFilter",
+		}))
+		return false
+	}
+	if !validateProjectExistsByID(c, m.Database, dbTrigger.TargetProjectID, "when validating target project of build trigger") {
+		return false
+	}
+	hasCycle, err := buildTriggerHasCycle(m.Database, dbTrigger)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed checking if build trigger from project with ID %d to project with ID %d would create a cycle.",
+			dbTrigger.SourceProjectID, dbTrigger.TargetProjectID))
+		return false
+	}
+	if hasCycle {
+		ginutil.WriteProblem(c, problems.BuildTriggerCycle.With(problem.Response{
+			Detail: fmt.Sprintf(
+				"Builds of project with ID %d would directly or indirectly trigger builds of project with ID %d.",
+				dbTrigger.TargetProjectID, dbTrigger.SourceProjectID),
+			Instance: c.Request.RequestURI + "#targetProjectId",
+		}))
+		return false
+	}
+	return true
+}
+
+// buildTriggerHasCycle returns true if builds of the trigger's target project
+// would, via the existing build triggers, start builds of the trigger's source
+// project. Branch filters are not taken into account.
+func buildTriggerHasCycle(db *gorm.DB, dbTrigger database.BuildTrigger) (bool, error) {
+	visited := map[uint]struct{}{}
+	queue := []uint{dbTrigger.TargetProjectID}
+	for len(queue) > 0 {
+		projectID := queue[0]
+		queue = queue[1:]
+		if projectID == dbTrigger.SourceProjectID {
+			return true, nil
+		}
+		if _, ok := visited[projectID]; ok {
+			continue
+		}
+		visited[projectID] = struct{}{}
+		var dbTriggers []database.BuildTrigger
+		if err := db.
+			Where(&database.BuildTrigger{SourceProjectID: projectID}, database.BuildTriggerFields.SourceProjectID).
+			Find(&dbTriggers).Error; err != nil {
+			return false, err
+		}
+		for _, t := range dbTriggers {
+			if t.BuildTriggerID == dbTrigger.BuildTriggerID {
+				// The trigger being updated is replaced by dbTrigger.
+				continue
+			}
+			queue = append(queue, t.TargetProjectID)
+		}
+	}
+	return false, nil
+}
+
+// startTriggeredBuilds starts builds of all projects that have build triggers
+// matching the completed build. Failures are logged, as the build's status
+// update should not fail because of them.
+func (m buildModule) startTriggeredBuilds(dbSourceBuild database.Build) {
+	var dbTriggers []database.BuildTrigger
+	if err := m.Database.
+		Where(&database.BuildTrigger{SourceProjectID: dbSourceBuild.ProjectID}, database.BuildTriggerFields.SourceProjectID).
+		Find(&dbTriggers).Error; err != nil {
+		log.Error().
+			WithError(err).
+			WithUint("build", dbSourceBuild.BuildID).
+			Message("Failed to fetch build triggers of completed build.")
+		return
+	}
+	for _, dbTrigger := range dbTriggers {
+		if !buildTriggerMatchesBranch(dbTrigger.BranchFilter, dbSourceBuild.GitBranch) {
+			continue
+		}
+		dbBuild, err := m.startTriggeredBuild(dbTrigger, dbSourceBuild)
+		if err != nil {
+			log.Warn().
+				WithError(err).
+				WithUint("build", dbSourceBuild.BuildID).
+				WithUint("buildTrigger", dbTrigger.BuildTriggerID).
+				WithUint("project", dbTrigger.TargetProjectID).
+				Message("Failed to start build from build trigger.")
+			continue
+		}
+		log.Info().
+			WithUint("build", dbSourceBuild.BuildID).
+			WithUint("buildTrigger", dbTrigger.BuildTriggerID).
+			WithUint("triggeredBuild", dbBuild.BuildID).
+			Message("Started build from build trigger.")
+	}
+}
+
+func buildTriggerMatchesBranch(branchFilter, branch string) bool {
+	if branchFilter == "" {
+		return true
+	}
+	ok, _ := path.Match(branchFilter, branch)
+	return ok
+}
+
+func (m buildModule) startTriggeredBuild(dbTrigger database.BuildTrigger, dbSourceBuild database.Build) (database.Build, error) {
+	var dbProject database.Project
+	if err := databaseProjectPreloaded(m.Database).
+		First(&dbProject, dbTrigger.TargetProjectID).Error; err != nil {
+		return database.Build{}, err
+	}
+	if dbProject.IsArchived {
+		return database.Build{}, errors.New("target project is archived")
+	}
+	dbBranch, ok := findDefaultBranch(dbProject.Branches)
+	if !ok {
+		return database.Build{}, errors.New("target project has no default branch")
+	}
+	engine, ok := lookupEngineOrDefaultFromConfig(m.Config.current().CI, "")
+	if !ok {
+		return database.Build{}, errors.New("no default execution engine configured")
+	}
+	stage := dbTrigger.Stage
+	if stage == "" {
+		stage = "ALL"
+	}
+	dbBuild := database.Build{
+		ProjectID:          dbProject.ProjectID,
+		GitBranch:          dbBranch.Name,
+		Stage:              stage,
+		EngineID:           engine.ID,
+		TriggeredByType:    database.BuildTriggerBuild,
+		TriggeredByName:    fmt.Sprintf("build %d of project %d", dbSourceBuild.BuildID, dbSourceBuild.ProjectID),
+		TriggeredBySubject: strconv.FormatUint(uint64(dbSourceBuild.BuildID), 10),
+	}
+	if err := m.startBuildInBackground(&dbBuild, dbProject, engine, nil); err != nil {
+		return database.Build{}, err
+	}
+	return dbBuild, nil
+}
+
+// startBuildInBackground adds the build with its parameters to the database,
+// and queues it to be sent to the execution engine in the background. Used for
+// builds that are not started by a client waiting for the engine's response.
+func (m buildModule) startBuildInBackground(dbBuild *database.Build, dbProject database.Project, engine CIEngineConfig, inputs []byte) error {
+	dbJobParams, err := m.insertBuild(dbBuild, dbProject, engine, inputs)
+	if err != nil {
+		return err
+	}
+	if m.Config.CI.MockTriggerResponse {
+		return nil
+	}
+	if err := m.Dispatcher.enqueue(buildTriggerJob{*dbBuild, dbJobParams, engine, ""}); err != nil {
+		if saveErr := saveBuildTriggerFailed(m.Database, dbBuild, err); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTriggerMatchesBranch(t *testing.T) {
+	testCases := []struct {
+		name         string
+		branchFilter string
+		branch       string
+		want         bool
+	}{
+		{name: "empty filter", branchFilter: "", branch: "feature/foo", want: true},
+		{name: "exact match", branchFilter: "main", branch: "main", want: true},
+		{name: "exact mismatch", branchFilter: "main", branch: "master", want: false},
+		{name: "glob match", branchFilter: "release/*", branch: "release/v1.0", want: true},
+		{name: "glob does not match slashes", branchFilter: "release/*", branch: "release/v1/hotfix", want: false},
+		{name: "invalid pattern", branchFilter: "[", branch: "[", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, buildTriggerMatchesBranch(tc.branchFilter, tc.branch))
+		})
+	}
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/secretbox"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+// webhookCallerContextKey is the gin context key of the name of the caller of
+// an inbound webhook, used as the name of who started the build.
+const webhookCallerContextKey = "wharf-webhook-caller"
+
+// webhookSignatureHeaders are the headers that may hold the HMAC-SHA256
+// signature of the request body, in the order they are checked. The second is
+// used by GitHub, so that GitHub push webhooks can be used as is.
+var webhookSignatureHeaders = []string{"X-Wharf-Signature-256", "X-Hub-Signature-256"}
+
+type projectWebhookModule struct {
+	Database *gorm.DB
+	Config   *Config
+}
+
+func (m projectWebhookModule) Register(g *gin.RouterGroup) {
+	projectWebhook := g.Group("/project/:projectId/webhook")
+	{
+		projectWebhook.GET("", m.getProjectWebhookHandler)
+		projectWebhook.PUT("", m.updateProjectWebhookHandler)
+		projectWebhook.DELETE("", m.deleteProjectWebhookHandler)
+		projectWebhook.POST("/regenerate", m.regenerateProjectWebhookHandler)
+	}
+}
+
+// getProjectWebhookHandler godoc
+// @id getProjectWebhook
+// @summary Get inbound webhook of project.
+// @description The webhook's token and secret are not included, as they are
+// @description only returned when the webhook is added or regenerated.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectWebhook "Project webhook"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project or project webhook not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/webhook [get]
+func (m projectWebhookModule) getProjectWebhookHandler(c *gin.Context) {
+	dbWebhook, ok := m.fetchProjectWebhookFromParams(c, "")
+	if !ok {
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectWebhookToResponse(dbWebhook))
+}
+
+// updateProjectWebhookHandler godoc
+// @id updateProjectWebhook
+// @summary Add or update inbound webhook of project.
+// @description Adds an inbound webhook to the project, or updates the
+// @description project's existing webhook. External systems can start builds
+// @description of the project by sending a POST request to the webhook's URL.
+// @description The webhook's token, URL, and secret are only included in the
+// @description response when the webhook is added, as they are not stored.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param webhook body request.ProjectWebhook true "Project webhook settings"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectWebhook "Updated project webhook"
+// @success 201 {object} response.ProjectWebhook "Added project webhook, including its token and secret"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/webhook [put]
+func (m projectWebhookModule) updateProjectWebhookHandler(c *gin.Context) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqWebhook request.ProjectWebhook
+	if err := c.ShouldBindJSON(&reqWebhook); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for project webhook to update.")
+		return
+	}
+	if !validateProjectExistsByID(c, m.Database, projectID, "when updating project webhook") {
+		return
+	}
+	dbWebhook, found, err := findProjectWebhook(m.Database, projectID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching webhook of project with ID %d from database.",
+			projectID))
+		return
+	}
+	dbWebhook.IsEnabled = reqWebhook.IsEnabled
+	dbWebhook.RequireSignature = reqWebhook.RequireSignature
+	if found {
+		if err := m.Database.Save(&dbWebhook).Error; err != nil {
+			ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+				"Failed writing webhook of project with ID %d to database.",
+				projectID))
+			return
+		}
+		ginrender.Response(c, http.StatusOK, modelconv.DBProjectWebhookToResponse(dbWebhook))
+		return
+	}
+
+	dbWebhook.ProjectID = projectID
+	token, secret, ok := m.generateProjectWebhookSecrets(c, &dbWebhook)
+	if !ok {
+		return
+	}
+	if err := m.Database.Create(&dbWebhook).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed adding webhook to project with ID %d in database.",
+			projectID))
+		return
+	}
+	ginrender.Response(c, http.StatusCreated, newProjectWebhookResponse(dbWebhook, token, secret))
+}
+
+// regenerateProjectWebhookHandler godoc
+// @id regenerateProjectWebhook
+// @summary Regenerate token and secret of inbound webhook of project.
+// @description Replaces the webhook's token and secret with new random ones,
+// @description such as after they have been leaked. Calls to the webhook's old
+// @description URL, or signed using its old secret, are rejected afterwards.
+// @description Added in v5.3.0.
+// @tags project
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProjectWebhook "Project webhook, including its new token and secret"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project or project webhook not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/webhook/regenerate [post]
+func (m projectWebhookModule) regenerateProjectWebhookHandler(c *gin.Context) {
+	dbWebhook, ok := m.fetchProjectWebhookFromParams(c, "when regenerating project webhook")
+	if !ok {
+		return
+	}
+	token, secret, ok := m.generateProjectWebhookSecrets(c, &dbWebhook)
+	if !ok {
+		return
+	}
+	if err := m.Database.Save(&dbWebhook).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed writing webhook of project with ID %d to database.",
+			dbWebhook.ProjectID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, newProjectWebhookResponse(dbWebhook, token, secret))
+}
+
+// deleteProjectWebhookHandler godoc
+// @id deleteProjectWebhook
+// @summary Delete inbound webhook of project.
+// @description Added in v5.3.0.
+// @tags project
+// @param projectId path uint true "project ID" minimum(0)
+// @success 204 "Deleted"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Project or project webhook not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /project/{projectId}/webhook [delete]
+func (m projectWebhookModule) deleteProjectWebhookHandler(c *gin.Context) {
+	dbWebhook, ok := m.fetchProjectWebhookFromParams(c, "when deleting project webhook")
+	if !ok {
+		return
+	}
+	if err := m.Database.Delete(&dbWebhook).Error; err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed deleting webhook of project with ID %d from database.",
+			dbWebhook.ProjectID))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (m projectWebhookModule) fetchProjectWebhookFromParams(c *gin.Context, whenMsg string) (database.ProjectWebhook, bool) {
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return database.ProjectWebhook{}, false
+	}
+	dbWebhook, found, err := findProjectWebhook(m.Database, projectID)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching webhook of project with ID %d%s.",
+			projectID, spaceWhenMessage(whenMsg)))
+		return database.ProjectWebhook{}, false
+	}
+	if !found {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Project with ID %d has no webhook%s.",
+			projectID, spaceWhenMessage(whenMsg)))
+		return database.ProjectWebhook{}, false
+	}
+	return dbWebhook, true
+}
+
+// generateProjectWebhookSecrets sets a new random token and secret on the
+// webhook, and returns them. Problem responses are written on failure.
+func (m projectWebhookModule) generateProjectWebhookSecrets(c *gin.Context, dbWebhook *database.ProjectWebhook) (token, secret string, ok bool) {
+	token = newWebhookRandomString()
+	secret = newWebhookRandomString()
+	storedSecret, err := encryptWebhookSecret(m.Config.DB.EncryptionKey, secret)
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed encrypting secret of webhook of project with ID %d.",
+			dbWebhook.ProjectID))
+		return "", "", false
+	}
+	dbWebhook.TokenHash = hashWebhookToken(token)
+	dbWebhook.Secret = storedSecret
+	return token, secret, true
+}
+
+func findProjectWebhook(db *gorm.DB, projectID uint) (database.ProjectWebhook, bool, error) {
+	var dbWebhooks []database.ProjectWebhook
+	err := db.
+		Where(&database.ProjectWebhook{ProjectID: projectID}, database.ProjectWebhookFields.ProjectID).
+		Limit(1).
+		Find(&dbWebhooks).
+		Error
+	if err != nil || len(dbWebhooks) == 0 {
+		return database.ProjectWebhook{}, false, err
+	}
+	return dbWebhooks[0], true, nil
+}
+
+func newProjectWebhookResponse(dbWebhook database.ProjectWebhook, token, secret string) response.ProjectWebhook {
+	resWebhook := modelconv.DBProjectWebhookToResponse(dbWebhook)
+	resWebhook.Token = token
+	resWebhook.URL = "/api/webhook/trigger/" + token
+	resWebhook.Secret = secret
+	return resWebhook
+}
+
+// newWebhookRandomString returns 32 random bytes, hex-encoded.
+func newWebhookRandomString() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+func hashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// encryptWebhookSecret encrypts the secret if a database encryption key is
+// configured, and otherwise returns it as is.
+func encryptWebhookSecret(encryptionKey, secret string) (string, error) {
+	if encryptionKey == "" {
+		return secret, nil
+	}
+	box, err := secretbox.New(encryptionKey)
+	if err != nil {
+		return "", err
+	}
+	return box.Encrypt(secret)
+}
+
+// decryptWebhookSecret decrypts the secret if it was encrypted by
+// encryptWebhookSecret, and otherwise returns it as is.
+func decryptWebhookSecret(encryptionKey, storedSecret string) (string, error) {
+	if !secretbox.IsEncrypted(storedSecret) {
+		return storedSecret, nil
+	}
+	if encryptionKey == "" {
+		return "", errors.New("webhook secret is encrypted, but no database encryption key is configured")
+	}
+	box, err := secretbox.New(encryptionKey)
+	if err != nil {
+		return "", err
+	}
+	return box.Decrypt(storedSecret)
+}
+
+// verifyWebhookSignature returns true if the signature, in the format
+// "sha256=<hex>", is the HMAC-SHA256 of the body using the secret.
+func verifyWebhookSignature(secret string, body []byte, signature string) bool {
+	sigHex := strings.TrimPrefix(signature, "sha256=")
+	if sigHex == signature {
+		return false
+	}
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// webhookTriggerModule holds the inbound webhook endpoint, which is
+// authenticated by the secret token in its URL instead of the wharf-api's
+// regular authentication.
+type webhookTriggerModule struct {
+	Builds buildModule
+}
+
+func (m webhookTriggerModule) Register(g *gin.RouterGroup) {
+	g.POST("/webhook/trigger/:projectToken", m.triggerWebhookHandler)
+}
+
+// triggerWebhookHandler godoc
+// @id triggerWebhook
+// @summary Start a build via the inbound webhook of a project.
+// @description Starts a build of the project that the webhook belongs to, as
+// @description identified by the secret token in the URL. Does not use the
+// @description wharf-api's regular authentication, so that external systems,
+// @description such as Git providers or monitoring systems, can call it.
+// @description The body is optional, and unknown fields in it are ignored.
+// @description If the webhook requires signatures, then the body must be
+// @description signed using HMAC-SHA256 and the webhook's secret, and the
+// @description signature given in the `X-Wharf-Signature-256` or
+// @description `X-Hub-Signature-256` header as `sha256=<hex>`.
+// @description The build is triggered in the background, using the default
+// @description execution engine.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectToken path string true "Secret token of the project's webhook"
+// @param trigger body request.WebhookTrigger false "Optional build overrides"
+// @param X-Wharf-Signature-256 header string false "HMAC-SHA256 signature of the body, as `sha256=<hex>`"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 202 {object} response.BuildReferenceWrapper "Build created, and will be triggered in the background"
+// @failure 400 {object} problem.Response "Bad request, such as invalid body JSON or input variables"
+// @failure 401 {object} problem.Response "Missing or invalid signature"
+// @failure 403 {object} problem.Response "Webhook is disabled"
+// @failure 404 {object} problem.Response "Webhook not found, or project has no default branch"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @failure 503 {object} problem.Response "Too many builds are waiting to be triggered"
+// @router /webhook/trigger/{projectToken} [post]
+func (m webhookTriggerModule) triggerWebhookHandler(c *gin.Context) {
+	db := m.Builds.Database
+	var dbWebhooks []database.ProjectWebhook
+	if err := db.
+		Where(&database.ProjectWebhook{TokenHash: hashWebhookToken(c.Param("projectToken"))},
+			database.ProjectWebhookFields.TokenHash).
+		Limit(1).
+		Find(&dbWebhooks).
+		Error; err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching webhook from database.")
+		return
+	}
+	if len(dbWebhooks) == 0 {
+		ginutil.WriteProblem(c, problems.WebhookNotFound.New(
+			"No webhook was found by the token in the URL."))
+		return
+	}
+	dbWebhook := dbWebhooks[0]
+	if !dbWebhook.IsEnabled {
+		ginutil.WriteProblem(c, problems.WebhookDisabled.Newf(
+			"The webhook of project with ID %d is disabled.",
+			dbWebhook.ProjectID))
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		ginutil.WriteBodyReadError(c, err, fmt.Sprintf(
+			"Failed to read the body of the call to the webhook of project with ID %d.",
+			dbWebhook.ProjectID))
+		return
+	}
+	if !m.validateWebhookSignature(c, dbWebhook, body) {
+		return
+	}
+	var reqTrigger request.WebhookTrigger
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &reqTrigger); err != nil {
+			writeInvalidBindError(c, err,
+				"One or more parameters failed to parse when reading the request body of the webhook call.")
+			return
+		}
+	}
+	if !validateBuildGitRefs(c, reqTrigger.Commit, reqTrigger.Tag) {
+		return
+	}
+	var inputs []byte
+	if reqTrigger.Inputs != nil {
+		// Cannot fail, as the inputs were just unmarshaled from JSON.
+		inputs, _ = json.Marshal(reqTrigger.Inputs)
+	}
+
+	engine, ok := m.Builds.lookupEngineOrWriteProblem(c, "")
+	if !ok {
+		return
+	}
+	dbProject, ok := fetchProjectByID(c, db, dbWebhook.ProjectID, "when starting a build via webhook")
+	if !ok || !validateProjectNotArchived(c, dbProject) ||
+		!validateProjectEngineAllowed(c, db, dbProject.ProjectID, engine) {
+		return
+	}
+	branch := reqTrigger.Branch
+	if branch == "" {
+		b, ok := findDefaultBranch(dbProject.Branches)
+		if !ok {
+			ginutil.WriteDBNotFound(c, fmt.Sprintf(
+				"No branch to build for project with ID %d was specified, and no default branch was found on the project.",
+				dbProject.ProjectID))
+			return
+		}
+		branch = b.Name
+	}
+	stage := reqTrigger.Stage
+	if stage == "" {
+		stage = "ALL"
+	}
+
+	c.Set(webhookCallerContextKey, webhookCallerName(c))
+	dbBuild, dbJobParams, ok := m.Builds.createBuild(c, dbProject, newBuildOptions{
+		stage:       stage,
+		branch:      branch,
+		commit:      reqTrigger.Commit,
+		tag:         reqTrigger.Tag,
+		environment: null.NewString(reqTrigger.Environment, reqTrigger.Environment != ""),
+		engine:      engine,
+		inputs:      inputs,
+	})
+	if !ok {
+		return
+	}
+	requestLog(c).Info().
+		WithUint("project", dbProject.ProjectID).
+		WithUint("build", dbBuild.BuildID).
+		Message("Started build via webhook.")
+	m.Builds.triggerBuildAndRespond(c, dbBuild, dbJobParams, engine, true)
+}
+
+// validateWebhookSignature writes a problem response and returns false if the
+// body is not signed using the webhook's secret. Unsigned bodies are allowed
+// if the webhook does not require signatures, while invalid signatures are
+// always rejected.
+func (m webhookTriggerModule) validateWebhookSignature(c *gin.Context, dbWebhook database.ProjectWebhook, body []byte) bool {
+	var signature string
+	for _, header := range webhookSignatureHeaders {
+		if signature = c.GetHeader(header); signature != "" {
+			break
+		}
+	}
+	if signature == "" && !dbWebhook.RequireSignature {
+		return true
+	}
+	if signature == "" {
+		ginutil.WriteProblem(c, problems.WebhookInvalidSignature.Newf(
+			"The webhook of project with ID %d requires the body to be signed, but the %s header is missing.",
+			dbWebhook.ProjectID, webhookSignatureHeaders[0]))
+		return false
+	}
+	secret, err := decryptWebhookSecret(m.Builds.Config.DB.EncryptionKey, dbWebhook.Secret)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed decrypting secret of webhook of project with ID %d.",
+			dbWebhook.ProjectID))
+		return false
+	}
+	if !verifyWebhookSignature(secret, body, signature) {
+		ginutil.WriteProblem(c, problems.WebhookInvalidSignature.Newf(
+			"The signature of the body does not match the secret of the webhook of project with ID %d.",
+			dbWebhook.ProjectID))
+		return false
+	}
+	return true
+}
+
+// webhookCallerName returns the name of the caller of a webhook, used as the
+// name of who started the build. Uses the caller's user agent, such as
+// "GitHub-Hookshot/1a2b3c", if any.
+func webhookCallerName(c *gin.Context) string {
+	name := c.Request.UserAgent()
+	if name == "" {
+		return "webhook"
+	}
+	if len(name) > database.BuildSizes.TriggeredByName {
+		name = name[:database.BuildSizes.TriggeredByName]
+	}
+	return name
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWebhookRouter(t *testing.T, encryptionKey string) (*gin.Engine, buildModule, database.Project) {
+	gin.SetMode(gin.TestMode)
+	builds, dbProject := newTestInsertBuildModule(t)
+	builds.Config.CI.MockTriggerResponse = true
+	builds.Config.DB.EncryptionKey = encryptionKey
+	require.NoError(t, builds.Database.Create(&database.Branch{
+		ProjectID: dbProject.ProjectID,
+		Name:      "master",
+		Default:   true,
+	}).Error)
+	r := gin.New()
+	projectWebhookModule{Database: builds.Database, Config: builds.Config}.Register(r.Group("/api"))
+	webhookTriggerModule{Builds: builds}.Register(r.Group("/api"))
+	return r, builds, dbProject
+}
+
+func serveTestWebhookRequest(r *gin.Engine, method, url, body string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func addTestProjectWebhook(t *testing.T, r *gin.Engine, projectID uint, body string) response.ProjectWebhook {
+	w := serveTestWebhookRequest(r, http.MethodPut, fmt.Sprintf("/api/project/%d/webhook", projectID), body, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resWebhook response.ProjectWebhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resWebhook))
+	require.NotEmpty(t, resWebhook.Token)
+	require.NotEmpty(t, resWebhook.Secret)
+	return resWebhook
+}
+
+func signTestWebhookBody(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestTriggerWebhookHandler(t *testing.T) {
+	const encryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	r, builds, dbProject := newTestWebhookRouter(t, encryptionKey)
+	resWebhook := addTestProjectWebhook(t, r, dbProject.ProjectID, `{"isEnabled":true,"requireSignature":true}`)
+	assert.Equal(t, "/api/webhook/trigger/"+resWebhook.Token, resWebhook.URL)
+
+	var dbWebhook database.ProjectWebhook
+	require.NoError(t, builds.Database.First(&dbWebhook).Error)
+	assert.NotContains(t, dbWebhook.Secret, resWebhook.Secret, "must encrypt secret")
+	assert.NotEqual(t, resWebhook.Token, dbWebhook.TokenHash, "must not store token")
+
+	body := `{"branch":"feature","environment":"stage","inputs":{"message":"from webhook"}}`
+	testCases := []struct {
+		name      string
+		url       string
+		signature string
+		want      int
+		wantType  string
+	}{
+		{
+			name: "unknown token",
+			url:  "/api/webhook/trigger/foo",
+			want: http.StatusNotFound, wantType: "/prob/api/webhook/not-found",
+		},
+		{
+			name: "missing signature",
+			want: http.StatusUnauthorized, wantType: "/prob/api/webhook/invalid-signature",
+		},
+		{
+			name:      "invalid signature",
+			signature: signTestWebhookBody("wrong secret", body),
+			want:      http.StatusUnauthorized, wantType: "/prob/api/webhook/invalid-signature",
+		},
+		{
+			name:      "valid signature",
+			signature: signTestWebhookBody(resWebhook.Secret, body),
+			want:      http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := tc.url
+			if url == "" {
+				url = resWebhook.URL
+			}
+			headers := map[string]string{"User-Agent": "GitHub-Hookshot/abc"}
+			if tc.signature != "" {
+				headers["X-Hub-Signature-256"] = tc.signature
+			}
+			w := serveTestWebhookRequest(r, http.MethodPost, url, body, headers)
+			assert.Equal(t, tc.want, w.Code, w.Body.String())
+			if tc.wantType != "" {
+				assert.Contains(t, w.Body.String(), tc.wantType)
+			}
+		})
+	}
+
+	var dbBuild database.Build
+	require.NoError(t, builds.Database.Preload(database.BuildFields.Params).First(&dbBuild).Error)
+	assert.Equal(t, "feature", dbBuild.GitBranch)
+	assert.Equal(t, "stage", dbBuild.Environment.String)
+	assert.Equal(t, database.BuildTriggerWebhook, dbBuild.TriggeredByType)
+	assert.Equal(t, "GitHub-Hookshot/abc", dbBuild.TriggeredByName)
+	var message string
+	for _, dbParam := range dbBuild.Params {
+		if dbParam.Name == "message" {
+			message = dbParam.Value
+		}
+	}
+	assert.Equal(t, "from webhook", message)
+}
+
+func TestTriggerWebhookHandler_unsignedAndDisabled(t *testing.T) {
+	r, builds, dbProject := newTestWebhookRouter(t, "")
+	resWebhook := addTestProjectWebhook(t, r, dbProject.ProjectID, `{"isEnabled":true,"requireSignature":false}`)
+
+	w := serveTestWebhookRequest(r, http.MethodPost, resWebhook.URL, "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var dbBuild database.Build
+	require.NoError(t, builds.Database.First(&dbBuild).Error)
+	assert.Equal(t, "master", dbBuild.GitBranch, "must default to default branch")
+	assert.Equal(t, "webhook", dbBuild.TriggeredByName)
+
+	w = serveTestWebhookRequest(r, http.MethodPost, resWebhook.URL, "{}",
+		map[string]string{"X-Wharf-Signature-256": "sha256=00"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "must reject invalid signatures even if not required")
+
+	w = serveTestWebhookRequest(r, http.MethodPut, fmt.Sprintf("/api/project/%d/webhook", dbProject.ProjectID),
+		`{"isEnabled":false,"requireSignature":false}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), resWebhook.Token)
+
+	w = serveTestWebhookRequest(r, http.MethodPost, resWebhook.URL, "", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "/prob/api/webhook/disabled")
+}
+
+func TestRegenerateProjectWebhookHandler(t *testing.T) {
+	r, _, dbProject := newTestWebhookRouter(t, "")
+	resWebhook := addTestProjectWebhook(t, r, dbProject.ProjectID, `{"isEnabled":true,"requireSignature":false}`)
+
+	w := serveTestWebhookRequest(r, http.MethodPost,
+		fmt.Sprintf("/api/project/%d/webhook/regenerate", dbProject.ProjectID), "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resRegenerated response.ProjectWebhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resRegenerated))
+	assert.NotEqual(t, resWebhook.Token, resRegenerated.Token)
+	assert.NotEqual(t, resWebhook.Secret, resRegenerated.Secret)
+
+	w = serveTestWebhookRequest(r, http.MethodPost, resWebhook.URL, "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "old URL must stop working")
+	w = serveTestWebhookRequest(r, http.MethodPost, resRegenerated.URL, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -2,16 +2,18 @@ package main
 
 import (
 	"fmt"
-
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
 	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
 	"github.com/iver-wharf/wharf-core/pkg/problem"
 	"gorm.io/gorm"
@@ -19,6 +21,7 @@ import (
 
 type providerModule struct {
 	Database *gorm.DB
+	Config   *ProvidersConfig
 }
 
 func (m providerModule) Register(g *gin.RouterGroup) {
@@ -31,14 +34,17 @@ func (m providerModule) Register(g *gin.RouterGroup) {
 		{
 			providerByID.GET("", m.getProviderHandler)
 			providerByID.PUT("", m.updateProviderHandler)
+			providerByID.PATCH("", m.patchProviderHandler)
 		}
 	}
 }
 
 var providerJSONToColumns = map[string]database.SafeSQLName{
-	response.ProviderJSONFields.ProviderID: database.ProviderColumns.ProviderID,
-	response.ProviderJSONFields.Name:       database.ProviderColumns.Name,
-	response.ProviderJSONFields.URL:        database.ProviderColumns.URL,
+	response.ProviderJSONFields.ProviderID:    database.ProviderColumns.ProviderID,
+	response.ProviderJSONFields.Name:          database.ProviderColumns.Name,
+	response.ProviderJSONFields.URL:           database.ProviderColumns.URL,
+	response.TimeMetadataJSONFields.CreatedAt: database.TimeMetadataColumns.CreatedAt,
+	response.TimeMetadataJSONFields.UpdatedAt: database.TimeMetadataColumns.UpdatedAt,
 }
 
 var defaultGetProvidersOrderBy = orderby.Column{Name: database.ProviderColumns.ProviderID, Direction: orderby.Desc}
@@ -52,14 +58,19 @@ var defaultGetProvidersOrderBy = orderby.Column{Name: database.ProviderColumns.P
 // @description Added in v5.0.0.
 // @tags provider
 // @produce json
-// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`). Required if `offset` is used." default(100)
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
 // @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
-// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc'. Can be specified multiple times for more granular sorting. Defaults to `?orderby=providerId desc`"
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Casing, prefixes, and commas added in v5.3.0. Defaults to `?orderby=providerId desc`"
 // @param name query string false "Filter by verbatim provider name."
 // @param url query string false "Filter by verbatim provider URL."
 // @param nameMatch query string false "Filter by matching provider name. Cannot be used with `name`."
 // @param urlMatch query string false "Filter by matching provider URL. Cannot be used with `url`."
 // @param match query string false "Filter by matching on any supported fields."
+// @param createdAfter query string false "Filter by providers with created date later than value. Added in v5.3.0." format(date-time)
+// @param createdBefore query string false "Filter by providers with created date earlier than value. Added in v5.3.0." format(date-time)
+// @param updatedAfter query string false "Filter by providers with last updated date later than value. Added in v5.3.0." format(date-time)
+// @param updatedBefore query string false "Filter by providers with last updated date earlier than value. Added in v5.3.0." format(date-time)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedProviders
 // @failure 400 {object} problem.Response "Bad request"
@@ -77,6 +88,8 @@ func (m providerModule) getProviderListHandler(c *gin.Context) {
 		URLMatch  *string `form:"urlMatch" binding:"excluded_with=URL"`
 
 		Match *string `form:"match"`
+
+		timeMetadataQueryParams
 	}{
 		commonGetQueryParams: defaultCommonGetQueryParams,
 	}
@@ -105,17 +118,19 @@ func (m providerModule) getProviderListHandler(c *gin.Context) {
 				database.ProviderColumns.Name,
 				database.ProviderColumns.URL,
 			),
+			optionalTimeRangeScope(database.TimeMetadataColumns.CreatedAt, params.CreatedAfter, params.CreatedBefore),
+			optionalTimeRangeScope(database.TimeMetadataColumns.UpdatedAt, params.UpdatedAfter, params.UpdatedBefore),
 		)
 
 	var dbProviders []database.Provider
 	var totalCount int64
-	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, &dbProviders, &totalCount)
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbProviders, &totalCount)
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, "Failed fetching list of providers from database.")
 		return
 	}
 
-	renderJSON(c, http.StatusOK, response.PaginatedProviders{
+	ginrender.Response(c, http.StatusOK, response.PaginatedProviders{
 		List:       modelconv.DBProvidersToResponses(dbProviders),
 		TotalCount: totalCount,
 	})
@@ -130,6 +145,7 @@ func (m providerModule) getProviderListHandler(c *gin.Context) {
 // @param providerId path uint true "Provider ID" minimum(0)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.Provider
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 404 {object} problem.Response "Provider not found"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
@@ -147,7 +163,8 @@ func (m providerModule) getProviderHandler(c *gin.Context) {
 	}
 
 	resProvider := modelconv.DBProviderToResponse(dbProvider)
-	renderJSON(c, http.StatusOK, resProvider)
+	setETagHeader(c, dbProvider.Version)
+	ginrender.Response(c, http.StatusOK, resProvider)
 }
 
 // createProviderHandler godoc
@@ -169,21 +186,23 @@ func (m providerModule) getProviderHandler(c *gin.Context) {
 func (m providerModule) createProviderHandler(c *gin.Context) {
 	var reqProvider request.Provider
 	if err := c.ShouldBindJSON(&reqProvider); err != nil {
-		ginutil.WriteInvalidBindError(c, err,
+		writeInvalidBindError(c, err,
 			"One or more parameters failed to parse when reading the request body for the provider object to search with.")
 		return
 	}
 
-	validName, isValid := reqProvider.Name.ValidString()
+	validName, isValid := m.validProviderName(reqProvider.Name)
 	if !isValid {
-		writeInvalidProviderNameProblem(c, reqProvider.Name)
+		m.writeInvalidProviderNameProblem(c, reqProvider.Name)
 		return
 	}
 
 	dbProvider := database.Provider{
-		Name:    validName,
-		URL:     reqProvider.URL,
-		TokenID: reqProvider.TokenID,
+		Name:      validName,
+		URL:       reqProvider.URL,
+		UploadURL: reqProvider.UploadURL,
+		TokenID:   reqProvider.TokenID,
+		Metadata:  reqProvider.Metadata,
 	}
 	// Sets provider.TokenID through association
 	if err := m.Database.Create(&dbProvider).Error; err != nil {
@@ -194,7 +213,7 @@ func (m providerModule) createProviderHandler(c *gin.Context) {
 	}
 
 	resProvider := modelconv.DBProviderToResponse(dbProvider)
-	renderJSON(c, http.StatusCreated, resProvider)
+	ginrender.Response(c, http.StatusCreated, resProvider)
 }
 
 // updateProviderHandler godoc
@@ -207,11 +226,14 @@ func (m providerModule) createProviderHandler(c *gin.Context) {
 // @produce json
 // @param providerId path uint _ "ID of provider to update" minimum(0)
 // @param provider body request.ProviderUpdate _ "New provider values"
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches. Added in v5.3.0."
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.Provider
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 404 {object} problem.Response "Provider or token not found"
+// @failure 412 {object} problem.Response "Provider was modified since it was fetched, as the If-Match header did not match"
 // @failure 502 {object} problem.Response "Database is unreachable"
 // @router /provider/{providerId} [put]
 func (m providerModule) updateProviderHandler(c *gin.Context) {
@@ -221,18 +243,66 @@ func (m providerModule) updateProviderHandler(c *gin.Context) {
 	}
 	var reqProviderUpdate request.ProviderUpdate
 	if err := c.ShouldBindJSON(&reqProviderUpdate); err != nil {
-		ginutil.WriteInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
 		return
 	}
-	validName, isValid := reqProviderUpdate.Name.ValidString()
-	if !isValid {
-		writeInvalidProviderNameProblem(c, reqProviderUpdate.Name)
+	dbProvider, ok := fetchProviderByID(c, m.Database, providerID, "when updating provider")
+	if !ok {
+		return
+	}
+	if !validateIfMatch(c, dbProvider.Version, "provider") {
+		return
+	}
+	m.saveProviderUpdate(c, dbProvider, reqProviderUpdate)
+}
+
+// patchProviderHandler godoc
+// @id patchProvider
+// @summary Partially update provider in database.
+// @description Updates a provider by applying a JSON Merge Patch (RFC 7396), where only the supplied fields are modified.
+// @description Fields set to null are reset to their zero values.
+// @description Added in v5.3.0.
+// @tags provider
+// @accept json,application/merge-patch+json
+// @produce json
+// @param providerId path uint _ "ID of provider to update" minimum(0)
+// @param provider body request.ProviderUpdate _ "Provider fields to update"
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Provider
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Provider or token not found"
+// @failure 412 {object} problem.Response "Provider was modified since it was fetched, as the If-Match header did not match"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /provider/{providerId} [patch]
+func (m providerModule) patchProviderHandler(c *gin.Context) {
+	providerID, ok := ginutil.ParseParamUint(c, "providerId")
+	if !ok {
 		return
 	}
 	dbProvider, ok := fetchProviderByID(c, m.Database, providerID, "when updating provider")
 	if !ok {
 		return
 	}
+	if !validateIfMatch(c, dbProvider.Version, "provider") {
+		return
+	}
+	reqProviderUpdate := modelconv.DBProviderToUpdateRequest(dbProvider)
+	if !bindMergePatch(c, &reqProviderUpdate) {
+		return
+	}
+	m.saveProviderUpdate(c, dbProvider, reqProviderUpdate)
+}
+
+func (m providerModule) saveProviderUpdate(c *gin.Context, dbProvider database.Provider, reqProviderUpdate request.ProviderUpdate) {
+	providerID := dbProvider.ProviderID
+	validName, isValid := m.validProviderName(reqProviderUpdate.Name)
+	if !isValid {
+		m.writeInvalidProviderNameProblem(c, reqProviderUpdate.Name)
+		return
+	}
 	if reqProviderUpdate.TokenID != 0 {
 		// Only called to validate the TokenID field
 		_, ok := fetchTokenByID(c, m.Database, reqProviderUpdate.TokenID, "when updating provider")
@@ -243,17 +313,26 @@ func (m providerModule) updateProviderHandler(c *gin.Context) {
 
 	dbProvider.Name = validName
 	dbProvider.URL = reqProviderUpdate.URL
+	dbProvider.UploadURL = reqProviderUpdate.UploadURL
 	dbProvider.TokenID = reqProviderUpdate.TokenID
+	dbProvider.Metadata = reqProviderUpdate.Metadata
 
-	if err := m.Database.Save(&dbProvider).Error; err != nil {
+	saved, err := saveVersioned(m.Database, &dbProvider, &dbProvider.Version,
+		database.ProviderColumns.Version, hasIfMatchHeader(c))
+	if err != nil {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 			"Failed to update provider by ID %d.",
 			providerID))
 		return
 	}
+	if !saved {
+		writePreconditionFailedProblem(c, "provider")
+		return
+	}
 
 	resProvider := modelconv.DBProviderToResponse(dbProvider)
-	renderJSON(c, http.StatusOK, resProvider)
+	setETagHeader(c, dbProvider.Version)
+	ginrender.Response(c, http.StatusOK, resProvider)
 }
 
 func fetchProviderByID(c *gin.Context, db *gorm.DB, providerID uint, whenMsg string) (database.Provider, bool) {
@@ -262,14 +341,33 @@ func fetchProviderByID(c *gin.Context, db *gorm.DB, providerID uint, whenMsg str
 	return dbProvider, ok
 }
 
-func writeInvalidProviderNameProblem(c *gin.Context, actual request.ProviderName) {
-	ginutil.WriteProblem(c, problem.Response{
-		Type:   "/prob/api/provider/invalid-name",
-		Title:  "Invalid provider name.",
-		Status: http.StatusBadRequest,
-		Detail: fmt.Sprintf(
+// validProviderName returns the name as a string if it is not empty, and is
+// allowed by the providers.allowedNames config, if set.
+func (m providerModule) validProviderName(name request.ProviderName) (string, bool) {
+	validName, ok := name.ValidString()
+	if !ok {
+		return "", false
+	}
+	if len(m.Config.AllowedNames) == 0 {
+		return validName, true
+	}
+	for _, allowed := range m.Config.AllowedNames {
+		if validName == allowed {
+			return validName, true
+		}
+	}
+	return "", false
+}
+
+func (m providerModule) writeInvalidProviderNameProblem(c *gin.Context, actual request.ProviderName) {
+	detail := fmt.Sprintf("Provider name was %q but must not be empty.", actual)
+	if len(m.Config.AllowedNames) > 0 {
+		detail = fmt.Sprintf(
 			"Provider name was %q but can only be one of the following values: %s.",
-			actual, request.ProviderNameValues),
+			actual, strings.Join(m.Config.AllowedNames, ", "))
+	}
+	ginutil.WriteProblem(c, problems.ProviderInvalidName.With(problem.Response{
+		Detail:   detail,
 		Instance: c.Request.RequestURI + "#name",
-	})
+	}))
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/gorm"
+)
+
+var errProviderSyncInProgress = errors.New("provider sync is already in progress")
+
+type providerSyncModule struct {
+	Database *gorm.DB
+	Config   *ProvidersConfig
+}
+
+func (m providerSyncModule) Register(g *gin.RouterGroup) {
+	providerSync := g.Group("/provider/:providerId/sync")
+	{
+		providerSync.POST("", m.startProviderSyncHandler)
+		providerSync.GET("/:syncId", m.getProviderSyncHandler)
+	}
+}
+
+// providerSyncRequest is the request body sent to the provider plugin when
+// syncing a provider.
+type providerSyncRequest struct {
+	SyncID     uint           `json:"syncId"`
+	ProviderID uint           `json:"providerId"`
+	TokenID    uint           `json:"tokenId"`
+	URL        string         `json:"url"`
+	UploadURL  string         `json:"uploadUrl"`
+	Metadata   map[string]any `json:"metadata"`
+}
+
+// startProviderSyncHandler godoc
+// @id startProviderSync
+// @summary Sync projects and branches of provider.
+// @description Asks the provider plugin, such as wharf-provider-github, to
+// @description re-import all projects and branches of the provider. The sync
+// @description runs in the background, and its status can be polled using the
+// @description URL in the Location response header.
+// @description The provider plugin URLs are configured per provider name
+// @description using the `providers.syncUrls` config.
+// @description Added in v5.3.0.
+// @tags provider
+// @produce json
+// @param providerId path uint true "provider ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 202 {object} response.ProviderSync "Started sync"
+// @header 202 {string} Location "URL of the started sync"
+// @failure 400 {object} problem.Response "Bad request, or no sync URL configured for the provider's name"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Provider not found"
+// @failure 409 {object} problem.Response "Sync of provider is already in progress"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /provider/{providerId}/sync [post]
+func (m providerSyncModule) startProviderSyncHandler(c *gin.Context) {
+	providerID, ok := ginutil.ParseParamUint(c, "providerId")
+	if !ok {
+		return
+	}
+	dbProvider, ok := fetchProviderByID(c, m.Database, providerID, "when starting provider sync")
+	if !ok {
+		return
+	}
+	syncURL, ok := m.getSyncURL(dbProvider.Name)
+	if !ok {
+		ginutil.WriteProblem(c, problems.ProviderSyncNotConfigured.Newf(
+			"No sync URL is configured for providers named %q, needed to sync provider with ID %d.",
+			dbProvider.Name, providerID))
+		return
+	}
+	dbSync, err := createProviderSync(m.Database, providerID, m.Config.SyncTimeout)
+	if errors.Is(err, errProviderSyncInProgress) {
+		ginutil.WriteProblemError(c, err, problems.ProviderSyncInProgress.Newf(
+			"A sync of provider with ID %d is already in progress.",
+			providerID))
+		return
+	}
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed creating sync for provider with ID %d in database.",
+			providerID))
+		return
+	}
+	go m.runProviderSync(dbSync, dbProvider, syncURL)
+
+	c.Header("Location", fmt.Sprintf("%s/%d", strings.TrimSuffix(c.Request.URL.Path, "/"), dbSync.ProviderSyncID))
+	ginrender.Response(c, http.StatusAccepted, modelconv.DBProviderSyncToResponse(dbSync))
+}
+
+// getProviderSyncHandler godoc
+// @id getProviderSync
+// @summary Get status of provider sync.
+// @description Added in v5.3.0.
+// @tags provider
+// @produce json
+// @param providerId path uint true "provider ID" minimum(0)
+// @param syncId path uint true "provider sync ID" minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.ProviderSync "Sync"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Provider sync not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /provider/{providerId}/sync/{syncId} [get]
+func (m providerSyncModule) getProviderSyncHandler(c *gin.Context) {
+	providerID, ok := ginutil.ParseParamUint(c, "providerId")
+	if !ok {
+		return
+	}
+	syncID, ok := ginutil.ParseParamUint(c, "syncId")
+	if !ok {
+		return
+	}
+	var dbSync database.ProviderSync
+	err := m.Database.
+		Where(&database.ProviderSync{ProviderSyncID: syncID, ProviderID: providerID},
+			database.ProviderSyncFields.ProviderSyncID,
+			database.ProviderSyncFields.ProviderID).
+		First(&dbSync).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Sync with ID %d was not found for provider with ID %d.",
+			syncID, providerID))
+		return
+	}
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching sync with ID %d for provider with ID %d.",
+			syncID, providerID))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, modelconv.DBProviderSyncToResponse(dbSync))
+}
+
+func (m providerSyncModule) getSyncURL(providerName string) (string, bool) {
+	// The config keys are lowercased when read from YAML.
+	for name, url := range m.Config.SyncURLs {
+		if strings.EqualFold(name, providerName) && url != "" {
+			return url, true
+		}
+	}
+	return "", false
+}
+
+// createProviderSync adds a new running sync of the provider, or returns
+// errProviderSyncInProgress if the provider already has a running sync that
+// has not timed out.
+func createProviderSync(db *gorm.DB, providerID uint, timeout time.Duration) (database.ProviderSync, error) {
+	now := time.Now().UTC()
+	dbSync := database.ProviderSync{
+		ProviderID: providerID,
+		Status:     database.ProviderSyncRunning,
+		StartedOn:  null.TimeFrom(now),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		query := tx.
+			Model(&database.ProviderSync{}).
+			Where(&database.ProviderSync{ProviderID: providerID, Status: database.ProviderSyncRunning},
+				database.ProviderSyncFields.ProviderID,
+				database.ProviderSyncFields.Status)
+		if timeout > 0 {
+			query = query.Where(database.ProviderSyncColumns.StartedOn+" > ?", now.Add(-timeout))
+		}
+		var running int64
+		if err := query.Count(&running).Error; err != nil {
+			return err
+		}
+		if running > 0 {
+			return errProviderSyncInProgress
+		}
+		return tx.Create(&dbSync).Error
+	})
+	return dbSync, err
+}
+
+// runProviderSync sends the sync request to the provider plugin and saves the
+// outcome. Meant to be run in a separate goroutine.
+func (m providerSyncModule) runProviderSync(dbSync database.ProviderSync, dbProvider database.Provider, syncURL string) {
+	err := sendProviderSyncRequest(syncURL, m.Config.SyncTimeout, providerSyncRequest{
+		SyncID:     dbSync.ProviderSyncID,
+		ProviderID: dbProvider.ProviderID,
+		TokenID:    dbProvider.TokenID,
+		URL:        dbProvider.URL,
+		UploadURL:  dbProvider.UploadURL,
+		Metadata:   dbProvider.Metadata,
+	})
+	dbSync.CompletedOn = null.TimeFrom(time.Now().UTC())
+	if err != nil {
+		log.Warn().
+			WithError(err).
+			WithUint("provider", dbProvider.ProviderID).
+			WithUint("sync", dbSync.ProviderSyncID).
+			Message("Failed to sync provider.")
+		dbSync.Status = database.ProviderSyncFailed
+		dbSync.Error = truncateString(err.Error(), database.ProviderSyncSizes.Error)
+	} else {
+		log.Info().
+			WithUint("provider", dbProvider.ProviderID).
+			WithUint("sync", dbSync.ProviderSyncID).
+			Message("Synced provider.")
+		dbSync.Status = database.ProviderSyncCompleted
+	}
+	if err := m.Database.Save(&dbSync).Error; err != nil {
+		log.Error().
+			WithError(err).
+			WithUint("sync", dbSync.ProviderSyncID).
+			Message("Failed to save provider sync status.")
+	}
+}
+
+func sendProviderSyncRequest(syncURL string, timeout time.Duration, body providerSyncRequest) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Transport: http.DefaultClient.Transport,
+		Timeout:   timeout,
+	}
+	resp, err := client.Post(syncURL, "application/json", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("non-2xx response: %s: %q", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderSyncModule_getSyncURL(t *testing.T) {
+	m := providerSyncModule{Config: &ProvidersConfig{
+		SyncURLs: map[string]string{
+			"github": "http://wharf-provider-github/import/github",
+			"gitlab": "",
+		},
+	}}
+
+	url, ok := m.getSyncURL("GitHub")
+	assert.True(t, ok)
+	assert.Equal(t, "http://wharf-provider-github/import/github", url)
+
+	_, ok = m.getSyncURL("gitlab")
+	assert.False(t, ok, "empty URL")
+
+	_, ok = m.getSyncURL("azuredevops")
+	assert.False(t, ok, "missing URL")
+}
+
+func TestSendProviderSyncRequest(t *testing.T) {
+	var got providerSyncRequest
+	statusCode := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+
+	want := providerSyncRequest{SyncID: 1, ProviderID: 2, TokenID: 3, URL: "https://github.com"}
+	assert.NoError(t, sendProviderSyncRequest(server.URL, 0, want))
+	assert.Equal(t, want, got)
+
+	statusCode = http.StatusUnauthorized
+	assert.EqualError(t, sendProviderSyncRequest(server.URL, 0, want),
+		`non-2xx response: 401 Unauthorized: ""`)
+}
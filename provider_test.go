@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderModule_validProviderName(t *testing.T) {
+	testCases := []struct {
+		name         string
+		allowedNames []string
+		providerName request.ProviderName
+		wantValid    bool
+	}{
+		{name: "well-known", providerName: request.ProviderGitHub, wantValid: true},
+		{name: "custom", providerName: "gitea", wantValid: true},
+		{name: "empty", providerName: "", wantValid: false},
+		{name: "allowed", allowedNames: []string{"gitea"}, providerName: "gitea", wantValid: true},
+		{name: "not allowed", allowedNames: []string{"gitea"}, providerName: request.ProviderGitHub, wantValid: false},
+		{name: "allowlist is case-sensitive", allowedNames: []string{"gitea"}, providerName: "Gitea", wantValid: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := providerModule{Config: &ProvidersConfig{AllowedNames: tc.allowedNames}}
+			got, ok := m.validProviderName(tc.providerName)
+			assert.Equal(t, tc.wantValid, ok)
+			if tc.wantValid {
+				assert.Equal(t, string(tc.providerName), got)
+			}
+		})
+	}
+}
+
+func TestGetProviderListHandler_timeMetadataFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestSqliteDB(t)
+	created := func(day int) *time.Time {
+		date := time.Date(2022, 5, day, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	dbProviders := []database.Provider{
+		{Name: "github", URL: "https://github.com", TimeMetadata: database.TimeMetadata{CreatedAt: created(1)}},
+		{Name: "gitlab", URL: "https://gitlab.com", TimeMetadata: database.TimeMetadata{CreatedAt: created(10)}},
+		{Name: "azuredevops", URL: "https://dev.azure.com", TimeMetadata: database.TimeMetadata{CreatedAt: created(20)}},
+	}
+	require.NoError(t, db.Create(&dbProviders).Error)
+
+	r := gin.New()
+	r.GET("/provider", providerModule{Database: db, Config: &ProvidersConfig{}}.getProviderListHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/provider?createdAfter=2022-05-05T00:00:00Z&createdBefore=2022-05-15T00:00:00Z", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resProviders response.PaginatedProviders
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resProviders))
+	require.Len(t, resProviders.List, 1)
+	assert.Equal(t, response.ProviderName("gitlab"), resProviders.List[0].Name)
+	assert.Equal(t, int64(1), resProviders.TotalCount)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// queryPlanTestDSNEnv is the environment variable of the PostgreSQL connection
+// string used by the query plan tests. The tests are skipped when it is unset.
+//
+// All tables are created in a temporary schema inside a transaction that is
+// rolled back when the test is done, so the database is left untouched.
+const queryPlanTestDSNEnv = "WHARF_TEST_POSTGRES_DSN"
+
+// newTestQueryPlanDB returns a transaction on a PostgreSQL database with the
+// full schema migrated and seeded with builds, or skips the test if no
+// database is configured.
+func newTestQueryPlanDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv(queryPlanTestDSNEnv)
+	if dsn == "" {
+		t.Skipf("Skipping query plan test, as the %s environment variable is not set.", queryPlanTestDSNEnv)
+	}
+	cfg := getGormConfig(DBConfig{})
+	db, err := gorm.Open(postgres.Open(dsn), &cfg)
+	require.NoError(t, err, "open database")
+
+	tx := db.Begin()
+	require.NoError(t, tx.Error, "begin transaction")
+	t.Cleanup(func() { tx.Rollback() })
+
+	schemaName := fmt.Sprintf("wharf_test_query_plan_%d", time.Now().UnixNano())
+	require.NoError(t, tx.Exec("CREATE SCHEMA "+schemaName).Error, "create schema")
+	require.NoError(t, tx.Exec("SET LOCAL search_path TO "+schemaName).Error, "set search path")
+	require.NoError(t, migrateInitSchema(tx), "migrate schema")
+
+	seedTestQueryPlanBuilds(t, tx)
+	require.NoError(t, tx.Exec("ANALYZE").Error, "analyze tables")
+	// Sequential scans are disabled, as the planner otherwise always prefers
+	// them for the small amount of seeded rows.
+	require.NoError(t, tx.Exec("SET LOCAL enable_seqscan = off").Error, "disable sequential scans")
+	return tx
+}
+
+func seedTestQueryPlanBuilds(t *testing.T, tx *gorm.DB) {
+	const (
+		projectCount     = 20
+		buildsPerProject = 200
+	)
+	scheduledOn := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < projectCount; i++ {
+		dbProject := database.Project{Name: fmt.Sprintf("project-%d", i)}
+		require.NoError(t, tx.Create(&dbProject).Error, "create project")
+		dbBuilds := make([]database.Build, buildsPerProject)
+		for j := range dbBuilds {
+			scheduledOn = scheduledOn.Add(time.Minute)
+			dbBuilds[j] = database.Build{
+				ProjectID:   dbProject.ProjectID,
+				StatusID:    database.BuildStatus(j % 5),
+				ScheduledOn: null.TimeFrom(scheduledOn),
+				WorkerID:    fmt.Sprintf("worker-%d", j%50),
+			}
+		}
+		require.NoError(t, tx.CreateInBatches(&dbBuilds, 100).Error, "create builds")
+	}
+}
+
+// explainQuery returns the query plan of the query as text.
+func explainQuery(t *testing.T, tx *gorm.DB, query func(tx *gorm.DB) *gorm.DB) string {
+	sql := tx.ToSQL(query)
+	var planLines []string
+	require.NoError(t, tx.Raw("EXPLAIN "+sql).Scan(&planLines).Error, "explain query: %s", sql)
+	return strings.Join(planLines, "\n")
+}
+
+func TestQueryPlan_buildListFilters(t *testing.T) {
+	tx := newTestQueryPlanDB(t)
+
+	testCases := []struct {
+		name      string
+		wantIndex string
+		query     func(tx *gorm.DB) *gorm.DB
+	}{
+		{
+			name:      "project and status",
+			wantIndex: "build_idx_project_id_status_id",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.
+					Where(&database.Build{ProjectID: 3}).
+					Where(fmt.Sprintf("%s IN ?", database.BuildColumns.StatusID),
+						[]any{int(database.BuildScheduling), int(database.BuildRunning)}).
+					Find(&[]database.Build{})
+			},
+		},
+		{
+			name:      "project ordered by scheduled on",
+			wantIndex: "build_idx_project_id_scheduled_on",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.
+					Where(&database.Build{ProjectID: 3}).
+					Order(database.BuildColumns.ScheduledOn + " DESC").
+					Limit(10).
+					Find(&[]database.Build{})
+			},
+		},
+		{
+			name:      "worker",
+			wantIndex: "build_idx_worker_id",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.
+					Where(&database.Build{WorkerID: "worker-7"}).
+					Find(&[]database.Build{})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := explainQuery(t, tx, tc.query)
+			assert.Contains(t, plan, tc.wantIndex, "query plan:\n%s", plan)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
+)
+
+// The repositories decouple the HTTP handlers from GORM, so that the handlers
+// can be tested using the in-memory fakes instead of a live database. The GORM
+// implementations are found in repository_gorm.go.
+//
+// Modules are moved over to the repositories one at a time, so many modules
+// still query the database directly.
+
+// errRepoNotFound is returned by the repositories when the requested record
+// does not exist.
+var errRepoNotFound = errors.New("record not found")
+
+// ProjectRepo reads and writes projects.
+type ProjectRepo interface {
+	// Exists returns true if a project exists by the ID.
+	Exists(projectID uint) (bool, error)
+}
+
+// BuildRepo reads and writes builds.
+type BuildRepo interface {
+	// Exists returns true if a build exists by the ID.
+	Exists(buildID uint) (bool, error)
+	// FindLatest returns the latest build of the project, or errRepoNotFound if
+	// the project has no matching builds.
+	FindLatest(projectID uint, filter BuildLatestFilter) (database.Build, error)
+}
+
+// BuildLatestFilter holds the optional filters of BuildRepo.FindLatest. Nil
+// values are not filtered on.
+type BuildLatestFilter struct {
+	GitBranch *string
+	Stage     *string
+}
+
+// ArtifactRepo reads and writes build artifacts.
+type ArtifactRepo interface {
+	// List returns a page of the build's artifacts, together with the total
+	// count of matching artifacts, which is totalCountSkipped if
+	// ArtifactListOptions.SkipTotal is true.
+	List(buildID uint, opts ArtifactListOptions) ([]database.Artifact, int64, error)
+	// ListByProject returns a page of the artifacts of all of the project's
+	// builds, with their builds preloaded and without their data, together
+	// with the total count the same way as List.
+	ListByProject(projectID uint, opts ArtifactListOptions) ([]database.Artifact, int64, error)
+	// Get returns the build's artifact, or errRepoNotFound if the build has no
+	// artifact by the ID.
+	Get(buildID, artifactID uint) (database.Artifact, error)
+	// ListByFileExtension returns all of the build's artifacts with file names
+	// ending with the extension, such as ".trx".
+	ListByFileExtension(buildID uint, extension string) ([]database.Artifact, error)
+	// Create adds the artifact, and sets its ID.
+	Create(dbArtifact *database.Artifact) error
+	// Delete removes the build's artifact, or returns errRepoNotFound if the
+	// build has no artifact by the ID. Any test results parsed from the
+	// artifact are kept, but no longer reference the artifact.
+	Delete(buildID, artifactID uint) error
+}
+
+// ArtifactListOptions holds the pagination, sorting, and filters of
+// ArtifactRepo.List and ArtifactRepo.ListByProject. Nil filters are not filtered on. The "Match" filters are
+// case-insensitive "contains" matches.
+type ArtifactListOptions struct {
+	Limit     int
+	Offset    int
+	SkipTotal bool
+	// OrderBy defaults to sorting on the artifact ID in descending order.
+	OrderBy orderby.Slice
+
+	Name          *string
+	FileName      *string
+	NameMatch     *string
+	FileNameMatch *string
+	// Match filters on either the name or file name.
+	Match *string
+	// MinSize and MaxSize filter on the number of bytes, inclusively.
+	MinSize *int64
+	MaxSize *int64
+}
+
+// validateRepoObjExistsByID writes a problem response and returns false if the
+// object does not exist by the ID, or if the lookup failed.
+func validateRepoObjExistsByID(c *gin.Context, exists func(id uint) (bool, error), id uint, name, whenMsg string) bool {
+	ok, err := exists(id)
+	if err != nil {
+		writeDBFetchObjByIDErrorProblem(c, err, id, name, whenMsg)
+		return false
+	}
+	if !ok {
+		writeDBFetchObjByIDNotFoundProblem(c, id, name, whenMsg)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
+)
+
+// The fake repositories keep all records in memory, and are used to test the
+// handlers without a database. All methods return the err field if set.
+
+type fakeProjectRepo struct {
+	projects []database.Project
+	err      error
+}
+
+func (r *fakeProjectRepo) Exists(projectID uint) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	for _, p := range r.projects {
+		if p.ProjectID == projectID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeBuildRepo struct {
+	builds []database.Build
+	err    error
+}
+
+func (r *fakeBuildRepo) Exists(buildID uint) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	for _, b := range r.builds {
+		if b.BuildID == buildID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeBuildRepo) FindLatest(projectID uint, filter BuildLatestFilter) (database.Build, error) {
+	if r.err != nil {
+		return database.Build{}, r.err
+	}
+	var latest *database.Build
+	for i, b := range r.builds {
+		if b.ProjectID != projectID ||
+			(filter.GitBranch != nil && b.GitBranch != *filter.GitBranch) ||
+			(filter.Stage != nil && b.Stage != *filter.Stage) {
+			continue
+		}
+		if latest == nil || b.BuildID > latest.BuildID {
+			latest = &r.builds[i]
+		}
+	}
+	if latest == nil {
+		return database.Build{}, errRepoNotFound
+	}
+	return *latest, nil
+}
+
+type fakeArtifactRepo struct {
+	artifacts []database.Artifact
+	err       error
+}
+
+func (r *fakeArtifactRepo) List(buildID uint, opts ArtifactListOptions) ([]database.Artifact, int64, error) {
+	return r.list(func(a database.Artifact) bool {
+		return a.BuildID == buildID
+	}, opts)
+}
+
+// ListByProject only finds artifacts that have their Build field set.
+func (r *fakeArtifactRepo) ListByProject(projectID uint, opts ArtifactListOptions) ([]database.Artifact, int64, error) {
+	matches, totalCount, err := r.list(func(a database.Artifact) bool {
+		return a.Build != nil && a.Build.ProjectID == projectID
+	}, opts)
+	for i := range matches {
+		matches[i].Data = nil
+	}
+	return matches, totalCount, err
+}
+
+func (r *fakeArtifactRepo) list(include func(database.Artifact) bool, opts ArtifactListOptions) ([]database.Artifact, int64, error) {
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	var matches []database.Artifact
+	for _, a := range r.artifacts {
+		if !include(a) ||
+			(opts.Name != nil && a.Name != *opts.Name) ||
+			(opts.FileName != nil && a.FileName != *opts.FileName) ||
+			!fakeRepoMatch(opts.NameMatch, a.Name) ||
+			!fakeRepoMatch(opts.FileNameMatch, a.FileName) ||
+			!(fakeRepoMatch(opts.Match, a.Name) || fakeRepoMatch(opts.Match, a.FileName)) ||
+			(opts.MinSize != nil && a.Size < *opts.MinSize) ||
+			(opts.MaxSize != nil && a.Size > *opts.MaxSize) {
+			continue
+		}
+		matches = append(matches, a)
+	}
+	orderBy := opts.OrderBy
+	if len(orderBy) == 0 {
+		orderBy = orderby.Slice{defaultGetArtifactsOrderBy}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		for _, col := range orderBy {
+			a, b := fakeArtifactColumn(matches[i], col.Name), fakeArtifactColumn(matches[j], col.Name)
+			if a == b {
+				continue
+			}
+			if col.Direction == orderby.Desc {
+				return a > b
+			}
+			return a < b
+		}
+		return false
+	})
+	totalCount := int64(len(matches))
+	if opts.SkipTotal {
+		totalCount = totalCountSkipped
+	}
+	if opts.Offset > 0 {
+		if opts.Offset > len(matches) {
+			opts.Offset = len(matches)
+		}
+		matches = matches[opts.Offset:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(matches) {
+		matches = matches[:opts.Limit]
+	}
+	return matches, totalCount, nil
+}
+
+func (r *fakeArtifactRepo) Get(buildID, artifactID uint) (database.Artifact, error) {
+	if r.err != nil {
+		return database.Artifact{}, r.err
+	}
+	for _, a := range r.artifacts {
+		if a.BuildID == buildID && a.ArtifactID == artifactID {
+			return a, nil
+		}
+	}
+	return database.Artifact{}, errRepoNotFound
+}
+
+func (r *fakeArtifactRepo) ListByFileExtension(buildID uint, extension string) ([]database.Artifact, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	matches := []database.Artifact{}
+	for _, a := range r.artifacts {
+		if a.BuildID == buildID && strings.HasSuffix(a.FileName, extension) {
+			matches = append(matches, a)
+		}
+	}
+	return matches, nil
+}
+
+func (r *fakeArtifactRepo) Create(dbArtifact *database.Artifact) error {
+	if r.err != nil {
+		return r.err
+	}
+	dbArtifact.ArtifactID = uint(len(r.artifacts) + 1)
+	r.artifacts = append(r.artifacts, *dbArtifact)
+	return nil
+}
+
+func (r *fakeArtifactRepo) Delete(buildID, artifactID uint) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, a := range r.artifacts {
+		if a.BuildID == buildID && a.ArtifactID == artifactID {
+			r.artifacts = append(r.artifacts[:i], r.artifacts[i+1:]...)
+			return nil
+		}
+	}
+	return errRepoNotFound
+}
+
+// fakeRepoMatch mimics the case-insensitive "contains" matching of
+// whereLikeScope.
+func fakeRepoMatch(filter *string, value string) bool {
+	if filter == nil || *filter == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(*filter))
+}
+
+// fakeArtifactColumn returns the value of the column, formatted so that
+// comparing the strings gives the same order as comparing the values.
+func fakeArtifactColumn(a database.Artifact, column database.SafeSQLName) string {
+	switch column {
+	case database.ArtifactColumns.ArtifactID:
+		return fmt.Sprintf("%020d", a.ArtifactID)
+	case database.ArtifactColumns.Name:
+		return a.Name
+	case database.ArtifactColumns.FileName:
+		return a.FileName
+	default:
+		panic(fmt.Sprintf("fake artifact repo: unsupported order by column: %s", column))
+	}
+}
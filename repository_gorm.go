@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
+	"gorm.io/gorm"
+)
+
+// repositories holds all repositories, as used by the modules.
+type repositories struct {
+	Projects  ProjectRepo
+	Builds    BuildRepo
+	Artifacts ArtifactRepo
+}
+
+func newGormRepositories(db *gorm.DB) repositories {
+	return repositories{
+		Projects:  gormProjectRepo{db},
+		Builds:    gormBuildRepo{db},
+		Artifacts: gormArtifactRepo{db},
+	}
+}
+
+// gormRepoError converts GORM's not found error to errRepoNotFound, so that
+// callers do not have to depend on GORM.
+func gormRepoError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errRepoNotFound
+	}
+	return err
+}
+
+func gormExistsByID(db *gorm.DB, modelPtr any, id uint) (bool, error) {
+	var count int64
+	if err := db.Model(modelPtr).Where(id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+type gormProjectRepo struct {
+	db *gorm.DB
+}
+
+func (r gormProjectRepo) Exists(projectID uint) (bool, error) {
+	return gormExistsByID(r.db, &database.Project{}, projectID)
+}
+
+type gormBuildRepo struct {
+	db *gorm.DB
+}
+
+func (r gormBuildRepo) Exists(buildID uint) (bool, error) {
+	return gormExistsByID(r.db, &database.Build{}, buildID)
+}
+
+func (r gormBuildRepo) FindLatest(projectID uint, filter BuildLatestFilter) (database.Build, error) {
+	var where wherefields.Collection
+	var dbBuild database.Build
+	err := r.db.
+		Where(&database.Build{
+			ProjectID: projectID,
+			GitBranch: where.String(database.BuildFields.GitBranch, filter.GitBranch),
+			Stage:     where.String(database.BuildFields.Stage, filter.Stage),
+		}, append(where.NonNilFieldNames(), database.BuildFields.ProjectID)...).
+		Order(database.BuildColumns.BuildID + " DESC").
+		First(&dbBuild).
+		Error
+	return dbBuild, gormRepoError(err)
+}
+
+type gormArtifactRepo struct {
+	db *gorm.DB
+}
+
+var defaultGetArtifactsOrderBy = orderby.Column{Name: database.ArtifactColumns.ArtifactID, Direction: orderby.Desc}
+
+func (r gormArtifactRepo) List(buildID uint, opts ArtifactListOptions) ([]database.Artifact, int64, error) {
+	query := r.db.
+		Where(&database.Artifact{BuildID: buildID}, database.ArtifactFields.BuildID).
+		Scopes(artifactListOptionsScope(opts))
+
+	var dbArtifacts []database.Artifact
+	var totalCount int64
+	err := findDBPaginatedSliceAndTotalCount(query, opts.Limit, opts.Offset, opts.SkipTotal, &dbArtifacts, &totalCount)
+	return dbArtifacts, totalCount, err
+}
+
+func (r gormArtifactRepo) ListByProject(projectID uint, opts ArtifactListOptions) ([]database.Artifact, int64, error) {
+	projectBuildIDs := r.db.
+		Model(&database.Build{}).
+		Select(database.BuildColumns.BuildID).
+		Where(&database.Build{ProjectID: projectID}, database.BuildFields.ProjectID)
+	query := r.db.
+		Preload(database.ArtifactFields.Build).
+		Omit(database.ArtifactColumns.Data).
+		Where(database.ArtifactColumns.BuildID+" IN (?)", projectBuildIDs).
+		Scopes(artifactListOptionsScope(opts))
+
+	var dbArtifacts []database.Artifact
+	var totalCount int64
+	err := findDBPaginatedSliceAndTotalCount(query, opts.Limit, opts.Offset, opts.SkipTotal, &dbArtifacts, &totalCount)
+	return dbArtifacts, totalCount, err
+}
+
+// artifactListOptionsScope applies the sorting and filters of the options,
+// but not the pagination.
+func artifactListOptionsScope(opts ArtifactListOptions) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		var where wherefields.Collection
+		db = db.
+			Clauses(opts.OrderBy.ClauseIfNone(defaultGetArtifactsOrderBy)).
+			Scopes(
+				whereLikeScope(map[database.SafeSQLName]*string{
+					database.ArtifactColumns.Name:     opts.NameMatch,
+					database.ArtifactColumns.FileName: opts.FileNameMatch,
+				}),
+				whereAnyLikeScope(
+					opts.Match,
+					database.ArtifactColumns.Name,
+					database.ArtifactColumns.FileName,
+				),
+				optionalInt64RangeScope(database.ArtifactColumns.Size, opts.MinSize, opts.MaxSize),
+			)
+		dbFilter := database.Artifact{
+			Name:     where.String(database.ArtifactFields.Name, opts.Name),
+			FileName: where.String(database.ArtifactFields.FileName, opts.FileName),
+		}
+		if fieldNames := where.NonNilFieldNames(); len(fieldNames) > 0 {
+			db = db.Where(&dbFilter, fieldNames...)
+		}
+		return db
+	}
+}
+
+func (r gormArtifactRepo) Get(buildID, artifactID uint) (database.Artifact, error) {
+	var dbArtifact database.Artifact
+	err := r.db.
+		Where(&database.Artifact{
+			BuildID:    buildID,
+			ArtifactID: artifactID}).
+		First(&dbArtifact).
+		Error
+	return dbArtifact, gormRepoError(err)
+}
+
+func (r gormArtifactRepo) ListByFileExtension(buildID uint, extension string) ([]database.Artifact, error) {
+	dbArtifacts := []database.Artifact{}
+	err := r.db.
+		Where(&database.Artifact{BuildID: buildID}).
+		Where(database.ArtifactColumns.FileName+" LIKE ?", "%"+extension).
+		Find(&dbArtifacts).
+		Error
+	return dbArtifacts, err
+}
+
+func (r gormArtifactRepo) Create(dbArtifact *database.Artifact) error {
+	return r.db.Create(dbArtifact).Error
+}
+
+func (r gormArtifactRepo) Delete(buildID, artifactID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Where(&database.Artifact{BuildID: buildID, ArtifactID: artifactID}).
+			Delete(&database.Artifact{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRepoNotFound
+		}
+		// Not relying on the foreign keys' "ON DELETE SET NULL", as foreign
+		// keys are not enforced in all databases, such as in Sqlite by default.
+		for _, model := range []any{&database.TestResultSummary{}, &database.TestResultDetail{}} {
+			if err := tx.
+				Model(model).
+				Where(database.TestResultSummaryColumns.ArtifactID+" = ?", artifactID).
+				Update(database.TestResultSummaryColumns.ArtifactID, nil).
+				Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
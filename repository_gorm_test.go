@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGormRepositories(t *testing.T) {
+	db := newTestSqliteDB(t)
+	repos := newGormRepositories(db)
+	dbProject := database.Project{Name: "wharf-api"}
+	require.NoError(t, db.Create(&dbProject).Error)
+	dbBuilds := []database.Build{
+		{ProjectID: dbProject.ProjectID, GitBranch: "master", Stage: "ALL"},
+		{ProjectID: dbProject.ProjectID, GitBranch: "feature", Stage: "ALL"},
+	}
+	require.NoError(t, db.Create(&dbBuilds).Error)
+
+	exists, err := repos.Projects.Exists(dbProject.ProjectID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repos.Builds.Exists(dbBuilds[1].BuildID + 1)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	master := "master"
+	latest, err := repos.Builds.FindLatest(dbProject.ProjectID, BuildLatestFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, dbBuilds[1].BuildID, latest.BuildID)
+	latest, err = repos.Builds.FindLatest(dbProject.ProjectID, BuildLatestFilter{GitBranch: &master})
+	require.NoError(t, err)
+	assert.Equal(t, dbBuilds[0].BuildID, latest.BuildID)
+	_, err = repos.Builds.FindLatest(dbProject.ProjectID+1, BuildLatestFilter{})
+	assert.ErrorIs(t, err, errRepoNotFound)
+
+	buildID := dbBuilds[0].BuildID
+	for _, fileName := range []string{"cache.tar", "results.trx"} {
+		require.NoError(t, repos.Artifacts.Create(&database.Artifact{BuildID: buildID, Name: fileName, FileName: fileName}))
+	}
+	match := "TRX"
+	dbArtifacts, totalCount, err := repos.Artifacts.List(buildID, ArtifactListOptions{Match: &match})
+	require.NoError(t, err)
+	require.Len(t, dbArtifacts, 1)
+	assert.Equal(t, "results.trx", dbArtifacts[0].FileName)
+	assert.Equal(t, int64(1), totalCount)
+
+	dbArtifacts, err = repos.Artifacts.ListByFileExtension(buildID, ".trx")
+	require.NoError(t, err)
+	require.Len(t, dbArtifacts, 1)
+
+	dbArtifact, err := repos.Artifacts.Get(buildID, dbArtifacts[0].ArtifactID)
+	require.NoError(t, err)
+	assert.Equal(t, "results.trx", dbArtifact.FileName)
+	_, err = repos.Artifacts.Get(dbBuilds[1].BuildID, dbArtifacts[0].ArtifactID)
+	assert.ErrorIs(t, err, errRepoNotFound)
+
+	dbOtherProject := database.Project{Name: "wharf-web"}
+	require.NoError(t, db.Create(&dbOtherProject).Error)
+	dbOtherBuild := database.Build{ProjectID: dbOtherProject.ProjectID, GitBranch: "master", Stage: "ALL"}
+	require.NoError(t, db.Create(&dbOtherBuild).Error)
+	for _, buildID := range []uint{dbBuilds[0].BuildID, dbBuilds[1].BuildID, dbOtherBuild.BuildID} {
+		require.NoError(t, repos.Artifacts.Create(&database.Artifact{
+			BuildID: buildID, Name: "coverage", FileName: "coverage.html", Data: []byte("<html>"),
+		}))
+	}
+	coverage := "coverage"
+	dbArtifacts, totalCount, err = repos.Artifacts.ListByProject(dbProject.ProjectID, ArtifactListOptions{Limit: 1, FileNameMatch: &coverage})
+	require.NoError(t, err)
+	require.Len(t, dbArtifacts, 1)
+	assert.Equal(t, int64(2), totalCount)
+	require.NotNil(t, dbArtifacts[0].Build)
+	assert.Equal(t, "feature", dbArtifacts[0].Build.GitBranch, "must list latest first")
+	assert.Empty(t, dbArtifacts[0].Data, "must not load data")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-core/pkg/logger"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+)
+
+// requestIDHeader is the HTTP header used to correlate a request across
+// wharf-api, the execution engines, and any proxies in between.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDContextKey is the gin.Context key of the request ID.
+const requestIDContextKey = "requestId"
+
+// requestIDMaxLength is the maximum length of request IDs sent by clients.
+// Longer or otherwise invalid request IDs are replaced with generated ones.
+const requestIDMaxLength = 200
+
+// requestIDMiddleware reads the request ID from the X-Request-ID request
+// header, or generates a new one if missing or invalid. The request ID is
+// added to the response headers, and to the "instance" field of all problem
+// responses as the "requestId" query parameter.
+func requestIDMiddleware(c *gin.Context) {
+	requestID := c.GetHeader(requestIDHeader)
+	if !isValidRequestID(requestID) {
+		requestID = newRequestID()
+	}
+	c.Set(requestIDContextKey, requestID)
+	c.Header(requestIDHeader, requestID)
+	c.Writer = &requestIDResponseWriter{
+		ResponseWriter: c.Writer,
+		requestID:      requestID,
+	}
+	c.Next()
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > requestIDMaxLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// getRequestID returns the ID of the request, as set by requestIDMiddleware.
+func getRequestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// requestLog returns a logger that adds the ID of the request to all log
+// events.
+func requestLog(c *gin.Context) logger.Logger {
+	return logWithRequestID(getRequestID(c))
+}
+
+// logWithRequestID returns a logger that adds the request ID to all log
+// events, unless the request ID is empty.
+func logWithRequestID(requestID string) logger.Logger {
+	if requestID == "" {
+		return log
+	}
+	return requestIDLogger{Logger: log, requestID: requestID}
+}
+
+type requestIDLogger struct {
+	logger.Logger
+	requestID string
+}
+
+func (l requestIDLogger) Debug() logger.Event { return l.with(l.Logger.Debug()) }
+func (l requestIDLogger) Info() logger.Event  { return l.with(l.Logger.Info()) }
+func (l requestIDLogger) Warn() logger.Event  { return l.with(l.Logger.Warn()) }
+func (l requestIDLogger) Error() logger.Event { return l.with(l.Logger.Error()) }
+func (l requestIDLogger) Panic() logger.Event { return l.with(l.Logger.Panic()) }
+
+func (l requestIDLogger) with(ev logger.Event) logger.Event {
+	return ev.WithString("requestId", l.requestID)
+}
+
+// requestIDResponseWriter adds the request ID to the "instance" field of
+// problem responses, as they are written by the wharf-core ginutil package.
+type requestIDResponseWriter struct {
+	gin.ResponseWriter
+	requestID string
+}
+
+func (w *requestIDResponseWriter) Write(b []byte) (int, error) {
+	if w.Header().Get("Content-Type") != problem.HTTPContentType {
+		return w.ResponseWriter.Write(b)
+	}
+	var prob problem.Response
+	if err := json.Unmarshal(b, &prob); err != nil {
+		return w.ResponseWriter.Write(b)
+	}
+	prob.Instance = addRequestIDToInstance(prob.Instance, w.requestID)
+	probBytes, err := json.Marshal(prob)
+	if err != nil {
+		return w.ResponseWriter.Write(b)
+	}
+	if _, err := w.ResponseWriter.Write(probBytes); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func addRequestIDToInstance(instance, requestID string) string {
+	u, err := url.Parse(instance)
+	if err != nil {
+		return instance
+	}
+	q := u.Query()
+	q.Set(requestIDContextKey, requestID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
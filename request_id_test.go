@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(requestIDMiddleware)
+	r.GET("/ok", func(c *gin.Context) {
+		c.String(http.StatusOK, getRequestID(c))
+	})
+	r.GET("/problem", func(c *gin.Context) {
+		ginutil.WriteProblemError(c, errors.New("some error"), problems.InvalidParam.With(problem.Response{
+			Instance: c.Request.RequestURI + "#name",
+		}))
+	})
+	return r
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{name: "generated if missing", requestID: "", wantSame: false},
+		{name: "kept if valid", requestID: "abc-123", wantSame: true},
+		{name: "replaced if whitespace", requestID: "abc 123", wantSame: false},
+		{name: "replaced if too long", requestID: strings.Repeat("a", requestIDMaxLength+1), wantSame: false},
+	}
+	r := newTestRequestIDRouter()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+			if tc.requestID != "" {
+				req.Header.Set(requestIDHeader, tc.requestID)
+			}
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(requestIDHeader)
+			assert.NotEmpty(t, got)
+			assert.Equal(t, got, w.Body.String())
+			if tc.wantSame {
+				assert.Equal(t, tc.requestID, got)
+			} else {
+				assert.NotEqual(t, tc.requestID, got)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware_problemInstance(t *testing.T) {
+	r := newTestRequestIDRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/problem?limit=5", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, problem.HTTPContentType, w.Header().Get("Content-Type"))
+	var prob problem.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prob))
+	assert.Equal(t, "/problem?limit=5&requestId=abc-123#name", prob.Instance)
+	assert.Equal(t, []string{"some error"}, prob.Errors)
+}
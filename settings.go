@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gopkg.in/typ.v4/maps"
+	"gopkg.in/typ.v4/slices"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type settingsModule struct {
+	Database *gorm.DB
+	Config   *Config
+}
+
+func (m settingsModule) Register(g *gin.RouterGroup) {
+	userSettings := g.Group("/user/settings")
+	{
+		userSettings.GET("", m.getUserSettingsHandler)
+		userSettings.PUT("", m.updateUserSettingsHandler)
+	}
+	adminSettings := g.Group("/admin/settings")
+	{
+		adminSettings.GET("", m.getInstanceSettingsHandler)
+		adminSettings.PUT("", m.updateInstanceSettingsHandler)
+	}
+}
+
+// settingType is an enum of the value types a setting can have.
+type settingType string
+
+const (
+	settingTypeString  settingType = "string"
+	settingTypeInteger settingType = "integer"
+	settingTypeBoolean settingType = "boolean"
+)
+
+// settingDefinition declares the type, default value, and constraints of a
+// single setting. Only settings with a definition can be stored.
+type settingDefinition struct {
+	typ          settingType
+	defaultValue any
+	// min and max are only used for integer settings.
+	min, max int64
+	// maxLength is only used for string settings, where zero means no limit.
+	maxLength int
+	// values is the list of allowed values of string settings, or all values
+	// if empty.
+	values []string
+}
+
+// userSettingDefinitions is the allowlist of settings stored per user.
+var userSettingDefinitions = map[string]settingDefinition{
+	"theme": {
+		typ:          settingTypeString,
+		defaultValue: "system",
+		values:       []string{"system", "light", "dark"},
+	},
+	// pageSize defaults to null, meaning the instance-wide defaultPageSize
+	// should be used.
+	"pageSize": {
+		typ:          settingTypeInteger,
+		defaultValue: nil,
+		min:          1,
+		max:          1000,
+	},
+	"timeZone": {
+		typ:          settingTypeString,
+		defaultValue: "",
+		maxLength:    100,
+	},
+	"showLogTimestamps": {
+		typ:          settingTypeBoolean,
+		defaultValue: true,
+	},
+}
+
+// instanceSettingDefinitions is the allowlist of instance-wide settings.
+var instanceSettingDefinitions = map[string]settingDefinition{
+	"defaultPageSize": {
+		typ:          settingTypeInteger,
+		defaultValue: int64(25),
+		min:          1,
+		max:          1000,
+	},
+	"bannerMessage": {
+		typ:          settingTypeString,
+		defaultValue: "",
+		maxLength:    1000,
+	},
+	"bannerLevel": {
+		typ:          settingTypeString,
+		defaultValue: "info",
+		values:       []string{"info", "warning", "error"},
+	},
+}
+
+// getUserSettingsHandler godoc
+// @id getUserSettings
+// @summary Get settings of the current user.
+// @description Returns all settings of the user identified by the OIDC access token's `sub` claim.
+// @description Settings that have not been set hold their default values.
+// @description Allowed settings are `theme` ("system", "light", or "dark"), `pageSize` (1-1000, or null to use the instance's `defaultPageSize`),
+// @description `timeZone` (string), and `showLogTimestamps` (boolean).
+// @description Added in v5.3.0.
+// @tags settings
+// @produce json
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Settings "User settings"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /user/settings [get]
+func (m settingsModule) getUserSettingsHandler(c *gin.Context) {
+	subject, ok := getUserSubject(c, "User settings")
+	if !ok {
+		return
+	}
+	m.renderSettings(c, database.SettingScopeUser, subject, userSettingDefinitions)
+}
+
+// updateUserSettingsHandler godoc
+// @id updateUserSettings
+// @summary Update settings of the current user.
+// @description Updates the given settings of the user identified by the OIDC access token's `sub` claim.
+// @description Settings left out of the request are left untouched, while settings set to null are reset to their default values.
+// @description Added in v5.3.0.
+// @tags settings
+// @accept json
+// @produce json
+// @param settings body request.Settings _ "Settings to update"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Settings "Updated user settings"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /user/settings [put]
+func (m settingsModule) updateUserSettingsHandler(c *gin.Context) {
+	subject, ok := getUserSubject(c, "User settings")
+	if !ok {
+		return
+	}
+	m.updateSettings(c, database.SettingScopeUser, subject, userSettingDefinitions)
+}
+
+// getInstanceSettingsHandler godoc
+// @id getInstanceSettings
+// @summary Get instance-wide settings.
+// @description Returns all settings that apply to the whole wharf-api instance.
+// @description Settings that have not been set hold their default values.
+// @description Allowed settings are `defaultPageSize` (1-1000), `bannerMessage` (string),
+// @description and `bannerLevel` ("info", "warning", or "error").
+// @description Added in v5.3.0.
+// @tags settings
+// @produce json
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Settings "Instance-wide settings"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /admin/settings [get]
+func (m settingsModule) getInstanceSettingsHandler(c *gin.Context) {
+	m.renderSettings(c, database.SettingScopeInstance, "", instanceSettingDefinitions)
+}
+
+// updateInstanceSettingsHandler godoc
+// @id updateInstanceSettings
+// @summary Update instance-wide settings.
+// @description Updates the given settings that apply to the whole wharf-api instance.
+// @description Settings left out of the request are left untouched, while settings set to null are reset to their default values.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags settings
+// @accept json
+// @produce json
+// @param settings body request.Settings _ "Settings to update"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Settings "Updated instance-wide settings"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /admin/settings [put]
+func (m settingsModule) updateInstanceSettingsHandler(c *gin.Context) {
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	m.updateSettings(c, database.SettingScopeInstance, "", instanceSettingDefinitions)
+}
+
+// getUserSubject returns the OIDC subject of the current user, or writes an
+// unauthorized problem if there is none, where feature is the name of the
+// feature that requires it, such as "User settings".
+func getUserSubject(c *gin.Context, feature string) (string, bool) {
+	claims, _ := getOIDCClaims(c)
+	subject := getOIDCClaimString(claims, "sub")
+	if subject == "" {
+		ginutil.WriteUnauthorized(c, fmt.Sprintf(
+			"%s requires OIDC to be enabled, and an OIDC access token with a 'sub' claim.",
+			feature))
+		return "", false
+	}
+	return subject, true
+}
+
+func (m settingsModule) renderSettings(c *gin.Context, scope database.SettingScope, subject string, defs map[string]settingDefinition) {
+	settings, err := findSettings(m.Database, scope, subject, defs)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching %s settings from database.", scope))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, settings)
+}
+
+func (m settingsModule) updateSettings(c *gin.Context, scope database.SettingScope, subject string, defs map[string]settingDefinition) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		ginutil.WriteBodyReadError(c, err, fmt.Sprintf(
+			"Failed reading the request body for %s settings to update.", scope))
+		return
+	}
+	values, err := parseSettingsUpdate(body, defs)
+	if err != nil {
+		var settingsErr invalidSettingsError
+		if errors.As(err, &settingsErr) {
+			ginutil.WriteProblemError(c, err, problems.SettingsInvalid.With(problem.Response{
+				Detail: fmt.Sprintf(
+					"One or more %s settings are unknown or have invalid values.", scope),
+				Errors: settingsErr.problems,
+			}))
+			return
+		}
+		writeInvalidBindError(c, err, fmt.Sprintf(
+			"One or more parameters failed to parse when reading the request body for %s settings to update.", scope))
+		return
+	}
+	if err := saveSettings(m.Database, scope, subject, values); err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed writing %s settings to database.", scope))
+		return
+	}
+	m.renderSettings(c, scope, subject, defs)
+}
+
+// invalidSettingsError is returned by parseSettingsUpdate when the settings
+// are not in the allowlist, or do not match their declared types.
+type invalidSettingsError struct {
+	problems []string
+}
+
+func (err invalidSettingsError) Error() string {
+	return "invalid settings: " + strings.Join(err.problems, "; ")
+}
+
+// parseSettingsUpdate parses and validates the JSON-encoded settings. Settings
+// set to null are returned with a nil value.
+func parseSettingsUpdate(body []byte, defs map[string]settingDefinition) (map[string]any, error) {
+	var reqSettings request.Settings
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&reqSettings); err != nil {
+		return nil, err
+	}
+	keys := maps.Keys(reqSettings)
+	sort.Strings(keys)
+	values := make(map[string]any, len(reqSettings))
+	var problems []string
+	for _, key := range keys {
+		def, ok := defs[key]
+		if !ok {
+			allowed := maps.Keys(defs)
+			sort.Strings(allowed)
+			problems = append(problems, fmt.Sprintf(
+				"unknown setting %q, allowed settings are: %s",
+				key, strings.Join(allowed, ", ")))
+			continue
+		}
+		if reqSettings[key] == nil {
+			values[key] = nil
+			continue
+		}
+		value, err := coerceSettingValue(def, reqSettings[key])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("setting %q: %s", key, err))
+			continue
+		}
+		values[key] = value
+	}
+	if len(problems) > 0 {
+		return nil, invalidSettingsError{problems}
+	}
+	return values, nil
+}
+
+// coerceSettingValue validates the value against the setting definition, and
+// returns it as a string, int64, or bool.
+func coerceSettingValue(def settingDefinition, value any) (any, error) {
+	switch def.typ {
+	case settingTypeInteger:
+		num, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("expected an integer, but got %T", value)
+		}
+		i, err := strconv.ParseInt(num.String(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer, but got %s", num)
+		}
+		if i < def.min || i > def.max {
+			return nil, fmt.Errorf("expected an integer between %d and %d, but got %d", def.min, def.max, i)
+		}
+		return i, nil
+	case settingTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean, but got %T", value)
+		}
+		return b, nil
+	default:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, but got %T", value)
+		}
+		if len(def.values) > 0 && !slices.Contains(def.values, str) {
+			return nil, fmt.Errorf("value %q is not allowed, allowed values are: %s",
+				str, strings.Join(def.values, ", "))
+		}
+		if def.maxLength > 0 && len(str) > def.maxLength {
+			return nil, fmt.Errorf("value is too long: max %d chars, but was: %d", def.maxLength, len(str))
+		}
+		return str, nil
+	}
+}
+
+// findSettings returns all settings in the allowlist, where settings that have
+// not been set hold their default values.
+func findSettings(db *gorm.DB, scope database.SettingScope, subject string, defs map[string]settingDefinition) (response.Settings, error) {
+	var dbSettings []database.Setting
+	if err := db.
+		Where(&database.Setting{Scope: scope, Subject: subject},
+			database.SettingFields.Scope, database.SettingFields.Subject).
+		Find(&dbSettings).Error; err != nil {
+		return nil, err
+	}
+	settings := make(response.Settings, len(defs))
+	for key, def := range defs {
+		settings[key] = def.defaultValue
+	}
+	for _, dbSetting := range dbSettings {
+		def, ok := defs[dbSetting.Key]
+		if !ok {
+			continue
+		}
+		value, err := decodeSettingValue(def, dbSetting.Value)
+		if err != nil {
+			log.Warn().
+				WithError(err).
+				WithString("scope", string(scope)).
+				WithString("key", dbSetting.Key).
+				Message("Ignoring invalid stored setting value, using the default value instead.")
+			continue
+		}
+		settings[dbSetting.Key] = value
+	}
+	return settings, nil
+}
+
+func decodeSettingValue(def settingDefinition, encoded string) (any, error) {
+	var value any
+	dec := json.NewDecoder(strings.NewReader(encoded))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return coerceSettingValue(def, value)
+}
+
+// saveSettings stores the given setting values, where settings with a nil
+// value are deleted so that they fall back to their default values.
+func saveSettings(db *gorm.DB, scope database.SettingScope, subject string, values map[string]any) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for key, value := range values {
+			if value == nil {
+				if err := tx.
+					Where(&database.Setting{Scope: scope, Subject: subject, Key: key},
+						database.SettingFields.Scope, database.SettingFields.Subject, database.SettingFields.Key).
+					Delete(&database.Setting{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if err := tx.
+				Clauses(clause.OnConflict{
+					Columns: []clause.Column{
+						{Name: database.SettingColumns.Scope},
+						{Name: database.SettingColumns.Subject},
+						{Name: database.SettingColumns.Key},
+					},
+					DoUpdates: clause.AssignmentColumns([]string{
+						database.SettingColumns.Value,
+						database.SettingColumns.UpdatedAt,
+					}),
+				}).
+				Create(&database.Setting{
+					Scope:   scope,
+					Subject: subject,
+					Key:     key,
+					Value:   string(encoded),
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
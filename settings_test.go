@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSettingsUpdate(t *testing.T) {
+	got, err := parseSettingsUpdate(
+		[]byte(`{"theme":"dark","pageSize":50,"timeZone":null,"showLogTimestamps":false}`),
+		userSettingDefinitions)
+	require.NoError(t, err)
+	want := map[string]any{
+		"theme":             "dark",
+		"pageSize":          int64(50),
+		"timeZone":          nil,
+		"showLogTimestamps": false,
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestParseSettingsUpdate_invalid(t *testing.T) {
+	_, err := parseSettingsUpdate(
+		[]byte(`{"theme":"red","pageSize":5.5,"showLogTimestamps":"yes","foo":1}`),
+		userSettingDefinitions)
+	var settingsErr invalidSettingsError
+	require.ErrorAs(t, err, &settingsErr)
+	assert.Len(t, settingsErr.problems, 4)
+}
+
+func TestCoerceSettingValue_integerRange(t *testing.T) {
+	def := instanceSettingDefinitions["defaultPageSize"]
+	_, err := decodeSettingValue(def, "0")
+	assert.Error(t, err)
+	_, err = decodeSettingValue(def, "1001")
+	assert.Error(t, err)
+	value, err := decodeSettingValue(def, "1000")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), value)
+}
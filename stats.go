@@ -0,0 +1,512 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gopkg.in/guregu/null.v4"
+	"gopkg.in/typ.v4/maps"
+	"gorm.io/gorm"
+)
+
+// statsDateLayout is the layout of the dates in the build statistics, such as
+// in the from and to query parameters.
+const statsDateLayout = "2006-01-02"
+
+// defaultBuildStatsDays is the number of days, counting back from and
+// including the current UTC day, that the build statistics are listed for when
+// the from query parameter is not set.
+const defaultBuildStatsDays = 30
+
+const (
+	buildStatsGroupByProject = "project"
+	buildStatsGroupByDay     = "day"
+)
+
+type statsModule struct {
+	Database *gorm.DB
+	Config   *Config
+}
+
+func (m statsModule) Register(g *gin.RouterGroup) {
+	g.GET("/stats/builds", m.getBuildStatsHandler)
+	g.POST("/admin/stats/builds/backfill", m.backfillBuildStatsHandler)
+	g.GET("/stats/workers", m.getWorkerStatsHandler)
+}
+
+// getBuildStatsHandler godoc
+// @id getBuildStats
+// @summary Get build statistics.
+// @description Lists the number of builds, their success rate, and their average duration, over a range of days.
+// @description The statistics are served from the daily build statistics, which are recomputed by the rollup job configured by the `stats` config,
+// @description and therefore lag behind the builds by up to the `stats.rollupInterval` config.
+// @description Builds are counted on the UTC day they were scheduled on.
+// @description Added in v5.3.0.
+// @tags stats
+// @produce json
+// @param from query string false "First day to include, as a date such as `2022-05-10`. Defaults to 29 days before `to`." format(date)
+// @param to query string false "Last day to include, as a date such as `2022-05-10`. Defaults to the current UTC day." format(date)
+// @param groupBy query []string false "Group the statistics by project and/or by day. Can be specified multiple times to group by both. Responds with a single group of all builds if not set." Enums(project,day)
+// @param projectId query uint false "Filter by project ID." minimum(0)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.BuildStatsList
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /stats/builds [get]
+func (m statsModule) getBuildStatsHandler(c *gin.Context) {
+	var params = struct {
+		From      *string  `form:"from"`
+		To        *string  `form:"to"`
+		GroupBy   []string `form:"groupBy"`
+		ProjectID *uint    `form:"projectId"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	from, to, ok := parseStatsDateRangeOrWriteError(c, params.From, params.To)
+	if !ok {
+		return
+	}
+
+	var groupByProject, groupByDay bool
+	for _, groupBy := range params.GroupBy {
+		switch groupBy {
+		case buildStatsGroupByProject:
+			groupByProject = true
+		case buildStatsGroupByDay:
+			groupByDay = true
+		default:
+			err := fmt.Errorf("invalid build stats grouping: %q", groupBy)
+			ginutil.WriteInvalidParamError(c, err, "groupBy", fmt.Sprintf(
+				"Invalid grouping %q, must be either %q or %q.",
+				groupBy, buildStatsGroupByProject, buildStatsGroupByDay))
+			return
+		}
+	}
+
+	var groupColumns []string
+	if groupByProject {
+		groupColumns = append(groupColumns, database.BuildStatsDailyColumns.ProjectID)
+	}
+	if groupByDay {
+		groupColumns = append(groupColumns, database.BuildStatsDailyColumns.Day)
+	}
+	selects := append(groupColumns,
+		sumColumnSQL(database.BuildStatsDailyColumns.Total),
+		sumColumnSQL(database.BuildStatsDailyColumns.Completed),
+		sumColumnSQL(database.BuildStatsDailyColumns.Failed),
+		sumColumnSQL(database.BuildStatsDailyColumns.DurationSum),
+		sumColumnSQL(database.BuildStatsDailyColumns.DurationCount))
+
+	query := m.Database.
+		Model(&database.BuildStatsDaily{}).
+		Select(strings.Join(selects, ", ")).
+		Where(fmt.Sprintf("%[1]s >= ? AND %[1]s <= ?", database.BuildStatsDailyColumns.Day), from, to).
+		Scopes(whereBuildStatsInstanceScope(c, m.Database))
+	if params.ProjectID != nil {
+		query = query.Where(&database.BuildStatsDaily{ProjectID: *params.ProjectID})
+	}
+	if len(groupColumns) > 0 {
+		query = query.
+			Group(strings.Join(groupColumns, ", ")).
+			Order(strings.Join(groupColumns, ", "))
+	}
+
+	var dbStats []database.BuildStatsDaily
+	if err := query.Scan(&dbStats).Error; err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching build statistics from database.")
+		return
+	}
+
+	resStats := response.BuildStatsList{List: make([]response.BuildStats, 0, len(dbStats))}
+	for _, dbStat := range dbStats {
+		resStat := dbBuildStatsDailyToResponse(dbStat)
+		if groupByProject {
+			resStat.ProjectID = null.IntFrom(int64(dbStat.ProjectID))
+		}
+		if groupByDay {
+			resStat.Day = null.StringFrom(dbStat.Day.UTC().Format(statsDateLayout))
+		}
+		resStats.List = append(resStats.List, resStat)
+	}
+	ginrender.Response(c, http.StatusOK, resStats)
+}
+
+// backfillBuildStatsHandler godoc
+// @id backfillBuildStats
+// @summary Recompute the build statistics of a range of days.
+// @description Recomputes the daily build statistics of all projects from the builds, for each day from `from` to `to`, inclusive.
+// @description Used to fill in the statistics of days before the ones recomputed by the rollup job, such as when upgrading from a version without build statistics.
+// @description Each day is recomputed in its own transaction, so a failed backfill can be retried from the day it failed on.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags stats
+// @param from query string true "First day to recompute, as a date such as `2022-05-10`." format(date)
+// @param to query string true "Last day to recompute, as a date such as `2022-05-10`." format(date)
+// @success 204 "Recomputed"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /admin/stats/builds/backfill [post]
+func (m statsModule) backfillBuildStatsHandler(c *gin.Context) {
+	var params = struct {
+		From string `form:"from" binding:"required"`
+		To   string `form:"to" binding:"required"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	from, ok := parseStatsDateOrWriteError(c, params.From, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseStatsDateOrWriteError(c, params.To, "to")
+	if !ok {
+		return
+	}
+	if from.After(to) {
+		err := fmt.Errorf("from date is after to date: %s > %s", params.From, params.To)
+		ginutil.WriteInvalidParamError(c, err, "from",
+			"The from date must not be after the to date.")
+		return
+	}
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	days, err := rollupBuildStats(m.Database, from, to)
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed recomputing build statistics from %s to %s, after recomputing %d days.",
+			params.From, params.To, days))
+		return
+	}
+	log.Info().
+		WithString("from", params.From).
+		WithString("to", params.To).
+		WithInt("days", days).
+		Message("Backfilled build statistics.")
+	c.Status(http.StatusNoContent)
+}
+
+// getWorkerStatsHandler godoc
+// @id getWorkerStats
+// @summary Get worker statistics.
+// @description Lists the number of builds, their failure rate, and their average duration, per worker that the builds scheduled within a range of days have run on,
+// @description together with the number of builds currently running on each worker. Workers are identified by the `workerId` field of the builds,
+// @description and builds without a worker ID are left out.
+// @description Unlike `GET /api/stats/builds`, the statistics are aggregated from the builds on each request, so long ranges are slower.
+// @description Builds are counted on the UTC day they were scheduled on.
+// @description Added in v5.3.0.
+// @tags stats
+// @produce json
+// @param from query string false "First day to include, as a date such as `2022-05-10`. Defaults to 29 days before `to`." format(date)
+// @param to query string false "Last day to include, as a date such as `2022-05-10`. Defaults to the current UTC day." format(date)
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.WorkerStatsList
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /stats/workers [get]
+func (m statsModule) getWorkerStatsHandler(c *gin.Context) {
+	var params = struct {
+		From *string `form:"from"`
+		To   *string `form:"to"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+	from, to, ok := parseStatsDateRangeOrWriteError(c, params.From, params.To)
+	if !ok {
+		return
+	}
+
+	type workerBuildCounts struct {
+		WorkerID      string
+		Total         uint
+		Completed     uint
+		Failed        uint
+		Running       uint
+		DurationSum   int64
+		DurationCount uint
+	}
+	var dbCounts []workerBuildCounts
+	if err := m.Database.
+		Model(&database.Build{}).
+		Scopes(
+			aggregateBuildsScope(database.BuildColumns.WorkerID),
+			whereBuildInstanceScope(c),
+		).
+		Where(fmt.Sprintf("%s <> ''", database.BuildColumns.WorkerID)).
+		Where(fmt.Sprintf("%[1]s >= ? AND %[1]s < ?", database.BuildColumns.ScheduledOn),
+			from, to.AddDate(0, 0, 1)).
+		Scan(&dbCounts).
+		Error; err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching worker statistics from database.")
+		return
+	}
+	var dbRunning []workerBuildCounts
+	if err := m.Database.
+		Model(&database.Build{}).
+		Select(fmt.Sprintf("%s, COUNT(*) AS running", database.BuildColumns.WorkerID)).
+		Scopes(whereBuildInstanceScope(c)).
+		Where(fmt.Sprintf("%s <> ''", database.BuildColumns.WorkerID)).
+		Where(&database.Build{StatusID: database.BuildRunning}, database.BuildFields.StatusID).
+		Group(database.BuildColumns.WorkerID).
+		Scan(&dbRunning).
+		Error; err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching running builds per worker from database.")
+		return
+	}
+
+	resStatsByWorkerID := make(map[string]response.WorkerStats, len(dbCounts))
+	for _, dbCount := range dbCounts {
+		resStatsByWorkerID[dbCount.WorkerID] = response.WorkerStats{
+			WorkerID:        dbCount.WorkerID,
+			Total:           dbCount.Total,
+			Completed:       dbCount.Completed,
+			Failed:          dbCount.Failed,
+			FailureRate:     buildRate(dbCount.Failed, dbCount.Completed+dbCount.Failed),
+			AverageDuration: averageBuildDuration(dbCount.DurationSum, dbCount.DurationCount),
+		}
+	}
+	// Workers may have running builds that were scheduled before the range.
+	for _, dbCount := range dbRunning {
+		resStat := resStatsByWorkerID[dbCount.WorkerID]
+		resStat.WorkerID = dbCount.WorkerID
+		resStat.Running = dbCount.Running
+		resStatsByWorkerID[dbCount.WorkerID] = resStat
+	}
+	resStats := response.WorkerStatsList{List: maps.Values(resStatsByWorkerID)}
+	sort.Slice(resStats.List, func(i, j int) bool {
+		return resStats.List[i].WorkerID < resStats.List[j].WorkerID
+	})
+	ginrender.Response(c, http.StatusOK, resStats)
+}
+
+// parseStatsDateRangeOrWriteError parses the optional from and to query
+// parameters, where to defaults to the current UTC day, and from defaults to
+// include the defaultBuildStatsDays days up to and including to.
+func parseStatsDateRangeOrWriteError(c *gin.Context, fromParam, toParam *string) (from, to time.Time, ok bool) {
+	to = buildStatsDay(time.Now())
+	if toParam != nil {
+		if to, ok = parseStatsDateOrWriteError(c, *toParam, "to"); !ok {
+			return
+		}
+	}
+	from = to.AddDate(0, 0, 1-defaultBuildStatsDays)
+	if fromParam != nil {
+		if from, ok = parseStatsDateOrWriteError(c, *fromParam, "from"); !ok {
+			return
+		}
+	}
+	if from.After(to) {
+		err := fmt.Errorf("from date is after to date: %s > %s",
+			from.Format(statsDateLayout), to.Format(statsDateLayout))
+		ginutil.WriteInvalidParamError(c, err, "from",
+			"The from date must not be after the to date.")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+func parseStatsDateOrWriteError(c *gin.Context, str, paramName string) (time.Time, bool) {
+	t, err := time.Parse(statsDateLayout, str)
+	if err != nil {
+		ginutil.WriteInvalidParamError(c, err, paramName, fmt.Sprintf(
+			"Invalid date %q, must be formatted as YYYY-MM-DD.", str))
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func sumColumnSQL(column database.SafeSQLName) string {
+	return fmt.Sprintf("COALESCE(SUM(%[1]s), 0) AS %[1]s", column)
+}
+
+func dbBuildStatsDailyToResponse(dbStats database.BuildStatsDaily) response.BuildStats {
+	return response.BuildStats{
+		Total:           dbStats.Total,
+		Completed:       dbStats.Completed,
+		Failed:          dbStats.Failed,
+		SuccessRate:     buildRate(dbStats.Completed, dbStats.Completed+dbStats.Failed),
+		AverageDuration: averageBuildDuration(dbStats.DurationSum, dbStats.DurationCount),
+	}
+}
+
+// buildRate returns the number of builds divided by the number of finished
+// builds, or null if no builds have finished.
+func buildRate(builds, finished uint) null.Float {
+	if finished == 0 {
+		return null.Float{}
+	}
+	return null.FloatFrom(float64(builds) / float64(finished))
+}
+
+// averageBuildDuration returns the average duration in milliseconds, or null
+// if no builds have a duration.
+func averageBuildDuration(durationSum int64, durationCount uint) null.Int {
+	if durationCount == 0 {
+		return null.Int{}
+	}
+	return null.IntFrom(durationSum / int64(durationCount))
+}
+
+// whereBuildStatsInstanceScope filters on the statistics of the projects of the
+// request's instance ID, if tenancy is enabled.
+func whereBuildStatsInstanceScope(c *gin.Context, db *gorm.DB) func(*gorm.DB) *gorm.DB {
+	if _, ok := getRequestInstanceID(c); !ok {
+		return gormIdentityScope
+	}
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Where(fmt.Sprintf("%s IN (?)", database.BuildStatsDailyColumns.ProjectID),
+			db.Model(&database.Project{}).
+				Select(database.ProjectColumns.ProjectID).
+				Scopes(whereProjectInstanceScope(c)))
+	}
+}
+
+// buildStatsDay returns the UTC day of the time, as the time at midnight UTC.
+func buildStatsDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// rollupBuildStats recomputes the daily build statistics of all projects for
+// each day from the first to the last day, inclusive, and returns the number
+// of days that were recomputed.
+func rollupBuildStats(db *gorm.DB, from, to time.Time) (int, error) {
+	days := 0
+	for day := buildStatsDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if err := rollupBuildStatsDay(db, day); err != nil {
+			return days, fmt.Errorf("day %s: %w", day.Format(statsDateLayout), err)
+		}
+		days++
+	}
+	return days, nil
+}
+
+// aggregateBuildsScope counts the builds, and sums their durations, per value
+// of the group column. The results are named after the columns of the
+// BuildStatsDaily fields.
+func aggregateBuildsScope(groupColumn database.SafeSQLName) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Select(fmt.Sprintf(
+				"%[1]s, COUNT(*) AS %[4]s, "+
+					"COALESCE(SUM(CASE WHEN %[2]s = ? THEN 1 ELSE 0 END), 0) AS %[5]s, "+
+					"COALESCE(SUM(CASE WHEN %[2]s IN ? THEN 1 ELSE 0 END), 0) AS %[6]s, "+
+					"COALESCE(SUM(%[3]s), 0) AS %[7]s, "+
+					"COUNT(%[3]s) AS %[8]s",
+				groupColumn,
+				database.BuildColumns.StatusID,
+				database.BuildColumns.Duration,
+				database.BuildStatsDailyColumns.Total,
+				database.BuildStatsDailyColumns.Completed,
+				database.BuildStatsDailyColumns.Failed,
+				database.BuildStatsDailyColumns.DurationSum,
+				database.BuildStatsDailyColumns.DurationCount),
+				int(database.BuildCompleted),
+				[]int{int(database.BuildFailed), int(database.BuildTriggerFailed)}).
+			Group(groupColumn)
+	}
+}
+
+// rollupBuildStatsDay replaces the build statistics of all projects for the
+// day with new statistics aggregated from the builds scheduled on that day.
+func rollupBuildStatsDay(db *gorm.DB, day time.Time) error {
+	var dbStats []database.BuildStatsDaily
+	if err := db.
+		Model(&database.Build{}).
+		Scopes(aggregateBuildsScope(database.BuildColumns.ProjectID)).
+		Where(fmt.Sprintf("%[1]s >= ? AND %[1]s < ?", database.BuildColumns.ScheduledOn),
+			day, day.AddDate(0, 0, 1)).
+		Scan(&dbStats).
+		Error; err != nil {
+		return fmt.Errorf("aggregate builds: %w", err)
+	}
+	for i := range dbStats {
+		dbStats[i].Day = day
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(fmt.Sprintf("%s = ?", database.BuildStatsDailyColumns.Day), day).
+			Delete(&database.BuildStatsDaily{}).
+			Error; err != nil {
+			return fmt.Errorf("delete previous stats: %w", err)
+		}
+		if len(dbStats) == 0 {
+			return nil
+		}
+		if err := tx.Create(&dbStats).Error; err != nil {
+			return fmt.Errorf("insert stats: %w", err)
+		}
+		return nil
+	})
+}
+
+// buildStatsRollup recomputes the daily build statistics of the most recent
+// days in the background, as configured by the StatsConfig.
+type buildStatsRollup struct {
+	db     *gorm.DB
+	config StatsConfig
+}
+
+func newBuildStatsRollup(db *gorm.DB, config StatsConfig) *buildStatsRollup {
+	return &buildStatsRollup{
+		db:     db,
+		config: config,
+	}
+}
+
+// start starts the background worker, unless disabled by the config. The
+// worker runs for the remaining lifetime of the process.
+func (r *buildStatsRollup) start() {
+	if r.config.RollupInterval <= 0 {
+		log.Info().Message("Build statistics rollup job disabled.")
+		return
+	}
+	go r.work()
+}
+
+func (r *buildStatsRollup) work() {
+	ticker := time.NewTicker(r.config.RollupInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.rollup(time.Now()); err != nil {
+			log.Warn().
+				WithError(err).
+				Message("Failed to recompute build statistics.")
+		}
+		<-ticker.C
+	}
+}
+
+// rollup recomputes the daily build statistics of the RollupDays most recent
+// days, counting back from and including the day of the given time.
+func (r *buildStatsRollup) rollup(now time.Time) error {
+	to := buildStatsDay(now)
+	from := to.AddDate(0, 0, 1-r.config.RollupDays)
+	start := time.Now()
+	days, err := rollupBuildStats(r.db, from, to)
+	if err != nil {
+		return err
+	}
+	log.Debug().
+		WithInt("days", days).
+		WithDuration("took", time.Since(start)).
+		Message("Recomputed build statistics.")
+	return nil
+}
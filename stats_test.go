@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestBuildStatsDay(t *testing.T) {
+	cest := time.FixedZone("CEST", 2*60*60)
+	got := buildStatsDay(time.Date(2022, 5, 11, 1, 30, 0, 0, cest))
+	assert.Equal(t, time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC), got)
+}
+
+func TestDBBuildStatsDailyToResponse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		dbStats database.BuildStatsDaily
+		want    response.BuildStats
+	}{
+		{
+			name:    "no builds",
+			dbStats: database.BuildStatsDaily{},
+			want:    response.BuildStats{},
+		},
+		{
+			name: "no finished builds",
+			dbStats: database.BuildStatsDaily{
+				Total: 2,
+			},
+			want: response.BuildStats{
+				Total: 2,
+			},
+		},
+		{
+			name: "finished builds",
+			dbStats: database.BuildStatsDaily{
+				Total:         5,
+				Completed:     3,
+				Failed:        1,
+				DurationSum:   9000,
+				DurationCount: 4,
+			},
+			want: response.BuildStats{
+				Total:           5,
+				Completed:       3,
+				Failed:          1,
+				SuccessRate:     null.FloatFrom(0.75),
+				AverageDuration: null.IntFrom(2250),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := dbBuildStatsDailyToResponse(tc.dbStats)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
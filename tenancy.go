@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// instanceIDContextKey is the gin.Context key of the request's instance ID.
+// Only set when tenancy is enabled.
+const instanceIDContextKey = "wharf-instance-id"
+
+// newTenancyMiddleware creates a Gin middleware that sets the instance ID of
+// the request, as described in TenancyConfig. Must be added after the OIDC
+// middleware, so the access token's claims are available.
+//
+// Requests to routes with path parameters referencing a project or build of
+// another instance, directly or via one of their child rows, respond as if the
+// row did not exist. The admin routes are excluded.
+func newTenancyMiddleware(config Config, db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		instanceID := config.InstanceID
+		paramName := "instanceId"
+		if claimName := config.Tenancy.OIDCClaim; claimName != "" {
+			// The header is never used when a claim is configured, as it is
+			// set by the client, which could then pick any instance.
+			claims, _ := getOIDCClaims(c)
+			claim := getOIDCClaimString(claims, claimName)
+			if claim == "" {
+				ginutil.WriteProblem(c, problems.TenancyClaimMissing.Newf(
+					"The access token must have the %q claim, to tell which Wharf instance the request belongs to.",
+					claimName))
+				c.Abort()
+				return
+			}
+			instanceID = claim
+			paramName = claimName
+		} else if header := c.GetHeader(config.Tenancy.Header); config.Tenancy.Header != "" && header != "" {
+			instanceID = header
+			paramName = config.Tenancy.Header
+		}
+		if utf8.RuneCountInString(instanceID) > database.ProjectSizes.InstanceID {
+			err := fmt.Errorf("instance ID too long: %d > %d", len(instanceID), database.ProjectSizes.InstanceID)
+			ginutil.WriteInvalidParamError(c, err, paramName, fmt.Sprintf(
+				"The instance ID must not be longer than %d characters.",
+				database.ProjectSizes.InstanceID))
+			c.Abort()
+			return
+		}
+		c.Set(instanceIDContextKey, instanceID)
+		if !strings.HasPrefix(c.FullPath(), "/api/admin/") &&
+			!validatePathParamsInstance(c, db, instanceID) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// tenancyPathParam is a path parameter holding the ID of a row that belongs to
+// an instance, either directly or via its project or build.
+type tenancyPathParam struct {
+	param string
+	name  string
+	// whereInstance returns the query for the row by ID, filtered on the
+	// instance ID.
+	whereInstance func(db *gorm.DB, id uint, instanceID string) *gorm.DB
+}
+
+var tenancyPathParams = []tenancyPathParam{
+	{"projectId", "project", func(db *gorm.DB, id uint, instanceID string) *gorm.DB {
+		return db.Model(&database.Project{}).
+			Where(database.ProjectColumns.ProjectID+" = ?", id).
+			Where(database.ProjectColumns.InstanceID+" = ?", instanceID)
+	}},
+	{"buildId", "build", func(db *gorm.DB, id uint, instanceID string) *gorm.DB {
+		return db.Model(&database.Build{}).
+			Where(database.BuildColumns.BuildID+" = ?", id).
+			Where(database.BuildColumns.InstanceID+" = ?", instanceID)
+	}},
+	{"artifactId", "artifact", func(db *gorm.DB, id uint, instanceID string) *gorm.DB {
+		return db.Model(&database.Artifact{}).
+			Where(database.ArtifactColumns.ArtifactID+" = ?", id).
+			Where(database.ArtifactColumns.BuildID+" IN (?)", buildIDsOfInstance(db, instanceID))
+	}},
+	{"commentId", "build comment", func(db *gorm.DB, id uint, instanceID string) *gorm.DB {
+		return db.Model(&database.BuildComment{}).
+			Where(database.BuildCommentColumns.BuildCommentID+" = ?", id).
+			Where(database.BuildCommentColumns.BuildID+" IN (?)", buildIDsOfInstance(db, instanceID))
+	}},
+	{"buildGroupId", "build group", func(db *gorm.DB, id uint, instanceID string) *gorm.DB {
+		return db.Model(&database.BuildGroup{}).
+			Where(database.BuildGroupColumns.BuildGroupID+" = ?", id).
+			Where(database.BuildGroupColumns.ProjectID+" IN (?)", projectIDsOfInstance(db, instanceID))
+	}},
+	{"branchId", "branch", func(db *gorm.DB, id uint, instanceID string) *gorm.DB {
+		return db.Model(&database.Branch{}).
+			Where(database.BranchColumns.BranchID+" = ?", id).
+			Where(database.BranchColumns.ProjectID+" IN (?)", projectIDsOfInstance(db, instanceID))
+	}},
+}
+
+func projectIDsOfInstance(db *gorm.DB, instanceID string) *gorm.DB {
+	return db.Model(&database.Project{}).
+		Select(database.ProjectColumns.ProjectID).
+		Where(database.ProjectColumns.InstanceID+" = ?", instanceID)
+}
+
+func buildIDsOfInstance(db *gorm.DB, instanceID string) *gorm.DB {
+	return db.Model(&database.Build{}).
+		Select(database.BuildColumns.BuildID).
+		Where(database.BuildColumns.InstanceID+" = ?", instanceID)
+}
+
+// validatePathParamsInstance writes a problem response and returns false if
+// any of the request's path parameters references a row of another instance,
+// or a row that does not exist. Path parameters that are not valid IDs are
+// left for the handler to reject.
+func validatePathParamsInstance(c *gin.Context, db *gorm.DB, instanceID string) bool {
+	for _, p := range tenancyPathParams {
+		id, err := strconv.ParseUint(c.Param(p.param), 10, 0)
+		if err != nil {
+			continue
+		}
+		var count int64
+		if err := p.whereInstance(db, uint(id), instanceID).Count(&count).Error; err != nil {
+			writeDBFetchObjByIDErrorProblem(c, err, uint(id), p.name, "")
+			return false
+		}
+		if count == 0 {
+			writeDBFetchObjByIDNotFoundProblem(c, uint(id), p.name, "")
+			return false
+		}
+	}
+	return true
+}
+
+// getRequestInstanceID returns the instance ID of the request, as set by the
+// tenancy middleware, or false if tenancy is disabled.
+func getRequestInstanceID(c *gin.Context) (string, bool) {
+	value, ok := c.Get(instanceIDContextKey)
+	if !ok {
+		return "", false
+	}
+	instanceID, ok := value.(string)
+	return instanceID, ok
+}
+
+// whereInstanceScope filters on the request's instance ID, if tenancy is
+// enabled. The column is qualified with the table name, so that it stays
+// unambiguous in queries that join other tables.
+func whereInstanceScope(c *gin.Context, table string, column database.SafeSQLName) func(*gorm.DB) *gorm.DB {
+	instanceID, ok := getRequestInstanceID(c)
+	if !ok {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(fmt.Sprintf("%s.%s = ?", table, column), instanceID)
+	}
+}
+
+func whereProjectInstanceScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return whereInstanceScope(c, database.ProjectTable, database.ProjectColumns.InstanceID)
+}
+
+func whereBuildInstanceScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return whereInstanceScope(c, database.BuildTable, database.BuildColumns.InstanceID)
+}
+
+type tenancyModule struct {
+	Database *gorm.DB
+	Config   *Config
+}
+
+func (m tenancyModule) Register(g *gin.RouterGroup) {
+	g.PUT("/admin/project/:projectId/instance", m.updateProjectInstanceHandler)
+}
+
+// updateProjectInstanceHandler godoc
+// @id updateProjectInstance
+// @summary Move a project to another Wharf installation.
+// @description Moves the project, together with all of its builds, to the
+// @description given instance ID. Used when multiple Wharf installations share
+// @description the same database, as configured via the `tenancy` config.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags project
+// @accept json
+// @produce json
+// @param projectId path uint true "project ID" minimum(0)
+// @param instance body request.ProjectInstanceUpdate true "Instance to move the project to"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Project
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /admin/project/{projectId}/instance [put]
+func (m tenancyModule) updateProjectInstanceHandler(c *gin.Context) {
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	projectID, ok := ginutil.ParseParamUint(c, "projectId")
+	if !ok {
+		return
+	}
+	var reqInstance request.ProjectInstanceUpdate
+	if err := c.ShouldBindJSON(&reqInstance); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for the project instance.")
+		return
+	}
+	var dbProject database.Project
+	err := databaseProjectPreloaded(m.Database).First(&dbProject, projectID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ginutil.WriteDBNotFound(c, fmt.Sprintf(
+			"Project with ID %d was not found when moving it to another instance.",
+			projectID))
+		return
+	} else if err != nil {
+		ginutil.WriteDBReadError(c, err, fmt.Sprintf(
+			"Failed fetching project with ID %d from database.",
+			projectID))
+		return
+	}
+	oldInstanceID := dbProject.InstanceID
+	err = m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dbProject).Updates(map[string]any{
+			string(database.ProjectColumns.InstanceID): reqInstance.InstanceID,
+			string(database.ProjectColumns.Version):    gorm.Expr(string(database.ProjectColumns.Version) + " + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&database.Build{}).
+			Where(&database.Build{ProjectID: projectID}, database.BuildFields.ProjectID).
+			UpdateColumn(database.BuildColumns.InstanceID, reqInstance.InstanceID).
+			Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed moving project with ID %d to instance %q in database.",
+			projectID, reqInstance.InstanceID))
+		return
+	}
+	dbProject.InstanceID = reqInstance.InstanceID
+	dbProject.Version++
+	requestLog(c).Info().
+		WithUint("project", projectID).
+		WithString("from", oldInstanceID).
+		WithString("to", reqInstance.InstanceID).
+		Message("Moved project to another instance.")
+	setETagHeader(c, dbProject.Version)
+	ginrender.Response(c, http.StatusOK, modelconv.DBProjectToResponse(dbProject))
+}
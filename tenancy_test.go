@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/iver-wharf/wharf-api/v5/internal/deprecated"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenancyMiddleware(t *testing.T) {
+	headerTenancy := TenancyConfig{
+		Enable: true,
+		Header: "X-Wharf-Instance",
+	}
+	claimTenancy := TenancyConfig{
+		Enable:    true,
+		Header:    "X-Wharf-Instance",
+		OIDCClaim: "wharf_instance",
+	}
+	testCases := []struct {
+		name       string
+		tenancy    TenancyConfig
+		header     string
+		claims     jwt.MapClaims
+		wantStatus int
+		want       string
+	}{
+		{name: "fallback to config", tenancy: headerTenancy, wantStatus: http.StatusOK, want: "default"},
+		{name: "from header", tenancy: headerTenancy, header: "foo", wantStatus: http.StatusOK, want: "foo"},
+		{name: "from claim", tenancy: claimTenancy, claims: jwt.MapClaims{"wharf_instance": "bar"}, wantStatus: http.StatusOK, want: "bar"},
+		{name: "claim before header", tenancy: claimTenancy, header: "foo", claims: jwt.MapClaims{"wharf_instance": "bar"}, wantStatus: http.StatusOK, want: "bar"},
+		{name: "claim missing", tenancy: claimTenancy, header: "foo", claims: jwt.MapClaims{"sub": "jane"}, wantStatus: http.StatusForbidden},
+		{name: "anonymous with claim configured", tenancy: claimTenancy, header: "foo", wantStatus: http.StatusForbidden},
+		{name: "too long", tenancy: headerTenancy, header: strings.Repeat("a", 101), wantStatus: http.StatusBadRequest},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tc.claims != nil {
+					c.Set(oidcClaimsContextKey, tc.claims)
+				}
+			}, newTenancyMiddleware(Config{InstanceID: "default", Tenancy: tc.tenancy}, nil))
+			r.GET("/", func(c *gin.Context) {
+				instanceID, _ := getRequestInstanceID(c)
+				c.String(http.StatusOK, instanceID)
+			})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("X-Wharf-Instance", tc.header)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			if tc.wantStatus == http.StatusOK {
+				assert.Equal(t, tc.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTenancyMiddleware_otherInstance(t *testing.T) {
+	db := newTestSqliteDB(t)
+	dbProjectA := database.Project{Name: "a", InstanceID: "a"}
+	dbProjectB := database.Project{Name: "b", InstanceID: "b"}
+	require.NoError(t, db.Create(&dbProjectA).Error)
+	require.NoError(t, db.Create(&dbProjectB).Error)
+	dbBuildB := database.Build{ProjectID: dbProjectB.ProjectID, InstanceID: "b"}
+	require.NoError(t, db.Create(&dbBuildB).Error)
+	require.NoError(t, db.Create(&database.Log{BuildID: dbBuildB.BuildID, Message: "secret"}).Error)
+
+	config := Config{InstanceID: "default", Tenancy: TenancyConfig{Enable: true, Header: "X-Wharf-Instance"}}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(newTenancyMiddleware(config, db))
+	api := r.Group("/api")
+	projectModule{Database: db, Config: &config}.Register(api)
+	buildModule{Database: db, Config: &config}.Register(api)
+	logModule{Database: db}.Register(api)
+	deprecated.ProjectModule{Database: db, InstanceID: getRequestInstanceID}.Register(api)
+	deprecated.BranchModule{Database: db, InstanceID: getRequestInstanceID}.Register(api)
+
+	// Rows of other instances must look like rows that do not exist.
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "project by ID", method: http.MethodGet, path: fmt.Sprintf("/api/project/%d", dbProjectB.ProjectID)},
+		{name: "build by ID", method: http.MethodGet, path: fmt.Sprintf("/api/build/%d", dbBuildB.BuildID)},
+		{name: "build logs", method: http.MethodGet, path: fmt.Sprintf("/api/build/%d/log", dbBuildB.BuildID)},
+		{name: "deprecated project builds", method: http.MethodGet, path: fmt.Sprintf("/api/projects/%d/builds?limit=10&offset=0", dbProjectB.ProjectID)},
+		{name: "deprecated branch", method: http.MethodPost, path: "/api/branch", body: fmt.Sprintf(`{"projectId":%d,"name":"main"}`, dbProjectB.ProjectID)},
+		{name: "deprecated project by ID", method: http.MethodPut, path: "/api/project", body: fmt.Sprintf(`{"projectId":%d,"name":"b"}`, dbProjectB.ProjectID)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("X-Wharf-Instance", "a")
+			r.ServeHTTP(w, req)
+			assert.Contains(t, w.Body.String(), "/prob/api/record-not-found")
+		})
+	}
+
+	t.Run("log search", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/log/search?match=secret", nil)
+		req.Header.Set("X-Wharf-Instance", "a")
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.NotContains(t, w.Body.String(), "secret")
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/api/log/search?match=secret", nil)
+		req.Header.Set("X-Wharf-Instance", "b")
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "secret")
+	})
+
+	for _, path := range []string{"/api/projects", "/api/project"} {
+		t.Run("project list "+path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("X-Wharf-Instance", "a")
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), `"name":"a"`)
+			assert.NotContains(t, w.Body.String(), `"name":"b"`)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"net/http"
@@ -8,22 +9,32 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/iver-wharf/wharf-api/v5/internal/ctxparser"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/internal/ptrconv"
+	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
 	"github.com/iver-wharf/wharf-api/v5/pkg/modelconv"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
-	"github.com/iver-wharf/wharf-core/pkg/problem"
 	"gorm.io/gorm"
 )
 
 type buildTestResultModule struct {
 	Database *gorm.DB
+	Config   *Config
+	// UploadLimit is the middleware that limits the size of uploads.
+	UploadLimit gin.HandlerFunc
+	// Idempotent is the middleware that handles the Idempotency-Key header on
+	// uploads.
+	Idempotent gin.HandlerFunc
 }
 
 func (m buildTestResultModule) Register(r gin.IRouter) {
 	testResult := r.Group("/test-result")
 	{
-		testResult.POST("/", m.createBuildTestResultHandler)
+		testResult.POST("/", m.UploadLimit, m.Idempotent, m.createBuildTestResultHandler)
+		testResult.POST("/reparse", m.reparseBuildTestResultsHandler)
 
 		testResult.GET("/detail", m.getBuildAllTestResultDetailListHandler)
 
@@ -44,9 +55,13 @@ func (m buildTestResultModule) Register(r gin.IRouter) {
 // @produce json
 // @param buildId path uint true "Build ID" minimum(0)
 // @param files formData file true "Test result file"
+// @param Idempotency-Key header string false "Unique key of this request. Retries using the same key respond with the original response, instead of being handled again." maxlength(255)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 201 {object} []response.ArtifactMetadata "Added new test result data and created summaries"
 // @failure 400 {object} problem.Response "Bad request"
+// @failure 409 {object} problem.Response "Request with the same idempotency key is still being handled"
+// @failure 413 {object} problem.Response "Request body is larger than the artifact.maxUploadSize config. Added in v5.3.0."
+// @failure 422 {object} problem.Response "Idempotency key was already used for a different request"
 // @failure 502 {object} problem.Response "Database unreachable or bad gateway"
 // @router /build/{buildId}/test-result [post]
 func (m buildTestResultModule) createBuildTestResultHandler(c *gin.Context) {
@@ -57,47 +72,44 @@ func (m buildTestResultModule) createBuildTestResultHandler(c *gin.Context) {
 
 	files, err := ctxparser.ParseMultipartFormDataFiles(c, "files")
 	if err != nil {
+		if writeUploadTooLargeIfExceeded(c) {
+			return
+		}
 		ginutil.WriteMultipartFormReadError(c, err,
 			fmt.Sprintf("Failed reading multipart-form's file data from request body when uploading"+
 				" new test result for build with ID %d.", buildID))
 		return
 	}
 
-	dbArtifacts, ok := createArtifacts(c, m.Database, files, buildID)
+	dbArtifacts, ok := createArtifacts(c, gormArtifactRepo{m.Database}, files, buildID)
 	if !ok {
 		return
 	}
 
-	var dbAllDetails []database.TestResultDetail
-
 	dbAllSummaries := make([]database.TestResultSummary, 0, len(dbArtifacts))
+	dbDetailsPerSummary := make([][]database.TestResultDetail, 0, len(dbArtifacts))
 	resArtifactMetadataList := make([]response.ArtifactMetadata, 0, len(dbArtifacts))
 
 	for _, dbArtifact := range dbArtifacts {
 		dbSummary, dbDetails, err := getTestSummaryAndDetails(dbArtifact.Data, dbArtifact.ArtifactID, buildID)
 		if err != nil {
-			log.Warn().
+			requestLog(c).Warn().
 				WithError(err).
 				WithString("filename", dbArtifact.FileName).
 				WithUint("build", buildID).
 				WithUint("artifact", dbArtifact.ArtifactID).
-				Message("Failed to unmarshal; invalid/unsupported TRX/XML format.")
+				Message("Failed to unmarshal; invalid/unsupported TRX or JUnit XML format.")
 
 			ginutil.WriteProblemError(c, err,
-				problem.Response{
-					Type:   "/prob/api/test-results-parse",
-					Status: http.StatusBadRequest,
-					Title:  "Unexpected response format.",
-					Detail: fmt.Sprintf(
-						"Failed parsing test result ID %d, for build with ID %d in"+
-							" database. Invalid/unsupported TRX/XML format.", dbSummary.ArtifactID, buildID),
-				})
+				problems.TestResultsParse.Newf(
+					"Failed parsing test result ID %d, for build with ID %d in"+
+						" database. Invalid/unsupported TRX or JUnit XML format.", dbArtifact.ArtifactID, buildID))
 			return
 		}
 
 		dbSummary.FileName = dbArtifact.FileName
 		dbAllSummaries = append(dbAllSummaries, dbSummary)
-		dbAllDetails = append(dbAllDetails, dbDetails...)
+		dbDetailsPerSummary = append(dbDetailsPerSummary, dbDetails)
 
 		resArtifactMetadataList = append(resArtifactMetadataList, response.ArtifactMetadata{
 			TimeMetadata: modelconv.DBTimeMetadataToResponse(dbArtifact.TimeMetadata),
@@ -113,6 +125,16 @@ func (m buildTestResultModule) createBuildTestResultHandler(c *gin.Context) {
 		return
 	}
 
+	// Linked to the summaries, as the artifacts may be deleted later on while
+	// keeping the parsed test results.
+	var dbAllDetails []database.TestResultDetail
+	for i, dbSummary := range dbAllSummaries {
+		for _, dbDetail := range dbDetailsPerSummary[i] {
+			dbDetail.TestResultSummaryID.SetValid(int64(dbSummary.TestResultSummaryID))
+			dbAllDetails = append(dbAllDetails, dbDetail)
+		}
+	}
+
 	err = m.Database.
 		CreateInBatches(dbAllDetails, 100).
 		Error
@@ -120,18 +142,26 @@ func (m buildTestResultModule) createBuildTestResultHandler(c *gin.Context) {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 			"Failed saving test result details for build with ID %d in database.",
 			buildID))
+		return
 	}
 
-	renderJSON(c, http.StatusOK, resArtifactMetadataList)
+	ginrender.Response(c, http.StatusOK, resArtifactMetadataList)
 }
 
 // getBuildAllTestResultDetailListHandler godoc
 // @id getBuildAllTestResultDetailList
 // @summary Get all test result details for specified build
 // @description Added in v5.0.0.
+// @description Responds with CSV instead, using the same filters and ordering, if requested via the `Accept: text/csv` header or the `format=csv` query parameter. CSV added in v5.3.0.
 // @tags test-result
-// @produce json
+// @produce json,text/csv
 // @param buildId path uint true "Build ID" minimum(0)
+// @param testResultSummaryId query uint false "Filter by the ID of the test result summary that the tests were parsed from. Added in v5.3.0." minimum(0)
+// @param className query string false "Filter by verbatim class name. Added in v5.3.0."
+// @param classNameMatch query string false "Filter by matching class name. Cannot be used with `className`. Added in v5.3.0."
+// @param filePath query string false "Filter by verbatim source file path. Added in v5.3.0."
+// @param filePathMatch query string false "Filter by matching source file path. Cannot be used with `filePath`. Added in v5.3.0."
+// @param format query string false "Response format, where `csv` responds with CSV instead of JSON. Added in v5.3.0." Enums(json,csv)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedTestResultDetails
 // @failure 400 {object} problem.Response "Bad request"
@@ -143,9 +173,26 @@ func (m buildTestResultModule) getBuildAllTestResultDetailListHandler(c *gin.Con
 		return
 	}
 
-	var dbDetails []database.TestResultDetail
-	err := m.Database.
+	var params testResultDetailQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+
+	query := m.Database.
 		Where(&database.TestResultDetail{BuildID: buildID}).
+		Scopes(params.whereScope).
+		Order(database.TestResultDetailColumns.TestResultDetailID)
+	if ginrender.IsCSVRequested(c) {
+		writeDBQueryCSV(c, query, 0, 0, fmt.Sprintf("build-%d-test-results.csv", buildID), testResultDetailCSVHeader,
+			dbTestResultDetailToCSVRecord, fmt.Sprintf(
+				"Failed fetching test result details for build with ID %d from database.",
+				buildID))
+		return
+	}
+
+	var dbDetails []database.TestResultDetail
+	err := query.
 		Find(&dbDetails).
 		Error
 
@@ -157,7 +204,7 @@ func (m buildTestResultModule) getBuildAllTestResultDetailListHandler(c *gin.Con
 	}
 
 	resDetails := modelconv.DBTestResultDetailsToResponses(dbDetails)
-	renderJSON(c, http.StatusOK, response.PaginatedTestResultDetails{
+	ginrender.Response(c, http.StatusOK, response.PaginatedTestResultDetails{
 		List:       resDetails,
 		TotalCount: int64(len(resDetails)),
 	})
@@ -167,9 +214,11 @@ func (m buildTestResultModule) getBuildAllTestResultDetailListHandler(c *gin.Con
 // @id getBuildAllTestResultSummaryList
 // @summary Get all test result summaries for specified build
 // @description Added in v5.0.0.
+// @description Responds with CSV instead, using the same filters and ordering, if requested via the `Accept: text/csv` header or the `format=csv` query parameter. CSV added in v5.3.0.
 // @tags test-result
-// @produce json
+// @produce json,text/csv
 // @param buildId path uint true "Build ID" minimum(0)
+// @param format query string false "Response format, where `csv` responds with CSV instead of JSON. Added in v5.3.0." Enums(json,csv)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedTestResultSummaries
 // @failure 400 {object} problem.Response "Bad Request"
@@ -181,9 +230,19 @@ func (m buildTestResultModule) getBuildAllTestResultSummaryListHandler(c *gin.Co
 		return
 	}
 
-	var dbSummaries []database.TestResultSummary
-	err := m.Database.
+	query := m.Database.
 		Where(&database.TestResultSummary{BuildID: buildID}).
+		Order(database.TestResultSummaryColumns.TestResultSummaryID)
+	if ginrender.IsCSVRequested(c) {
+		writeDBQueryCSV(c, query, 0, 0, fmt.Sprintf("build-%d-test-result-summaries.csv", buildID), testResultSummaryCSVHeader,
+			dbTestResultSummaryToCSVRecord, fmt.Sprintf(
+				"Failed fetching test result summaries from build with ID %d from database.",
+				buildID))
+		return
+	}
+
+	var dbSummaries []database.TestResultSummary
+	err := query.
 		Find(&dbSummaries).
 		Error
 
@@ -199,7 +258,7 @@ func (m buildTestResultModule) getBuildAllTestResultSummaryListHandler(c *gin.Co
 		resSummaries[i] = modelconv.DBTestResultSummaryToResponse(dbSummary)
 	}
 
-	renderJSON(c, http.StatusOK, response.PaginatedTestResultSummaries{
+	ginrender.Response(c, http.StatusOK, response.PaginatedTestResultSummaries{
 		List:       resSummaries,
 		TotalCount: int64(len(resSummaries)),
 	})
@@ -231,7 +290,7 @@ func (m buildTestResultModule) getBuildTestResultSummaryHandler(c *gin.Context)
 
 	var dbSummary database.TestResultSummary
 	err := m.Database.
-		Where(&database.TestResultSummary{BuildID: buildID, ArtifactID: artifactID}).
+		Where(&database.TestResultSummary{BuildID: buildID, ArtifactID: &artifactID}).
 		Find(&dbSummary).
 		Error
 
@@ -243,17 +302,24 @@ func (m buildTestResultModule) getBuildTestResultSummaryHandler(c *gin.Context)
 	}
 
 	resSummary := modelconv.DBTestResultSummaryToResponse(dbSummary)
-	renderJSON(c, http.StatusOK, resSummary)
+	ginrender.Response(c, http.StatusOK, resSummary)
 }
 
 // getBuildTestResultDetailListHandler godoc
 // @id getBuildTestResultDetailList
 // @summary Get all test result details for specified test
 // @description Added in v5.0.0.
+// @description Responds with CSV instead, using the same filters and ordering, if requested via the `Accept: text/csv` header or the `format=csv` query parameter. CSV added in v5.3.0.
 // @tags test-result
-// @produce json
+// @produce json,text/csv
 // @param buildId path uint true "Build ID" minimum(0)
 // @param artifactId path uint true "Artifact ID" minimum(0)
+// @param testResultSummaryId query uint false "Filter by the ID of the test result summary that the tests were parsed from. Added in v5.3.0." minimum(0)
+// @param className query string false "Filter by verbatim class name. Added in v5.3.0."
+// @param classNameMatch query string false "Filter by matching class name. Cannot be used with `className`. Added in v5.3.0."
+// @param filePath query string false "Filter by verbatim source file path. Added in v5.3.0."
+// @param filePathMatch query string false "Filter by matching source file path. Cannot be used with `filePath`. Added in v5.3.0."
+// @param format query string false "Response format, where `csv` responds with CSV instead of JSON. Added in v5.3.0." Enums(json,csv)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedTestResultDetails
 // @failure 400 {object} problem.Response "Bad Request"
@@ -270,9 +336,26 @@ func (m buildTestResultModule) getBuildTestResultDetailListHandler(c *gin.Contex
 		return
 	}
 
+	var params testResultDetailQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return
+	}
+
+	query := m.Database.
+		Where(&database.TestResultDetail{BuildID: buildID, ArtifactID: &artifactID}).
+		Scopes(params.whereScope).
+		Order(database.TestResultDetailColumns.TestResultDetailID)
+	if ginrender.IsCSVRequested(c) {
+		writeDBQueryCSV(c, query, 0, 0, fmt.Sprintf("build-%d-test-%d-test-results.csv", buildID, artifactID), testResultDetailCSVHeader,
+			dbTestResultDetailToCSVRecord, fmt.Sprintf(
+				"Failed fetching test result details from test with ID %d for build with ID %d from database.",
+				artifactID, buildID))
+		return
+	}
+
 	var dbDetails []database.TestResultDetail
-	err := m.Database.
-		Where(&database.TestResultDetail{BuildID: buildID, ArtifactID: artifactID}).
+	err := query.
 		Find(&dbDetails).
 		Error
 
@@ -284,7 +367,7 @@ func (m buildTestResultModule) getBuildTestResultDetailListHandler(c *gin.Contex
 	}
 
 	resDetails := modelconv.DBTestResultDetailsToResponses(dbDetails)
-	renderJSON(c, http.StatusOK, response.PaginatedTestResultDetails{
+	ginrender.Response(c, http.StatusOK, response.PaginatedTestResultDetails{
 		List:       resDetails,
 		TotalCount: int64(len(dbDetails)),
 	})
@@ -336,7 +419,33 @@ func (m buildTestResultModule) getBuildAllTestResultListSummaryHandler(c *gin.Co
 		Failed:  dbListSummary.Failed,
 	}
 
-	renderJSON(c, http.StatusOK, resListSummary)
+	ginrender.Response(c, http.StatusOK, resListSummary)
+}
+
+// testResultDetailQueryParams holds the query parameters used to filter the
+// test result details.
+type testResultDetailQueryParams struct {
+	TestResultSummaryID *uint `form:"testResultSummaryId"`
+
+	ClassName      *string `form:"className"`
+	ClassNameMatch *string `form:"classNameMatch" binding:"excluded_with=ClassName"`
+	FilePath       *string `form:"filePath"`
+	FilePathMatch  *string `form:"filePathMatch" binding:"excluded_with=FilePath"`
+}
+
+func (params testResultDetailQueryParams) whereScope(db *gorm.DB) *gorm.DB {
+	var where wherefields.Collection
+	return db.
+		Where(&database.TestResultDetail{
+			TestResultSummaryID: ptrconv.UintPtrNullInt(
+				where.UintPtrZeroNil(database.TestResultDetailFields.TestResultSummaryID, params.TestResultSummaryID)),
+			ClassName: where.String(database.TestResultDetailFields.ClassName, params.ClassName),
+			FilePath:  where.String(database.TestResultDetailFields.FilePath, params.FilePath),
+		}, where.NonNilFieldNames()...).
+		Scopes(whereLikeScope(map[database.SafeSQLName]*string{
+			database.TestResultDetailColumns.ClassName: params.ClassNameMatch,
+			database.TestResultDetailColumns.FilePath:  params.FilePathMatch,
+		}))
 }
 
 type xmlInnerString struct {
@@ -344,9 +453,18 @@ type xmlInnerString struct {
 }
 
 type trxTestRun struct {
-	XMLName xml.Name `xml:"TestRun"`
+	XMLName         xml.Name `xml:"TestRun"`
+	TestDefinitions struct {
+		UnitTests []struct {
+			ID         string `xml:"id,attr"`
+			TestMethod struct {
+				ClassName string `xml:"className,attr"`
+			} `xml:"TestMethod"`
+		} `xml:"UnitTest"`
+	} `xml:"TestDefinitions"`
 	Results struct {
 		UnitTestResults []struct {
+			TestID    string `xml:"testId,attr"`
 			TestName  string `xml:"testName,attr"`
 			Duration  string `xml:"duration,attr"`
 			StartTime string `xml:"startTime,attr"`
@@ -383,18 +501,52 @@ type trxTestRun struct {
 	} `xml:"ResultSummary"`
 }
 
+// getTestSummaryAndDetails parses a test result file, in either the TRX or
+// the JUnit XML format, based on its root element.
 func getTestSummaryAndDetails(data []byte, artifactID, buildID uint) (database.TestResultSummary, []database.TestResultDetail, error) {
+	root, err := getXMLRootElementName(data)
+	if err != nil {
+		return database.TestResultSummary{}, nil, err
+	}
+	switch root {
+	case "testsuites", "testsuite":
+		return getJUnitTestSummaryAndDetails(data, artifactID, buildID)
+	default:
+		return getTRXTestSummaryAndDetails(data, artifactID, buildID)
+	}
+}
+
+func getXMLRootElementName(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func getTRXTestSummaryAndDetails(data []byte, artifactID, buildID uint) (database.TestResultSummary, []database.TestResultDetail, error) {
 	var testRun trxTestRun
 	if err := xml.Unmarshal(data, &testRun); err != nil {
 		return database.TestResultSummary{}, nil, err
 	}
 
+	classNames := make(map[string]string, len(testRun.TestDefinitions.UnitTests))
+	for _, ut := range testRun.TestDefinitions.UnitTests {
+		classNames[ut.ID] = ut.TestMethod.ClassName
+	}
+
 	dbDetails := make([]database.TestResultDetail, len(testRun.Results.UnitTestResults))
 	for idx, utr := range testRun.Results.UnitTestResults {
 		detail := &dbDetails[idx]
-		detail.ArtifactID = artifactID
+		detail.ArtifactID = &artifactID
 		detail.BuildID = buildID
 		detail.Name = utr.TestName
+		detail.ClassName = truncateString(classNames[utr.TestID], database.TestResultDetailSizes.ClassName)
 		if utr.Outcome == "Passed" {
 			detail.Status = database.TestResultStatusSuccess
 		} else if utr.Outcome == "Failed" {
@@ -434,7 +586,7 @@ func getTestSummaryAndDetails(data []byte, artifactID, buildID uint) (database.T
 
 	counters := testRun.ResultSummary.Counters
 	dbSummary := database.TestResultSummary{
-		ArtifactID: artifactID,
+		ArtifactID: &artifactID,
 		BuildID:    buildID,
 		Failed:     counters.Failed,
 		Passed:     counters.Passed,
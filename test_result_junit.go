@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+)
+
+// junitTestSuite is a <testsuite> element of a JUnit XML file. The root
+// <testsuites> element is also parsed as a junitTestSuite, as it only holds
+// nested test suites.
+type junitTestSuite struct {
+	File       string           `xml:"file,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+	TestCases  []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	File      string         `xml:"file,attr"`
+	Line      string         `xml:"line,attr"`
+	Failures  []junitProblem `xml:"failure"`
+	Errors    []junitProblem `xml:"error"`
+	Skipped   *junitProblem  `xml:"skipped"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (p junitProblem) String() string {
+	text := strings.TrimSpace(p.Text)
+	if text == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("%s\n%s", p.Message, text)
+}
+
+func getJUnitTestSummaryAndDetails(data []byte, artifactID, buildID uint) (database.TestResultSummary, []database.TestResultDetail, error) {
+	var root junitTestSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return database.TestResultSummary{}, nil, err
+	}
+
+	dbSummary := database.TestResultSummary{
+		ArtifactID: &artifactID,
+		BuildID:    buildID,
+	}
+	var dbDetails []database.TestResultDetail
+
+	var addSuite func(suite junitTestSuite, file string)
+	addSuite = func(suite junitTestSuite, file string) {
+		if suite.File != "" {
+			file = suite.File
+		}
+		for _, tc := range suite.TestCases {
+			detail := database.TestResultDetail{
+				ArtifactID: &artifactID,
+				BuildID:    buildID,
+				Name:       tc.Name,
+				ClassName:  truncateString(tc.ClassName, database.TestResultDetailSizes.ClassName),
+				FilePath:   truncateString(file, database.TestResultDetailSizes.FilePath),
+				Status:     database.TestResultStatusSuccess,
+			}
+			if tc.File != "" {
+				detail.FilePath = truncateString(tc.File, database.TestResultDetailSizes.FilePath)
+			}
+			if line, err := strconv.ParseInt(tc.Line, 10, 64); err == nil && line > 0 {
+				detail.LineNumber.SetValid(line)
+			}
+			switch {
+			case len(tc.Failures) > 0:
+				detail.Status = database.TestResultStatusFailed
+				detail.Message.SetValid(tc.Failures[0].String())
+				dbSummary.Failed++
+			case len(tc.Errors) > 0:
+				detail.Status = database.TestResultStatusFailed
+				detail.Message.SetValid(tc.Errors[0].String())
+				dbSummary.Failed++
+			case tc.Skipped != nil:
+				detail.Status = database.TestResultStatusSkipped
+				detail.Message.SetValid(tc.Skipped.String())
+				dbSummary.Skipped++
+			default:
+				dbSummary.Passed++
+			}
+			dbDetails = append(dbDetails, detail)
+		}
+		for _, nested := range suite.TestSuites {
+			addSuite(nested, file)
+		}
+	}
+	addSuite(root, "")
+
+	dbSummary.Total = uint(len(dbDetails))
+	return dbSummary, dbDetails, nil
+}
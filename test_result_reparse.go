@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"gorm.io/gorm"
+)
+
+// testResultReparseBatchSize is the number of artifacts to fetch the file
+// names of at a time when re-parsing test results. The artifacts' data is
+// fetched one artifact at a time.
+const testResultReparseBatchSize = 100
+
+// defaultTestResultFileExtensions are the file extensions of the artifacts
+// that are re-parsed as test results when no file name pattern is given.
+var defaultTestResultFileExtensions = []string{".trx", ".xml"}
+
+// reparseBuildTestResultsHandler godoc
+// @id reparseBuildTestResults
+// @summary Re-parse the test results of a build's artifacts.
+// @description Parses the build's stored artifacts as test results again, in either the TRX or the JUnit XML format,
+// @description such as after the parsing has been improved in a newer version of wharf-api.
+// @description The test result summary and details previously parsed from each artifact are replaced,
+// @description so re-parsing the same artifacts multiple times does not duplicate the test results.
+// @description Artifacts that fail to parse are skipped, and keep any previous test results.
+// @description Added in v5.3.0.
+// @tags test-result
+// @produce json
+// @param buildId path uint true "Build ID" minimum(0)
+// @param fileName query string false "Only re-parse artifacts with file names matching this glob pattern, such as `*.trx`. Defaults to all `.trx` and `.xml` files."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.TestResultReparse
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Build not found"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /build/{buildId}/test-result/reparse [post]
+func (m buildTestResultModule) reparseBuildTestResultsHandler(c *gin.Context) {
+	buildID, ok := ginutil.ParseParamUint(c, "buildId")
+	if !ok {
+		return
+	}
+	fileNamePattern, ok := bindTestResultReparseFileNamePattern(c)
+	if !ok {
+		return
+	}
+	if !validateDatabaseObjExistsByID(c, m.Database, &database.Build{}, buildID, "build", "when re-parsing test results") {
+		return
+	}
+	query := m.Database.Where(&database.Artifact{BuildID: buildID}, database.ArtifactFields.BuildID)
+	resReparse, err := reparseTestResultArtifacts(m.Database, query, fileNamePattern)
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed re-parsing test results for build with ID %d, after re-parsing %d artifacts.",
+			buildID, len(resReparse.Reparsed)))
+		return
+	}
+	ginrender.Response(c, http.StatusOK, resReparse)
+}
+
+// reparseAllTestResultsHandler godoc
+// @id reparseAllTestResults
+// @summary Re-parse the test results of all builds' artifacts.
+// @description Parses the stored artifacts of all builds as test results again, in either the TRX or the JUnit XML format,
+// @description such as after the parsing has been improved in a newer version of wharf-api.
+// @description The test result summary and details previously parsed from each artifact are replaced,
+// @description so re-parsing the same artifacts multiple times does not duplicate the test results.
+// @description Artifacts that fail to parse are skipped, and keep any previous test results.
+// @description Each artifact is re-parsed in its own transaction, so a failed re-parse can be retried.
+// @description Requires the user to be listed in the `http.admins` config.
+// @description Added in v5.3.0.
+// @tags test-result
+// @produce json
+// @param fileName query string false "Only re-parse artifacts with file names matching this glob pattern, such as `*.trx`. Defaults to all `.trx` and `.xml` files."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.TestResultReparse
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 403 {object} problem.Response "Not an admin"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /admin/test-result/reparse [post]
+func (m buildTestResultModule) reparseAllTestResultsHandler(c *gin.Context) {
+	fileNamePattern, ok := bindTestResultReparseFileNamePattern(c)
+	if !ok {
+		return
+	}
+	if !validateIsAdmin(c, m.Config.HTTP) {
+		return
+	}
+	resReparse, err := reparseTestResultArtifacts(m.Database, m.Database, fileNamePattern)
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
+			"Failed re-parsing test results, after re-parsing %d artifacts.",
+			len(resReparse.Reparsed)))
+		return
+	}
+	log.Info().
+		WithString("fileName", fileNamePattern).
+		WithInt("reparsed", len(resReparse.Reparsed)).
+		WithInt("skipped", len(resReparse.Skipped)).
+		Message("Re-parsed test results.")
+	ginrender.Response(c, http.StatusOK, resReparse)
+}
+
+// bindTestResultReparseFileNamePattern returns the fileName query parameter,
+// or writes an "invalid param" problem and returns false if it is not a valid
+// glob pattern.
+func bindTestResultReparseFileNamePattern(c *gin.Context) (string, bool) {
+	var params = struct {
+		FileName string `form:"fileName"`
+	}{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		return "", false
+	}
+	if _, err := path.Match(params.FileName, ""); err != nil {
+		ginutil.WriteInvalidParamError(c, err, "fileName", fmt.Sprintf(
+			"Invalid file name glob pattern %q.", params.FileName))
+		return "", false
+	}
+	return params.FileName, true
+}
+
+// matchTestResultFileName reports whether the artifact file name matches the
+// glob pattern, or has one of the default test result file extensions if the
+// pattern is empty.
+func matchTestResultFileName(pattern, fileName string) bool {
+	if pattern == "" {
+		ext := strings.ToLower(filepath.Ext(fileName))
+		for _, defaultExt := range defaultTestResultFileExtensions {
+			if ext == defaultExt {
+				return true
+			}
+		}
+		return false
+	}
+	ok, _ := path.Match(pattern, fileName)
+	return ok
+}
+
+// reparseTestResultArtifacts re-parses the test results of the artifacts found
+// by the query whose file names match the glob pattern. The returned
+// response holds the artifacts re-parsed so far, even if an error occurred.
+func reparseTestResultArtifacts(db, query *gorm.DB, fileNamePattern string) (response.TestResultReparse, error) {
+	resReparse := response.TestResultReparse{
+		Reparsed: []response.TestResultReparseArtifact{},
+		Skipped:  []response.TestResultReparseArtifact{},
+	}
+	var dbArtifacts []database.Artifact
+	err := query.
+		Model(&database.Artifact{}).
+		Select(
+			database.ArtifactColumns.ArtifactID,
+			database.ArtifactColumns.BuildID,
+			database.ArtifactColumns.FileName).
+		FindInBatches(&dbArtifacts, testResultReparseBatchSize, func(*gorm.DB, int) error {
+			for _, dbArtifact := range dbArtifacts {
+				if !matchTestResultFileName(fileNamePattern, dbArtifact.FileName) {
+					continue
+				}
+				resArtifact := response.TestResultReparseArtifact{
+					ArtifactID: dbArtifact.ArtifactID,
+					BuildID:    dbArtifact.BuildID,
+					FileName:   dbArtifact.FileName,
+				}
+				parsed, err := reparseTestResultArtifact(db, dbArtifact.ArtifactID)
+				if err != nil {
+					return err
+				}
+				if parsed {
+					resReparse.Reparsed = append(resReparse.Reparsed, resArtifact)
+				} else {
+					resReparse.Skipped = append(resReparse.Skipped, resArtifact)
+				}
+			}
+			return nil
+		}).
+		Error
+	return resReparse, err
+}
+
+// reparseTestResultArtifact replaces the test result summary and details
+// parsed from the artifact, in a single transaction. Returns false if the
+// artifact could not be parsed as test results, in which case any previous
+// test results of the artifact are kept as-is.
+func reparseTestResultArtifact(db *gorm.DB, artifactID uint) (bool, error) {
+	var dbArtifact database.Artifact
+	if err := db.First(&dbArtifact, artifactID).Error; err != nil {
+		return false, err
+	}
+	dbSummary, dbDetails, err := getTestSummaryAndDetails(dbArtifact.Data, dbArtifact.ArtifactID, dbArtifact.BuildID)
+	if err != nil {
+		log.Debug().
+			WithError(err).
+			WithString("filename", dbArtifact.FileName).
+			WithUint("build", dbArtifact.BuildID).
+			WithUint("artifact", dbArtifact.ArtifactID).
+			Message("Skipped re-parsing artifact; invalid/unsupported TRX or JUnit XML format.")
+		return false, nil
+	}
+	dbSummary.FileName = dbArtifact.FileName
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&database.TestResultDetail{}, &database.TestResultSummary{}} {
+			if err := tx.
+				Where(database.TestResultSummaryColumns.ArtifactID+" = ?", dbArtifact.ArtifactID).
+				Delete(model).
+				Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&dbSummary).Error; err != nil {
+			return err
+		}
+		if len(dbDetails) == 0 {
+			return nil
+		}
+		for i := range dbDetails {
+			dbDetails[i].TestResultSummaryID.SetValid(int64(dbSummary.TestResultSummaryID))
+		}
+		return tx.CreateInBatches(dbDetails, 100).Error
+	})
+	return err == nil, err
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestGetTestSummaryAndDetails_trxClassName(t *testing.T) {
+	const trx = `<TestRun>
+  <TestDefinitions>
+    <UnitTest id="a"><TestMethod className="Wharf.Api.Tests.BuildTests" name="TestPass" /></UnitTest>
+  </TestDefinitions>
+  <Results>
+    <UnitTestResult testId="a" testName="TestPass" outcome="Passed" />
+    <UnitTestResult testId="b" testName="TestUnknown" outcome="Passed" />
+  </Results>
+  <ResultSummary><Counters total="2" passed="2" /></ResultSummary>
+</TestRun>`
+	dbSummary, dbDetails, err := getTestSummaryAndDetails([]byte(trx), 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), dbSummary.Total)
+	require.Len(t, dbDetails, 2)
+	assert.Equal(t, "Wharf.Api.Tests.BuildTests", dbDetails[0].ClassName)
+	assert.Equal(t, "", dbDetails[1].ClassName)
+	assert.False(t, dbDetails[0].LineNumber.Valid)
+}
+
+func TestGetTestSummaryAndDetails_junit(t *testing.T) {
+	const junit = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="wharf" file="pkg/orderby/orderby_test.go">
+    <testcase name="TestParse" classname="orderby" line="42" />
+    <testcase name="TestParseSlice" classname="orderby" file="pkg/orderby/slice_test.go" line="7">
+      <failure message="not equal">expected 1, got 2</failure>
+    </testcase>
+    <testsuite name="nested">
+      <testcase name="TestError" classname="orderby.nested"><error message="panic" /></testcase>
+      <testcase name="TestSkip" classname="orderby.nested" line="x"><skipped message="flaky" /></testcase>
+    </testsuite>
+  </testsuite>
+</testsuites>`
+	dbSummary, dbDetails, err := getTestSummaryAndDetails([]byte(junit), 1, 2)
+	require.NoError(t, err)
+	artifactID := uint(1)
+	assert.Equal(t, database.TestResultSummary{
+		ArtifactID: &artifactID,
+		BuildID:    2,
+		Total:      4,
+		Failed:     2,
+		Passed:     1,
+		Skipped:    1,
+	}, dbSummary)
+	want := []database.TestResultDetail{
+		{
+			ArtifactID: &artifactID, BuildID: 2, Name: "TestParse", ClassName: "orderby",
+			FilePath: "pkg/orderby/orderby_test.go", LineNumber: null.IntFrom(42),
+			Status: database.TestResultStatusSuccess,
+		},
+		{
+			ArtifactID: &artifactID, BuildID: 2, Name: "TestParseSlice", ClassName: "orderby",
+			FilePath: "pkg/orderby/slice_test.go", LineNumber: null.IntFrom(7),
+			Status: database.TestResultStatusFailed, Message: null.StringFrom("not equal\nexpected 1, got 2"),
+		},
+		{
+			ArtifactID: &artifactID, BuildID: 2, Name: "TestError", ClassName: "orderby.nested",
+			FilePath: "pkg/orderby/orderby_test.go",
+			Status:   database.TestResultStatusFailed, Message: null.StringFrom("panic"),
+		},
+		{
+			ArtifactID: &artifactID, BuildID: 2, Name: "TestSkip", ClassName: "orderby.nested",
+			FilePath: "pkg/orderby/orderby_test.go",
+			Status:   database.TestResultStatusSkipped, Message: null.StringFrom("flaky"),
+		},
+	}
+	assert.Equal(t, want, dbDetails)
+}
+
+func TestGetTestSummaryAndDetails_invalid(t *testing.T) {
+	_, _, err := getTestSummaryAndDetails([]byte("not xml"), 1, 2)
+	assert.Error(t, err)
+	_, _, err = getTestSummaryAndDetails([]byte("<other />"), 1, 2)
+	assert.Error(t, err)
+}
+
+func TestGetBuildAllTestResultDetailListHandler_filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, dbProject := newTestInsertBuildModule(t)
+	dbBuild := database.Build{ProjectID: dbProject.ProjectID}
+	require.NoError(t, m.Database.Create(&dbBuild).Error)
+	dbArtifact := database.Artifact{BuildID: dbBuild.BuildID, FileName: "results.xml"}
+	require.NoError(t, m.Database.Create(&dbArtifact).Error)
+	for _, dbDetail := range []database.TestResultDetail{
+		{Name: "TestA", ClassName: "wharf.api", FilePath: "api/a_test.go"},
+		{Name: "TestB", ClassName: "wharf.api.sub", FilePath: "api/sub/b_test.go"},
+		{Name: "TestC", ClassName: "wharf.cmd", FilePath: "cmd/c_test.go"},
+	} {
+		dbDetail.BuildID = dbBuild.BuildID
+		dbDetail.ArtifactID = &dbArtifact.ArtifactID
+		dbDetail.Status = database.TestResultStatusSuccess
+		require.NoError(t, m.Database.Create(&dbDetail).Error)
+	}
+
+	r := gin.New()
+	buildTestResultModule{Database: m.Database}.Register(r.Group("/build/:buildId"))
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/build/1/test-result/detail"+query, nil))
+		return w
+	}
+	getNames := func(query string) []string {
+		w := get(query)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resDetails response.PaginatedTestResultDetails
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resDetails))
+		var names []string
+		for _, resDetail := range resDetails.List {
+			names = append(names, resDetail.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"TestA", "TestB", "TestC"}, getNames(""))
+	assert.Equal(t, []string{"TestA"}, getNames("?className=wharf.api"))
+	assert.Equal(t, []string{"TestA", "TestB"}, getNames("?classNameMatch=api"))
+	assert.Equal(t, []string{"TestB"}, getNames("?filePathMatch=sub&classNameMatch=wharf"))
+	assert.Equal(t, []string{"TestC"}, getNames("?filePath=cmd/c_test.go"))
+	assert.Equal(t, http.StatusBadRequest, get("?className=a&classNameMatch=b").Code)
+}
+
+func TestGormArtifactRepoDelete_keepsTestResults(t *testing.T) {
+	m, dbProject := newTestInsertBuildModule(t)
+	dbBuild := database.Build{ProjectID: dbProject.ProjectID}
+	require.NoError(t, m.Database.Create(&dbBuild).Error)
+	dbArtifact := database.Artifact{BuildID: dbBuild.BuildID, FileName: "results.trx"}
+	require.NoError(t, m.Database.Create(&dbArtifact).Error)
+	dbSummary := database.TestResultSummary{ArtifactID: &dbArtifact.ArtifactID, BuildID: dbBuild.BuildID, Total: 1, Passed: 1}
+	require.NoError(t, m.Database.Create(&dbSummary).Error)
+	dbDetail := database.TestResultDetail{
+		ArtifactID:          &dbArtifact.ArtifactID,
+		BuildID:             dbBuild.BuildID,
+		TestResultSummaryID: null.IntFrom(int64(dbSummary.TestResultSummaryID)),
+		Name:                "TestPass",
+		Status:              database.TestResultStatusSuccess,
+	}
+	require.NoError(t, m.Database.Create(&dbDetail).Error)
+
+	repo := gormArtifactRepo{db: m.Database}
+	assert.ErrorIs(t, repo.Delete(dbBuild.BuildID+1, dbArtifact.ArtifactID), errRepoNotFound)
+	require.NoError(t, repo.Delete(dbBuild.BuildID, dbArtifact.ArtifactID))
+	assert.ErrorIs(t, repo.Delete(dbBuild.BuildID, dbArtifact.ArtifactID), errRepoNotFound)
+
+	var gotSummary database.TestResultSummary
+	require.NoError(t, m.Database.First(&gotSummary, dbSummary.TestResultSummaryID).Error)
+	assert.Nil(t, gotSummary.ArtifactID)
+	assert.Equal(t, uint(1), gotSummary.Passed)
+
+	var gotDetail database.TestResultDetail
+	require.NoError(t, m.Database.First(&gotDetail, dbDetail.TestResultDetailID).Error)
+	assert.Nil(t, gotDetail.ArtifactID)
+	assert.Equal(t, null.IntFrom(int64(dbSummary.TestResultSummaryID)), gotDetail.TestResultSummaryID)
+	assert.Equal(t, "TestPass", gotDetail.Name)
+}
+
+func TestMatchTestResultFileName(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		fileName string
+		want     bool
+	}{
+		{pattern: "", fileName: "results.trx", want: true},
+		{pattern: "", fileName: "junit.XML", want: true},
+		{pattern: "", fileName: "report.txt", want: false},
+		{pattern: "*.trx", fileName: "results.trx", want: true},
+		{pattern: "*.trx", fileName: "junit.xml", want: false},
+		{pattern: "junit-*.xml", fileName: "junit-api.xml", want: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.fileName, func(t *testing.T) {
+			assert.Equal(t, tc.want, matchTestResultFileName(tc.pattern, tc.fileName))
+		})
+	}
+}
+
+func TestReparseBuildTestResultsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, dbProject := newTestInsertBuildModule(t)
+	dbBuild := database.Build{ProjectID: dbProject.ProjectID}
+	require.NoError(t, m.Database.Create(&dbBuild).Error)
+	const junit = `<testsuite><testcase name="TestPass" classname="wharf" /></testsuite>`
+	dbArtifacts := []database.Artifact{
+		{BuildID: dbBuild.BuildID, FileName: "junit.xml", Data: []byte(junit)},
+		{BuildID: dbBuild.BuildID, FileName: "other.xml", Data: []byte("<other />")},
+		{BuildID: dbBuild.BuildID, FileName: "report.txt", Data: []byte("hello")},
+	}
+	require.NoError(t, m.Database.Create(&dbArtifacts).Error)
+
+	r := gin.New()
+	buildTestResultModule{Database: m.Database}.Register(r.Group("/build/:buildId"))
+	reparse := func() response.TestResultReparse {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/build/1/test-result/reparse", nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resReparse response.TestResultReparse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resReparse))
+		return resReparse
+	}
+
+	resReparse := reparse()
+	require.Len(t, resReparse.Reparsed, 1)
+	assert.Equal(t, "junit.xml", resReparse.Reparsed[0].FileName)
+	require.Len(t, resReparse.Skipped, 1)
+	assert.Equal(t, "other.xml", resReparse.Skipped[0].FileName)
+
+	reparse()
+	var summaryCount, detailCount int64
+	require.NoError(t, m.Database.Model(&database.TestResultSummary{}).Count(&summaryCount).Error)
+	require.NoError(t, m.Database.Model(&database.TestResultDetail{}).Count(&detailCount).Error)
+	assert.Equal(t, int64(1), summaryCount, "re-parsing must replace the previous summary")
+	assert.Equal(t, int64(1), detailCount, "re-parsing must replace the previous details")
+
+	var dbDetail database.TestResultDetail
+	require.NoError(t, m.Database.First(&dbDetail).Error)
+	assert.Equal(t, "wharf", dbDetail.ClassName)
+	assert.True(t, dbDetail.TestResultSummaryID.Valid)
+}
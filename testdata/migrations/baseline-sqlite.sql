@@ -0,0 +1,29 @@
+CREATE TABLE migration (migration_id VARCHAR(255) PRIMARY KEY);
+INSERT INTO migration VALUES('SCHEMA_INIT');
+CREATE TABLE IF NOT EXISTS "token" (`created_at` datetime,`updated_at` datetime,`token_id` integer,`value` text NOT NULL,`user_name` text NOT NULL DEFAULT "",PRIMARY KEY (`token_id`));
+CREATE TABLE IF NOT EXISTS "provider" (`created_at` datetime,`updated_at` datetime,`provider_id` integer,`name` text NOT NULL,`url` text NOT NULL,`token_id` integer DEFAULT NULL,PRIMARY KEY (`provider_id`),CONSTRAINT `fk_provider_token` FOREIGN KEY (`token_id`) REFERENCES `token`(`token_id`) ON DELETE RESTRICT ON UPDATE RESTRICT);
+CREATE TABLE IF NOT EXISTS "project" (`created_at` datetime,`updated_at` datetime,`project_id` integer,`remote_project_id` text NOT NULL DEFAULT "",`name` text NOT NULL,`group_name` text NOT NULL DEFAULT "",`description` text NOT NULL DEFAULT "",`avatar_url` text NOT NULL DEFAULT "",`token_id` integer DEFAULT NULL,`provider_id` integer DEFAULT NULL,`build_definition` text NOT NULL DEFAULT "",`git_url` text NOT NULL DEFAULT "",PRIMARY KEY (`project_id`),CONSTRAINT `fk_project_token` FOREIGN KEY (`token_id`) REFERENCES `token`(`token_id`) ON DELETE RESTRICT ON UPDATE RESTRICT,CONSTRAINT `fk_project_provider` FOREIGN KEY (`provider_id`) REFERENCES `provider`(`provider_id`) ON DELETE RESTRICT ON UPDATE RESTRICT);
+CREATE TABLE IF NOT EXISTS "project_overrides" (`project_overrides_id` integer,`project_id` integer,`description` text NOT NULL DEFAULT "",`avatar_url` text NOT NULL DEFAULT "",`git_url` text NOT NULL DEFAULT "",PRIMARY KEY (`project_overrides_id`),CONSTRAINT `fk_project_overrides` FOREIGN KEY (`project_id`) REFERENCES `project`(`project_id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS "branch" (`created_at` datetime,`updated_at` datetime,`branch_id` integer,`project_id` integer NOT NULL,`name` text NOT NULL,`default` numeric NOT NULL,`token_id` integer DEFAULT NULL,PRIMARY KEY (`branch_id`),CONSTRAINT `fk_project_branches` FOREIGN KEY (`project_id`) REFERENCES `project`(`project_id`) ON DELETE CASCADE ON UPDATE CASCADE,CONSTRAINT `fk_branch_token` FOREIGN KEY (`token_id`) REFERENCES `token`(`token_id`) ON DELETE RESTRICT ON UPDATE RESTRICT);
+CREATE TABLE IF NOT EXISTS "build" (`created_at` datetime,`updated_at` datetime,`build_id` integer,`status_id` integer NOT NULL,`project_id` integer NOT NULL,`scheduled_on` datetime DEFAULT NULL,`started_on` datetime DEFAULT NULL,`completed_on` datetime DEFAULT NULL,`git_branch` text NOT NULL DEFAULT "",`environment` text,`stage` text NOT NULL DEFAULT "",`worker_id` text NOT NULL DEFAULT "",`is_invalid` numeric NOT NULL DEFAULT false,`engine_id` text NOT NULL DEFAULT "",PRIMARY KEY (`build_id`),CONSTRAINT `fk_build_project` FOREIGN KEY (`project_id`) REFERENCES `project`(`project_id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS "log" (`log_id` integer,`build_id` integer NOT NULL,`message` text,`timestamp` datetime NOT NULL,PRIMARY KEY (`log_id`),CONSTRAINT `fk_log_build` FOREIGN KEY (`build_id`) REFERENCES `build`(`build_id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS "artifact" (`created_at` datetime,`updated_at` datetime,`artifact_id` integer,`build_id` integer NOT NULL,`name` text NOT NULL,`file_name` text NOT NULL DEFAULT "",`data` blob,PRIMARY KEY (`artifact_id`),CONSTRAINT `fk_artifact_build` FOREIGN KEY (`build_id`) REFERENCES `build`(`build_id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS "build_param" (`build_param_id` integer,`build_id` integer NOT NULL,`name` text NOT NULL,`value` text NOT NULL DEFAULT "",PRIMARY KEY (`build_param_id`),CONSTRAINT `fk_build_params` FOREIGN KEY (`build_id`) REFERENCES `build`(`build_id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS "param" (`param_id` integer,`name` text NOT NULL,`type` text NOT NULL,`value` text NOT NULL DEFAULT "",`default_value` text NOT NULL DEFAULT "",PRIMARY KEY (`param_id`));
+CREATE TABLE IF NOT EXISTS "test_result_detail" (`created_at` datetime,`updated_at` datetime,`test_result_detail_id` integer,`artifact_id` integer NOT NULL,`build_id` integer NOT NULL,`name` text NOT NULL,`message` text,`started_on` datetime DEFAULT NULL,`completed_on` datetime DEFAULT NULL,`status` text NOT NULL,PRIMARY KEY (`test_result_detail_id`),CONSTRAINT `fk_test_result_detail_artifact` FOREIGN KEY (`artifact_id`) REFERENCES `artifact`(`artifact_id`) ON DELETE SET NULL ON UPDATE CASCADE,CONSTRAINT `fk_test_result_detail_build` FOREIGN KEY (`build_id`) REFERENCES `build`(`build_id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS "test_result_summary" (`created_at` datetime,`updated_at` datetime,`test_result_summary_id` integer,`file_name` text NOT NULL DEFAULT "",`artifact_id` integer NOT NULL,`build_id` integer NOT NULL,`total` integer NOT NULL,`failed` integer NOT NULL,`passed` integer NOT NULL,`skipped` integer NOT NULL,PRIMARY KEY (`test_result_summary_id`),CONSTRAINT `fk_build_test_result_summaries` FOREIGN KEY (`build_id`) REFERENCES `build`(`build_id`),CONSTRAINT `fk_test_result_summary_artifact` FOREIGN KEY (`artifact_id`) REFERENCES `artifact`(`artifact_id`) ON DELETE SET NULL ON UPDATE CASCADE);
+CREATE INDEX `provider_idx_token_id` ON `provider`(`token_id`);
+CREATE INDEX `project_idx_token_id` ON `project`(`token_id`);
+CREATE INDEX `project_idx_provider_id` ON `project`(`provider_id`);
+CREATE UNIQUE INDEX `project_overrides_idx_project_id` ON `project_overrides`(`project_id`);
+CREATE INDEX `branch_idx_project_id` ON `branch`(`project_id`);
+CREATE INDEX `branch_idx_token_id` ON `branch`(`token_id`);
+CREATE INDEX `build_idx_project_id` ON `build`(`project_id`);
+CREATE INDEX `build_idx_worker_id` ON `build`(`worker_id`);
+CREATE INDEX `log_idx_build_id` ON `log`(`build_id`);
+CREATE INDEX `artifact_idx_build_id` ON `artifact`(`build_id`);
+CREATE INDEX `buildparam_idx_build_id` ON `build_param`(`build_id`);
+CREATE INDEX `testresultdetail_idx_artifact_id` ON `test_result_detail`(`artifact_id`);
+CREATE INDEX `testresultdetail_idx_build_id` ON `test_result_detail`(`build_id`);
+CREATE INDEX `testresultsummary_idx_artifact_id` ON `test_result_summary`(`artifact_id`);
+CREATE INDEX `testresultsummary_idx_build_id` ON `test_result_summary`(`build_id`);
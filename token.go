@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-api/v5/internal/wherefields"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
@@ -31,14 +32,17 @@ func (m tokenModule) Register(g *gin.RouterGroup) {
 		{
 			tokenByID.GET("", m.getTokenHandler)
 			tokenByID.PUT("", m.updateTokenHandler)
+			tokenByID.PATCH("", m.patchTokenHandler)
 		}
 	}
 }
 
 var tokenJSONToColumns = map[string]database.SafeSQLName{
-	response.TokenJSONFields.TokenID:  database.TokenColumns.TokenID,
-	response.TokenJSONFields.Token:    database.TokenColumns.Token,
-	response.TokenJSONFields.UserName: database.TokenColumns.UserName,
+	response.TokenJSONFields.TokenID:          database.TokenColumns.TokenID,
+	response.TokenJSONFields.Token:            database.TokenColumns.Token,
+	response.TokenJSONFields.UserName:         database.TokenColumns.UserName,
+	response.TimeMetadataJSONFields.CreatedAt: database.TimeMetadataColumns.CreatedAt,
+	response.TimeMetadataJSONFields.UpdatedAt: database.TimeMetadataColumns.UpdatedAt,
 }
 
 var defaultGetTokensOrderBy = orderby.Column{Name: database.TokenColumns.TokenID, Direction: orderby.Desc}
@@ -52,11 +56,16 @@ var defaultGetTokensOrderBy = orderby.Column{Name: database.TokenColumns.TokenID
 // @description Added in v5.0.0.
 // @tags token
 // @produce json
-// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`). Required if `offset` is used." default(100)
+// @param limit query int false "Number of results to return. No limiting is applied if empty (`?limit=`) or non-positive (`?limit=0`), unless a max limit is configured, which rejects larger limits. The default and max limit are configurable since v5.3.0. Required if `offset` is used." default(100)
 // @param offset query int false "Skipped results, where 0 means from the start." minimum(0) default(0)
-// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc'. Can be specified multiple times for more granular sorting. Defaults to `?orderby=tokenId desc`"
+// @param skipTotal query bool false "Skip counting the total number of results, and respond with `totalCount` set to -1. Added in v5.3.0."
+// @param orderby query []string false "Sorting orders. Takes the property name followed by either 'asc' or 'desc', in any casing, or the property name prefixed with '+' (URL-encoded as `%2B`) for ascending or '-' for descending. Can be specified multiple times, or comma-separated, for more granular sorting, up to 10 orderings. Casing, prefixes, and commas added in v5.3.0. Defaults to `?orderby=tokenId desc`"
 // @param userName query string false "Filter by verbatim token user name."
 // @param userNameMatch query string false "Filter by matching token user name. Cannot be used with `userName`."
+// @param createdAfter query string false "Filter by tokens with created date later than value. Added in v5.3.0." format(date-time)
+// @param createdBefore query string false "Filter by tokens with created date earlier than value. Added in v5.3.0." format(date-time)
+// @param updatedAfter query string false "Filter by tokens with last updated date later than value. Added in v5.3.0." format(date-time)
+// @param updatedBefore query string false "Filter by tokens with last updated date earlier than value. Added in v5.3.0." format(date-time)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.PaginatedTokens
 // @failure 400 {object} problem.Response "Bad request"
@@ -69,6 +78,8 @@ func (m tokenModule) getTokenListHandler(c *gin.Context) {
 
 		UserName      *string `form:"userName"`
 		UserNameMatch *string `form:"userNameMatch" binding:"excluded_with=UserNameMatch"`
+
+		timeMetadataQueryParams
 	}{
 		commonGetQueryParams: defaultCommonGetQueryParams,
 	}
@@ -90,17 +101,19 @@ func (m tokenModule) getTokenListHandler(c *gin.Context) {
 			whereLikeScope(map[database.SafeSQLName]*string{
 				database.TokenColumns.UserName: params.UserNameMatch,
 			}),
+			optionalTimeRangeScope(database.TimeMetadataColumns.CreatedAt, params.CreatedAfter, params.CreatedBefore),
+			optionalTimeRangeScope(database.TimeMetadataColumns.UpdatedAt, params.UpdatedAfter, params.UpdatedBefore),
 		)
 
 	var dbTokens []database.Token
 	var totalCount int64
-	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, &dbTokens, &totalCount)
+	err := findDBPaginatedSliceAndTotalCount(query, params.Limit, params.Offset, params.SkipTotal, &dbTokens, &totalCount)
 	if err != nil {
 		ginutil.WriteDBReadError(c, err, "Failed fetching list of tokens from database.")
 		return
 	}
 
-	renderJSON(c, http.StatusOK, response.PaginatedTokens{
+	ginrender.Response(c, http.StatusOK, response.PaginatedTokens{
 		List:       modelconv.DBTokensToResponses(dbTokens),
 		TotalCount: totalCount,
 	})
@@ -115,6 +128,7 @@ func (m tokenModule) getTokenListHandler(c *gin.Context) {
 // @param tokenId path uint true "Token ID" minimum(0)
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.Token
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 502 {object} problem.Response "Database is unreachable"
@@ -131,7 +145,8 @@ func (m tokenModule) getTokenHandler(c *gin.Context) {
 	}
 
 	resToken := modelconv.DBTokenToResponse(dbToken)
-	renderJSON(c, http.StatusOK, resToken)
+	setETagHeader(c, dbToken.Version)
+	ginrender.Response(c, http.StatusOK, resToken)
 }
 
 // createTokenHandler godoc
@@ -153,7 +168,7 @@ func (m tokenModule) getTokenHandler(c *gin.Context) {
 func (m tokenModule) createTokenHandler(c *gin.Context) {
 	var reqToken request.Token
 	if err := c.ShouldBindJSON(&reqToken); err != nil {
-		ginutil.WriteInvalidBindError(c, err,
+		writeInvalidBindError(c, err,
 			"One or more parameters failed to parse when reading the request body for the token object to create.")
 		return
 	}
@@ -183,7 +198,7 @@ func (m tokenModule) createTokenHandler(c *gin.Context) {
 	}
 
 	resToken := modelconv.DBTokenToResponse(dbToken)
-	renderJSON(c, http.StatusCreated, resToken)
+	ginrender.Response(c, http.StatusCreated, resToken)
 }
 
 // updateTokenHandler godoc
@@ -196,11 +211,14 @@ func (m tokenModule) createTokenHandler(c *gin.Context) {
 // @produce json
 // @param tokenId path uint true "ID of token to update" minimum(0)
 // @param token body request.TokenUpdate _ "New token values"
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches. Added in v5.3.0."
 // @param pretty query bool false "Pretty indented JSON output"
 // @success 200 {object} response.Token
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
 // @failure 400 {object} problem.Response "Bad request"
 // @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
 // @failure 404 {object} problem.Response "Token not found"
+// @failure 412 {object} problem.Response "Token was modified since it was fetched, as the If-Match header did not match"
 // @failure 502 {object} problem.Response "Database is unreachable"
 // @router /token/{tokenId} [put]
 func (m tokenModule) updateTokenHandler(c *gin.Context) {
@@ -210,25 +228,80 @@ func (m tokenModule) updateTokenHandler(c *gin.Context) {
 	}
 	var reqToken request.TokenUpdate
 	if err := c.ShouldBindJSON(&reqToken); err != nil {
-		ginutil.WriteInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading the request body.")
 		return
 	}
 	dbToken, ok := fetchTokenByID(c, m.Database, tokenID, "when updating token")
 	if !ok {
 		return
 	}
+	if !validateIfMatch(c, dbToken.Version, "token") {
+		return
+	}
+	m.saveTokenUpdate(c, dbToken, reqToken)
+}
 
+// patchTokenHandler godoc
+// @id patchToken
+// @summary Partially update token in database.
+// @description Updates a token by applying a JSON Merge Patch (RFC 7396), where only the supplied fields are modified.
+// @description Fields set to null are reset to their zero values.
+// @description Added in v5.3.0.
+// @tags token
+// @accept json,application/merge-patch+json
+// @produce json
+// @param tokenId path uint true "ID of token to update" minimum(0)
+// @param token body request.TokenUpdate _ "Token fields to update"
+// @param If-Match header string false "Only update if the current entity tag (ETag) matches."
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.Token
+// @header 200 {string} ETag "Entity tag of the current version, for use in the If-Match header of updates"
+// @failure 400 {object} problem.Response "Bad request"
+// @failure 401 {object} problem.Response "Unauthorized or missing jwt token"
+// @failure 404 {object} problem.Response "Token not found"
+// @failure 412 {object} problem.Response "Token was modified since it was fetched, as the If-Match header did not match"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /token/{tokenId} [patch]
+func (m tokenModule) patchTokenHandler(c *gin.Context) {
+	tokenID, ok := ginutil.ParseParamUint(c, "tokenId")
+	if !ok {
+		return
+	}
+	dbToken, ok := fetchTokenByID(c, m.Database, tokenID, "when updating token")
+	if !ok {
+		return
+	}
+	if !validateIfMatch(c, dbToken.Version, "token") {
+		return
+	}
+	reqToken := modelconv.DBTokenToUpdateRequest(dbToken)
+	if !bindMergePatch(c, &reqToken) {
+		return
+	}
+	m.saveTokenUpdate(c, dbToken, reqToken)
+}
+
+func (m tokenModule) saveTokenUpdate(c *gin.Context, dbToken database.Token, reqToken request.TokenUpdate) {
+	tokenID := dbToken.TokenID
 	dbToken.Value = reqToken.Token
 	dbToken.UserName = reqToken.UserName
 
-	if err := m.Database.Save(&dbToken).Error; err != nil {
+	saved, err := saveVersioned(m.Database, &dbToken, &dbToken.Version,
+		database.TokenColumns.Version, hasIfMatchHeader(c))
+	if err != nil {
 		ginutil.WriteDBWriteError(c, err, fmt.Sprintf(
 			"Failed to update token by ID %d.",
 			tokenID))
+		return
+	}
+	if !saved {
+		writePreconditionFailedProblem(c, "token")
+		return
 	}
 
 	resToken := modelconv.DBTokenToResponse(dbToken)
-	renderJSON(c, http.StatusOK, resToken)
+	setETagHeader(c, dbToken.Version)
+	ginrender.Response(c, http.StatusOK, resToken)
 }
 
 func fetchTokenByID(c *gin.Context, db *gorm.DB, tokenID uint, whenMsg string) (database.Token, bool) {
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+const uploadSizeLimitContextKey = "uploadSizeLimit"
+
+var errUploadTooLarge = errors.New("request body exceeds the maximum upload size")
+
+// uploadSizeLimitMiddleware rejects request bodies larger than the
+// artifact.maxUploadSize config. Requests that declare a too large
+// Content-Length are rejected right away, while the rest have their body
+// limited, so that chunked uploads are not read past the limit.
+type uploadSizeLimitMiddleware struct {
+	maxSize int64
+}
+
+func newUploadSizeLimitMiddleware(maxSize int64) uploadSizeLimitMiddleware {
+	return uploadSizeLimitMiddleware{maxSize: maxSize}
+}
+
+// handle is a gin middleware function. Does nothing if the max size is zero.
+//
+// Must be added before any other middleware that reads the request body,
+// such as the idempotency key middleware.
+func (m uploadSizeLimitMiddleware) handle(c *gin.Context) {
+	if m.maxSize <= 0 {
+		return
+	}
+	if c.Request.ContentLength > m.maxSize {
+		writeUploadTooLargeProblem(c, m.maxSize)
+		c.Abort()
+		return
+	}
+	body := &limitedUploadBody{ReadCloser: c.Request.Body, maxSize: m.maxSize, remaining: m.maxSize}
+	c.Request.Body = body
+	c.Set(uploadSizeLimitContextKey, body)
+}
+
+// limitedUploadBody is a request body that fails with errUploadTooLarge when
+// reading more than its max size.
+type limitedUploadBody struct {
+	io.ReadCloser
+	maxSize   int64
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedUploadBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errUploadTooLarge
+	}
+	// Reads one byte past the limit, to tell a body of exactly the max size
+	// apart from a larger body.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, errUploadTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// writeUploadTooLargeIfExceeded writes an "upload too large" problem and
+// returns true if the request body was read past the upload size limit. Meant
+// to be called when reading the request body fails, before writing any other
+// problem, as the error from reading the body may have been wrapped in a way
+// that hides errUploadTooLarge, such as when parsing multipart form data.
+func writeUploadTooLargeIfExceeded(c *gin.Context) bool {
+	value, ok := c.Get(uploadSizeLimitContextKey)
+	if !ok {
+		return false
+	}
+	body, ok := value.(*limitedUploadBody)
+	if !ok || !body.exceeded {
+		return false
+	}
+	writeUploadTooLargeProblem(c, body.maxSize)
+	return true
+}
+
+func writeUploadTooLargeProblem(c *gin.Context, maxSize int64) {
+	ginutil.WriteProblemError(c, errUploadTooLarge, problems.UploadTooLarge.Newf(
+		"The request body must not be larger than %d bytes, as set by the artifact.maxUploadSize config.",
+		maxSize))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadSizeLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(maxSize int64) *gin.Engine {
+		r := gin.New()
+		r.POST("/upload", newUploadSizeLimitMiddleware(maxSize).handle, func(c *gin.Context) {
+			if _, err := io.ReadAll(c.Request.Body); err != nil {
+				if !writeUploadTooLargeIfExceeded(c) {
+					c.Status(http.StatusBadRequest)
+				}
+				return
+			}
+			c.Status(http.StatusCreated)
+		})
+		return r
+	}
+	post := func(r *gin.Engine, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	testCases := []struct {
+		name       string
+		maxSize    int64
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{name: "below limit", maxSize: 5, body: "abcd", wantStatus: http.StatusCreated},
+		{name: "at limit", maxSize: 5, body: "abcde", wantStatus: http.StatusCreated},
+		{name: "above limit", maxSize: 5, body: "abcdef", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "chunked at limit", maxSize: 5, body: "abcde", chunked: true, wantStatus: http.StatusCreated},
+		{name: "chunked above limit", maxSize: 5, body: "abcdef", chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "no limit", maxSize: 0, body: "abcdef", chunked: true, wantStatus: http.StatusCreated},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := post(newRouter(tc.maxSize), tc.body, tc.chunked)
+			assert.Equal(t, tc.wantStatus, w.Code)
+			if tc.wantStatus == http.StatusRequestEntityTooLarge {
+				assert.Contains(t, w.Body.String(), problems.UploadTooLarge.Type)
+				assert.Contains(t, w.Body.String(), "larger than 5 bytes")
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+	"github.com/iver-wharf/wharf-core/pkg/problem"
+	"gorm.io/gorm"
+)
+
+type userSubscriptionModule struct {
+	Database *gorm.DB
+	Config   *Config
+}
+
+func (m userSubscriptionModule) Register(g *gin.RouterGroup) {
+	userSubscriptions := g.Group("/user/subscriptions")
+	{
+		userSubscriptions.GET("", m.getUserSubscriptionsHandler)
+		userSubscriptions.PUT("", m.updateUserSubscriptionsHandler)
+	}
+}
+
+// getUserSubscriptionsHandler godoc
+// @id getUserSubscriptions
+// @summary Get email subscriptions of the current user.
+// @description Returns the projects that the user identified by the OIDC
+// @description access token's `sub` claim receives emails about when their
+// @description builds fail.
+// @description Added in v5.3.0.
+// @tags user
+// @produce json
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.UserSubscriptions "User subscriptions"
+// @failure 401 {object} problem.Response "Unauthorized, missing jwt token, or OIDC is disabled"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /user/subscriptions [get]
+func (m userSubscriptionModule) getUserSubscriptionsHandler(c *gin.Context) {
+	subject, ok := getUserSubject(c, "User subscriptions")
+	if !ok {
+		return
+	}
+	dbSubscriptions, err := findProjectSubscriptions(m.Database, subject)
+	if err != nil {
+		ginutil.WriteDBReadError(c, err, "Failed fetching user subscriptions from database.")
+		return
+	}
+	ginrender.Response(c, http.StatusOK, newUserSubscriptionsResponse(dbSubscriptions))
+}
+
+// updateUserSubscriptionsHandler godoc
+// @id updateUserSubscriptions
+// @summary Update email subscriptions of the current user.
+// @description Replaces the projects that the user identified by the OIDC
+// @description access token's `sub` claim receives emails about when their
+// @description builds fail. The emails are sent using the SMTP server
+// @description configured by notification.smtp, which is required unless the
+// @description list of projects is empty.
+// @description Added in v5.3.0.
+// @tags user
+// @accept json
+// @produce json
+// @param subscriptions body request.UserSubscriptions true "New user subscriptions"
+// @param pretty query bool false "Pretty indented JSON output"
+// @success 200 {object} response.UserSubscriptions "Updated user subscriptions"
+// @failure 400 {object} problem.Response "Bad request, such as an invalid or missing email address"
+// @failure 401 {object} problem.Response "Unauthorized, missing jwt token, or OIDC is disabled"
+// @failure 404 {object} problem.Response "Project not found"
+// @failure 409 {object} problem.Response "No SMTP server is configured"
+// @failure 502 {object} problem.Response "Database is unreachable"
+// @router /user/subscriptions [put]
+func (m userSubscriptionModule) updateUserSubscriptionsHandler(c *gin.Context) {
+	subject, ok := getUserSubject(c, "User subscriptions")
+	if !ok {
+		return
+	}
+	var reqSubscriptions request.UserSubscriptions
+	if err := c.ShouldBindJSON(&reqSubscriptions); err != nil {
+		writeInvalidBindError(c, err,
+			"One or more parameters failed to parse when reading the request body for user subscriptions to update.")
+		return
+	}
+
+	var dbSubscriptions []database.ProjectSubscription
+	if len(reqSubscriptions.ProjectIDs) > 0 {
+		email, ok := m.validateSubscriptionEmail(c, reqSubscriptions.Email)
+		if !ok {
+			return
+		}
+		seen := make(map[uint]struct{}, len(reqSubscriptions.ProjectIDs))
+		for _, projectID := range reqSubscriptions.ProjectIDs {
+			if _, ok := seen[projectID]; ok {
+				continue
+			}
+			seen[projectID] = struct{}{}
+			if !validateProjectExistsByID(c, m.Database, projectID, "when subscribing to project") {
+				return
+			}
+			dbSubscriptions = append(dbSubscriptions, database.ProjectSubscription{
+				Subject:   subject,
+				ProjectID: projectID,
+				Email:     email,
+			})
+		}
+		sort.Slice(dbSubscriptions, func(i, j int) bool {
+			return dbSubscriptions[i].ProjectID < dbSubscriptions[j].ProjectID
+		})
+	}
+
+	err := m.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(&database.ProjectSubscription{Subject: subject}, database.ProjectSubscriptionFields.Subject).
+			Delete(&database.ProjectSubscription{}).
+			Error; err != nil {
+			return err
+		}
+		if len(dbSubscriptions) == 0 {
+			return nil
+		}
+		return tx.Create(&dbSubscriptions).Error
+	})
+	if err != nil {
+		ginutil.WriteDBWriteError(c, err, "Failed updating user subscriptions in database.")
+		return
+	}
+	ginrender.Response(c, http.StatusOK, newUserSubscriptionsResponse(dbSubscriptions))
+}
+
+// validateSubscriptionEmail returns the email address to subscribe with,
+// which defaults to the OIDC access token's "email" claim.
+func (m userSubscriptionModule) validateSubscriptionEmail(c *gin.Context, email string) (string, bool) {
+	if m.Config.Notification.SMTP.Host == "" {
+		ginutil.WriteProblem(c, problems.NotificationNoSMTP.With(problem.Response{
+			Detail:   "Cannot subscribe to emails, as no SMTP server is configured in wharf-api.",
+			Instance: c.Request.RequestURI + "#projectIds",
+		}))
+		return "", false
+	}
+	if email == "" {
+		claims, _ := getOIDCClaims(c)
+		email = getOIDCClaimString(claims, "email")
+	}
+	if email == "" {
+		ginutil.WriteProblem(c, problems.InvalidParam.With(problem.Response{
+			Detail:   "An email address is required, as the OIDC access token has no 'email' claim.",
+			Instance: c.Request.RequestURI + "#email",
+		}))
+		return "", false
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || len(addr.Address) > database.ProjectSubscriptionSizes.Email {
+		ginutil.WriteProblem(c, problems.InvalidParam.With(problem.Response{
+			Detail:   fmt.Sprintf("The value %q is not a valid email address.", email),
+			Instance: c.Request.RequestURI + "#email",
+		}))
+		return "", false
+	}
+	return addr.Address, true
+}
+
+func findProjectSubscriptions(db *gorm.DB, subject string) ([]database.ProjectSubscription, error) {
+	var dbSubscriptions []database.ProjectSubscription
+	err := db.
+		Where(&database.ProjectSubscription{Subject: subject}, database.ProjectSubscriptionFields.Subject).
+		Order(database.ProjectSubscriptionColumns.ProjectID).
+		Find(&dbSubscriptions).
+		Error
+	return dbSubscriptions, err
+}
+
+func newUserSubscriptionsResponse(dbSubscriptions []database.ProjectSubscription) response.UserSubscriptions {
+	resSubscriptions := response.UserSubscriptions{
+		ProjectIDs: make([]uint, len(dbSubscriptions)),
+	}
+	for i, dbSubscription := range dbSubscriptions {
+		resSubscriptions.Email = dbSubscription.Email
+		resSubscriptions.ProjectIDs[i] = dbSubscription.ProjectID
+	}
+	return resSubscriptions
+}
+
+// emailFailedBuildSubscribers sends emails in the background to the users
+// subscribed to the failed build's project. Does nothing if no SMTP server is
+// configured.
+func (m buildModule) emailFailedBuildSubscribers(dbBuild database.Build) {
+	if m.Email == nil {
+		return
+	}
+	go func() {
+		if err := m.sendFailedBuildEmails(dbBuild); err != nil {
+			log.Warn().
+				WithError(err).
+				WithUint("build", dbBuild.BuildID).
+				Message("Failed to email subscribers about failed build.")
+		}
+	}()
+}
+
+// sendFailedBuildEmails sends one email to each user subscribed to the failed
+// build's project, so that the subscribers do not see each other's email
+// addresses.
+func (m buildModule) sendFailedBuildEmails(dbBuild database.Build) error {
+	var dbSubscriptions []database.ProjectSubscription
+	if err := m.Database.
+		Where(&database.ProjectSubscription{ProjectID: dbBuild.ProjectID}, database.ProjectSubscriptionFields.ProjectID).
+		Find(&dbSubscriptions).
+		Error; err != nil {
+		return fmt.Errorf("fetch subscriptions: %w", err)
+	}
+	if len(dbSubscriptions) == 0 {
+		return nil
+	}
+	projectName, err := findProjectName(m.Database, dbBuild.ProjectID)
+	if err != nil {
+		return fmt.Errorf("fetch project name: %w", err)
+	}
+	subject := fmt.Sprintf("Build %d of %s failed", dbBuild.BuildID, projectName)
+	var body strings.Builder
+	fmt.Fprintf(&body, "Build %d of project %s failed on branch %s.",
+		dbBuild.BuildID, projectName, dbBuild.GitBranch)
+	if dbBuild.StatusMessage != "" {
+		fmt.Fprintf(&body, "\r\n\r\nStatus message: %s", dbBuild.StatusMessage)
+	}
+	body.WriteString("\r\n\r\nYou receive this email as you are subscribed to the project's failed builds.")
+
+	sent := make(map[string]struct{}, len(dbSubscriptions))
+	for _, dbSubscription := range dbSubscriptions {
+		if _, ok := sent[dbSubscription.Email]; ok {
+			continue
+		}
+		sent[dbSubscription.Email] = struct{}{}
+		if err := m.Email.sendEmail(dbSubscription.Email, subject, body.String()); err != nil {
+			log.Warn().
+				WithError(err).
+				WithUint("build", dbBuild.BuildID).
+				WithString("subject", dbSubscription.Subject).
+				Message("Failed to email subscriber about failed build.")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSubscriptionsHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestSqliteDB(t)
+	dbProjects := []database.Project{{Name: "wharf-api"}, {Name: "wharf-web"}}
+	require.NoError(t, db.Create(&dbProjects).Error)
+	config := &Config{}
+	m := userSubscriptionModule{Database: db, Config: config}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(oidcClaimsContextKey, jwt.MapClaims{"sub": "alice-sub", "email": "alice@example.com"})
+	})
+	m.Register(r.Group(""))
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/user/subscriptions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) response.UserSubscriptions {
+		var res response.UserSubscriptions
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+
+	w := serve(http.MethodPut, `{"projectIds":[1]}`)
+	assert.Equal(t, http.StatusConflict, w.Code, "must require SMTP")
+	assert.Contains(t, w.Body.String(), "/prob/api/notification/no-smtp")
+
+	config.Notification.SMTP.Host = "smtp.example.com"
+	w = serve(http.MethodPut, `{"projectIds":[2,1,2]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, response.UserSubscriptions{Email: "alice@example.com", ProjectIDs: []uint{1, 2}}, decode(w))
+
+	w = serve(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, response.UserSubscriptions{Email: "alice@example.com", ProjectIDs: []uint{1, 2}}, decode(w))
+
+	w = serve(http.MethodPut, `{"email":"not an email","projectIds":[1]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(http.MethodPut, `{"email":"Alice <alice.work@example.com>","projectIds":[1]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, response.UserSubscriptions{Email: "alice.work@example.com", ProjectIDs: []uint{1}}, decode(w))
+
+	w = serve(http.MethodPut, `{"projectIds":[]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, response.UserSubscriptions{ProjectIDs: []uint{}}, decode(w))
+}
+
+func TestSendFailedBuildEmails(t *testing.T) {
+	m, dbProject := newTestInsertBuildModule(t)
+	dbSubscriptions := []database.ProjectSubscription{
+		{Subject: "alice-sub", ProjectID: dbProject.ProjectID, Email: "alice@example.com"},
+		{Subject: "bob-sub", ProjectID: dbProject.ProjectID, Email: "bob@example.com"},
+		{Subject: "bob-other-sub", ProjectID: dbProject.ProjectID, Email: "bob@example.com"},
+	}
+	require.NoError(t, m.Database.Create(&dbSubscriptions).Error)
+	var gotTo [][]string
+	var gotMsgs []string
+	m.Email = &emailNotifier{
+		config: SMTPConfig{From: "wharf@example.com"},
+		sendMail: func(from string, to []string, msg []byte) error {
+			gotTo = append(gotTo, to)
+			gotMsgs = append(gotMsgs, string(msg))
+			return nil
+		},
+	}
+	dbBuild := database.Build{
+		BuildID:       5,
+		ProjectID:     dbProject.ProjectID,
+		GitBranch:     "master",
+		StatusID:      database.BuildFailed,
+		StatusMessage: "timed out",
+	}
+
+	require.NoError(t, m.sendFailedBuildEmails(dbBuild))
+	assert.Equal(t, [][]string{{"alice@example.com"}, {"bob@example.com"}}, gotTo)
+	require.Len(t, gotMsgs, 2)
+	assert.Contains(t, gotMsgs[0], "Subject: Build 5 of wharf-api failed\r\n")
+	assert.Contains(t, gotMsgs[0], "Build 5 of project wharf-api failed on branch master.\r\n\r\nStatus message: timed out")
+}
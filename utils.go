@@ -20,3 +20,12 @@ func asAnySlice[S ~[]E, E any](values S) []any {
 	}
 	return newSlice
 }
+
+// truncateString returns the string cut down to at most maxLen characters.
+func truncateString(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
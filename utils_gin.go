@@ -1,62 +1,87 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/iver-wharf/wharf-api/v5/pkg/orderby"
 	"github.com/iver-wharf/wharf-core/pkg/ginutil"
-	ua "github.com/mileusna/useragent"
 )
 
+type paginationQueryParams struct {
+	Limit     int  `form:"limit" binding:"required_with=Offset"`
+	Offset    int  `form:"offset" binding:"min=0"`
+	SkipTotal bool `form:"skipTotal"`
+}
+
+func (p *paginationQueryParams) pagination() *paginationQueryParams {
+	return p
+}
+
 type commonGetQueryParams struct {
-	Limit  int `form:"limit" binding:"required_with=Offset"`
-	Offset int `form:"offset" binding:"min=0"`
+	paginationQueryParams
 
 	OrderBy []string `form:"orderby"`
 }
 
+// timeMetadataQueryParams holds the filters on the database.TimeMetadata
+// fields, for use with optionalTimeRangeScope.
+type timeMetadataQueryParams struct {
+	CreatedAfter  *time.Time `form:"createdAfter"`
+	CreatedBefore *time.Time `form:"createdBefore"`
+	UpdatedAfter  *time.Time `form:"updatedAfter"`
+	UpdatedBefore *time.Time `form:"updatedBefore"`
+}
+
 var defaultCommonGetQueryParams = commonGetQueryParams{
-	Limit:  100,
-	Offset: 0,
+	paginationQueryParams: paginationQueryParams{
+		Limit:  DefaultConfig.HTTP.Pagination.DefaultLimit,
+		Offset: 0,
+	},
 }
 
 func bindCommonGetQueryParams(c *gin.Context, params any) bool {
 	if err := c.ShouldBindQuery(params); err != nil {
-		ginutil.WriteInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
+		writeInvalidBindError(c, err, "One or more parameters failed to parse when reading query parameters.")
 		return false
 	}
+	if p, ok := params.(interface{ pagination() *paginationQueryParams }); ok {
+		return applyPaginationConfig(c, p.pagination())
+	}
 	return true
 }
 
 func parseCommonOrderBySlice(c *gin.Context, orders []string, fieldToColumnNames map[string]database.SafeSQLName) (orderby.Slice, bool) {
-	orderBySlice, err := orderby.ParseSlice(orders, fieldToColumnNames)
+	return parseCommonOrderBySliceWithExpressions(c, orders, fieldToColumnNames, nil)
+}
+
+// parseCommonOrderBySliceWithExpressions works the same as
+// parseCommonOrderBySlice, but also accepts sorting on the SQL expressions,
+// as described in orderby.ParseSliceWithExpressions.
+func parseCommonOrderBySliceWithExpressions(c *gin.Context, orders []string, fieldToColumnNames, fieldToExpressions map[string]database.SafeSQLName) (orderby.Slice, bool) {
+	orderBySlice, err := orderby.ParseSliceWithExpressions(orders, fieldToColumnNames, fieldToExpressions)
 	if err != nil {
-		joinedOrders := strings.Join(orders, ", ")
-		ginutil.WriteInvalidParamError(c, err, "orderby", fmt.Sprintf(
-			"Failed parsing the %d sort ordering values: %s",
-			len(orders),
-			joinedOrders))
+		var parseErr *orderby.ParseError
+		if !errors.As(err, &parseErr) {
+			joinedOrders := strings.Join(orders, ", ")
+			ginutil.WriteInvalidParamError(c, err, "orderby", fmt.Sprintf(
+				"Failed parsing the %d sort ordering values: %s",
+				len(orders),
+				joinedOrders))
+		} else if parseErr.Token == "" {
+			ginutil.WriteInvalidParamError(c, err, "orderby", fmt.Sprintf(
+				"Invalid sort ordering number %d, %q: %v.",
+				parseErr.Index+1, parseErr.Query, parseErr.Err))
+		} else {
+			ginutil.WriteInvalidParamError(c, err, "orderby", fmt.Sprintf(
+				"Invalid value %q in sort ordering number %d, %q: %v.",
+				parseErr.Token, parseErr.Index+1, parseErr.Query, parseErr.Err))
+		}
 		return nil, false
 	}
 	return orderBySlice, true
 }
-
-func renderJSON(c *gin.Context, code int, response any) {
-	if shouldIndentJSONResponse(c) {
-		c.IndentedJSON(code, response)
-	} else {
-		c.JSON(code, response)
-	}
-}
-
-func shouldIndentJSONResponse(c *gin.Context) bool {
-	prettyQuery, ok := c.GetQuery("pretty")
-	if ok {
-		return prettyQuery == "" || strings.EqualFold(prettyQuery, "true")
-	}
-	agent := ua.Parse(c.Request.UserAgent())
-	return agent.Name == "curl"
-}
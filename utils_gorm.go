@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -13,15 +14,131 @@ import (
 	"golang.org/x/text/language"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
-func findDBPaginatedSliceAndTotalCount(dbQuery *gorm.DB, limit, offset int, slicePtr any, totalCount *int64) error {
-	err := dbQuery.Scopes(optionalLimitOffsetScope(limit, offset)).Find(slicePtr).Error
-	if err != nil {
+// totalCountSkipped is the total count of paginated responses when the
+// client asked to skip counting the total number of results.
+const totalCountSkipped = -1
+
+// findDBPaginatedSliceAndTotalCount finds a page of results, and the total
+// number of results regardless of the limit and offset. The total count is set
+// to totalCountSkipped without querying the database if skipTotal is true.
+//
+// The total count is only queried when it cannot be told from the page
+// itself, such as when the page is full. On Postgres, the results are then
+// filtered and counted in a single windowed query.
+func findDBPaginatedSliceAndTotalCount(dbQuery *gorm.DB, limit, offset int, skipTotal bool, slicePtr any, totalCount *int64) error {
+	if offset < 0 {
+		offset = 0
+	}
+	// Each query starts from a new session, so that the limit and offset are
+	// not added to the statement that is reused by the next query.
+	dbQuery = dbQuery.Session(&gorm.Session{})
+	if !skipTotal && limit > 0 && canUseWindowedCount(dbQuery, slicePtr) {
+		return findDBPaginatedSliceWithWindowedCount(dbQuery, limit, offset, slicePtr, totalCount)
+	}
+	if err := dbQuery.
+		Scopes(optionalLimitOffsetScope(limit, offset)).
+		Find(slicePtr).
+		Error; err != nil {
 		return err
 	}
+	if skipTotal {
+		*totalCount = totalCountSkipped
+		return nil
+	}
+	if n, ok := totalCountFromPage(limit, offset, reflect.ValueOf(slicePtr).Elem().Len()); ok {
+		*totalCount = n
+		return nil
+	}
+	return countDBQuery(dbQuery, slicePtr, totalCount)
+}
+
+// totalCountFromPage returns the total number of results, or false if it
+// cannot be told from the number of results in the page, such as when the
+// page is full.
+func totalCountFromPage(limit, offset, pageLen int) (int64, bool) {
+	if limit <= 0 {
+		// Neither limit nor offset is applied, so the page has all results.
+		return int64(pageLen), true
+	}
+	if pageLen >= limit || (pageLen == 0 && offset > 0) {
+		return 0, false
+	}
+	return int64(offset + pageLen), true
+}
+
+// countDBQuery counts the results of the query, without the preloads that
+// would otherwise add further queries.
+func countDBQuery(dbQuery *gorm.DB, slicePtr any, totalCount *int64) error {
+	countQuery := dbQuery.Session(&gorm.Session{})
+	if countQuery.Statement.Model == nil {
+		countQuery = countQuery.Model(slicePtr)
+	} else {
+		countQuery = countQuery.Model(countQuery.Statement.Model)
+	}
+	countQuery.Statement.Preloads = nil
+	return countQuery.Count(totalCount).Error
+}
+
+// canUseWindowedCount returns true if the query can be counted using a
+// window function. Queries that select specific columns, join other tables,
+// or have non-integer primary keys are always counted separately.
+func canUseWindowedCount(dbQuery *gorm.DB, slicePtr any) bool {
+	if dbQuery.Dialector.Name() != "postgres" ||
+		len(dbQuery.Statement.Selects) > 0 ||
+		len(dbQuery.Statement.Joins) > 0 {
+		return false
+	}
+	stmt := dbQuery.Model(slicePtr).Statement
+	if err := stmt.Parse(slicePtr); err != nil {
+		return false
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	return pk != nil && pk.DataType == schema.Uint
+}
 
-	return dbQuery.Count(totalCount).Error
+// findDBPaginatedSliceWithWindowedCount finds the primary keys of a page of
+// results together with the total count in a single query, using the
+// COUNT(*) OVER () window function, and then finds the results by their
+// primary keys with any preloads applied.
+func findDBPaginatedSliceWithWindowedCount(dbQuery *gorm.DB, limit, offset int, slicePtr any, totalCount *int64) error {
+	keysQuery := dbQuery.Model(slicePtr)
+	if err := keysQuery.Statement.Parse(slicePtr); err != nil {
+		return err
+	}
+	pkColumn := fmt.Sprintf("%s.%s",
+		keysQuery.Statement.Schema.Table,
+		keysQuery.Statement.Schema.PrioritizedPrimaryField.DBName)
+	keysQuery = keysQuery.
+		Select(pkColumn + " AS id, COUNT(*) OVER () AS total_count").
+		Scopes(optionalLimitOffsetScope(limit, offset))
+	keysQuery.Statement.Preloads = nil
+	var rows []struct {
+		ID         uint
+		TotalCount int64
+	}
+	if err := keysQuery.Scan(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		if offset == 0 {
+			*totalCount = 0
+			return nil
+		}
+		// Paged past the last result, so the total count is still unknown.
+		return countDBQuery(dbQuery, slicePtr, totalCount)
+	}
+	keys := make([]uint, len(rows))
+	for i, row := range rows {
+		keys[i] = row.ID
+	}
+	*totalCount = rows[0].TotalCount
+	return dbQuery.
+		Where(pkColumn+" IN ?", keys).
+		Find(slicePtr).
+		Error
 }
 
 func fetchDatabaseObjByID(c *gin.Context, db *gorm.DB, modelPtr any, id uint, name, whenMsg string) bool {
@@ -101,6 +218,36 @@ func optionalTimeRangeScope(column database.SafeSQLName, min, max *time.Time) fu
 	}
 }
 
+func optionalInt64RangeScope(column database.SafeSQLName, min, max *int64) func(*gorm.DB) *gorm.DB {
+	if min == nil && max == nil {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		if min != nil {
+			db = db.Where(string(column)+" >= ?", *min)
+		}
+		if max != nil {
+			db = db.Where(string(column)+" <= ?", *max)
+		}
+		return db
+	}
+}
+
+func optionalDurationRangeScope(column database.SafeSQLName, min, max *time.Duration) func(*gorm.DB) *gorm.DB {
+	if min == nil && max == nil {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		if min != nil {
+			db = db.Where(string(column)+" >= ?", min.Milliseconds())
+		}
+		if max != nil {
+			db = db.Where(string(column)+" <= ?", max.Milliseconds())
+		}
+		return db
+	}
+}
+
 func gormIdentityScope(db *gorm.DB) *gorm.DB {
 	return db
 }
@@ -130,6 +277,33 @@ func whereAnyLikeScope(value *string, keys ...database.SafeSQLName) func(*gorm.D
 	}
 }
 
+// whereFullTextMatchScope filters on the column matching all words in the
+// value. Uses full-text search in PostgreSQL, which relies on the expression
+// index created by migrateAddLogMessageSearchIndex for performance, and falls
+// back to a LIKE "contains" query in other databases.
+func whereFullTextMatchScope(value *string, key database.SafeSQLName) func(*gorm.DB) *gorm.DB {
+	if value == nil || *value == "" {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		b := newGormClauseBuilder(db.Dialector)
+		return db.Clauses(b.fullTextMatchExpr(key, value))
+	}
+}
+
+func whereAnyEqualScope(value *string, keys ...database.SafeSQLName) func(*gorm.DB) *gorm.DB {
+	if value == nil || len(keys) == 0 {
+		return gormIdentityScope
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		expressions := make([]clause.Expression, len(keys))
+		for i, key := range keys {
+			expressions[i] = clause.Eq{Column: clause.Column{Name: key}, Value: *value}
+		}
+		return db.Clauses(clause.Or(expressions...))
+	}
+}
+
 type gormClauseBuilder struct {
 	dialect DBDriver
 }
@@ -187,6 +361,24 @@ func (b gormClauseBuilder) likeExpr(key database.SafeSQLName, value *string) cla
 	}
 }
 
+// fullTextSearchConfig is the PostgreSQL text search configuration used in
+// full-text searches. The "simple" configuration does not remove stop words or
+// stem words, which suits searching for error messages in logs.
+const fullTextSearchConfig = "simple"
+
+func (b gormClauseBuilder) fullTextMatchExpr(key database.SafeSQLName, value *string) clause.Expression {
+	if value == nil || *value == "" {
+		return nil
+	}
+	if b.dialect != DBDriverPostgres {
+		return b.likeExpr(key, value)
+	}
+	return clause.Expr{
+		SQL:  fmt.Sprintf("to_tsvector('%[1]s', %[2]s) @@ plainto_tsquery('%[1]s', ?)", fullTextSearchConfig, key),
+		Vars: []any{*value},
+	}
+}
+
 // newLikeContainsValue generates an SQL value for a LIKE query, and escapes all
 // special LIKE characters such as %, ?, _, and \ itself. Examples:
 // 	"foo" // => "%foo%"
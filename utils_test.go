@@ -5,8 +5,39 @@ import (
 
 	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
+// newTestDryRunDB returns a database that only builds the SQL statements of
+// the queries, without connecting to a database.
+func newTestDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		NamingStrategy:       schema.NamingStrategy{SingularTable: true},
+	})
+	require.NoError(t, err)
+	return db
+}
+
+// newTestSqliteDB returns an in-memory SQLite database with the full schema
+// migrated.
+func newTestSqliteDB(t *testing.T) *gorm.DB {
+	db, err := openDatabase(DBConfig{Driver: DBDriverSqlite, Path: ":memory:"})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Each connection to ":memory:" gets its own database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, migrateInitSchema(db))
+	return db
+}
+
 func TestFindDefaultGroupSuccess(t *testing.T) {
 	var (
 		main = database.Branch{Name: "main", Default: true}
@@ -93,3 +124,56 @@ func TestNewLikeContainsValue(t *testing.T) {
 		})
 	}
 }
+
+func TestGormClauseBuilder_fullTextMatchExpr(t *testing.T) {
+	value := "connection refused"
+
+	postgres := gormClauseBuilder{dialect: DBDriverPostgres}
+	assert.Equal(t, clause.Expr{
+		SQL:  "to_tsvector('simple', message) @@ plainto_tsquery('simple', ?)",
+		Vars: []any{value},
+	}, postgres.fullTextMatchExpr(database.LogColumns.Message, &value))
+
+	sqlite := gormClauseBuilder{dialect: DBDriverSqlite}
+	assert.Equal(t, clause.Expr{
+		SQL:  `message LIKE ? ESCAPE '\'`,
+		Vars: []any{"%connection refused%"},
+	}, sqlite.fullTextMatchExpr(database.LogColumns.Message, &value))
+}
+
+func TestTotalCountFromPage(t *testing.T) {
+	testCases := []struct {
+		name    string
+		limit   int
+		offset  int
+		pageLen int
+		want    int64
+		wantOK  bool
+	}{
+		{name: "no limit", limit: 0, offset: 0, pageLen: 7, want: 7, wantOK: true},
+		{name: "last page", limit: 5, offset: 10, pageLen: 3, want: 13, wantOK: true},
+		{name: "empty first page", limit: 5, offset: 0, pageLen: 0, want: 0, wantOK: true},
+		{name: "full page", limit: 5, offset: 10, pageLen: 5},
+		{name: "past last page", limit: 5, offset: 10, pageLen: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := totalCountFromPage(tc.limit, tc.offset, tc.pageLen)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCanUseWindowedCount(t *testing.T) {
+	db := newTestDryRunDB(t)
+	var builds []database.Build
+	assert.True(t, canUseWindowedCount(db.Where("stage = ?", "deploy"), &builds))
+	assert.False(t, canUseWindowedCount(db.Select(database.BuildColumns.BuildID), &builds))
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "abc", truncateString("abc", 5))
+	assert.Equal(t, "abc", truncateString("abcdef", 3))
+	assert.Equal(t, "åäö", truncateString("åäöü", 3))
+}
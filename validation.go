@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/iver-wharf/wharf-api/v5/pkg/problems"
+	"github.com/iver-wharf/wharf-core/pkg/ginutil"
+)
+
+// requestMaxLengths maps request model fields to the size limits of the
+// database columns they are stored in, so that too long values are rejected
+// when binding the request instead of failing when writing to the database.
+var requestMaxLengths = []struct {
+	model      any
+	maxLengths map[string]int
+}{
+	{request.Token{}, map[string]int{
+		"Token":    database.TokenSizes.Value,
+		"UserName": database.TokenSizes.UserName,
+	}},
+	{request.TokenUpdate{}, map[string]int{
+		"Token":    database.TokenSizes.Value,
+		"UserName": database.TokenSizes.UserName,
+	}},
+	{request.BuildReview{}, map[string]int{
+		"Comment": database.BuildSizes.ReviewComment,
+	}},
+	{request.BuildComment{}, map[string]int{
+		"Text": database.BuildCommentSizes.Text,
+	}},
+	{request.ProjectInstanceUpdate{}, map[string]int{
+		"InstanceID": database.ProjectSizes.InstanceID,
+	}},
+	{request.BuildStatusUpdate{}, map[string]int{
+		"StatusMessage": database.BuildSizes.StatusMessage,
+	}},
+	{request.BuildLink{}, map[string]int{
+		"Name": database.BuildLinkSizes.Name,
+		"URL":  database.BuildLinkSizes.URL,
+	}},
+	{request.ArtifactPromotion{}, map[string]int{
+		"Release": database.ReleaseArtifactSizes.Release,
+	}},
+	{request.BuildTrigger{}, map[string]int{
+		"BranchFilter": database.BuildTriggerSizes.BranchFilter,
+		"Stage":        database.BuildTriggerSizes.Stage,
+	}},
+	{request.BuildTriggerUpdate{}, map[string]int{
+		"BranchFilter": database.BuildTriggerSizes.BranchFilter,
+		"Stage":        database.BuildTriggerSizes.Stage,
+	}},
+	{request.GitTrigger{}, map[string]int{
+		"BranchFilter": database.GitTriggerSizes.BranchFilter,
+		"Stage":        database.GitTriggerSizes.Stage,
+		"Environment":  database.GitTriggerSizes.Environment,
+	}},
+	{request.Environment{}, map[string]int{
+		"Name":        database.EnvironmentSizes.Name,
+		"Description": database.EnvironmentSizes.Description,
+	}},
+	{request.EnvironmentUpdate{}, map[string]int{
+		"Name":        database.EnvironmentSizes.Name,
+		"Description": database.EnvironmentSizes.Description,
+	}},
+	{request.Project{}, map[string]int{
+		"Name":        database.ProjectSizes.Name,
+		"GroupName":   database.ProjectSizes.GroupName,
+		"Description": database.ProjectSizes.Description,
+		"AvatarURL":   database.ProjectSizes.AvatarURL,
+	}},
+	{request.ProjectUpdate{}, map[string]int{
+		"Name":        database.ProjectSizes.Name,
+		"GroupName":   database.ProjectSizes.GroupName,
+		"Description": database.ProjectSizes.Description,
+		"AvatarURL":   database.ProjectSizes.AvatarURL,
+	}},
+	{request.ProjectOverridesUpdate{}, map[string]int{
+		"Description": database.ProjectOverridesSizes.Description,
+		"AvatarURL":   database.ProjectOverridesSizes.AvatarURL,
+	}},
+	{request.Provider{}, map[string]int{
+		"Name":      database.ProviderSizes.Name,
+		"URL":       database.ProviderSizes.URL,
+		"UploadURL": database.ProviderSizes.UploadURL,
+	}},
+	{request.ProviderUpdate{}, map[string]int{
+		"Name":      database.ProviderSizes.Name,
+		"URL":       database.ProviderSizes.URL,
+		"UploadURL": database.ProviderSizes.UploadURL,
+	}},
+}
+
+// registerRequestValidations adds the custom validation rules for the request
+// models to the validator used by Gin's binding. Validation errors then refer
+// to fields by their JSON or query parameter names.
+//
+// Must be called before serving any requests, as the validator is not safe
+// for concurrent modification.
+func registerRequestValidations(v *validator.Validate) {
+	v.RegisterTagNameFunc(getJSONFieldName)
+	for _, rule := range requestMaxLengths {
+		v.RegisterStructValidation(newMaxLengthsValidation(rule.model, rule.maxLengths), rule.model)
+	}
+}
+
+func getJSONFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		name, _, _ = strings.Cut(field.Tag.Get("form"), ",")
+	}
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+func newMaxLengthsValidation(model any, maxLengths map[string]int) validator.StructLevelFunc {
+	type fieldMaxLength struct {
+		field     reflect.StructField
+		maxLength int
+	}
+	modelType := reflect.TypeOf(model)
+	var fields []fieldMaxLength
+	for fieldName, maxLength := range maxLengths {
+		field, ok := modelType.FieldByName(fieldName)
+		if !ok || field.Type.Kind() != reflect.String {
+			panic(fmt.Sprintf("no string field %q in %s", fieldName, modelType))
+		}
+		fields = append(fields, fieldMaxLength{field, maxLength})
+	}
+	// Sorted by declaration order for consistent validation error order.
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].field.Index[0] < fields[j].field.Index[0]
+	})
+	return func(sl validator.StructLevel) {
+		current := sl.Current()
+		for _, f := range fields {
+			value := current.FieldByIndex(f.field.Index).String()
+			if utf8.RuneCountInString(value) > f.maxLength {
+				sl.ReportError(value, getJSONFieldName(f.field), f.field.Name, "max", strconv.Itoa(f.maxLength))
+			}
+		}
+	}
+}
+
+// writeInvalidBindError writes a 400 "Bad Request" problem response, the same
+// as ginutil.WriteInvalidBindError, but with one error message per invalid
+// field in case of validation errors.
+func writeInvalidBindError(c *gin.Context, err error, detail string) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		ginutil.WriteInvalidBindError(c, err, detail)
+		return
+	}
+	prob := problems.InvalidParam.New(detail)
+	prob.Errors = make([]string, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		prob.Errors[i] = formatFieldError(fieldErr)
+	}
+	c.Error(err)
+	ginutil.WriteProblem(c, prob)
+}
+
+func formatFieldError(fieldErr validator.FieldError) string {
+	// Namespace is prefixed with the name of the request model type.
+	_, field, ok := strings.Cut(fieldErr.Namespace(), ".")
+	if !ok {
+		field = fieldErr.Field()
+	}
+	switch fieldErr.Tag() {
+	case "required":
+		return fmt.Sprintf("%s: is required", field)
+	case "max":
+		return fmt.Sprintf("%s: must not be longer than %s characters", field, fieldErr.Param())
+	case "url":
+		return fmt.Sprintf("%s: must be an absolute URL", field)
+	default:
+		return fmt.Sprintf("%s: failed on the %q validation rule", field, fieldErr.Tag())
+	}
+}
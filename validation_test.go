@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/database"
+	"github.com/iver-wharf/wharf-api/v5/pkg/model/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRequestValidator(t *testing.T) *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	require.NotPanics(t, func() { registerRequestValidations(v) })
+	return v
+}
+
+func TestRequestValidation_maxLengths(t *testing.T) {
+	v := newTestRequestValidator(t)
+	reqProject := request.ProjectUpdate{
+		Name:        "my-project",
+		Description: strings.Repeat("x", database.ProjectSizes.Description+1),
+	}
+
+	err := v.Struct(reqProject)
+	require.Error(t, err)
+	var validationErrs validator.ValidationErrors
+	require.ErrorAs(t, err, &validationErrs)
+	require.Len(t, validationErrs, 1)
+	assert.Equal(t, "description: must not be longer than 500 characters", formatFieldError(validationErrs[0]))
+
+	reqProject.Description = strings.Repeat("å", database.ProjectSizes.Description)
+	assert.NoError(t, v.Struct(reqProject), "counts characters, not bytes")
+}
+
+func TestRequestValidation_fieldTags(t *testing.T) {
+	v := newTestRequestValidator(t)
+	reqProvider := request.ProviderUpdate{
+		Name: request.ProviderGitLab,
+		URL:  "not a URL",
+	}
+
+	err := v.Struct(reqProvider)
+	require.Error(t, err)
+	var validationErrs validator.ValidationErrors
+	require.ErrorAs(t, err, &validationErrs)
+	require.Len(t, validationErrs, 1)
+	assert.Equal(t, "url: must be an absolute URL", formatFieldError(validationErrs[0]))
+
+	reqProvider.URL = "https://gitlab.example.com"
+	assert.NoError(t, v.Struct(reqProvider))
+}
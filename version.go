@@ -6,6 +6,7 @@ import (
 	_ "embed"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-api/v5/internal/ginrender"
 	"github.com/iver-wharf/wharf-core/pkg/app"
 )
 
@@ -31,5 +32,5 @@ func loadEmbeddedVersionFile() error {
 // @success 200 {object} app.Version
 // @router /version [get]
 func getVersionHandler(c *gin.Context) {
-	renderJSON(c, http.StatusOK, AppVersion)
+	ginrender.Response(c, http.StatusOK, AppVersion)
 }